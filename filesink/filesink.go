@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
@@ -28,8 +29,13 @@ type CommonConfig struct {
 	Extension      string
 	UploadInterval string
 	FileSizeLimit  int
+	// MaxOpenPartitions is the maximum number of files of partitioned bindings
+	// that may be open at a time. Defaults to defaultMaxOpenPartitions if 0.
+	MaxOpenPartitions int
 }
 
+const defaultMaxOpenPartitions = 10
+
 // Store represents a file/object storage system capable of put'ing a stream of data to a binary
 // object with a specified key.
 type Store interface {
@@ -44,7 +50,10 @@ type StreamEncoder interface {
 }
 
 type resource struct {
-	Path string `json:"path" jsonschema:"title=Path,description=The path that objects will be materialized to." jsonschema_extras:"x-collection-name=true"`
+	Path            string     `json:"path" jsonschema:"title=Path,description=The path that objects will be materialized to." jsonschema_extras:"x-collection-name=true"`
+	PartitionFields []string   `json:"partitionFields,omitempty" jsonschema:"title=Partition Fields,description=Fields to partition files by. Each field adds a Hive-style <field>=<value> directory to the keys of files."`
+	TimeBucket      timeBucket `json:"timeBucket,omitempty" jsonschema:"title=Time Bucket,description=Partition files by the day or hour of a timestamp. This adds a dt=<date> directory and for hourly buckets an hour=<hour> directory to the keys of files before any partition fields.,enum=day,enum=hour"`
+	TimeField       string     `json:"timeField,omitempty" jsonschema:"title=Time Field,description=Date-time or date field to use for the time bucket. The commit time of the prior transaction is used if this is not set."`
 }
 
 func (r resource) Validate() error {
@@ -61,7 +70,7 @@ var _ boilerplate.Connector = &FileDriver{}
 type FileDriver struct {
 	NewConfig        func(raw json.RawMessage) (Config, error)
 	NewStore         func(ctx context.Context, config Config) (Store, error)
-	NewEncoder       func(config Config, b *pf.MaterializationSpec_Binding, w io.WriteCloser) StreamEncoder
+	NewEncoder       func(config Config, b *pf.MaterializationSpec_Binding, w io.WriteCloser) (StreamEncoder, error)
 	NewConstraints   func(p *pf.Projection) *pm.Response_Validated_Constraint
 	DocumentationURL func() string
 	ConfigSchema     func() ([]byte, error)
@@ -100,10 +109,19 @@ func (d FileDriver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm
 		var res resource
 		if err := pf.UnmarshalStrict(b.ResourceConfigJson, &res); err != nil {
 			return nil, fmt.Errorf("parsing resource config: %w", err)
+		} else if err := validatePartitioning(res, b.Collection); err != nil {
+			return nil, fmt.Errorf("validating partitioning for %s: %w", res.Path, err)
 		}
 
 		constraints := make(map[string]*pm.Response_Validated_Constraint)
 		for _, p := range b.Collection.Projections {
+			if p.Field == res.TimeField || slices.Contains(res.PartitionFields, p.Field) {
+				constraints[p.Field] = &pm.Response_Validated_Constraint{
+					Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
+					Reason: "This field is used to partition files",
+				}
+				continue
+			}
 			constraints[p.Field] = d.NewConstraints(&p)
 		}
 
@@ -129,6 +147,7 @@ func (d FileDriver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *
 	}
 
 	bindings := make([]binding, 0, len(open.Materialization.Bindings))
+	commitTimeBuckets := false
 
 	for _, b := range open.Materialization.Bindings {
 		b := b // for the newEncoder closure
@@ -138,12 +157,19 @@ func (d FileDriver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *
 			return nil, nil, nil, err
 		}
 
+		partitioner, err := newPartitioner(res, b.FieldSelection.AllFields())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("binding for %s: %w", res.Path, err)
+		}
+		commitTimeBuckets = commitTimeBuckets || partitioner.usesCommitTime()
+
 		bindings = append(bindings, binding{
-			stateKey:   b.StateKey,
-			backfill:   b.Backfill,
-			path:       res.Path,
-			includeDoc: b.FieldSelection.Document != "",
-			newEncoder: func(w io.WriteCloser) StreamEncoder {
+			stateKey:    b.StateKey,
+			backfill:    b.Backfill,
+			path:        res.Path,
+			partitioner: partitioner,
+			includeDoc:  b.FieldSelection.Document != "",
+			newEncoder: func(w io.WriteCloser) (StreamEncoder, error) {
 				// Partial application of the driverCfg and b arguments to the FileDriver's NewEncoder
 				// function, for convenience.
 				return d.NewEncoder(driverCfg, b, w)
@@ -159,15 +185,26 @@ func (d FileDriver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *
 		},
 	}
 
+	common := driverCfg.CommonConfig()
+	if common.MaxOpenPartitions == 0 {
+		common.MaxOpenPartitions = defaultMaxOpenPartitions
+	}
+
 	return &transactor{
-		bindings: bindings,
-		store:    store,
-		common:   driverCfg.CommonConfig(),
+		bindings:          bindings,
+		store:             store,
+		common:            common,
+		commitTimeBuckets: commitTimeBuckets,
 	}, &pm.Response_Opened{}, opts, nil
 }
 
 type connectorState struct {
 	FileCounts map[string]uint64 `json:"fileCounts"`
+	// BucketTime is the time used for the time buckets of bindings without a
+	// time field. It is recorded in the checkpoint of the prior transaction, so
+	// that a transaction which is replayed after a failure uses the same time
+	// and overwrites the same keys, in the same way as the file counts.
+	BucketTime *time.Time `json:"bucketTime,omitempty"`
 }
 
 func (cs connectorState) Validate() error { return nil }
@@ -177,14 +214,18 @@ type transactor struct {
 	store    Store
 	state    connectorState
 	common   CommonConfig
+	// commitTimeBuckets is true if any binding has a time bucket without a
+	// time field, and the bucket time must be recorded in the checkpoint.
+	commitTimeBuckets bool
 }
 
 type binding struct {
-	stateKey   string
-	backfill   uint32
-	path       string
-	includeDoc bool
-	newEncoder func(w io.WriteCloser) StreamEncoder
+	stateKey    string
+	backfill    uint32
+	path        string
+	partitioner partitioner
+	includeDoc  bool
+	newEncoder  func(w io.WriteCloser) (StreamEncoder, error)
 }
 
 // File keys are the full "path" to a file, usually applied as a key for an object in an object
@@ -193,15 +234,17 @@ type binding struct {
 // 2) The resource path.
 // 3) The backfill version: Incrementing the backfill counter will not delete any files, but it will
 // start to materialize them using a different backfill version part of their keys.
-// 4) A monotonic counter for the files. Each file gets the next highest counter value. There may be
+// 4) The Hive-style partition of the file, like "dt=2024-05-06/region=eu", if the binding is
+// partitioned.
+// 5) A monotonic counter for the files. Each file gets the next highest counter value. There may be
 // multiple files created within the same transaction. If the connector fails part-way through a
 // transaction, some files may have been written without committing that transaction to the recovery
 // log. In that case, when the connector restarts files with the same names will be uploaded to
 // overwrite the previous files. In this way the connector is effectively-once, although the most
 // recent files may have some churn. This will become less of a concern when/if Flow transactions
 // are idempotent, since the re-uploaded files will at least have the same data.
-// 5) The file extension from the specific driver implementation.
-func (t *transactor) nextFileKey(b binding) string {
+// 6) The file extension from the specific driver implementation.
+func (t *transactor) nextFileKey(b binding, partition string) string {
 	sk := b.stateKey
 
 	// If we haven't generated any file keys yet for this state key, start at 0. Otherwise use one
@@ -216,6 +259,7 @@ func (t *transactor) nextFileKey(b binding) string {
 		t.common.Prefix,
 		b.path,
 		fmt.Sprintf("v%010d", b.backfill), // 10 digits to hold the largest possible uint32
+		partition,
 		fmt.Sprintf("%020d%s", next, t.common.Extension), // 20 digits to hold the largest possible uint64
 	)
 }
@@ -239,18 +283,38 @@ func (t *transactor) Load(it *m.LoadIterator, _ func(int, json.RawMessage) error
 	return nil
 }
 
+// openFile is a file which is being uploaded as rows are encoded to it.
+type openFile struct {
+	encoder StreamEncoder
+	group   errgroup.Group
+	// lastUsed orders open files by when a row was last encoded to them.
+	lastUsed int
+}
+
 func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 	var ctx = it.Context()
-	var encoder StreamEncoder
-	var group errgroup.Group
+	var bucketTime = time.Now()
+	if t.state.BucketTime != nil {
+		bucketTime = *t.state.BucketTime
+	}
+	// Files which are open for writing, by partition. There is only ever a single open file for
+	// bindings that aren't partitioned.
+	var files = make(map[string]*openFile)
+	var rowCount int
 
-	startFile := func(b binding) {
+	startFile := func(b binding, partition string) (*openFile, error) {
 		// Start a new file upload. This may be called multiple times in a single transaction if
 		// there is more data than can fit in a single file.
 		r, w := io.Pipe()
+		encoder, err := b.newEncoder(w)
+		if err != nil {
+			w.Close()
+			return nil, fmt.Errorf("creating encoder: %w", err)
+		}
+		f := &openFile{encoder: encoder}
+		k := t.nextFileKey(b, partition)
 
-		group.Go(func() error {
-			k := t.nextFileKey(b)
+		f.group.Go(func() error {
 			log.WithField("key", k).Info("started uploading file")
 			if err := t.store.PutStream(ctx, r, k); err != nil {
 				r.CloseWithError(err)
@@ -261,20 +325,30 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			return nil
 		})
 
-		encoder = b.newEncoder(w)
+		files[partition] = f
+		return f, nil
 	}
 
-	finishFile := func() error {
-		// Close out the current file and wait for its upload to complete.
-		if encoder == nil {
-			return nil
-		} else if err := encoder.Close(); err != nil {
+	finishFile := func(partition string) error {
+		// Close out the file and wait for its upload to complete.
+		f := files[partition]
+		delete(files, partition)
+
+		if err := f.encoder.Close(); err != nil {
 			return fmt.Errorf("closing encoder: %w", err)
-		} else if err := group.Wait(); err != nil {
+		} else if err := f.group.Wait(); err != nil {
 			return fmt.Errorf("group.Wait(): %w", err)
 		}
 
-		encoder = nil
+		return nil
+	}
+
+	finishAllFiles := func() error {
+		for partition := range files {
+			if err := finishFile(partition); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
@@ -283,16 +357,12 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		b := t.bindings[it.Binding]
 
 		if lastBinding != -1 && lastBinding != it.Binding {
-			if err := finishFile(); err != nil {
+			if err := finishAllFiles(); err != nil {
 				return nil, fmt.Errorf("finishFile after binding change: %w", err)
 			}
 		}
 		lastBinding = it.Binding
 
-		if encoder == nil {
-			startFile(b)
-		}
-
 		row := make([]any, 0, len(it.Key)+len(it.Values)+1)
 		row = append(row, it.Key.ToInterface()...)
 		row = append(row, it.Values.ToInterface()...)
@@ -300,12 +370,39 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			row = append(row, it.RawJSON)
 		}
 
-		if err := encoder.Encode(row); err != nil {
+		partition, err := b.partitioner.partition(row, bucketTime)
+		if err != nil {
+			return nil, fmt.Errorf("computing partition: %w", err)
+		}
+
+		f, ok := files[partition]
+		if !ok {
+			if len(files) >= t.common.MaxOpenPartitions {
+				// Finish the least recently used file to make room for this one.
+				var lru string
+				var lruUsed = -1
+				for p, other := range files {
+					if lruUsed == -1 || other.lastUsed < lruUsed {
+						lru, lruUsed = p, other.lastUsed
+					}
+				}
+				if err := finishFile(lru); err != nil {
+					return nil, fmt.Errorf("finishFile on maximum open partitions: %w", err)
+				}
+			}
+			if f, err = startFile(b, partition); err != nil {
+				return nil, err
+			}
+		}
+		rowCount++
+		f.lastUsed = rowCount
+
+		if err := f.encoder.Encode(row); err != nil {
 			return nil, fmt.Errorf("encoding row: %w", err)
 		}
 
-		if t.common.FileSizeLimit != 0 && encoder.Written() >= t.common.FileSizeLimit {
-			if err := finishFile(); err != nil {
+		if t.common.FileSizeLimit != 0 && f.encoder.Written() >= t.common.FileSizeLimit {
+			if err := finishFile(partition); err != nil {
 				return nil, fmt.Errorf("finishFile on file size limit: %w", err)
 			}
 		}
@@ -315,11 +412,17 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		return nil, fmt.Errorf("store iterator error: %w", err)
 	}
 
-	if err := finishFile(); err != nil {
+	if err := finishAllFiles(); err != nil {
 		return nil, fmt.Errorf("final finishFile: %w", err)
 	}
 
 	return func(ctx context.Context, _ *protocol.Checkpoint) (*pf.ConnectorState, m.OpFuture) {
+		if t.commitTimeBuckets {
+			// The commit time of this transaction is the bucket time of the next.
+			var now = time.Now().UTC()
+			t.state.BucketTime = &now
+		}
+
 		checkpointJSON, err := json.Marshal(t.state)
 		if err != nil {
 			return nil, m.FinishedOperation(fmt.Errorf("creating checkpoint json: %w", err))
@@ -1,7 +1,9 @@
 package filesink
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +17,7 @@ func TestNextFilekey(t *testing.T) {
 		path      string
 		backfill  uint32
 		prevCount uint64
+		partition string
 		want      string
 	}{
 		{
@@ -59,6 +62,14 @@ func TestNextFilekey(t *testing.T) {
 			prevCount: 3,
 			want:      "prefix/path/v0000000002/00000000000000000004.something.gz",
 		},
+		{
+			prefix:    "prefix/",
+			path:      "path",
+			backfill:  0,
+			prevCount: 0,
+			partition: "dt=2024-05-06/region=eu",
+			want:      "prefix/path/v0000000000/dt=2024-05-06/region=eu/00000000000000000000.something.gz",
+		},
 	}
 
 	for _, tt := range tests {
@@ -84,8 +95,32 @@ func TestNextFilekey(t *testing.T) {
 				ta.common.Prefix = tt.prefix
 			}
 
-			require.Equal(t, tt.want, ta.nextFileKey(b))
+			require.Equal(t, tt.want, ta.nextFileKey(b, tt.partition))
 		})
 	}
 
 }
+
+func TestUnmarshalStateBucketTime(t *testing.T) {
+	var ta transactor
+	require.NoError(t, ta.UnmarshalState(json.RawMessage(`{"fileCounts":{"val":2}}`)))
+	require.Nil(t, ta.state.BucketTime)
+	require.Equal(t, uint64(2), ta.state.FileCounts["val"])
+
+	// The bucket time recorded by the prior transaction is restored, so that a
+	// replayed transaction is partitioned in the same way.
+	ta = transactor{}
+	require.NoError(t, ta.UnmarshalState(json.RawMessage(`{"fileCounts":{},"bucketTime":"2024-05-06T07:08:09Z"}`)))
+	require.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), *ta.state.BucketTime)
+
+	p, err := newPartitioner(resource{TimeBucket: timeBucketHour}, nil)
+	require.NoError(t, err)
+	require.True(t, p.usesCommitTime())
+	partition, err := p.partition(nil, *ta.state.BucketTime)
+	require.NoError(t, err)
+	require.Equal(t, "dt=2024-05-06/hour=07", partition)
+
+	p, err = newPartitioner(resource{TimeBucket: timeBucketDay, TimeField: "ts"}, []string{"ts"})
+	require.NoError(t, err)
+	require.False(t, p.usesCommitTime())
+}
@@ -61,3 +61,74 @@ func NewCsvStreamEncoder(cfg CsvConfig, b *pf.MaterializationSpec_Binding, w io.
 
 	return enc.NewCsvEncoder(w, b.FieldSelection.AllFields(), opts...)
 }
+
+type JsonCompression string
+
+const (
+	JsonCompressionGzip JsonCompression = "gzip"
+	JsonCompressionZstd JsonCompression = "zstd"
+	JsonCompressionNone JsonCompression = "none"
+)
+
+type JsonConfig struct {
+	Compression JsonCompression `json:"compression,omitempty" jsonschema:"title=Compression,description=Compression to use for files. Defaults to gzip if blank.,enum=gzip,enum=zstd,enum=none" jsonschema_extras:"order=0"`
+}
+
+func (c JsonConfig) Validate() error {
+	switch c.Compression {
+	case "", JsonCompressionGzip, JsonCompressionZstd, JsonCompressionNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid compression %q", c.Compression)
+	}
+}
+
+// Extension returns the file extension for the configured compression.
+func (c JsonConfig) Extension() string {
+	switch c.Compression {
+	case JsonCompressionZstd:
+		return ".jsonl.zst"
+	case JsonCompressionNone:
+		return ".jsonl"
+	default:
+		return ".jsonl.gz"
+	}
+}
+
+func NewJsonStreamEncoder(cfg JsonConfig, b *pf.MaterializationSpec_Binding, w io.WriteCloser) StreamEncoder {
+	var opts []enc.JsonOption
+
+	switch cfg.Compression {
+	case JsonCompressionZstd:
+		opts = append(opts, enc.WithJsonZstdCompression())
+	case JsonCompressionNone:
+		opts = append(opts, enc.WithJsonDisableCompression())
+	}
+
+	return enc.NewJsonEncoder(w, b.FieldSelection.AllFields(), opts...)
+}
+
+type AvroConfig struct {
+	Compression enc.AvroCompression `json:"compression,omitempty" jsonschema:"title=Compression,description=Compression codec to use for file blocks. Defaults to snappy if blank.,enum=snappy,enum=deflate,enum=null" jsonschema_extras:"order=0"`
+}
+
+func (c AvroConfig) Validate() error {
+	switch c.Compression {
+	case "", enc.AvroSnappy, enc.AvroDeflate, enc.AvroUncompressed:
+		return nil
+	default:
+		return fmt.Errorf("invalid compression %q", c.Compression)
+	}
+}
+
+func NewAvroStreamEncoder(cfg AvroConfig, b *pf.MaterializationSpec_Binding, w io.WriteCloser) (StreamEncoder, error) {
+	sch := enc.FieldsToAvroSchema(b.FieldSelection.AllFields(), b.Collection)
+
+	var opts []enc.AvroOption
+
+	if cfg.Compression != "" {
+		opts = append(opts, enc.WithAvroCompression(cfg.Compression))
+	}
+
+	return enc.NewAvroEncoder(w, sch, opts...)
+}
@@ -0,0 +1,33 @@
+package filesink
+
+import (
+	"testing"
+
+	enc "github.com/estuary/connectors/materialize-boilerplate/stream-encode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonConfig(t *testing.T) {
+	for _, tt := range []struct {
+		compression JsonCompression
+		extension   string
+	}{
+		{"", ".jsonl.gz"},
+		{JsonCompressionGzip, ".jsonl.gz"},
+		{JsonCompressionZstd, ".jsonl.zst"},
+		{JsonCompressionNone, ".jsonl"},
+	} {
+		cfg := JsonConfig{Compression: tt.compression}
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, tt.extension, cfg.Extension())
+	}
+
+	require.ErrorContains(t, JsonConfig{Compression: "brotli"}.Validate(), "invalid compression")
+}
+
+func TestAvroConfig(t *testing.T) {
+	for _, c := range []string{"", "snappy", "deflate", "null"} {
+		require.NoError(t, AvroConfig{Compression: enc.AvroCompression(c)}.Validate())
+	}
+	require.ErrorContains(t, AvroConfig{Compression: "zstd"}.Validate(), "invalid compression")
+}
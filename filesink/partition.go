@@ -0,0 +1,194 @@
+package filesink
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+)
+
+type timeBucket string
+
+const (
+	timeBucketNone timeBucket = ""
+	timeBucketDay  timeBucket = "day"
+	timeBucketHour timeBucket = "hour"
+
+	// hiveDefaultPartition is the partition value Hive uses for null values.
+	hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+)
+
+// partitioner computes the Hive-style partition that a row belongs to, like
+// "dt=2024-05-06/region=eu". Rows of bindings without partitioning all belong
+// to the empty partition.
+type partitioner struct {
+	bucket timeBucket
+	// timeIdx is the index of the time bucket field in a row, or -1 if the
+	// commit time is used for the bucket.
+	timeIdx int
+	fields  []string
+	// fieldIdx are the indices of the partition fields in a row.
+	fieldIdx []int
+}
+
+func newPartitioner(res resource, allFields []string) (partitioner, error) {
+	var p = partitioner{bucket: res.TimeBucket, timeIdx: -1, fields: res.PartitionFields}
+
+	if res.TimeField != "" {
+		if p.timeIdx = slices.Index(allFields, res.TimeField); p.timeIdx == -1 {
+			return partitioner{}, fmt.Errorf("time field %q is not included in the field selection", res.TimeField)
+		}
+	}
+	for _, field := range res.PartitionFields {
+		idx := slices.Index(allFields, field)
+		if idx == -1 {
+			return partitioner{}, fmt.Errorf("partition field %q is not included in the field selection", field)
+		}
+		p.fieldIdx = append(p.fieldIdx, idx)
+	}
+
+	return p, nil
+}
+
+// usesCommitTime is true if the time bucket of the binding is computed from
+// the commit time rather than a field of the row.
+func (p partitioner) usesCommitTime() bool {
+	return p.bucket != timeBucketNone && p.timeIdx == -1
+}
+
+// partition returns the partition of the row. The commit time is used for
+// time buckets if the binding has no time field.
+func (p partitioner) partition(row []any, commitTime time.Time) (string, error) {
+	if p.bucket == timeBucketNone && len(p.fields) == 0 {
+		return "", nil
+	}
+
+	var parts []string
+
+	if p.bucket != timeBucketNone {
+		var ts = commitTime
+		var isNull bool
+		if p.timeIdx != -1 {
+			var err error
+			if ts, isNull, err = parseBucketTime(row[p.timeIdx]); err != nil {
+				return "", err
+			}
+		}
+
+		if isNull {
+			parts = append(parts, "dt="+hiveDefaultPartition)
+			if p.bucket == timeBucketHour {
+				parts = append(parts, "hour="+hiveDefaultPartition)
+			}
+		} else {
+			ts = ts.UTC()
+			parts = append(parts, "dt="+ts.Format(time.DateOnly))
+			if p.bucket == timeBucketHour {
+				parts = append(parts, fmt.Sprintf("hour=%02d", ts.Hour()))
+			}
+		}
+	}
+
+	for i, field := range p.fields {
+		parts = append(parts, escapePartitionPath(field)+"="+partitionValue(row[p.fieldIdx[i]]))
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+func parseBucketTime(v any) (time.Time, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}, true, nil
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return ts, false, nil
+		} else if ts, err := time.Parse(time.DateOnly, v); err == nil {
+			return ts, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("could not parse time bucket value %q as a date-time or date", v)
+	default:
+		return time.Time{}, false, fmt.Errorf("time bucket value has unexpected type %T", v)
+	}
+}
+
+func partitionValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return hiveDefaultPartition
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case bool:
+		s = strconv.FormatBool(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" {
+		return hiveDefaultPartition
+	}
+	return escapePartitionPath(s)
+}
+
+// escapePartitionPath escapes characters of a partition name or value in the
+// same way as Hive, so that the value can be recovered from the path.
+func escapePartitionPath(s string) string {
+	var out strings.Builder
+	for _, c := range []byte(s) {
+		if c < 0x20 || c == 0x7F || strings.IndexByte("\"#%'*/:=?\\{[]^", c) != -1 {
+			fmt.Fprintf(&out, "%%%02X", c)
+		} else {
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// validatePartitioning returns an error if the partitioning of the resource
+// can't be used with the collection.
+func validatePartitioning(res resource, collection pf.CollectionSpec) error {
+	switch res.TimeBucket {
+	case timeBucketNone:
+		if res.TimeField != "" {
+			return fmt.Errorf("timeField %q is set but timeBucket is not", res.TimeField)
+		}
+	case timeBucketDay, timeBucketHour:
+	default:
+		return fmt.Errorf("invalid timeBucket %q", res.TimeBucket)
+	}
+
+	if res.TimeField != "" {
+		p := collection.GetProjection(res.TimeField)
+		if p == nil {
+			return fmt.Errorf("time field %q is not a projection of collection %s", res.TimeField, collection.Name)
+		} else if !slices.Contains(p.Inference.Types, "string") || p.Inference.String_ == nil ||
+			(p.Inference.String_.Format != "date-time" && p.Inference.String_.Format != "date") {
+			return fmt.Errorf("time field %q must be a string with a date-time or date format", res.TimeField)
+		}
+	}
+
+	for idx, field := range res.PartitionFields {
+		p := collection.GetProjection(field)
+		if p == nil {
+			return fmt.Errorf("partition field %q is not a projection of collection %s", field, collection.Name)
+		} else if !p.Inference.IsSingleScalarType() {
+			return fmt.Errorf("partition field %q must have a single scalar type", field)
+		} else if slices.Contains(res.PartitionFields[:idx], field) {
+			return fmt.Errorf("partition field %q is included more than once", field)
+		}
+	}
+
+	return nil
+}
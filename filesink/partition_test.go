@@ -0,0 +1,99 @@
+package filesink
+
+import (
+	"testing"
+	"time"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartition(t *testing.T) {
+	allFields := []string{"key", "region", "created_at", "flow_document"}
+	commitTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		res  resource
+		row  []any
+		want string
+	}{
+		{
+			name: "unpartitioned",
+			res:  resource{},
+			row:  []any{"a", "eu", "2024-01-02T03:04:05Z", []byte("{}")},
+			want: "",
+		},
+		{
+			name: "fields",
+			res:  resource{PartitionFields: []string{"region", "key"}},
+			row:  []any{int64(12), "eu", nil, []byte("{}")},
+			want: "region=eu/key=12",
+		},
+		{
+			name: "escaped and null values",
+			res:  resource{PartitionFields: []string{"region", "key"}},
+			row:  []any{"a/b=c%", nil, nil, []byte("{}")},
+			want: "region=__HIVE_DEFAULT_PARTITION__/key=a%2Fb%3Dc%25",
+		},
+		{
+			name: "commit time day",
+			res:  resource{TimeBucket: timeBucketDay, PartitionFields: []string{"region"}},
+			row:  []any{"a", "eu", nil, []byte("{}")},
+			want: "dt=2024-05-06/region=eu",
+		},
+		{
+			name: "field time hour",
+			res:  resource{TimeBucket: timeBucketHour, TimeField: "created_at"},
+			row:  []any{"a", "eu", "2024-01-02T23:04:05-02:00", []byte("{}")},
+			want: "dt=2024-01-03/hour=01",
+		},
+		{
+			name: "field date",
+			res:  resource{TimeBucket: timeBucketDay, TimeField: "created_at"},
+			row:  []any{"a", "eu", "2024-01-02", []byte("{}")},
+			want: "dt=2024-01-02",
+		},
+		{
+			name: "null field time",
+			res:  resource{TimeBucket: timeBucketHour, TimeField: "created_at"},
+			row:  []any{"a", "eu", nil, []byte("{}")},
+			want: "dt=__HIVE_DEFAULT_PARTITION__/hour=__HIVE_DEFAULT_PARTITION__",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newPartitioner(tt.res, allFields)
+			require.NoError(t, err)
+			got, err := p.partition(tt.row, commitTime)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := newPartitioner(resource{PartitionFields: []string{"missing"}}, allFields)
+	require.Error(t, err)
+}
+
+func TestValidatePartitioning(t *testing.T) {
+	collection := pf.CollectionSpec{
+		Name: "acmeCo/things",
+		Projections: []pf.Projection{
+			{Field: "created_at", Inference: pf.Inference{Types: []string{"string"}, String_: &pf.Inference_String{Format: "date-time"}}},
+			{Field: "nested", Inference: pf.Inference{Types: []string{"object"}}},
+			{Field: "region", Inference: pf.Inference{Types: []string{"string", "null"}}},
+		},
+	}
+
+	require.NoError(t, validatePartitioning(resource{}, collection))
+	require.NoError(t, validatePartitioning(resource{TimeBucket: timeBucketDay}, collection))
+	require.NoError(t, validatePartitioning(resource{TimeBucket: timeBucketHour, TimeField: "created_at", PartitionFields: []string{"region"}}, collection))
+
+	require.ErrorContains(t, validatePartitioning(resource{TimeBucket: "week"}, collection), "invalid timeBucket")
+	require.ErrorContains(t, validatePartitioning(resource{TimeField: "created_at"}, collection), "timeBucket is not")
+	require.ErrorContains(t, validatePartitioning(resource{TimeBucket: timeBucketDay, TimeField: "region"}, collection), "date-time")
+	require.ErrorContains(t, validatePartitioning(resource{PartitionFields: []string{"nested"}}, collection), "scalar")
+	require.ErrorContains(t, validatePartitioning(resource{PartitionFields: []string{"missing"}}, collection), "not a projection")
+	require.ErrorContains(t, validatePartitioning(resource{PartitionFields: []string{"region", "region"}}, collection), "more than once")
+}
@@ -5,15 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	storage "cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
 	"google.golang.org/api/option"
 )
 
@@ -26,23 +32,23 @@ type S3Store struct {
 }
 
 type S3StoreConfig struct {
-	Bucket             string `json:"bucket" jsonschema:"title=Bucket,description=Bucket to store materialized objects." jsonschema_extras:"order=0"`
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=AWS Access Key ID,description=Access Key ID for writing data to the bucket." jsonschema_extras:"order=1"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=AWS Secret Access key,description=Secret Access Key for writing data to the bucket." jsonschema_extras:"secret=true,order=2"`
-	Region             string `json:"region" jsonschema:"title=Region,description=Region of the bucket to write to." jsonschema_extras:"order=3"`
+	Bucket      string                    `json:"bucket" jsonschema:"title=Bucket,description=Bucket to store materialized objects." jsonschema_extras:"order=0"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication" jsonschema_extras:"order=1"`
+	awsauth.AccessKeys
+	Region string `json:"region" jsonschema:"title=Region,description=Region of the bucket to write to." jsonschema_extras:"order=4"`
 
-	UploadInterval string `json:"uploadInterval" jsonschema:"title=Upload Interval,description=Frequency at which files will be uploaded. Must be a valid Go duration string.,enum=5m,enum=15m,enum=30m,enum=1h,default=5m" jsonschema_extras:"order=4"`
-	Prefix         string `json:"prefix,omitempty" jsonschema:"title=Prefix,description=Optional prefix that will be used to store objects." jsonschema_extras:"order=5"`
-	FileSizeLimit  int    `json:"fileSizeLimit,omitempty" jsonschema:"title=File Size Limit,description=Approximate maximum size of materialized files in bytes. Defaults to 10737418240 (10 GiB) if blank." jsonschema_extras:"order=6"`
+	UploadInterval string `json:"uploadInterval" jsonschema:"title=Upload Interval,description=Frequency at which files will be uploaded. Must be a valid Go duration string.,enum=5m,enum=15m,enum=30m,enum=1h,default=5m" jsonschema_extras:"order=5"`
+	Prefix         string `json:"prefix,omitempty" jsonschema:"title=Prefix,description=Optional prefix that will be used to store objects." jsonschema_extras:"order=6"`
+	FileSizeLimit  int    `json:"fileSizeLimit,omitempty" jsonschema:"title=File Size Limit,description=Approximate maximum size of materialized files in bytes. Defaults to 10737418240 (10 GiB) if blank." jsonschema_extras:"order=7"`
 
-	Endpoint string `json:"endpoint,omitempty" jsonschema:"title=Custom S3 Endpoint,description=The S3 endpoint URI to connect to. Use if you're materializing to a compatible API that isn't provided by AWS. Should normally be left blank." jsonschema_extras:"order=7"`
+	Endpoint string `json:"endpoint,omitempty" jsonschema:"title=Custom S3 Endpoint,description=The S3 endpoint URI to connect to. Use if you're materializing to a compatible API that isn't provided by AWS. Should normally be left blank." jsonschema_extras:"order=8"`
+
+	MaxOpenPartitions int `json:"maxOpenPartitions,omitempty" jsonschema:"title=Max Open Partitions,description=Maximum number of files that partitioned bindings may have open at a time. Each open file buffers data in memory. Defaults to 10 if blank." jsonschema_extras:"order=9"`
 }
 
 func (c S3StoreConfig) Validate() error {
 	var requiredProperties = [][]string{
 		{"bucket", c.Bucket},
-		{"awsAccessKeyId", c.AWSAccessKeyID},
-		{"awsSecretAccessKey", c.AWSSecretAccessKey},
 		{"region", c.Region},
 		{"uploadInterval", c.UploadInterval},
 	}
@@ -52,6 +58,10 @@ func (c S3StoreConfig) Validate() error {
 		}
 	}
 
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
+	}
+
 	if _, err := time.ParseDuration(c.UploadInterval); err != nil {
 		return fmt.Errorf("parsing upload interval %q: %w", c.UploadInterval, err)
 	} else if c.Prefix != "" {
@@ -62,14 +72,15 @@ func (c S3StoreConfig) Validate() error {
 		return fmt.Errorf("fileSizeLimit '%d' cannot be negative", c.FileSizeLimit)
 	}
 
+	if c.MaxOpenPartitions < 0 {
+		return fmt.Errorf("maxOpenPartitions '%d' cannot be negative", c.MaxOpenPartitions)
+	}
+
 	return nil
 }
 
 func NewS3Store(ctx context.Context, cfg S3StoreConfig) (*S3Store, error) {
 	opts := []func(*awsConfig.LoadOptions) error{
-		awsConfig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, ""),
-		),
 		awsConfig.WithRegion(cfg.Region),
 	}
 
@@ -81,7 +92,8 @@ func NewS3Store(ctx context.Context, cfg S3StoreConfig) (*S3Store, error) {
 		opts = append(opts, awsConfig.WithEndpointResolverWithOptions(customResolver))
 	}
 
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, opts...)
+	creds := awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey)
+	awsCfg, err := creds.AWSConfig(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating aws config: %w", err)
 	}
@@ -125,6 +137,8 @@ type GCSStoreConfig struct {
 	UploadInterval string `json:"uploadInterval" jsonschema:"title=Upload Interval,description=Frequency at which files will be uploaded. Must be a valid Go duration string.,enum=5m,enum=15m,enum=30m,enum=1h,default=5m" jsonschema_extras:"order=2"`
 	Prefix         string `json:"prefix,omitempty" jsonschema:"title=Prefix,description=Optional prefix that will be used to store objects." jsonschema_extras:"order=3"`
 	FileSizeLimit  int    `json:"fileSizeLimit,omitempty" jsonschema:"title=File Size Limit,description=Approximate maximum size of materialized files in bytes. Defaults to 10737418240 (10 GiB) if blank." jsonschema_extras:"order=4"`
+
+	MaxOpenPartitions int `json:"maxOpenPartitions,omitempty" jsonschema:"title=Max Open Partitions,description=Maximum number of files that partitioned bindings may have open at a time. Each open file buffers data in memory. Defaults to 10 if blank." jsonschema_extras:"order=5"`
 }
 
 func (c GCSStoreConfig) Validate() error {
@@ -151,6 +165,10 @@ func (c GCSStoreConfig) Validate() error {
 		return fmt.Errorf("fileSizeLimit '%d' cannot be negative", c.FileSizeLimit)
 	}
 
+	if c.MaxOpenPartitions < 0 {
+		return fmt.Errorf("maxOpenPartitions '%d' cannot be negative", c.MaxOpenPartitions)
+	}
+
 	return nil
 }
 
@@ -181,3 +199,246 @@ func (s *GCSStore) PutStream(ctx context.Context, r io.Reader, key string) error
 
 	return nil
 }
+
+type AzureBlobStore struct {
+	client    *azblob.Client
+	container string
+}
+
+const (
+	AZURE_SHARED_KEY_AUTH_TYPE        = "SharedKey"
+	AZURE_SAS_AUTH_TYPE               = "SAS"
+	AZURE_SERVICE_PRINCIPAL_AUTH_TYPE = "ServicePrincipal"
+)
+
+type AzureBlobCredentials struct {
+	AuthType string `json:"auth_type"`
+
+	StorageAccountKey string `json:"storageAccountKey,omitempty"`
+
+	SASToken string `json:"sasToken,omitempty"`
+
+	AzureTenantID     string `json:"azureTenantId,omitempty"`
+	AzureClientID     string `json:"azureClientId,omitempty"`
+	AzureClientSecret string `json:"azureClientSecret,omitempty"`
+}
+
+func (c AzureBlobCredentials) Validate() error {
+	var requiredProperties [][]string
+	switch c.AuthType {
+	case AZURE_SHARED_KEY_AUTH_TYPE:
+		requiredProperties = [][]string{{"storageAccountKey", c.StorageAccountKey}}
+	case AZURE_SAS_AUTH_TYPE:
+		requiredProperties = [][]string{{"sasToken", c.SASToken}}
+	case AZURE_SERVICE_PRINCIPAL_AUTH_TYPE:
+		requiredProperties = [][]string{
+			{"azureTenantId", c.AzureTenantID},
+			{"azureClientId", c.AzureClientID},
+			{"azureClientSecret", c.AzureClientSecret},
+		}
+	default:
+		return fmt.Errorf("invalid credentials auth type %q", c.AuthType)
+	}
+
+	for _, req := range requiredProperties {
+		if req[1] == "" {
+			return fmt.Errorf("missing '%s'", req[0])
+		}
+	}
+
+	return nil
+}
+
+// JSONSchema allows for the schema to be (semi-)manually specified when used with the
+// github.com/invopop/jsonschema package in go-schema-gen, to represent the different
+// authentication methods as a oneOf.
+func (AzureBlobCredentials) JSONSchema() *jsonschema.Schema {
+	authType := func(t string) *jsonschema.Schema {
+		return &jsonschema.Schema{Type: "string", Default: t, Const: t}
+	}
+	secret := func(title, description string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Title:       title,
+			Description: description,
+			Type:        "string",
+			Extras:      map[string]interface{}{"secret": true},
+		}
+	}
+
+	sharedKeyProps := orderedmap.New[string, *jsonschema.Schema]()
+	sharedKeyProps.Set("auth_type", authType(AZURE_SHARED_KEY_AUTH_TYPE))
+	sharedKeyProps.Set("storageAccountKey", secret("Storage Account Key", "Access key of the storage account."))
+
+	sasProps := orderedmap.New[string, *jsonschema.Schema]()
+	sasProps.Set("auth_type", authType(AZURE_SAS_AUTH_TYPE))
+	sasProps.Set("sasToken", secret("SAS Token", "Shared access signature token with permission to create and write blobs in the container."))
+
+	spProps := orderedmap.New[string, *jsonschema.Schema]()
+	spProps.Set("auth_type", authType(AZURE_SERVICE_PRINCIPAL_AUTH_TYPE))
+	spProps.Set("azureTenantId", &jsonschema.Schema{
+		Title:       "Azure Tenant ID",
+		Description: "ID of the Azure tenant of the service principal.",
+		Type:        "string",
+	})
+	spProps.Set("azureClientId", &jsonschema.Schema{
+		Title:       "Azure Client ID",
+		Description: "Client ID of the service principal.",
+		Type:        "string",
+	})
+	spProps.Set("azureClientSecret", secret("Azure Client Secret", "Client secret of the service principal."))
+
+	return &jsonschema.Schema{
+		Title:       "Authentication",
+		Description: "Azure Storage Credentials",
+		Default:     map[string]string{"auth_type": AZURE_SHARED_KEY_AUTH_TYPE},
+		OneOf: []*jsonschema.Schema{
+			{
+				Title:      "Shared Key",
+				Required:   []string{"auth_type", "storageAccountKey"},
+				Properties: sharedKeyProps,
+			},
+			{
+				Title:      "Shared Access Signature",
+				Required:   []string{"auth_type", "sasToken"},
+				Properties: sasProps,
+			},
+			{
+				Title:      "Service Principal",
+				Required:   []string{"auth_type", "azureTenantId", "azureClientId", "azureClientSecret"},
+				Properties: spProps,
+			},
+		},
+		Extras: map[string]interface{}{
+			"discriminator": map[string]string{"propertyName": "auth_type"},
+		},
+		Type: "object",
+	}
+}
+
+type AzureBlobStoreConfig struct {
+	StorageAccountName string               `json:"storageAccountName" jsonschema:"title=Storage Account Name,description=Name of the storage account that files will be written to." jsonschema_extras:"order=0"`
+	ContainerName      string               `json:"containerName" jsonschema:"title=Container Name,description=Name of the container to store materialized objects." jsonschema_extras:"order=1"`
+	Credentials        AzureBlobCredentials `json:"credentials" jsonschema:"title=Authentication" jsonschema_extras:"order=2"`
+
+	UploadInterval string `json:"uploadInterval" jsonschema:"title=Upload Interval,description=Frequency at which files will be uploaded. Must be a valid Go duration string.,enum=5m,enum=15m,enum=30m,enum=1h,default=5m" jsonschema_extras:"order=3"`
+	Prefix         string `json:"prefix,omitempty" jsonschema:"title=Prefix,description=Optional prefix that will be used to store objects." jsonschema_extras:"order=4"`
+	FileSizeLimit  int    `json:"fileSizeLimit,omitempty" jsonschema:"title=File Size Limit,description=Approximate maximum size of materialized files in bytes. Defaults to 10737418240 (10 GiB) if blank." jsonschema_extras:"order=5"`
+
+	MaxOpenPartitions int `json:"maxOpenPartitions,omitempty" jsonschema:"title=Max Open Partitions,description=Maximum number of files that partitioned bindings may have open at a time. Each open file buffers data in memory. Defaults to 10 if blank." jsonschema_extras:"order=6"`
+
+	Endpoint string `json:"endpoint,omitempty" jsonschema:"title=Custom Blob Endpoint,description=The blob service endpoint URI to connect to. Use if you're materializing to a compatible API or a cloud other than the Azure public cloud. Defaults to https://<storageAccountName>.blob.core.windows.net/ if blank." jsonschema_extras:"order=7"`
+}
+
+func (c AzureBlobStoreConfig) Validate() error {
+	var requiredProperties = [][]string{
+		{"storageAccountName", c.StorageAccountName},
+		{"containerName", c.ContainerName},
+		{"uploadInterval", c.UploadInterval},
+	}
+	for _, req := range requiredProperties {
+		if req[1] == "" {
+			return fmt.Errorf("missing '%s'", req[0])
+		}
+	}
+
+	if err := c.Credentials.Validate(); err != nil {
+		return fmt.Errorf("credentials: %w", err)
+	} else if _, err := time.ParseDuration(c.UploadInterval); err != nil {
+		return fmt.Errorf("parsing upload interval %q: %w", c.UploadInterval, err)
+	} else if strings.HasPrefix(c.Prefix, "/") {
+		return fmt.Errorf("prefix %q cannot start with /", c.Prefix)
+	} else if c.FileSizeLimit < 0 {
+		return fmt.Errorf("fileSizeLimit '%d' cannot be negative", c.FileSizeLimit)
+	} else if c.MaxOpenPartitions < 0 {
+		return fmt.Errorf("maxOpenPartitions '%d' cannot be negative", c.MaxOpenPartitions)
+	}
+
+	return nil
+}
+
+func NewAzureBlobStore(ctx context.Context, cfg AzureBlobStoreConfig) (*AzureBlobStore, error) {
+	serviceURL := cfg.Endpoint
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.StorageAccountName)
+	}
+
+	var client *azblob.Client
+	var err error
+
+	switch cfg.Credentials.AuthType {
+	case AZURE_SHARED_KEY_AUTH_TYPE:
+		cred, credErr := azblob.NewSharedKeyCredential(cfg.StorageAccountName, cfg.Credentials.StorageAccountKey)
+		if credErr != nil {
+			return nil, fmt.Errorf("creating shared key credential: %w", credErr)
+		}
+		client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	case AZURE_SAS_AUTH_TYPE:
+		serviceURL = strings.TrimSuffix(serviceURL, "/") + "/?" + strings.TrimPrefix(cfg.Credentials.SASToken, "?")
+		client, err = azblob.NewClientWithNoCredential(serviceURL, nil)
+	case AZURE_SERVICE_PRINCIPAL_AUTH_TYPE:
+		cred, credErr := azidentity.NewClientSecretCredential(cfg.Credentials.AzureTenantID, cfg.Credentials.AzureClientID, cfg.Credentials.AzureClientSecret, nil)
+		if credErr != nil {
+			return nil, fmt.Errorf("creating service principal credential: %w", credErr)
+		}
+		client, err = azblob.NewClient(serviceURL, cred, nil)
+	default:
+		return nil, fmt.Errorf("invalid credentials auth type %q", cfg.Credentials.AuthType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating azure blob client: %w", err)
+	}
+
+	return &AzureBlobStore{
+		client:    client,
+		container: cfg.ContainerName,
+	}, nil
+}
+
+// PutStream uploads the stream as a block blob, staging blocks as they are read from r.
+func (s *AzureBlobStore) PutStream(ctx context.Context, r io.Reader, key string) error {
+	_, err := s.client.UploadStream(ctx, s.container, key, r, &azblob.UploadStreamOptions{
+		BlockSize:   4 * 1024 * 1024,
+		Concurrency: 1,
+	})
+	return err
+}
+
+func (s *AzureBlobStore) Client() *azblob.Client {
+	return s.client
+}
+
+// LocalStore writes files to a directory of the local filesystem. It is mostly useful for testing
+// file materializations.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+// PutStream writes the stream to a temporary file which is renamed to the key once it is complete,
+// so that partially written files are never visible at the key.
+func (s *LocalStore) PutStream(ctx context.Context, r io.Reader, key string) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating directory for %q: %w", key, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file for %q: %w", key, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %q: %w", key, err)
+	} else if err := f.Close(); err != nil {
+		return fmt.Errorf("closing %q: %w", key, err)
+	} else if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("renaming %q: %w", key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package filesink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+
+	key := "prefix/path/v0000000000/dt=2024-05-06/00000000000000000000.csv"
+	require.NoError(t, store.PutStream(ctx, strings.NewReader("first"), key))
+	require.NoError(t, store.PutStream(ctx, strings.NewReader("second"), key))
+
+	got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key)))
+	require.NoError(t, err)
+	require.Equal(t, "second", string(got))
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Join(dir, "prefix/path/v0000000000/dt=2024-05-06"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestAzureBlobCredentialsValidate(t *testing.T) {
+	require.NoError(t, AzureBlobCredentials{AuthType: AZURE_SHARED_KEY_AUTH_TYPE, StorageAccountKey: "key"}.Validate())
+	require.NoError(t, AzureBlobCredentials{AuthType: AZURE_SAS_AUTH_TYPE, SASToken: "sv=2022"}.Validate())
+	require.NoError(t, AzureBlobCredentials{
+		AuthType:          AZURE_SERVICE_PRINCIPAL_AUTH_TYPE,
+		AzureTenantID:     "tenant",
+		AzureClientID:     "client",
+		AzureClientSecret: "secret",
+	}.Validate())
+
+	require.ErrorContains(t, AzureBlobCredentials{AuthType: AZURE_SAS_AUTH_TYPE}.Validate(), "sasToken")
+	require.ErrorContains(t, AzureBlobCredentials{AuthType: AZURE_SERVICE_PRINCIPAL_AUTH_TYPE, AzureTenantID: "tenant"}.Validate(), "azureClientId")
+	require.ErrorContains(t, AzureBlobCredentials{AuthType: "other"}.Validate(), "invalid credentials auth type")
+}
@@ -0,0 +1,264 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+const (
+	ACCESS_KEY_AUTH_TYPE    = "AccessKey"    // For static access key credentials
+	ASSUME_ROLE_AUTH_TYPE   = "AssumeRole"   // For assuming an IAM role, optionally with an external ID
+	WEB_IDENTITY_AUTH_TYPE  = "WebIdentity"  // For assuming an IAM role with an OIDC web identity token
+	DEFAULT_CHAIN_AUTH_TYPE = "DefaultChain" // For the default credential chain of the connector's environment
+
+	// defaultRoleSessionName is the session name used when assuming a role if one is not
+	// configured. It shows up in CloudTrail logs of the role's account.
+	defaultRoleSessionName = "estuary-flow-connector"
+
+	// webIdentityTokenFileEnv is the environment variable with the path of the web identity token
+	// file provided by the connector's runtime environment, which is used if a token file is not
+	// configured.
+	webIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+)
+
+type CredentialConfig struct {
+	AuthType string `json:"auth_type"`
+
+	AWSAccessKeyID     string `json:"awsAccessKeyId,omitempty"`
+	AWSSecretAccessKey string `json:"awsSecretAccessKey,omitempty"`
+
+	RoleARN         string `json:"roleArn,omitempty"`
+	ExternalID      string `json:"externalId,omitempty"`
+	RoleSessionName string `json:"roleSessionName,omitempty"`
+
+	TokenFile string `json:"tokenFile,omitempty"`
+}
+
+// AccessKeyCredentials returns a CredentialConfig for static access keys. Connectors use it for
+// endpoint configurations which have access keys as top-level properties, from before they had a
+// credentials configuration.
+func AccessKeyCredentials(accessKeyID, secretAccessKey string) CredentialConfig {
+	return CredentialConfig{
+		AuthType:           ACCESS_KEY_AUTH_TYPE,
+		AWSAccessKeyID:     accessKeyID,
+		AWSSecretAccessKey: secretAccessKey,
+	}
+}
+
+func (c *CredentialConfig) Validate() error {
+	switch c.AuthType {
+	case ACCESS_KEY_AUTH_TYPE:
+		return c.validateAccessKeyCreds()
+	case ASSUME_ROLE_AUTH_TYPE, WEB_IDENTITY_AUTH_TYPE:
+		return c.validateRoleARN()
+	case DEFAULT_CHAIN_AUTH_TYPE:
+		return nil
+	default:
+		return fmt.Errorf("invalid credentials auth type %q", c.AuthType)
+	}
+}
+
+func (c *CredentialConfig) validateAccessKeyCreds() error {
+	if c.AWSAccessKeyID == "" {
+		return fmt.Errorf("missing awsAccessKeyId")
+	} else if c.AWSSecretAccessKey == "" {
+		return fmt.Errorf("missing awsSecretAccessKey")
+	}
+
+	return nil
+}
+
+func (c *CredentialConfig) validateRoleARN() error {
+	if c.RoleARN == "" {
+		return fmt.Errorf("missing role ARN")
+	} else if !strings.HasPrefix(c.RoleARN, "arn:") {
+		return fmt.Errorf("invalid role ARN %q: must start with 'arn:'", c.RoleARN)
+	}
+
+	return nil
+}
+
+// AWSConfig loads an aws.Config with the configured credentials. The optFns are applied when
+// loading the configuration, and should include the region since assuming a role requires one.
+//
+// Roles are assumed using the default credential chain of the connector's environment, and any
+// custom endpoint resolver from optFns is not used for STS requests. The default credential chain is
+// also used as-is for DEFAULT_CHAIN_AUTH_TYPE.
+func (c *CredentialConfig) AWSConfig(ctx context.Context, optFns ...func(*awsConfig.LoadOptions) error) (aws.Config, error) {
+	if err := c.Validate(); err != nil {
+		return aws.Config{}, err
+	}
+
+	opts := append([]func(*awsConfig.LoadOptions) error{}, optFns...)
+	if c.AuthType == ACCESS_KEY_AUTH_TYPE {
+		opts = append(opts, awsConfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.AWSAccessKeyID, c.AWSSecretAccessKey, ""),
+		))
+	}
+
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("loading aws config: %w", err)
+	}
+
+	if c.AuthType != ASSUME_ROLE_AUTH_TYPE && c.AuthType != WEB_IDENTITY_AUTH_TYPE {
+		return cfg, nil
+	}
+
+	stsCfg := cfg.Copy()
+	stsCfg.EndpointResolver = nil
+	stsCfg.EndpointResolverWithOptions = nil
+	stsClient := sts.NewFromConfig(stsCfg)
+
+	sessionName := c.RoleSessionName
+	if sessionName == "" {
+		sessionName = defaultRoleSessionName
+	}
+
+	var provider aws.CredentialsProvider
+	if c.AuthType == ASSUME_ROLE_AUTH_TYPE {
+		provider = stscreds.NewAssumeRoleProvider(stsClient, c.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if c.ExternalID != "" {
+				o.ExternalID = aws.String(c.ExternalID)
+			}
+		})
+	} else {
+		tokenFile := c.TokenFile
+		if tokenFile == "" {
+			tokenFile = os.Getenv(webIdentityTokenFileEnv)
+		}
+		if tokenFile == "" {
+			return aws.Config{}, fmt.Errorf("web identity authentication is not available: tokenFile is not configured and %s is not set in the connector's environment", webIdentityTokenFileEnv)
+		}
+
+		provider = stscreds.NewWebIdentityRoleProvider(
+			stsClient,
+			c.RoleARN,
+			stscreds.IdentityTokenFile(tokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = sessionName
+			},
+		)
+	}
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+
+	return cfg, nil
+}
+
+// JSONSchema allows for the schema to be (semi-)manually specified when used with the
+// github.com/invopop/jsonschema package in go-schema-gen, to fullfill the required schema shape for
+// a discriminated union of credential types.
+func (CredentialConfig) JSONSchema() *jsonschema.Schema {
+	authType := func(t string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Type:    "string",
+			Default: t,
+			Const:   t,
+		}
+	}
+
+	roleArn := func(desc string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Title:       "Role ARN",
+			Description: desc,
+			Type:        "string",
+			Pattern:     "^arn:",
+			Extras:      map[string]interface{}{"order": 1},
+		}
+	}
+
+	roleSessionName := &jsonschema.Schema{
+		Title:       "Role Session Name",
+		Description: "Session name to use when assuming the role. Defaults to '" + defaultRoleSessionName + "' if blank.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 3},
+	}
+
+	accessKeyProps := orderedmap.New[string, *jsonschema.Schema]()
+	accessKeyProps.Set("auth_type", authType(ACCESS_KEY_AUTH_TYPE))
+	accessKeyProps.Set("awsAccessKeyId", &jsonschema.Schema{
+		Title:       "AWS Access Key ID",
+		Description: "Access Key ID of the AWS credentials.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 1},
+	})
+	accessKeyProps.Set("awsSecretAccessKey", &jsonschema.Schema{
+		Title:       "AWS Secret Access Key",
+		Description: "Secret Access Key of the AWS credentials.",
+		Type:        "string",
+		Extras: map[string]interface{}{
+			"secret": true,
+			"order":  2,
+		},
+	})
+
+	assumeRoleProps := orderedmap.New[string, *jsonschema.Schema]()
+	assumeRoleProps.Set("auth_type", authType(ASSUME_ROLE_AUTH_TYPE))
+	assumeRoleProps.Set("roleArn", roleArn("ARN of the IAM role to assume. The role's trust policy must allow the connector's AWS identity to assume it."))
+	assumeRoleProps.Set("externalId", &jsonschema.Schema{
+		Title:       "External ID",
+		Description: "External ID required by the role's trust policy, if any.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 2},
+	})
+	assumeRoleProps.Set("roleSessionName", roleSessionName)
+
+	webIdentityProps := orderedmap.New[string, *jsonschema.Schema]()
+	webIdentityProps.Set("auth_type", authType(WEB_IDENTITY_AUTH_TYPE))
+	webIdentityProps.Set("roleArn", roleArn("ARN of the IAM role to assume with the web identity token."))
+	webIdentityProps.Set("tokenFile", &jsonschema.Schema{
+		Title:       "Token File",
+		Description: "Path to the file containing the OIDC web identity token in the connector's environment. Defaults to the path of the AWS_WEB_IDENTITY_TOKEN_FILE environment variable if blank.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 2},
+	})
+	webIdentityProps.Set("roleSessionName", roleSessionName)
+
+	defaultChainProps := orderedmap.New[string, *jsonschema.Schema]()
+	defaultChainProps.Set("auth_type", authType(DEFAULT_CHAIN_AUTH_TYPE))
+
+	return &jsonschema.Schema{
+		Title:       "Authentication",
+		Description: "AWS Credentials",
+		Default:     map[string]string{"auth_type": ASSUME_ROLE_AUTH_TYPE},
+		OneOf: []*jsonschema.Schema{
+			{
+				Title:      "AWS IAM Role",
+				Required:   []string{"auth_type", "roleArn"},
+				Properties: assumeRoleProps,
+			},
+			{
+				Title:       "AWS Web Identity",
+				Description: "Assume an IAM role with an OIDC web identity token of the connector's environment.",
+				Required:    []string{"auth_type", "roleArn"},
+				Properties:  webIdentityProps,
+			},
+			{
+				Title:      "AWS Access Key",
+				Required:   []string{"auth_type", "awsAccessKeyId", "awsSecretAccessKey"},
+				Properties: accessKeyProps,
+			},
+			{
+				Title:       "Default Credential Chain",
+				Description: "Use the default AWS credential chain of the connector's environment. Only useful for connectors running in your own infrastructure.",
+				Required:    []string{"auth_type"},
+				Properties:  defaultChainProps,
+			},
+		},
+		Extras: map[string]interface{}{
+			"discriminator": map[string]string{"propertyName": "auth_type"},
+		},
+		Type: "object",
+	}
+}
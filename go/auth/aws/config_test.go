@@ -0,0 +1,185 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/bradleyjkemp/cupaloy"
+	schemagen "github.com/estuary/connectors/go/schema-gen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSchema(t *testing.T) {
+	t.Parallel()
+
+	schema := schemagen.GenerateSchema("Test Config Schema", CredentialConfig{})
+	formatted, err := json.MarshalIndent(schema, "", "  ")
+	require.NoError(t, err)
+	cupaloy.SnapshotT(t, formatted)
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		conf CredentialConfig
+		want error
+	}{
+		{
+			name: "valid access key credentials",
+			conf: AccessKeyCredentials("something", "something"),
+			want: nil,
+		},
+		{
+			name: "valid assume role credentials",
+			conf: CredentialConfig{
+				AuthType:   ASSUME_ROLE_AUTH_TYPE,
+				RoleARN:    "arn:aws:iam::123456789012:role/flow",
+				ExternalID: "something",
+			},
+			want: nil,
+		},
+		{
+			name: "valid web identity credentials",
+			conf: CredentialConfig{
+				AuthType: WEB_IDENTITY_AUTH_TYPE,
+				RoleARN:  "arn:aws:iam::123456789012:role/flow",
+			},
+			want: nil,
+		},
+		{
+			name: "valid web identity credentials with token file",
+			conf: CredentialConfig{
+				AuthType:  WEB_IDENTITY_AUTH_TYPE,
+				RoleARN:   "arn:aws:iam::123456789012:role/flow",
+				TokenFile: "/var/run/secrets/token",
+			},
+			want: nil,
+		},
+		{
+			name: "valid default credential chain",
+			conf: CredentialConfig{AuthType: DEFAULT_CHAIN_AUTH_TYPE},
+			want: nil,
+		},
+		{
+			name: "invalid auth type",
+			conf: CredentialConfig{AuthType: "Invalid"},
+			want: fmt.Errorf("invalid credentials auth type %q", "Invalid"),
+		},
+		{
+			name: "missing access key id",
+			conf: AccessKeyCredentials("", "something"),
+			want: fmt.Errorf("missing awsAccessKeyId"),
+		},
+		{
+			name: "missing secret access key",
+			conf: AccessKeyCredentials("something", ""),
+			want: fmt.Errorf("missing awsSecretAccessKey"),
+		},
+		{
+			name: "missing role arn",
+			conf: CredentialConfig{AuthType: ASSUME_ROLE_AUTH_TYPE},
+			want: fmt.Errorf("missing role ARN"),
+		},
+		{
+			name: "invalid role arn",
+			conf: CredentialConfig{AuthType: ASSUME_ROLE_AUTH_TYPE, RoleARN: "flow"},
+			want: fmt.Errorf("invalid role ARN %q: must start with 'arn:'", "flow"),
+		},
+		{
+			name: "missing web identity role arn",
+			conf: CredentialConfig{AuthType: WEB_IDENTITY_AUTH_TYPE},
+			want: fmt.Errorf("missing role ARN"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.conf.Validate()
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAWSConfig(t *testing.T) {
+	ctx := context.Background()
+
+	creds := AccessKeyCredentials("id", "secret")
+	cfg, err := creds.AWSConfig(ctx, awsConfig.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	got, err := cfg.Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "id", got.AccessKeyID)
+	require.Equal(t, "secret", got.SecretAccessKey)
+
+	v1, err := creds.V1Credentials(ctx, awsConfig.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	gotV1, err := v1.Get()
+	require.NoError(t, err)
+	require.Equal(t, "id", gotV1.AccessKeyID)
+	require.Equal(t, "secret", gotV1.SecretAccessKey)
+
+	// Assumed role credentials are cached, and are not retrieved until they are first used.
+	creds = CredentialConfig{AuthType: ASSUME_ROLE_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/flow"}
+	cfg, err = creds.AWSConfig(ctx, awsConfig.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	require.IsType(t, &aws.CredentialsCache{}, cfg.Credentials)
+
+	creds = CredentialConfig{AuthType: ASSUME_ROLE_AUTH_TYPE}
+	_, err = creds.AWSConfig(ctx)
+	require.EqualError(t, err, "missing role ARN")
+
+	// The web identity token file is provided by the connector's environment.
+	creds = CredentialConfig{AuthType: WEB_IDENTITY_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/flow"}
+	t.Setenv(webIdentityTokenFileEnv, "")
+	_, err = creds.AWSConfig(ctx, awsConfig.WithRegion("us-east-1"))
+	require.ErrorContains(t, err, "tokenFile is not configured and AWS_WEB_IDENTITY_TOKEN_FILE is not set")
+
+	// A configured token file is used instead of the one of the environment.
+	creds.TokenFile = "/var/run/secrets/configured"
+	cfg, err = creds.AWSConfig(ctx, awsConfig.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	require.IsType(t, &aws.CredentialsCache{}, cfg.Credentials)
+	creds.TokenFile = ""
+
+	// The SDK's default credential chain also uses the token file, along with the role of the
+	// environment.
+	t.Setenv(webIdentityTokenFileEnv, "/var/run/secrets/token")
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/environment")
+	cfg, err = creds.AWSConfig(ctx, awsConfig.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	require.IsType(t, &aws.CredentialsCache{}, cfg.Credentials)
+
+	// The default credential chain uses the credentials of the connector's environment.
+	t.Setenv(webIdentityTokenFileEnv, "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "environment-id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "environment-secret")
+	creds = CredentialConfig{AuthType: DEFAULT_CHAIN_AUTH_TYPE}
+	cfg, err = creds.AWSConfig(ctx, awsConfig.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	got, err = cfg.Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "environment-id", got.AccessKeyID)
+	require.Equal(t, "environment-secret", got.SecretAccessKey)
+}
+
+func TestLegacyCredentials(t *testing.T) {
+	t.Parallel()
+
+	role := &CredentialConfig{AuthType: ASSUME_ROLE_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/flow"}
+
+	// Configured credentials take precedence over legacy access keys.
+	require.Equal(t, *role, ResolveCredentials(role, "id", "secret"))
+	require.Equal(t, AccessKeyCredentials("id", "secret"), ResolveCredentials(nil, "id", "secret"))
+
+	require.NoError(t, ValidateCredentials(role, "", ""))
+	require.NoError(t, ValidateCredentials(nil, "id", "secret"))
+	require.EqualError(t, ValidateCredentials(&CredentialConfig{AuthType: ASSUME_ROLE_AUTH_TYPE}, "id", "secret"), "invalid credentials: missing role ARN")
+	require.EqualError(t, ValidateCredentials(nil, "", ""), "missing credentials")
+	require.EqualError(t, ValidateCredentials(nil, "id", ""), "missing awsSecretAccessKey")
+}
@@ -0,0 +1,36 @@
+package aws
+
+import "fmt"
+
+// AccessKeys are the top-level access key properties of endpoint configurations from before they
+// had a credentials configuration. Endpoint configurations embed them alongside their credentials
+// so that existing configurations continue to work.
+type AccessKeys struct {
+	AWSAccessKeyID     string `json:"awsAccessKeyId,omitempty" jsonschema:"title=AWS Access Key ID,description=Deprecated: use Authentication instead."`
+	AWSSecretAccessKey string `json:"awsSecretAccessKey,omitempty" jsonschema:"title=AWS Secret Access Key,description=Deprecated: use Authentication instead." jsonschema_extras:"secret=true"`
+}
+
+// ResolveCredentials returns creds if they are configured, or otherwise credentials for the
+// access keys of an endpoint configuration from before credentials were configurable.
+func ResolveCredentials(creds *CredentialConfig, accessKeyID, secretAccessKey string) CredentialConfig {
+	if creds != nil {
+		return *creds
+	}
+	return AccessKeyCredentials(accessKeyID, secretAccessKey)
+}
+
+// ValidateCredentials validates creds if they are configured, or otherwise the access keys of an
+// endpoint configuration from before credentials were configurable.
+func ValidateCredentials(creds *CredentialConfig, accessKeyID, secretAccessKey string) error {
+	if creds != nil {
+		if err := creds.Validate(); err != nil {
+			return fmt.Errorf("invalid credentials: %w", err)
+		}
+		return nil
+	} else if accessKeyID == "" && secretAccessKey == "" {
+		return fmt.Errorf("missing credentials")
+	}
+
+	legacy := AccessKeyCredentials(accessKeyID, secretAccessKey)
+	return legacy.Validate()
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	credentialsV1 "github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// V1Credentials returns credentials for clients of the older aws-sdk-go, which are retrieved using
+// an aws.Config loaded by AWSConfig with the optFns.
+func (c *CredentialConfig) V1Credentials(ctx context.Context, optFns ...func(*awsConfig.LoadOptions) error) (*credentialsV1.Credentials, error) {
+	cfg, err := c.AWSConfig(ctx, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentialsV1.NewCredentials(&v1Provider{provider: cfg.Credentials}), nil
+}
+
+// v1Provider adapts an aws-sdk-go-v2 credentials provider to the aws-sdk-go credentials.Provider
+// interface.
+type v1Provider struct {
+	provider  aws.CredentialsProvider
+	canExpire bool
+	expires   time.Time
+}
+
+var _ credentialsV1.ProviderWithContext = (*v1Provider)(nil)
+
+func (p *v1Provider) Retrieve() (credentialsV1.Value, error) {
+	return p.RetrieveWithContext(context.Background())
+}
+
+func (p *v1Provider) RetrieveWithContext(ctx credentialsV1.Context) (credentialsV1.Value, error) {
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return credentialsV1.Value{}, err
+	}
+
+	p.canExpire = creds.CanExpire
+	p.expires = creds.Expires
+
+	return credentialsV1.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    creds.Source,
+	}, nil
+}
+
+func (p *v1Provider) IsExpired() bool {
+	return p.canExpire && time.Now().After(p.expires)
+}
@@ -0,0 +1,489 @@
+package decoder
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/segmentio/encoding/json"
+)
+
+// confluentMagicByte is the first byte of messages serialized with the
+// Confluent wire format, which is followed by a 4-byte big-endian schema ID and
+// then the Avro binary encoded datum.
+const confluentMagicByte = 0
+
+// avroDecoder decodes binary Avro datums. Datums are decoded with the inline
+// schema if one is provided. Otherwise they must use the Confluent wire format,
+// with schemas fetched from the schema registry by their ID.
+type avroDecoder struct {
+	inline   *avroSchema
+	registry *schemaRegistryClient
+	subject  string
+
+	mu      sync.Mutex
+	schemas map[uint32]*avroSchema
+}
+
+// avroSchema is a codec for decoding Avro datums along with a parsed
+// representation of its schema, which is used for converting decoded datums
+// into JSON and deriving JSON schemas.
+type avroSchema struct {
+	codec *goavro.Codec
+	root  any
+	named map[string]any
+}
+
+func newAvroDecoder(inline string, registry *SchemaRegistryConfig, subject string) (*avroDecoder, error) {
+	d := &avroDecoder{
+		subject: subject,
+		schemas: make(map[uint32]*avroSchema),
+	}
+
+	if inline != "" {
+		s, err := parseAvroSchema(inline)
+		if err != nil {
+			return nil, fmt.Errorf("parsing avro schema: %w", err)
+		}
+		d.inline = s
+	}
+
+	if registry != nil && registry.URL != "" {
+		d.registry = newSchemaRegistryClient(*registry)
+	}
+
+	if d.inline == nil && d.registry == nil {
+		return nil, fmt.Errorf("avro format requires either an avro schema or a schema registry")
+	}
+
+	return d, nil
+}
+
+func (d *avroDecoder) Decode(ctx context.Context, data []byte) (map[string]any, error) {
+	schema := d.inline
+	if schema == nil {
+		if len(data) < 5 || data[0] != confluentMagicByte {
+			return nil, fmt.Errorf("avro message without an inline schema was not encoded with the schema registry wire format")
+		}
+
+		var err error
+		if schema, err = d.registrySchema(ctx, binary.BigEndian.Uint32(data[1:5])); err != nil {
+			return nil, err
+		}
+		data = data[5:]
+	}
+
+	native, _, err := schema.codec.NativeFromBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding avro datum: %w", err)
+	}
+
+	doc, ok := schema.toJSON(schema.root, "", native).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("avro datum must be a record, but was %T", native)
+	}
+
+	return doc, nil
+}
+
+func (d *avroDecoder) Schema(ctx context.Context) (map[string]any, error) {
+	schema := d.inline
+	if schema == nil {
+		raw, err := d.registry.latestSchema(ctx, d.subject)
+		if err != nil {
+			return nil, err
+		} else if raw == "" {
+			// No schema has been registered for the subject, so there is
+			// nothing to derive a schema from.
+			return nil, nil
+		}
+
+		if schema, err = parseAvroSchema(raw); err != nil {
+			return nil, fmt.Errorf("parsing avro schema for subject %q: %w", d.subject, err)
+		}
+	}
+
+	out := schema.jsonSchema(schema.root, "", nil)
+	if out["type"] != "object" {
+		return nil, fmt.Errorf("avro schema must be a record")
+	}
+
+	return out, nil
+}
+
+func (d *avroDecoder) registrySchema(ctx context.Context, id uint32) (*avroSchema, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s, ok := d.schemas[id]; ok {
+		return s, nil
+	}
+
+	raw, err := d.registry.schemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseAvroSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing avro schema with id %d: %w", id, err)
+	}
+	d.schemas[id] = s
+
+	return s, nil
+}
+
+func parseAvroSchema(raw string) (*avroSchema, error) {
+	codec, err := goavro.NewCodec(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var root any
+	if err := json.Unmarshal([]byte(raw), &root); err != nil {
+		return nil, err
+	}
+
+	s := &avroSchema{
+		codec: codec,
+		root:  root,
+		named: make(map[string]any),
+	}
+	s.collectNamed(root, "")
+
+	return s, nil
+}
+
+// collectNamed records the definitions of all named types (records, enums and
+// fixed) so that references to them can be resolved.
+func (s *avroSchema) collectNamed(schema any, namespace string) {
+	switch t := schema.(type) {
+	case []any:
+		for _, member := range t {
+			s.collectNamed(member, namespace)
+		}
+	case map[string]any:
+		switch t["type"] {
+		case "record", "error", "enum", "fixed":
+			fullName, ns := avroFullName(t, namespace)
+			s.named[fullName] = t
+			if fields, ok := t["fields"].([]any); ok {
+				for _, f := range fields {
+					if field, ok := f.(map[string]any); ok {
+						s.collectNamed(field["type"], ns)
+					}
+				}
+			}
+		case "array":
+			s.collectNamed(t["items"], namespace)
+		case "map":
+			s.collectNamed(t["values"], namespace)
+		default:
+			s.collectNamed(t["type"], namespace)
+		}
+	}
+}
+
+// resolve returns the definition for a schema, following references to named
+// types. The returned namespace is the enclosing namespace for nested
+// definitions.
+func (s *avroSchema) resolve(schema any, namespace string) (any, string) {
+	name, ok := schema.(string)
+	if !ok {
+		if m, ok := schema.(map[string]any); ok {
+			if _, isNamed := s.named[nameOf(m, namespace)]; isNamed {
+				_, ns := avroFullName(m, namespace)
+				return m, ns
+			}
+			// A complex type given as e.g. {"type": "string"}, or a reference
+			// to a named type in that form.
+			if ref, ok := m["type"].(string); ok && m["logicalType"] == nil && !isAvroComplexType(ref) {
+				return s.resolve(ref, namespace)
+			}
+		}
+		return schema, namespace
+	}
+
+	for _, candidate := range []string{name, namespace + "." + name} {
+		if def, ok := s.named[candidate]; ok {
+			_, ns := avroFullName(def.(map[string]any), namespace)
+			return def, ns
+		}
+	}
+
+	return schema, namespace
+}
+
+func nameOf(m map[string]any, namespace string) string {
+	if _, ok := m["name"]; !ok {
+		return ""
+	}
+	fullName, _ := avroFullName(m, namespace)
+	return fullName
+}
+
+// avroFullName returns the full name of a named type and the namespace its
+// nested definitions are enclosed in.
+func avroFullName(m map[string]any, namespace string) (string, string) {
+	name, _ := m["name"].(string)
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		return name, name[:idx]
+	}
+	if ns, ok := m["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name, namespace
+	}
+	return namespace + "." + name, namespace
+}
+
+func isAvroComplexType(t string) bool {
+	switch t {
+	case "record", "error", "enum", "fixed", "array", "map":
+		return true
+	default:
+		return false
+	}
+}
+
+// unionMemberName returns the name goavro uses to identify the member of a
+// union in decoded datums.
+func (s *avroSchema) unionMemberName(member any, namespace string) string {
+	def, ns := s.resolve(member, namespace)
+	switch t := def.(type) {
+	case string:
+		return t
+	case map[string]any:
+		if _, ok := t["name"]; ok {
+			fullName, _ := avroFullName(t, ns)
+			return fullName
+		}
+		typ, _ := t["type"].(string)
+		if lt, ok := t["logicalType"].(string); ok {
+			return typ + "." + lt
+		}
+		return typ
+	default:
+		return ""
+	}
+}
+
+// toJSON converts a datum decoded by goavro into a value that can be
+// serialized as JSON, using the schema to unwrap union values.
+func (s *avroSchema) toJSON(schema any, namespace string, native any) any {
+	if native == nil {
+		return nil
+	}
+
+	def, ns := s.resolve(schema, namespace)
+	switch t := def.(type) {
+	case []any:
+		wrapped, ok := native.(map[string]any)
+		if !ok || len(wrapped) != 1 {
+			return avroScalarToJSON(native)
+		}
+		for name, val := range wrapped {
+			for _, member := range t {
+				if s.unionMemberName(member, ns) == name {
+					return s.toJSON(member, ns, val)
+				}
+			}
+			return avroScalarToJSON(val)
+		}
+	case map[string]any:
+		switch t["type"] {
+		case "record", "error":
+			rec, ok := native.(map[string]any)
+			if !ok {
+				return avroScalarToJSON(native)
+			}
+			out := make(map[string]any, len(rec))
+			fields, _ := t["fields"].([]any)
+			for _, f := range fields {
+				field, _ := f.(map[string]any)
+				name, _ := field["name"].(string)
+				if val, ok := rec[name]; ok {
+					out[name] = s.toJSON(field["type"], ns, val)
+				}
+			}
+			return out
+		case "array":
+			arr, ok := native.([]any)
+			if !ok {
+				return avroScalarToJSON(native)
+			}
+			out := make([]any, 0, len(arr))
+			for _, v := range arr {
+				out = append(out, s.toJSON(t["items"], ns, v))
+			}
+			return out
+		case "map":
+			m, ok := native.(map[string]any)
+			if !ok {
+				return avroScalarToJSON(native)
+			}
+			out := make(map[string]any, len(m))
+			for k, v := range m {
+				out[k] = s.toJSON(t["values"], ns, v)
+			}
+			return out
+		case "bytes", "fixed":
+			if r, ok := native.(*big.Rat); ok {
+				scale, _ := t["scale"].(float64)
+				return r.FloatString(int(scale))
+			}
+		case "int":
+			if t["logicalType"] == "date" {
+				if v, ok := native.(time.Time); ok {
+					return v.Format("2006-01-02")
+				}
+			}
+		}
+	}
+
+	return avroScalarToJSON(native)
+}
+
+func avroScalarToJSON(native any) any {
+	switch v := native.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case float32:
+		return floatToJSON(float64(v))
+	case float64:
+		return floatToJSON(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		// Time of day logical types.
+		return time.Time{}.Add(v).Format("15:04:05.999999")
+	case *big.Rat:
+		return v.FloatString(10)
+	default:
+		return v
+	}
+}
+
+func floatToJSON(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	default:
+		return f
+	}
+}
+
+// jsonSchema returns the JSON schema for the JSON representation of datums of
+// the Avro schema. Recursive types are represented as unconstrained schemas
+// past their first occurrence.
+func (s *avroSchema) jsonSchema(schema any, namespace string, seen []string) map[string]any {
+	def, ns := s.resolve(schema, namespace)
+
+	switch t := def.(type) {
+	case string:
+		return avroPrimitiveSchema(t, "")
+	case []any:
+		var nullable bool
+		var members []any
+		for _, member := range t {
+			if s.unionMemberName(member, ns) == "null" {
+				nullable = true
+				continue
+			}
+			members = append(members, s.jsonSchema(member, ns, seen))
+		}
+		if nullable {
+			members = append(members, map[string]any{"type": "null"})
+		}
+		if len(members) == 1 {
+			return members[0].(map[string]any)
+		}
+		return map[string]any{"anyOf": members}
+	case map[string]any:
+		typ, _ := t["type"].(string)
+		lt, _ := t["logicalType"].(string)
+
+		switch typ {
+		case "record", "error":
+			name := nameOf(t, namespace)
+			for _, n := range seen {
+				if n == name {
+					return map[string]any{}
+				}
+			}
+			seen = append(seen, name)
+
+			properties := make(map[string]any)
+			required := []string{}
+			fields, _ := t["fields"].([]any)
+			for _, f := range fields {
+				field, _ := f.(map[string]any)
+				fieldName, _ := field["name"].(string)
+				fieldSchema := s.jsonSchema(field["type"], ns, seen)
+				if doc, ok := field["doc"].(string); ok {
+					fieldSchema["description"] = doc
+				}
+				properties[fieldName] = fieldSchema
+				required = append(required, fieldName)
+			}
+			return ObjectSchema(properties, required)
+		case "enum":
+			return map[string]any{"type": "string", "enum": t["symbols"]}
+		case "fixed":
+			if lt == "decimal" {
+				return avroPrimitiveSchema("bytes", lt)
+			}
+			return avroPrimitiveSchema("bytes", "")
+		case "array":
+			return map[string]any{"type": "array", "items": s.jsonSchema(t["items"], ns, seen)}
+		case "map":
+			return map[string]any{"type": "object", "additionalProperties": s.jsonSchema(t["values"], ns, seen)}
+		default:
+			return avroPrimitiveSchema(typ, lt)
+		}
+	default:
+		return map[string]any{}
+	}
+}
+
+func avroPrimitiveSchema(typ, logicalType string) map[string]any {
+	switch typ + "." + logicalType {
+	case "int.date":
+		return map[string]any{"type": "string", "format": "date"}
+	case "int.time-millis", "long.time-micros":
+		return map[string]any{"type": "string", "format": "time"}
+	case "long.timestamp-millis", "long.timestamp-micros":
+		return map[string]any{"type": "string", "format": "date-time"}
+	case "bytes.decimal":
+		return map[string]any{"type": "string", "format": "number"}
+	}
+
+	switch typ {
+	case "null":
+		return map[string]any{"type": "null"}
+	case "boolean":
+		return map[string]any{"type": "boolean"}
+	case "int", "long":
+		return map[string]any{"type": "integer"}
+	case "float", "double":
+		return map[string]any{"type": []string{"number", "string"}, "format": "number"}
+	case "bytes":
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case "string":
+		return map[string]any{"type": "string"}
+	default:
+		return map[string]any{}
+	}
+}
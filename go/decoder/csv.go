@@ -0,0 +1,55 @@
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+)
+
+// csvDecoder decodes messages that are each a single line of delimited values.
+// All values are decoded as strings, since there is no type information
+// available for them.
+type csvDecoder struct {
+	delimiter rune
+	columns   []string
+}
+
+func newCSVDecoder(delimiter rune, columns []string) *csvDecoder {
+	return &csvDecoder{
+		delimiter: delimiter,
+		columns:   columns,
+	}
+}
+
+func (d *csvDecoder) Decode(_ context.Context, data []byte) (map[string]any, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = d.delimiter
+	r.FieldsPerRecord = -1
+	// Tab-separated values conventionally do not quote fields, and may contain
+	// quote characters anywhere.
+	r.LazyQuotes = d.delimiter == '\t'
+
+	record, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading delimited values: %w", err)
+	} else if len(record) > len(d.columns) {
+		return nil, fmt.Errorf("message had %d values but only %d columns are configured", len(record), len(d.columns))
+	}
+
+	doc := make(map[string]any, len(record))
+	for idx, val := range record {
+		doc[d.columns[idx]] = val
+	}
+
+	return doc, nil
+}
+
+func (d *csvDecoder) Schema(context.Context) (map[string]any, error) {
+	properties := make(map[string]any, len(d.columns))
+	for _, col := range d.columns {
+		properties[col] = map[string]any{"type": "string"}
+	}
+
+	return ObjectSchema(properties, nil), nil
+}
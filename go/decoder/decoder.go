@@ -0,0 +1,152 @@
+// Package decoder converts the raw payloads of messages read from streaming
+// systems like Kinesis or PubSub into JSON documents, and derives JSON schemas
+// for those documents where the payload format carries its own schema.
+package decoder
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// Format is the encoding of message payloads.
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatAvro     Format = "avro"
+	FormatProtobuf Format = "protobuf"
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatRaw      Format = "raw"
+)
+
+// rawDataProperty is the document property that holds the base64 encoded
+// payload of messages read with FormatRaw.
+const rawDataProperty = "data"
+
+// FormatConfig selects the format of message payloads, along with any
+// additional information needed to decode that format.
+type FormatConfig struct {
+	Format             Format   `json:"format,omitempty" jsonschema:"title=Message Format,description=Encoding of message payloads. Defaults to JSON if not set.,enum=json,enum=avro,enum=protobuf,enum=csv,enum=tsv,enum=raw" jsonschema_extras:"order=0"`
+	AvroSchema         string   `json:"avroSchema,omitempty" jsonschema:"title=Avro Schema,description=Avro schema of message payloads. Not required if a schema registry is configured and messages are encoded with the Confluent wire format." jsonschema_extras:"multiline=true,order=1"`
+	ProtobufDescriptor string   `json:"protobufDescriptor,omitempty" jsonschema:"title=Protobuf Descriptor Set,description=Base64 encoded FileDescriptorSet containing the message type and all of its imports. This can be created with 'protoc --include_imports --descriptor_set_out'." jsonschema_extras:"multiline=true,order=2"`
+	ProtobufMessage    string   `json:"protobufMessage,omitempty" jsonschema:"title=Protobuf Message Name,description=Fully qualified name of the Protobuf message type of payloads (e.g. 'my.package.MyMessage')." jsonschema_extras:"order=3"`
+	Columns            []string `json:"columns,omitempty" jsonschema:"title=Columns,description=Names of the columns of CSV or TSV lines in the order they appear." jsonschema_extras:"order=4"`
+}
+
+func (c *FormatConfig) Validate() error {
+	switch c.Format {
+	case "", FormatJSON, FormatRaw:
+	case FormatAvro:
+		// An Avro schema may be provided by a schema registry instead, which
+		// is validated by New.
+	case FormatProtobuf:
+		if c.ProtobufDescriptor == "" {
+			return fmt.Errorf("missing protobufDescriptor for protobuf format")
+		} else if c.ProtobufMessage == "" {
+			return fmt.Errorf("missing protobufMessage for protobuf format")
+		}
+	case FormatCSV, FormatTSV:
+		if len(c.Columns) == 0 {
+			return fmt.Errorf("missing columns for %s format", c.Format)
+		}
+	default:
+		return fmt.Errorf("unknown message format %q", c.Format)
+	}
+
+	return nil
+}
+
+// SchemaRegistryConfig is the connection information for a Confluent-compatible
+// schema registry.
+type SchemaRegistryConfig struct {
+	URL      string `json:"url,omitempty" jsonschema:"title=Schema Registry URL,description=Schema registry API endpoint. For example: https://registry-id.us-east-2.aws.confluent.cloud" jsonschema_extras:"order=0"`
+	Username string `json:"username,omitempty" jsonschema:"title=Username,description=Schema registry username to use for authentication. If you are using Confluent Cloud this will be the 'Key' from your schema registry API key." jsonschema_extras:"order=1"`
+	Password string `json:"password,omitempty" jsonschema:"title=Password,description=Schema registry password to use for authentication. If you are using Confluent Cloud this will be the 'Secret' from your schema registry API key." jsonschema_extras:"secret=true,order=2"`
+}
+
+// Decoder converts message payloads into JSON documents.
+type Decoder interface {
+	// Decode returns the document corresponding to the message payload. The
+	// returned document is always a JSON object.
+	Decode(ctx context.Context, data []byte) (map[string]any, error)
+	// Schema returns the JSON schema for decoded documents, or nil if the
+	// format has no schema to derive one from.
+	Schema(ctx context.Context) (map[string]any, error)
+}
+
+// New creates a Decoder for the configured format. The subject is used to look
+// up the latest schema from the registry for deriving document schemas, and
+// follows the Confluent "TopicNameStrategy" of "<topic>-value". A nil registry
+// means no schema registry is available.
+func New(cfg FormatConfig, registry *SchemaRegistryConfig, subject string) (Decoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Format {
+	case "", FormatJSON:
+		return jsonDecoder{}, nil
+	case FormatRaw:
+		return rawDecoder{}, nil
+	case FormatAvro:
+		return newAvroDecoder(cfg.AvroSchema, registry, subject)
+	case FormatProtobuf:
+		return newProtobufDecoder(cfg.ProtobufDescriptor, cfg.ProtobufMessage)
+	case FormatCSV:
+		return newCSVDecoder(',', cfg.Columns), nil
+	case FormatTSV:
+		return newCSVDecoder('\t', cfg.Columns), nil
+	default:
+		return nil, fmt.Errorf("unknown message format %q", cfg.Format)
+	}
+}
+
+// ObjectSchema returns an object schema with the given properties and required
+// properties.
+func ObjectSchema(properties map[string]any, required []string) map[string]any {
+	out := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+
+	return out
+}
+
+type jsonDecoder struct{}
+
+func (jsonDecoder) Decode(_ context.Context, data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not unmarshal message data as a JSON object: %w", err)
+	} else if doc == nil {
+		return nil, fmt.Errorf("message data was not a JSON object")
+	}
+
+	return doc, nil
+}
+
+func (jsonDecoder) Schema(context.Context) (map[string]any, error) {
+	return nil, nil
+}
+
+type rawDecoder struct{}
+
+func (rawDecoder) Decode(_ context.Context, data []byte) (map[string]any, error) {
+	return map[string]any{rawDataProperty: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+func (rawDecoder) Schema(context.Context) (map[string]any, error) {
+	return ObjectSchema(map[string]any{
+		rawDataProperty: map[string]any{
+			"type":            "string",
+			"contentEncoding": "base64",
+		},
+	}, []string{rawDataProperty}), nil
+}
@@ -0,0 +1,293 @@
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testAvroSchema = `{
+	"type": "record",
+	"name": "Event",
+	"namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": ["null", "string"], "doc": "The name"},
+		{"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "nested", "type": ["null", {"type": "record", "name": "Nested", "fields": [{"name": "value", "type": "double"}]}]},
+		{"name": "other", "type": ["null", "Nested"]}
+	]
+}`
+
+func TestAvroDecoder(t *testing.T) {
+	ctx := context.Background()
+
+	codec, err := goavro.NewCodec(testAvroSchema)
+	require.NoError(t, err)
+
+	datum, err := codec.BinaryFromNative(nil, map[string]any{
+		"id":     int64(42),
+		"name":   goavro.Union("string", "hello"),
+		"ts":     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"kind":   "B",
+		"tags":   []any{"x", "y"},
+		"nested": goavro.Union("com.example.Nested", map[string]any{"value": 1.5}),
+		"other":  nil,
+	})
+	require.NoError(t, err)
+
+	want := map[string]any{
+		"id":     json.Number("42"),
+		"name":   "hello",
+		"ts":     "2024-01-02T03:04:05Z",
+		"kind":   "B",
+		"tags":   []any{"x", "y"},
+		"nested": map[string]any{"value": json.Number("1.5")},
+		"other":  nil,
+	}
+
+	t.Run("inline schema", func(t *testing.T) {
+		dec, err := New(FormatConfig{Format: FormatAvro, AvroSchema: testAvroSchema}, nil, "")
+		require.NoError(t, err)
+
+		doc, err := dec.Decode(ctx, datum)
+		require.NoError(t, err)
+		require.Equal(t, want, roundTrip(t, doc))
+	})
+
+	t.Run("schema registry", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, _ := r.BasicAuth()
+			require.Equal(t, "user", user)
+			require.Equal(t, "pass", pass)
+
+			switch r.URL.Path {
+			case "/schemas/ids/7", "/subjects/events-value/versions/latest":
+				require.NoError(t, json.NewEncoder(w).Encode(registrySchemaResponse{Schema: testAvroSchema}))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+
+		registry := &SchemaRegistryConfig{URL: srv.URL, Username: "user", Password: "pass"}
+		dec, err := New(FormatConfig{Format: FormatAvro}, registry, "events-value")
+		require.NoError(t, err)
+
+		framed := []byte{confluentMagicByte, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(framed[1:], 7)
+		doc, err := dec.Decode(ctx, append(framed, datum...))
+		require.NoError(t, err)
+		require.Equal(t, want, roundTrip(t, doc))
+
+		binary.BigEndian.PutUint32(framed[1:], 8)
+		_, err = dec.Decode(ctx, append(framed, datum...))
+		require.ErrorContains(t, err, "does not exist")
+
+		schema, err := dec.Schema(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, schema)
+
+		missing, err := New(FormatConfig{Format: FormatAvro}, registry, "other-value")
+		require.NoError(t, err)
+		schema, err = missing.Schema(ctx)
+		require.NoError(t, err)
+		require.Nil(t, schema)
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		dec, err := New(FormatConfig{Format: FormatAvro, AvroSchema: testAvroSchema}, nil, "")
+		require.NoError(t, err)
+
+		schema, err := dec.Schema(ctx)
+		require.NoError(t, err)
+
+		nested := map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"value": map[string]any{"type": []any{"number", "string"}, "format": "number"}},
+			"required":   []any{"value"},
+		}
+		require.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id":     map[string]any{"type": "integer"},
+				"name":   map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}, "description": "The name"},
+				"ts":     map[string]any{"type": "string", "format": "date-time"},
+				"kind":   map[string]any{"type": "string", "enum": []any{"A", "B"}},
+				"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"nested": map[string]any{"anyOf": []any{nested, map[string]any{"type": "null"}}},
+				"other":  map[string]any{"anyOf": []any{nested, map[string]any{"type": "null"}}},
+			},
+			"required": []any{"id", "name", "ts", "kind", "tags", "nested", "other"},
+		}, roundTrip(t, schema))
+	})
+
+	t.Run("not a record", func(t *testing.T) {
+		dec, err := New(FormatConfig{Format: FormatAvro, AvroSchema: `"string"`}, nil, "")
+		require.NoError(t, err)
+
+		_, err = dec.Schema(ctx)
+		require.ErrorContains(t, err, "must be a record")
+	})
+
+	t.Run("no schema", func(t *testing.T) {
+		_, err := New(FormatConfig{Format: FormatAvro}, nil, "")
+		require.Error(t, err)
+	})
+}
+
+func TestProtobufDecoder(t *testing.T) {
+	ctx := context.Background()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Thing"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("display_name"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("scores"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()},
+				{Name: proto.String("child"), Number: proto.Int32(4), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".test.Thing"), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}
+	fds := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}}
+	fdsBytes, err := proto.Marshal(fds)
+	require.NoError(t, err)
+
+	cfg := FormatConfig{
+		Format:             FormatProtobuf,
+		ProtobufDescriptor: base64.StdEncoding.EncodeToString(fdsBytes),
+		ProtobufMessage:    "test.Thing",
+	}
+	dec, err := New(cfg, nil, "")
+	require.NoError(t, err)
+
+	fd, err := protodesc.NewFile(file, nil)
+	require.NoError(t, err)
+	desc := fd.Messages().ByName("Thing")
+	msg := dynamicpb.NewMessage(desc)
+	msg.Set(desc.Fields().ByName("id"), protoreflect.ValueOfInt64(12345))
+	msg.Set(desc.Fields().ByName("display_name"), protoreflect.ValueOfString("thing"))
+	scores := msg.Mutable(desc.Fields().ByName("scores")).List()
+	scores.Append(protoreflect.ValueOfInt32(1))
+	scores.Append(protoreflect.ValueOfInt32(2))
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+
+	doc, err := dec.Decode(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"id":           "12345",
+		"display_name": "thing",
+		"scores":       []any{json.Number("1"), json.Number("2")},
+		"child":        nil,
+	}, roundTrip(t, doc))
+
+	schema, err := dec.Schema(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":           map[string]any{"type": "string", "format": "integer"},
+			"display_name": map[string]any{"type": "string"},
+			"scores":       map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+			"child":        map[string]any{"anyOf": []any{map[string]any{}, map[string]any{"type": "null"}}},
+		},
+		"required": []any{"id", "display_name", "scores", "child"},
+	}, roundTrip(t, schema))
+
+	_, err = New(FormatConfig{Format: FormatProtobuf, ProtobufDescriptor: cfg.ProtobufDescriptor, ProtobufMessage: "test.Missing"}, nil, "")
+	require.ErrorContains(t, err, "test.Missing")
+}
+
+func TestCSVDecoder(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		format Format
+		input  string
+		want   map[string]any
+		err    string
+	}{
+		{format: FormatCSV, input: "1,\"hello, world\",x\n", want: map[string]any{"a": "1", "b": "hello, world", "c": "x"}},
+		{format: FormatCSV, input: "1,2", want: map[string]any{"a": "1", "b": "2"}},
+		{format: FormatCSV, input: "1,2,3,4", err: "only 3 columns"},
+		{format: FormatTSV, input: "1\tsay \"hi\"\tx", want: map[string]any{"a": "1", "b": "say \"hi\"", "c": "x"}},
+	} {
+		dec, err := New(FormatConfig{Format: tt.format, Columns: []string{"a", "b", "c"}}, nil, "")
+		require.NoError(t, err)
+
+		doc, err := dec.Decode(ctx, []byte(tt.input))
+		if tt.err != "" {
+			require.ErrorContains(t, err, tt.err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tt.want, doc)
+	}
+
+	_, err := New(FormatConfig{Format: FormatCSV}, nil, "")
+	require.ErrorContains(t, err, "missing columns")
+}
+
+func TestJSONAndRawDecoders(t *testing.T) {
+	ctx := context.Background()
+
+	dec, err := New(FormatConfig{}, nil, "")
+	require.NoError(t, err)
+
+	doc, err := dec.Decode(ctx, []byte(`{"hello":"world"}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"hello": "world"}, doc)
+
+	_, err = dec.Decode(ctx, []byte(`[1, 2]`))
+	require.Error(t, err)
+	_, err = dec.Decode(ctx, []byte(`null`))
+	require.Error(t, err)
+
+	schema, err := dec.Schema(ctx)
+	require.NoError(t, err)
+	require.Nil(t, schema)
+
+	dec, err = New(FormatConfig{Format: FormatRaw}, nil, "")
+	require.NoError(t, err)
+
+	doc, err = dec.Decode(ctx, []byte{0xde, 0xad, 0xbe, 0xef})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"data": "3q2+7w=="}, doc)
+}
+
+// roundTrip serializes and deserializes a value to normalize its types for
+// comparison.
+func roundTrip(t *testing.T, v any) any {
+	t.Helper()
+
+	bs, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	var out any
+	d := json.NewDecoder(bytes.NewReader(bs))
+	d.UseNumber()
+	require.NoError(t, d.Decode(&out))
+
+	return out
+}
@@ -0,0 +1,241 @@
+package decoder
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/segmentio/encoding/json"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufDecoder decodes binary Protobuf messages of a single type described
+// by a user-provided descriptor set. Messages are converted to JSON using the
+// canonical Protobuf JSON mapping with original field names.
+type protobufDecoder struct {
+	desc      protoreflect.MessageDescriptor
+	marshaler protojson.MarshalOptions
+}
+
+func newProtobufDecoder(descriptor, message string) (*protobufDecoder, error) {
+	raw, err := base64.StdEncoding.DecodeString(descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("decoding protobuf descriptor set as base64: %w", err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &fds); err != nil {
+		return nil, fmt.Errorf("parsing protobuf descriptor set: %w", err)
+	}
+
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("building protobuf descriptors: %w", err)
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, fmt.Errorf("finding protobuf message %q: %w", message, err)
+	}
+	desc, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("protobuf descriptor %q is not a message", message)
+	}
+
+	return &protobufDecoder{
+		desc: desc,
+		marshaler: protojson.MarshalOptions{
+			UseProtoNames: true,
+			// Include fields with default values, so that documents always
+			// have every field of the message.
+			EmitUnpopulated: true,
+			Resolver:        dynamicResolver{files},
+		},
+	}, nil
+}
+
+func (d *protobufDecoder) Decode(_ context.Context, data []byte) (map[string]any, error) {
+	msg := dynamicpb.NewMessage(d.desc)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("unmarshalling protobuf message %q: %w", d.desc.FullName(), err)
+	}
+
+	bs, err := d.marshaler.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("converting protobuf message to JSON: %w", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(bs, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (d *protobufDecoder) Schema(context.Context) (map[string]any, error) {
+	return protobufMessageSchema(d.desc, nil), nil
+}
+
+// protobufMessageSchema returns the JSON schema for the JSON mapping of a
+// message. Recursive messages are represented as unconstrained schemas past
+// their first occurrence.
+func protobufMessageSchema(desc protoreflect.MessageDescriptor, seen []protoreflect.FullName) map[string]any {
+	if wkt := protobufWellKnownSchema(desc.FullName()); wkt != nil {
+		return wkt
+	}
+	for _, s := range seen {
+		if s == desc.FullName() {
+			return map[string]any{}
+		}
+	}
+	seen = append(seen, desc.FullName())
+
+	properties := make(map[string]any)
+	required := []string{}
+	fields := desc.Fields()
+	for idx := 0; idx < fields.Len(); idx++ {
+		field := fields.Get(idx)
+		properties[string(field.Name())] = protobufFieldSchema(field, seen)
+
+		// All fields are emitted because of EmitUnpopulated, except for those
+		// that are part of a oneof.
+		if field.ContainingOneof() == nil {
+			required = append(required, string(field.Name()))
+		}
+	}
+
+	return ObjectSchema(properties, required)
+}
+
+func protobufFieldSchema(field protoreflect.FieldDescriptor, seen []protoreflect.FullName) map[string]any {
+	if field.IsMap() {
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": protobufValueSchema(field.MapValue(), seen),
+		}
+	} else if field.IsList() {
+		return map[string]any{
+			"type":  "array",
+			"items": protobufValueSchema(field, seen),
+		}
+	}
+
+	out := protobufValueSchema(field, seen)
+	if field.HasPresence() && field.ContainingOneof() == nil {
+		// Unpopulated message fields and proto2 scalar fields are emitted as
+		// null.
+		return map[string]any{"anyOf": []any{out, map[string]any{"type": "null"}}}
+	}
+
+	return out
+}
+
+func protobufValueSchema(field protoreflect.FieldDescriptor, seen []protoreflect.FullName) map[string]any {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64-bit integers are encoded as strings in the Protobuf JSON mapping.
+		return map[string]any{"type": "string", "format": "integer"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// Non-finite values are encoded as the strings "NaN", "Infinity" and
+		// "-Infinity".
+		return map[string]any{"type": []string{"number", "string"}, "format": "number"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]any, 0, values.Len())
+		for idx := 0; idx < values.Len(); idx++ {
+			names = append(names, string(values.Get(idx).Name()))
+		}
+		// Unknown enum values are encoded as their integer value.
+		return map[string]any{"anyOf": []any{
+			map[string]any{"type": "string", "enum": names},
+			map[string]any{"type": "integer"},
+		}}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protobufMessageSchema(field.Message(), seen)
+	default:
+		return map[string]any{}
+	}
+}
+
+// protobufWellKnownSchema returns the schema for well-known types that have a
+// special representation in the Protobuf JSON mapping, or nil if the message is
+// not one of those.
+func protobufWellKnownSchema(name protoreflect.FullName) map[string]any {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration", "google.protobuf.FieldMask":
+		return map[string]any{"type": "string"}
+	case "google.protobuf.Struct", "google.protobuf.Any", "google.protobuf.Empty":
+		return map[string]any{"type": "object"}
+	case "google.protobuf.ListValue":
+		return map[string]any{"type": "array"}
+	case "google.protobuf.Value":
+		return map[string]any{}
+	case "google.protobuf.BoolValue":
+		return map[string]any{"type": []string{"boolean", "null"}}
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return map[string]any{"type": []string{"integer", "null"}}
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return map[string]any{"type": []string{"string", "null"}, "format": "integer"}
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return map[string]any{"type": []string{"number", "string", "null"}, "format": "number"}
+	case "google.protobuf.StringValue":
+		return map[string]any{"type": []string{"string", "null"}}
+	case "google.protobuf.BytesValue":
+		return map[string]any{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+	default:
+		return nil
+	}
+}
+
+// dynamicResolver resolves message types for google.protobuf.Any values from
+// the provided descriptor set, falling back to the global registry.
+type dynamicResolver struct {
+	files *protoregistry.Files
+}
+
+func (r dynamicResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		if md, ok := d.(protoreflect.MessageDescriptor); ok {
+			return dynamicpb.NewMessageType(md), nil
+		}
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r dynamicResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	for idx := len(url) - 1; idx >= 0; idx-- {
+		if url[idx] == '/' {
+			name = url[idx+1:]
+			break
+		}
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r dynamicResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r dynamicResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
@@ -0,0 +1,102 @@
+package decoder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// schemaRegistryClient fetches schemas from a Confluent-compatible schema
+// registry.
+type schemaRegistryClient struct {
+	cfg  SchemaRegistryConfig
+	http *http.Client
+}
+
+func newSchemaRegistryClient(cfg SchemaRegistryConfig) *schemaRegistryClient {
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+
+	return &schemaRegistryClient{
+		cfg:  cfg,
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type registrySchemaResponse struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+// schemaByID fetches the schema with the given ID.
+func (c *schemaRegistryClient) schemaByID(ctx context.Context, id uint32) (string, error) {
+	var res registrySchemaResponse
+	if found, err := c.get(ctx, fmt.Sprintf("/schemas/ids/%d", id), &res); err != nil {
+		return "", fmt.Errorf("fetching schema with id %d: %w", id, err)
+	} else if !found {
+		return "", fmt.Errorf("schema with id %d does not exist in the schema registry", id)
+	} else if err := checkAvroSchemaType(res.SchemaType); err != nil {
+		return "", fmt.Errorf("schema with id %d: %w", id, err)
+	}
+
+	return res.Schema, nil
+}
+
+// latestSchema fetches the latest version of the schema for a subject. An
+// empty string is returned if the subject does not exist.
+func (c *schemaRegistryClient) latestSchema(ctx context.Context, subject string) (string, error) {
+	var res registrySchemaResponse
+	if found, err := c.get(ctx, fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)), &res); err != nil {
+		return "", fmt.Errorf("fetching latest schema for subject %q: %w", subject, err)
+	} else if !found {
+		return "", nil
+	} else if err := checkAvroSchemaType(res.SchemaType); err != nil {
+		return "", fmt.Errorf("latest schema for subject %q: %w", subject, err)
+	}
+
+	return res.Schema, nil
+}
+
+func (c *schemaRegistryClient) get(ctx context.Context, path string, out any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL+path, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if c.cfg.Username != "" || c.cfg.Password != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	} else if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return false, fmt.Errorf("schema registry responded with status %q: %s", res.Status, string(body))
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("decoding schema registry response: %w", err)
+	}
+
+	return true, nil
+}
+
+func checkAvroSchemaType(schemaType string) error {
+	// The registry omits the schema type for Avro schemas.
+	if schemaType != "" && schemaType != "AVRO" {
+		return fmt.Errorf("schema type %q is not supported, only AVRO schemas can be read from a schema registry", schemaType)
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+// Package payloadtemplate renders JSON request payloads from the fields of
+// materialized documents using Go text/template templates.
+//
+// Templates are executed with a map of each selected field name to its value.
+// Fields with names that are valid Go identifiers may be accessed directly as
+// {{ .field }}, and any field may be accessed as {{ index . "my-field" }}.
+// Templates also have these functions available:
+//
+//   - json: Encodes a value as JSON, for example {{ json .message }}.
+//   - formatTime: Formats an RFC3339 timestamp string with a named format
+//     (RFC3339, RFC3339Nano, RFC1123, DateOnly, DateTime, Unix, UnixMilli) or a
+//     Go time layout, for example {{ formatTime "DateOnly" .created_at }}.
+//   - now: The current UTC time as an RFC3339 timestamp string.
+//   - pointer: Resolves a JSON pointer within a value, for example
+//     {{ pointer "/customer/name" .flow_document }}.
+//   - default: Returns its first argument if the second is null or empty, for
+//     example {{ default "unknown" .region }}.
+package payloadtemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+)
+
+// Template is a parsed payload template.
+type Template struct {
+	tmpl   *template.Template
+	fields []string
+}
+
+// Parse parses the text of a template.
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("payload").Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	var fields []string
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walkFields(t.Tree.Root, true, &fields)
+		}
+	}
+	slices.Sort(fields)
+
+	return &Template{tmpl: tmpl, fields: slices.Compact(fields)}, nil
+}
+
+// Fields returns the names of the fields the template references directly.
+// Fields accessed in ways that can't be determined without executing the
+// template are not included.
+func (t *Template) Fields() []string {
+	return t.fields
+}
+
+// Validate returns an error if the template references a field that is not
+// one of the projections.
+func (t *Template) Validate(projections []pf.Projection) error {
+	for _, field := range t.fields {
+		if !slices.ContainsFunc(projections, func(p pf.Projection) bool { return p.Field == field }) {
+			return fmt.Errorf("template references field %q which is not a projection of the collection", field)
+		}
+	}
+	return nil
+}
+
+// Render executes the template with the provided fields and returns the
+// result, which must be valid JSON, in compact form.
+func (t *Template) Render(data map[string]any) (json.RawMessage, error) {
+	var out bytes.Buffer
+	if err := t.tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, out.Bytes()); err != nil {
+		return nil, fmt.Errorf("template did not render valid JSON: %w (rendered: %q)", err, truncate(out.String(), 256))
+	}
+
+	return compacted.Bytes(), nil
+}
+
+// Data builds the data for rendering a template from field names and their
+// values. Values which are raw JSON are decoded so that templates may access
+// their contents.
+func Data(fields []string, values []any) (map[string]any, error) {
+	if len(fields) != len(values) {
+		return nil, fmt.Errorf("got %d fields but %d values", len(fields), len(values))
+	}
+
+	var data = make(map[string]any, len(fields))
+	for idx, field := range fields {
+		var raw []byte
+		switch v := values[idx].(type) {
+		case json.RawMessage:
+			raw = v
+		case []byte:
+			raw = v
+		default:
+			data[field] = v
+			continue
+		}
+
+		var decoded any
+		var dec = json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("decoding value of field %q: %w", field, err)
+		}
+		data[field] = decoded
+	}
+
+	return data, nil
+}
+
+// walkFields collects the fields referenced by node into fields. Fields are
+// only collected from the root of the template data, so rootDot tracks
+// whether dot is still the root or has been changed by a range or with.
+func walkFields(node parse.Node, rootDot bool, fields *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkFields(child, rootDot, fields)
+		}
+	case *parse.ActionNode:
+		walkFields(n.Pipe, rootDot, fields)
+	case *parse.IfNode:
+		walkFields(n.Pipe, rootDot, fields)
+		walkFields(n.List, rootDot, fields)
+		walkFields(n.ElseList, rootDot, fields)
+	case *parse.RangeNode:
+		walkFields(n.Pipe, rootDot, fields)
+		walkFields(n.List, false, fields)
+		walkFields(n.ElseList, rootDot, fields)
+	case *parse.WithNode:
+		walkFields(n.Pipe, rootDot, fields)
+		walkFields(n.List, false, fields)
+		walkFields(n.ElseList, rootDot, fields)
+	case *parse.TemplateNode:
+		walkFields(n.Pipe, rootDot, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkFields(cmd, rootDot, fields)
+		}
+	case *parse.CommandNode:
+		// {{ index . "field" }} and {{ index $ "field" }}
+		if len(n.Args) >= 3 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" && isRoot(n.Args[1], rootDot) {
+				if s, ok := n.Args[2].(*parse.StringNode); ok {
+					*fields = append(*fields, s.Text)
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			walkFields(arg, rootDot, fields)
+		}
+	case *parse.FieldNode:
+		if rootDot {
+			*fields = append(*fields, n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			*fields = append(*fields, n.Ident[1])
+		}
+	case *parse.ChainNode:
+		walkFields(n.Node, rootDot, fields)
+	}
+}
+
+func isRoot(node parse.Node, rootDot bool) bool {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return rootDot
+	case *parse.VariableNode:
+		return len(n.Ident) == 1 && n.Ident[0] == "$"
+	}
+	return false
+}
+
+var funcs = template.FuncMap{
+	"json":       toJSON,
+	"formatTime": formatTime,
+	"now":        func() string { return time.Now().UTC().Format(time.RFC3339Nano) },
+	"pointer":    pointer,
+	"default":    defaultValue,
+}
+
+func toJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	var enc = json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+var namedLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"DateOnly":    time.DateOnly,
+	"DateTime":    time.DateTime,
+}
+
+func formatTime(layout string, v any) (string, error) {
+	var ts time.Time
+	switch v := v.(type) {
+	case time.Time:
+		ts = v
+	case string:
+		var err error
+		if ts, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return "", fmt.Errorf("formatTime: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("formatTime: expected an RFC3339 timestamp string but got %T", v)
+	}
+
+	switch layout {
+	case "Unix":
+		return strconv.FormatInt(ts.Unix(), 10), nil
+	case "UnixMilli":
+		return strconv.FormatInt(ts.UnixMilli(), 10), nil
+	}
+	if named, ok := namedLayouts[layout]; ok {
+		layout = named
+	}
+	return ts.Format(layout), nil
+}
+
+// pointer resolves a JSON pointer within a decoded JSON value. The result is
+// nil if the location doesn't exist.
+func pointer(ptr string, v any) (any, error) {
+	if ptr == "" {
+		return v, nil
+	} else if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("pointer: %q must begin with '/'", ptr)
+	}
+
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch vv := v.(type) {
+		case map[string]any:
+			v = vv[token]
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(vv) {
+				return nil, nil
+			}
+			v = vv[idx]
+		default:
+			return nil, nil
+		}
+	}
+
+	return v, nil
+}
+
+func defaultValue(def any, v any) any {
+	switch vv := v.(type) {
+	case nil:
+		return def
+	case string:
+		if vv == "" {
+			return def
+		}
+	case []any:
+		if len(vv) == 0 {
+			return def
+		}
+	case map[string]any:
+		if len(vv) == 0 {
+			return def
+		}
+	}
+	return v
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
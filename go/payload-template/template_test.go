@@ -0,0 +1,82 @@
+package payloadtemplate
+
+import (
+	"encoding/json"
+	"testing"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFields(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"plain", `{"a": {{ json .a }}, "b": {{ json .b.nested }}}`, []string{"a", "b"}},
+		{"index", `{"a": {{ index . "my-field" | json }}, "b": {{ json (index $ "other") }}}`, []string{"my-field", "other"}},
+		{"range", `[{{ range $i, $v := .items }}{{ if $i }},{{ end }}{{ json .name }}{{ json $.c }}{{ end }}]`, []string{"c", "items"}},
+		{"with", `{{ with .doc }}{{ json .inner }}{{ else }}{{ json .fallback }}{{ end }}`, []string{"doc", "fallback"}},
+		{"funcs", `{{ formatTime "DateOnly" .ts | json }}{{ pointer "/x" .doc | default .d | json }}`, []string{"d", "doc", "ts"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.text)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, tmpl.Fields())
+		})
+	}
+
+	_, err := Parse(`{{ .a `)
+	require.Error(t, err)
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tmpl, err := Parse(`{"summary": {{ json .summary }}, "id": {{ json .id }}}`)
+	require.NoError(t, err)
+
+	require.NoError(t, tmpl.Validate([]pf.Projection{{Field: "id"}, {Field: "summary"}, {Field: "flow_document"}}))
+	require.ErrorContains(t, tmpl.Validate([]pf.Projection{{Field: "id"}}), `"summary"`)
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl, err := Parse(`{
+		"summary": {{ printf "Order %v failed" .id | json }},
+		"severity": {{ default "warning" .severity | json }},
+		"day": {{ formatTime "DateOnly" .ts | json }},
+		"millis": {{ formatTime "UnixMilli" .ts }},
+		"customer": {{ pointer "/customer/name" .flow_document | json }},
+		"first_item": {{ pointer "/items/0" .flow_document | json }},
+		"quoted": {{ json .note }}
+	}`)
+	require.NoError(t, err)
+
+	data, err := Data(
+		[]string{"id", "severity", "ts", "note", "flow_document"},
+		[]any{int64(42), nil, "2024-05-06T07:08:09.5Z", `say "hi" <now>`, json.RawMessage(`{"customer":{"name":"Ann"},"items":[12345678901234567890]}`)},
+	)
+	require.NoError(t, err)
+
+	out, err := tmpl.Render(data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"summary": "Order 42 failed",
+		"severity": "warning",
+		"day": "2024-05-06",
+		"millis": 1714979289500,
+		"customer": "Ann",
+		"first_item": 12345678901234567890,
+		"quoted": "say \"hi\" <now>"
+	}`, string(out))
+
+	// Missing fields are an error.
+	delete(data, "note")
+	_, err = tmpl.Render(data)
+	require.ErrorContains(t, err, "note")
+
+	// So is output that isn't JSON.
+	tmpl, err = Parse(`{"a": {{ .a }}}`)
+	require.NoError(t, err)
+	_, err = tmpl.Render(map[string]any{"a": "not quoted"})
+	require.ErrorContains(t, err, "did not render valid JSON")
+}
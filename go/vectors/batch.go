@@ -0,0 +1,37 @@
+package vectors
+
+import "sync"
+
+// adaptiveBatchSize is the number of documents to include in each embeddings
+// request. It is shared by the concurrent store workers, is halved each time
+// the embedding provider rate limits a request, and grows back towards its
+// maximum as requests succeed.
+type adaptiveBatchSize struct {
+	mu   sync.Mutex
+	size int
+	max  int
+}
+
+func newAdaptiveBatchSize(maxSize int) *adaptiveBatchSize {
+	return &adaptiveBatchSize{size: maxSize, max: maxSize}
+}
+
+func (a *adaptiveBatchSize) get() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.size
+}
+
+// rateLimited reduces the batch size and returns the new size.
+func (a *adaptiveBatchSize) rateLimited() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.size = max(1, a.size/2)
+	return a.size
+}
+
+func (a *adaptiveBatchSize) succeeded() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.size = min(a.max, a.size+max(1, a.max/10))
+}
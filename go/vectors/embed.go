@@ -0,0 +1,91 @@
+package vectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/estuary/connectors/go/vectors/embeddings"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	maxRateLimitedAttempts = 10
+	rateLimitedBackoff     = time.Second
+	maxRateLimitedBackoff  = time.Minute
+)
+
+// BatchEmbedder creates embeddings for batches of inputs of any size, in
+// requests with an adaptive number of inputs which is reduced when the
+// embedding provider rate limits requests.
+type BatchEmbedder struct {
+	embedder  embeddings.Embedder
+	batchSize *adaptiveBatchSize
+}
+
+// NewBatchEmbedder returns a BatchEmbedder which sends at most maxBatchSize
+// inputs per request, or the provider maximum if it is less.
+func NewBatchEmbedder(embedder embeddings.Embedder, maxBatchSize int) *BatchEmbedder {
+	return &BatchEmbedder{
+		embedder:  embedder,
+		batchSize: newAdaptiveBatchSize(min(maxBatchSize, embedder.MaxBatchSize())),
+	}
+}
+
+// Embed returns an embedding for each input, in the same order as the inputs.
+func (e *BatchEmbedder) Embed(ctx context.Context, input []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(input))
+
+	attempt := 0
+	for len(input) > 0 {
+		n := min(len(input), e.batchSize.get())
+		created, err := e.embedder.CreateEmbeddings(ctx, input[:n])
+
+		var rateLimitErr *embeddings.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			attempt++
+			if attempt > maxRateLimitedAttempts {
+				return nil, err
+			}
+
+			delay := rateLimitErr.RetryAfter
+			if delay == 0 {
+				delay = min(rateLimitedBackoff<<(attempt-1), maxRateLimitedBackoff)
+			}
+
+			log.WithFields(log.Fields{
+				"attempt":   attempt,
+				"batchSize": e.batchSize.rateLimited(),
+				"delay":     delay.String(),
+			}).Info("embedding provider rate limited request, reducing batch size")
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+				continue
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		attempt = 0
+		e.batchSize.succeeded()
+		out = append(out, created...)
+		input = input[n:]
+	}
+
+	return out, nil
+}
+
+// VerifyEmbedder creates an embedding to verify access to the embedding
+// provider, and returns the dimensions of its vectors.
+func VerifyEmbedder(ctx context.Context, embedder embeddings.Embedder) (int, error) {
+	vecs, err := embedder.CreateEmbeddings(ctx, []string{"Estuary Flow materialization validation"})
+	if err != nil {
+		return 0, fmt.Errorf("verifying embedding provider: %w", err)
+	}
+
+	return len(vecs[0]), nil
+}
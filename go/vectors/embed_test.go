@@ -0,0 +1,62 @@
+package vectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/estuary/connectors/go/vectors/embeddings"
+	"github.com/stretchr/testify/require"
+)
+
+// rateLimitingEmbedder rate limits requests with more than limit inputs.
+type rateLimitingEmbedder struct {
+	limit    int
+	requests []int
+}
+
+func (e *rateLimitingEmbedder) MaxBatchSize() int { return 100 }
+
+func (e *rateLimitingEmbedder) CreateEmbeddings(ctx context.Context, input []string) ([][]float32, error) {
+	e.requests = append(e.requests, len(input))
+	if len(input) > e.limit {
+		return nil, &embeddings.RateLimitError{Provider: "test"}
+	}
+
+	var out [][]float32
+	for _, in := range input {
+		out = append(out, []float32{float32(len(in))})
+	}
+	return out, nil
+}
+
+func TestBatchEmbedderAdaptiveBatchSize(t *testing.T) {
+	defer func(d time.Duration) { rateLimitedBackoff = d }(rateLimitedBackoff)
+	rateLimitedBackoff = time.Millisecond
+
+	embedder := &rateLimitingEmbedder{limit: 30}
+	e := NewBatchEmbedder(embedder, 100)
+
+	var input []string
+	for idx := 0; idx < 60; idx++ {
+		input = append(input, string(make([]byte, idx)))
+	}
+
+	got, err := e.Embed(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, got, len(input))
+	for idx, e := range got {
+		require.Equal(t, []float32{float32(idx)}, e)
+	}
+
+	// The batch size is halved for each rate limited request, and grows by a
+	// tenth of its maximum after each successful one.
+	require.Equal(t, []int{60, 50, 25, 35, 17, 18}, embedder.requests)
+	require.Equal(t, 37, e.batchSize.get())
+
+	// Requests which are always rate limited eventually fail.
+	embedder.limit = 0
+	_, err = e.Embed(context.Background(), input)
+	require.ErrorContains(t, err, "test rate limited the request")
+	require.Equal(t, 1, e.batchSize.get())
+}
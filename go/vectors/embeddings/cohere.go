@@ -0,0 +1,68 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type cohereEmbedRequest struct {
+	Model          string   `json:"model"`
+	Texts          []string `json:"texts"`
+	InputType      string   `json:"input_type"`
+	EmbeddingTypes []string `json:"embedding_types"`
+}
+
+type cohereEmbedResponse struct {
+	Embeddings struct {
+		Float [][]float32 `json:"float"`
+	} `json:"embeddings"`
+}
+
+// CohereClient creates embeddings with the Cohere embed API.
+type CohereClient struct {
+	http           *http.Client
+	embeddingModel string
+	apiKey         string
+}
+
+var _ Embedder = (*CohereClient)(nil)
+
+func NewCohereClient(embeddingModel string, apiKey string) *CohereClient {
+	return &CohereClient{
+		http:           http.DefaultClient,
+		embeddingModel: embeddingModel,
+		apiKey:         apiKey,
+	}
+}
+
+// The Cohere embed API accepts at most 96 texts per request.
+func (c *CohereClient) MaxBatchSize() int { return 96 }
+
+func (c *CohereClient) CreateEmbeddings(ctx context.Context, input []string) ([][]float32, error) {
+	var res cohereEmbedResponse
+	if err := postEmbeddingsRequest(
+		ctx,
+		c.http,
+		"Cohere",
+		"https://api.cohere.com/v2/embed",
+		map[string]string{"Authorization": fmt.Sprintf("Bearer %s", c.apiKey)},
+		&cohereEmbedRequest{
+			Model: c.embeddingModel,
+			Texts: input,
+			// Materialized documents are stored for retrieval by later
+			// queries.
+			InputType:      "search_document",
+			EmbeddingTypes: []string{"float"},
+		},
+		&res,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := checkEmbeddingsCount("Cohere", input, res.Embeddings.Float); err != nil {
+		return nil, err
+	}
+
+	return res.Embeddings.Float, nil
+}
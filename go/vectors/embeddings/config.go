@@ -0,0 +1,242 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+const (
+	providerOpenAi           = "openai"
+	providerAzureOpenAi      = "azure_openai"
+	providerCohere           = "cohere"
+	providerVertexAi         = "vertex_ai"
+	providerOpenAiCompatible = "openai_compatible"
+	// No embeddings are created, and every binding must provide its own
+	// vectors from a field of its documents.
+	providerNone = "none"
+
+	DefaultOpenAiModel = "text-embedding-ada-002"
+
+	defaultAzureApiVersion = "2024-02-01"
+	defaultCohereModel     = "embed-english-v3.0"
+	defaultVertexAiModel   = "text-embedding-004"
+)
+
+// ProviderConfig is the configuration of an embedding provider, for inclusion
+// in the endpoint configuration of a materialization.
+type ProviderConfig struct {
+	Provider string `json:"provider"`
+
+	ApiKey       string `json:"apiKey,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Organization string `json:"organization,omitempty"`
+
+	ResourceName string `json:"resourceName,omitempty"`
+	DeploymentID string `json:"deploymentId,omitempty"`
+	ApiVersion   string `json:"apiVersion,omitempty"`
+
+	ProjectID       string `json:"projectId,omitempty"`
+	Region          string `json:"region,omitempty"`
+	CredentialsJSON string `json:"credentialsJson,omitempty"`
+
+	BaseURL string `json:"baseUrl,omitempty"`
+}
+
+func (c *ProviderConfig) Validate() error {
+	var requiredProperties [][]string
+	switch c.Provider {
+	case providerOpenAi, providerCohere:
+		requiredProperties = [][]string{{"apiKey", c.ApiKey}}
+	case providerAzureOpenAi:
+		requiredProperties = [][]string{
+			{"apiKey", c.ApiKey},
+			{"resourceName", c.ResourceName},
+			{"deploymentId", c.DeploymentID},
+		}
+	case providerVertexAi:
+		requiredProperties = [][]string{
+			{"projectId", c.ProjectID},
+			{"region", c.Region},
+			{"credentialsJson", c.CredentialsJSON},
+		}
+		if c.CredentialsJSON != "" && !json.Valid([]byte(c.CredentialsJSON)) {
+			return fmt.Errorf("embedding provider credentialsJson must be valid JSON")
+		}
+	case providerOpenAiCompatible:
+		requiredProperties = [][]string{
+			{"baseUrl", c.BaseURL},
+			{"model", c.Model},
+		}
+	case providerNone:
+	default:
+		return fmt.Errorf("invalid embedding provider %q", c.Provider)
+	}
+
+	for _, req := range requiredProperties {
+		if req[1] == "" {
+			return fmt.Errorf("embedding provider %q missing required property '%s'", c.Provider, req[0])
+		}
+	}
+
+	return nil
+}
+
+// Embedder returns the client for the configured provider, which is nil if
+// the provider is "none".
+func (c *ProviderConfig) Embedder(ctx context.Context) (Embedder, error) {
+	model := func(defaultModel string) string {
+		if c.Model != "" {
+			return c.Model
+		}
+		return defaultModel
+	}
+
+	switch c.Provider {
+	case providerOpenAi:
+		return NewOpenAiClient(model(DefaultOpenAiModel), c.Organization, c.ApiKey), nil
+	case providerAzureOpenAi:
+		apiVersion := defaultAzureApiVersion
+		if c.ApiVersion != "" {
+			apiVersion = c.ApiVersion
+		}
+		return NewAzureOpenAiClient(c.ResourceName, c.DeploymentID, apiVersion, c.ApiKey), nil
+	case providerCohere:
+		return NewCohereClient(model(defaultCohereModel), c.ApiKey), nil
+	case providerVertexAi:
+		return NewVertexAiClient(ctx, c.ProjectID, c.Region, model(defaultVertexAiModel), c.CredentialsJSON)
+	case providerOpenAiCompatible:
+		return NewOpenAiCompatibleClient(c.BaseURL, c.Model, c.ApiKey), nil
+	case providerNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid embedding provider %q", c.Provider)
+	}
+}
+
+func (ProviderConfig) JSONSchema() *jsonschema.Schema {
+	providerSchema := func(provider string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Type:    "string",
+			Default: provider,
+			Const:   provider,
+		}
+	}
+	stringSchema := func(title, description string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Title:       title,
+			Description: description,
+			Type:        "string",
+		}
+	}
+	secretSchema := func(title, description string) *jsonschema.Schema {
+		s := stringSchema(title, description)
+		s.Extras = map[string]interface{}{"secret": true}
+		return s
+	}
+	modelSchema := func(description, defaultModel string) *jsonschema.Schema {
+		s := stringSchema("Embedding Model ID", description)
+		if defaultModel != "" {
+			s.Default = defaultModel
+		}
+		return s
+	}
+
+	openAiProps := orderedmap.New[string, *jsonschema.Schema]()
+	openAiProps.Set("provider", providerSchema(providerOpenAi))
+	openAiProps.Set("apiKey", secretSchema("OpenAI API Key", "OpenAI API key used for authentication."))
+	openAiProps.Set("model", modelSchema("OpenAI embedding model ID.", DefaultOpenAiModel))
+	openAiProps.Set("organization", stringSchema(
+		"OpenAI Organization",
+		"Optional organization name for OpenAI requests. Use this if you belong to multiple organizations to specify which organization is used for API requests.",
+	))
+
+	azureProps := orderedmap.New[string, *jsonschema.Schema]()
+	azureProps.Set("provider", providerSchema(providerAzureOpenAi))
+	azureProps.Set("apiKey", secretSchema("API Key", "API key of the Azure OpenAI resource."))
+	azureProps.Set("resourceName", stringSchema("Resource Name", "Name of the Azure OpenAI resource."))
+	azureProps.Set("deploymentId", stringSchema("Deployment ID", "Name of the deployment of an embedding model in the Azure OpenAI resource."))
+	azureProps.Set("apiVersion", &jsonschema.Schema{
+		Title:       "API Version",
+		Description: "Azure OpenAI API version to use for requests.",
+		Type:        "string",
+		Default:     defaultAzureApiVersion,
+	})
+
+	cohereProps := orderedmap.New[string, *jsonschema.Schema]()
+	cohereProps.Set("provider", providerSchema(providerCohere))
+	cohereProps.Set("apiKey", secretSchema("Cohere API Key", "Cohere API key used for authentication."))
+	cohereProps.Set("model", modelSchema("Cohere embedding model ID.", defaultCohereModel))
+
+	vertexProps := orderedmap.New[string, *jsonschema.Schema]()
+	vertexProps.Set("provider", providerSchema(providerVertexAi))
+	vertexProps.Set("projectId", stringSchema("Project ID", "Google Cloud project ID to use for Vertex AI requests."))
+	vertexProps.Set("region", stringSchema("Region", "Google Cloud region of the Vertex AI endpoint. Example: us-central1"))
+	vertexProps.Set("credentialsJson", &jsonschema.Schema{
+		Title:       "Service Account JSON",
+		Description: "The JSON key of a Google Cloud service account which is permitted to use Vertex AI models.",
+		Type:        "string",
+		Extras: map[string]interface{}{
+			"secret":    true,
+			"multiline": true,
+		},
+	})
+	vertexProps.Set("model", modelSchema("Vertex AI text embedding model ID.", defaultVertexAiModel))
+
+	compatibleProps := orderedmap.New[string, *jsonschema.Schema]()
+	compatibleProps.Set("provider", providerSchema(providerOpenAiCompatible))
+	compatibleProps.Set("baseUrl", stringSchema(
+		"Base URL",
+		"Base URL of an endpoint implementing the OpenAI embeddings API. Requests are sent to the /embeddings path of this URL. Example: http://localhost:8080/v1",
+	))
+	compatibleProps.Set("model", modelSchema("Embedding model ID to include in requests.", ""))
+	compatibleProps.Set("apiKey", secretSchema("API Key", "Optional API key sent as a bearer token."))
+
+	noneProps := orderedmap.New[string, *jsonschema.Schema]()
+	noneProps.Set("provider", providerSchema(providerNone))
+
+	return &jsonschema.Schema{
+		Title:       "Embedding Provider",
+		Description: "Provider of the embedding model used to create vectors from documents.",
+		OneOf: []*jsonschema.Schema{
+			{
+				Title:      "OpenAI",
+				Required:   []string{"provider", "apiKey"},
+				Properties: openAiProps,
+			},
+			{
+				Title:      "Azure OpenAI",
+				Required:   []string{"provider", "apiKey", "resourceName", "deploymentId"},
+				Properties: azureProps,
+			},
+			{
+				Title:      "Cohere",
+				Required:   []string{"provider", "apiKey"},
+				Properties: cohereProps,
+			},
+			{
+				Title:      "Google Vertex AI",
+				Required:   []string{"provider", "projectId", "region", "credentialsJson"},
+				Properties: vertexProps,
+			},
+			{
+				Title:      "OpenAI-Compatible Endpoint",
+				Required:   []string{"provider", "baseUrl", "model"},
+				Properties: compatibleProps,
+			},
+			{
+				Title:       "None",
+				Description: "Vectors are provided by a field of the documents of each binding.",
+				Required:    []string{"provider"},
+				Properties:  noneProps,
+			},
+		},
+		Extras: map[string]interface{}{
+			"discriminator": map[string]string{"propertyName": "provider"},
+		},
+		Type: "object",
+	}
+}
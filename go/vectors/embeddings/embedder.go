@@ -0,0 +1,125 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Embedder creates vector embeddings for text inputs.
+type Embedder interface {
+	// CreateEmbeddings returns an embedding for each input, in the same order
+	// as the inputs. A *RateLimitError is returned if the provider rate limited
+	// the request, in which case it may be retried later or with fewer inputs.
+	CreateEmbeddings(ctx context.Context, input []string) ([][]float32, error)
+	// MaxBatchSize is the maximum number of inputs the provider accepts in a
+	// single request.
+	MaxBatchSize() int
+}
+
+// RateLimitError is returned by an Embedder when the embedding provider
+// responds to a request with HTTP 429 Too Many Requests.
+type RateLimitError struct {
+	Provider string
+	// RetryAfter is the delay requested by the provider before sending
+	// another request, if it provided one.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s rate limited the request (retry after %s)", e.Provider, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s rate limited the request", e.Provider)
+}
+
+// embeddingsErrorBody covers the shapes of error response bodies from the
+// supported embedding providers, which all include a message in one place or
+// the other.
+type embeddingsErrorBody struct {
+	Message string `json:"message"`
+	Error   struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// postEmbeddingsRequest sends a JSON request body to an embeddings endpoint
+// and decodes the response body into out.
+func postEmbeddingsRequest(
+	ctx context.Context,
+	httpClient *http.Client,
+	provider string,
+	url string,
+	headers map[string]string,
+	in any,
+	out any,
+) error {
+	res, err := withRetry(ctx, func() (*http.Response, error) {
+		body := new(bytes.Buffer)
+		if err := json.NewEncoder(body).Encode(in); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		return httpClient.Do(req)
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		rateLimitErr := &RateLimitError{Provider: provider}
+		if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs > 0 {
+			rateLimitErr.RetryAfter = time.Duration(secs) * time.Second
+		}
+		return rateLimitErr
+	} else if res.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+		var parsed embeddingsErrorBody
+		if err := json.Unmarshal(errBody, &parsed); err == nil && parsed.Error.Message != "" {
+			return fmt.Errorf("%s creating embeddings failed (%s): %s", provider, res.Status, parsed.Error.Message)
+		} else if err == nil && parsed.Message != "" {
+			return fmt.Errorf("%s creating embeddings failed (%s): %s", provider, res.Status, parsed.Message)
+		}
+
+		return fmt.Errorf("%s creating embeddings unexpected status: %s: %s", provider, res.Status, string(errBody))
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s embeddings response: %w", provider, err)
+	}
+
+	return nil
+}
+
+// checkEmbeddingsCount verifies that a provider returned an embedding for each
+// input.
+func checkEmbeddingsCount(provider string, input []string, embeddings [][]float32) error {
+	if len(embeddings) != len(input) {
+		return fmt.Errorf("%s returned %d embeddings for %d inputs", provider, len(embeddings), len(input))
+	}
+
+	for idx, e := range embeddings {
+		if len(e) == 0 {
+			return fmt.Errorf("%s returned an empty embedding for input %d", provider, idx)
+		}
+	}
+
+	return nil
+}
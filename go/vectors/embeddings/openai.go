@@ -0,0 +1,124 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type OpenAIEmbeddingsRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type OpenAIEmbeddingsResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  usage       `json:"usage"`
+}
+
+type Embedding struct {
+	Object    string    `json:"object"`
+	Embedding []float32 `json:"embedding"`
+	Index     int       `json:"index"`
+}
+
+type usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// OpenAiClient creates embeddings with the OpenAI embeddings API, or with
+// another service implementing the same API such as Azure OpenAI.
+type OpenAiClient struct {
+	http           *http.Client
+	provider       string
+	url            string
+	headers        map[string]string
+	embeddingModel string
+	maxBatchSize   int
+}
+
+var _ Embedder = (*OpenAiClient)(nil)
+
+func NewOpenAiClient(embeddingModel string, org string, apiKey string) *OpenAiClient {
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", apiKey)}
+	if org != "" {
+		headers["OpenAI-Organization"] = org
+	}
+
+	return &OpenAiClient{
+		http:           http.DefaultClient,
+		provider:       "OpenAI",
+		url:            "https://api.openai.com/v1/embeddings",
+		headers:        headers,
+		embeddingModel: embeddingModel,
+		maxBatchSize:   2048,
+	}
+}
+
+// NewAzureOpenAiClient returns a client for an embedding model deployment of
+// an Azure OpenAI resource. The model is determined by the deployment.
+func NewAzureOpenAiClient(resourceName string, deploymentID string, apiVersion string, apiKey string) *OpenAiClient {
+	return &OpenAiClient{
+		http:     http.DefaultClient,
+		provider: "Azure OpenAI",
+		url: fmt.Sprintf(
+			"https://%s.openai.azure.com/openai/deployments/%s/embeddings?api-version=%s",
+			resourceName,
+			url.PathEscape(deploymentID),
+			url.QueryEscape(apiVersion),
+		),
+		headers:      map[string]string{"api-key": apiKey},
+		maxBatchSize: 2048,
+	}
+}
+
+// NewOpenAiCompatibleClient returns a client for an HTTP endpoint which
+// implements the OpenAI embeddings API, such as a self-hosted embedding
+// server. The API key is optional.
+func NewOpenAiCompatibleClient(baseURL string, embeddingModel string, apiKey string) *OpenAiClient {
+	headers := make(map[string]string)
+	if apiKey != "" {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", apiKey)
+	}
+
+	return &OpenAiClient{
+		http:           http.DefaultClient,
+		provider:       "OpenAI-compatible endpoint",
+		url:            strings.TrimSuffix(baseURL, "/") + "/embeddings",
+		headers:        headers,
+		embeddingModel: embeddingModel,
+		maxBatchSize:   2048,
+	}
+}
+
+func (c *OpenAiClient) MaxBatchSize() int { return c.maxBatchSize }
+
+func (c *OpenAiClient) CreateEmbeddings(ctx context.Context, input []string) ([][]float32, error) {
+	var res OpenAIEmbeddingsResponse
+	if err := postEmbeddingsRequest(ctx, c.http, c.provider, c.url, c.headers, &OpenAIEmbeddingsRequest{
+		Model: c.embeddingModel,
+		Input: input,
+	}, &res); err != nil {
+		return nil, err
+	}
+
+	// Embeddings are not necessarily returned in the order of their inputs.
+	out := make([][]float32, len(input))
+	for _, e := range res.Data {
+		if e.Index < 0 || e.Index >= len(input) {
+			return nil, fmt.Errorf("%s returned an embedding for input %d of %d", c.provider, e.Index, len(input))
+		}
+		out[e.Index] = e.Embedding
+	}
+
+	if err := checkEmbeddingsCount(c.provider, input, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
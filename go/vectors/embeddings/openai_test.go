@@ -0,0 +1,65 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAiCompatibleClient(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/embeddings", r.URL.Path)
+		gotAuth = r.Header.Get("Authorization")
+
+		var req OpenAIEmbeddingsRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "local-model", req.Model)
+
+		if len(req.Input) > 2 {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		} else if req.Input[0] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"message": "input is bad"}}`))
+			return
+		}
+
+		// Respond with embeddings in reverse order of the inputs.
+		var res OpenAIEmbeddingsResponse
+		for idx := len(req.Input) - 1; idx >= 0; idx-- {
+			res.Data = append(res.Data, Embedding{
+				Embedding: []float32{float32(idx), float32(len(req.Input[idx]))},
+				Index:     idx,
+			})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	defer srv.Close()
+
+	c := NewOpenAiCompatibleClient(srv.URL+"/v1/", "local-model", "")
+
+	got, err := c.CreateEmbeddings(context.Background(), []string{"a", "bbb"})
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{0, 1}, {1, 3}}, got)
+	require.Equal(t, "", gotAuth)
+
+	_, err = c.CreateEmbeddings(context.Background(), []string{"a", "b", "c"})
+	var rateLimitErr *RateLimitError
+	require.True(t, errors.As(err, &rateLimitErr))
+	require.Equal(t, 3*time.Second, rateLimitErr.RetryAfter)
+
+	_, err = c.CreateEmbeddings(context.Background(), []string{"bad"})
+	require.ErrorContains(t, err, "input is bad")
+
+	_, err = NewOpenAiCompatibleClient(srv.URL+"/v1", "local-model", "secret").CreateEmbeddings(context.Background(), []string{"a"})
+	require.NoError(t, err)
+	require.Equal(t, "Bearer secret", gotAuth)
+}
@@ -0,0 +1,84 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+type vertexPredictRequest struct {
+	Instances []vertexInstance `json:"instances"`
+}
+
+type vertexInstance struct {
+	Content  string `json:"content"`
+	TaskType string `json:"task_type"`
+}
+
+type vertexPredictResponse struct {
+	Predictions []struct {
+		Embeddings struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	} `json:"predictions"`
+}
+
+// VertexAiClient creates embeddings with a Google Vertex AI text embeddings
+// model.
+type VertexAiClient struct {
+	http *http.Client
+	url  string
+}
+
+var _ Embedder = (*VertexAiClient)(nil)
+
+// NewVertexAiClient returns a client which authenticates with the provided
+// Google Cloud service account credentials JSON.
+func NewVertexAiClient(ctx context.Context, projectID string, region string, embeddingModel string, credentialsJSON string) (*VertexAiClient, error) {
+	creds, err := google.CredentialsFromJSON(ctx, []byte(credentialsJSON), "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, fmt.Errorf("parsing Google Cloud credentials: %w", err)
+	}
+
+	return &VertexAiClient{
+		// The client is used for the lifetime of the connector, so it must not
+		// be tied to the context of a single request.
+		http: oauth2.NewClient(context.Background(), creds.TokenSource),
+		url: fmt.Sprintf(
+			"https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:predict",
+			region,
+			projectID,
+			region,
+			embeddingModel,
+		),
+	}, nil
+}
+
+// Vertex AI text embedding models accept at most 250 instances per request.
+func (c *VertexAiClient) MaxBatchSize() int { return 250 }
+
+func (c *VertexAiClient) CreateEmbeddings(ctx context.Context, input []string) ([][]float32, error) {
+	req := vertexPredictRequest{Instances: make([]vertexInstance, 0, len(input))}
+	for _, in := range input {
+		req.Instances = append(req.Instances, vertexInstance{Content: in, TaskType: "RETRIEVAL_DOCUMENT"})
+	}
+
+	var res vertexPredictResponse
+	if err := postEmbeddingsRequest(ctx, c.http, "Vertex AI", c.url, nil, &req, &res); err != nil {
+		return nil, err
+	}
+
+	out := make([][]float32, 0, len(res.Predictions))
+	for _, p := range res.Predictions {
+		out = append(out, p.Embeddings.Values)
+	}
+
+	if err := checkEmbeddingsCount("Vertex AI", input, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
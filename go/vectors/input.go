@@ -0,0 +1,63 @@
+package vectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MakeInput creates the embedding input for a document from the values of its
+// selected fields. The embedding input is an aggregate string of all included
+// keys and values of the materialization. It is arranged as "key: value" pairs
+// on newlines with the value being the stringified JSON-encoded of the field
+// value.
+func MakeInput(fields map[string]interface{}) (string, error) {
+	var out strings.Builder
+
+	count := 0
+	for k, v := range fields {
+		if c, ok := v.([]byte); ok {
+			// We get JSON arrays and objects as raw bytes of their encoded JSON. Rather than
+			// encoding that as a base64-string, we want to handle these bytes as precomputed JSON.
+			v = json.RawMessage(c)
+		}
+
+		m, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("serializing input field: %w", err)
+		}
+		out.WriteString(k + ": " + string(m))
+
+		count++
+		if count < len(fields) {
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String(), nil
+}
+
+// ParseVector parses a precomputed vector from the value of a field, which is
+// the JSON encoding of an array.
+func ParseVector(val interface{}, dimension int) ([]float32, error) {
+	var raw []byte
+	switch v := val.(type) {
+	case []byte:
+		raw = v
+	case json.RawMessage:
+		raw = v
+	case nil:
+		return nil, fmt.Errorf("vector is missing")
+	default:
+		return nil, fmt.Errorf("vector must be an array of numbers but was %T", val)
+	}
+
+	var vector []float32
+	if err := json.Unmarshal(raw, &vector); err != nil {
+		return nil, fmt.Errorf("vector must be an array of numbers: %w", err)
+	} else if len(vector) != dimension {
+		return nil, fmt.Errorf("vector has %d dimensions but must have %d", len(vector), dimension)
+	}
+
+	return vector, nil
+}
@@ -0,0 +1,40 @@
+package vectors
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeInput(t *testing.T) {
+	got, err := MakeInput(map[string]interface{}{"nested": []byte(`{"a":1}`)})
+	require.NoError(t, err)
+	require.Equal(t, `nested: {"a":1}`, got)
+
+	got, err = MakeInput(map[string]interface{}{"str": "hello"})
+	require.NoError(t, err)
+	require.Equal(t, `str: "hello"`, got)
+}
+
+func TestParseVector(t *testing.T) {
+	got, err := ParseVector([]byte(`[0.5, 1, -2]`), 3)
+	require.NoError(t, err)
+	require.Equal(t, []float32{0.5, 1, -2}, got)
+
+	got, err = ParseVector(json.RawMessage(`[1, 2]`), 2)
+	require.NoError(t, err)
+	require.Equal(t, []float32{1, 2}, got)
+
+	_, err = ParseVector([]byte(`[1, 2]`), 3)
+	require.EqualError(t, err, "vector has 2 dimensions but must have 3")
+
+	_, err = ParseVector([]byte(`["a"]`), 1)
+	require.ErrorContains(t, err, "vector must be an array of numbers")
+
+	_, err = ParseVector(nil, 1)
+	require.EqualError(t, err, "vector is missing")
+
+	_, err = ParseVector("[1]", 1)
+	require.EqualError(t, err, "vector must be an array of numbers but was string")
+}
@@ -0,0 +1,205 @@
+package vectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	m "github.com/estuary/connectors/go/protocols/materialize"
+	"github.com/estuary/flow/go/protocols/fdb/tuple"
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"golang.org/x/sync/errgroup"
+)
+
+// TODO(whb): These may need tuning based on real-world use.
+var (
+	BatchSize         = 100
+	concurrentWorkers = 5
+)
+
+// Transactor is a delta updates transactor which creates vectors for stored
+// documents and upserts them into a Store. The vectors of deleted documents
+// are deleted if hard deletes are enabled, and otherwise the deletion
+// documents are upserted like any other document.
+type Transactor struct {
+	store Store
+	// embedder is nil if every binding provides its own vectors.
+	embedder   *BatchEmbedder
+	bindings   []Binding
+	hardDelete bool
+
+	group    *errgroup.Group
+	groupCtx context.Context
+}
+
+var _ m.Transactor = (*Transactor)(nil)
+
+func NewTransactor(store Store, embedder *BatchEmbedder, bindings []Binding, hardDelete bool) *Transactor {
+	return &Transactor{
+		store:      store,
+		embedder:   embedder,
+		bindings:   bindings,
+		hardDelete: hardDelete,
+	}
+}
+
+type upsertDoc struct {
+	input  string
+	record Record
+}
+
+func (t *Transactor) UnmarshalState(state json.RawMessage) error                  { return nil }
+func (t *Transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error) { return nil, nil }
+
+func (t *Transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage) error) error {
+	for it.Next() {
+		panic("driver only supports delta updates")
+	}
+	return nil
+}
+
+func (t *Transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
+	ctx := it.Context()
+
+	t.group, t.groupCtx = errgroup.WithContext(ctx)
+	t.group.SetLimit(concurrentWorkers)
+
+	var batch []upsertDoc
+	var deletes []string
+
+	sendBatches := func(binding int) error {
+		if len(batch) != 0 {
+			if err := t.sendUpserts(binding, batch); err != nil {
+				return fmt.Errorf("sending batch of documents: %w", err)
+			}
+			batch = nil
+		}
+		if len(deletes) != 0 {
+			if err := t.sendDeletes(binding, deletes); err != nil {
+				return fmt.Errorf("sending batch of deletions: %w", err)
+			}
+			deletes = nil
+		}
+		return nil
+	}
+
+	lastBinding := -1
+	for it.Next() {
+		if lastBinding != -1 && it.Binding != lastBinding {
+			if err := sendBatches(lastBinding); err != nil {
+				return nil, err
+			}
+		}
+		lastBinding = it.Binding
+
+		b := t.bindings[it.Binding]
+		id := fmt.Sprintf("%x", it.PackedKey)
+
+		if it.Delete && t.hardDelete {
+			deletes = append(deletes, id)
+		} else {
+			doc, err := t.makeUpsertDoc(b, id, append(it.Key, it.Values...), it.RawJSON)
+			if err != nil {
+				return nil, err
+			}
+			batch = append(batch, doc)
+		}
+
+		if len(batch) >= BatchSize || len(deletes) >= BatchSize {
+			if err := sendBatches(it.Binding); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if lastBinding != -1 {
+		if err := sendBatches(lastBinding); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, t.group.Wait()
+}
+
+func (t *Transactor) makeUpsertDoc(b Binding, id string, values tuple.Tuple, doc json.RawMessage) (upsertDoc, error) {
+	out := upsertDoc{record: Record{
+		ID:       id,
+		Fields:   make(map[string]interface{}),
+		Document: doc,
+	}}
+
+	for idx, val := range values {
+		if idx == b.vectorIdx {
+			vector, err := ParseVector(val, b.Dimension)
+			if err != nil {
+				return upsertDoc{}, fmt.Errorf("field %q: %w", b.Fields[idx], err)
+			}
+			out.record.Vector = vector
+		} else if val != nil {
+			out.record.Fields[b.Fields[idx]] = val
+		}
+	}
+
+	if b.vectorIdx == -1 {
+		var err error
+		if out.input, err = MakeInput(out.record.Fields); err != nil {
+			return upsertDoc{}, err
+		}
+	}
+
+	return out, nil
+}
+
+func (t *Transactor) sendUpserts(binding int, batch []upsertDoc) error {
+	b := t.bindings[binding]
+
+	select {
+	case <-t.groupCtx.Done():
+		return t.group.Wait()
+	default:
+		t.group.Go(func() error {
+			if b.vectorIdx == -1 {
+				input := make([]string, 0, len(batch))
+				for _, d := range batch {
+					input = append(input, d.input)
+				}
+
+				created, err := t.embedder.Embed(t.groupCtx, input)
+				if err != nil {
+					return fmt.Errorf("creating embeddings: %w", err)
+				}
+
+				for idx, e := range created {
+					if len(e) != b.Dimension {
+						return fmt.Errorf("embedding has %d dimensions but must have %d", len(e), b.Dimension)
+					}
+					batch[idx].record.Vector = e
+				}
+			}
+
+			records := make([]Record, 0, len(batch))
+			for _, d := range batch {
+				records = append(records, d.record)
+			}
+
+			return t.store.Upsert(t.groupCtx, binding, records)
+		})
+	}
+
+	return nil
+}
+
+func (t *Transactor) sendDeletes(binding int, ids []string) error {
+	select {
+	case <-t.groupCtx.Done():
+		return t.group.Wait()
+	default:
+		t.group.Go(func() error {
+			return t.store.Delete(t.groupCtx, binding, ids)
+		})
+	}
+
+	return nil
+}
+
+func (t *Transactor) Destroy() {}
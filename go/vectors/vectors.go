@@ -0,0 +1,120 @@
+// Package vectors implements the common parts of materializations to vector
+// databases: creating a text input from the selected fields of each document,
+// creating an embedding of it, and upserting it along with metadata into the
+// destination, or deleting it when its source document is deleted.
+package vectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+)
+
+// FlowDocumentField is the metadata field of the materialized document.
+const FlowDocumentField = "flow_document"
+
+// Record is a vector to upsert into a destination.
+type Record struct {
+	// ID is the hex-encoded packed key of the document.
+	ID     string
+	Vector []float32
+	// Fields are the values of the selected fields of the document, excluding
+	// the vector field of bindings with precomputed vectors.
+	Fields map[string]interface{}
+	// Document is the full JSON document.
+	Document json.RawMessage
+}
+
+// Store is a vector database destination.
+type Store interface {
+	// Upsert inserts or replaces the records of a binding.
+	Upsert(ctx context.Context, binding int, records []Record) error
+	// Delete removes the records of a binding with the given IDs, if they
+	// exist.
+	Delete(ctx context.Context, binding int, ids []string) error
+}
+
+// Binding describes the documents of a binding of a Transactor.
+type Binding struct {
+	// Fields are the selected fields of the binding, in the order of keys and
+	// values provided by the store iterator.
+	Fields []string
+	// Dimension is the number of dimensions of the binding's vectors.
+	Dimension int
+	// vectorIdx is the index of the vector field in Fields, or -1 if vectors
+	// are created by the embedding provider.
+	vectorIdx int
+}
+
+// NewBinding returns a binding for a materialization binding spec, which
+// takes its vectors from vectorField if it is set.
+func NewBinding(spec *pf.MaterializationSpec_Binding, vectorField string, dimension int) (Binding, error) {
+	fields := spec.FieldSelection.AllFields()
+
+	vectorIdx := -1
+	if vectorField != "" {
+		if vectorIdx = slices.Index(fields, vectorField); vectorIdx == -1 {
+			return Binding{}, fmt.Errorf("vector field %q is not included in the field selection", vectorField)
+		}
+	}
+
+	return Binding{
+		Fields:    fields,
+		Dimension: dimension,
+		vectorIdx: vectorIdx,
+	}, nil
+}
+
+// ValidateBinding checks the vector field of a binding, which is required if
+// there is no embedding provider and must be a required array otherwise, and
+// returns the constraints for its projections.
+func ValidateBinding(
+	vectorField string,
+	hasEmbedder bool,
+	projections []pf.Projection,
+) (map[string]*pm.Response_Validated_Constraint, error) {
+	if vectorField == "" && !hasEmbedder {
+		return nil, fmt.Errorf("a vectorField must be set since no embedding provider is configured")
+	} else if vectorField != "" {
+		idx := slices.IndexFunc(projections, func(p pf.Projection) bool { return p.Field == vectorField })
+		if idx == -1 {
+			return nil, fmt.Errorf("vector field %q does not exist in the collection", vectorField)
+		} else if p := projections[idx]; !slices.Equal(p.Inference.Types, []string{"array"}) || p.Inference.Exists != pf.Inference_MUST {
+			return nil, fmt.Errorf("vector field %q must be a required array of numbers but has types %v", vectorField, p.Inference.Types)
+		}
+	}
+
+	constraints := make(map[string]*pm.Response_Validated_Constraint)
+	for _, projection := range projections {
+
+		var constraint = new(pm.Response_Validated_Constraint)
+		switch {
+		case vectorField != "" && projection.Field == vectorField:
+			constraint.Type = pm.Response_Validated_Constraint_FIELD_REQUIRED
+			constraint.Reason = "The vector field must be materialized"
+		// We require collection keys be materialized because it seems pretty reasonable to
+		// require they be included as metadata since the composite key is used as the basis for
+		// the vector ID, and also to avoid complications from
+		// https://github.com/estuary/flow/issues/1057.
+		case projection.IsPrimaryKey:
+			constraint.Type = pm.Response_Validated_Constraint_LOCATION_REQUIRED
+			constraint.Reason = "Components of the collection key must be materialized"
+		case projection.Inference.IsSingleScalarType():
+			constraint.Type = pm.Response_Validated_Constraint_LOCATION_RECOMMENDED
+			constraint.Reason = "The projection has a single scalar type"
+		case projection.IsRootDocumentProjection():
+			constraint.Type = pm.Response_Validated_Constraint_LOCATION_REQUIRED
+			constraint.Reason = "The root document must be materialized"
+		default:
+			constraint.Type = pm.Response_Validated_Constraint_FIELD_OPTIONAL
+			constraint.Reason = "This field can be materializaed"
+		}
+		constraints[projection.Field] = constraint
+	}
+
+	return constraints, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
 	storage "cloud.google.com/go/storage"
 	"github.com/estuary/connectors/go/common"
 	"github.com/estuary/connectors/go/dbt"
@@ -28,10 +29,11 @@ type config struct {
 	CredentialsJSON  string                     `json:"credentials_json" jsonschema:"title=Service Account JSON,description=The JSON credentials of the service account to use for authorization." jsonschema_extras:"secret=true,multiline=true,order=1"`
 	Region           string                     `json:"region" jsonschema:"title=Region,description=Region where both the Bucket and the BigQuery dataset is located. They both need to be within the same region." jsonschema_extras:"order=2"`
 	Dataset          string                     `json:"dataset" jsonschema:"title=Dataset,description=BigQuery dataset for bound collection tables (unless overridden within the binding resource configuration) as well as associated materialization metadata tables." jsonschema_extras:"order=3"`
-	Bucket           string                     `json:"bucket" jsonschema:"title=Bucket,description=Google Cloud Storage bucket that is going to be used to store specfications & temporary data before merging into BigQuery." jsonschema_extras:"order=4"`
+	Bucket           string                     `json:"bucket,omitempty" jsonschema:"title=Bucket,description=Google Cloud Storage bucket that is going to be used to store specfications & temporary data before merging into BigQuery. Required unless the Storage Write API is enabled." jsonschema_extras:"order=4"`
 	BucketPath       string                     `json:"bucket_path,omitempty" jsonschema:"title=Bucket Path,description=A prefix that will be used to store objects to Google Cloud Storage's bucket." jsonschema_extras:"order=5"`
 	BillingProjectID string                     `json:"billing_project_id,omitempty" jsonschema:"title=Billing Project ID,description=Billing Project ID connected to the BigQuery dataset. Defaults to Project ID if not specified." jsonschema_extras:"order=6"`
 	HardDelete       bool                       `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=7"`
+	StorageWriteAPI  bool                       `json:"storage_write_api,omitempty" jsonschema:"title=Use Storage Write API,description=Write data to BigQuery with the Storage Write API instead of staging files in a Google Cloud Storage bucket. When enabled a bucket is not needed. The service account must be able to create tables in the dataset.,default=false" jsonschema_extras:"order=8"`
 	Schedule         boilerplate.ScheduleConfig `json:"syncSchedule,omitempty" jsonschema:"title=Sync Schedule,description=Configure schedule of transactions for the materialization."`
 	DBTJobTrigger    dbt.JobConfig              `json:"dbt_job_trigger,omitempty" jsonschema:"title=dbt Cloud Job Trigger,description=Trigger a dbt job when new data is available"`
 
@@ -48,7 +50,9 @@ func (c *config) Validate() error {
 		{"credentials_json", c.CredentialsJSON},
 		{"dataset", c.Dataset},
 		{"region", c.Region},
-		{"bucket", c.Bucket},
+	}
+	if !c.StorageWriteAPI {
+		requiredProperties = append(requiredProperties, []string{"bucket", c.Bucket})
 	}
 	for _, req := range requiredProperties {
 		if req[1] == "" {
@@ -110,21 +114,46 @@ func (c *config) client(ctx context.Context, ep *sql.Endpoint) (*client, error)
 		return nil, fmt.Errorf("creating cloud storage client: %w", err)
 	}
 
+	var storageWriteClient *managedwriter.Client
+	if c.StorageWriteAPI {
+		if storageWriteClient, err = managedwriter.NewClient(ctx, billingProjectID, clientOpts...); err != nil {
+			return nil, fmt.Errorf("creating storage write client: %w", err)
+		}
+	}
+
 	return &client{
 		bigqueryClient:     bigqueryClient,
 		cloudStorageClient: cloudStorageClient,
+		storageWriteClient: storageWriteClient,
 		cfg:                *c,
 		ep:                 ep,
 	}, nil
 }
 
 type tableConfig struct {
-	Table     string `json:"table" jsonschema:"title=Table,description=Table in the BigQuery dataset to store materialized result in." jsonschema_extras:"x-collection-name=true"`
-	Dataset   string `json:"dataset,omitempty" jsonschema:"title=Alternative Dataset,description=Alternative dataset for this table (optional). Must be located in the region set in the endpoint configuration." jsonschema_extras:"x-schema-name=true"`
-	Delta     bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Defaults is false." jsonschema_extras:"x-delta-updates=true"`
-	projectID string
+	Table                string          `json:"table" jsonschema:"title=Table,description=Table in the BigQuery dataset to store materialized result in." jsonschema_extras:"x-collection-name=true"`
+	Dataset              string          `json:"dataset,omitempty" jsonschema:"title=Alternative Dataset,description=Alternative dataset for this table (optional). Must be located in the region set in the endpoint configuration." jsonschema_extras:"x-schema-name=true"`
+	Delta                bool            `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Defaults is false." jsonschema_extras:"x-delta-updates=true"`
+	History              bool            `json:"history_mode,omitempty" jsonschema:"default=false,title=History Mode,description=Keep a row for every version of each document instead of only the latest version. Rows have valid_from and valid_to times and an is_current flag. Default is false."`
+	PartitionField       string          `json:"partition_field,omitempty" jsonschema:"title=Partition Field,description=Field to partition the table by (optional). Must be a date or date-time field for time-unit partitioning or an integer field for integer-range partitioning. Changing the partitioning of an existing table requires the binding to be backfilled."`
+	PartitionGranularity string          `json:"partition_granularity,omitempty" jsonschema:"title=Partition Granularity,description=Granularity of time-unit partitioning by a date or date-time field. Defaults to DAY.,enum=HOUR,enum=DAY,enum=MONTH,enum=YEAR"`
+	PartitionRange       *partitionRange `json:"partition_range,omitempty" jsonschema:"title=Partition Range,description=Range of integer-range partitioning by an integer field. Required when partitioning by an integer field."`
+	ClusteringFields     []string        `json:"clustering_fields,omitempty" jsonschema:"title=Clustering Fields,description=Fields to cluster the table by in order of precedence (optional). Up to four fields may be used. Defaults to the first four fields of the collection key."`
+	projectID            string
 }
 
+type partitionRange struct {
+	Start    int64 `json:"start" jsonschema:"title=Start,description=Start of the range of partitioning (inclusive)."`
+	End      int64 `json:"end" jsonschema:"title=End,description=End of the range of partitioning (exclusive)."`
+	Interval int64 `json:"interval" jsonschema:"title=Interval,description=Width of each partition of the range."`
+}
+
+// BigQuery allows at most 4 clustering columns, and 10,000 partitions for a table.
+const (
+	maxClusteringFields = 4
+	maxPartitions       = 10_000
+)
+
 func newTableConfig(ep *sql.Endpoint) sql.Resource {
 	return &tableConfig{
 		// Default to the explicit endpoint configuration dataset. This may be over-written by a
@@ -138,6 +167,33 @@ func (c tableConfig) Validate() error {
 	if c.Table == "" {
 		return fmt.Errorf("expected table")
 	}
+
+	if c.PartitionField == "" && (c.PartitionGranularity != "" || c.PartitionRange != nil) {
+		return fmt.Errorf("partition_granularity and partition_range require a partition_field")
+	} else if c.PartitionGranularity != "" && c.PartitionRange != nil {
+		return fmt.Errorf("partition_granularity and partition_range cannot both be set")
+	}
+
+	switch c.PartitionGranularity {
+	case "", "HOUR", "DAY", "MONTH", "YEAR":
+	default:
+		return fmt.Errorf("invalid partition_granularity %q: must be one of HOUR, DAY, MONTH, or YEAR", c.PartitionGranularity)
+	}
+
+	if r := c.PartitionRange; r != nil {
+		if r.Interval <= 0 {
+			return fmt.Errorf("partition_range interval must be greater than 0")
+		} else if r.End <= r.Start {
+			return fmt.Errorf("partition_range end must be greater than its start")
+		} else if (r.End-r.Start)/r.Interval > maxPartitions {
+			return fmt.Errorf("partition_range must have no more than %d partitions", maxPartitions)
+		}
+	}
+
+	if len(c.ClusteringFields) > maxClusteringFields {
+		return fmt.Errorf("no more than %d clustering_fields may be used", maxClusteringFields)
+	}
+
 	return nil
 }
 
@@ -151,6 +207,35 @@ func (c tableConfig) DeltaUpdates() bool {
 	return c.Delta
 }
 
+// HistoryMode returns if the table is materialized in history mode.
+func (c tableConfig) HistoryMode() bool {
+	return c.History
+}
+
+// Layout returns the partitioning and clustering of the table. Tables without
+// configured clustering fields are clustered by up to the first 4 key columns.
+func (c tableConfig) Layout() *sql.TableLayout {
+	if c.PartitionField == "" && len(c.ClusteringFields) == 0 {
+		return nil
+	}
+
+	layout := &sql.TableLayout{ClusterBy: c.ClusteringFields}
+	if c.PartitionField != "" {
+		opts := partitionOptions{Granularity: c.PartitionGranularity}
+		if c.PartitionRange != nil {
+			opts.Range = *c.PartitionRange
+			opts.IsRange = true
+		} else if opts.Granularity == "" {
+			opts.Granularity = "DAY"
+		}
+
+		layout.PartitionBy = []string{c.PartitionField}
+		layout.Options = opts
+	}
+
+	return layout
+}
+
 func Driver() *sql.Driver {
 	return newBigQueryDriver()
 }
@@ -12,15 +12,33 @@ type binding struct {
 	target         sql.Table
 	storeInsertSQL string
 
-	loadFile         *stagedFile
-	storeFile        *stagedFile
-	tempTableName    string
+	loadFile      *stagedFile
+	storeFile     *stagedFile
+	tempTableName string
+
+	// Staging tables and their write streams are used instead of staged files
+	// when writing with the Storage Write API. The store stream of delta
+	// updates bindings writes to the target table, and they have no staging
+	// tables.
+	loadStream        *writeStream
+	storeStream       *writeStream
+	loadStagingTable  string
+	storeStagingTable string
+	hasLoads          bool
+
 	hasData          bool
 	mustMerge        bool
 	loadMergeBounds  *sql.MergeBoundsBuilder
 	storeMergeBounds *sql.MergeBoundsBuilder
 }
 
+// writesToTarget is true if stored rows are written directly to the target
+// table with the Storage Write API, rather than staged and then inserted or
+// merged into it by a query.
+func (b *binding) writesToTarget(cfg *config) bool {
+	return cfg.StorageWriteAPI && b.target.DeltaUpdates
+}
+
 // bindingDocument is used by the load operation to fetch binding flow_document values
 type bindingDocument struct {
 	Binding  int
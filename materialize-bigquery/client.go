@@ -12,6 +12,7 @@ import (
 	"sync"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
 	storage "cloud.google.com/go/storage"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
@@ -27,6 +28,7 @@ var _ sql.SchemaManager = (*client)(nil)
 type client struct {
 	bigqueryClient     *bigquery.Client
 	cloudStorageClient *storage.Client
+	storageWriteClient *managedwriter.Client
 	cfg                config
 	ep                 *sql.Endpoint
 }
@@ -196,16 +198,75 @@ func (c *client) AlterTable(ctx context.Context, ta sql.TableAlter) (string, boi
 		}
 	}
 
-	return strings.Join(stmts, "\n"), func(ctx context.Context) error {
+	// The partitioning of an existing table can't be changed, but its clustering can be updated.
+	// Only data written after the update is clustered by the new clustering columns.
+	var clustering []string
+	var desc = slices.Clone(stmts)
+	if ta.LayoutChanged {
+		prevPartitionBy, prevOpts := partitioning(ta.PreviousLayout)
+		partitionBy, opts := partitioning(ta.Layout)
+		if !slices.Equal(prevPartitionBy, partitionBy) || prevOpts != opts {
+			return "", nil, fmt.Errorf("the partitioning of table %s cannot be changed: the binding must be backfilled to re-create the table with the new partitioning", ta.Identifier)
+		}
+
+		if prev, next := clusteringFields(ta.PreviousLayout, ta.Keys), clusteringFields(ta.Layout, ta.Keys); !slices.Equal(prev, next) {
+			for _, f := range next {
+				clustering = append(clustering, translateFlowIdentifier(f))
+			}
+			desc = append(desc, fmt.Sprintf("-- Update clustering of table %s to (%s)", ta.Identifier, strings.Join(clustering, ", ")))
+		}
+	}
+
+	if len(stmts) == 0 && clustering == nil {
+		return "", nil, nil
+	}
+
+	return strings.Join(desc, "\n"), func(ctx context.Context) error {
 		for _, stmt := range stmts {
 			if _, err := c.query(ctx, stmt); err != nil {
 				return err
 			}
 		}
+
+		if clustering != nil {
+			table := c.bigqueryClient.DatasetInProject(c.cfg.ProjectID, ta.InfoLocation.TableSchema).Table(ta.InfoLocation.TableName)
+			if _, err := table.Update(ctx, bigquery.TableMetadataToUpdate{
+				Clustering: &bigquery.Clustering{Fields: clustering},
+			}, ""); err != nil {
+				return fmt.Errorf("updating clustering of table %s: %w", ta.Identifier, err)
+			}
+		}
+
 		return nil
 	}, nil
 }
 
+// partitioning returns the partitioning fields and options of the layout.
+func partitioning(l *sql.TableLayout) ([]string, any) {
+	if l == nil || len(l.PartitionBy) == 0 {
+		return nil, nil
+	}
+	return l.PartitionBy, l.Options
+}
+
+// clusteringFields returns the fields a table with the layout is clustered by,
+// which are up to the first 4 keys if the layout doesn't have clustering
+// fields.
+func clusteringFields(l *sql.TableLayout, keys []sql.Column) []string {
+	if l != nil && len(l.ClusterBy) > 0 {
+		return l.ClusterBy
+	}
+
+	var out []string
+	for _, k := range keys {
+		if len(out) == maxClusteringFields {
+			break
+		}
+		out = append(out, k.Field)
+	}
+	return out
+}
+
 func (c *client) ListSchemas(ctx context.Context) ([]string, error) {
 	// BigQuery represents the concept of a "schema" with datasets.
 	iter := c.bigqueryClient.Datasets(ctx)
@@ -258,6 +319,11 @@ func preReqs(ctx context.Context, conf any, tenant string) *sql.PrereqErr {
 		errs.Err(fmt.Errorf("dataset %q is actually in region %q, which is different than the configured region %q", c.cfg.Dataset, meta.Location, c.cfg.Region))
 	}
 
+	if c.cfg.StorageWriteAPI {
+		// No bucket is used when writing with the Storage Write API.
+		return errs
+	}
+
 	// Verify cloud storage abilities.
 	data := []byte("test")
 
@@ -357,4 +423,7 @@ func (c *client) InstallFence(ctx context.Context, _ sql.Table, fence sql.Fence)
 func (c *client) Close() {
 	c.bigqueryClient.Close()
 	c.cloudStorageClient.Close()
+	if c.storageWriteClient != nil {
+		c.storageWriteClient.Close()
+	}
 }
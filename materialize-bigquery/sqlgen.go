@@ -155,6 +155,48 @@ func toBigNumericCast(m sql.ColumnTypeMigration) string {
 	return fmt.Sprintf("CAST(%s AS BIGNUMERIC)", m.Identifier)
 }
 
+// partitionOptions are the options for partitioning a table by its partition column.
+type partitionOptions struct {
+	// Granularity of time-unit partitioning by a DATE or TIMESTAMP column.
+	Granularity string
+	// Range of integer-range partitioning by an INTEGER column.
+	Range   partitionRange
+	IsRange bool
+}
+
+// Expression returns the partitioning expression of the PARTITION BY clause of a table which is
+// partitioned by the column.
+func (o partitionOptions) Expression(col sql.Column) (string, error) {
+	var colType string
+	if f := strings.Fields(col.DDL); len(f) > 0 {
+		colType = strings.ToUpper(f[0])
+	}
+
+	switch colType {
+	case "DATE":
+		if o.IsRange {
+			return "", fmt.Errorf("partition field %q is a date, and cannot be partitioned by an integer range", col.Field)
+		} else if o.Granularity == "HOUR" {
+			return "", fmt.Errorf("partition field %q is a date, and cannot be partitioned by HOUR", col.Field)
+		} else if o.Granularity == "DAY" {
+			return col.Identifier, nil
+		}
+		return fmt.Sprintf("DATE_TRUNC(%s, %s)", col.Identifier, o.Granularity), nil
+	case "TIMESTAMP":
+		if o.IsRange {
+			return "", fmt.Errorf("partition field %q is a date-time, and cannot be partitioned by an integer range", col.Field)
+		}
+		return fmt.Sprintf("TIMESTAMP_TRUNC(%s, %s)", col.Identifier, o.Granularity), nil
+	case "INTEGER", "INT64":
+		if !o.IsRange {
+			return "", fmt.Errorf("partition field %q is an integer, and requires a partition_range", col.Field)
+		}
+		return fmt.Sprintf("RANGE_BUCKET(%s, GENERATE_ARRAY(%d, %d, %d))", col.Identifier, o.Range.Start, o.Range.End, o.Range.Interval), nil
+	default:
+		return "", fmt.Errorf("partition field %q has type %s, and must be a date, date-time, or integer to be used for partitioning", col.Field, col.DDL)
+	}
+}
+
 type templates struct {
 	tempTableName     *template.Template
 	createTargetTable *template.Template
@@ -164,16 +206,22 @@ type templates struct {
 	loadQuery         *template.Template
 	storeInsert       *template.Template
 	storeUpdate       *template.Template
+	historyMerge      *template.Template
 }
 
 func renderTemplates(dialect sql.Dialect) templates {
 	var tplAll = sql.MustParseTemplate(dialect, "root", `
 {{ define "tempTableName" -}}
+{{- if $.StagingTable -}}
+{{ $.StagingTable }}
+{{- else -}}
 flow_temp_table_{{ $.Binding }}
 {{- end }}
+{{- end }}
 
 -- Templated creation of a materialized table definition and comments.
--- Note: BigQuery only allows a maximum of 4 columns for clustering.
+-- Note: BigQuery only allows a maximum of 4 columns for clustering. Tables
+-- without configured clustering columns are clustered by up to the first 4 keys.
 
 {{ define "createTargetTable" -}}
 CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
@@ -181,13 +229,24 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
 		{{- if $ind }},{{ end }}
 		{{$col.Identifier}} {{$col.DDL}}
 	{{- end }}
+	{{- template "historyColumnDefinitions" $ }}
 )
-CLUSTER BY {{ range $ind, $key := $.Keys }}
+{{- if and $.LayoutColumns $.LayoutColumns.PartitionBy }}
+PARTITION BY {{ $.Layout.Options.Expression (index $.LayoutColumns.PartitionBy 0) }}
+{{- end }}
+CLUSTER BY {{ if and $.LayoutColumns $.LayoutColumns.ClusterBy -}}
+	{{- range $ind, $col := $.LayoutColumns.ClusterBy }}
+		{{- if $ind }}, {{end -}}
+		{{$col.Identifier}}
+	{{- end -}}
+{{- else -}}
+	{{- range $ind, $key := $.Keys }}
 	{{- if lt $ind 4 -}}
 		{{- if $ind }}, {{end -}}
 			{{$key.Identifier}}
 		{{- end -}}
-	{{- end}};
+	{{- end}}
+{{- end }};
 {{ end }}
 
 -- Templated query which performs table alterations by adding columns and/or
@@ -230,6 +289,9 @@ SELECT {{ $.Binding }}, l.{{$.Document.Identifier}}
 		l.{{ $bound.Identifier }} = r.c{{$ind}}
 		{{- if $bound.LiteralLower }} AND l.{{ $bound.Identifier }} >= {{ $bound.LiteralLower }} AND l.{{ $bound.Identifier }} <= {{ $bound.LiteralUpper }}{{ end }}
 	{{- end }}
+	{{- if $.History }}
+		AND l.{{ $.History.IsCurrent.Identifier }}
+	{{- end }}
 {{ else }}
 SELECT -1, NULL LIMIT 0
 {{ end }}
@@ -291,6 +353,41 @@ WHEN NOT MATCHED THEN
 	);
 {{ end }}
 
+-- Templated query which stores rows in a table materialized in history mode.
+-- The current rows for the stored keys are closed out, and then new current
+-- rows are inserted for documents which are not deletions.
+
+{{ define "historyPublishedAt" -}}
+{{- range $ind, $col := $.Columns }}
+	{{- if eq $col.Identifier $.History.PublishedAt.Identifier }}r.c{{$ind}}{{ end }}
+{{- end }}
+{{- end }}
+
+{{ define "historyMerge" -}}
+UPDATE {{ $.Identifier }} AS l
+SET {{ $.History.ValidTo.Identifier }} = {{ template "historyPublishedAt" . }}, {{ $.History.IsCurrent.Identifier }} = FALSE
+FROM {{ template "tempTableName" . }} AS r
+WHERE {{ range $ind, $bound := $.Bounds }}
+	{{- if $ind }} AND {{ end -}}
+	l.{{$bound.Identifier}} = r.c{{$ind}}
+	{{- if $bound.LiteralLower }} AND l.{{ $bound.Identifier }} >= {{ $bound.LiteralLower }} AND l.{{ $bound.Identifier }} <= {{ $bound.LiteralUpper }}{{ end }}
+{{- end }}
+	AND l.{{ $.History.IsCurrent.Identifier }} AND l.{{ $.History.ValidFrom.Identifier }} < {{ template "historyPublishedAt" . }};
+
+INSERT INTO {{ $.Identifier }} ({{ template "historyInsertColumns" $ }})
+SELECT {{ range $ind, $col := $.Columns }}
+		{{- "r.c" }}{{$ind}}, {{ end -}}
+	{{ template "historyPublishedAt" . }}, NULL, TRUE
+FROM {{ template "tempTableName" . }} AS r
+{{- if $.Document }}
+WHERE {{ if $.ObjAndArrayAsJson -}}
+	TO_JSON_STRING(r.c{{ Add (len $.Columns) -1 }})
+{{- else -}}
+	r.c{{ Add (len $.Columns) -1 }}
+{{- end }}!='"delete"'
+{{- end }};
+{{ end }}
+
 {{ define "installFence" }}
 -- Our desired fence
 DECLARE vMaterialization STRING DEFAULT {{ Literal $.Materialization.String }};
@@ -371,6 +468,7 @@ UPDATE {{ Identifier $.TablePath }}
 		loadQuery:         tplAll.Lookup("loadQuery"),
 		storeInsert:       tplAll.Lookup("storeInsert"),
 		storeUpdate:       tplAll.Lookup("storeUpdate"),
+		historyMerge:      tplAll.Lookup("historyMerge"),
 	}
 }
 
@@ -378,14 +476,19 @@ type queryParams struct {
 	sql.Table
 	Bounds            []sql.MergeBound
 	ObjAndArrayAsJson bool
+	// StagingTable is the identifier of the table that rows are staged to
+	// when using the Storage Write API. If empty, rows are staged as files
+	// and referenced as an external table.
+	StagingTable string
 }
 
-func renderQueryTemplate(table sql.Table, tpl *template.Template, bounds []sql.MergeBound, objAndArrayAsJson bool) (string, error) {
+func renderQueryTemplate(table sql.Table, tpl *template.Template, bounds []sql.MergeBound, objAndArrayAsJson bool, stagingTable string) (string, error) {
 	var w strings.Builder
 	if err := tpl.Execute(&w, &queryParams{
 		Table:             table,
 		Bounds:            bounds,
 		ObjAndArrayAsJson: objAndArrayAsJson,
+		StagingTable:      stagingTable,
 	}); err != nil {
 		return "", err
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bradleyjkemp/cupaloy"
 	sql "github.com/estuary/connectors/materialize-sql"
+	pf "github.com/estuary/flow/go/protocols/flow"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,7 +29,17 @@ func TestSQLGeneration(t *testing.T) {
 		sql.TestTemplates{
 			TableTemplates: []*template.Template{
 				templates.createTargetTable,
-				templates.storeInsert,
+			},
+			HistoryTableTemplates: []*template.Template{
+				templates.createTargetTable,
+			},
+			LayoutTableTemplates: []*template.Template{
+				templates.createTargetTable,
+			},
+			Layout: &sql.TableLayout{
+				PartitionBy: []string{"flow_published_at"},
+				ClusterBy:   []string{"string", "key1"},
+				Options:     partitionOptions{Granularity: "MONTH"},
 			},
 			TplAddColumns:    templates.alterTableColumns,
 			TplDropNotNulls:  templates.alterTableColumns,
@@ -38,13 +49,28 @@ func TestSQLGeneration(t *testing.T) {
 		},
 	)
 
-	for _, tpl := range []*template.Template{
-		templates.storeUpdate,
-		templates.loadQuery,
+	for _, tc := range []struct {
+		tbl          sql.Table
+		tpl          *template.Template
+		stagingTable string
+	}{
+		{tables[0], templates.storeInsert, ""},
+		{tables[0], templates.storeUpdate, ""},
+		{tables[0], templates.loadQuery, ""},
+		{tables[2], templates.loadQuery, ""},
+		{tables[2], templates.historyMerge, ""},
+		{tables[0], templates.storeInsert, "projectID.dataset.flow_staging_store"},
+		{tables[0], templates.storeUpdate, "projectID.dataset.flow_staging_store"},
+		{tables[0], templates.loadQuery, "projectID.dataset.flow_staging_load"},
 	} {
-		tbl := tables[0]
+		tbl, tpl := tc.tbl, tc.tpl
 		require.False(t, tbl.DeltaUpdates)
 		var testcase = tbl.Identifier + " " + tpl.Name()
+		if tbl.History != nil {
+			testcase = tbl.Identifier + " history " + tpl.Name()
+		} else if tc.stagingTable != "" {
+			testcase = tbl.Identifier + " staging " + tpl.Name()
+		}
 
 		bounds := []sql.MergeBound{
 			{
@@ -65,8 +91,9 @@ func TestSQLGeneration(t *testing.T) {
 		}
 
 		tf := queryParams{
-			Table:  tbl,
-			Bounds: bounds,
+			Table:        tbl,
+			Bounds:       bounds,
+			StagingTable: tc.stagingTable,
 		}
 
 		snap.WriteString("--- Begin " + testcase + " ---\n")
@@ -76,3 +103,115 @@ func TestSQLGeneration(t *testing.T) {
 
 	cupaloy.SnapshotT(t, snap.String())
 }
+
+func TestPartitionExpression(t *testing.T) {
+	col := func(ddl string) sql.Column {
+		return sql.Column{
+			Projection: sql.Projection{Projection: pf.Projection{Field: "theField"}},
+			MappedType: sql.MappedType{DDL: ddl},
+			Identifier: "theField",
+		}
+	}
+
+	for _, tt := range []struct {
+		name    string
+		col     sql.Column
+		opts    partitionOptions
+		want    string
+		wantErr string
+	}{
+		{
+			name: "timestamp",
+			col:  col("TIMESTAMP NOT NULL"),
+			opts: partitionOptions{Granularity: "HOUR"},
+			want: "TIMESTAMP_TRUNC(theField, HOUR)",
+		},
+		{
+			name: "date by day",
+			col:  col("DATE"),
+			opts: partitionOptions{Granularity: "DAY"},
+			want: "theField",
+		},
+		{
+			name: "date by year",
+			col:  col("DATE"),
+			opts: partitionOptions{Granularity: "YEAR"},
+			want: "DATE_TRUNC(theField, YEAR)",
+		},
+		{
+			name:    "date by hour",
+			col:     col("DATE"),
+			opts:    partitionOptions{Granularity: "HOUR"},
+			wantErr: "cannot be partitioned by HOUR",
+		},
+		{
+			name: "integer range",
+			col:  col("INTEGER NOT NULL"),
+			opts: partitionOptions{Range: partitionRange{Start: 0, End: 1000, Interval: 10}, IsRange: true},
+			want: "RANGE_BUCKET(theField, GENERATE_ARRAY(0, 1000, 10))",
+		},
+		{
+			name:    "integer without range",
+			col:     col("INTEGER"),
+			opts:    partitionOptions{Granularity: "DAY"},
+			wantErr: "requires a partition_range",
+		},
+		{
+			name:    "timestamp with range",
+			col:     col("TIMESTAMP"),
+			opts:    partitionOptions{Range: partitionRange{Start: 0, End: 1000, Interval: 10}, IsRange: true},
+			wantErr: "cannot be partitioned by an integer range",
+		},
+		{
+			name:    "string",
+			col:     col("STRING"),
+			opts:    partitionOptions{Granularity: "DAY"},
+			wantErr: "must be a date, date-time, or integer",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.Expression(tt.col)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTableConfigLayout(t *testing.T) {
+	require.Nil(t, tableConfig{Table: "t"}.Layout())
+
+	require.Equal(t, &sql.TableLayout{
+		PartitionBy: []string{"ts"},
+		Options:     partitionOptions{Granularity: "DAY"},
+	}, tableConfig{Table: "t", PartitionField: "ts"}.Layout())
+
+	require.Equal(t, &sql.TableLayout{
+		PartitionBy: []string{"id"},
+		ClusterBy:   []string{"a"},
+		Options:     partitionOptions{Range: partitionRange{Start: 0, End: 100, Interval: 10}, IsRange: true},
+	}, tableConfig{
+		Table:            "t",
+		PartitionField:   "id",
+		PartitionRange:   &partitionRange{Start: 0, End: 100, Interval: 10},
+		ClusteringFields: []string{"a"},
+	}.Layout())
+
+	for _, tt := range []struct {
+		cfg     tableConfig
+		wantErr string
+	}{
+		{tableConfig{Table: "t", PartitionGranularity: "DAY"}, "require a partition_field"},
+		{tableConfig{Table: "t", PartitionField: "ts", PartitionGranularity: "WEEK"}, "invalid partition_granularity"},
+		{tableConfig{Table: "t", PartitionField: "id", PartitionGranularity: "DAY", PartitionRange: &partitionRange{End: 10, Interval: 1}}, "cannot both be set"},
+		{tableConfig{Table: "t", PartitionField: "id", PartitionRange: &partitionRange{End: 10}}, "interval must be greater than 0"},
+		{tableConfig{Table: "t", PartitionField: "id", PartitionRange: &partitionRange{Start: 10, End: 10, Interval: 1}}, "end must be greater than its start"},
+		{tableConfig{Table: "t", PartitionField: "id", PartitionRange: &partitionRange{End: 100_000, Interval: 1}}, "no more than 10000 partitions"},
+		{tableConfig{Table: "t", ClusteringFields: []string{"a", "b", "c", "d", "e"}}, "no more than 4 clustering_fields"},
+	} {
+		require.ErrorContains(t, tt.cfg.Validate(), tt.wantErr)
+	}
+}
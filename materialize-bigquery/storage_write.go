@@ -0,0 +1,369 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Rows are appended in requests of up to this size. The Storage Write API
+// allows up to 10MB per request.
+const appendRequestSizeLimit = 5 * 1024 * 1024
+
+// writeStream writes rows to a table using a pending stream of the BigQuery
+// Storage Write API. Rows written to a pending stream are not visible in the
+// table until the stream is committed, which is done atomically for all of the
+// rows of a transaction.
+//
+// Rows of standard bindings are written to staging tables, which have a column
+// per column of the target table named c0, c1, etc. This is the same shape as
+// the external tables used for files staged to GCS, so the same queries can
+// read from either. Rows of delta updates bindings are written directly to
+// their target tables.
+type writeStream struct {
+	client *managedwriter.Client
+	// Fully qualified name of the table, in the form used by the Storage Write
+	// API: projects/{project}/datasets/{dataset}/tables/{table}.
+	table      string
+	schema     []*bigquery.FieldSchema
+	descriptor *descriptorpb.DescriptorProto
+	msg        protoreflect.MessageDescriptor
+
+	stream   *managedwriter.ManagedStream
+	rows     [][]byte
+	rowsSize int
+	results  []*managedwriter.AppendResult
+
+	// Indicates if the writeStream has been started for this transaction yet.
+	// Set `true` by start() and `false` by commit().
+	started bool
+}
+
+// newWriteStream creates a writeStream for the table. The columns are the names
+// of the columns of the table for each field of the schema, if they are
+// different than the placeholder names of the schema.
+func newWriteStream(client *managedwriter.Client, table string, schema []*bigquery.FieldSchema, columns []string) (*writeStream, error) {
+	descriptor, msg, err := protoDescriptor(schema, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeStream{
+		client:     client,
+		table:      table,
+		schema:     schema,
+		descriptor: descriptor,
+		msg:        msg,
+	}, nil
+}
+
+func (s *writeStream) start() {
+	s.started = true
+}
+
+func (s *writeStream) encodeRow(ctx context.Context, row []any) error {
+	encoded, err := encodeProtoRow(s.msg, s.schema, row)
+	if err != nil {
+		return err
+	}
+
+	s.rows = append(s.rows, encoded)
+	s.rowsSize += len(encoded)
+
+	if s.rowsSize >= appendRequestSizeLimit {
+		return s.appendRows(ctx)
+	}
+
+	return nil
+}
+
+func (s *writeStream) appendRows(ctx context.Context) error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+
+	if s.stream == nil {
+		var err error
+		if s.stream, err = s.client.NewManagedStream(ctx,
+			managedwriter.WithDestinationTable(s.table),
+			managedwriter.WithType(managedwriter.PendingStream),
+			managedwriter.WithSchemaDescriptor(s.descriptor),
+		); err != nil {
+			return fmt.Errorf("creating write stream for %s: %w", s.table, err)
+		}
+	}
+
+	res, err := s.stream.AppendRows(ctx, s.rows)
+	if err != nil {
+		return fmt.Errorf("appending rows to %s: %w", s.table, err)
+	}
+	s.results = append(s.results, res)
+	s.rows = nil
+	s.rowsSize = 0
+
+	return nil
+}
+
+// finalize appends any remaining rows and waits for all of the appends to
+// complete. No more rows can be written to the stream after it is finalized.
+func (s *writeStream) finalize(ctx context.Context) error {
+	if err := s.appendRows(ctx); err != nil {
+		return err
+	}
+
+	for _, res := range s.results {
+		if _, err := res.GetResult(ctx); err != nil {
+			return fmt.Errorf("appending rows to %s: %w", s.table, err)
+		}
+	}
+	s.results = nil
+
+	if s.stream != nil {
+		if _, err := s.stream.Finalize(ctx); err != nil {
+			return fmt.Errorf("finalizing write stream for %s: %w", s.table, err)
+		}
+	}
+
+	return nil
+}
+
+// commit atomically makes the rows of the finalized stream visible in the
+// table.
+func (s *writeStream) commit(ctx context.Context) error {
+	defer func() {
+		s.stream = nil
+		s.started = false
+	}()
+
+	if s.stream == nil {
+		return nil
+	}
+	defer s.stream.Close()
+
+	resp, err := s.client.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       s.table,
+		WriteStreams: []string{s.stream.StreamName()},
+	})
+	if err != nil {
+		return fmt.Errorf("committing write stream for %s: %w", s.table, err)
+	} else if errs := resp.GetStreamErrors(); len(errs) > 0 {
+		return fmt.Errorf("committing write stream for %s: %s", s.table, errs[0].GetErrorMessage())
+	}
+
+	return nil
+}
+
+// ensureStagingTable creates the staging table if it does not exist, or
+// re-creates it if its schema does not match. Any rows left in an existing
+// staging table from a transaction that did not complete are removed.
+func (c *client) ensureStagingTable(ctx context.Context, dataset, name string, schema []*bigquery.FieldSchema) error {
+	var want = make(bigquery.Schema, 0, len(schema))
+	for _, f := range schema {
+		// Staging table columns are always nullable, and only the type of the
+		// target column is relevant.
+		want = append(want, &bigquery.FieldSchema{Name: f.Name, Type: f.Type})
+	}
+
+	var tbl = c.bigqueryClient.DatasetInProject(c.cfg.ProjectID, dataset).Table(name)
+	var ll = log.WithFields(log.Fields{"dataset": dataset, "table": name})
+
+	meta, err := tbl.Metadata(ctx)
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) && googleErr.Code == http.StatusNotFound {
+		ll.Info("creating staging table")
+		return tbl.Create(ctx, &bigquery.TableMetadata{Schema: want})
+	} else if err != nil {
+		return fmt.Errorf("getting staging table metadata: %w", err)
+	}
+
+	if !slices.EqualFunc(meta.Schema, want, func(a, b *bigquery.FieldSchema) bool {
+		return a.Name == b.Name && a.Type == b.Type
+	}) {
+		ll.Info("re-creating staging table with updated schema")
+		if err := tbl.Delete(ctx); err != nil {
+			return fmt.Errorf("deleting staging table: %w", err)
+		}
+		return tbl.Create(ctx, &bigquery.TableMetadata{Schema: want})
+	}
+
+	query := c.newQuery(fmt.Sprintf("DELETE FROM %s WHERE TRUE;", c.ep.Dialect.Identifier(c.cfg.ProjectID, dataset, name)))
+	if _, err := c.runQuery(ctx, query); err != nil {
+		return fmt.Errorf("clearing staging table: %w", err)
+	}
+
+	return nil
+}
+
+// protoFieldTypes are the protocol buffer types used for each type of column
+// of a staging table. Types with a canonical string representation are sent
+// as strings, which the Storage Write API converts to the column type.
+var protoFieldTypes = map[bigquery.FieldType]descriptorpb.FieldDescriptorProto_Type{
+	bigquery.StringFieldType:     descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.JSONFieldType:       descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.NumericFieldType:    descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.BigNumericFieldType: descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.DateFieldType:       descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.DateTimeFieldType:   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.TimeFieldType:       descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.GeographyFieldType:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	bigquery.IntegerFieldType:    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	bigquery.FloatFieldType:      descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	bigquery.BooleanFieldType:    descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	bigquery.BytesFieldType:      descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	// Timestamps are sent as microseconds since the epoch.
+	bigquery.TimestampFieldType: descriptorpb.FieldDescriptorProto_TYPE_INT64,
+}
+
+// protoDescriptor builds the protocol buffer message for rows of a table with
+// the given schema. Fields are named by the schema, which uses placeholder
+// names that are always valid protocol buffer field names. If columns are
+// provided, each field is annotated with the name of its column in the table,
+// which may not be a valid field name.
+func protoDescriptor(schema []*bigquery.FieldSchema, columns []string) (*descriptorpb.DescriptorProto, protoreflect.MessageDescriptor, error) {
+	var dp = &descriptorpb.DescriptorProto{Name: proto.String("Row")}
+
+	for idx, f := range schema {
+		typ, ok := protoFieldTypes[f.Type]
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported type %q for column %q", f.Type, f.Name)
+		}
+
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(f.Name),
+			Number: proto.Int32(int32(idx + 1)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if columns != nil {
+			field.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(field.Options, storagepb.E_ColumnName, columns[idx])
+		}
+		dp.Field = append(dp.Field, field)
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("row.proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{dp},
+	}, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building row descriptor: %w", err)
+	}
+
+	return dp, fd.Messages().Get(0), nil
+}
+
+func encodeProtoRow(md protoreflect.MessageDescriptor, schema []*bigquery.FieldSchema, row []any) ([]byte, error) {
+	var msg = dynamicpb.NewMessage(md)
+	var fields = md.Fields()
+
+	for idx, v := range row {
+		if v == nil {
+			continue
+		}
+
+		val, err := protoValue(schema[idx].Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("converting value for column %q: %w", schema[idx].Name, err)
+		}
+		msg.Set(fields.Get(idx), val)
+	}
+
+	return proto.Marshal(msg)
+}
+
+func protoValue(typ bigquery.FieldType, v any) (protoreflect.Value, error) {
+	switch typ {
+	case bigquery.IntegerFieldType:
+		switch n := v.(type) {
+		case int64:
+			return protoreflect.ValueOfInt64(n), nil
+		case int:
+			return protoreflect.ValueOfInt64(int64(n)), nil
+		case uint64:
+			if n > math.MaxInt64 {
+				return protoreflect.Value{}, fmt.Errorf("value %d overflows INT64", n)
+			}
+			return protoreflect.ValueOfInt64(int64(n)), nil
+		}
+	case bigquery.FloatFieldType:
+		switch n := v.(type) {
+		case float64:
+			return protoreflect.ValueOfFloat64(n), nil
+		case float32:
+			return protoreflect.ValueOfFloat64(float64(n)), nil
+		case int64:
+			return protoreflect.ValueOfFloat64(float64(n)), nil
+		case uint64:
+			return protoreflect.ValueOfFloat64(float64(n)), nil
+		case string:
+			// Special values like "NaN" and "Infinity".
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	case bigquery.BooleanFieldType:
+		if b, ok := v.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case bigquery.BytesFieldType:
+		switch b := v.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case string:
+			return protoreflect.ValueOfBytes([]byte(b)), nil
+		}
+	case bigquery.TimestampFieldType:
+		switch t := v.(type) {
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, t)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfInt64(parsed.UnixMicro()), nil
+		case time.Time:
+			return protoreflect.ValueOfInt64(t.UnixMicro()), nil
+		}
+	default:
+		switch s := v.(type) {
+		case string:
+			return protoreflect.ValueOfString(s), nil
+		case []byte:
+			return protoreflect.ValueOfString(string(s)), nil
+		case json.RawMessage:
+			return protoreflect.ValueOfString(string(s)), nil
+		case *big.Int:
+			return protoreflect.ValueOfString(s.String()), nil
+		case int64:
+			return protoreflect.ValueOfString(strconv.FormatInt(s, 10)), nil
+		case uint64:
+			return protoreflect.ValueOfString(strconv.FormatUint(s, 10)), nil
+		case float64:
+			return protoreflect.ValueOfString(strconv.FormatFloat(s, 'f', -1, 64)), nil
+		case bool:
+			return protoreflect.ValueOfString(strconv.FormatBool(s)), nil
+		}
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("unsupported value %#v (%T) for %s column", v, v, typ)
+}
@@ -0,0 +1,92 @@
+package connector
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestEncodeProtoRow(t *testing.T) {
+	schema := []*bigquery.FieldSchema{
+		{Name: "c0", Type: bigquery.IntegerFieldType},
+		{Name: "c1", Type: bigquery.FloatFieldType},
+		{Name: "c2", Type: bigquery.BooleanFieldType},
+		{Name: "c3", Type: bigquery.TimestampFieldType},
+		{Name: "c4", Type: bigquery.BigNumericFieldType},
+		{Name: "c5", Type: bigquery.JSONFieldType},
+		{Name: "c6", Type: bigquery.DateFieldType},
+		{Name: "c7", Type: bigquery.StringFieldType},
+	}
+
+	_, md, err := protoDescriptor(schema, nil)
+	require.NoError(t, err)
+	require.Equal(t, len(schema), md.Fields().Len())
+
+	encoded, err := encodeProtoRow(md, schema, []any{
+		int64(42),
+		"NaN",
+		true,
+		"2024-01-02T03:04:05.123456Z",
+		new(big.Int).Lsh(big.NewInt(1), 70),
+		json.RawMessage(`{"hello":"world"}`),
+		"2024-01-02",
+		nil,
+	})
+	require.NoError(t, err)
+
+	msg := dynamicpb.NewMessage(md)
+	require.NoError(t, proto.Unmarshal(encoded, msg))
+
+	get := func(idx int) any { return msg.Get(md.Fields().Get(idx)).Interface() }
+	require.Equal(t, int64(42), get(0))
+	require.True(t, math.IsNaN(get(1).(float64)))
+	require.Equal(t, true, get(2))
+	require.Equal(t, int64(1704164645123456), get(3))
+	require.Equal(t, "1180591620717411303424", get(4))
+	require.Equal(t, `{"hello":"world"}`, get(5))
+	require.Equal(t, "2024-01-02", get(6))
+	require.False(t, msg.Has(md.Fields().Get(7)))
+}
+
+func TestProtoValueErrors(t *testing.T) {
+	_, err := protoValue(bigquery.IntegerFieldType, uint64(math.MaxUint64))
+	require.ErrorContains(t, err, "overflows INT64")
+
+	_, err = protoValue(bigquery.BooleanFieldType, "true")
+	require.ErrorContains(t, err, "unsupported value")
+
+	_, _, err = protoDescriptor([]*bigquery.FieldSchema{{Name: "c0", Type: bigquery.RecordFieldType}}, nil)
+	require.ErrorContains(t, err, "unsupported type")
+}
+
+func TestProtoDescriptorColumnNames(t *testing.T) {
+	schema := []*bigquery.FieldSchema{
+		{Name: "c0", Type: bigquery.StringFieldType},
+		{Name: "c1", Type: bigquery.IntegerFieldType},
+	}
+
+	// Rows of delta updates bindings are written to the columns of the target
+	// table, whose names may not be valid protocol buffer field names.
+	dp, md, err := protoDescriptor(schema, []string{"some-key", "1value"})
+	require.NoError(t, err)
+	require.Equal(t, "c0", dp.Field[0].GetName())
+	require.Equal(t, "some-key", proto.GetExtension(dp.Field[0].Options, storagepb.E_ColumnName))
+	require.Equal(t, "1value", proto.GetExtension(dp.Field[1].Options, storagepb.E_ColumnName))
+
+	encoded, err := encodeProtoRow(md, schema, []any{"a", int64(1)})
+	require.NoError(t, err)
+	msg := dynamicpb.NewMessage(md)
+	require.NoError(t, proto.Unmarshal(encoded, msg))
+	require.Equal(t, "a", msg.Get(md.Fields().Get(0)).Interface())
+
+	dp, _, err = protoDescriptor(schema, nil)
+	require.NoError(t, err)
+	require.Nil(t, dp.Field[0].Options)
+}
@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"text/template"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
@@ -99,7 +99,7 @@ func prepareNewTransactor(
 				fieldSchemas[f.Name] = f
 			}
 
-			if err = t.addBinding(binding, fieldSchemas, &ep.Dialect); err != nil {
+			if err = t.addBinding(ctx, binding, dataset, table, fieldSchemas, &ep.Dialect); err != nil {
 				return nil, nil, fmt.Errorf("addBinding of %s: %w", binding.Path, err)
 			}
 		}
@@ -117,7 +117,7 @@ func prepareNewTransactor(
 	}
 }
 
-func (t *transactor) addBinding(target sql.Table, fieldSchemas map[string]*bigquery.FieldSchema, dialect *sql.Dialect) error {
+func (t *transactor) addBinding(ctx context.Context, target sql.Table, dataset, table string, fieldSchemas map[string]*bigquery.FieldSchema, dialect *sql.Dialect) error {
 	loadSchema, err := schemaForCols(target.KeyPtrs(), fieldSchemas)
 	if err != nil {
 		return err
@@ -130,21 +130,62 @@ func (t *transactor) addBinding(target sql.Table, fieldSchemas map[string]*bigqu
 
 	b := &binding{
 		target:           target,
-		loadFile:         newStagedFile(t.client.cloudStorageClient, t.bucket, t.bucketPath, loadSchema),
-		storeFile:        newStagedFile(t.client.cloudStorageClient, t.bucket, t.bucketPath, storeSchema),
 		loadMergeBounds:  sql.NewMergeBoundsBuilder(target.Keys, dialect.Literal),
 		storeMergeBounds: sql.NewMergeBoundsBuilder(target.Keys, dialect.Literal),
 	}
 
-	for _, m := range []struct {
-		sql *string
-		tpl *template.Template
-	}{
-		{&b.tempTableName, t.templates.tempTableName},
-		{&b.storeInsertSQL, t.templates.storeInsert},
-	} {
-		var err error
-		if *m.sql, err = sql.RenderTableTemplate(target, m.tpl); err != nil {
+	if b.writesToTarget(t.cfg) {
+		// Rows of delta updates bindings are only ever appended, so they are
+		// written directly to the target table rather than merged from a
+		// staging table. Delta updates bindings have no loads.
+		columns := make([]string, 0, len(target.Columns()))
+		for _, col := range target.Columns() {
+			columns = append(columns, translateFlowIdentifier(col.Field))
+		}
+
+		if b.storeStream, err = newWriteStream(
+			t.client.storageWriteClient,
+			managedwriter.TableParentFromParts(t.cfg.ProjectID, dataset, translateFlowIdentifier(table)),
+			storeSchema,
+			columns,
+		); err != nil {
+			return fmt.Errorf("creating store write stream: %w", err)
+		}
+	} else if t.cfg.StorageWriteAPI {
+		for _, s := range []struct {
+			kind       string
+			schema     []*bigquery.FieldSchema
+			stream     **writeStream
+			identifier *string
+		}{
+			{"load", loadSchema, &b.loadStream, &b.loadStagingTable},
+			{"store", storeSchema, &b.storeStream, &b.storeStagingTable},
+		} {
+			name := fmt.Sprintf("flow_staging_%s_%s_%08x", s.kind, translateFlowIdentifier(table), t.fence.KeyBegin)
+			if err := t.client.ensureStagingTable(ctx, dataset, name, s.schema); err != nil {
+				return fmt.Errorf("preparing %s staging table: %w", s.kind, err)
+			}
+
+			*s.identifier = dialect.Identifier(t.cfg.ProjectID, dataset, name)
+			if *s.stream, err = newWriteStream(
+				t.client.storageWriteClient,
+				managedwriter.TableParentFromParts(t.cfg.ProjectID, dataset, name),
+				s.schema,
+				nil,
+			); err != nil {
+				return fmt.Errorf("creating %s write stream: %w", s.kind, err)
+			}
+		}
+	} else {
+		b.loadFile = newStagedFile(t.client.cloudStorageClient, t.bucket, t.bucketPath, loadSchema)
+		b.storeFile = newStagedFile(t.client.cloudStorageClient, t.bucket, t.bucketPath, storeSchema)
+	}
+
+	if b.tempTableName, err = renderQueryTemplate(target, t.templates.tempTableName, nil, t.objAndArrayAsJson, ""); err != nil {
+		return err
+	}
+	if !b.writesToTarget(t.cfg) {
+		if b.storeInsertSQL, err = renderQueryTemplate(target, t.templates.storeInsert, nil, t.objAndArrayAsJson, b.storeStagingTable); err != nil {
 			return err
 		}
 	}
@@ -182,20 +223,32 @@ func (t *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 
 	for it.Next() {
 		var b = t.bindings[it.Binding]
-		b.loadFile.start()
 
-		if converted, err := b.target.ConvertKey(it.Key); err != nil {
+		converted, err := b.target.ConvertKey(it.Key)
+		if err != nil {
 			return fmt.Errorf("converting load key: %w", err)
-		} else if err = b.loadFile.encodeRow(ctx, converted); err != nil {
-			return fmt.Errorf("writing normalized key to keyfile: %w", err)
+		}
+
+		if t.cfg.StorageWriteAPI {
+			b.loadStream.start()
+			err = b.loadStream.encodeRow(ctx, converted)
 		} else {
-			b.loadMergeBounds.NextKey(converted)
+			b.loadFile.start()
+			err = b.loadFile.encodeRow(ctx, converted)
+		}
+		if err != nil {
+			return fmt.Errorf("writing normalized key to keyfile: %w", err)
 		}
+		b.loadMergeBounds.NextKey(converted)
 	}
 	if it.Err() != nil {
 		return it.Err()
 	}
 
+	if t.cfg.StorageWriteAPI {
+		return t.loadFromStagingTables(ctx, loaded)
+	}
+
 	// Build the queries of all documents across all bindings that were requested.
 	var subqueries []string
 	// This is the map of external table references we will populate.
@@ -207,7 +260,7 @@ func (t *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 			continue
 		}
 
-		loadQuery, err := renderQueryTemplate(b.target, t.templates.loadQuery, b.loadMergeBounds.Build(), t.objAndArrayAsJson)
+		loadQuery, err := renderQueryTemplate(b.target, t.templates.loadQuery, b.loadMergeBounds.Build(), t.objAndArrayAsJson, "")
 		if err != nil {
 			return fmt.Errorf("rendering load query template: %w", err)
 		}
@@ -230,7 +283,47 @@ func (t *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 	queryStr := strings.Join(subqueries, "\nUNION ALL\n") + ";"
 	query := t.client.newQuery(queryStr)
 	query.TableDefinitions = edcTableDefs // Tell bigquery where to get the external references in gcs.
-	ll := log.WithField("query", queryStr)
+
+	return t.runLoadQuery(ctx, query, loaded)
+}
+
+// loadFromStagingTables commits the keys written to the load staging tables
+// and queries the target tables for the staged keys.
+func (t *transactor) loadFromStagingTables(ctx context.Context, loaded func(int, json.RawMessage) error) error {
+	var subqueries []string
+
+	for idx, b := range t.bindings {
+		if b.loadStream == nil || !b.loadStream.started {
+			// No loads for this binding.
+			continue
+		}
+
+		if err := b.loadStream.finalize(ctx); err != nil {
+			return fmt.Errorf("finalizing load stream for binding[%d]: %w", idx, err)
+		} else if err := b.loadStream.commit(ctx); err != nil {
+			return fmt.Errorf("committing load stream for binding[%d]: %w", idx, err)
+		}
+		// The staging table is cleared when the transaction commits.
+		b.hasLoads = true
+
+		loadQuery, err := renderQueryTemplate(b.target, t.templates.loadQuery, b.loadMergeBounds.Build(), t.objAndArrayAsJson, b.loadStagingTable)
+		if err != nil {
+			return fmt.Errorf("rendering load query template: %w", err)
+		}
+		subqueries = append(subqueries, loadQuery)
+	}
+
+	if len(subqueries) == 0 {
+		return nil // Nothing to load.
+	}
+
+	queryStr := strings.Join(subqueries, "\nUNION ALL\n") + ";"
+
+	return t.runLoadQuery(ctx, t.client.newQuery(queryStr), loaded)
+}
+
+func (t *transactor) runLoadQuery(ctx context.Context, query *bigquery.Query, loaded func(int, json.RawMessage) error) error {
+	ll := log.WithField("query", query.Q)
 
 	t.be.StartedEvaluatingLoads()
 	job, err := t.client.runQuery(ctx, query)
@@ -286,7 +379,11 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			// There may be no staged file if the binding has received nothing
 			// but hard deletion requests for keys that aren't in the
 			// destination table.
-			if b.storeFile.started {
+			if t.cfg.StorageWriteAPI {
+				if err := b.storeStream.finalize(ctx); err != nil {
+					return nil, fmt.Errorf("finalizing store stream for collection %q: %w", b.target.Source.String(), err)
+				}
+			} else if b.storeFile.started {
 				cleanupFn, err := b.storeFile.flush()
 				if err != nil {
 					return nil, fmt.Errorf("flushing staged files for collection %q: %w", b.target.Source.String(), err)
@@ -303,7 +400,7 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		}
 
 		var flowDocument = it.RawJSON
-		if t.cfg.HardDelete && it.Delete {
+		if (t.cfg.HardDelete || b.target.History != nil) && it.Delete {
 			if it.Exists {
 				flowDocument = json.RawMessage(`"delete"`)
 			} else {
@@ -312,22 +409,35 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			}
 		}
 
-		b.storeFile.start()
-		b.hasData = true
-		if converted, err := b.target.ConvertAll(it.Key, it.Values, flowDocument); err != nil {
+		converted, err := b.target.ConvertAll(it.Key, it.Values, flowDocument)
+		if err != nil {
 			return nil, fmt.Errorf("converting store parameters: %w", err)
-		} else if err = b.storeFile.encodeRow(ctx, converted); err != nil {
-			return nil, fmt.Errorf("encoding Store to scratch file: %w", err)
+		}
+
+		b.hasData = true
+		if t.cfg.StorageWriteAPI {
+			b.storeStream.start()
+			err = b.storeStream.encodeRow(ctx, converted)
 		} else {
-			b.storeMergeBounds.NextKey(converted[:len(b.target.Keys)])
+			b.storeFile.start()
+			err = b.storeFile.encodeRow(ctx, converted)
 		}
+		if err != nil {
+			return nil, fmt.Errorf("encoding Store to scratch file: %w", err)
+		}
+		b.storeMergeBounds.NextKey(converted[:len(b.target.Keys)])
 	}
 	if it.Err() != nil {
 		return nil, it.Err()
 	}
 
 	// Flush the final binding.
-	if lastBinding != -1 {
+	if lastBinding != -1 && t.cfg.StorageWriteAPI {
+		var b = t.bindings[lastBinding]
+		if err := b.storeStream.finalize(ctx); err != nil {
+			return nil, fmt.Errorf("final binding finalizing store stream for collection %q: %w", b.target.Source.String(), err)
+		}
+	} else if lastBinding != -1 {
 		var b = t.bindings[lastBinding]
 		cleanupFn, err := b.storeFile.flush()
 		if err != nil {
@@ -353,6 +463,22 @@ func (t *transactor) commit(ctx context.Context, cleanupFiles []func(context.Con
 		}
 	}()
 
+	// Rows written with the Storage Write API become visible in the staging
+	// tables when their streams are committed. If the transaction below fails
+	// the staging tables are cleared when the connector restarts. Rows of
+	// delta updates bindings become visible in their target tables, and are
+	// written again if the transaction below fails and is retried.
+	if t.cfg.StorageWriteAPI {
+		for _, b := range t.bindings {
+			if !b.hasData {
+				continue
+			}
+			if err := b.storeStream.commit(ctx); err != nil {
+				return fmt.Errorf("committing store stream for collection %q: %w", b.target.Source.String(), err)
+			}
+		}
+	}
+
 	// Build the slice of transactions required for a commit.
 	var subqueries []string
 
@@ -372,23 +498,45 @@ func (t *transactor) commit(ctx context.Context, cleanupFiles []func(context.Con
 	// append the SQL for that table.
 	var edcTableDefs = make(map[string]bigquery.ExternalData)
 	for _, b := range t.bindings {
+		if b.hasLoads {
+			subqueries = append(subqueries, fmt.Sprintf("DELETE FROM %s WHERE TRUE;", b.loadStagingTable))
+			b.hasLoads = false
+		}
+
 		if !b.hasData {
 			// No stores for this binding.
 			continue
 		}
 
-		edcTableDefs[b.tempTableName] = b.storeFile.edc()
+		if b.writesToTarget(t.cfg) {
+			// The rows were committed to the target table with its write
+			// stream above.
+			b.hasData = false
+			continue
+		} else if !t.cfg.StorageWriteAPI {
+			edcTableDefs[b.tempTableName] = b.storeFile.edc()
+		}
 
-		if !b.mustMerge {
+		if b.target.History != nil {
+			historyQuery, err := renderQueryTemplate(b.target, t.templates.historyMerge, b.storeMergeBounds.Build(), t.objAndArrayAsJson, b.storeStagingTable)
+			if err != nil {
+				return fmt.Errorf("rendering history merge query template: %w", err)
+			}
+			subqueries = append(subqueries, historyQuery)
+		} else if !b.mustMerge {
 			subqueries = append(subqueries, b.storeInsertSQL)
 		} else {
-			mergeQuery, err := renderQueryTemplate(b.target, t.templates.storeUpdate, b.storeMergeBounds.Build(), t.objAndArrayAsJson)
+			mergeQuery, err := renderQueryTemplate(b.target, t.templates.storeUpdate, b.storeMergeBounds.Build(), t.objAndArrayAsJson, b.storeStagingTable)
 			if err != nil {
 				return fmt.Errorf("rendering merge query template: %w", err)
 			}
 			subqueries = append(subqueries, mergeQuery)
 		}
 
+		if t.cfg.StorageWriteAPI {
+			subqueries = append(subqueries, fmt.Sprintf("DELETE FROM %s WHERE TRUE;", b.storeStagingTable))
+		}
+
 		// Reset for the next round.
 		b.hasData = false
 		b.mustMerge = false
@@ -417,4 +565,7 @@ func (t *transactor) commit(ctx context.Context, cleanupFiles []func(context.Con
 func (t *transactor) Destroy() {
 	_ = t.client.bigqueryClient.Close()
 	_ = t.client.cloudStorageClient.Close()
+	if t.client.storageWriteClient != nil {
+		_ = t.client.storageWriteClient.Close()
+	}
 }
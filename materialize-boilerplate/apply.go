@@ -2,6 +2,7 @@ package boilerplate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
@@ -38,6 +39,12 @@ type BindingUpdate struct {
 	// applied materialization spec, and is now delta updates. Some systems may need to do things
 	// like drop primary key restraints in response to this change.
 	NewlyDeltaUpdates bool
+
+	// ExistingResourceConfigJson is the resource configuration of the binding per the previously
+	// applied materialization spec, or nil if there isn't one. Systems with resource configuration
+	// that affects the materialized resource, like the partitioning of a table, may compare it to
+	// the current resource configuration to determine if the resource needs to be changed.
+	ExistingResourceConfigJson json.RawMessage
 }
 
 // Applier represents the capabilities needed for an endpoint to apply changes to materialized
@@ -142,6 +149,9 @@ func ApplyChanges(ctx context.Context, req *pm.Request_Apply, applier Applier, i
 			params := BindingUpdate{
 				NewlyDeltaUpdates: existingBinding != nil && !existingBinding.DeltaUpdates && binding.DeltaUpdates,
 			}
+			if existingBinding != nil {
+				params.ExistingResourceConfigJson = existingBinding.ResourceConfigJson
+			}
 
 			for _, field := range binding.FieldSelection.AllFields() {
 				projection := *binding.Collection.GetProjection(field)
@@ -0,0 +1,341 @@
+package stream_encode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	// Rows are buffered and written as an OCF block when either limit is reached. Each block is
+	// compressed separately, so larger blocks compress better at the cost of more connector memory.
+	defaultAvroBlockRowLimit  = 10_000
+	defaultAvroBlockByteLimit = 16 * 1024 * 1024
+
+	avroRecordName = "FlowRow"
+)
+
+type AvroCompression string
+
+const (
+	AvroUncompressed AvroCompression = goavro.CompressionNullLabel
+	AvroDeflate      AvroCompression = goavro.CompressionDeflateLabel
+	AvroSnappy       AvroCompression = goavro.CompressionSnappyLabel
+)
+
+// AvroSchema is the schema of the records written by an AvroEncoder. The column types are
+// determined in the same way as for a ParquetSchema, and are then mapped to their Avro equivalents:
+//
+//   - Integers are longs, numbers are doubles, and booleans are booleans.
+//   - Base64-encoded strings are bytes.
+//   - Strings, and objects, arrays, and fields with multiple types which are encoded as JSON, are
+//     strings.
+//   - Dates, times, and timestamps are the "date", "time-micros" and "timestamp-micros" logical
+//     types.
+//   - UUIDs are strings with the "uuid" logical type.
+//   - Durations are a 12-byte fixed with the "duration" logical type, which has the same
+//     representation as the Parquet interval type.
+//
+// Fields which are not required are a union of "null" and their type. Avro names can only contain
+// letters, digits, and underscores, so other characters in field names are replaced with
+// underscores.
+type AvroSchema []AvroSchemaElement
+
+type AvroSchemaElement struct {
+	// Name is the sanitized name of the field in the Avro schema.
+	Name     string
+	DataType ParquetDataType
+	Required bool
+}
+
+func FieldsToAvroSchema(fields []string, collection pf.CollectionSpec) AvroSchema {
+	out := make(AvroSchema, 0, len(fields))
+	used := make(map[string]bool)
+
+	for _, f := range fields {
+		p := collection.GetProjection(f)
+		el := ProjectionToParquetSchemaElement(*p)
+
+		// Disambiguate fields which sanitize to the same name. A suffixed name
+		// may itself be the name of another field, so every candidate is
+		// checked against all of the names used so far.
+		base := avroName(f)
+		name := base
+		for n := 1; used[name]; n++ {
+			name = base + "_" + strconv.Itoa(n)
+		}
+		used[name] = true
+
+		out = append(out, AvroSchemaElement{Name: name, DataType: el.DataType, Required: el.Required})
+	}
+
+	return out
+}
+
+var avroInvalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func avroName(field string) string {
+	name := avroInvalidNameChars.ReplaceAllString(field, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// avroType returns the Avro schema of the element's type, along with the name of that type as a
+// branch of a union.
+func (e AvroSchemaElement) avroType() (schema any, unionName string) {
+	switch e.DataType {
+	case PrimitiveTypeInteger:
+		return "long", "long"
+	case PrimitiveTypeNumber:
+		return "double", "double"
+	case PrimitiveTypeBoolean:
+		return "boolean", "boolean"
+	case PrimitiveTypeBinary:
+		return "bytes", "bytes"
+	case LogicalTypeString, LogicalTypeJson:
+		return "string", "string"
+	case LogicalTypeUuid:
+		// goavro has no uuid logical type and treats it as a plain string.
+		return map[string]any{"type": "string", "logicalType": "uuid"}, "string"
+	case LogicalTypeDate:
+		return map[string]any{"type": "int", "logicalType": "date"}, "int.date"
+	case LogicalTypeTime:
+		return map[string]any{"type": "long", "logicalType": "time-micros"}, "long.time-micros"
+	case LogicalTypeTimestamp:
+		return map[string]any{"type": "long", "logicalType": "timestamp-micros"}, "long.timestamp-micros"
+	case LogicalTypeInterval:
+		name := e.Name + "_duration"
+		return map[string]any{"type": "fixed", "name": name, "size": 12, "logicalType": "duration"}, name
+	case LogicalTypeUnknown:
+		return "null", "null"
+	default:
+		panic(fmt.Sprintf("avroType unknown type: %d", e.DataType))
+	}
+}
+
+// JSON returns the Avro schema as JSON.
+func (s AvroSchema) JSON() (string, error) {
+	fields := make([]map[string]any, 0, len(s))
+	for _, e := range s {
+		typ, _ := e.avroType()
+		field := map[string]any{"name": e.Name}
+		if e.Required || e.DataType == LogicalTypeUnknown {
+			field["type"] = typ
+		} else {
+			field["type"] = []any{"null", typ}
+			field["default"] = nil
+		}
+		fields = append(fields, field)
+	}
+
+	out, err := json.Marshal(map[string]any{
+		"type":   "record",
+		"name":   avroRecordName,
+		"fields": fields,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+type avroConfig struct {
+	compression   AvroCompression
+	blockRowLimit int
+}
+
+type AvroOption func(*avroConfig)
+
+func WithAvroCompression(c AvroCompression) AvroOption {
+	return func(cfg *avroConfig) {
+		cfg.compression = c
+	}
+}
+
+func WithAvroBlockRowLimit(n int) AvroOption {
+	return func(cfg *avroConfig) {
+		cfg.blockRowLimit = n
+	}
+}
+
+// AvroEncoder encodes rows as records of an Avro object container file.
+type AvroEncoder struct {
+	cfg        avroConfig
+	cwc        *countingWriteCloser
+	sch        AvroSchema
+	unionNames []string
+	ocf        *goavro.OCFWriter
+
+	buffer          []any
+	bufferSizeBytes int
+}
+
+// NewAvroEncoder creates an AvroEncoder from w. w is closed when the AvroEncoder is closed.
+func NewAvroEncoder(w io.WriteCloser, sch AvroSchema, opts ...AvroOption) (*AvroEncoder, error) {
+	cfg := avroConfig{
+		compression:   AvroSnappy,
+		blockRowLimit: defaultAvroBlockRowLimit,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	schemaJSON, err := sch.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("building avro schema: %w", err)
+	}
+
+	codec, err := goavro.NewCodec(schemaJSON)
+	if err != nil {
+		return nil, fmt.Errorf("creating avro codec: %w", err)
+	}
+
+	cwc := &countingWriteCloser{w: w}
+	ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               cwc,
+		Codec:           codec,
+		CompressionName: string(cfg.compression),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating avro OCF writer: %w", err)
+	}
+
+	unionNames := make([]string, 0, len(sch))
+	for _, e := range sch {
+		_, name := e.avroType()
+		unionNames = append(unionNames, name)
+	}
+
+	return &AvroEncoder{
+		cfg:        cfg,
+		cwc:        cwc,
+		sch:        sch,
+		unionNames: unionNames,
+		ocf:        ocf,
+	}, nil
+}
+
+func (e *AvroEncoder) Encode(row []any) error {
+	if len(row) != len(e.sch) {
+		return fmt.Errorf("row has %d values but schema has %d fields", len(row), len(e.sch))
+	}
+
+	record := make(map[string]any, len(row))
+	for idx, val := range row {
+		el := e.sch[idx]
+		if val == nil {
+			if el.Required && el.DataType != LogicalTypeUnknown {
+				return fmt.Errorf("required field %q has a null value", el.Name)
+			}
+			record[el.Name] = nil
+			continue
+		}
+
+		native, size, err := avroNativeValue(el.DataType, val)
+		if err != nil {
+			return fmt.Errorf("converting value of field %q: %w", el.Name, err)
+		}
+		e.bufferSizeBytes += size
+
+		if el.Required || el.DataType == LogicalTypeUnknown {
+			record[el.Name] = native
+		} else {
+			record[el.Name] = goavro.Union(e.unionNames[idx], native)
+		}
+	}
+
+	e.buffer = append(e.buffer, record)
+	if len(e.buffer) >= e.cfg.blockRowLimit || e.bufferSizeBytes >= defaultAvroBlockByteLimit {
+		return e.flush()
+	}
+
+	return nil
+}
+
+func (e *AvroEncoder) flush() error {
+	if len(e.buffer) == 0 {
+		return nil
+	}
+
+	if err := e.ocf.Append(e.buffer); err != nil {
+		return fmt.Errorf("appending avro block: %w", err)
+	}
+
+	clear(e.buffer)
+	e.buffer = e.buffer[:0]
+	e.bufferSizeBytes = 0
+
+	return nil
+}
+
+// Written returns the number of bytes of blocks that have been written. Buffered rows that have not
+// yet been written as a block are not included.
+func (e *AvroEncoder) Written() int {
+	return e.cwc.written
+}
+
+// Close writes any buffered rows and closes the underlying io.WriteCloser.
+func (e *AvroEncoder) Close() error {
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	if err := e.cwc.Close(); err != nil {
+		return fmt.Errorf("closing counting writer: %w", err)
+	}
+	return nil
+}
+
+// avroNativeValue converts a value to the native goavro representation of the data type, also
+// returning its approximate size in bytes.
+func avroNativeValue(dt ParquetDataType, val any) (any, int, error) {
+	switch dt {
+	case PrimitiveTypeInteger:
+		v, err := getIntVal(val)
+		return v, 8, err
+	case PrimitiveTypeNumber:
+		v, err := getNumberVal(val)
+		return v, 8, err
+	case PrimitiveTypeBoolean:
+		v, err := getBooleanVal(val)
+		return v, 1, err
+	case PrimitiveTypeBinary:
+		v, err := getBinaryVal(val)
+		return []byte(v), len(v), err
+	case LogicalTypeJson:
+		v, err := getJsonVal(val)
+		return string(v), len(v), err
+	case LogicalTypeString, LogicalTypeUuid:
+		// UUIDs are validated by parsing them, even though they are written as strings.
+		if dt == LogicalTypeUuid {
+			if _, err := getUuidVal(val); err != nil {
+				return nil, 0, err
+			}
+		}
+		v, err := getStringVal(val)
+		return string(v), len(v), err
+	case LogicalTypeDate:
+		v, err := getDateVal(val)
+		return v, 4, err
+	case LogicalTypeTime:
+		v, err := getTimeVal(val)
+		return v, 8, err
+	case LogicalTypeTimestamp:
+		v, err := getTimestampVal(val)
+		return v, 8, err
+	case LogicalTypeInterval:
+		v, err := getIntervalVal(val)
+		return []byte(v), 12, err
+	case LogicalTypeUnknown:
+		return nil, 0, nil
+	default:
+		return nil, 0, fmt.Errorf("avroNativeValue unknown type: %d", dt)
+	}
+}
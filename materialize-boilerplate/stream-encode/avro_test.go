@@ -0,0 +1,158 @@
+package stream_encode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvroEncoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		nulls bool
+		opts  []AvroOption
+	}{
+		{
+			name:  "required values",
+			nulls: false,
+			opts:  nil,
+		},
+		{
+			name:  "optional values",
+			nulls: true,
+			opts:  nil,
+		},
+		{
+			name:  "small blocks uncompressed",
+			nulls: false,
+			opts:  []AvroOption{WithAvroBlockRowLimit(1), WithAvroCompression(AvroUncompressed)},
+		},
+		{
+			name:  "deflate",
+			nulls: false,
+			opts:  []AvroOption{WithAvroCompression(AvroDeflate)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := &testWriter{w: &buf}
+
+			enc, err := NewAvroEncoder(tw, makeTestAvroSchema(!tt.nulls), tt.opts...)
+			require.NoError(t, err)
+			for i := 0; i < 10; i++ {
+				row := makeTestRow(t, i)
+				if tt.nulls {
+					row[i] = nil
+				}
+				require.NoError(t, enc.Encode(row))
+			}
+			require.NoError(t, enc.Close())
+			require.True(t, tw.closed)
+			require.Equal(t, buf.Len(), enc.Written())
+
+			cupaloy.SnapshotT(t, readAvroFile(t, buf.Bytes()))
+		})
+	}
+}
+
+func TestAvroEncoderErrors(t *testing.T) {
+	enc, err := NewAvroEncoder(&testWriter{w: &bytes.Buffer{}}, makeTestAvroSchema(true))
+	require.NoError(t, err)
+
+	require.ErrorContains(t, enc.Encode([]any{1}), "row has 1 values but schema has 11 fields")
+
+	row := makeTestRow(t, 0)
+	row[4] = nil
+	require.ErrorContains(t, enc.Encode(row), `required field "stringField" has a null value`)
+
+	row = makeTestRow(t, 0)
+	row[5] = "not a uuid"
+	require.ErrorContains(t, enc.Encode(row), `converting value of field "uuidField"`)
+}
+
+func TestFieldsToAvroSchema(t *testing.T) {
+	collection := pf.CollectionSpec{
+		Projections: []pf.Projection{
+			{Field: "1st", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"boolean"}}},
+			{Field: "flow_document", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"object"}}},
+			{Field: "key", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"integer"}}},
+			{Field: "nested/value", Inference: pf.Inference{Types: []string{"string", "null"}, String_: &pf.Inference_String{}}},
+			{Field: "nested_value", Inference: pf.Inference{Types: []string{"number"}}},
+		},
+	}
+
+	got := FieldsToAvroSchema([]string{"key", "nested/value", "nested_value", "1st", "flow_document"}, collection)
+	require.Equal(t, AvroSchema{
+		{Name: "key", DataType: PrimitiveTypeInteger, Required: true},
+		{Name: "nested_value", DataType: LogicalTypeString, Required: false},
+		{Name: "nested_value_1", DataType: PrimitiveTypeNumber, Required: false},
+		{Name: "_1st", DataType: PrimitiveTypeBoolean, Required: true},
+		{Name: "flow_document", DataType: LogicalTypeJson, Required: true},
+	}, got)
+
+	sch, err := got.JSON()
+	require.NoError(t, err)
+	_, err = goavro.NewCodec(sch)
+	require.NoError(t, err)
+
+	// Suffixed names don't collide with the names of other fields.
+	collection = pf.CollectionSpec{
+		Projections: []pf.Projection{
+			{Field: "a-b", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"integer"}}},
+			{Field: "a.b", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"integer"}}},
+			{Field: "a_b", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"integer"}}},
+			{Field: "a_b_1", Inference: pf.Inference{Exists: pf.Inference_MUST, Types: []string{"integer"}}},
+		},
+	}
+
+	got = FieldsToAvroSchema([]string{"a_b", "a-b", "a_b_1", "a.b"}, collection)
+	require.Equal(t, AvroSchema{
+		{Name: "a_b", DataType: PrimitiveTypeInteger, Required: true},
+		{Name: "a_b_1", DataType: PrimitiveTypeInteger, Required: true},
+		{Name: "a_b_1_1", DataType: PrimitiveTypeInteger, Required: true},
+		{Name: "a_b_2", DataType: PrimitiveTypeInteger, Required: true},
+	}, got)
+
+	sch, err = got.JSON()
+	require.NoError(t, err)
+	_, err = goavro.NewCodec(sch)
+	require.NoError(t, err)
+}
+
+func makeTestAvroSchema(required bool) AvroSchema {
+	var out AvroSchema
+	for _, e := range makeTestParquetSchema(required) {
+		out = append(out, AvroSchemaElement{Name: e.Name, DataType: e.DataType, Required: e.Required})
+	}
+	return out
+}
+
+// readAvroFile decodes the records of an Avro OCF file as JSON lines, preceded by the file's
+// schema.
+func readAvroFile(t *testing.T, b []byte) string {
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	out.WriteString(r.Codec().Schema())
+	out.WriteString("\n")
+	for r.Scan() {
+		datum, err := r.Read()
+		require.NoError(t, err)
+		j, err := json.Marshal(datum)
+		require.NoError(t, err)
+		fmt.Fprintf(&out, "%s\n", j)
+	}
+	require.NoError(t, r.Err())
+
+	return out.String()
+}
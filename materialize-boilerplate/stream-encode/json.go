@@ -7,6 +7,7 @@ import (
 
 	"github.com/estuary/connectors/go/encrow"
 	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
 	"github.com/segmentio/encoding/json"
 )
 
@@ -26,6 +27,7 @@ const (
 
 type jsonConfig struct {
 	disableCompression bool
+	zstdCompression    bool
 	skipNulls          bool
 }
 
@@ -37,6 +39,13 @@ func WithJsonDisableCompression() JsonOption {
 	}
 }
 
+// WithJsonZstdCompression compresses the output with zstd rather than gzip.
+func WithJsonZstdCompression() JsonOption {
+	return func(cfg *jsonConfig) {
+		cfg.zstdCompression = true
+	}
+}
+
 func WithJsonSkipNulls() JsonOption {
 	return func(cfg *jsonConfig) {
 		cfg.skipNulls = true
@@ -44,9 +53,9 @@ func WithJsonSkipNulls() JsonOption {
 }
 
 type JsonEncoder struct {
-	w     io.Writer // will be set to `gz` for compressed writes or `cwc` if compression is disabled
+	w     io.Writer // will be set to `comp` for compressed writes or `cwc` if compression is disabled
 	cwc   *countingWriteCloser
-	gz    *gzip.Writer
+	comp  io.WriteCloser // gzip or zstd writer, if compression is enabled
 	shape *encrow.Shape
 	buf   []byte
 }
@@ -63,16 +72,24 @@ func NewJsonEncoder(w io.WriteCloser, fields []string, opts ...JsonOption) *Json
 		cwc: &countingWriteCloser{w: w},
 	}
 
-	if !cfg.disableCompression {
+	if cfg.disableCompression {
+		enc.w = enc.cwc
+	} else if cfg.zstdCompression {
+		zw, err := zstd.NewWriter(enc.cwc, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			// Only possible if the options are not valid.
+			panic(fmt.Sprintf("invalid options for zstd.NewWriter: %v", err))
+		}
+		enc.comp = zw
+		enc.w = zw
+	} else {
 		gz, err := gzip.NewWriterLevel(enc.cwc, jsonCompressionlevel)
 		if err != nil {
 			// Only possible if compressionLevel is not valid.
 			panic("invalid compression level for gzip.NewWriterLevel")
 		}
-		enc.gz = gz
+		enc.comp = gz
 		enc.w = gz
-	} else {
-		enc.w = enc.cwc
 	}
 
 	if fields != nil {
@@ -116,7 +133,7 @@ func (e *JsonEncoder) Encode(vals []any) (err error) {
 	e.buf = append(e.buf, '\n')
 
 	if _, err := e.w.Write(e.buf); err != nil {
-		return fmt.Errorf("writing gzip bytes: %w", err)
+		return fmt.Errorf("writing JSON bytes: %w", err)
 	}
 
 	return nil
@@ -126,13 +143,13 @@ func (e *JsonEncoder) Written() int {
 	return e.cwc.written
 }
 
-// Close closes the underlying gzip writer if compression is enabled, flushing its data and writing
-// the GZIP footer. It also closes the underlying io.WriteCloser that was used to initialize the
+// Close closes the underlying gzip or zstd writer if compression is enabled, flushing its data and
+// writing the compression footer. It also closes the underlying io.WriteCloser that was used to initialize the
 // counting encoder.
 func (e *JsonEncoder) Close() error {
-	if e.gz != nil {
-		if err := e.gz.Close(); err != nil {
-			return fmt.Errorf("closing gzip writer: %w", err)
+	if e.comp != nil {
+		if err := e.comp.Close(); err != nil {
+			return fmt.Errorf("closing compression writer: %w", err)
 		}
 	}
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/bradleyjkemp/cupaloy"
 	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/require"
 )
 
@@ -85,14 +86,19 @@ func TestJsonEncoderInputs(t *testing.T) {
 			require.NoError(t, err)
 			require.NoError(t, gzw.Close())
 
-			for _, compress := range []bool{true, false} {
+			for _, compression := range []string{"gzip", "zstd", "none"} {
 				var buf bytes.Buffer
 				tw := &testWriter{
 					w: &buf,
 				}
 
-				enc := NewJsonEncoder(tw, tt.fields)
-				if !compress {
+				var enc *JsonEncoder
+				switch compression {
+				case "gzip":
+					enc = NewJsonEncoder(tw, tt.fields)
+				case "zstd":
+					enc = NewJsonEncoder(tw, tt.fields, WithJsonZstdCompression())
+				case "none":
 					enc = NewJsonEncoder(tw, tt.fields, WithJsonDisableCompression())
 				}
 				require.NoError(t, enc.Encode(tt.input))
@@ -101,14 +107,22 @@ func TestJsonEncoderInputs(t *testing.T) {
 				// The provided writer is closed when enc is closed.
 				require.True(t, tw.closed)
 
-				if compress {
+				switch compression {
+				case "gzip":
 					// The written bytes can be unzip'd correctly.
 					r, err := gzip.NewReader(&buf)
 					require.NoError(t, err)
 					gotBytes, err := io.ReadAll(r)
 					require.NoError(t, err)
 					require.Equal(t, string(tt.wantBytes), string(gotBytes))
-				} else {
+				case "zstd":
+					r, err := zstd.NewReader(&buf)
+					require.NoError(t, err)
+					gotBytes, err := io.ReadAll(r)
+					r.Close()
+					require.NoError(t, err)
+					require.Equal(t, string(tt.wantBytes), string(gotBytes))
+				case "none":
 					require.Equal(t, string(tt.wantBytes), buf.String())
 				}
 			}
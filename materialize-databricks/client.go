@@ -12,15 +12,12 @@ import (
 	"time"
 
 	"github.com/databricks/databricks-sdk-go"
-	dbConfig "github.com/databricks/databricks-sdk-go/config"
 	"github.com/databricks/databricks-sdk-go/service/catalog"
 	databricksSql "github.com/databricks/databricks-sdk-go/service/sql"
 	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
 	log "github.com/sirupsen/logrus"
-
-	_ "github.com/databricks/databricks-sql-go"
 )
 
 var _ sql.SchemaManager = (*client)(nil)
@@ -35,16 +32,12 @@ type client struct {
 func newClient(ctx context.Context, ep *sql.Endpoint) (sql.Client, error) {
 	cfg := ep.Config.(*config)
 
-	db, err := stdsql.Open("databricks", cfg.ToURI())
+	db, err := cfg.openDB()
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
 
-	wsClient, err := databricks.NewWorkspaceClient(&databricks.Config{
-		Host:        fmt.Sprintf("%s/%s", cfg.Address, cfg.HTTPPath),
-		Token:       cfg.Credentials.PersonalAccessToken,
-		Credentials: dbConfig.PatCredentials{}, // enforce PAT auth
-	})
+	wsClient, err := databricks.NewWorkspaceClient(cfg.sdkConfig())
 	if err != nil {
 		return nil, fmt.Errorf("creating workspace client: %w", err)
 	}
@@ -139,6 +132,37 @@ func (c *client) DeleteTable(ctx context.Context, path []string) (string, boiler
 func (c *client) AlterTable(ctx context.Context, ta sql.TableAlter) (string, boilerplate.ActionApplyFn, error) {
 	var stmts []string
 
+	if ta.LayoutChanged {
+		var prevPartitionBy, partitionBy []string
+		if ta.PreviousLayout != nil {
+			prevPartitionBy = ta.PreviousLayout.PartitionBy
+		}
+		if ta.Layout != nil {
+			partitionBy = ta.Layout.PartitionBy
+		}
+
+		// The partitioning of a Delta table is fixed when it is created, and a
+		// table can't be both partitioned and use Liquid Clustering.
+		if !slices.Equal(prevPartitionBy, partitionBy) {
+			return "", nil, fmt.Errorf(
+				"the partitioning of table %s cannot be changed: the binding must be backfilled for the new partitioning to take effect",
+				ta.Identifier,
+			)
+		}
+
+		if len(partitionBy) == 0 {
+			if ta.LayoutColumns == nil || len(ta.LayoutColumns.ClusterBy) == 0 {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s CLUSTER BY NONE;", ta.Identifier))
+			} else {
+				var cols []string
+				for _, col := range ta.LayoutColumns.ClusterBy {
+					cols = append(cols, col.Identifier)
+				}
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s CLUSTER BY (%s);", ta.Identifier, strings.Join(cols, ", ")))
+			}
+		}
+	}
+
 	// Databricks doesn't support multi-statement queries with the driver we are using, and also
 	// doesn't support dropping nullability for multiple columns in a single statement. Multiple
 	// columns can be added in a single statement though.
@@ -206,17 +230,13 @@ func preReqs(ctx context.Context, conf any, tenant string) *sql.PrereqErr {
 	errs := &sql.PrereqErr{}
 
 	cfg := conf.(*config)
-	wsClient, err := databricks.NewWorkspaceClient(&databricks.Config{
-		Host:        fmt.Sprintf("%s/%s", cfg.Address, cfg.HTTPPath),
-		Token:       cfg.Credentials.PersonalAccessToken,
-		Credentials: dbConfig.PatCredentials{}, // enforce PAT auth
-	})
+	wsClient, err := databricks.NewWorkspaceClient(cfg.sdkConfig())
 	if err != nil {
 		errs.Err(fmt.Errorf("creating workspace client: %w", err))
 		return errs
 	}
 
-	db, err := stdsql.Open("databricks", cfg.ToURI())
+	db, err := cfg.openDB()
 	if err != nil {
 		errs.Err(fmt.Errorf("opening database: %w", err))
 		return errs
@@ -1,10 +1,18 @@
 package main
 
 import (
+	stdsql "database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
-	"net/url"
+	"net"
+	"strconv"
 	"strings"
 
+	"github.com/databricks/databricks-sdk-go"
+	dbConfig "github.com/databricks/databricks-sdk-go/config"
+	dbsql "github.com/databricks/databricks-sql-go"
+
 	"github.com/estuary/connectors/go/dbt"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	"github.com/invopop/jsonschema"
@@ -21,23 +29,43 @@ type config struct {
 	Credentials   credentialConfig           `json:"credentials" jsonschema:"title=Authentication" jsonschema_extras:"order=5"`
 	Schedule      boilerplate.ScheduleConfig `json:"syncSchedule,omitempty" jsonschema:"title=Sync Schedule,description=Configure schedule of transactions for the materialization."`
 	DBTJobTrigger dbt.JobConfig              `json:"dbt_job_trigger,omitempty" jsonschema:"title=dbt Cloud Job Trigger,description=Trigger a dbt Job when new data is available"`
+
+	// The shared Databricks SDK configuration, built by sdkConfig.
+	sdk *databricks.Config
 }
 
 const (
-	// TODO: support Azure, GCP and OAuth authentication
-	PAT_AUTH_TYPE = "PAT" // personal access token
+	PAT_AUTH_TYPE                 = "PAT"                 // personal access token
+	OAUTH_M2M_AUTH_TYPE           = "OAUTH_M2M"           // OAuth machine-to-machine with a Databricks service principal
+	AZURE_CLIENT_SECRET_AUTH_TYPE = "AZURE_CLIENT_SECRET" // Azure AD service principal
+	GCP_SERVICE_ACCOUNT_AUTH_TYPE = "GCP_SERVICE_ACCOUNT" // Google Cloud service account
 )
 
 type credentialConfig struct {
 	AuthType string `json:"auth_type"`
 
-	PersonalAccessToken string `json:"personal_access_token"`
+	PersonalAccessToken string `json:"personal_access_token,omitempty"`
+
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+
+	AzureTenantID     string `json:"azure_tenant_id,omitempty"`
+	AzureClientID     string `json:"azure_client_id,omitempty"`
+	AzureClientSecret string `json:"azure_client_secret,omitempty"`
+
+	GoogleCredentials string `json:"google_credentials_json,omitempty"`
 }
 
 func (c *credentialConfig) Validate() error {
 	switch c.AuthType {
 	case PAT_AUTH_TYPE:
 		return c.validatePATCreds()
+	case OAUTH_M2M_AUTH_TYPE:
+		return c.validateOAuthM2MCreds()
+	case AZURE_CLIENT_SECRET_AUTH_TYPE:
+		return c.validateAzureCreds()
+	case GCP_SERVICE_ACCOUNT_AUTH_TYPE:
+		return c.validateGCPCreds()
 	default:
 		return fmt.Errorf("invalid credentials auth type %q", c.AuthType)
 	}
@@ -51,22 +79,105 @@ func (c *credentialConfig) validatePATCreds() error {
 	return nil
 }
 
+func (c *credentialConfig) validateOAuthM2MCreds() error {
+	if c.ClientID == "" {
+		return fmt.Errorf("missing client_id")
+	} else if c.ClientSecret == "" {
+		return fmt.Errorf("missing client_secret")
+	}
+
+	return nil
+}
+
+func (c *credentialConfig) validateAzureCreds() error {
+	if c.AzureTenantID == "" {
+		return fmt.Errorf("missing azure_tenant_id")
+	} else if c.AzureClientID == "" {
+		return fmt.Errorf("missing azure_client_id")
+	} else if c.AzureClientSecret == "" {
+		return fmt.Errorf("missing azure_client_secret")
+	}
+
+	return nil
+}
+
+func (c *credentialConfig) validateGCPCreds() error {
+	if c.GoogleCredentials == "" {
+		return fmt.Errorf("missing google_credentials_json")
+	} else if !json.Valid([]byte(c.GoogleCredentials)) {
+		return fmt.Errorf("google_credentials_json must be valid JSON")
+	}
+
+	return nil
+}
+
 // JSONSchema allows for the schema to be (semi-)manually specified when used with the
 // github.com/invopop/jsonschema package in go-schema-gen, to fullfill the required schema shape for
 // our oauth
 func (credentialConfig) JSONSchema() *jsonschema.Schema {
+	authTypeSchema := func(authType string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Type:    "string",
+			Default: authType,
+			Const:   authType,
+		}
+	}
+	secretSchema := func(title, description string) *jsonschema.Schema {
+		return &jsonschema.Schema{
+			Title:       title,
+			Description: description,
+			Type:        "string",
+			Extras: map[string]interface{}{
+				"secret": true,
+			},
+		}
+	}
+
 	patProps := orderedmap.New[string, *jsonschema.Schema]()
-	patProps.Set("auth_type", &jsonschema.Schema{
-		Type:    "string",
-		Default: PAT_AUTH_TYPE,
-		Const:   PAT_AUTH_TYPE,
+	patProps.Set("auth_type", authTypeSchema(PAT_AUTH_TYPE))
+	patProps.Set("personal_access_token", secretSchema(
+		"Personal Access Token",
+		"Personal Access Token,description=Your personal access token for accessing the SQL warehouse",
+	))
+
+	m2mProps := orderedmap.New[string, *jsonschema.Schema]()
+	m2mProps.Set("auth_type", authTypeSchema(OAUTH_M2M_AUTH_TYPE))
+	m2mProps.Set("client_id", &jsonschema.Schema{
+		Title:       "Client ID",
+		Description: "The client ID (application ID) of the Databricks service principal.",
+		Type:        "string",
 	})
-	patProps.Set("personal_access_token", &jsonschema.Schema{
-		Title:       "Personal Access Token",
-		Description: "Personal Access Token,description=Your personal access token for accessing the SQL warehouse",
+	m2mProps.Set("client_secret", secretSchema(
+		"Client Secret",
+		"An OAuth secret generated for the Databricks service principal.",
+	))
+
+	azureProps := orderedmap.New[string, *jsonschema.Schema]()
+	azureProps.Set("auth_type", authTypeSchema(AZURE_CLIENT_SECRET_AUTH_TYPE))
+	azureProps.Set("azure_tenant_id", &jsonschema.Schema{
+		Title:       "Tenant ID",
+		Description: "The ID of the Azure AD tenant of the service principal.",
+		Type:        "string",
+	})
+	azureProps.Set("azure_client_id", &jsonschema.Schema{
+		Title:       "Client ID",
+		Description: "The application (client) ID of the Azure AD service principal.",
+		Type:        "string",
+	})
+	azureProps.Set("azure_client_secret", secretSchema(
+		"Client Secret",
+		"A client secret of the Azure AD service principal.",
+	))
+
+	gcpProps := orderedmap.New[string, *jsonschema.Schema]()
+	gcpProps.Set("auth_type", authTypeSchema(GCP_SERVICE_ACCOUNT_AUTH_TYPE))
+	gcpProps.Set("google_credentials_json", &jsonschema.Schema{
+		Title:       "Service Account JSON",
+		Description: "The JSON key of a Google Cloud service account that has been added to the Databricks workspace.",
 		Type:        "string",
 		Extras: map[string]interface{}{
-			"secret": true,
+			"secret":    true,
+			"multiline": true,
 		},
 	})
 
@@ -80,6 +191,21 @@ func (credentialConfig) JSONSchema() *jsonschema.Schema {
 				Required:   []string{"auth_type", "personal_access_token"},
 				Properties: patProps,
 			},
+			{
+				Title:      "OAuth Service Principal",
+				Required:   []string{"auth_type", "client_id", "client_secret"},
+				Properties: m2mProps,
+			},
+			{
+				Title:      "Azure AD Service Principal",
+				Required:   []string{"auth_type", "azure_tenant_id", "azure_client_id", "azure_client_secret"},
+				Properties: azureProps,
+			},
+			{
+				Title:      "Google Cloud Service Account",
+				Required:   []string{"auth_type", "google_credentials_json"},
+				Properties: gcpProps,
+			},
 		},
 		Extras: map[string]interface{}{
 			"discriminator": map[string]string{"propertyName": "auth_type"},
@@ -113,24 +239,90 @@ func (c *config) Validate() error {
 	return c.Credentials.Validate()
 }
 
-// ToURI puts together address and http_path to form the full workspace URL
-func (c *config) ToURI() string {
+// host returns the address of the workspace, including the port.
+func (c *config) host() (string, int, error) {
 	var address = c.Address
 	if !strings.Contains(address, ":") {
 		address = address + ":" + defaultPort
 	}
 
-	var params = make(url.Values)
-	params.Add("catalog", c.CatalogName)
-	params.Add("schema", c.SchemaName)
-	params.Add("userAgentEntry", "Estuary Technologies Flow")
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid address %q: %w", c.Address, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in address %q: %w", c.Address, err)
+	}
+
+	return host, port, nil
+}
+
+// sdkConfig returns the Databricks SDK configuration for the configured
+// credentials. The SDK configuration handles obtaining and refreshing OAuth
+// tokens for the authentication types that use them, and is also used to
+// authenticate requests made by the SQL driver. It is built once and shared by
+// the workspace clients and database handles of the connector, so that they
+// share the same tokens.
+func (c *config) sdkConfig() *databricks.Config {
+	if c.sdk != nil {
+		return c.sdk
+	}
+
+	var cfg = &databricks.Config{
+		Host:               fmt.Sprintf("%s/%s", c.Address, c.HTTPPath),
+		HTTPTimeoutSeconds: 5 * 60, // This is necessary for file uploads as they can sometimes take longer than the default 60s
+	}
+
+	// Each authentication type is enforced explicitly so that the SDK does not
+	// fall back to picking up credentials from the environment.
+	switch c.Credentials.AuthType {
+	case PAT_AUTH_TYPE:
+		cfg.Token = c.Credentials.PersonalAccessToken
+		cfg.Credentials = dbConfig.PatCredentials{}
+	case OAUTH_M2M_AUTH_TYPE:
+		cfg.ClientID = c.Credentials.ClientID
+		cfg.ClientSecret = c.Credentials.ClientSecret
+		cfg.Credentials = dbConfig.M2mCredentials{}
+	case AZURE_CLIENT_SECRET_AUTH_TYPE:
+		cfg.AzureTenantID = c.Credentials.AzureTenantID
+		cfg.AzureClientID = c.Credentials.AzureClientID
+		cfg.AzureClientSecret = c.Credentials.AzureClientSecret
+		cfg.Credentials = dbConfig.AzureClientSecretCredentials{}
+	case GCP_SERVICE_ACCOUNT_AUTH_TYPE:
+		cfg.GoogleCredentials = c.Credentials.GoogleCredentials
+		cfg.Credentials = dbConfig.GoogleCredentials{}
+	}
+	c.sdk = cfg
+
+	return cfg
+}
+
+// connector returns a connector for the SQL warehouse. A new connector should
+// be created for each database handle, and the credentials of the handle are
+// refreshed as needed for as long as it is open.
+func (c *config) connector() (driver.Connector, error) {
+	host, port, err := c.host()
+	if err != nil {
+		return nil, err
+	}
+
+	return dbsql.NewConnector(
+		dbsql.WithServerHostname(host),
+		dbsql.WithPort(port),
+		dbsql.WithHTTPPath(c.HTTPPath),
+		dbsql.WithInitialNamespace(c.CatalogName, c.SchemaName),
+		dbsql.WithUserAgentEntry("Estuary Technologies Flow"),
+		dbsql.WithAuthenticator((*dbConfig.Config)(c.sdkConfig())),
+	)
+}
 
-	var uri = url.URL{
-		Host:     address,
-		Path:     c.HTTPPath,
-		User:     url.UserPassword("token", c.Credentials.PersonalAccessToken),
-		RawQuery: params.Encode(),
+// openDB opens a database handle for the SQL warehouse.
+func (c *config) openDB() (*stdsql.DB, error) {
+	connector, err := c.connector()
+	if err != nil {
+		return nil, err
 	}
 
-	return strings.TrimLeft(uri.String(), "/")
+	return stdsql.OpenDB(connector), nil
 }
@@ -22,14 +22,18 @@ func TestDatabricksConfig(t *testing.T) {
 		SchemaName: "default",
 	}
 	require.NoError(t, validConfig.Validate())
-	var uri = validConfig.ToURI()
-	require.Equal(t, "token:secret@db-something.cloud.databricks.com:400/sql/1.0/warehouses/someid?catalog=mycatalog&schema=default&userAgentEntry=Estuary+Technologies+Flow", uri)
+	host, port, err := validConfig.host()
+	require.NoError(t, err)
+	require.Equal(t, "db-something.cloud.databricks.com", host)
+	require.Equal(t, 400, port)
 
 	var noPort = validConfig
 	noPort.Address = "db-something.cloud.databricks.com"
 	require.NoError(t, noPort.Validate())
-	uri = noPort.ToURI()
-	require.Equal(t, "token:secret@db-something.cloud.databricks.com:443/sql/1.0/warehouses/someid?catalog=mycatalog&schema=default&userAgentEntry=Estuary+Technologies+Flow", uri)
+	host, port, err = noPort.host()
+	require.NoError(t, err)
+	require.Equal(t, "db-something.cloud.databricks.com", host)
+	require.Equal(t, 443, port)
 
 	var noAddress = validConfig
 	noAddress.Address = ""
@@ -52,6 +56,79 @@ func TestDatabricksConfig(t *testing.T) {
 	require.Error(t, noSchema.Validate(), "expected validation error")
 }
 
+func TestDatabricksCredentials(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		creds    credentialConfig
+		wantErr  string
+		wantAuth string
+	}{
+		{
+			name:     "pat",
+			creds:    credentialConfig{AuthType: PAT_AUTH_TYPE, PersonalAccessToken: "secret"},
+			wantAuth: "pat",
+		},
+		{
+			name:     "oauth m2m",
+			creds:    credentialConfig{AuthType: OAUTH_M2M_AUTH_TYPE, ClientID: "id", ClientSecret: "secret"},
+			wantAuth: "oauth-m2m",
+		},
+		{
+			name:    "oauth m2m missing secret",
+			creds:   credentialConfig{AuthType: OAUTH_M2M_AUTH_TYPE, ClientID: "id"},
+			wantErr: "missing client_secret",
+		},
+		{
+			name: "azure",
+			creds: credentialConfig{
+				AuthType:          AZURE_CLIENT_SECRET_AUTH_TYPE,
+				AzureTenantID:     "tenant",
+				AzureClientID:     "id",
+				AzureClientSecret: "secret",
+			},
+			wantAuth: "azure-client-secret",
+		},
+		{
+			name:    "azure missing tenant",
+			creds:   credentialConfig{AuthType: AZURE_CLIENT_SECRET_AUTH_TYPE, AzureClientID: "id", AzureClientSecret: "secret"},
+			wantErr: "missing azure_tenant_id",
+		},
+		{
+			name:     "gcp",
+			creds:    credentialConfig{AuthType: GCP_SERVICE_ACCOUNT_AUTH_TYPE, GoogleCredentials: `{"type":"service_account"}`},
+			wantAuth: "google-credentials",
+		},
+		{
+			name:    "gcp invalid json",
+			creds:   credentialConfig{AuthType: GCP_SERVICE_ACCOUNT_AUTH_TYPE, GoogleCredentials: "not json"},
+			wantErr: "google_credentials_json must be valid JSON",
+		},
+		{
+			name:    "unknown",
+			creds:   credentialConfig{AuthType: "other"},
+			wantErr: "invalid credentials auth type",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config{
+				Address:     "db-something.cloud.databricks.com",
+				CatalogName: "mycatalog",
+				HTTPPath:    "/sql/1.0/warehouses/someid",
+				SchemaName:  "default",
+				Credentials: tt.creds,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAuth, cfg.sdkConfig().Credentials.Name())
+		})
+	}
+}
+
 func TestSpecification(t *testing.T) {
 	var resp, err = newDatabricksDriver().
 		Spec(context.Background(), &pm.Request_Spec{})
@@ -11,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/databricks/databricks-sdk-go"
-	dbConfig "github.com/databricks/databricks-sdk-go/config"
 	"github.com/databricks/databricks-sdk-go/logger"
 	dbsqllog "github.com/databricks/databricks-sql-go/logger"
 	m "github.com/estuary/connectors/go/protocols/materialize"
@@ -21,17 +20,21 @@ import (
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	log "github.com/sirupsen/logrus"
 	"go.gazette.dev/core/consumer/protocol"
-
-	_ "github.com/databricks/databricks-sql-go"
 )
 
 const defaultPort = "443"
 const volumeName = "flow_staging"
 
+// Databricks allows at most 4 Liquid Clustering keys for a table.
+const maxClusteringColumns = 4
+
 type tableConfig struct {
-	Table  string `json:"table" jsonschema:"title=Table,description=Name of the table" jsonschema_extras:"x-collection-name=true"`
-	Schema string `json:"schema,omitempty" jsonschema:"title=Schema,description=Schema where the table resides" jsonschema_extras:"x-schema-name=true"`
-	Delta  bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	Table         string   `json:"table" jsonschema:"title=Table,description=Name of the table" jsonschema_extras:"x-collection-name=true"`
+	Schema        string   `json:"schema,omitempty" jsonschema:"title=Schema,description=Schema where the table resides" jsonschema_extras:"x-schema-name=true"`
+	Delta         bool     `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	History       bool     `json:"history_mode,omitempty" jsonschema:"default=false,title=History Mode,description=Keep a row for every version of each document instead of only the latest version. Rows have valid_from and valid_to times and an is_current flag. Default is false."`
+	PartitionedBy []string `json:"partitioned_by,omitempty" jsonschema:"title=Partition Columns,description=Fields to partition the table by (optional). Partitioning cannot be changed once the table is created. Cannot be used with Liquid Clustering."`
+	ClusterBy     []string `json:"cluster_by,omitempty" jsonschema:"title=Liquid Clustering Columns,description=Fields to use as the Liquid Clustering keys of the table (optional). At most 4 fields may be used. Cannot be used with partitioning."`
 }
 
 func newTableConfig(ep *sql.Endpoint) sql.Resource {
@@ -53,6 +56,12 @@ func (r tableConfig) Validate() error {
 		return fmt.Errorf("schema name %q contains one of the forbidden characters %q", r.Schema, forbiddenChars)
 	}
 
+	if len(r.PartitionedBy) > 0 && len(r.ClusterBy) > 0 {
+		return fmt.Errorf("partitioned_by and cluster_by cannot both be set")
+	} else if len(r.ClusterBy) > maxClusteringColumns {
+		return fmt.Errorf("cluster_by may include at most %d fields, got %d", maxClusteringColumns, len(r.ClusterBy))
+	}
+
 	return nil
 }
 
@@ -68,6 +77,17 @@ func (c tableConfig) DeltaUpdates() bool {
 	return c.Delta
 }
 
+func (c tableConfig) HistoryMode() bool {
+	return c.History
+}
+
+func (c tableConfig) Layout() *sql.TableLayout {
+	if len(c.PartitionedBy) == 0 && len(c.ClusterBy) == 0 {
+		return nil
+	}
+	return &sql.TableLayout{PartitionBy: c.PartitionedBy, ClusterBy: c.ClusterBy}
+}
+
 func newDatabricksDriver() *sql.Driver {
 	return &sql.Driver{
 		DocumentationURL: "https://go.estuary.dev/materialize-databricks",
@@ -104,6 +124,7 @@ func newDatabricksDriver() *sql.Driver {
 
 type transactor struct {
 	cfg              *config
+	db               *stdsql.DB
 	cp               checkpoint
 	cpRecovery       bool // is this checkpoint a recovered checkpoint?
 	wsClient         *databricks.WorkspaceClient
@@ -132,12 +153,7 @@ func newTransactor(
 ) (_ m.Transactor, _ *boilerplate.MaterializeOptions, err error) {
 	var cfg = ep.Config.(*config)
 
-	wsClient, err := databricks.NewWorkspaceClient(&databricks.Config{
-		Host:               fmt.Sprintf("%s/%s", cfg.Address, cfg.HTTPPath),
-		Token:              cfg.Credentials.PersonalAccessToken,
-		Credentials:        dbConfig.PatCredentials{}, // enforce PAT auth
-		HTTPTimeoutSeconds: 5 * 60,                    // This is necessary for file uploads as they can sometimes take longer than the default 60s
-	})
+	wsClient, err := databricks.NewWorkspaceClient(cfg.sdkConfig())
 	if err != nil {
 		return nil, nil, fmt.Errorf("initialising workspace client: %w", err)
 	}
@@ -147,11 +163,16 @@ func newTransactor(
 	var httpPathSplit = strings.Split(cfg.HTTPPath, "/")
 	var warehouseId = httpPathSplit[len(httpPathSplit)-1]
 
-	db, err := stdsql.Open("databricks", d.cfg.ToURI())
+	db, err := d.cfg.openDB()
 	if err != nil {
 		return nil, nil, fmt.Errorf("sql.Open: %w", err)
 	}
-	defer db.Close()
+	defer func() {
+		if err != nil {
+			db.Close()
+		}
+	}()
+	d.db = db
 
 	schemas := map[string]struct{}{cfg.SchemaName: {}}
 	for _, binding := range bindings {
@@ -220,8 +241,8 @@ func (t *transactor) addBinding(target sql.Table) error {
 		return out
 	}
 
-	b.loadFile = newStagedFile(t.cfg, b.rootStagingPath, translatedFieldNames(target.KeyNames()))
-	b.storeFile = newStagedFile(t.cfg, b.rootStagingPath, translatedFieldNames(target.ColumnNames()))
+	b.loadFile = newStagedFile(t.db, b.rootStagingPath, translatedFieldNames(target.KeyNames()))
+	b.storeFile = newStagedFile(t.db, b.rootStagingPath, translatedFieldNames(target.ColumnNames()))
 
 	t.bindings = append(t.bindings, b)
 
@@ -273,17 +294,11 @@ func (d *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 	}
 
 	if len(queries) > 0 {
-		db, err := stdsql.Open("databricks", d.cfg.ToURI())
-		if err != nil {
-			return fmt.Errorf("sql.Open: %w", err)
-		}
-		defer db.Close()
-
 		// Issue a union join of the target tables and their (now staged) load keys,
 		// and send results to the |loaded| callback.
 		d.be.StartedEvaluatingLoads()
 		var unionQuery = strings.Join(queries, "\nUNION ALL\n")
-		rows, err := db.QueryContext(ctx, unionQuery)
+		rows, err := d.db.QueryContext(ctx, unionQuery)
 		if err != nil {
 			return fmt.Errorf("querying Load documents: %w", err)
 		}
@@ -343,7 +358,7 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 		var b = d.bindings[it.Binding]
 
 		var flowDocument = it.RawJSON
-		if d.cfg.HardDelete && it.Delete {
+		if (d.cfg.HardDelete || b.target.History != nil) && it.Delete {
 			if it.Exists {
 				flowDocument = json.RawMessage(`"delete"`)
 			} else {
@@ -389,7 +404,21 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 		// given that COPY INTO is idempotent by default: files that have already been loaded into a table will
 		// not be loaded again
 		// see https://docs.databricks.com/en/sql/language-manual/delta-copy-into.html
-		if b.target.DeltaUpdates || !b.needsMerge {
+		if b.target.History != nil {
+			// Tables in history mode are always merged into, since the current
+			// rows of existing documents must be closed out.
+			for i := 0; i < len(toCopy); i += queryBatchSize {
+				end := i + queryBatchSize
+				if end > len(toCopy) {
+					end = len(toCopy)
+				}
+				if query, err := RenderTableWithFiles(b.target, fullPaths[i:end], b.rootStagingPath, tplHistoryMerge); err != nil {
+					return nil, fmt.Errorf("historyMerge template: %w", err)
+				} else {
+					queries = append(queries, query)
+				}
+			}
+		} else if b.target.DeltaUpdates || !b.needsMerge {
 			// TODO: switch to slices.Chunk once we switch to go1.23
 			for i := 0; i < len(toCopy); i += queryBatchSize {
 				end := i + queryBatchSize
@@ -437,16 +466,6 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 // Acknowledge merges data from temporary table to main table
 // TODO: run these queries concurrently for improved performance
 func (d *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error) {
-	var db *stdsql.DB
-	if len(d.cp) > 0 {
-		var err error
-		db, err = stdsql.Open("databricks", d.cfg.ToURI())
-		if err != nil {
-			return nil, fmt.Errorf("sql.Open: %w", err)
-		}
-		defer db.Close()
-	}
-
 	for stateKey, item := range d.cp {
 		path := d.pathForStateKey(stateKey)
 		// we skip queries that belong to tables which do not have a binding anymore
@@ -464,7 +483,7 @@ func (d *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 		}
 		d.be.StartedResourceCommit(path)
 		for _, query := range queries {
-			if _, err := db.ExecContext(ctx, query); err != nil {
+			if _, err := d.db.ExecContext(ctx, query); err != nil {
 				// When doing a recovery apply, it may be the case that some tables & files have already been deleted after being applied
 				// it is okay to skip them in this case
 				if d.cpRecovery {
@@ -523,6 +542,7 @@ func pathsWithRoot(root string, paths []string) []string {
 }
 
 func (d *transactor) Destroy() {
+	d.db.Close()
 }
 
 func main() {
@@ -4,7 +4,6 @@ package main
 
 import (
 	"context"
-	stdsql "database/sql"
 	"fmt"
 	"os"
 	"strings"
@@ -13,8 +12,6 @@ import (
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
 	"github.com/stretchr/testify/require"
-
-	_ "github.com/databricks/databricks-sql-go"
 )
 
 func mustGetCfg(t *testing.T) config {
@@ -57,7 +54,7 @@ func TestValidateAndApply(t *testing.T) {
 		Schema: cfg.SchemaName,
 	}
 
-	db, err := stdsql.Open("databricks", cfg.ToURI())
+	db, err := cfg.openDB()
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -91,7 +88,7 @@ func TestValidateAndApplyMigrations(t *testing.T) {
 		Schema: cfg.SchemaName,
 	}
 
-	db, err := stdsql.Open("databricks", cfg.ToURI())
+	db, err := cfg.openDB()
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -127,7 +127,26 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
   {{- if $ind }},{{ end }}
   {{$col.Identifier}} {{$col.DDL}} COMMENT {{ Literal $col.Comment }}
   {{- end }}
-) COMMENT {{ Literal $.Comment }} TBLPROPERTIES ('delta.columnMapping.mode' = 'name');
+  {{- if $.History }}
+  {{- range $col := $.History.Columns }},
+  {{$col.Identifier}} {{$col.DDL}} COMMENT {{ Literal $col.Comment }}
+  {{- end }}
+  {{- end }}
+) COMMENT {{ Literal $.Comment }}
+{{- if and $.LayoutColumns $.LayoutColumns.PartitionBy }} PARTITIONED BY (
+	{{- range $ind, $col := $.LayoutColumns.PartitionBy }}
+	{{- if $ind }}, {{ end -}}
+	{{$col.Identifier}}
+	{{- end -}}
+)
+{{- end }}
+{{- if and $.LayoutColumns $.LayoutColumns.ClusterBy }} CLUSTER BY (
+	{{- range $ind, $col := $.LayoutColumns.ClusterBy }}
+	{{- if $ind }}, {{ end -}}
+	{{$col.Identifier}}
+	{{- end -}}
+)
+{{- end }} TBLPROPERTIES ('delta.columnMapping.mode' = 'name');
 {{ end }}
 
 -- Templated query which performs table alterations by adding columns.
@@ -167,6 +186,9 @@ SELECT {{ $.Table.Binding }}, {{ $.Table.Identifier }}.{{ $.Table.Document.Ident
 	{{- if $ind }} AND {{ end -}}
 	{{ $.Table.Identifier }}.{{ $key.Identifier }} = r.{{ $key.Identifier }}
 	{{- end }}
+	{{- if $.Table.History }}
+	AND {{ $.Table.Identifier }}.{{ $.Table.History.IsCurrent.Identifier }}
+	{{- end }}
 {{ else -}}
 SELECT -1, ""
 {{ end -}}
@@ -246,12 +268,54 @@ SELECT -1, ""
 		{{- end -}}
 	);
 {{ end }}
+
+-- Merges staged documents into a table in history mode. Each staged document
+-- is included twice in the source of the merge: Once to close out the current
+-- row for its key, and once to insert a new current row if it isn't a
+-- deletion. The conditions for matching are such that running the query again
+-- for the same files has no effect.
+{{ define "historyMerge" }}
+	MERGE INTO {{ $.Table.Identifier }} AS l
+	USING (
+		WITH staged AS (
+		{{- range $fi, $file := $.Files }}
+		{{ if $fi }} UNION ALL {{ end -}}
+		(
+			SELECT
+			{{ range $ind, $key := $.Table.Columns }}
+			{{- if $ind }}, {{ end -}}
+			{{ template "cast" $key -}}
+			{{- end }}
+			FROM json.`+"`{{ $file }}`"+`
+		)
+		{{- end }}
+		)
+		SELECT staged.*, true AS flow_history_close FROM staged
+		UNION ALL
+		SELECT staged.*, false AS flow_history_close FROM staged
+		{{- if $.Table.Document }} WHERE staged.{{ $.Table.Document.Identifier }}!='"delete"'{{ end }}
+	) AS r
+	ON {{ range $ind, $key := $.Table.Keys }}
+		{{- if $ind }} AND {{ end -}}
+		l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
+	{{- end }}
+	AND (
+		(r.flow_history_close AND l.{{ $.Table.History.IsCurrent.Identifier }} AND l.{{ $.Table.History.ValidFrom.Identifier }} < r.{{ $.Table.History.PublishedAt.Identifier }})
+		OR (NOT r.flow_history_close AND l.{{ $.Table.History.ValidFrom.Identifier }} = r.{{ $.Table.History.PublishedAt.Identifier }})
+	)
+	WHEN MATCHED AND r.flow_history_close THEN
+		UPDATE SET l.{{ $.Table.History.ValidTo.Identifier }} = r.{{ $.Table.History.PublishedAt.Identifier }}, l.{{ $.Table.History.IsCurrent.Identifier }} = false
+	WHEN NOT MATCHED AND NOT r.flow_history_close THEN
+		INSERT ({{ template "historyInsertColumns" $.Table }})
+		VALUES ({{ template "historyInsertValues" $.Table }});
+{{ end }}
   `)
 	tplCreateTargetTable = tplAll.Lookup("createTargetTable")
 	tplAlterTableColumns = tplAll.Lookup("alterTableColumns")
 	tplLoadQuery         = tplAll.Lookup("loadQuery")
 	tplCopyIntoDirect    = tplAll.Lookup("copyIntoDirect")
 	tplMergeInto         = tplAll.Lookup("mergeInto")
+	tplHistoryMerge      = tplAll.Lookup("historyMerge")
 )
 
 type tableWithFiles struct {
@@ -24,6 +24,13 @@ func TestSQLGeneration(t *testing.T) {
 			TableTemplates: []*template.Template{
 				tplCreateTargetTable,
 			},
+			HistoryTableTemplates: []*template.Template{
+				tplCreateTargetTable,
+			},
+			LayoutTableTemplates: []*template.Template{
+				tplCreateTargetTable,
+			},
+			Layout:        &sql.TableLayout{ClusterBy: []string{"flow_published_at", "key1"}},
 			TplAddColumns: tplAlterTableColumns,
 		},
 	)
@@ -59,5 +66,20 @@ func TestSQLGeneration(t *testing.T) {
 		snap.WriteString("--- End " + testcase + " ---\n\n")
 	}
 
+	for _, tpl := range []*template.Template{
+		tplLoadQuery,
+		tplHistoryMerge,
+	} {
+		tbl := tables[2]
+		require.NotNil(t, tbl.History)
+
+		var testcase = tbl.Identifier + " history " + tpl.Name()
+
+		var tplData = tableWithFiles{Table: &tbl, StagingPath: "test-staging-path", Files: []string{"file1", "file2"}}
+		snap.WriteString("--- Begin " + testcase + " ---")
+		require.NoError(t, tpl.Execute(snap, &tplData))
+		snap.WriteString("--- End " + testcase + " ---\n\n")
+	}
+
 	cupaloy.SnapshotT(t, snap.String())
 }
@@ -9,8 +9,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
@@ -19,9 +19,9 @@ import (
 )
 
 type config struct {
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=Access Key ID,description=AWS Access Key ID for materializing to DynamoDB." jsonschema_extras:"order=1"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=Secret Access Key,description=AWS Secret Access Key for materializing to DynamoDB." jsonschema_extras:"secret=true,order=2"`
-	Region             string `json:"region" jsonschema:"title=Region,description=Region of the materialized tables." jsonschema_extras:"order=3"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication" jsonschema_extras:"order=0"`
+	awsauth.AccessKeys
+	Region string `json:"region" jsonschema:"title=Region,description=Region of the materialized tables." jsonschema_extras:"order=3"`
 
 	Advanced advancedConfig `json:"advanced,omitempty" jsonschema:"title=Advanced Options,description=Options for advanced users. You should not typically need to modify these." jsonschema_extra:"advanced=true"`
 }
@@ -32,8 +32,6 @@ type advancedConfig struct {
 
 func (c *config) Validate() error {
 	var requiredProperties = [][]string{
-		{"awsAccessKeyId", c.AWSAccessKeyID},
-		{"awsSecretAccessKey", c.AWSSecretAccessKey},
 		{"region", c.Region},
 	}
 	for _, req := range requiredProperties {
@@ -42,7 +40,7 @@ func (c *config) Validate() error {
 		}
 	}
 
-	return nil
+	return awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey)
 }
 
 type resource struct {
@@ -96,9 +94,6 @@ func normalizeTableName(t string) (string, error) {
 
 func (c *config) client(ctx context.Context) (*client, error) {
 	opts := []func(*awsConfig.LoadOptions) error{
-		awsConfig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(c.AWSAccessKeyID, c.AWSSecretAccessKey, ""),
-		),
 		awsConfig.WithRegion(c.Region),
 		awsConfig.WithRetryer(func() aws.Retryer {
 			// Bump up the number of retry maximum attempts from the default of 3. The maximum retry
@@ -118,7 +113,8 @@ func (c *config) client(ctx context.Context) (*client, error) {
 		opts = append(opts, awsConfig.WithEndpointResolverWithOptions(customResolver))
 	}
 
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, opts...)
+	creds := awsauth.ResolveCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey)
+	awsCfg, err := creds.AWSConfig(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating aws config: %w", err)
 	}
@@ -15,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/bradleyjkemp/cupaloy"
 
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	"github.com/stretchr/testify/require"
@@ -22,9 +23,11 @@ import (
 
 func testConfig() *config {
 	return &config{
-		AWSAccessKeyID:     "anything",
-		AWSSecretAccessKey: "anything",
-		Region:             "anything",
+		AccessKeys: awsauth.AccessKeys{
+			AWSAccessKeyID:     "anything",
+			AWSSecretAccessKey: "anything",
+		},
+		Region: "anything",
 		Advanced: advancedConfig{
 			Endpoint: "http://localhost:8000",
 		},
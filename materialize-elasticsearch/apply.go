@@ -27,12 +27,29 @@ func (e *elasticApplier) CreateResource(ctx context.Context, spec *pf.Materializ
 		return "", nil, err
 	}
 
+	if res.Rollover != "" {
+		return fmt.Sprintf("create index template %q", binding.ResourcePath[0]), func(ctx context.Context) error {
+			return e.client.putIndexTemplate(ctx, binding.ResourcePath[0], res, e.cfg.Advanced.Replicas, props)
+		}, nil
+	}
+
 	return fmt.Sprintf("create index %q", binding.ResourcePath[0]), func(ctx context.Context) error {
 		return e.client.createIndex(ctx, binding.ResourcePath[0], res.Shards, e.cfg.Advanced.Replicas, props)
 	}, nil
 }
 
 func (e *elasticApplier) DeleteResource(ctx context.Context, path []string) (string, boilerplate.ActionApplyFn, error) {
+	templates, err := e.client.getIndexTemplates(ctx, path[0])
+	if err != nil {
+		return "", nil, err
+	}
+
+	if tpl, ok := templates[path[0]]; ok {
+		return fmt.Sprintf("delete index template %q and its indices", path[0]), func(ctx context.Context) error {
+			return e.client.deleteIndexTemplate(ctx, path[0], tpl)
+		}, nil
+	}
+
 	return fmt.Sprintf("delete index %q", path[0]), func(ctx context.Context) error {
 		return e.client.deleteIndex(ctx, path[0])
 	}, nil
@@ -46,7 +63,23 @@ func (e *elasticApplier) UpdateResource(ctx context.Context, spec *pf.Materializ
 		return "", nil, nil
 	}
 
+	var res resource
+	if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
+		return "", nil, fmt.Errorf("parsing resource config: %w", err)
+	}
+
 	var actions []string
+	if res.Rollover != "" {
+		actions = append(actions, fmt.Sprintf("update mappings of index template %q", binding.ResourcePath[0]))
+	}
+
+	// Mappings are added to all existing indices for the binding, which for
+	// routed documents are the indices matching its index template.
+	indices := []string{binding.ResourcePath[0]}
+	if res.Rollover != "" {
+		indices = indexPatterns(binding.ResourcePath[0], res.Rollover)
+	}
+
 	for _, newProjection := range bindingUpdate.NewProjections {
 		prop, err := propForField(newProjection.Field, binding)
 		if err != nil {
@@ -62,10 +95,19 @@ func (e *elasticApplier) UpdateResource(ctx context.Context, spec *pf.Materializ
 	}
 
 	return strings.Join(actions, "\n"), func(ctx context.Context) error {
+		if res.Rollover != "" {
+			props, err := buildIndexProperties(binding)
+			if err != nil {
+				return err
+			} else if err := e.client.putIndexTemplate(ctx, binding.ResourcePath[0], res, e.cfg.Advanced.Replicas, props); err != nil {
+				return err
+			}
+		}
+
 		for _, newProjection := range bindingUpdate.NewProjections {
 			if prop, err := propForField(newProjection.Field, binding); err != nil {
 				return err
-			} else if err := e.client.addMappingToIndex(ctx, binding.ResourcePath[0], translateField(newProjection.Field), prop); err != nil {
+			} else if err := e.client.addMappingToIndex(ctx, indices, res.Rollover != "", translateField(newProjection.Field), prop); err != nil {
 				return err
 			}
 		}
@@ -8,8 +8,10 @@ import (
 	"strings"
 
 	elasticsearch "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/elastic/go-elasticsearch/v8/esutil"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
+	log "github.com/sirupsen/logrus"
 )
 
 type client struct {
@@ -22,8 +24,29 @@ type createIndexParams struct {
 }
 
 type indexSettings struct {
-	Shards   *int `json:"number_of_shards,omitempty"`
-	Replicas *int `json:"number_of_replicas,omitempty"`
+	Shards    *int   `json:"number_of_shards,omitempty"`
+	Replicas  *int   `json:"number_of_replicas,omitempty"`
+	ILMPolicy string `json:"index.lifecycle.name,omitempty"`
+}
+
+type indexTemplate struct {
+	IndexPatterns []string          `json:"index_patterns"`
+	Priority      int               `json:"priority"`
+	DataStream    *struct{}         `json:"data_stream,omitempty"`
+	Template      createIndexParams `json:"template"`
+	Meta          indexTemplateMeta `json:"_meta"`
+}
+
+type indexTemplateMeta struct {
+	ManagedBy string `json:"managed_by"`
+	Rollover  string `json:"rollover,omitempty"`
+}
+
+type getIndexTemplatesResponse struct {
+	IndexTemplates []struct {
+		Name          string        `json:"name"`
+		IndexTemplate indexTemplate `json:"index_template"`
+	} `json:"index_templates"`
 }
 
 type indexMappings struct {
@@ -91,11 +114,22 @@ func (c *client) deleteIndex(ctx context.Context, index string) error {
 	return nil
 }
 
-func (c *client) addMappingToIndex(ctx context.Context, index string, field string, prop property) error {
+// addMappingToIndex adds a mapping to the indices. If allowMissing is set, it is
+// not an error for the indices to not exist, which is the case for time-based
+// indices or data streams that have not been routed to yet.
+func (c *client) addMappingToIndex(ctx context.Context, indices []string, allowMissing bool, field string, prop property) error {
+	opts := []func(*esapi.IndicesPutMappingRequest){c.es.Indices.PutMapping.WithContext(ctx)}
+	if allowMissing {
+		opts = append(opts,
+			c.es.Indices.PutMapping.WithAllowNoIndices(true),
+			c.es.Indices.PutMapping.WithIgnoreUnavailable(true),
+		)
+	}
+
 	res, err := c.es.Indices.PutMapping(
-		[]string{index},
+		indices,
 		esutil.NewJSONReader(map[string]map[string]property{"properties": {field: prop}}),
-		c.es.Indices.PutMapping.WithContext(ctx),
+		opts...,
 	)
 	if err != nil {
 		return fmt.Errorf("addMappingToIndex: %w", err)
@@ -108,6 +142,163 @@ func (c *client) addMappingToIndex(ctx context.Context, index string, field stri
 	return nil
 }
 
+// putIndexTemplate creates or replaces the index template for an index that is
+// routed to time-based indices or a data stream. Settings and mappings of the
+// template apply to indices that are created from it, and existing indices are
+// not changed.
+func (c *client) putIndexTemplate(ctx context.Context, name string, res resource, replicas *int, indexProps map[string]property) error {
+	tpl := indexTemplate{
+		IndexPatterns: indexPatterns(name, res.Rollover),
+		Priority:      indexTemplatePriority,
+		Template: createIndexParams{
+			Settings: indexSettings{
+				Shards:    res.Shards,
+				Replicas:  replicas,
+				ILMPolicy: res.ILMPolicy,
+			},
+			Mappings: indexMappings{
+				Properties: indexProps,
+			},
+		},
+		Meta: indexTemplateMeta{ManagedBy: indexTemplateManagedBy, Rollover: res.Rollover},
+	}
+	if res.Rollover == rolloverDataStream {
+		tpl.DataStream = &struct{}{}
+		tpl.Template.Mappings.Properties[dataStreamTimestampField] = property{Type: elasticTypeDate}
+	}
+
+	resp, err := c.es.Indices.PutIndexTemplate(
+		name,
+		esutil.NewJSONReader(tpl),
+		c.es.Indices.PutIndexTemplate.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("putIndexTemplate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return fmt.Errorf("putIndexTemplate error response [%s] %s", resp.Status(), resp.String())
+	}
+
+	return nil
+}
+
+// getIndexTemplates returns the index templates managed by the connector, by
+// name. If name is provided only the index template with that name is
+// returned, if it exists.
+func (c *client) getIndexTemplates(ctx context.Context, name string) (map[string]indexTemplate, error) {
+	opts := []func(*esapi.IndicesGetIndexTemplateRequest){c.es.Indices.GetIndexTemplate.WithContext(ctx)}
+	if name != "" {
+		opts = append(opts, c.es.Indices.GetIndexTemplate.WithName(name))
+	}
+
+	res, err := c.es.Indices.GetIndexTemplate(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("getting index templates: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if res.IsError() {
+		return nil, fmt.Errorf("getting index templates error response [%s] %s", res.Status(), res.String())
+	}
+
+	var templates getIndexTemplatesResponse
+	if err := json.NewDecoder(res.Body).Decode(&templates); err != nil {
+		return nil, fmt.Errorf("decoding index templates response: %w", err)
+	}
+
+	out := make(map[string]indexTemplate)
+	for _, t := range templates.IndexTemplates {
+		if t.IndexTemplate.Meta.ManagedBy == indexTemplateManagedBy {
+			out[t.Name] = t.IndexTemplate
+		}
+	}
+
+	return out, nil
+}
+
+// deleteIndexTemplate deletes an index template along with the data stream or
+// indices that were created from it.
+func (c *client) deleteIndexTemplate(ctx context.Context, name string, tpl indexTemplate) error {
+	if tpl.DataStream != nil {
+		res, err := c.es.Indices.DeleteDataStream(
+			[]string{name},
+			c.es.Indices.DeleteDataStream.WithContext(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("deleting data stream: %w", err)
+		}
+		defer res.Body.Close()
+		if res.IsError() && res.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("delete data stream error response [%s] %s", res.Status(), res.String())
+		}
+	} else {
+		// Indices are deleted by name, since deleting indices with a wildcard
+		// pattern is disallowed by default. Only indices with the name of a
+		// time-based index created from the template are deleted, and not any
+		// other indices that happen to match its patterns.
+		indices, err := c.matchingIndices(ctx, tpl.IndexPatterns)
+		if err != nil {
+			return err
+		}
+		for _, index := range indices {
+			if !isRolloverIndex(index, name, tpl.Meta.Rollover) {
+				log.WithFields(log.Fields{
+					"index":         index,
+					"indexTemplate": name,
+				}).Info("not deleting index matching index template which is not a time-based index")
+				continue
+			}
+			if err := c.deleteIndex(ctx, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	res, err := c.es.Indices.DeleteIndexTemplate(
+		name,
+		c.es.Indices.DeleteIndexTemplate.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("deleting index template: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("delete index template error response [%s] %s", res.Status(), res.String())
+	}
+
+	return nil
+}
+
+// matchingIndices returns the names of the indices matching the patterns.
+func (c *client) matchingIndices(ctx context.Context, patterns []string) ([]string, error) {
+	res, err := c.es.Indices.Get(
+		patterns,
+		c.es.Indices.Get.WithContext(ctx),
+		c.es.Indices.Get.WithAllowNoIndices(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting indices: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("getting indices error response [%s] %s", res.Status(), res.String())
+	}
+
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("decoding indices response: %w", err)
+	}
+
+	out := make([]string, 0, len(indices))
+	for index := range indices {
+		out = append(out, index)
+	}
+
+	return out, nil
+}
+
 type indexMetaResponse struct {
 	Mappings struct {
 		Properties map[string]property `json:"properties"`
@@ -155,6 +346,29 @@ func (c *client) infoSchema(ctx context.Context) (*boilerplate.InfoSchema, error
 		}
 	}
 
+	// The resources of bindings that route documents to time-based indices or
+	// data streams are the index templates for them.
+	templates, err := c.getIndexTemplates(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for name, tpl := range templates {
+		if is.HasResource([]string{name}) {
+			continue
+		}
+		is.PushResource(name)
+
+		for field, prop := range tpl.Template.Mappings.Properties {
+			is.PushField(boilerplate.EndpointField{
+				Name:               field,
+				Nullable:           true,
+				Type:               string(prop.Type),
+				CharacterMaxLength: 0,
+			}, name)
+		}
+	}
+
 	return is, nil
 }
 
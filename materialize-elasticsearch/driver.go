@@ -52,7 +52,8 @@ type config struct {
 }
 
 type advancedConfig struct {
-	Replicas *int `json:"number_of_replicas,omitempty"`
+	Replicas *int   `json:"number_of_replicas,omitempty"`
+	Pipeline string `json:"pipeline,omitempty"`
 }
 
 // The `go-schema-gen` package doesn't have a good way of dealing with oneOf, and I couldn't get it
@@ -125,6 +126,11 @@ func configSchema() json.RawMessage {
 				"type": "integer",
 				"title": "Index Replicas",
 				"description": "The number of replicas to create new indexes with. Leave blank to use the cluster default."
+			  },
+			  "pipeline": {
+				"type": "string",
+				"title": "Ingest Pipeline",
+				"description": "Name of an ingest pipeline to process documents with when they are stored. Leave blank to use the default pipeline of the index, if any."
 			  }
 			},
 			"type": "object",
@@ -276,9 +282,12 @@ func (c config) toClient(disableRetry bool) (*client, error) {
 }
 
 type resource struct {
-	Index        string `json:"index" jsonschema_extras:"x-collection-name=true"`
-	DeltaUpdates bool   `json:"delta_updates" jsonschema:"default=false" jsonschema_extras:"x-delta-updates=true"`
-	Shards       *int   `json:"number_of_shards,omitempty"`
+	Index          string `json:"index" jsonschema_extras:"x-collection-name=true"`
+	DeltaUpdates   bool   `json:"delta_updates" jsonschema:"default=false" jsonschema_extras:"x-delta-updates=true"`
+	Shards         *int   `json:"number_of_shards,omitempty"`
+	Rollover       string `json:"rollover,omitempty" jsonschema:"enum=daily,enum=monthly,enum=yearly,enum=data_stream"`
+	TimestampField string `json:"timestamp_field,omitempty"`
+	ILMPolicy      string `json:"ilm_policy,omitempty"`
 }
 
 func (r resource) Validate() error {
@@ -288,6 +297,23 @@ func (r resource) Validate() error {
 		return fmt.Errorf("number_of_shards must be greater than 0")
 	}
 
+	if r.Rollover == "" {
+		if r.TimestampField != "" {
+			return fmt.Errorf("timestamp_field can only be set with rollover")
+		} else if r.ILMPolicy != "" {
+			return fmt.Errorf("ilm_policy can only be set with rollover")
+		}
+		return nil
+	}
+
+	if !validRollover(r.Rollover) {
+		return fmt.Errorf("invalid rollover %q: must be one of %q, %q, %q or %q", r.Rollover, rolloverDaily, rolloverMonthly, rolloverYearly, rolloverDataStream)
+	} else if !r.DeltaUpdates {
+		return fmt.Errorf("rollover requires delta_updates to be enabled")
+	} else if r.TimestampField == "" {
+		return fmt.Errorf("missing timestamp_field: it is required with rollover")
+	}
+
 	return nil
 }
 
@@ -300,6 +326,12 @@ func (resource) GetFieldDocString(fieldName string) string {
 		return "Should updates to this table be done via delta updates. Default is false."
 	case "Shards":
 		return "The number of shards to create the index with. Leave blank to use the cluster default."
+	case "Rollover":
+		return "Route documents to date-suffixed indices or a data stream based on the timestamp field instead of a single index. An index template for the index name is managed instead of the index itself. Requires delta updates."
+	case "TimestampField":
+		return "The date or date-time field used to route documents when rollover is enabled."
+	case "ILMPolicy":
+		return "Name of an existing index lifecycle management policy to apply to indices created from the index template."
 	default:
 		return ""
 	}
@@ -423,6 +455,16 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 			return nil, fmt.Errorf("validating binding: %w", err)
 		}
 
+		if res.Rollover != "" {
+			if err := validateTimestampField(binding, res.TimestampField); err != nil {
+				return nil, err
+			}
+			constraints[res.TimestampField] = &pm.Response_Validated_Constraint{
+				Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
+				Reason: "This field is used to route documents to indices",
+			}
+		}
+
 		out = append(out, &pm.Response_Validated_Binding{
 			Constraints:  constraints,
 			DeltaUpdates: res.DeltaUpdates,
@@ -498,10 +540,19 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 			}
 		}
 
+		timestampIdx := -1
+		if res.Rollover != "" {
+			if timestampIdx = slices.Index(allFields, res.TimestampField); timestampIdx == -1 {
+				return nil, nil, nil, fmt.Errorf("timestamp field %q for index %q is not in the field selection", res.TimestampField, b.ResourcePath[0])
+			}
+		}
+
 		indexToBinding[b.ResourcePath[0]] = idx
 		bindings = append(bindings, binding{
 			index:        b.ResourcePath[0],
 			deltaUpdates: res.DeltaUpdates,
+			rollover:     res.Rollover,
+			timestampIdx: timestampIdx,
 			fields:       fields,
 			floatFields:  floatFields,
 			wrapFields:   wrapFields,
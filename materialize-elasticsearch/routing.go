@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+)
+
+// Values for the resource `rollover` setting. Bindings with a rollover setting
+// route their documents to time-based indices or data streams matching an index
+// template, rather than a single fixed index.
+const (
+	rolloverDaily      = "daily"
+	rolloverMonthly    = "monthly"
+	rolloverYearly     = "yearly"
+	rolloverDataStream = "data_stream"
+)
+
+// Date suffixes for time-based indices are in the same format that Logstash
+// and Beats use, ex: "my-index-2024.01.31".
+var rolloverSuffixFormats = map[string]string{
+	rolloverDaily:   "2006.01.02",
+	rolloverMonthly: "2006.01",
+	rolloverYearly:  "2006",
+}
+
+// Data streams require every document to have a "@timestamp" field.
+const dataStreamTimestampField = "@timestamp"
+
+// Index templates created by the connector are marked with this value for
+// `_meta.managed_by`, so they can be distinguished from other index templates
+// of the cluster.
+const indexTemplateManagedBy = "estuary-flow"
+
+// Index templates created by the connector are given a higher priority than the
+// built-in index templates of Elasticsearch, which use priority 100.
+const indexTemplatePriority = 200
+
+func validRollover(rollover string) bool {
+	_, ok := rolloverSuffixFormats[rollover]
+	return ok || rollover == rolloverDataStream
+}
+
+// indexPatterns returns the patterns of the index template for a binding with
+// the given index name and rollover setting. Time-based indices are matched as
+// `<index>-[0-9]*`, so that the template does not overlap with the template of
+// another binding with an index name like `<index>-other`. Index patterns only
+// support `*` wildcards, so there is a pattern for each digit.
+func indexPatterns(index string, rollover string) []string {
+	if rollover == rolloverDataStream {
+		return []string{index}
+	}
+
+	patterns := make([]string, 0, 10)
+	for digit := '0'; digit <= '9'; digit++ {
+		patterns = append(patterns, fmt.Sprintf("%s-%c*", index, digit))
+	}
+	return patterns
+}
+
+// isRolloverIndex returns true if name is the name of a time-based index for a
+// binding with the given index name and rollover setting.
+func isRolloverIndex(name string, index string, rollover string) bool {
+	format, ok := rolloverSuffixFormats[rollover]
+	if !ok {
+		return false
+	}
+
+	suffix, ok := strings.CutPrefix(name, index+"-")
+	if !ok {
+		return false
+	}
+
+	_, err := time.Parse(format, suffix)
+	return err == nil
+}
+
+// routeDocument returns the index or data stream to write a document to, based
+// on its timestamp value.
+func routeDocument(index string, rollover string, timestamp any) (string, error) {
+	if rollover == rolloverDataStream {
+		return index, nil
+	}
+
+	str, ok := timestamp.(string)
+	if !ok {
+		return "", fmt.Errorf("timestamp value must be a string but was %#v (%T)", timestamp, timestamp)
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		if ts, err = time.Parse(time.DateOnly, str); err != nil {
+			return "", fmt.Errorf("parsing timestamp value %q: %w", str, err)
+		}
+	}
+
+	return index + "-" + ts.UTC().Format(rolloverSuffixFormats[rollover]), nil
+}
+
+// validateTimestampField checks that the timestamp field used for routing the
+// documents of a binding is a date or date-time.
+func validateTimestampField(binding *pm.Request_Validate_Binding, field string) error {
+	p := binding.Collection.GetProjection(field)
+	if p == nil {
+		return fmt.Errorf("timestamp field %q does not exist in collection %q", field, binding.Collection.Name)
+	}
+
+	if prop, err := propForProjection(p, p.Inference.Types, nil); err != nil {
+		return err
+	} else if prop.Type != elasticTypeDate || p.Inference.Exists != pf.Inference_MUST {
+		return fmt.Errorf("timestamp field %q must be a required date or date-time", field)
+	}
+
+	return nil
+}
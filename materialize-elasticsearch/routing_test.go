@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteDocument(t *testing.T) {
+	for _, tt := range []struct {
+		rollover  string
+		timestamp any
+		want      string
+		wantErr   string
+	}{
+		{rolloverDaily, "2024-01-31T23:30:00-05:00", "logs-2024.02.01", ""},
+		{rolloverDaily, "2024-01-31", "logs-2024.01.31", ""},
+		{rolloverMonthly, "2024-01-31T10:00:00.123456Z", "logs-2024.01", ""},
+		{rolloverYearly, "2024-01-31T10:00:00Z", "logs-2024", ""},
+		{rolloverDataStream, "2024-01-31T10:00:00Z", "logs", ""},
+		{rolloverDaily, "yesterday", "", "parsing timestamp value"},
+		{rolloverDaily, nil, "", "timestamp value must be a string"},
+	} {
+		got, err := routeDocument("logs", tt.rollover, tt.timestamp)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tt.want, got)
+	}
+}
+
+func TestResourceValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		res     resource
+		wantErr string
+	}{
+		{"fixed index", resource{Index: "target"}, ""},
+		{"daily", resource{Index: "logs", DeltaUpdates: true, Rollover: rolloverDaily, TimestampField: "ts"}, ""},
+		{"data stream with ilm", resource{Index: "logs", DeltaUpdates: true, Rollover: rolloverDataStream, TimestampField: "ts", ILMPolicy: "logs"}, ""},
+		{"not delta", resource{Index: "logs", Rollover: rolloverDaily, TimestampField: "ts"}, "requires delta_updates"},
+		{"no timestamp", resource{Index: "logs", DeltaUpdates: true, Rollover: rolloverDaily}, "missing timestamp_field"},
+		{"invalid rollover", resource{Index: "logs", DeltaUpdates: true, Rollover: "hourly", TimestampField: "ts"}, "invalid rollover"},
+		{"timestamp without rollover", resource{Index: "logs", TimestampField: "ts"}, "can only be set with rollover"},
+		{"ilm without rollover", resource{Index: "logs", ILMPolicy: "logs"}, "can only be set with rollover"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.res.Validate()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestIndexPatterns(t *testing.T) {
+	require.Equal(t, []string{"logs"}, indexPatterns("logs", rolloverDataStream))
+
+	patterns := indexPatterns("logs", rolloverDaily)
+	require.Len(t, patterns, 10)
+	require.Equal(t, "logs-0*", patterns[0])
+	require.Equal(t, "logs-9*", patterns[9])
+}
+
+func TestIsRolloverIndex(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		rollover string
+		want     bool
+	}{
+		{"logs-2024.01.31", rolloverDaily, true},
+		{"logs-2024.01", rolloverMonthly, true},
+		{"logs-2024", rolloverYearly, true},
+		{"logs-2024.01", rolloverDaily, false},
+		{"logs-2024-other", rolloverYearly, false},
+		{"logs-other", rolloverDaily, false},
+		{"other-2024", rolloverYearly, false},
+		{"logs", rolloverDataStream, false},
+		{"logs-2024", "", false},
+	} {
+		require.Equal(t, tt.want, isRolloverIndex(tt.name, "logs", tt.rollover), tt.name)
+	}
+}
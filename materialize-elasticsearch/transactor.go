@@ -31,6 +31,12 @@ type binding struct {
 	index        string
 	deltaUpdates bool
 
+	// Set if documents are routed to time-based indices or a data stream
+	// rather than stored in the fixed index, along with the position of the
+	// timestamp field used for routing in the keys and values of a document.
+	rollover     string
+	timestampIdx int
+
 	// Ordered list of field names included in the field selection for the binding, which are used
 	// to build the JSON document to be stored Elasticsearch.
 	fields []string
@@ -144,6 +150,7 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		NumWorkers: storeWorkers,
 		FlushBytes: storeBatchSize,
 		Client:     t.client.es,
+		Pipeline:   t.cfg.Advanced.Pipeline,
 		OnError: func(_ context.Context, err error) {
 			log.WithField("error", err.Error()).Error("bulk indexer error")
 
@@ -207,6 +214,7 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			id = base64.RawStdEncoding.EncodeToString(it.PackedKey)
 		}
 
+		var index = b.index
 		var action string
 		var bodyReader *bytes.Reader = bytes.NewReader([]byte{})
 		if it.Delete && t.cfg.HardDelete {
@@ -218,7 +226,18 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 				continue
 			}
 		} else {
-			for idx, v := range append(it.Key, it.Values...) {
+			values := append(it.Key, it.Values...)
+			if b.rollover != "" {
+				var err error
+				if index, err = routeDocument(b.index, b.rollover, values[b.timestampIdx]); err != nil {
+					return nil, fmt.Errorf("routing document for index %q: %w", b.index, err)
+				}
+				if b.rollover == rolloverDataStream {
+					doc[dataStreamTimestampField] = values[b.timestampIdx]
+				}
+			}
+
+			for idx, v := range values {
 				if b, ok := v.([]byte); ok {
 					v = json.RawMessage(b)
 				}
@@ -256,7 +275,7 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		}
 
 		if err := indexer.Add(it.Context(), esutil.BulkIndexerItem{
-			Index:      b.index,
+			Index:      index,
 			Action:     action,
 			DocumentID: id,
 			Body:       bodyReader,
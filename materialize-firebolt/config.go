@@ -3,19 +3,22 @@ package main
 import (
 	"fmt"
 	"strings"
+
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 )
 
 type config struct {
-	ClientId     string `json:"client_id" jsonschema:"title=Client ID" jsonschema_extras:"order=0"`
-	ClientSecret string `json:"client_secret" jsonschema:"title=Client Secret" jsonschema_extras:"secret=true,order=1"`
-	AccountName  string `json:"account_name" jsonschema:"title=Account Name" jsonschema_extras:"order=2"`
-	EngineName   string `json:"engine_name" jsonschema:"title=Engine Name" jsonschema_extras:"order=3"`
-	Database     string `json:"database" jsonschema:"title=Database" jsonschema_extras:"order=4"`
-	S3Bucket     string `json:"s3_bucket" jsonschema:"title=S3 Bucket" jsonschema_extras:"order=5"`
-	S3Prefix     string `json:"s3_prefix,omitempty" jsonschema:"title=S3 Prefix,default=/" jsonschema_extras:"order=6"`
-	AWSKeyId     string `json:"aws_key_id,omitempty" jsonschema:"title=AWS Key ID" jsonschema_extras:"order=7"`
-	AWSSecretKey string `json:"aws_secret_key,omitempty" jsonschema:"title=AWS Secret Key" jsonschema_extras:"secret=true,order=8"`
-	AWSRegion    string `json:"aws_region,omitempty" jsonschema:"title=AWS Region" jsonschema_extras:"order=9"`
+	ClientId     string                    `json:"client_id" jsonschema:"title=Client ID" jsonschema_extras:"order=0"`
+	ClientSecret string                    `json:"client_secret" jsonschema:"title=Client Secret" jsonschema_extras:"secret=true,order=1"`
+	AccountName  string                    `json:"account_name" jsonschema:"title=Account Name" jsonschema_extras:"order=2"`
+	EngineName   string                    `json:"engine_name" jsonschema:"title=Engine Name" jsonschema_extras:"order=3"`
+	Database     string                    `json:"database" jsonschema:"title=Database" jsonschema_extras:"order=4"`
+	S3Bucket     string                    `json:"s3_bucket" jsonschema:"title=S3 Bucket" jsonschema_extras:"order=5"`
+	S3Prefix     string                    `json:"s3_prefix,omitempty" jsonschema:"title=S3 Prefix,default=/" jsonschema_extras:"order=6"`
+	Credentials  *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication" jsonschema_extras:"order=7"`
+	AWSKeyId     string                    `json:"aws_key_id,omitempty" jsonschema:"title=AWS Key ID" jsonschema_extras:"order=8"`
+	AWSSecretKey string                    `json:"aws_secret_key,omitempty" jsonschema:"title=AWS Secret Key" jsonschema_extras:"secret=true,order=9"`
+	AWSRegion    string                    `json:"aws_region,omitempty" jsonschema:"title=AWS Region" jsonschema_extras:"order=10"`
 }
 
 func (c config) Validate() error {
@@ -37,6 +40,15 @@ func (c config) Validate() error {
 	if c.S3Bucket == "" {
 		return fmt.Errorf("missing required bucket")
 	}
+	if c.Credentials != nil {
+		if err := c.Credentials.Validate(); err != nil {
+			return fmt.Errorf("invalid credentials: %w", err)
+		}
+		switch c.Credentials.AuthType {
+		case awsauth.WEB_IDENTITY_AUTH_TYPE, awsauth.DEFAULT_CHAIN_AUTH_TYPE:
+			return fmt.Errorf("invalid credentials: firebolt external tables do not support %q credentials", c.Credentials.AuthType)
+		}
+	}
 	return nil
 }
 
@@ -58,10 +70,12 @@ func (config) GetFieldDocString(fieldName string) string {
 		return "Name of S3 bucket where the intermediate files for external table will be stored."
 	case "S3Prefix":
 		return "A prefix for files stored in the bucket. Example: my-prefix."
+	case "Credentials":
+		return "AWS credentials for accessing the S3 bucket."
 	case "AWSKeyId":
-		return "AWS Key ID for accessing the S3 bucket."
+		return "Deprecated: use Authentication instead. AWS Key ID for accessing the S3 bucket."
 	case "AWSSecretKey":
-		return "AWS Secret Key for accessing the S3 bucket."
+		return "Deprecated: use Authentication instead. AWS Secret Key for accessing the S3 bucket."
 	case "AWSRegion":
 		return "AWS Region the bucket is in."
 	default:
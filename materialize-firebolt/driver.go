@@ -8,6 +8,7 @@ import (
 
 	"database/sql"
 
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	"github.com/estuary/connectors/materialize-firebolt/schemalate"
@@ -37,8 +38,8 @@ func (driver) Spec(ctx context.Context, req *pm.Request_Spec) (*pm.Response_Spec
 	}, nil
 }
 
-func ValidateBindings(cfg config, materialization string, bindings []*pm.Request_Validate_Binding) ([]map[string]*pm.Response_Validated_Constraint, error) {
-	existing, err := LoadSpec(cfg, materialization)
+func ValidateBindings(ctx context.Context, cfg config, materialization string, bindings []*pm.Request_Validate_Binding) ([]map[string]*pm.Response_Validated_Constraint, error) {
+	existing, err := LoadSpec(ctx, cfg, materialization)
 	if err != nil {
 		return nil, fmt.Errorf("loading materialization spec: %w", err)
 	}
@@ -78,7 +79,7 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 		return nil, fmt.Errorf("parsing endpoint config: %w", err)
 	}
 
-	var constraints, err = ValidateBindings(cfg, req.Name.String(), req.Bindings)
+	var constraints, err = ValidateBindings(ctx, cfg, req.Name.String(), req.Bindings)
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +136,7 @@ func (d driver) Apply(ctx context.Context, req *pm.Request_Apply) (*pm.Response_
 		mappedBindings = append(mappedBindings, &mappedBinding)
 	}
 
-	constraints, err := ValidateBindings(cfg, req.Materialization.Name.String(), mappedBindings)
+	constraints, err := ValidateBindings(ctx, cfg, req.Materialization.Name.String(), mappedBindings)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +149,7 @@ func (d driver) Apply(ctx context.Context, req *pm.Request_Apply) (*pm.Response_
 		}
 	}
 
-	queries, err := schemalate.GetQueriesBundle(req.Materialization)
+	queries, err := schemalate.GetQueriesBundle(req.Materialization, awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSKeyId, cfg.AWSSecretKey))
 	if err != nil {
 		return nil, fmt.Errorf("building queries bundle: %w", err)
 	}
@@ -167,7 +168,7 @@ func (d driver) Apply(ctx context.Context, req *pm.Request_Apply) (*pm.Response_
 		tables = append(tables, string(req.Materialization.Bindings[i].ResourceConfigJson))
 	}
 
-	err = WriteSpec(cfg, req.Materialization, req.Version)
+	err = WriteSpec(ctx, cfg, req.Materialization, req.Version)
 	if err != nil {
 		return nil, fmt.Errorf("writing materialization spec to s3: %w", err)
 	}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
+	"strings"
 
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	"github.com/gogo/protobuf/jsonpb"
@@ -31,12 +34,29 @@ type QueriesBundle struct {
 	Bindings []BindingBundle
 }
 
+// roleCredentialsPlaceholder is passed to flow-schemalate as the access keys of endpoint
+// configurations which assume a role, and the resulting credentials of external table queries are
+// replaced with those of the role.
+const roleCredentialsPlaceholder = "ROLE_CREDENTIALS_PLACEHOLDER"
+
+var externalTableCredentials = regexp.MustCompile(`CREDENTIALS\s*=\s*\([^)]*\)`)
+
+// GetQueriesBundle builds the queries of each binding of the spec. External tables are created
+// with creds, which are the resolved credentials of the endpoint configuration.
 func GetQueriesBundle(
 	spec *pf.MaterializationSpec,
+	creds awsauth.CredentialConfig,
 ) (*QueriesBundle, error) {
 	var args = []string{"firebolt-schema", "query-bundle"}
 
-	var specBytes, err = proto.Marshal(spec)
+	configJson, err := withAccessKeys(spec.ConfigJson, creds)
+	if err != nil {
+		return nil, err
+	}
+	var withCreds = *spec
+	withCreds.ConfigJson = configJson
+
+	specBytes, err := proto.Marshal(&withCreds)
 	if err != nil {
 		return nil, fmt.Errorf("marshalling materialization spec: %w", err)
 	}
@@ -52,9 +72,72 @@ func GetQueriesBundle(
 		return nil, fmt.Errorf("parsing queries bundle %w with stdout %s", err, out)
 	}
 
+	if creds.AuthType == awsauth.ASSUME_ROLE_AUTH_TYPE {
+		for i := range bundle.Bindings {
+			if bundle.Bindings[i].CreateExternalTable, err = withRoleCredentials(bundle.Bindings[i].CreateExternalTable, creds); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &bundle, nil
 }
 
+// withAccessKeys returns the endpoint configuration with the access keys that flow-schemalate uses
+// for external tables set from creds.
+func withAccessKeys(configJson json.RawMessage, creds awsauth.CredentialConfig) (json.RawMessage, error) {
+	var cfg map[string]json.RawMessage
+	if err := json.Unmarshal(configJson, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing endpoint config: %w", err)
+	}
+	delete(cfg, "credentials")
+
+	var keyID, secretKey string
+	switch creds.AuthType {
+	case awsauth.ACCESS_KEY_AUTH_TYPE:
+		keyID, secretKey = creds.AWSAccessKeyID, creds.AWSSecretAccessKey
+	case awsauth.ASSUME_ROLE_AUTH_TYPE:
+		keyID, secretKey = roleCredentialsPlaceholder, roleCredentialsPlaceholder
+	default:
+		return nil, fmt.Errorf("firebolt external tables do not support %q credentials", creds.AuthType)
+	}
+
+	for key, val := range map[string]string{"aws_key_id": keyID, "aws_secret_key": secretKey} {
+		if val == "" {
+			delete(cfg, key)
+			continue
+		}
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", key, err)
+		}
+		cfg[key] = encoded
+	}
+
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("encoding endpoint config: %w", err)
+	}
+	return out, nil
+}
+
+// withRoleCredentials replaces the credentials of an external table query with those of the role
+// of creds.
+func withRoleCredentials(query string, creds awsauth.CredentialConfig) (string, error) {
+	if !externalTableCredentials.MatchString(query) {
+		return "", fmt.Errorf("external table query is missing credentials")
+	}
+
+	var quote = func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	var clause = "CREDENTIALS = (AWS_ROLE_ARN = " + quote(creds.RoleARN)
+	if creds.ExternalID != "" {
+		clause += " AWS_ROLE_EXTERNAL_ID = " + quote(creds.ExternalID)
+	}
+	clause += ")"
+
+	return externalTableCredentials.ReplaceAllLiteralString(query, clause), nil
+}
+
 func GetDropQuery(
 	table string,
 ) (string, error) {
@@ -0,0 +1,49 @@
+package schemalate
+
+import (
+	"encoding/json"
+	"testing"
+
+	awsauth "github.com/estuary/connectors/go/auth/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAccessKeys(t *testing.T) {
+	var legacy = json.RawMessage(`{"s3_bucket":"bucket","aws_key_id":"old","aws_secret_key":"old"}`)
+	var configured = json.RawMessage(`{"s3_bucket":"bucket","credentials":{"auth_type":"AccessKey","awsAccessKeyId":"id","awsSecretAccessKey":"secret"}}`)
+
+	got, err := withAccessKeys(legacy, awsauth.AccessKeyCredentials("old", "old"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"s3_bucket":"bucket","aws_key_id":"old","aws_secret_key":"old"}`, string(got))
+
+	got, err = withAccessKeys(configured, awsauth.AccessKeyCredentials("id", "secret"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"s3_bucket":"bucket","aws_key_id":"id","aws_secret_key":"secret"}`, string(got))
+
+	got, err = withAccessKeys(legacy, awsauth.AccessKeyCredentials("", ""))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"s3_bucket":"bucket"}`, string(got))
+
+	got, err = withAccessKeys(configured, awsauth.CredentialConfig{AuthType: awsauth.ASSUME_ROLE_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/test"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"s3_bucket":"bucket","aws_key_id":"ROLE_CREDENTIALS_PLACEHOLDER","aws_secret_key":"ROLE_CREDENTIALS_PLACEHOLDER"}`, string(got))
+
+	_, err = withAccessKeys(configured, awsauth.CredentialConfig{AuthType: awsauth.WEB_IDENTITY_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/test"})
+	require.ErrorContains(t, err, `do not support "WebIdentity" credentials`)
+}
+
+func TestWithRoleCredentials(t *testing.T) {
+	var query = `CREATE EXTERNAL TABLE IF NOT EXISTS things_external (id TEXT) CREDENTIALS = ( AWS_KEY_ID = 'ROLE_CREDENTIALS_PLACEHOLDER' AWS_SECRET_KEY = 'ROLE_CREDENTIALS_PLACEHOLDER' ) URL = 's3://bucket/prefix/' OBJECT_PATTERN = '*.json' TYPE = (JSON);`
+
+	got, err := withRoleCredentials(query, awsauth.CredentialConfig{AuthType: awsauth.ASSUME_ROLE_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/test"})
+	require.NoError(t, err)
+	require.Equal(t, `CREATE EXTERNAL TABLE IF NOT EXISTS things_external (id TEXT) CREDENTIALS = (AWS_ROLE_ARN = 'arn:aws:iam::123456789012:role/test') URL = 's3://bucket/prefix/' OBJECT_PATTERN = '*.json' TYPE = (JSON);`, got)
+
+	got, err = withRoleCredentials(query, awsauth.CredentialConfig{AuthType: awsauth.ASSUME_ROLE_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/test", ExternalID: "it's"})
+	require.NoError(t, err)
+	require.Contains(t, got, `CREDENTIALS = (AWS_ROLE_ARN = 'arn:aws:iam::123456789012:role/test' AWS_ROLE_EXTERNAL_ID = 'it''s')`)
+	require.NotContains(t, got, roleCredentialsPlaceholder)
+
+	_, err = withRoleCredentials(`CREATE EXTERNAL TABLE things_external (id TEXT) URL = 's3://bucket/';`, awsauth.CredentialConfig{AuthType: awsauth.ASSUME_ROLE_AUTH_TYPE})
+	require.ErrorContains(t, err, "missing credentials")
+}
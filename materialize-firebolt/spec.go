@@ -2,25 +2,45 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	proto "github.com/gogo/protobuf/proto"
 )
 
-// LoadSpec loads existing spec from S3 and create a map of it by table name
-func LoadSpec(cfg config, materialization string) (map[string]*pf.MaterializationSpec_Binding, error) {
-	awsConfig := aws.Config{
-		Credentials: credentials.NewStaticCredentials(cfg.AWSKeyId, cfg.AWSSecretKey, ""),
+// newAWSSession creates a session for accessing the S3 bucket with the configured credentials.
+func newAWSSession(ctx context.Context, cfg config) (*session.Session, error) {
+	creds := awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSKeyId, cfg.AWSSecretKey)
+	v1Creds, err := creds.V1Credentials(ctx, awsConfig.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		return nil, fmt.Errorf("creating aws credentials: %w", err)
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: v1Creds,
 		Region:      &cfg.AWSRegion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating aws session: %w", err)
+	}
+
+	return sess, nil
+}
+
+// LoadSpec loads existing spec from S3 and create a map of it by table name
+func LoadSpec(ctx context.Context, cfg config, materialization string) (map[string]*pf.MaterializationSpec_Binding, error) {
+	sess, err := newAWSSession(ctx, cfg)
+	if err != nil {
+		return nil, err
 	}
-	sess := session.Must(session.NewSession(&awsConfig))
 	downloader := s3manager.NewDownloader(sess)
 	buf := aws.NewWriteAtBuffer([]byte{})
 
@@ -28,7 +48,7 @@ func LoadSpec(cfg config, materialization string) (map[string]*pf.Materializatio
 
 	var existing pf.MaterializationSpec
 
-	_, err := downloader.Download(buf, &s3.GetObjectInput{
+	_, err = downloader.Download(buf, &s3.GetObjectInput{
 		Bucket: &cfg.S3Bucket,
 		Key:    &existingSpecKey,
 	})
@@ -58,12 +78,11 @@ func LoadSpec(cfg config, materialization string) (map[string]*pf.Materializatio
 	return bindingsByTable, nil
 }
 
-func WriteSpec(cfg config, materialization *pf.MaterializationSpec, version string) error {
-	awsConfig := aws.Config{
-		Credentials: credentials.NewStaticCredentials(cfg.AWSKeyId, cfg.AWSSecretKey, ""),
-		Region:      &cfg.AWSRegion,
+func WriteSpec(ctx context.Context, cfg config, materialization *pf.MaterializationSpec, version string) error {
+	sess, err := newAWSSession(ctx, cfg)
+	if err != nil {
+		return err
 	}
-	sess := session.Must(session.NewSession(&awsConfig))
 	uploader := s3manager.NewUploader(sess)
 	materializationBytes, err := proto.Marshal(materialization)
 	if err != nil {
@@ -9,9 +9,9 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	"github.com/estuary/connectors/materialize-firebolt/schemalate"
@@ -50,20 +50,23 @@ func (driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boiler
 			})
 	}
 
-	queries, err := schemalate.GetQueriesBundle(open.Materialization)
+	queries, err := schemalate.GetQueriesBundle(open.Materialization, awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSKeyId, cfg.AWSSecretKey))
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("building firebolt search schema: %w", err)
 	}
 
+	awsSession, err := newAWSSession(ctx, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	var transactor = &transactor{
-		fb:           db,
-		queries:      queries,
-		bindings:     bindings,
-		awsKeyId:     cfg.AWSKeyId,
-		awsSecretKey: cfg.AWSSecretKey,
-		awsRegion:    cfg.AWSRegion,
-		bucket:       cfg.S3Bucket,
-		prefix:       strings.TrimLeft(fmt.Sprintf("%s/%s/", CleanPrefix(cfg.S3Prefix), open.Materialization.Name), "/"),
+		fb:         db,
+		queries:    queries,
+		bindings:   bindings,
+		awsSession: awsSession,
+		bucket:     cfg.S3Bucket,
+		prefix:     strings.TrimLeft(fmt.Sprintf("%s/%s/", CleanPrefix(cfg.S3Prefix), open.Materialization.Name), "/"),
 	}
 
 	return transactor, &pm.Response_Opened{}, nil, nil
@@ -85,14 +88,12 @@ type FireboltCheckpoint struct {
 }
 
 type transactor struct {
-	fb           *sql.DB
-	queries      *schemalate.QueriesBundle
-	awsKeyId     string
-	awsSecretKey string
-	awsRegion    string
-	bucket       string
-	prefix       string
-	bindings     []*binding
+	fb         *sql.DB
+	queries    *schemalate.QueriesBundle
+	awsSession *session.Session
+	bucket     string
+	prefix     string
+	bindings   []*binding
 
 	cp FireboltCheckpoint
 }
@@ -200,12 +201,7 @@ func (t *transactor) projectDocument(spec *pf.MaterializationSpec_Binding, keys
 }
 
 func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
-	var awsConfig = aws.Config{
-		Credentials: credentials.NewStaticCredentials(t.awsKeyId, t.awsSecretKey, ""),
-		Region:      &t.awsRegion,
-	}
-	var session = session.Must(session.NewSession(&awsConfig))
-	var uploader = s3manager.NewUploader(session)
+	var uploader = s3manager.NewUploader(t.awsSession)
 	var pipes = make([]*io.PipeWriter, len(t.bindings))
 	var group, groupCtx = errgroup.WithContext(it.Context())
 
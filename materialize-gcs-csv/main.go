@@ -29,10 +29,11 @@ func (c config) Validate() error {
 
 func (c config) CommonConfig() filesink.CommonConfig {
 	return filesink.CommonConfig{
-		Prefix:         c.Prefix,
-		Extension:      ".csv.gz",
-		UploadInterval: c.UploadInterval,
-		FileSizeLimit:  c.FileSizeLimit,
+		Prefix:            c.Prefix,
+		Extension:         ".csv.gz",
+		UploadInterval:    c.UploadInterval,
+		FileSizeLimit:     c.FileSizeLimit,
+		MaxOpenPartitions: c.MaxOpenPartitions,
 	}
 }
 
@@ -47,8 +48,8 @@ var driver = filesink.FileDriver{
 	NewStore: func(ctx context.Context, c filesink.Config) (filesink.Store, error) {
 		return filesink.NewGCSStore(ctx, c.(config).GCSStoreConfig)
 	},
-	NewEncoder: func(c filesink.Config, b *pf.MaterializationSpec_Binding, w io.WriteCloser) filesink.StreamEncoder {
-		return filesink.NewCsvStreamEncoder(c.(config).CsvConfig, b, w)
+	NewEncoder: func(c filesink.Config, b *pf.MaterializationSpec_Binding, w io.WriteCloser) (filesink.StreamEncoder, error) {
+		return filesink.NewCsvStreamEncoder(c.(config).CsvConfig, b, w), nil
 	},
 	NewConstraints: func(p *pf.Projection) *materialize.Response_Validated_Constraint {
 		return filesink.StdConstraints(p)
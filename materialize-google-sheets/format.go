@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/estuary/flow/go/protocols/fdb/tuple"
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"google.golang.org/api/sheets/v4"
+)
+
+// columnFormat is the display format of a materialized column.
+type columnFormat string
+
+const (
+	formatNone     columnFormat = ""
+	formatDate     columnFormat = "date"
+	formatDateTime columnFormat = "date-time"
+	formatCurrency columnFormat = "currency"
+	formatPercent  columnFormat = "percent"
+)
+
+var columnFormats = []columnFormat{formatDate, formatDateTime, formatCurrency, formatPercent}
+
+// sheetsEpoch is the zero day of Google Sheets date serial numbers.
+var sheetsEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// deriveColumnFormat returns the format of the projection's column, which is
+// the user-provided `override` if set, or otherwise is derived from its type
+// if `dateFormats` is enabled. Columns are otherwise left unformatted, so that
+// their values are written as-is.
+func deriveColumnFormat(projection *pf.Projection, override columnFormat, dateFormats bool) columnFormat {
+	if override != formatNone {
+		return override
+	} else if !dateFormats || projection == nil || projection.Inference.String_ == nil {
+		return formatNone
+	}
+
+	switch projection.Inference.String_.Format {
+	case "date":
+		return formatDate
+	case "date-time":
+		return formatDateTime
+	default:
+		return formatNone
+	}
+}
+
+func validateColumnFormats(formats map[string]columnFormat) error {
+	for field, format := range formats {
+		if !slices.Contains(columnFormats, format) {
+			return fmt.Errorf("invalid format %q for field %q: must be one of %v", format, field, columnFormats)
+		}
+	}
+	return nil
+}
+
+// numberFormat returns the sheets NumberFormat of the column, or nil if the
+// column uses the default automatic format of Google Sheets.
+func (f columnFormat) numberFormat() *sheets.NumberFormat {
+	switch f {
+	case formatDate:
+		return &sheets.NumberFormat{Type: "DATE", Pattern: "yyyy-mm-dd"}
+	case formatDateTime:
+		return &sheets.NumberFormat{Type: "DATE_TIME", Pattern: "yyyy-mm-dd hh:mm:ss"}
+	case formatCurrency:
+		return &sheets.NumberFormat{Type: "CURRENCY"}
+	case formatPercent:
+		return &sheets.NumberFormat{Type: "PERCENT", Pattern: "0.00%"}
+	default:
+		return nil
+	}
+}
+
+// toCell marshals the tuple element into a cell of the column. Dates and
+// date-times are written as serial numbers so that the column's number format
+// applies to them. Values which don't parse are written as-is.
+func (f columnFormat) toCell(e tuple.TupleElement) *sheets.CellData {
+	var s, ok = e.(string)
+	if !ok {
+		return valueToCell(e)
+	}
+
+	var layout string
+	switch f {
+	case formatDate:
+		layout = time.DateOnly
+	case formatDateTime:
+		layout = time.RFC3339Nano
+	default:
+		return valueToCell(e)
+	}
+
+	if t, err := time.Parse(layout, s); err == nil {
+		return valueToCell(timeToSerial(t))
+	}
+	return valueToCell(e)
+}
+
+// timeToSerial converts a time into a Google Sheets date serial number, which
+// counts days since the epoch in UTC.
+func timeToSerial(t time.Time) float64 {
+	var secs = float64(t.Unix()-sheetsEpoch.Unix()) + float64(t.Nanosecond())/float64(time.Second)
+	return secs / (24 * 60 * 60)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveColumnFormat(t *testing.T) {
+	var stringProjection = func(format string) *pf.Projection {
+		return &pf.Projection{Inference: pf.Inference{
+			Types:   []string{"string"},
+			String_: &pf.Inference_String{Format: format},
+		}}
+	}
+	var numberProjection = &pf.Projection{Inference: pf.Inference{Types: []string{"number"}}}
+
+	require.Equal(t, formatDate, deriveColumnFormat(stringProjection("date"), formatNone, true))
+	require.Equal(t, formatDateTime, deriveColumnFormat(stringProjection("date-time"), formatNone, true))
+	require.Equal(t, formatNone, deriveColumnFormat(stringProjection("email"), formatNone, true))
+	require.Equal(t, formatNone, deriveColumnFormat(numberProjection, formatNone, true))
+	require.Equal(t, formatCurrency, deriveColumnFormat(numberProjection, formatCurrency, true))
+	require.Equal(t, formatPercent, deriveColumnFormat(stringProjection("date"), formatPercent, true))
+
+	// Date formats are only derived when enabled.
+	require.Equal(t, formatNone, deriveColumnFormat(stringProjection("date"), formatNone, false))
+	require.Equal(t, formatNone, deriveColumnFormat(stringProjection("date-time"), formatNone, false))
+	require.Equal(t, formatDateTime, deriveColumnFormat(stringProjection("email"), formatDateTime, false))
+
+	require.NoError(t, validateColumnFormats(map[string]columnFormat{"a": formatCurrency, "b": formatDate}))
+	require.Error(t, validateColumnFormats(map[string]columnFormat{"a": "fancy"}))
+}
+
+func TestColumnFormatToCell(t *testing.T) {
+	require.Equal(t, 0.0, timeToSerial(sheetsEpoch))
+	require.Equal(t, 45292.5, timeToSerial(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	var cell = formatDate.toCell("2024-01-01")
+	require.Equal(t, 45292.0, *cell.UserEnteredValue.NumberValue)
+
+	cell = formatDateTime.toCell("2024-01-01T18:00:00+06:00")
+	require.Equal(t, 45292.5, *cell.UserEnteredValue.NumberValue)
+
+	// Values which don't parse are written as-is.
+	cell = formatDateTime.toCell("not a date")
+	require.Equal(t, "not a date", *cell.UserEnteredValue.StringValue)
+
+	cell = formatCurrency.toCell(int64(12))
+	require.Equal(t, 12.0, *cell.UserEnteredValue.NumberValue)
+	cell = formatNone.toCell("2024-01-01")
+	require.Equal(t, "2024-01-01", *cell.UserEnteredValue.StringValue)
+
+	require.Nil(t, formatNone.numberFormat())
+	require.Equal(t, "CURRENCY", formatCurrency.numberFormat().Type)
+}
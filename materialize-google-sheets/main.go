@@ -31,12 +31,15 @@ type driver struct{}
 type config struct {
 	SpreadsheetURL string                        `json:"spreadsheetUrl" jsonschema:"title=Spreadsheet URL"`
 	Credentials    *google_auth.CredentialConfig `json:"credentials" jsonschema:"title=Authentication"`
+	SummarySheet   string                        `json:"summarySheet,omitempty" jsonschema:"title=Summary Sheet Name"`
 }
 
 func (config) GetFieldDocString(fieldName string) string {
 	switch fieldName {
 	case "SpreadsheetURL":
 		return "URL of the spreadsheet to materialize into."
+	case "SummarySheet":
+		return "Optional name of a sheet to create in the spreadsheet which summarizes the row counts and last update times of all materialized sheets."
 	default:
 		return ""
 	}
@@ -73,13 +76,25 @@ func (c config) buildService(ctx context.Context) (*sheets.Service, error) {
 }
 
 type resource struct {
-	Sheet string `json:"sheet" jsonschema:"title=Sheet Name" jsonschema_extras:"x-collection-name=true"`
+	Sheet         string                  `json:"sheet" jsonschema:"title=Sheet Name" jsonschema_extras:"x-collection-name=true"`
+	MaxRows       int                     `json:"maxRows,omitempty" jsonschema:"title=Maximum Rows"`
+	RollingRows   bool                    `json:"rollingRows,omitempty" jsonschema:"title=Keep Most Recent Rows,default=false"`
+	ColumnFormats map[string]columnFormat `json:"columnFormats,omitempty" jsonschema:"title=Column Formats"`
+	DateFormats   bool                    `json:"dateFormats,omitempty" jsonschema:"title=Format Dates,default=false"`
 }
 
 func (resource) GetFieldDocString(fieldName string) string {
 	switch fieldName {
 	case "Sheet":
 		return "Name of the spreadsheet sheet to materialize into."
+	case "MaxRows":
+		return "Maximum number of rows to keep in the sheet. The materialization fails if the sheet would exceed this number of rows, unless Keep Most Recent Rows is enabled. Sheets are always limited to the Google Sheets cell limit."
+	case "RollingRows":
+		return "Instead of failing when the sheet exceeds its maximum number of rows (or the Google Sheets cell limit), remove the least recently updated rows to keep only the most recent rows."
+	case "ColumnFormats":
+		return "Display formats of columns, keyed by field name. Formats may be 'date', 'date-time', 'currency' or 'percent'. Values of columns with a 'date' or 'date-time' format are written as dates, and date-times are shown in UTC."
+	case "DateFormats":
+		return "Format columns of fields with a 'date' or 'date-time' string format as dates, unless another column format is configured. Values are written as dates, and date-times are shown in UTC without their original offset."
 	default:
 		return ""
 	}
@@ -88,8 +103,10 @@ func (resource) GetFieldDocString(fieldName string) string {
 func (r resource) Validate() error {
 	if r.Sheet == "" {
 		return fmt.Errorf("missing required sheet name")
+	} else if r.MaxRows < 0 {
+		return fmt.Errorf("maxRows must not be negative")
 	}
-	return nil
+	return validateColumnFormats(r.ColumnFormats)
 }
 
 type driverCheckpoint struct {
@@ -152,6 +169,13 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 		var res resource
 		if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
 			return nil, fmt.Errorf("parsing resource config: %w", err)
+		} else if cfg.SummarySheet != "" && res.Sheet == cfg.SummarySheet {
+			return nil, cerrors.NewUserError(nil, fmt.Sprintf("sheet %q is the configured summary sheet and cannot be materialized into", res.Sheet))
+		}
+		for field := range res.ColumnFormats {
+			if binding.Collection.GetProjection(field) == nil {
+				return nil, cerrors.NewUserError(nil, fmt.Sprintf("column format of sheet %q is configured for field %q, which doesn't exist", res.Sheet, field))
+			}
 		}
 
 		var constraints = make(map[string]*pm.Response_Validated_Constraint)
@@ -206,22 +230,30 @@ func (driver) Apply(ctx context.Context, req *pm.Request_Apply) (*pm.Response_Ap
 	var description string
 	var rand = rand.New(rand.NewSource(time.Now().UnixMicro()))
 
+	var sheetNames []string
 	for _, binding := range req.Materialization.Bindings {
 		var res resource
 		if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
 			return nil, fmt.Errorf("parsing resource config: %w", err)
 		}
-		var _, exists = sheetIDs[res.Sheet]
+		sheetNames = append(sheetNames, res.Sheet)
+	}
+	if cfg.SummarySheet != "" {
+		sheetNames = append(sheetNames, cfg.SummarySheet)
+	}
+
+	for _, sheetName := range sheetNames {
+		var _, exists = sheetIDs[sheetName]
 
 		if !exists {
-			description += fmt.Sprintf("Created sheet %q.\n", res.Sheet)
+			description += fmt.Sprintf("Created sheet %q.\n", sheetName)
 
 			// Create a new sheet.
 			var sheetID = int64(rand.Int31())
 			actions = append(actions, &sheets.Request{
 				AddSheet: &sheets.AddSheetRequest{
 					Properties: &sheets.SheetProperties{
-						Title:   res.Sheet,
+						Title:   sheetName,
 						SheetId: sheetID,
 					},
 				},
@@ -278,11 +310,29 @@ func (driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boiler
 		return nil, nil, nil, fmt.Errorf("writing sheet headers: %w", err)
 	}
 
+	var summary *summarySheet
+	if cfg.SummarySheet != "" {
+		sheetIDs, err := loadSheetIDMapping(svc, cfg.spreadsheetID())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		summaryID, ok := sheetIDs[cfg.SummarySheet]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("summary sheet %q doesn't exist", cfg.SummarySheet)
+		}
+		summary = &summarySheet{SheetID: summaryID, SheetName: cfg.SummarySheet}
+
+		if err := summary.writeHeaders(ctx, svc, cfg.spreadsheetID(), bindings); err != nil {
+			return nil, nil, nil, fmt.Errorf("writing summary sheet headers: %w", err)
+		}
+	}
+
 	var transactor = &transactor{
 		bindings:      bindings,
 		client:        svc,
 		round:         checkpoint.Round,
 		spreadsheetId: cfg.spreadsheetID(),
+		summary:       summary,
 	}
 	return transactor, &pm.Response_Opened{}, nil, nil
 }
@@ -29,7 +29,8 @@ func checkCellCount(rows int, cellsPerRow int) error {
 		return fmt.Errorf(
 			"Maximum cells limit of %d exceeded for this materialization. If you are materializing "+
 				"a collection with a large number of unique keys, consider creating a derivation to "+
-				"transform your data into a collection with fewer unique keys.",
+				"transform your data into a collection with fewer unique keys, or enable keeping "+
+				"only the most recent rows of the sheet.",
 			cellsLimit,
 		)
 	}
@@ -37,6 +38,20 @@ func checkCellCount(rows int, cellsPerRow int) error {
 	return nil
 }
 
+func checkRowCount(rows int, b transactorBinding) error {
+	if err := checkCellCount(rows, b.columnCount()); err != nil {
+		return err
+	} else if b.MaxRows != 0 && rows > b.MaxRows {
+		return fmt.Errorf(
+			"Maximum rows limit of %d exceeded for sheet %q. Increase the limit, or enable keeping "+
+				"only the most recent rows of the sheet.",
+			b.MaxRows, b.UserSheetName,
+		)
+	}
+
+	return nil
+}
+
 type transactor struct {
 	bindings []transactorBinding
 	client   *sheets.Service
@@ -45,6 +60,12 @@ type transactor struct {
 	round int64
 	// Spreadsheet to which we're materializing.
 	spreadsheetId string
+	// Summary sheet of the spreadsheet, which is not written if nil.
+	summary *summarySheet
+	// Requests which delete the rows evicted by the last stored transaction.
+	// They're sent once it's acknowledged, so that the evicted rows and their
+	// row states remain available to recover from if it instead rolls back.
+	evictions []*sheets.Request
 }
 
 type transactorRow struct {
@@ -61,6 +82,16 @@ type transactorBinding struct {
 	UserSheetId int64
 	// User-facing sheet name for this binding.
 	UserSheetName string
+	// Formats of the key and value columns of this binding, in order.
+	Formats []columnFormat
+	// Maximum number of rows of the sheet, or zero if only the cells limit applies.
+	MaxRows int
+	// Remove the least recently updated rows rather than fail when the
+	// sheet exceeds its row limit.
+	RollingRows bool
+	// Time of the last transaction which stored to this binding, or zero if
+	// it hasn't been stored to since the transactor started.
+	lastUpdated time.Time
 }
 
 func (b transactorBinding) columnCount() int {
@@ -68,8 +99,31 @@ func (b transactorBinding) columnCount() int {
 	return len(b.Fields.Keys) + len(b.Fields.Values) + 1
 }
 
-func (t *transactor) UnmarshalState(state json.RawMessage) error                  { return nil }
-func (t *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error) { return nil, nil }
+// rowLimit is the maximum number of rows which are kept when RollingRows is enabled.
+func (b transactorBinding) rowLimit() int {
+	var limit = cellsLimit / b.columnCount()
+	if b.MaxRows != 0 && b.MaxRows < limit {
+		limit = b.MaxRows
+	}
+	return limit
+}
+
+func (t *transactor) UnmarshalState(state json.RawMessage) error { return nil }
+
+func (t *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error) {
+	if len(t.evictions) == 0 {
+		return nil, nil
+	}
+
+	// Row indexes of the evictions are those of the sheet as of the
+	// acknowledged transaction, which no other transaction has since modified.
+	if err := batchRequestWithRetry(ctx, t.client, t.spreadsheetId, t.evictions); err != nil {
+		return nil, fmt.Errorf("deleting evicted rows: %w", err)
+	}
+	t.evictions = nil
+
+	return nil, nil
+}
 
 func (d *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage) error) error {
 	it.WaitForAcknowledged()
@@ -110,21 +164,25 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 
 	// Gather all of the stored rows on a per-binding basis.
 	for it.Next() {
+		var b = d.bindings[it.Binding]
+
 		// Verify that we don't read an excessive amount of data from the store iterator, which
 		// would indicate we are reading from a high cardinality collection that will not fit into a
 		// reasonable amount of connector memory.
-		if err := checkCellCount(len(stores[it.Binding]), d.bindings[it.Binding].columnCount()); err != nil {
-			return nil, err
+		if !b.RollingRows {
+			if err := checkRowCount(len(stores[it.Binding]), b); err != nil {
+				return nil, err
+			}
 		}
 
 		// Marshal key and value fields into cells of the row.
 		// cells[0] is a placeholder for internal state that's written later.
 		var cells = make([]*sheets.CellData, 1, 1+len(it.Key)+len(it.Values))
-		for _, e := range it.Key {
-			cells = append(cells, valueToCell(e))
+		for i, e := range it.Key {
+			cells = append(cells, b.Formats[i].toCell(e))
 		}
-		for _, e := range it.Values {
-			cells = append(cells, valueToCell(e))
+		for i, e := range it.Values {
+			cells = append(cells, b.Formats[len(it.Key)+i].toCell(e))
 		}
 
 		stores[it.Binding] = append(stores[it.Binding], storedRow{
@@ -165,9 +223,11 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		for pi != len(prev) || si != len(stores) {
 			// Verify that our in-memory view of the sheet has not grown excessively large would
 			// indicate we are reading from a high cardinality collection that will not fit into a
-			// reasonable amount of connector memory.
-			if err := checkCellCount(len(next)+len(stores), d.bindings[bindInd].columnCount()); err != nil {
-				return nil, err
+			// reasonable amount of connector memory. Rolling sheets are instead trimmed below.
+			if !d.bindings[bindInd].RollingRows {
+				if err := checkRowCount(len(next)+len(stores), d.bindings[bindInd]); err != nil {
+					return nil, err
+				}
 			}
 
 			// Compare next `prev` vs `stores`.
@@ -254,11 +314,26 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 
 		} // Done with merge of `prev` and `stored` into `next`.
 
+		// Remove the least recently updated rows of a rolling sheet which exceeds
+		// its limit. The rows are deleted from the sheet once this transaction
+		// has been acknowledged, and are otherwise recovered along with the
+		// other rows if it rolls back.
+		if d.bindings[bindInd].RollingRows {
+			var deleteRows []*sheets.Request
+			next, deleteRows = evictRows(next, d.bindings[bindInd].rowLimit(), d.bindings[bindInd].UserSheetId)
+			d.evictions = append(d.evictions, deleteRows...)
+		}
+
 		d.bindings[bindInd].rows = next
+		d.bindings[bindInd].lastUpdated = started
 		batchRequests = append(batchRequests, addRows...)
 		batchRequests = append(batchRequests, updateCells...)
 	}
 
+	if d.summary != nil {
+		batchRequests = append(batchRequests, d.summary.updateRequest(d.bindings))
+	}
+
 	if err := batchRequestWithRetry(
 		it.Context(),
 		d.client,
@@ -279,6 +354,58 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 	}, nil
 }
 
+// evictRows removes the rows with the oldest rounds from `rows` until at most
+// `limit` remain, returning the remaining rows and requests which delete the
+// removed rows from the sheet. Rows of equal rounds are removed in key order.
+func evictRows(rows []transactorRow, limit int, sheetID int64) ([]transactorRow, []*sheets.Request) {
+	if len(rows) <= limit {
+		return rows, nil
+	}
+
+	var order = make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return rows[order[i]].Round < rows[order[j]].Round })
+
+	var evict = order[:len(rows)-limit]
+	sort.Ints(evict)
+
+	var kept = make([]transactorRow, 0, limit)
+	for i, ei := 0, 0; i != len(rows); i++ {
+		if ei != len(evict) && evict[ei] == i {
+			ei++
+		} else {
+			kept = append(kept, rows[i])
+		}
+	}
+
+	// Delete runs of evicted rows from the bottom of the sheet up, so that
+	// the row indexes of earlier runs are unaffected. Sheet row indexes are
+	// 1-indexed due to the header.
+	var deletes []*sheets.Request
+	for i := len(evict) - 1; i >= 0; i-- {
+		var rowInd = int64(evict[i] + 1)
+
+		if l := len(deletes); l != 0 && deletes[l-1].DeleteDimension.Range.StartIndex == rowInd+1 {
+			deletes[l-1].DeleteDimension.Range.StartIndex--
+		} else {
+			deletes = append(deletes, &sheets.Request{
+				DeleteDimension: &sheets.DeleteDimensionRequest{
+					Range: &sheets.DimensionRange{
+						SheetId:    sheetID,
+						Dimension:  "ROWS",
+						StartIndex: rowInd,
+						EndIndex:   rowInd + 1,
+					},
+				},
+			})
+		}
+	}
+
+	return kept, deletes
+}
+
 func valueToCell(e tuple.TupleElement) *sheets.CellData {
 	switch ee := e.(type) {
 	case nil:
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictRows(t *testing.T) {
+	var rows = []transactorRow{
+		{PackedKey: "a", Round: 3},
+		{PackedKey: "b", Round: 1},
+		{PackedKey: "c", Round: 1},
+		{PackedKey: "d", Round: 0},
+		{PackedKey: "e", Round: 3},
+		{PackedKey: "f", Round: 2},
+		{PackedKey: "g", Round: 1},
+	}
+
+	// Rows within the limit are unchanged.
+	var kept, deletes = evictRows(rows, 7, 42)
+	require.Equal(t, rows, kept)
+	require.Empty(t, deletes)
+
+	// The placeholder and the oldest rows are evicted, in key order for equal rounds.
+	kept, deletes = evictRows(rows, 3, 42)
+	require.Equal(t, []transactorRow{
+		{PackedKey: "a", Round: 3},
+		{PackedKey: "e", Round: 3},
+		{PackedKey: "f", Round: 2},
+	}, kept)
+
+	// Runs of deleted rows are 1-indexed due to the header, and ordered from
+	// the bottom of the sheet up.
+	var ranges [][2]int64
+	for _, d := range deletes {
+		require.Equal(t, int64(42), d.DeleteDimension.Range.SheetId)
+		require.Equal(t, "ROWS", d.DeleteDimension.Range.Dimension)
+		ranges = append(ranges, [2]int64{d.DeleteDimension.Range.StartIndex, d.DeleteDimension.Range.EndIndex})
+	}
+	require.Equal(t, [][2]int64{{7, 8}, {2, 5}}, ranges)
+}
+
+func TestCheckRowCount(t *testing.T) {
+	var b = transactorBinding{UserSheetName: "sheet"}
+	b.Fields.Keys = []string{"key"}
+	b.Fields.Values = []string{"value"}
+
+	require.NoError(t, checkRowCount(cellsLimit/3, b))
+	require.ErrorContains(t, checkRowCount(cellsLimit/3+1, b), "Maximum cells limit")
+	require.Equal(t, cellsLimit/3, b.rowLimit())
+
+	b.MaxRows = 10
+	require.NoError(t, checkRowCount(10, b))
+	require.ErrorContains(t, checkRowCount(11, b), `Maximum rows limit of 10 exceeded for sheet "sheet"`)
+	require.Equal(t, 10, b.rowLimit())
+}
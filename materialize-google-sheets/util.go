@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"slices"
 	"time"
 
 	pf "github.com/estuary/flow/go/protocols/flow"
@@ -27,6 +28,16 @@ func buildTransactorBindings(
 	for bindInd, binding := range bindings {
 		var state = states[bindInd]
 
+		var res resource
+		if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
+			return nil, fmt.Errorf("parsing resource config: %w", err)
+		}
+
+		var formats []columnFormat
+		for _, field := range slices.Concat(binding.FieldSelection.Keys, binding.FieldSelection.Values) {
+			formats = append(formats, deriveColumnFormat(binding.Collection.GetProjection(field), res.ColumnFormats[field], res.DateFormats))
+		}
+
 		var rows []transactorRow
 		for rowInd, row := range state.Rows {
 
@@ -75,6 +86,9 @@ func buildTransactorBindings(
 			Fields:        binding.FieldSelection,
 			UserSheetId:   state.SheetID,
 			UserSheetName: state.SheetName,
+			Formats:       formats,
+			MaxRows:       res.MaxRows,
+			RollingRows:   res.RollingRows,
 		})
 	}
 
@@ -84,16 +98,13 @@ func buildTransactorBindings(
 func writeSheetHeaders(ctx context.Context, client *sheets.Service, spreadsheetID string, bindings []transactorBinding) error {
 	var actions []*sheets.Request
 	for _, binding := range bindings {
+		// The first column contains Flow internal data and has no header.
 		var headers = []*sheets.CellData{{}}
-		for i := range binding.Fields.Keys {
-			headers = append(headers, &sheets.CellData{
-				UserEnteredValue: &sheets.ExtendedValue{StringValue: &binding.Fields.Keys[i]},
-			})
+		for _, field := range binding.Fields.Keys {
+			headers = append(headers, headerCell(field))
 		}
-		for i := range binding.Fields.Values {
-			headers = append(headers, &sheets.CellData{
-				UserEnteredValue: &sheets.ExtendedValue{StringValue: &binding.Fields.Values[i]},
-			})
+		for _, field := range binding.Fields.Values {
+			headers = append(headers, headerCell(field))
 		}
 		actions = append(actions,
 			// Resize horizontally to the correct number of fields, and freeze the header row
@@ -109,7 +120,7 @@ func writeSheetHeaders(ctx context.Context, client *sheets.Service, spreadsheetI
 					},
 				},
 			},
-			// Write header names to row 0
+			// Write bolded header names to row 0
 			&sheets.Request{
 				UpdateCells: &sheets.UpdateCellsRequest{
 					Range: &sheets.GridRange{
@@ -118,7 +129,7 @@ func writeSheetHeaders(ctx context.Context, client *sheets.Service, spreadsheetI
 						EndRowIndex:   1,
 					},
 					Rows:   []*sheets.RowData{{Values: headers}},
-					Fields: "userEnteredValue",
+					Fields: "userEnteredValue,userEnteredFormat.textFormat.bold",
 				},
 			},
 			// Hide the first column, which contains Flow internal data
@@ -137,10 +148,125 @@ func writeSheetHeaders(ctx context.Context, client *sheets.Service, spreadsheetI
 				},
 			})
 
+		// Apply number formats to the data rows of formatted columns.
+		for colInd, format := range binding.Formats {
+			if nf := format.numberFormat(); nf != nil {
+				actions = append(actions, columnFormatRequest(binding.UserSheetId, int64(colInd+1), nf))
+			}
+		}
 	}
 	return batchRequestWithRetry(ctx, client, spreadsheetID, actions)
 }
 
+func headerCell(name string) *sheets.CellData {
+	return &sheets.CellData{
+		UserEnteredValue: &sheets.ExtendedValue{StringValue: &name},
+		UserEnteredFormat: &sheets.CellFormat{
+			TextFormat: &sheets.TextFormat{Bold: true},
+		},
+	}
+}
+
+// columnFormatRequest applies a number format to all rows of a column after the header.
+func columnFormatRequest(sheetID int64, colInd int64, nf *sheets.NumberFormat) *sheets.Request {
+	return &sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: &sheets.GridRange{
+				SheetId:          sheetID,
+				StartRowIndex:    1,
+				StartColumnIndex: colInd,
+				EndColumnIndex:   colInd + 1,
+			},
+			Cell: &sheets.CellData{
+				UserEnteredFormat: &sheets.CellFormat{NumberFormat: nf},
+			},
+			Fields: "userEnteredFormat.numberFormat",
+		},
+	}
+}
+
+// summarySheet is a sheet which summarizes the other sheets of the materialization,
+// with a row for each binding.
+type summarySheet struct {
+	SheetID   int64
+	SheetName string
+}
+
+var summaryHeaders = []string{"Sheet", "Rows", "Last Updated"}
+
+// writeHeaders writes the bolded and frozen header of the summary sheet, and
+// resizes it to a row for each of `bindings`.
+func (s summarySheet) writeHeaders(ctx context.Context, client *sheets.Service, spreadsheetID string, bindings []transactorBinding) error {
+	var headers []*sheets.CellData
+	for _, name := range summaryHeaders {
+		headers = append(headers, headerCell(name))
+	}
+
+	return batchRequestWithRetry(ctx, client, spreadsheetID, []*sheets.Request{
+		{
+			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+				Fields: "gridProperties(rowCount,columnCount,frozenRowCount)",
+				Properties: &sheets.SheetProperties{
+					SheetId: s.SheetID,
+					GridProperties: &sheets.GridProperties{
+						RowCount:       int64(1 + len(bindings)),
+						ColumnCount:    int64(len(headers)),
+						FrozenRowCount: 1,
+					},
+				},
+			},
+		},
+		{
+			UpdateCells: &sheets.UpdateCellsRequest{
+				Range: &sheets.GridRange{
+					SheetId:       s.SheetID,
+					StartRowIndex: 0,
+					EndRowIndex:   1,
+				},
+				Rows:   []*sheets.RowData{{Values: headers}},
+				Fields: "userEnteredValue,userEnteredFormat.textFormat.bold",
+			},
+		},
+		columnFormatRequest(s.SheetID, 2, formatDateTime.numberFormat()),
+	})
+}
+
+// updateRequest returns a request which writes the current row count and last
+// update time of each binding into the summary sheet. The last update time of
+// a binding which hasn't been stored to since the transactor started is left as-is.
+func (s summarySheet) updateRequest(bindings []transactorBinding) *sheets.Request {
+	var rows []*sheets.RowData
+	for _, binding := range bindings {
+		var count int
+		for _, row := range binding.rows {
+			if row.Round != 0 {
+				count++
+			}
+		}
+
+		var cells = []*sheets.CellData{
+			valueToCell(binding.UserSheetName),
+			valueToCell(int64(count)),
+		}
+		if !binding.lastUpdated.IsZero() {
+			cells = append(cells, valueToCell(timeToSerial(binding.lastUpdated)))
+		}
+		rows = append(rows, &sheets.RowData{Values: cells})
+	}
+
+	// Cells which are not present in `rows` are not modified.
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start: &sheets.GridCoordinate{
+				SheetId:  s.SheetID,
+				RowIndex: 1,
+			},
+			Rows:   rows,
+			Fields: "userEnteredValue",
+		},
+	}
+}
+
 // SheetState is the recovered state of a materialized sheet.
 type SheetState struct {
 	SheetID   int64
@@ -7,6 +7,7 @@ import (
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const specCollection = "flow_materializations"
@@ -17,8 +18,31 @@ type mongoApplier struct {
 }
 
 func (e *mongoApplier) CreateResource(ctx context.Context, spec *pf.MaterializationSpec, bindingIndex int) (string, boilerplate.ActionApplyFn, error) {
-	// No-op since new collections are automatically created when data is added to them.
-	return "", nil, nil
+	binding := spec.Bindings[bindingIndex]
+	res, err := resolveResourceConfig(binding.ResourceConfigJson)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if res.TimeSeries == nil {
+		// No-op since new collections are automatically created when data is added to them.
+		return "", nil, nil
+	}
+
+	// Time series collections must be created explicitly before any data is
+	// added to them.
+	tsOpts := options.TimeSeries().SetTimeField(res.TimeSeries.TimeField)
+	if res.TimeSeries.MetaField != "" {
+		tsOpts.SetMetaField(res.TimeSeries.MetaField)
+	}
+	if res.TimeSeries.Granularity != "" {
+		tsOpts.SetGranularity(res.TimeSeries.Granularity)
+	}
+
+	path := binding.ResourcePath
+	return fmt.Sprintf("create time series collection %q", path[1]), func(ctx context.Context) error {
+		return e.client.Database(path[0]).CreateCollection(ctx, path[1], options.CreateCollection().SetTimeSeriesOptions(tsOpts))
+	}, nil
 }
 
 func (a *mongoApplier) DeleteResource(ctx context.Context, path []string) (string, boilerplate.ActionApplyFn, error) {
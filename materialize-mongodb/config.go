@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"slices"
 
 	"net/url"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
 )
 
 type sshForwarding struct {
@@ -74,14 +77,85 @@ func (c *config) ToURI() string {
 	return uri.String()
 }
 
+const (
+	updateModeReplace = "replace"
+	updateModeSet     = "set"
+)
+
 type resource struct {
-	Collection   string `json:"collection" jsonschema:"title=Collection name" jsonschema_extras:"x-collection-name=true"`
-	DeltaUpdates bool   `json:"delta_updates,omitempty" jsonschema:"title=Delta updates,default=false" jsonschema_extras:"x-delta-updates=true"`
+	Collection   string            `json:"collection" jsonschema:"title=Collection name" jsonschema_extras:"x-collection-name=true"`
+	DeltaUpdates bool              `json:"delta_updates,omitempty" jsonschema:"title=Delta updates,default=false" jsonschema_extras:"x-delta-updates=true"`
+	UpdateMode   string            `json:"update_mode,omitempty" jsonschema:"title=Update Mode,description=How existing documents are updated. 'replace' replaces the whole document. 'set' upserts only the fields of the materialized document with $set and leaves other fields of the existing document unchanged.,enum=replace,enum=set,default=replace"`
+	UnsetNulls   bool              `json:"unset_nulls,omitempty" jsonschema:"title=Unset Null Fields,description=When using the 'set' update mode remove fields with null values from the existing document with $unset instead of setting them to null.,default=false"`
+	TimeSeries   *timeSeriesConfig `json:"time_series,omitempty" jsonschema:"title=Time Series,description=Create the collection as a time series collection. Requires delta updates."`
+}
+
+type timeSeriesConfig struct {
+	TimeField   string `json:"timeField" jsonschema:"title=Time Field,description=Name of the top-level field of documents which contains the date-time of each document."`
+	MetaField   string `json:"metaField,omitempty" jsonschema:"title=Meta Field,description=Name of the top-level field of documents which contains metadata used to group related documents."`
+	Granularity string `json:"granularity,omitempty" jsonschema:"title=Granularity,description=Granularity of the time series data. Leave blank to use the MongoDB default.,enum=seconds,enum=minutes,enum=hours"`
 }
 
 func (r resource) Validate() error {
 	if r.Collection == "" {
 		return fmt.Errorf("collection is required")
 	}
+
+	switch r.UpdateMode {
+	case "", updateModeReplace:
+		if r.UnsetNulls {
+			return fmt.Errorf("unset_nulls requires the %q update mode", updateModeSet)
+		}
+	case updateModeSet:
+		if r.DeltaUpdates {
+			return fmt.Errorf("the %q update mode cannot be used with delta updates", updateModeSet)
+		}
+	default:
+		return fmt.Errorf("invalid update_mode %q: must be %q or %q", r.UpdateMode, updateModeReplace, updateModeSet)
+	}
+
+	if r.TimeSeries != nil {
+		if !r.DeltaUpdates {
+			return fmt.Errorf("time_series requires delta updates")
+		} else if r.TimeSeries.TimeField == "" {
+			return fmt.Errorf("time_series timeField is required")
+		} else if r.TimeSeries.TimeField == r.TimeSeries.MetaField {
+			return fmt.Errorf("time_series timeField and metaField must be different")
+		} else if !slices.Contains([]string{"", "seconds", "minutes", "hours"}, r.TimeSeries.Granularity) {
+			return fmt.Errorf("invalid time_series granularity %q: must be 'seconds', 'minutes' or 'hours'", r.TimeSeries.Granularity)
+		}
+	}
+
+	return nil
+}
+
+// validateProjections checks that the fields of the time series exist as
+// top-level projections of the collection. MongoDB requires that the time field
+// of every document is a date, so it must always exist and be a date-time
+// string.
+func (ts timeSeriesConfig) validateProjections(collection *pf.CollectionSpec) error {
+	timeProjection := topLevelProjection(collection, ts.TimeField)
+	if timeProjection == nil {
+		return fmt.Errorf("time_series timeField %q is not a top-level field of collection %q", ts.TimeField, collection.Name)
+	} else if timeProjection.Inference.Exists != pf.Inference_MUST || slices.Contains(timeProjection.Inference.Types, "null") {
+		return fmt.Errorf("time_series timeField %q must be a required field of collection %q", ts.TimeField, collection.Name)
+	} else if !slices.Equal(timeProjection.Inference.Types, []string{"string"}) ||
+		timeProjection.Inference.String_ == nil || timeProjection.Inference.String_.Format != "date-time" {
+		return fmt.Errorf("time_series timeField %q of collection %q must be a string with format date-time", ts.TimeField, collection.Name)
+	}
+
+	if ts.MetaField != "" && topLevelProjection(collection, ts.MetaField) == nil {
+		return fmt.Errorf("time_series metaField %q is not a top-level field of collection %q", ts.MetaField, collection.Name)
+	}
+
+	return nil
+}
+
+func topLevelProjection(collection *pf.CollectionSpec, field string) *pf.Projection {
+	for idx := range collection.Projections {
+		if collection.Projections[idx].Ptr == "/"+field {
+			return &collection.Projections[idx]
+		}
+	}
 	return nil
 }
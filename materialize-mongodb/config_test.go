@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
+	pf "github.com/estuary/flow/go/protocols/flow"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	"github.com/stretchr/testify/require"
 )
@@ -20,3 +21,131 @@ func TestSpecification(t *testing.T) {
 
 	cupaloy.SnapshotT(t, formatted)
 }
+
+func TestResourceValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		res     resource
+		wantErr string
+	}{
+		{
+			name: "replace",
+			res:  resource{Collection: "docs"},
+		},
+		{
+			name: "set with unset nulls",
+			res:  resource{Collection: "docs", UpdateMode: updateModeSet, UnsetNulls: true},
+		},
+		{
+			name:    "unset nulls without set",
+			res:     resource{Collection: "docs", UnsetNulls: true},
+			wantErr: `unset_nulls requires the "set" update mode`,
+		},
+		{
+			name:    "set with delta updates",
+			res:     resource{Collection: "docs", UpdateMode: updateModeSet, DeltaUpdates: true},
+			wantErr: `the "set" update mode cannot be used with delta updates`,
+		},
+		{
+			name:    "invalid update mode",
+			res:     resource{Collection: "docs", UpdateMode: "merge"},
+			wantErr: `invalid update_mode "merge": must be "replace" or "set"`,
+		},
+		{
+			name: "time series",
+			res:  resource{Collection: "docs", DeltaUpdates: true, TimeSeries: &timeSeriesConfig{TimeField: "ts", MetaField: "sensor", Granularity: "minutes"}},
+		},
+		{
+			name:    "time series without delta updates",
+			res:     resource{Collection: "docs", TimeSeries: &timeSeriesConfig{TimeField: "ts"}},
+			wantErr: "time_series requires delta updates",
+		},
+		{
+			name:    "time series without time field",
+			res:     resource{Collection: "docs", DeltaUpdates: true, TimeSeries: &timeSeriesConfig{}},
+			wantErr: "time_series timeField is required",
+		},
+		{
+			name:    "time series with invalid granularity",
+			res:     resource{Collection: "docs", DeltaUpdates: true, TimeSeries: &timeSeriesConfig{TimeField: "ts", Granularity: "days"}},
+			wantErr: `invalid time_series granularity "days": must be 'seconds', 'minutes' or 'hours'`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.res.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTimeSeriesValidateProjections(t *testing.T) {
+	timeProjection := func(exists pf.Inference_Exists, types []string, format string) pf.Projection {
+		return pf.Projection{
+			Ptr:   "/ts",
+			Field: "ts",
+			Inference: pf.Inference{
+				Types:   types,
+				Exists:  exists,
+				String_: &pf.Inference_String{Format: format},
+			},
+		}
+	}
+	sensor := pf.Projection{Ptr: "/sensor", Field: "sensor"}
+
+	for _, tt := range []struct {
+		name        string
+		projections []pf.Projection
+		ts          timeSeriesConfig
+		wantErr     string
+	}{
+		{
+			name:        "valid",
+			projections: []pf.Projection{sensor, timeProjection(pf.Inference_MUST, []string{"string"}, "date-time")},
+			ts:          timeSeriesConfig{TimeField: "ts", MetaField: "sensor"},
+		},
+		{
+			name:        "missing time field",
+			projections: []pf.Projection{sensor},
+			ts:          timeSeriesConfig{TimeField: "ts"},
+			wantErr:     `time_series timeField "ts" is not a top-level field of collection "acmeCo/readings"`,
+		},
+		{
+			name:        "optional time field",
+			projections: []pf.Projection{timeProjection(pf.Inference_MAY, []string{"string"}, "date-time")},
+			ts:          timeSeriesConfig{TimeField: "ts"},
+			wantErr:     `time_series timeField "ts" must be a required field of collection "acmeCo/readings"`,
+		},
+		{
+			name:        "nullable time field",
+			projections: []pf.Projection{timeProjection(pf.Inference_MUST, []string{"null", "string"}, "date-time")},
+			ts:          timeSeriesConfig{TimeField: "ts"},
+			wantErr:     `time_series timeField "ts" must be a required field of collection "acmeCo/readings"`,
+		},
+		{
+			name:        "time field without date-time format",
+			projections: []pf.Projection{timeProjection(pf.Inference_MUST, []string{"string"}, "date")},
+			ts:          timeSeriesConfig{TimeField: "ts"},
+			wantErr:     `time_series timeField "ts" of collection "acmeCo/readings" must be a string with format date-time`,
+		},
+		{
+			name:        "missing meta field",
+			projections: []pf.Projection{timeProjection(pf.Inference_MUST, []string{"string"}, "date-time")},
+			ts:          timeSeriesConfig{TimeField: "ts", MetaField: "sensor"},
+			wantErr:     `time_series metaField "sensor" is not a top-level field of collection "acmeCo/readings"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := &pf.CollectionSpec{Name: "acmeCo/readings", Projections: tt.projections}
+			err := tt.ts.validateProjections(collection)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
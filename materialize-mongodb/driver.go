@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	cerrors "github.com/estuary/connectors/go/connector-errors"
@@ -116,11 +117,16 @@ func (d driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Res
 		return nil, err
 	}
 
-	_, err = d.connect(ctx, cfg)
+	client, err := d.connect(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to database: %w", err)
 	}
 
+	collectionTypes, err := listCollectionTypes(ctx, client.Database(cfg.Database))
+	if err != nil {
+		return nil, fmt.Errorf("listing collections: %w", err)
+	}
+
 	// MongoDB always materializes the full root document.
 	var out []*pm.Response_Validated_Binding
 	for _, b := range req.Bindings {
@@ -129,6 +135,19 @@ func (d driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Res
 			return nil, err
 		}
 
+		if res.TimeSeries != nil {
+			if err := res.TimeSeries.validateProjections(&b.Collection); err != nil {
+				return nil, err
+			}
+
+			// Existing collections can't be converted to time series
+			// collections, and a time series collection is only created for a
+			// collection which doesn't exist yet.
+			if collectionType, ok := collectionTypes[res.Collection]; ok && collectionType != "timeseries" {
+				return nil, fmt.Errorf("time_series cannot be enabled for collection %q since it already exists and is not a time series collection", res.Collection)
+			}
+		}
+
 		constraints := make(map[string]*pm.Response_Validated_Constraint)
 		for _, projection := range b.Collection.Projections {
 			var constraint = new(pm.Response_Validated_Constraint)
@@ -199,9 +218,31 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 		}
 		var collection = client.Database(cfg.Database).Collection(res.Collection)
 
+		documentFields := make(map[string]bool)
+		for _, p := range b.Collection.Projections {
+			// Top-level properties have a pointer with a single token.
+			if strings.Count(p.Ptr, "/") == 1 {
+				field := strings.TrimPrefix(p.Ptr, "/")
+				if field == idField {
+					// Collection _id fields are stored with an alternate name.
+					field = idFieldAlt
+				}
+				documentFields[field] = true
+			}
+		}
+
+		var timeField string
+		if res.TimeSeries != nil {
+			timeField = res.TimeSeries.TimeField
+		}
+
 		bindings = append(bindings, &binding{
-			collection:   collection,
-			deltaUpdates: b.DeltaUpdates,
+			collection:     collection,
+			deltaUpdates:   b.DeltaUpdates,
+			updateMode:     res.UpdateMode,
+			unsetNulls:     res.UnsetNulls,
+			documentFields: documentFields,
+			timeField:      timeField,
 		})
 	}
 
@@ -250,6 +291,22 @@ func infoSchema(ctx context.Context, db *mongo.Database) (*boilerplate.InfoSchem
 	return is, nil
 }
 
+// listCollectionTypes returns the type of each existing collection in the
+// database, which is "collection", "timeseries" or "view".
+func listCollectionTypes(ctx context.Context, db *mongo.Database) (map[string]string, error) {
+	collections, err := db.ListCollectionSpecifications(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	for _, c := range collections {
+		out[c.Name] = c.Type
+	}
+
+	return out, nil
+}
+
 func main() {
 	boilerplate.RunMain(driver{})
 }
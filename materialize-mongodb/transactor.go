@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"time"
 
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	pf "github.com/estuary/flow/go/protocols/flow"
@@ -43,6 +44,20 @@ type transactor struct {
 type binding struct {
 	collection   *mongo.Collection
 	deltaUpdates bool
+
+	// Documents of bindings using the "set" update mode are upserted with $set
+	// of their top-level fields, and fields with null values are removed with
+	// $unset if unsetNulls is enabled.
+	updateMode string
+	unsetNulls bool
+	// Top-level properties of the collection documents. Only these are
+	// included in loaded documents for bindings using the "set" update mode,
+	// so that fields added by other applications are not loaded.
+	documentFields map[string]bool
+
+	// Name of the top-level field of documents which must be stored as a BSON
+	// date for time series collections, if the binding is for one.
+	timeField string
 }
 
 func (t *transactor) UnmarshalState(state json.RawMessage) error                  { return nil }
@@ -161,6 +176,13 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			if err := json.Unmarshal(it.RawJSON, &doc); err != nil {
 				return nil, fmt.Errorf("bson unmarshalling json doc: %w", err)
 			}
+
+			if b := t.bindings[it.Binding]; b.timeField != "" {
+				if err := convertTimeField(doc, b.timeField); err != nil {
+					return nil, err
+				}
+			}
+
 			if idVal, ok := doc[idField]; ok {
 				// Preserve the original value of a collection field with a name
 				// that collides with the MongoDB _id field by materializing it
@@ -176,7 +198,9 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			}
 
 			var m mongo.WriteModel
-			if it.Exists {
+			if t.bindings[it.Binding].updateMode == updateModeSet {
+				m = setUpdateModel(key, doc, t.bindings[it.Binding].unsetNulls)
+			} else if it.Exists {
 				m = &mongo.ReplaceOneModel{
 					Filter:      bson.D{{Key: idField, Value: bson.D{{Key: "$eq", Value: key}}}},
 					Replacement: doc,
@@ -241,6 +265,14 @@ func (t *transactor) loadWorker(
 						return fmt.Errorf("decoding document in collection %s: %w", collection.Name(), err)
 					}
 
+					if b := t.bindings[batch.binding]; b.updateMode == updateModeSet {
+						for field := range doc {
+							if field != idField && !b.documentFields[field] {
+								delete(doc, field)
+							}
+						}
+					}
+
 					js, err := json.Marshal(sanitizedLoadedDocument(doc))
 					if err != nil {
 						return fmt.Errorf("encoding document in collection %s as json: %w", collection.Name(), err)
@@ -275,11 +307,13 @@ func (t *transactor) storeWorker(
 				return nil
 			}
 
-			var expectModified, expectInserted, expectDeleted int64
+			var expectModified, expectUpserted, expectInserted, expectDeleted int64
 			for _, m := range batch.models {
 				switch m.(type) {
 				case *mongo.ReplaceOneModel:
 					expectModified++
+				case *mongo.UpdateOneModel:
+					expectUpserted++
 				case *mongo.InsertOneModel:
 					expectInserted++
 				case *mongo.DeleteOneModel:
@@ -302,8 +336,9 @@ func (t *transactor) storeWorker(
 			// MatchedCount instead of ModifiedCount since certain Flow reduction strategies can
 			// result in identical documents being stored, and MongoDB does not report an attempted
 			// "replace" with an identical document as an update when it's part of a bulk write
-			// operation.
-			if res.MatchedCount != expectModified || res.InsertedCount != expectInserted || res.DeletedCount != expectDeleted {
+			// operation. Upserts either match an existing document or insert a
+			// new one.
+			if res.MatchedCount+res.UpsertedCount != expectModified+expectUpserted || res.InsertedCount != expectInserted || res.DeletedCount != expectDeleted {
 				logrus.WithFields(logrus.Fields{
 					"deleted":        res.DeletedCount,
 					"inserted":       res.InsertedCount,
@@ -311,16 +346,17 @@ func (t *transactor) storeWorker(
 					"modified":       res.ModifiedCount,
 					"upserted":       res.UpsertedCount,
 					"expectModified": expectModified,
+					"expectUpserted": expectUpserted,
 					"expectInserted": expectInserted,
 					"expectDeleted":  expectDeleted,
 				}).Warn("bulk write counts")
 
 				return fmt.Errorf(
-					"unexpected bulkWrite counts for MongoDB collection %s.%s: got %d matched vs %d expected, %d inserted vs %d expected, %d deleted vs %d expected",
+					"unexpected bulkWrite counts for MongoDB collection %s.%s: got %d matched or upserted vs %d expected, %d inserted vs %d expected, %d deleted vs %d expected",
 					collection.Database().Name(),
 					collection.Name(),
-					res.MatchedCount,
-					expectModified,
+					res.MatchedCount+res.UpsertedCount,
+					expectModified+expectUpserted,
 					res.InsertedCount,
 					expectInserted,
 					res.DeletedCount,
@@ -331,6 +367,51 @@ func (t *transactor) storeWorker(
 	}
 }
 
+// setUpdateModel returns a model which upserts the top-level fields of a
+// document with $set, leaving other fields of an existing document unchanged.
+func setUpdateModel(key string, doc bson.M, unsetNulls bool) *mongo.UpdateOneModel {
+	set := bson.M{}
+	unset := bson.M{}
+	for field, val := range doc {
+		if field == idField {
+			// The _id of upserted documents is set from the filter.
+			continue
+		} else if val == nil && unsetNulls {
+			unset[field] = ""
+		} else {
+			set[field] = val
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: idField, Value: bson.D{{Key: "$eq", Value: key}}}}).
+		SetUpdate(update).
+		SetUpsert(true)
+}
+
+// convertTimeField converts the date-time string value of the time field of a
+// document for a time series collection to a time.Time, which is stored as a
+// BSON date as required by time series collections.
+func convertTimeField(doc bson.M, timeField string) error {
+	str, ok := doc[timeField].(string)
+	if !ok {
+		return fmt.Errorf("time series field %q must be a date-time string but was %#v", timeField, doc[timeField])
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return fmt.Errorf("parsing time series field %q: %w", timeField, err)
+	}
+	doc[timeField] = ts
+
+	return nil
+}
+
 func sanitizedLoadedDocument(doc map[string]interface{}) map[string]interface{} {
 	if idValAlt, ok := doc[idFieldAlt]; ok {
 		// Reverse the renaming of a collection's _id field to _flow_id by
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSetUpdateModel(t *testing.T) {
+	doc := bson.M{
+		idField:  "abc",
+		"name":   "widget",
+		"count":  int64(3),
+		"nested": bson.M{"a": nil},
+		"gone":   nil,
+	}
+
+	m := setUpdateModel("abc", doc, false)
+	require.True(t, *m.Upsert)
+	require.Equal(t, bson.M{"$set": bson.M{
+		"name":   "widget",
+		"count":  int64(3),
+		"nested": bson.M{"a": nil},
+		"gone":   nil,
+	}}, m.Update)
+
+	m = setUpdateModel("abc", doc, true)
+	require.Equal(t, bson.M{
+		"$set": bson.M{
+			"name":   "widget",
+			"count":  int64(3),
+			"nested": bson.M{"a": nil},
+		},
+		"$unset": bson.M{"gone": ""},
+	}, m.Update)
+}
+
+func TestConvertTimeField(t *testing.T) {
+	doc := bson.M{"ts": "2024-03-01T12:30:00.123Z"}
+	require.NoError(t, convertTimeField(doc, "ts"))
+	require.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 123000000, time.UTC), doc["ts"])
+
+	require.Error(t, convertTimeField(bson.M{"ts": "yesterday"}, "ts"))
+	require.Error(t, convertTimeField(bson.M{}, "ts"))
+}
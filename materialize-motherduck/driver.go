@@ -11,8 +11,8 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
@@ -28,15 +28,15 @@ import (
 )
 
 type config struct {
-	Token              string `json:"token" jsonschema:"title=Motherduck Service Token,description=Service token for authenticating with MotherDuck." jsonschema_extras:"secret=true,order=0"`
-	Database           string `json:"database" jsonschema:"title=Database,description=The database to materialize to." jsonschema_extras:"order=1"`
-	Schema             string `json:"schema" jsonschema:"title=Database Schema,default=main,description=Database schema for bound collection tables (unless overridden within the binding resource configuration) as well as associated materialization metadata tables." jsonschema_extras:"order=2"`
-	Bucket             string `json:"bucket" jsonschema:"title=S3 Staging Bucket,description=Name of the S3 bucket to use for staging data loads." jsonschema_extras:"order=3"`
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=Access Key ID,description=AWS Access Key ID for reading and writing data to the S3 staging bucket." jsonschema_extras:"order=4"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=Secret Access Key,description=AWS Secret Access Key for reading and writing data to the S3 staging bucket." jsonschema_extras:"secret=true,order=5"`
-	Region             string `json:"region" jsonschema:"title=S3 Bucket Region,description=Region of the S3 staging bucket." jsonschema_extras:"order=6"`
-	BucketPath         string `json:"bucketPath,omitempty" jsonschema:"title=Bucket Path,description=An optional prefix that will be used to store objects in S3." jsonschema_extras:"order=7"`
-	HardDelete         bool   `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=8"`
+	Token       string                    `json:"token" jsonschema:"title=Motherduck Service Token,description=Service token for authenticating with MotherDuck." jsonschema_extras:"secret=true,order=0"`
+	Database    string                    `json:"database" jsonschema:"title=Database,description=The database to materialize to." jsonschema_extras:"order=1"`
+	Schema      string                    `json:"schema" jsonschema:"title=Database Schema,default=main,description=Database schema for bound collection tables (unless overridden within the binding resource configuration) as well as associated materialization metadata tables." jsonschema_extras:"order=2"`
+	Bucket      string                    `json:"bucket" jsonschema:"title=S3 Staging Bucket,description=Name of the S3 bucket to use for staging data loads." jsonschema_extras:"order=3"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication,description=AWS credentials for reading and writing data to the S3 staging bucket. They are also used by MotherDuck to read and write staged data." jsonschema_extras:"order=4"`
+	awsauth.AccessKeys
+	Region     string `json:"region" jsonschema:"title=S3 Bucket Region,description=Region of the S3 staging bucket." jsonschema_extras:"order=7"`
+	BucketPath string `json:"bucketPath,omitempty" jsonschema:"title=Bucket Path,description=An optional prefix that will be used to store objects in S3." jsonschema_extras:"order=8"`
+	HardDelete bool   `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=9"`
 }
 
 func (c *config) Validate() error {
@@ -45,8 +45,6 @@ func (c *config) Validate() error {
 		{"database", c.Database},
 		{"schema", c.Schema},
 		{"bucket", c.Bucket},
-		{"awsAccessKeyId", c.AWSAccessKeyID},
-		{"awsSecretAccessKey", c.AWSSecretAccessKey},
 		{"region", c.Region},
 	}
 	for _, req := range requiredProperties {
@@ -55,6 +53,10 @@ func (c *config) Validate() error {
 		}
 	}
 
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
+	}
+
 	// Sanity check that the provided authentication token is a well-formed JWT. It it's not, the
 	// sql.Open function used elsewhere will return an error string that is difficult to comprehend.
 	// This check isn't perfect but it should catch most of the blatantly obvious error cases.
@@ -76,6 +78,15 @@ func (c *config) Validate() error {
 func (c *config) db(ctx context.Context) (*stdsql.DB, error) {
 	var userAgent = "Estuary"
 
+	awsCfg, err := c.toAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := awsCfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving AWS credentials: %w", err)
+	}
+
 	db, err := stdsql.Open("duckdb", fmt.Sprintf("md:%s?motherduck_token=%s&custom_user_agent=%s", c.Database, c.Token, userAgent))
 	if err != nil {
 		if strings.Contains(err.Error(), "Jwt header is an invalid JSON") {
@@ -84,13 +95,11 @@ func (c *config) db(ctx context.Context) (*stdsql.DB, error) {
 		return nil, err
 	}
 
-	for idx, c := range []string{
+	for idx, c := range append([]string{
 		"SET autoinstall_known_extensions=1;",
 		"SET autoload_known_extensions=1;",
-		fmt.Sprintf("SET s3_access_key_id='%s'", c.AWSAccessKeyID),
-		fmt.Sprintf("SET s3_secret_access_key='%s';", c.AWSSecretAccessKey),
 		fmt.Sprintf("SET s3_region='%s';", c.Region),
-	} {
+	}, s3CredentialsStatements(creds)...) {
 		if _, err := db.ExecContext(ctx, c); err != nil {
 			return nil, fmt.Errorf("executing setup command %d: %w", idx, err)
 		}
@@ -99,13 +108,23 @@ func (c *config) db(ctx context.Context) (*stdsql.DB, error) {
 	return db, err
 }
 
+// s3CredentialsStatements returns the statements that configure DuckDB to
+// access S3 with the credentials.
+func s3CredentialsStatements(creds aws.Credentials) []string {
+	return []string{
+		fmt.Sprintf("SET s3_access_key_id='%s'", creds.AccessKeyID),
+		fmt.Sprintf("SET s3_secret_access_key='%s';", creds.SecretAccessKey),
+		fmt.Sprintf("SET s3_session_token='%s';", creds.SessionToken),
+	}
+}
+
+func (c *config) toAWSConfig(ctx context.Context) (aws.Config, error) {
+	creds := awsauth.ResolveCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey)
+	return creds.AWSConfig(ctx, awsConfig.WithRegion(c.Region))
+}
+
 func (c *config) toS3Client(ctx context.Context) (*s3.Client, error) {
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx,
-		awsConfig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(c.AWSAccessKeyID, c.AWSSecretAccessKey, ""),
-		),
-		awsConfig.WithRegion(c.Region),
-	)
+	awsCfg, err := c.toAWSConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -186,6 +205,12 @@ type transactor struct {
 	conn     *stdsql.Conn
 	s3client *s3.Client
 
+	// awsCredentials provides the credentials that DuckDB uses for S3, which
+	// are set again when they are refreshed. Credentials of an assumed role
+	// are temporary.
+	awsCredentials  aws.CredentialsProvider
+	duckCredentials aws.Credentials
+
 	bindings []*binding
 	be       *boilerplate.BindingEvents
 }
@@ -201,10 +226,11 @@ func newTransactor(
 ) (_ m.Transactor, _ *boilerplate.MaterializeOptions, err error) {
 	cfg := ep.Config.(*config)
 
-	s3client, err := cfg.toS3Client(ctx)
+	awsCfg, err := cfg.toAWSConfig(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
+	s3client := s3.NewFromConfig(awsCfg)
 
 	db, err := cfg.db(ctx)
 	if err != nil {
@@ -217,11 +243,12 @@ func newTransactor(
 	}
 
 	t := &transactor{
-		cfg:      cfg,
-		conn:     conn,
-		s3client: s3client,
-		fence:    fence,
-		be:       be,
+		cfg:            cfg,
+		conn:           conn,
+		s3client:       s3client,
+		awsCredentials: awsCfg.Credentials,
+		fence:          fence,
+		be:             be,
 	}
 
 	for _, b := range bindings {
@@ -242,6 +269,26 @@ type binding struct {
 	mustMerge bool
 }
 
+// refreshS3Credentials sets the S3 credentials of DuckDB again if they have
+// changed since they were last set.
+func (d *transactor) refreshS3Credentials(ctx context.Context) error {
+	creds, err := d.awsCredentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving AWS credentials: %w", err)
+	} else if creds == d.duckCredentials {
+		return nil
+	}
+
+	for idx, stmt := range s3CredentialsStatements(creds) {
+		if _, err := d.conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("executing credentials command %d: %w", idx, err)
+		}
+	}
+	d.duckCredentials = creds
+
+	return nil
+}
+
 func (t *transactor) UnmarshalState(state json.RawMessage) error                  { return nil }
 func (t *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error) { return nil, nil }
 
@@ -287,6 +334,8 @@ func (d *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 
 	if len(subqueries) == 0 {
 		return nil // Nothing to load.
+	} else if err := d.refreshS3Credentials(ctx); err != nil {
+		return err
 	}
 	loadAllSql := strings.Join(subqueries, "\nUNION ALL\n")
 
@@ -412,6 +461,10 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		}
 
 		return nil, m.RunAsyncOperation(func() error {
+			if err := d.refreshS3Credentials(ctx); err != nil {
+				return err
+			}
+
 			txn, err := d.conn.BeginTx(ctx, nil)
 			if err != nil {
 				return fmt.Errorf("store BeginTx: %w", err)
@@ -8,25 +8,25 @@ import (
 
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
+	"github.com/estuary/connectors/go/vectors"
+	"github.com/estuary/connectors/go/vectors/embeddings"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
-	"github.com/estuary/connectors/materialize-pinecone/client"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	"github.com/pinecone-io/go-pinecone/pinecone"
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	textEmbeddingAda002             = "text-embedding-ada-002"
-	textEmbeddingAda002VectorLength = 1536
-)
-
 type config struct {
-	Index          string         `json:"index" jsonschema:"title=Pinecone Index" jsonschema_extras:"order=0"`
-	PineconeApiKey string         `json:"pineconeApiKey" jsonschema:"title=Pinecone API Key" jsonschema_extras:"secret=true,order=2"`
-	OpenAiApiKey   string         `json:"openAiApiKey" jsonschema:"title=OpenAI API Key" jsonschema_extras:"secret=true,order=3"`
-	EmbeddingModel string         `json:"embeddingModel,omitempty" jsonschema:"title=Embedding Model ID,default=text-embedding-ada-002" jsonschema_extras:"order=4"`
-	Advanced       advancedConfig `json:"advanced,omitempty" jsonschema_extras:"advanced=true"`
+	Index          string `json:"index" jsonschema:"title=Pinecone Index" jsonschema_extras:"order=0"`
+	PineconeApiKey string `json:"pineconeApiKey" jsonschema:"title=Pinecone API Key" jsonschema_extras:"secret=true,order=2"`
+	OpenAiApiKey   string `json:"openAiApiKey,omitempty" jsonschema:"title=OpenAI API Key" jsonschema_extras:"secret=true,order=3"`
+	EmbeddingModel string `json:"embeddingModel,omitempty" jsonschema:"title=Embedding Model ID,default=text-embedding-ada-002" jsonschema_extras:"order=4"`
+	// EmbeddingProvider takes precedence over the OpenAI configuration above,
+	// which is retained for existing materializations.
+	EmbeddingProvider *embeddings.ProviderConfig `json:"embeddingProvider,omitempty" jsonschema_extras:"order=5"`
+	HardDelete        bool                       `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,default=false" jsonschema_extras:"order=6"`
+	Advanced          advancedConfig             `json:"advanced,omitempty" jsonschema_extras:"advanced=true"`
 }
 
 func (config) GetFieldDocString(fieldName string) string {
@@ -38,9 +38,13 @@ func (config) GetFieldDocString(fieldName string) string {
 	case "PineconeApiKey":
 		return "Pinecone API key used for authentication."
 	case "OpenAiApiKey":
-		return "OpenAI API key used for authentication."
+		return "OpenAI API key used for authentication. Not required if an embedding provider is configured."
 	case "EmbeddingModel":
 		return "Embedding model ID for generating OpenAI bindings. The default text-embedding-ada-002 is recommended."
+	case "EmbeddingProvider":
+		return "Provider of the embedding model used to create vectors from documents. Leave unset to use the OpenAI API key configured above."
+	case "HardDelete":
+		return "If this option is enabled the vectors of documents deleted in the source will also be deleted from the index. By default is disabled and deleted documents are upserted with _meta/op signifying that they have been deleted."
 	case "Advanced":
 		return "Options for advanced users. You should not typically need to modify these."
 	default:
@@ -65,7 +69,9 @@ func (c *config) Validate() error {
 	var requiredProperties = [][]string{
 		{"index", c.Index},
 		{"pineconeApiKey", c.PineconeApiKey},
-		{"openAiApiKey", c.OpenAiApiKey},
+	}
+	if c.EmbeddingProvider == nil {
+		requiredProperties = append(requiredProperties, []string{"openAiApiKey", c.OpenAiApiKey})
 	}
 	for _, req := range requiredProperties {
 		if req[1] == "" {
@@ -73,6 +79,10 @@ func (c *config) Validate() error {
 		}
 	}
 
+	if c.EmbeddingProvider != nil {
+		return c.EmbeddingProvider.Validate()
+	}
+
 	return nil
 }
 
@@ -83,23 +93,32 @@ func (c *config) pineconeClient() (*pinecone.Client, error) {
 	})
 }
 
-func (c *config) openAiClient() *client.OpenAiClient {
-	selectedModel := textEmbeddingAda002
+// embedder returns the client for creating embeddings, which is nil if no
+// embedding provider is used.
+func (c *config) embedder(ctx context.Context) (embeddings.Embedder, error) {
+	if c.EmbeddingProvider != nil {
+		return c.EmbeddingProvider.Embedder(ctx)
+	}
+
+	selectedModel := embeddings.DefaultOpenAiModel
 	if c.EmbeddingModel != "" {
 		selectedModel = c.EmbeddingModel
 	}
 
-	return client.NewOpenAiClient(selectedModel, c.Advanced.OpenAiOrg, c.OpenAiApiKey)
+	return embeddings.NewOpenAiClient(selectedModel, c.Advanced.OpenAiOrg, c.OpenAiApiKey), nil
 }
 
 type resource struct {
-	Namespace string `json:"namespace" jsonschema:"title=Pinecone Namespace" jsonschema_extras:"x-collection-name=true"`
+	Namespace   string `json:"namespace" jsonschema:"title=Pinecone Namespace" jsonschema_extras:"x-collection-name=true"`
+	VectorField string `json:"vectorField,omitempty" jsonschema:"title=Vector Field"`
 }
 
 func (resource) GetFieldDocString(fieldName string) string {
 	switch fieldName {
 	case "Namespace":
 		return "Name of the Pinecone namespace that this collection will materialize vectors into."
+	case "VectorField":
+		return "Optional field of the collection with a precomputed vector for each document, as an array of numbers. If set, this vector is materialized instead of creating an embedding of the document."
 	default:
 		return ""
 	}
@@ -156,16 +175,20 @@ func (d driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Res
 		return nil, fmt.Errorf("describing index: %w", err)
 	}
 
-	if cfg.EmbeddingModel == textEmbeddingAda002 && idx.Dimension != textEmbeddingAda002VectorLength {
-		return nil, fmt.Errorf(
-			"index '%s' has dimensions of %d but must be %d for embedding model '%s'",
-			cfg.Index,
-			idx.Dimension,
-			textEmbeddingAda002VectorLength,
-			textEmbeddingAda002,
-		)
-	} else if err := cfg.openAiClient().VerifyModelExists(ctx); err != nil {
+	embedder, err := cfg.embedder(ctx)
+	if err != nil {
 		return nil, err
+	} else if embedder != nil {
+		if dims, err := vectors.VerifyEmbedder(ctx, embedder); err != nil {
+			return nil, err
+		} else if dims != int(idx.Dimension) {
+			return nil, fmt.Errorf(
+				"index '%s' has dimensions of %d but the embedding model creates vectors with %d dimensions",
+				cfg.Index,
+				idx.Dimension,
+				dims,
+			)
+		}
 	}
 
 	// Log a warning message if the 'flow_document' metadata field has not been
@@ -196,29 +219,9 @@ func (d driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Res
 			return nil, err
 		}
 
-		constraints := make(map[string]*pm.Response_Validated_Constraint)
-		for _, projection := range b.Collection.Projections {
-
-			var constraint = new(pm.Response_Validated_Constraint)
-			switch {
-			// We require collection keys be materialized because it seems pretty reasonable to
-			// require they be included as metadata since the composite key is used as the basis for
-			// the vector ID, and also to avoid complications from
-			// https://github.com/estuary/flow/issues/1057.
-			case projection.IsPrimaryKey:
-				constraint.Type = pm.Response_Validated_Constraint_LOCATION_REQUIRED
-				constraint.Reason = "Components of the collection key must be materialized"
-			case projection.Inference.IsSingleScalarType():
-				constraint.Type = pm.Response_Validated_Constraint_LOCATION_RECOMMENDED
-				constraint.Reason = "The projection has a single scalar type"
-			case projection.IsRootDocumentProjection():
-				constraint.Type = pm.Response_Validated_Constraint_LOCATION_REQUIRED
-				constraint.Reason = "The root document must be materialized"
-			default:
-				constraint.Type = pm.Response_Validated_Constraint_FIELD_OPTIONAL
-				constraint.Reason = "This field can be materializaed"
-			}
-			constraints[projection.Field] = constraint
+		constraints, err := vectors.ValidateBinding(res.VectorField, embedder != nil, b.Collection.Projections)
+		if err != nil {
+			return nil, fmt.Errorf("binding for namespace %q: %w", res.Namespace, err)
 		}
 
 		out = append(out, &pm.Response_Validated_Binding{
@@ -254,7 +257,13 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 		return nil, nil, nil, fmt.Errorf("describing index: %w", err)
 	}
 
-	var bindings []binding
+	embedder, err := cfg.embedder(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var bindings []vectors.Binding
+	var conns []*pinecone.IndexConnection
 	for _, b := range open.Materialization.Bindings {
 		res, err := resolveResourceConfig(b.ResourceConfigJson)
 		if err != nil {
@@ -265,17 +274,21 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("creating index connection: %w", err)
 		}
+		conns = append(conns, conn)
 
-		bindings = append(bindings, binding{
-			conn:        conn,
-			dataHeaders: b.FieldSelection.AllFields(),
-		})
+		binding, err := vectors.NewBinding(b, res.VectorField, int(idx.Dimension))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+
+	var batchEmbedder *vectors.BatchEmbedder
+	if embedder != nil {
+		batchEmbedder = vectors.NewBatchEmbedder(embedder, vectors.BatchSize)
 	}
 
-	return &transactor{
-		openAiClient: cfg.openAiClient(),
-		bindings:     bindings,
-	}, &pm.Response_Opened{}, nil, nil
+	return vectors.NewTransactor(&store{conns: conns}, batchEmbedder, bindings, cfg.HardDelete), &pm.Response_Opened{}, nil, nil
 }
 
 func resolveEndpointConfig(specJson json.RawMessage) (config, error) {
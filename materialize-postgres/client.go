@@ -5,6 +5,7 @@ import (
 	stdsql "database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -117,6 +118,15 @@ func (c *client) CreateTable(ctx context.Context, tc sql.TableCreate) error {
 		}).Info("executed AdditionalSql")
 	}
 
+	if column := tc.EmbeddingColumn; column != "" {
+		stmts, wantType, err := c.embeddingColumnStatements(ctx, tc.Table, column)
+		if err != nil {
+			return err
+		} else if err := addEmbeddingColumn(ctx, txn, tc.Table, column, stmts, wantType); err != nil {
+			return err
+		}
+	}
+
 	if err := txn.Commit(); err != nil {
 		return fmt.Errorf("committing transaction: %w", err)
 	}
@@ -156,13 +166,30 @@ func (c *client) AlterTable(ctx context.Context, ta sql.TableAlter) (string, boi
 		}
 	}
 
-	return strings.Join(stmts, "\n"), func(ctx context.Context) error {
+	// An embedding column is added when it is configured for an existing
+	// table. A column which is no longer configured is left in place.
+	var embeddingColumn, embeddingType string
+	var embeddingStmts []string
+	if column := ta.EmbeddingColumn; ta.EmbeddingColumnChanged && column != "" {
+		var err error
+		if embeddingStmts, embeddingType, err = c.embeddingColumnStatements(ctx, ta.Table, column); err != nil {
+			return "", nil, err
+		}
+		embeddingColumn = column
+	}
+
+	return strings.Join(append(slices.Clone(stmts), embeddingStmts...), "\n"), func(ctx context.Context) error {
 		for _, stmt := range stmts {
 			if _, err := c.db.ExecContext(ctx, stmt); err != nil {
 				log.WithField("stmt", stmt).Error("alter table statement failed")
 				return fmt.Errorf("executing alter table for table %s: %w", ta.Identifier, err)
 			}
 		}
+		if embeddingColumn != "" {
+			if err := addEmbeddingColumn(ctx, c.db, ta.Table, embeddingColumn, embeddingStmts, embeddingType); err != nil {
+				return fmt.Errorf("adding embedding column to table %s: %w", ta.Identifier, err)
+			}
+		}
 		return nil
 	}, nil
 }
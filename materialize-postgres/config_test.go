@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
+	sql "github.com/estuary/connectors/materialize-sql"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	"github.com/stretchr/testify/require"
 )
@@ -95,3 +96,50 @@ func TestConfigURI(t *testing.T) {
 		})
 	}
 }
+
+func TestTableConfigEmbedding(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		res     tableConfig
+		wantErr string
+	}{
+		{
+			name: "embedding column",
+			res:  tableConfig{Table: "docs", Embedding: "embedding", hasEmbeddingProvider: true},
+		},
+		{
+			name:    "no embedding provider",
+			res:     tableConfig{Table: "docs", Embedding: "embedding"},
+			wantErr: "embedding_column requires an embedding provider to be configured",
+		},
+		{
+			name:    "delta updates",
+			res:     tableConfig{Table: "docs", Embedding: "embedding", Delta: true, hasEmbeddingProvider: true},
+			wantErr: "embedding_column cannot be used with delta updates",
+		},
+		{
+			name:    "history mode",
+			res:     tableConfig{Table: "docs", Embedding: "embedding", History: true, hasEmbeddingProvider: true},
+			wantErr: "embedding_column cannot be used with history mode",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.res.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTableConfigEmbeddingColumn(t *testing.T) {
+	require.Equal(t, "", tableConfig{Table: "docs"}.EmbeddingColumn())
+	require.Equal(t, "embedding", tableConfig{Table: "docs", Embedding: "embedding"}.EmbeddingColumn())
+
+	// The embedding column is not part of the table's layout.
+	var res sql.Resource = tableConfig{Table: "docs", Embedding: "embedding"}
+	_, ok := res.(sql.LayoutResource)
+	require.False(t, ok)
+}
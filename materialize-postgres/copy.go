@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// copyBuffer accumulates rows to be copied into a table using COPY FROM STDIN
+// in its text format. Values are encoded as their text representations and
+// are parsed by the server, the same as if they were sent as query parameters,
+// so that types like timestamps and intervals accept the same inputs they do
+// with a regular INSERT.
+type copyBuffer struct {
+	copySQL string
+	buf     bytes.Buffer
+}
+
+func newCopyBuffer(table string, columns []string) copyBuffer {
+	return copyBuffer{
+		copySQL: fmt.Sprintf("COPY %s (%s) FROM STDIN", table, strings.Join(columns, ", ")),
+	}
+}
+
+// add encodes a row of values to the buffer.
+func (b *copyBuffer) add(row []any) error {
+	for idx, v := range row {
+		if idx > 0 {
+			b.buf.WriteByte('\t')
+		}
+		if err := encodeCopyValue(&b.buf, v); err != nil {
+			return err
+		}
+	}
+	b.buf.WriteByte('\n')
+
+	return nil
+}
+
+// flush copies the buffered rows to the table with the given transaction. The
+// buffer is emptied upon completion.
+func (b *copyBuffer) flush(ctx context.Context, txn pgx.Tx) error {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+
+	if _, err := txn.Conn().PgConn().CopyFrom(ctx, &b.buf, b.copySQL); err != nil {
+		return fmt.Errorf("%s: %w", b.copySQL, err)
+	}
+	b.buf.Reset()
+
+	return nil
+}
+
+// encodeCopyValue writes the text format representation of a converted value.
+func encodeCopyValue(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString(`\N`)
+	case string:
+		writeCopyEscaped(buf, v)
+	case json.RawMessage:
+		writeCopyEscaped(buf, string(v))
+	case []byte:
+		writeCopyEscaped(buf, string(v))
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			buf.WriteString("NaN")
+		case math.IsInf(v, 1):
+			buf.WriteString("Infinity")
+		case math.IsInf(v, -1):
+			buf.WriteString("-Infinity")
+		default:
+			buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	case []float32:
+		// The text representation of a pgvector vector.
+		buf.WriteByte('[')
+		for idx, f := range v {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+		}
+		buf.WriteByte(']')
+	case fmt.Stringer:
+		writeCopyEscaped(buf, v.String())
+	default:
+		return fmt.Errorf("unsupported type %T for COPY", v)
+	}
+
+	return nil
+}
+
+// writeCopyEscaped writes a string with the backslash escapes required by the
+// COPY text format for backslashes and the delimiter & newline characters.
+func writeCopyEscaped(buf *bytes.Buffer, s string) {
+	for idx := 0; idx < len(s); idx++ {
+		switch c := s[idx]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
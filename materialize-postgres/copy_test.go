@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyBuffer(t *testing.T) {
+	b := newCopyBuffer(`"some"."table"`, []string{"key", `"Value"`})
+	require.Equal(t, `COPY "some"."table" (key, "Value") FROM STDIN`, b.copySQL)
+
+	for _, row := range [][]any{
+		{int64(1), "plain"},
+		{uint64(2), "tab\tnewline\ncarriage\rbackslash\\"},
+		{3, nil},
+		{4, ""},
+		{float64(1.5), json.RawMessage(`{"a":"b\n"}`)},
+		{math.NaN(), math.Inf(-1)},
+		{true, []byte("bytes")},
+		{[]float32{0.5, -1, 3.25}, []float32{}},
+	} {
+		require.NoError(t, b.add(row))
+	}
+
+	require.Equal(t, "1\tplain\n"+
+		"2\ttab\\tnewline\\ncarriage\\rbackslash\\\\\n"+
+		"3\t\\N\n"+
+		"4\t\n"+
+		"1.5\t{\"a\":\"b\\\\n\"}\n"+
+		"NaN\t-Infinity\n"+
+		"true\tbytes\n"+
+		"[0.5,-1,3.25]\t[]\n",
+		b.buf.String())
+
+	require.Error(t, b.add([]any{struct{}{}}))
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/estuary/connectors/go/dbt"
 	networkTunnel "github.com/estuary/connectors/go/network-tunnel"
 	m "github.com/estuary/connectors/go/protocols/materialize"
+	"github.com/estuary/connectors/go/vectors"
+	"github.com/estuary/connectors/go/vectors/embeddings"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
 	pf "github.com/estuary/flow/go/protocols/flow"
@@ -59,6 +61,8 @@ type config struct {
 
 	DBTJobTrigger dbt.JobConfig `json:"dbt_job_trigger,omitempty" jsonschema:"title=dbt Cloud Job Trigger,description=Trigger a dbt Job when new data is available"`
 
+	EmbeddingProvider *embeddings.ProviderConfig `json:"embeddingProvider,omitempty" jsonschema:"title=Embedding Provider,description=Provider of the embedding model used for tables with an embedding column. Requires the pgvector extension."`
+
 	Advanced advancedConfig `json:"advanced,omitempty" jsonschema:"title=Advanced Options,description=Options for advanced users. You should not typically need to modify these." jsonschema_extras:"advanced=true"`
 
 	NetworkTunnel *tunnelConfig `json:"networkTunnel,omitempty" jsonschema:"title=Network Tunnel,description=Connect to your system through an SSH server that acts as a bastion host for your network."`
@@ -91,6 +95,12 @@ func (c *config) Validate() error {
 		return err
 	}
 
+	if c.EmbeddingProvider != nil {
+		if err := c.EmbeddingProvider.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Connection poolers cause all sorts of problems with the materialization's
 	// use of temporary tables and prepared statements, so the most common
 	// addresses that use connection poolers are not allowed.
@@ -147,13 +157,20 @@ type tableConfig struct {
 	Schema        string `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)" jsonschema_extras:"x-schema-name=true"`
 	AdditionalSql string `json:"additional_table_create_sql,omitempty" jsonschema:"title=Additional Table Create SQL,description=Additional SQL statement(s) to be run in the same transaction that creates the table." jsonschema_extras:"multiline=true"`
 	Delta         bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	History       bool   `json:"history_mode,omitempty" jsonschema:"default=false,title=History Mode,description=Keep a row for every version of each document instead of only the latest version. Rows have valid_from and valid_to times and an is_current flag. Default is false."`
+	Embedding     string `json:"embedding_column,omitempty" jsonschema:"title=Embedding Column,description=Name of a pgvector column in which to store an embedding of the selected fields of each document. The column is added if it does not exist. Requires an embedding provider and cannot be used with delta updates or history mode."`
+
+	// Whether the endpoint has an embedding provider configured.
+	hasEmbeddingProvider bool
 }
 
 func newTableConfig(ep *sql.Endpoint) sql.Resource {
+	cfg := ep.Config.(*config)
 	return &tableConfig{
 		// Default to an explicit endpoint configuration schema, if set.
 		// This will be over-written by a present `schema` property within `raw`.
-		Schema: ep.Config.(*config).Schema,
+		Schema:               cfg.Schema,
+		hasEmbeddingProvider: cfg.EmbeddingProvider != nil,
 	}
 }
 
@@ -162,6 +179,17 @@ func (r tableConfig) Validate() error {
 	if r.Table == "" {
 		return fmt.Errorf("missing table")
 	}
+
+	if r.Embedding != "" {
+		if !r.hasEmbeddingProvider {
+			return fmt.Errorf("embedding_column requires an embedding provider to be configured")
+		} else if r.Delta {
+			return fmt.Errorf("embedding_column cannot be used with delta updates")
+		} else if r.History {
+			return fmt.Errorf("embedding_column cannot be used with history mode")
+		}
+	}
+
 	return nil
 }
 
@@ -176,6 +204,10 @@ func (c tableConfig) DeltaUpdates() bool {
 	return c.Delta
 }
 
+func (c tableConfig) HistoryMode() bool {
+	return c.History
+}
+
 func newPostgresDriver() *sql.Driver {
 	return &sql.Driver{
 		DocumentationURL: "https://go.estuary.dev/materialize-postgresql",
@@ -254,9 +286,16 @@ type transactor struct {
 	store struct {
 		conn  *pgx.Conn
 		fence sql.Fence
+		// Postgres 15 added support for MERGE, which is used to apply staged
+		// documents if it is available.
+		useMerge bool
 	}
 	bindings []*binding
 	be       *boilerplate.BindingEvents
+
+	// Creates embeddings for bindings with an embedding column, if there are
+	// any.
+	embedder *vectors.BatchEmbedder
 }
 
 func newTransactor(
@@ -289,10 +328,26 @@ func newTransactor(
 		return nil, nil, fmt.Errorf("store set statement_timeout: %w", err)
 	}
 
+	var serverVersion int
+	if err := d.store.conn.QueryRow(ctx, "select current_setting('server_version_num')::int;").Scan(&serverVersion); err != nil {
+		return nil, nil, fmt.Errorf("querying server version: %w", err)
+	}
+	d.store.useMerge = serverVersion >= 150000
+
+	if err := d.setupEmbeddings(ctx, bindings); err != nil {
+		return nil, nil, err
+	}
+
 	for _, binding := range bindings {
 		if err = d.addBinding(ctx, binding, is); err != nil {
 			return nil, nil, fmt.Errorf("addBinding of %s: %w", binding.Path, err)
 		}
+
+		if column := binding.EmbeddingColumn; column != "" {
+			if err := d.addEmbeddingBinding(ctx, d.bindings[len(d.bindings)-1], column); err != nil {
+				return nil, nil, fmt.Errorf("adding embedding column of %s: %w", binding.Path, err)
+			}
+		}
 	}
 
 	// Build a query which unions the results of each load subquery.
@@ -311,23 +366,38 @@ func newTransactor(
 
 type binding struct {
 	target         sql.Table
-	loadInsertSQL  string
-	storeUpdateSQL string
-	storeInsertSQL string
+	mergeSQL       string
 	deleteQuerySQL string
 	loadQuerySQL   string
+
+	// Statements which apply staged documents to a table in history mode, in
+	// place of mergeSQL and deleteQuerySQL.
+	historyCloseSQL, historyInsertSQL string
+
+	// Buffered rows of keys to load, documents to store, and keys to delete,
+	// which are staged with COPY.
+	load, store, delete copyBuffer
+	// Whether documents or deletions have been staged in the current
+	// transaction, and must be applied to the target table.
+	stagedStores, stagedDeletes bool
+
+	// The embedding column of the table, if it has one.
+	embedding *embeddingColumn
 }
 
 func (t *transactor) addBinding(ctx context.Context, target sql.Table, is *boilerplate.InfoSchema) error {
 	var b = &binding{target: target}
 
+	var mergeTpl = tplUpsertFromStore
+	if t.store.useMerge {
+		mergeTpl = tplMergeInto
+	}
+
 	for _, m := range []struct {
 		sql *string
 		tpl *template.Template
 	}{
-		{&b.loadInsertSQL, tplLoadInsert},
-		{&b.storeInsertSQL, tplStoreInsert},
-		{&b.storeUpdateSQL, tplStoreUpdate},
+		{&b.mergeSQL, mergeTpl},
 		{&b.deleteQuerySQL, tplDeleteQuery},
 		{&b.loadQuerySQL, tplLoadQuery},
 	} {
@@ -337,6 +407,39 @@ func (t *transactor) addBinding(ctx context.Context, target sql.Table, is *boile
 		}
 	}
 
+	if target.History != nil {
+		for _, m := range []struct {
+			sql *string
+			tpl *template.Template
+		}{
+			{&b.historyCloseSQL, tplHistoryClose},
+			{&b.historyInsertSQL, tplHistoryInsert},
+		} {
+			var err error
+			if *m.sql, err = sql.RenderTableTemplate(target, m.tpl); err != nil {
+				return err
+			}
+		}
+	}
+
+	var keyIdents, columnIdents []string
+	for _, k := range target.Keys {
+		keyIdents = append(keyIdents, k.Identifier)
+	}
+	for _, c := range target.Columns() {
+		columnIdents = append(columnIdents, c.Identifier)
+	}
+
+	b.load = newCopyBuffer(fmt.Sprintf("flow_temp_table_%d", target.Binding), keyIdents)
+	b.delete = newCopyBuffer(fmt.Sprintf("flow_temp_delete_table_%d", target.Binding), keyIdents)
+	if target.DeltaUpdates {
+		// Delta updates never update existing rows, so documents are copied
+		// directly to the target table.
+		b.store = newCopyBuffer(target.Identifier, columnIdents)
+	} else {
+		b.store = newCopyBuffer(fmt.Sprintf("flow_temp_store_table_%d", target.Binding), columnIdents)
+	}
+
 	t.bindings = append(t.bindings, b)
 
 	// Create a binding-scoped temporary table for staged keys to load.
@@ -359,6 +462,18 @@ func (t *transactor) addBinding(ctx context.Context, target sql.Table, is *boile
 		return fmt.Errorf("Exec(%s): %w", w.String(), err)
 	}
 
+	// Create binding-scoped temporary tables for staging documents to store
+	// and keys to delete.
+	if !target.DeltaUpdates {
+		for _, tpl := range []*template.Template{tplCreateStoreTable, tplCreateDeleteTable} {
+			if query, err := sql.RenderTableTemplate(target, tpl); err != nil {
+				return err
+			} else if _, err := t.store.conn.Exec(ctx, query); err != nil {
+				return fmt.Errorf("Exec(%s): %w", query, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -376,37 +491,35 @@ func (d *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 	}
 	defer txn.Rollback(ctx)
 
-	var batch pgx.Batch
-	batchBytes := 0
+	batchBytes, batchLen := 0, 0
 	for it.Next() {
 		// This assumes that the length of the packed key is at least proportional to the amount of
-		// memory it will occupy when populated buffered in a pgx.Batch.
+		// memory it will occupy when buffered.
 		batchBytes += len(it.PackedKey)
+		batchLen++
 
 		var b = d.bindings[it.Binding]
 
 		if converted, err := b.target.ConvertKey(it.Key); err != nil {
 			return fmt.Errorf("converting Load key: %w", err)
-		} else {
-			batch.Queue(b.loadInsertSQL, converted...)
+		} else if err := b.load.add(converted); err != nil {
+			return fmt.Errorf("encoding Load key: %w", err)
 		}
 
-		if batchBytes >= batchBytesLimit || batch.Len() > batchSizeLimit {
-			if err := sendBatch(ctx, txn, &batch); err != nil {
-				return fmt.Errorf("sending load batch: %w", err)
+		if batchBytes >= batchBytesLimit || batchLen > batchSizeLimit {
+			if err := d.flushLoads(ctx, txn); err != nil {
+				return fmt.Errorf("copying load keys: %w", err)
 			}
-			batchBytes = 0
+			batchBytes, batchLen = 0, 0
 		}
 	}
 	if it.Err() != nil {
 		return it.Err()
 	}
 
-	// Send any remaining keys for this load.
-	if batch.Len() > 0 {
-		if err := sendBatch(ctx, txn, &batch); err != nil {
-			return fmt.Errorf("sending final load batch: %w", err)
-		}
+	// Copy any remaining keys for this load.
+	if err := d.flushLoads(ctx, txn); err != nil {
+		return fmt.Errorf("copying final load keys: %w", err)
 	}
 
 	// Issue a union join of the target tables and their (now staged) load keys,
@@ -450,18 +563,40 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 		}
 	}()
 
-	var batch pgx.Batch
-	batchBytes := 0
+	batchBytes, batchLen := 0, 0
 	for it.Next() {
 		var b = d.bindings[it.Binding]
 
-		if it.Delete && d.cfg.HardDelete {
+		if it.Delete && b.target.History != nil {
+			if !it.Exists {
+				// Ignore items which do not exist and are already deleted
+				continue
+			}
+
+			// Deleted documents are staged to close out their current row in
+			// the history table, and their keys are staged so that a new row
+			// is not inserted for them.
+			if converted, err := b.target.ConvertAll(it.Key, it.Values, it.RawJSON); err != nil {
+				return nil, fmt.Errorf("converting store parameters: %w", err)
+			} else if err := b.store.add(converted); err != nil {
+				return nil, fmt.Errorf("encoding store parameters: %w", err)
+			}
+			if converted, err := b.target.ConvertKey(it.Key); err != nil {
+				return nil, fmt.Errorf("converting delete keys: %w", err)
+			} else if err := b.delete.add(converted); err != nil {
+				return nil, fmt.Errorf("encoding delete keys: %w", err)
+			}
+			b.stagedStores = true
+
+			batchBytes += len(it.PackedKey) + len(it.PackedValues) + len(it.RawJSON)
+		} else if it.Delete && d.cfg.HardDelete {
 			if it.Exists {
 				if converted, err := b.target.ConvertKey(it.Key); err != nil {
 					return nil, fmt.Errorf("converting delete keys: %w", err)
-				} else if it.Exists {
-					batch.Queue(b.deleteQuerySQL, converted...)
+				} else if err := b.delete.add(converted); err != nil {
+					return nil, fmt.Errorf("encoding delete keys: %w", err)
 				}
+				b.stagedDeletes = true
 
 				batchBytes += len(it.PackedKey)
 			} else {
@@ -469,25 +604,60 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 				continue
 			}
 		} else {
-			// Similar to the accounting in (*transactor).Store, this assumes that lengths of packed
-			// tuples & the document JSON are proportional to the size of the item in the batch.
+			// Similar to the accounting in (*transactor).Load, this assumes that lengths of packed
+			// tuples & the document JSON are proportional to the size of the buffered row.
 			batchBytes += len(it.PackedKey) + len(it.PackedValues) + len(it.RawJSON)
 
 			if converted, err := b.target.ConvertAll(it.Key, it.Values, it.RawJSON); err != nil {
 				return nil, fmt.Errorf("converting store parameters: %w", err)
-			} else if it.Exists {
-				batch.Queue(b.storeUpdateSQL, converted...)
-			} else {
-				batch.Queue(b.storeInsertSQL, converted...)
+			} else if err := b.store.add(converted); err != nil {
+				return nil, fmt.Errorf("encoding store parameters: %w", err)
+			}
+			b.stagedStores = true
+
+			if b.embedding != nil {
+				if err := b.embedding.add(&b.target, it.Key, it.Values); err != nil {
+					return nil, fmt.Errorf("staging embedding input: %w", err)
+				}
 			}
 		}
+		batchLen++
 
-		if batchBytes >= batchBytesLimit || batch.Len() > batchSizeLimit {
-			if err := sendBatch(ctx, txn, &batch); err != nil {
-				return nil, fmt.Errorf("sending store batch: %w", err)
+		if batchBytes >= batchBytesLimit || batchLen > batchSizeLimit {
+			if err := d.flushStores(ctx, txn); err != nil {
+				return nil, fmt.Errorf("copying store documents: %w", err)
 			}
-			batchBytes = 0
+			batchBytes, batchLen = 0, 0
+		}
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+
+	// Copy any remaining documents for this store.
+	if err := d.flushStores(ctx, txn); err != nil {
+		return nil, fmt.Errorf("copying final store documents: %w", err)
+	}
+
+	// Apply the staged documents and deletions to each target table with a
+	// single statement per binding.
+	var batch pgx.Batch
+	for _, b := range d.bindings {
+		if b.stagedStores && b.target.History != nil {
+			batch.Queue(b.historyCloseSQL)
+			batch.Queue(b.historyInsertSQL)
+		} else if b.stagedStores && !b.target.DeltaUpdates {
+			batch.Queue(b.mergeSQL)
+		}
+		if b.embedding != nil && b.embedding.staged {
+			// Embeddings are applied after their documents have been merged.
+			batch.Queue(b.embedding.updateSQL)
+			b.embedding.staged = false
+		}
+		if b.stagedDeletes {
+			batch.Queue(b.deleteQuerySQL)
 		}
+		b.stagedStores, b.stagedDeletes = false, false
 	}
 
 	return func(ctx context.Context, runtimeCheckpoint *protocol.Checkpoint) (*pf.ConnectorState, m.OpFuture) {
@@ -509,7 +679,7 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 
 			results := txn.SendBatch(ctx, &batch)
 
-			// Execute all remaining doc inserts & updates.
+			// Execute all merges & deletes of staged documents.
 			for i := 0; i < batch.Len()-1; i++ {
 				if _, err := results.Exec(); err != nil {
 					return fmt.Errorf("store at index %d: %w", i, err)
@@ -532,30 +702,41 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 	}, nil
 }
 
-func (d *transactor) Destroy() {
-	d.load.conn.Close(context.Background())
-	d.store.conn.Close(context.Background())
+// flushLoads copies all buffered load keys to their staging tables.
+func (d *transactor) flushLoads(ctx context.Context, txn pgx.Tx) error {
+	for _, b := range d.bindings {
+		if err := b.load.flush(ctx, txn); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func main() {
-	boilerplate.RunMain(newPostgresDriver())
-}
+// flushStores copies all buffered documents and deleted keys to their staging
+// tables, or directly to the target table for delta updates bindings. The
+// embeddings of buffered documents are created and copied as well.
+func (d *transactor) flushStores(ctx context.Context, txn pgx.Tx) error {
+	for _, b := range d.bindings {
+		if b.embedding != nil {
+			if err := b.embedding.flush(ctx, txn, d.embedder); err != nil {
+				return err
+			}
+		}
 
-// Send a single batch of queries with the given transaction, discarding any results. The batch is
-// zero'd upon completion.
-func sendBatch(ctx context.Context, txn pgx.Tx, batch *pgx.Batch) error {
-	results := txn.SendBatch(ctx, batch)
-	for i := 0; i < batch.Len(); i++ {
-		if _, err := results.Exec(); err != nil {
-			return fmt.Errorf("exec at index %d: %w", i, err)
+		if err := b.store.flush(ctx, txn); err != nil {
+			return err
+		} else if err := b.delete.flush(ctx, txn); err != nil {
+			return err
 		}
 	}
-	if err := results.Close(); err != nil {
-		return fmt.Errorf("closing batch: %w", err)
-	}
+	return nil
+}
 
-	var newBatch pgx.Batch
-	*batch = newBatch
+func (d *transactor) Destroy() {
+	d.load.conn.Close(context.Background())
+	d.store.conn.Close(context.Background())
+}
 
-	return nil
+func main() {
+	boilerplate.RunMain(newPostgresDriver())
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/estuary/connectors/go/vectors"
+	sql "github.com/estuary/connectors/materialize-sql"
+	"github.com/estuary/flow/go/protocols/fdb/tuple"
+	"github.com/jackc/pgx/v5"
+)
+
+// embeddingTable is the template input for the embedding column of a table.
+type embeddingTable struct {
+	sql.Table
+	// Quoted identifier of the embedding column.
+	Column     string
+	Dimensions int
+}
+
+var _ sql.EmbeddingResource = tableConfig{}
+
+// EmbeddingColumn returns the name of the embedding column of the table, which
+// is added by CreateTable and AlterTable when the table is applied, including
+// when an embedding column is configured for an existing table.
+func (c tableConfig) EmbeddingColumn() string {
+	return c.Embedding
+}
+
+// embeddingColumn stages the embeddings of documents stored to a table with an
+// embedding column.
+type embeddingColumn struct {
+	updateSQL string
+	// Keys and embedding inputs of documents which have been stored but not
+	// yet embedded.
+	keys   [][]any
+	inputs []string
+	buffer copyBuffer
+	// Whether embeddings have been staged in the current transaction, and must
+	// be applied to the target table.
+	staged bool
+}
+
+// dbConn is a database connection or transaction which embedding column
+// statements are executed with.
+type dbConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (stdsql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *stdsql.Row
+}
+
+// embeddingColumnStatements returns the statements which create the pgvector
+// extension and add the embedding column to a table if it doesn't exist, and
+// the column type required by the configured embedding provider. The embedding
+// provider is verified to determine the dimensions of its vectors.
+func (c *client) embeddingColumnStatements(ctx context.Context, table sql.Table, column string) ([]string, string, error) {
+	if c.cfg.EmbeddingProvider == nil {
+		return nil, "", fmt.Errorf("an embedding provider must be configured for tables with an embedding column")
+	}
+	embedder, err := c.cfg.EmbeddingProvider.Embedder(ctx)
+	if err != nil {
+		return nil, "", err
+	} else if embedder == nil {
+		return nil, "", fmt.Errorf("an embedding provider must be configured for tables with an embedding column")
+	}
+
+	dimensions, err := vectors.VerifyEmbedder(ctx, embedder)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var w strings.Builder
+	if err := tplAddEmbeddingColumn.Execute(&w, &embeddingTable{
+		Table:      table,
+		Column:     pgDialect.Identifier(column),
+		Dimensions: dimensions,
+	}); err != nil {
+		return nil, "", fmt.Errorf("executing addEmbeddingColumn template: %w", err)
+	}
+
+	return []string{"CREATE EXTENSION IF NOT EXISTS vector;", w.String()}, fmt.Sprintf("vector(%d)", dimensions), nil
+}
+
+// addEmbeddingColumn executes the statements which add the embedding column to
+// a table, and verifies the type of the column, which may have already
+// existed with different dimensions.
+func addEmbeddingColumn(ctx context.Context, conn dbConn, table sql.Table, column string, stmts []string, wantType string) error {
+	for _, stmt := range stmts {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("executing embedding column statement %q, which requires the pgvector extension to be installed: %w", stmt, err)
+		}
+	}
+
+	var columnType string
+	if err := conn.QueryRowContext(
+		ctx,
+		"SELECT format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = $1::regclass AND attname = $2 AND NOT attisdropped;",
+		table.Identifier,
+		truncatedIdentifier(column),
+	).Scan(&columnType); err != nil {
+		return fmt.Errorf("querying type of embedding column: %w", err)
+	} else if columnType != wantType {
+		return fmt.Errorf("embedding column %q has type %s but must be %s for the embedding model", column, columnType, wantType)
+	}
+
+	return nil
+}
+
+// setupEmbeddings creates the embedder for bindings with an embedding column,
+// if there are any. Embedding columns are added to tables when they are
+// applied.
+func (t *transactor) setupEmbeddings(ctx context.Context, bindings []sql.Table) error {
+	if !slices.ContainsFunc(bindings, func(b sql.Table) bool { return b.EmbeddingColumn != "" }) {
+		return nil
+	}
+
+	if t.cfg.EmbeddingProvider == nil {
+		return fmt.Errorf("an embedding provider must be configured for tables with an embedding column")
+	}
+	embedder, err := t.cfg.EmbeddingProvider.Embedder(ctx)
+	if err != nil {
+		return err
+	} else if embedder == nil {
+		return fmt.Errorf("an embedding provider must be configured for tables with an embedding column")
+	}
+	t.embedder = vectors.NewBatchEmbedder(embedder, vectors.BatchSize)
+
+	return nil
+}
+
+// addEmbeddingBinding creates a temporary table for staging the embeddings of
+// a binding with an embedding column.
+func (t *transactor) addEmbeddingBinding(ctx context.Context, b *binding, column string) error {
+	params := embeddingTable{
+		Table:  b.target,
+		Column: pgDialect.Identifier(column),
+	}
+
+	var w strings.Builder
+	if err := tplCreateEmbeddingTable.Execute(&w, &params); err != nil {
+		return fmt.Errorf("executing createEmbeddingTable template: %w", err)
+	} else if _, err := t.store.conn.Exec(ctx, w.String()); err != nil {
+		return fmt.Errorf("Exec(%s): %w", w.String(), err)
+	}
+
+	w.Reset()
+	if err := tplUpdateEmbeddings.Execute(&w, &params); err != nil {
+		return fmt.Errorf("executing updateEmbeddings template: %w", err)
+	}
+
+	var columnIdents []string
+	for _, k := range b.target.Keys {
+		columnIdents = append(columnIdents, k.Identifier)
+	}
+	columnIdents = append(columnIdents, params.Column)
+
+	b.embedding = &embeddingColumn{
+		updateSQL: w.String(),
+		buffer:    newCopyBuffer(fmt.Sprintf("flow_temp_embedding_table_%d", b.target.Binding), columnIdents),
+	}
+
+	return nil
+}
+
+// add buffers the key of a stored document and the embedding input created
+// from its selected fields.
+func (e *embeddingColumn) add(target *sql.Table, key, values tuple.Tuple) error {
+	converted, err := target.ConvertKey(key)
+	if err != nil {
+		return fmt.Errorf("converting key: %w", err)
+	}
+
+	fields := make(map[string]interface{})
+	for idx, val := range key {
+		if val != nil {
+			fields[target.Keys[idx].Field] = val
+		}
+	}
+	for idx, val := range values {
+		if val != nil {
+			fields[target.Values[idx].Field] = val
+		}
+	}
+
+	input, err := vectors.MakeInput(fields)
+	if err != nil {
+		return err
+	}
+
+	e.keys = append(e.keys, converted)
+	e.inputs = append(e.inputs, input)
+
+	return nil
+}
+
+// flush creates embeddings for the buffered documents and copies them to the
+// staging table.
+func (e *embeddingColumn) flush(ctx context.Context, txn pgx.Tx, embedder *vectors.BatchEmbedder) error {
+	if len(e.inputs) == 0 {
+		return nil
+	}
+
+	created, err := embedder.Embed(ctx, e.inputs)
+	if err != nil {
+		return fmt.Errorf("creating embeddings: %w", err)
+	}
+
+	for idx, vector := range created {
+		if err := e.buffer.add(append(e.keys[idx], vector)); err != nil {
+			return fmt.Errorf("encoding embedding: %w", err)
+		}
+	}
+
+	if err := e.buffer.flush(ctx, txn); err != nil {
+		return err
+	}
+	e.keys, e.inputs, e.staged = nil, nil, true
+
+	return nil
+}
@@ -115,6 +115,18 @@ var (
 flow_temp_table_{{ $.Binding }}
 {{- end }}
 
+{{ define "temp_store_name" -}}
+flow_temp_store_table_{{ $.Binding }}
+{{- end }}
+
+{{ define "temp_delete_name" -}}
+flow_temp_delete_table_{{ $.Binding }}
+{{- end }}
+
+{{ define "temp_embedding_name" -}}
+flow_temp_embedding_table_{{ $.Binding }}
+{{- end }}
+
 -- Templated creation of a materialized table definition and comments:
 
 {{ define "createTargetTable" }}
@@ -123,6 +135,7 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
 		{{- if $ind }},{{ end }}
 		{{$col.Identifier}} {{$col.DDL}}
 	{{- end }}
+	{{- template "historyColumnDefinitions" $ }}
 	{{- if not $.DeltaUpdates }},
 
 		PRIMARY KEY (
@@ -130,6 +143,7 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
 		{{- if $ind }}, {{end -}}
 		{{$key.Identifier}}
 	{{- end -}}
+	{{- template "historyKeyColumns" $ -}}
 	)
 	{{- end }}
 );
@@ -138,6 +152,7 @@ COMMENT ON TABLE {{$.Identifier}} IS {{Literal $.Comment}};
 {{- range $col := .Columns }}
 COMMENT ON COLUMN {{$.Identifier}}.{{$col.Identifier}} IS {{Literal $col.Comment}};
 {{- end}}
+{{- template "historyColumnComments" $ }}
 {{ end }}
 
 -- Templated query which performs table alterations by adding columns and/or
@@ -168,23 +183,6 @@ CREATE TEMPORARY TABLE {{ template "temp_name" . }} (
 ) ON COMMIT DELETE ROWS;
 {{ end }}
 
--- Templated insertion into the temporary load table:
-
-{{ define "loadInsert" }}
-INSERT INTO {{ template "temp_name" . }} (
-	{{- range $ind, $key := $.Keys }}
-		{{- if $ind }}, {{ end -}}
-		{{ $key.Identifier }}
-	{{- end -}}
-	)
-	VALUES (
-	{{- range $ind, $key := $.Keys }}
-		{{- if $ind }}, {{ end -}}
-		{{ $key.Placeholder }}
-	{{- end -}}
-);
-{{ end }}
-
 -- Templated query which joins keys from the load table with the target table, and returns values. It
 -- deliberately skips the trailing semi-colon as these queries are composed with a UNION ALL.
 
@@ -197,53 +195,182 @@ SELECT {{ $.Binding }}, r.{{$.Document.Identifier}}
 		{{ if $ind }} AND {{ else }} ON  {{ end -}}
 		l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
 	{{- end }}
+	{{- if $.History }}
+		AND r.{{ $.History.IsCurrent.Identifier }}
+	{{- end }}
 {{ else -}}
 SELECT * FROM (SELECT -1, CAST(NULL AS JSON) LIMIT 0) as nodoc
 {{ end }}
 {{ end }}
 
--- Templated query which inserts a new, complete row to the target table:
+-- Templated creation of temporary tables for staging documents to store and
+-- keys to delete. Their columns have the same names and types as those of
+-- the target table, but without any constraints.
 
-{{ define "storeInsert" }}
-INSERT INTO {{ $.Identifier }} (
+{{ define "createStoreTable" }}
+CREATE TEMPORARY TABLE {{ template "temp_store_name" . }} ON COMMIT DELETE ROWS AS
+	SELECT
 	{{- range $ind, $col := $.Columns }}
 		{{- if $ind }},{{ end }}
 		{{$col.Identifier}}
 	{{- end }}
-) VALUES (
+	FROM {{ $.Identifier }} WITH NO DATA;
+{{ end }}
+
+{{ define "createDeleteTable" }}
+CREATE TEMPORARY TABLE {{ template "temp_delete_name" . }} ON COMMIT DELETE ROWS AS
+	SELECT
+	{{- range $ind, $key := $.Keys }}
+		{{- if $ind }},{{ end }}
+		{{$key.Identifier}}
+	{{- end }}
+	FROM {{ $.Identifier }} WITH NO DATA;
+{{ end }}
+
+-- Templated queries for tables with a pgvector embedding column. The column
+-- is added if it does not exist, and embeddings of stored documents are
+-- staged in a temporary table and applied after the documents have been.
+
+{{ define "addEmbeddingColumn" }}
+ALTER TABLE {{ $.Identifier }} ADD COLUMN IF NOT EXISTS {{ $.Column }} vector({{ $.Dimensions }});
+{{ end }}
+
+{{ define "createEmbeddingTable" }}
+CREATE TEMPORARY TABLE {{ template "temp_embedding_name" . }} ON COMMIT DELETE ROWS AS
+	SELECT
+	{{- range $ind, $key := $.Keys }}
+		{{$key.Identifier}},
+	{{- end }}
+		{{ $.Column }}
+	FROM {{ $.Identifier }} WITH NO DATA;
+{{ end }}
+
+{{ define "updateEmbeddings" }}
+UPDATE {{ $.Identifier }} AS l
+SET {{ $.Column }} = r.{{ $.Column }}
+FROM {{ template "temp_embedding_name" . }} AS r
+WHERE
+{{- range $ind, $key := $.Keys }}
+	{{ if $ind }} AND {{ end -}}
+	l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
+{{- end -}}
+;
+{{ end }}
+
+-- Templated query which merges staged documents into the target table, for
+-- Postgres 15 and later.
+
+{{ define "mergeInto" }}
+MERGE INTO {{ $.Identifier }} AS l
+USING {{ template "temp_store_name" . }} AS r
+ON {{ range $ind, $key := $.Keys }}
+	{{- if $ind }} AND {{ end -}}
+	l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
+{{- end }}
+{{- if or $.Values $.Document }}
+WHEN MATCHED THEN
+	UPDATE SET {{ range $ind, $val := $.Values }}
+	{{- if $ind }}, {{ end -}}
+		{{ $val.Identifier }} = r.{{ $val.Identifier }}
+	{{- end }}
+	{{- if $.Document -}}
+		{{ if $.Values }}, {{ end }}{{ $.Document.Identifier }} = r.{{ $.Document.Identifier }}
+	{{- end }}
+{{- end }}
+WHEN NOT MATCHED THEN
+	INSERT (
 	{{- range $ind, $col := $.Columns }}
-		{{- if $ind}}, {{ end -}}
-		{{ $col.Placeholder }}
+		{{- if $ind }}, {{ end -}}
+		{{ $col.Identifier }}
 	{{- end -}}
-);
+	)
+	VALUES (
+	{{- range $ind, $col := $.Columns }}
+		{{- if $ind }}, {{ end -}}
+		r.{{ $col.Identifier }}
+	{{- end -}}
+	);
 {{ end }}
 
--- Templated query which updates an existing row in the target table:
+-- Templated query which upserts staged documents into the target table, for
+-- versions of Postgres prior to 15 which do not support MERGE.
 
-{{ define "storeUpdate" }}
-UPDATE {{$.Identifier}} SET
+{{ define "upsertFromStore" }}
+INSERT INTO {{ $.Identifier }} (
+	{{- range $ind, $col := $.Columns }}
+		{{- if $ind }}, {{ end -}}
+		{{ $col.Identifier }}
+	{{- end -}}
+)
+SELECT
+	{{- range $ind, $col := $.Columns }}
+		{{- if $ind }},{{ end }}
+		{{ $col.Identifier }}
+	{{- end }}
+FROM {{ template "temp_store_name" . }}
+ON CONFLICT (
+	{{- range $ind, $key := $.Keys }}
+		{{- if $ind }}, {{ end -}}
+		{{ $key.Identifier }}
+	{{- end -}}
+)
+{{- if or $.Values $.Document }} DO UPDATE SET
 	{{- range $ind, $val := $.Values }}
 		{{- if $ind }},{{ end }}
-		{{ $val.Identifier}} = {{ $val.Placeholder }}
+		{{ $val.Identifier }} = EXCLUDED.{{ $val.Identifier }}
 	{{- end }}
 	{{- if $.Document -}}
 		{{ if $.Values }},{{ end }}
-		{{ $.Document.Identifier }} = {{ $.Document.Placeholder }}
-	{{- end -}}
-	{{ range $ind, $key := $.Keys }}
-	{{ if $ind }} AND   {{ else }} WHERE {{ end -}}
-	{{ $key.Identifier }} = {{ $key.Placeholder }}
+		{{ $.Document.Identifier }} = EXCLUDED.{{ $.Document.Identifier }}
 	{{- end -}}
-	;
+{{ else }} DO NOTHING
+{{- end -}}
+;
 {{ end }}
 
+-- Templated query which deletes rows with staged keys from the target table:
+
 {{ define "deleteQuery" }}
-DELETE FROM {{$.Identifier}} WHERE
-{{ range $ind, $key := $.Keys -}}
-	{{- if $ind }} AND {{ end -}}
-	{{ $key.Identifier }} = {{ $key.Placeholder }}
+DELETE FROM {{ $.Identifier }} AS l
+USING {{ template "temp_delete_name" . }} AS r
+WHERE
+{{- range $ind, $key := $.Keys }}
+	{{ if $ind }} AND {{ end -}}
+	l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
 {{- end -}}
-	;
+;
+{{ end }}
+
+-- Templated queries which apply staged documents to a table in history mode.
+-- The current row of each staged document is closed out, and a new current
+-- row is inserted for each staged document that isn't a deletion.
+
+{{ define "historyClose" }}
+UPDATE {{ $.Identifier }} AS l
+SET {{ $.History.ValidTo.Identifier }} = r.{{ $.History.PublishedAt.Identifier }},
+	{{ $.History.IsCurrent.Identifier }} = false
+FROM {{ template "temp_store_name" . }} AS r
+WHERE
+{{- range $ind, $key := $.Keys }}
+	{{ if $ind }} AND {{ end -}}
+	l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
+{{- end }}
+	AND l.{{ $.History.IsCurrent.Identifier }}
+	AND l.{{ $.History.ValidFrom.Identifier }} < r.{{ $.History.PublishedAt.Identifier }};
+{{ end }}
+
+{{ define "historyInsert" }}
+INSERT INTO {{ $.Identifier }} ({{ template "historyInsertColumns" $ }})
+SELECT {{ template "historyInsertValues" $ }}
+FROM {{ template "temp_store_name" . }} AS r
+WHERE NOT EXISTS (
+	SELECT 1 FROM {{ template "temp_delete_name" . }} AS d
+	WHERE
+	{{- range $ind, $key := $.Keys }}
+		{{ if $ind }} AND {{ end -}}
+		d.{{ $key.Identifier }} = r.{{ $key.Identifier }}
+	{{- end }}
+);
 {{ end }}
 
 {{ define "installFence" }}
@@ -301,16 +428,22 @@ BEGIN
 END $$;
 {{ end }}
 `)
-	tplCreateLoadTable   = tplAll.Lookup("createLoadTable")
-	tplCreateTargetTable = tplAll.Lookup("createTargetTable")
-	tplAlterTableColumns = tplAll.Lookup("alterTableColumns")
-	tplLoadInsert        = tplAll.Lookup("loadInsert")
-	tplStoreInsert       = tplAll.Lookup("storeInsert")
-	tplStoreUpdate       = tplAll.Lookup("storeUpdate")
-	tplDeleteQuery       = tplAll.Lookup("deleteQuery")
-	tplLoadQuery         = tplAll.Lookup("loadQuery")
-	tplInstallFence      = tplAll.Lookup("installFence")
-	tplUpdateFence       = tplAll.Lookup("updateFence")
+	tplCreateLoadTable      = tplAll.Lookup("createLoadTable")
+	tplCreateTargetTable    = tplAll.Lookup("createTargetTable")
+	tplAlterTableColumns    = tplAll.Lookup("alterTableColumns")
+	tplCreateStoreTable     = tplAll.Lookup("createStoreTable")
+	tplCreateDeleteTable    = tplAll.Lookup("createDeleteTable")
+	tplMergeInto            = tplAll.Lookup("mergeInto")
+	tplUpsertFromStore      = tplAll.Lookup("upsertFromStore")
+	tplDeleteQuery          = tplAll.Lookup("deleteQuery")
+	tplHistoryClose         = tplAll.Lookup("historyClose")
+	tplHistoryInsert        = tplAll.Lookup("historyInsert")
+	tplLoadQuery            = tplAll.Lookup("loadQuery")
+	tplAddEmbeddingColumn   = tplAll.Lookup("addEmbeddingColumn")
+	tplCreateEmbeddingTable = tplAll.Lookup("createEmbeddingTable")
+	tplUpdateEmbeddings     = tplAll.Lookup("updateEmbeddings")
+	tplInstallFence         = tplAll.Lookup("installFence")
+	tplUpdateFence          = tplAll.Lookup("updateFence")
 )
 
 // truncatedIdentifier produces a truncated form of an identifier, in accordance with Postgres'
@@ -25,12 +25,19 @@ func TestSQLGeneration(t *testing.T) {
 			TableTemplates: []*template.Template{
 				tplCreateTargetTable,
 				tplCreateLoadTable,
-				tplLoadInsert,
 				tplLoadQuery,
-				tplStoreInsert,
-				tplStoreUpdate,
+				tplCreateStoreTable,
+				tplCreateDeleteTable,
+				tplMergeInto,
+				tplUpsertFromStore,
 				tplDeleteQuery,
 			},
+			HistoryTableTemplates: []*template.Template{
+				tplCreateTargetTable,
+				tplLoadQuery,
+				tplHistoryClose,
+				tplHistoryInsert,
+			},
 			TplAddColumns:    tplAlterTableColumns,
 			TplDropNotNulls:  tplAlterTableColumns,
 			TplCombinedAlter: tplAlterTableColumns,
@@ -42,6 +49,40 @@ func TestSQLGeneration(t *testing.T) {
 	cupaloy.SnapshotT(t, snap.String())
 }
 
+func TestEmbeddingTemplates(t *testing.T) {
+	table, err := sql.ResolveTable(sql.TableShape{
+		Path:    sql.TablePath{"public", "docs"},
+		Binding: 3,
+		Keys: []sql.Projection{{Projection: pf.Projection{
+			Field:     "id",
+			Inference: pf.Inference{Types: []string{"string"}, String_: &pf.Inference_String{}, Exists: pf.Inference_MUST},
+		}}, {Projection: pf.Projection{
+			Field:     "Version",
+			Inference: pf.Inference{Types: []string{"integer"}, Exists: pf.Inference_MUST},
+		}}},
+	}, pgDialect)
+	require.NoError(t, err)
+
+	params := embeddingTable{
+		Table:      table,
+		Column:     pgDialect.Identifier("embedding"),
+		Dimensions: 1536,
+	}
+
+	var snap strings.Builder
+	for _, tpl := range []*template.Template{
+		tplAddEmbeddingColumn,
+		tplCreateEmbeddingTable,
+		tplUpdateEmbeddings,
+	} {
+		snap.WriteString("--- Begin " + tpl.Name() + " ---")
+		require.NoError(t, tpl.Execute(&snap, &params))
+		snap.WriteString("--- End " + tpl.Name() + " ---\n\n")
+	}
+
+	cupaloy.SnapshotT(t, snap.String())
+}
+
 func TestDateTimeColumn(t *testing.T) {
 	var mapped, err = pgDialect.MapType(&sql.Projection{
 		Projection: pf.Projection{
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// client is a minimal client for the Qdrant REST API.
+type client struct {
+	http   *http.Client
+	url    string
+	apiKey string
+}
+
+func newClient(baseURL string, apiKey string) *client {
+	return &client{
+		http:   http.DefaultClient,
+		url:    strings.TrimSuffix(baseURL, "/"),
+		apiKey: apiKey,
+	}
+}
+
+type qdrantResponse struct {
+	Result json.RawMessage `json:"result"`
+	// Status is "ok" for successful responses, and an object with an error
+	// message otherwise.
+	Status json.RawMessage `json:"status"`
+}
+
+type qdrantPoint struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// do sends a request to the Qdrant API and decodes the result of the response
+// into out, if it is not nil. The response status code is returned along with
+// an error for any unsuccessful response.
+func (c *client) do(ctx context.Context, method string, path string, in any, out any) (int, error) {
+	var body io.Reader
+	if in != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(in); err != nil {
+			return 0, err
+		}
+		body = buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("api-key", c.apiKey)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	var parsed qdrantResponse
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var status struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(resBody, &parsed); err == nil && json.Unmarshal(parsed.Status, &status) == nil && status.Error != "" {
+			return res.StatusCode, fmt.Errorf("qdrant %s %s failed (%s): %s", method, path, res.Status, status.Error)
+		}
+		return res.StatusCode, fmt.Errorf("qdrant %s %s unexpected status: %s: %s", method, path, res.Status, string(resBody))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(resBody, &parsed); err != nil {
+			return res.StatusCode, fmt.Errorf("decoding response: %w", err)
+		} else if err := json.Unmarshal(parsed.Result, out); err != nil {
+			return res.StatusCode, fmt.Errorf("decoding response result: %w", err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
+
+// collectionVectorSize returns the size of the vectors of a collection, and
+// whether the collection exists.
+func (c *client) collectionVectorSize(ctx context.Context, collection string) (int, bool, error) {
+	var result struct {
+		Config struct {
+			Params struct {
+				Vectors json.RawMessage `json:"vectors"`
+			} `json:"params"`
+		} `json:"config"`
+	}
+
+	status, err := c.do(ctx, "GET", "/collections/"+url.PathEscape(collection), nil, &result)
+	if status == http.StatusNotFound {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	var vectors struct {
+		Size int `json:"size"`
+	}
+	if err := json.Unmarshal(result.Config.Params.Vectors, &vectors); err != nil || vectors.Size == 0 {
+		// Collections with multiple named vectors have an object of vector
+		// parameters keyed by name.
+		return 0, true, fmt.Errorf("collection %q must have a single unnamed vector", collection)
+	}
+
+	return vectors.Size, true, nil
+}
+
+func (c *client) createCollection(ctx context.Context, collection string, size int) error {
+	_, err := c.do(ctx, "PUT", "/collections/"+url.PathEscape(collection), map[string]interface{}{
+		"vectors": map[string]interface{}{
+			"size":     size,
+			"distance": "Cosine",
+		},
+	}, nil)
+	return err
+}
+
+func (c *client) upsertPoints(ctx context.Context, collection string, points []qdrantPoint) error {
+	_, err := c.do(ctx, "PUT", "/collections/"+url.PathEscape(collection)+"/points?wait=true", map[string]interface{}{
+		"points": points,
+	}, nil)
+	return err
+}
+
+func (c *client) deletePoints(ctx context.Context, collection string, ids []string) error {
+	_, err := c.do(ctx, "POST", "/collections/"+url.PathEscape(collection)+"/points/delete?wait=true", map[string]interface{}{
+		"points": ids,
+	}, nil)
+	return err
+}
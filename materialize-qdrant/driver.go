@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	m "github.com/estuary/connectors/go/protocols/materialize"
+	schemagen "github.com/estuary/connectors/go/schema-gen"
+	"github.com/estuary/connectors/go/vectors"
+	"github.com/estuary/connectors/go/vectors/embeddings"
+	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+	log "github.com/sirupsen/logrus"
+)
+
+type config struct {
+	URL               string                    `json:"url" jsonschema:"title=Qdrant URL" jsonschema_extras:"order=0"`
+	ApiKey            string                    `json:"apiKey,omitempty" jsonschema:"title=Qdrant API Key" jsonschema_extras:"secret=true,order=1"`
+	EmbeddingProvider embeddings.ProviderConfig `json:"embeddingProvider" jsonschema_extras:"order=2"`
+	HardDelete        bool                      `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,default=false" jsonschema_extras:"order=3"`
+}
+
+func (config) GetFieldDocString(fieldName string) string {
+	switch fieldName {
+	case "URL":
+		return "Base URL of the Qdrant REST API. Example: https://xyz-example.eu-central.aws.cloud.qdrant.io:6333"
+	case "ApiKey":
+		return "Qdrant API key used for authentication. Not required for instances without authentication."
+	case "EmbeddingProvider":
+		return "Provider of the embedding model used to create vectors from documents."
+	case "HardDelete":
+		return "If this option is enabled the points of documents deleted in the source will also be deleted from the collection. By default is disabled and deleted documents are upserted with _meta/op signifying that they have been deleted."
+	default:
+		return ""
+	}
+}
+
+func (c *config) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("endpoint config missing required property 'url'")
+	} else if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("url %q must start with http:// or https://", c.URL)
+	}
+
+	return c.EmbeddingProvider.Validate()
+}
+
+func (c *config) client() *client {
+	return newClient(c.URL, c.ApiKey)
+}
+
+type resource struct {
+	Collection  string `json:"collection" jsonschema:"title=Qdrant Collection" jsonschema_extras:"x-collection-name=true"`
+	VectorField string `json:"vectorField,omitempty" jsonschema:"title=Vector Field"`
+}
+
+func (resource) GetFieldDocString(fieldName string) string {
+	switch fieldName {
+	case "Collection":
+		return "Name of the Qdrant collection that this collection will materialize points into. It is created with cosine distance if it does not exist. Collections for bindings with a vector field must already exist."
+	case "VectorField":
+		return "Optional field of the collection with a precomputed vector for each document, as an array of numbers. If set, this vector is materialized instead of creating an embedding of the document."
+	default:
+		return ""
+	}
+}
+
+func (r resource) Validate() error {
+	if r.Collection == "" {
+		return fmt.Errorf("missing collection")
+	}
+
+	return nil
+}
+
+type driver struct{}
+
+func (d driver) Spec(ctx context.Context, req *pm.Request_Spec) (*pm.Response_Spec, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validating request: %w", err)
+	}
+
+	endpointSchema, err := schemagen.GenerateSchema("Materialize Qdrant Spec", &config{}).MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("generating endpoint schema: %w", err)
+	}
+
+	resourceSchema, err := schemagen.GenerateSchema("Qdrant Collection", &resource{}).MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("generating resource schema: %w", err)
+	}
+
+	return &pm.Response_Spec{
+		ConfigSchemaJson:         json.RawMessage(endpointSchema),
+		ResourceConfigSchemaJson: json.RawMessage(resourceSchema),
+		DocumentationUrl:         "https://go.estuary.dev/materialize-qdrant",
+	}, nil
+}
+
+func (d driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Response_Validated, error) {
+	cfg, err := resolveEndpointConfig(req.ConfigJson)
+	if err != nil {
+		return nil, err
+	}
+
+	embedder, err := cfg.EmbeddingProvider.Embedder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var embedderDims int
+	if embedder != nil {
+		if embedderDims, err = vectors.VerifyEmbedder(ctx, embedder); err != nil {
+			return nil, err
+		}
+	}
+
+	c := cfg.client()
+
+	var out []*pm.Response_Validated_Binding
+	for _, b := range req.Bindings {
+		res, err := resolveResourceConfig(b.ResourceConfigJson)
+		if err != nil {
+			return nil, err
+		}
+
+		constraints, err := vectors.ValidateBinding(res.VectorField, embedder != nil, b.Collection.Projections)
+		if err != nil {
+			return nil, fmt.Errorf("binding for collection %q: %w", res.Collection, err)
+		}
+
+		// Validate connectivity and that the collection is appropriately
+		// dimensioned, if it exists.
+		size, exists, err := c.collectionVectorSize(ctx, res.Collection)
+		if err != nil {
+			return nil, fmt.Errorf("getting collection %q: %w", res.Collection, err)
+		} else if !exists && res.VectorField != "" {
+			return nil, fmt.Errorf("collection %q must exist since the binding has a vector field", res.Collection)
+		} else if exists && res.VectorField == "" && size != embedderDims {
+			return nil, fmt.Errorf(
+				"collection '%s' has vectors of size %d but the embedding model creates vectors with %d dimensions",
+				res.Collection,
+				size,
+				embedderDims,
+			)
+		}
+
+		out = append(out, &pm.Response_Validated_Binding{
+			Constraints:  constraints,
+			DeltaUpdates: true,
+			ResourcePath: []string{res.Collection},
+		})
+	}
+
+	return &pm.Response_Validated{Bindings: out}, nil
+}
+
+func (d driver) Apply(ctx context.Context, req *pm.Request_Apply) (*pm.Response_Applied, error) {
+	cfg, err := resolveEndpointConfig(req.Materialization.ConfigJson)
+	if err != nil {
+		return nil, err
+	}
+
+	c := cfg.client()
+
+	var actions []string
+	var embedderDims int
+	for _, b := range req.Materialization.Bindings {
+		collection := b.ResourcePath[0]
+		if _, exists, err := c.collectionVectorSize(ctx, collection); err != nil {
+			return nil, fmt.Errorf("getting collection %q: %w", collection, err)
+		} else if exists {
+			continue
+		}
+
+		// Only bindings using the embedding provider may not have an existing
+		// collection, and it determines the size of the vectors.
+		if embedderDims == 0 {
+			embedder, err := cfg.EmbeddingProvider.Embedder(ctx)
+			if err != nil {
+				return nil, err
+			} else if embedder == nil {
+				return nil, fmt.Errorf("collection %q does not exist", collection)
+			} else if embedderDims, err = vectors.VerifyEmbedder(ctx, embedder); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := c.createCollection(ctx, collection, embedderDims); err != nil {
+			return nil, fmt.Errorf("creating collection %q: %w", collection, err)
+		}
+		log.WithField("collection", collection).Info("created collection")
+		actions = append(actions, fmt.Sprintf("created collection %q with vectors of size %d", collection, embedderDims))
+	}
+
+	return &pm.Response_Applied{
+		ActionDescription: strings.Join(actions, "\n"),
+	}, nil
+}
+
+func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boilerplate.BindingEvents) (m.Transactor, *pm.Response_Opened, *boilerplate.MaterializeOptions, error) {
+	var cfg, err = resolveEndpointConfig(open.Materialization.ConfigJson)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	embedder, err := cfg.EmbeddingProvider.Embedder(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	c := cfg.client()
+
+	var bindings []vectors.Binding
+	var collections []string
+	for _, b := range open.Materialization.Bindings {
+		res, err := resolveResourceConfig(b.ResourceConfigJson)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		size, exists, err := c.collectionVectorSize(ctx, res.Collection)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("getting collection %q: %w", res.Collection, err)
+		} else if !exists {
+			return nil, nil, nil, fmt.Errorf("collection %q does not exist", res.Collection)
+		}
+		collections = append(collections, res.Collection)
+
+		binding, err := vectors.NewBinding(b, res.VectorField, size)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+
+	var batchEmbedder *vectors.BatchEmbedder
+	if embedder != nil {
+		batchEmbedder = vectors.NewBatchEmbedder(embedder, vectors.BatchSize)
+	}
+
+	return vectors.NewTransactor(&store{client: c, collections: collections}, batchEmbedder, bindings, cfg.HardDelete), &pm.Response_Opened{}, nil, nil
+}
+
+func resolveEndpointConfig(specJson json.RawMessage) (config, error) {
+	var cfg = config{}
+	if err := pf.UnmarshalStrict(specJson, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing endpoint config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func resolveResourceConfig(specJson json.RawMessage) (resource, error) {
+	var res = resource{}
+	if err := pf.UnmarshalStrict(specJson, &res); err != nil {
+		return res, fmt.Errorf("parsing resource config: %w", err)
+	}
+
+	return res, nil
+}
+
+func main() {
+	boilerplate.RunMain(driver{})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/estuary/connectors/go/vectors"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecification(t *testing.T) {
+	resp, err := driver{}.Spec(context.Background(), &pm.Request_Spec{})
+	require.NoError(t, err)
+
+	formatted, err := json.MarshalIndent(resp, "", "  ")
+	require.NoError(t, err)
+
+	cupaloy.SnapshotT(t, formatted)
+}
+
+func TestStore(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.Header.Get("api-key"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+string(body))
+
+		switch r.URL.Path {
+		case "/collections/docs":
+			w.Write([]byte(`{"result": {"config": {"params": {"vectors": {"size": 2, "distance": "Cosine"}}}}, "status": "ok"}`))
+		case "/collections/named":
+			w.Write([]byte(`{"result": {"config": {"params": {"vectors": {"a": {"size": 2, "distance": "Cosine"}}}}}, "status": "ok"}`))
+		case "/collections/docs/points", "/collections/docs/points/delete":
+			w.Write([]byte(`{"result": {"status": "completed"}, "status": "ok"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status": {"error": "Not found: Collection doesn't exist!"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := newClient(srv.URL+"/", "secret")
+
+	size, exists, err := c.collectionVectorSize(ctx, "docs")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, 2, size)
+
+	_, exists, err = c.collectionVectorSize(ctx, "missing")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, _, err = c.collectionVectorSize(ctx, "named")
+	require.EqualError(t, err, `collection "named" must have a single unnamed vector`)
+
+	s := &store{client: c, collections: []string{"docs"}}
+	require.NoError(t, s.Upsert(ctx, 0, []vectors.Record{{
+		ID:       "0102",
+		Vector:   []float32{0.5, 1},
+		Fields:   map[string]interface{}{"key": int64(1), "obj": []byte(`{"a":true}`)},
+		Document: json.RawMessage(`{"key":1,"obj":{"a":true}}`),
+	}}))
+	require.NoError(t, s.Delete(ctx, 0, []string{"0102"}))
+
+	err = (&store{client: c, collections: []string{"missing"}}).Delete(ctx, 0, []string{"0102"})
+	require.ErrorContains(t, err, "Not found: Collection doesn't exist!")
+
+	cupaloy.SnapshotT(t, requests)
+}
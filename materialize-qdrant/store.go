@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/estuary/connectors/go/vectors"
+	"github.com/google/uuid"
+)
+
+// Qdrant point IDs must be unsigned integers or UUIDs, so the ID of each point
+// is a UUID derived from the packed key of its document.
+var pointIDNamespace = uuid.MustParse("5e1b8a3c-6f0d-4c57-9b1e-2f6a9d4c8e71")
+
+func pointID(id string) string {
+	return uuid.NewSHA1(pointIDNamespace, []byte(id)).String()
+}
+
+// store upserts points into the Qdrant collection of each binding.
+type store struct {
+	client      *client
+	collections []string
+}
+
+var _ vectors.Store = (*store)(nil)
+
+func (s *store) Upsert(ctx context.Context, binding int, records []vectors.Record) error {
+	points := make([]qdrantPoint, 0, len(records))
+	for _, r := range records {
+		// The selected fields are included in the payload so that they can be
+		// used to filter queries, along with the full document.
+		payload := make(map[string]interface{}, len(r.Fields)+1)
+		for field, val := range r.Fields {
+			if raw, ok := val.([]byte); ok {
+				// JSON arrays and objects are provided as their encoded JSON.
+				val = json.RawMessage(raw)
+			}
+			payload[field] = val
+		}
+		payload[vectors.FlowDocumentField] = r.Document
+
+		points = append(points, qdrantPoint{
+			ID:      pointID(r.ID),
+			Vector:  r.Vector,
+			Payload: payload,
+		})
+	}
+
+	if err := s.client.upsertPoints(ctx, s.collections[binding], points); err != nil {
+		return fmt.Errorf("qdrant upserting batch: %w", err)
+	}
+
+	return nil
+}
+
+func (s *store) Delete(ctx context.Context, binding int, ids []string) error {
+	pointIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		pointIDs = append(pointIDs, pointID(id))
+	}
+
+	if err := s.client.deletePoints(ctx, s.collections[binding], pointIDs); err != nil {
+		return fmt.Errorf("qdrant deleting batch: %w", err)
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"path"
+	"slices"
 	"strings"
 	"time"
 
@@ -104,6 +105,42 @@ func (c *client) AlterTable(ctx context.Context, ta sql.TableAlter) (string, boi
 		}
 	}
 
+	if ta.LayoutChanged {
+		var prev sql.TableLayout
+		if ta.PreviousLayout != nil {
+			prev = *ta.PreviousLayout
+		}
+		var distKey *sql.Column
+		var sortKey []sql.Column
+		if ta.LayoutColumns != nil {
+			distKey = ta.LayoutColumns.DistributeBy
+			sortKey = ta.LayoutColumns.ClusterBy
+		}
+
+		// Changing the distribution or sort key of a table causes Redshift to
+		// re-write it in the background.
+		if distKey == nil && prev.DistributeBy != "" {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER DISTSTYLE AUTO;", ta.Identifier))
+		} else if distKey != nil && distKey.Field != prev.DistributeBy {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER DISTKEY %s;", ta.Identifier, distKey.Identifier))
+		}
+
+		var sortKeyFields, sortKeyIdentifiers []string
+		for _, col := range sortKey {
+			sortKeyFields = append(sortKeyFields, col.Field)
+			sortKeyIdentifiers = append(sortKeyIdentifiers, col.Identifier)
+		}
+		if len(sortKey) == 0 && len(prev.ClusterBy) != 0 {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER SORTKEY AUTO;", ta.Identifier))
+		} else if len(sortKey) != 0 && !slices.Equal(sortKeyFields, prev.ClusterBy) {
+			statements = append(statements, fmt.Sprintf(
+				"ALTER TABLE %s ALTER COMPOUND SORTKEY (%s);",
+				ta.Identifier,
+				strings.Join(sortKeyIdentifiers, ", "),
+			))
+		}
+	}
+
 	return strings.Join(statements, "\n"), func(ctx context.Context) error {
 		for _, stmt := range statements {
 			_, err := c.db.ExecContext(ctx, stmt)
@@ -11,9 +11,10 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	"github.com/estuary/connectors/go/dbt"
 	networkTunnel "github.com/estuary/connectors/go/network-tunnel"
 	m "github.com/estuary/connectors/go/protocols/materialize"
@@ -61,20 +62,20 @@ type tunnelConfig struct {
 }
 
 type config struct {
-	Address            string                     `json:"address" jsonschema:"title=Address,description=Host and port of the database. Example: red-shift-cluster-name.account.us-east-2.redshift.amazonaws.com:5439" jsonschema_extras:"order=0"`
-	User               string                     `json:"user" jsonschema:"title=User,description=Database user to connect as." jsonschema_extras:"order=1"`
-	Password           string                     `json:"password" jsonschema:"title=Password,description=Password for the specified database user." jsonschema_extras:"secret=true,order=2"`
-	Database           string                     `json:"database,omitempty" jsonschema:"title=Database,description=Name of the logical database to materialize to. The materialization will attempt to connect to the default database for the provided user if omitted." jsonschema_extras:"order=3"`
-	Schema             string                     `json:"schema,omitempty" jsonschema:"title=Database Schema,default=public,description=Database schema for bound collection tables (unless overridden within the binding resource configuration) as well as associated materialization metadata tables." jsonschema_extras:"order=4"`
-	Bucket             string                     `json:"bucket" jsonschema:"title=S3 Staging Bucket,description=Name of the S3 bucket to use for staging data loads." jsonschema_extras:"order=5"`
-	AWSAccessKeyID     string                     `json:"awsAccessKeyId" jsonschema:"title=Access Key ID,description=AWS Access Key ID for reading and writing data to the S3 staging bucket." jsonschema_extras:"order=6"`
-	AWSSecretAccessKey string                     `json:"awsSecretAccessKey" jsonschema:"title=Secret Access Key,description=AWS Secret Access Key for reading and writing data to the S3 staging bucket." jsonschema_extras:"secret=true,order=7"`
-	Region             string                     `json:"region" jsonschema:"title=Region,description=Region of the S3 staging bucket. For optimal performance this should be in the same region as the Redshift database cluster." jsonschema_extras:"order=8"`
-	BucketPath         string                     `json:"bucketPath,omitempty" jsonschema:"title=Bucket Path,description=A prefix that will be used to store objects in S3." jsonschema_extras:"order=9"`
-	HardDelete         bool                       `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=10"`
-	Schedule           boilerplate.ScheduleConfig `json:"syncSchedule,omitempty" jsonschema:"title=Sync Schedule,description=Configure schedule of transactions for the materialization."`
-	DBTJobTrigger      dbt.JobConfig              `json:"dbt_job_trigger,omitempty" jsonschema:"title=dbt Cloud Job Trigger,description=Trigger a dbt Job when new data is available"`
-	NetworkTunnel      *tunnelConfig              `json:"networkTunnel,omitempty" jsonschema:"title=Network Tunnel,description=Connect to your Redshift cluster through an SSH server that acts as a bastion host for your network."`
+	Address     string                    `json:"address" jsonschema:"title=Address,description=Host and port of the database. Example: red-shift-cluster-name.account.us-east-2.redshift.amazonaws.com:5439" jsonschema_extras:"order=0"`
+	User        string                    `json:"user" jsonschema:"title=User,description=Database user to connect as." jsonschema_extras:"order=1"`
+	Password    string                    `json:"password" jsonschema:"title=Password,description=Password for the specified database user." jsonschema_extras:"secret=true,order=2"`
+	Database    string                    `json:"database,omitempty" jsonschema:"title=Database,description=Name of the logical database to materialize to. The materialization will attempt to connect to the default database for the provided user if omitted." jsonschema_extras:"order=3"`
+	Schema      string                    `json:"schema,omitempty" jsonschema:"title=Database Schema,default=public,description=Database schema for bound collection tables (unless overridden within the binding resource configuration) as well as associated materialization metadata tables." jsonschema_extras:"order=4"`
+	Bucket      string                    `json:"bucket" jsonschema:"title=S3 Staging Bucket,description=Name of the S3 bucket to use for staging data loads." jsonschema_extras:"order=5"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication,description=AWS credentials for reading and writing data to the S3 staging bucket. They are also used by Redshift to load data from the bucket." jsonschema_extras:"order=6"`
+	awsauth.AccessKeys
+	Region        string                     `json:"region" jsonschema:"title=Region,description=Region of the S3 staging bucket. For optimal performance this should be in the same region as the Redshift database cluster." jsonschema_extras:"order=9"`
+	BucketPath    string                     `json:"bucketPath,omitempty" jsonschema:"title=Bucket Path,description=A prefix that will be used to store objects in S3." jsonschema_extras:"order=10"`
+	HardDelete    bool                       `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=11"`
+	Schedule      boilerplate.ScheduleConfig `json:"syncSchedule,omitempty" jsonschema:"title=Sync Schedule,description=Configure schedule of transactions for the materialization."`
+	DBTJobTrigger dbt.JobConfig              `json:"dbt_job_trigger,omitempty" jsonschema:"title=dbt Cloud Job Trigger,description=Trigger a dbt Job when new data is available"`
+	NetworkTunnel *tunnelConfig              `json:"networkTunnel,omitempty" jsonschema:"title=Network Tunnel,description=Connect to your Redshift cluster through an SSH server that acts as a bastion host for your network."`
 }
 
 func (c *config) Validate() error {
@@ -83,8 +84,6 @@ func (c *config) Validate() error {
 		{"user", c.User},
 		{"password", c.Password},
 		{"bucket", c.Bucket},
-		{"awsAccessKeyId", c.AWSAccessKeyID},
-		{"awsSecretAccessKey", c.AWSSecretAccessKey},
 		{"region", c.Region},
 	}
 	for _, req := range requiredProperties {
@@ -93,6 +92,10 @@ func (c *config) Validate() error {
 		}
 	}
 
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
+	}
+
 	if c.BucketPath != "" {
 		// If BucketPath starts with a / trim the leading / so that we don't end up with repeated /
 		// chars in the URI and so that the object key does not start with a /.
@@ -138,13 +141,13 @@ func (c *config) toURI() string {
 	return uri.String()
 }
 
+func (c *config) toAWSConfig(ctx context.Context) (aws.Config, error) {
+	creds := awsauth.ResolveCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey)
+	return creds.AWSConfig(ctx, awsConfig.WithRegion(c.Region))
+}
+
 func (c *config) toS3Client(ctx context.Context) (*s3.Client, error) {
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx,
-		awsConfig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(c.AWSAccessKeyID, c.AWSSecretAccessKey, ""),
-		),
-		awsConfig.WithRegion(c.Region),
-	)
+	awsCfg, err := c.toAWSConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -152,10 +155,16 @@ func (c *config) toS3Client(ctx context.Context) (*s3.Client, error) {
 	return s3.NewFromConfig(awsCfg), nil
 }
 
+// Redshift allows at most 400 columns in a compound sort key.
+const maxSortKeyColumns = 400
+
 type tableConfig struct {
-	Table  string `json:"table" jsonschema:"title=Table,description=Name of the database table." jsonschema_extras:"x-collection-name=true"`
-	Schema string `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)." jsonschema_extras:"x-schema-name=true"`
-	Delta  bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	Table   string   `json:"table" jsonschema:"title=Table,description=Name of the database table." jsonschema_extras:"x-collection-name=true"`
+	Schema  string   `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)." jsonschema_extras:"x-schema-name=true"`
+	Delta   bool     `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	History bool     `json:"history_mode,omitempty" jsonschema:"default=false,title=History Mode,description=Keep a row for every version of each document instead of only the latest version. Rows have valid_from and valid_to times and an is_current flag. Default is false."`
+	DistKey string   `json:"distkey,omitempty" jsonschema:"title=Distribution Key,description=Field to use as the distribution key of the table (optional). If not set the table uses automatic distribution."`
+	SortKey []string `json:"sortkey,omitempty" jsonschema:"title=Sort Key,description=Fields to use as the compound sort key of the table (optional). If not set the table uses an automatic sort key."`
 }
 
 func newTableConfig(ep *sql.Endpoint) sql.Resource {
@@ -170,6 +179,8 @@ func newTableConfig(ep *sql.Endpoint) sql.Resource {
 func (r tableConfig) Validate() error {
 	if r.Table == "" {
 		return fmt.Errorf("missing table")
+	} else if len(r.SortKey) > maxSortKeyColumns {
+		return fmt.Errorf("sortkey may include at most %d fields, got %d", maxSortKeyColumns, len(r.SortKey))
 	}
 	return nil
 }
@@ -185,6 +196,17 @@ func (c tableConfig) DeltaUpdates() bool {
 	return c.Delta
 }
 
+func (c tableConfig) HistoryMode() bool {
+	return c.History
+}
+
+func (c tableConfig) Layout() *sql.TableLayout {
+	if c.DistKey == "" && len(c.SortKey) == 0 {
+		return nil
+	}
+	return &sql.TableLayout{ClusterBy: c.SortKey, DistributeBy: c.DistKey}
+}
+
 func newRedshiftDriver() *sql.Driver {
 	return &sql.Driver{
 		DocumentationURL: "https://go.estuary.dev/materialize-redshift",
@@ -275,6 +297,12 @@ type transactor struct {
 	bindings  []*binding
 	be        *boilerplate.BindingEvents
 	cfg       *config
+
+	// awsCredentials provides the credentials used by COPY statements, which
+	// are re-rendered when the credentials are refreshed. Credentials of an
+	// assumed role are temporary, and Redshift can't refresh them itself.
+	awsCredentials      aws.CredentialsProvider
+	renderedCredentials aws.Credentials
 }
 
 func prepareNewTransactor(
@@ -300,10 +328,12 @@ func prepareNewTransactor(
 			be:        be,
 		}
 
-		s3client, err := d.cfg.toS3Client(ctx)
+		awsCfg, err := d.cfg.toAWSConfig(ctx)
 		if err != nil {
 			return nil, nil, err
 		}
+		s3client := s3.NewFromConfig(awsCfg)
+		d.awsCredentials = awsCfg.Credentials
 
 		for idx, target := range bindings {
 			if err = d.addBinding(
@@ -340,12 +370,15 @@ type binding struct {
 	createStoreTableSQL     string
 	createDeleteTableSQL    string
 	mergeIntoSQL            string
+	historyMergeSQL         string
 	deleteQuerySQL          string
 	loadQuerySQL            string
 	copyIntoLoadTableSQL    string
 	copyIntoMergeTableSQL   string
 	copyIntoDeleteTableSQL  string
 	copyIntoTargetTableSQL  string
+	// The COPY statements are rendered from these by renderCopyStatements.
+	copyStatements []copyStatement
 
 	// A reference of all staged file cleanup operations that should be run once
 	// the commit has completed. Will be empty if not data was processed for
@@ -360,6 +393,11 @@ type binding struct {
 	mustMerge bool
 }
 
+type copyStatement struct {
+	sql    *string
+	params copyFromS3Params
+}
+
 // varcharColumnMeta contains metadata about Redshift varchar columns. Currently this is just the
 // maximum length of the field as reported from the database, populated upon connector startup.
 type varcharColumnMeta struct {
@@ -382,7 +420,8 @@ func (t *transactor) addBinding(
 		deleteFile: newStagedFile(client, t.cfg.Bucket, t.cfg.BucketPath, target.KeyNames()),
 	}
 
-	// Render templates that require specific S3 "COPY INTO" parameters.
+	// Collect parameters of templates that require specific S3 "COPY INTO" parameters. These are
+	// rendered with the current AWS credentials before they are used by renderCopyStatements.
 	for _, m := range []struct {
 		sql             *string
 		target          string
@@ -395,18 +434,17 @@ func (t *transactor) addBinding(
 		{&b.copyIntoDeleteTableSQL, fmt.Sprintf("flow_temp_table_%d_deleted", bindingIdx), target.KeyPtrs(), false, b.deleteFile},
 		{&b.copyIntoTargetTableSQL, target.Identifier, target.Columns(), true, b.storeFile},
 	} {
-		var sql strings.Builder
-		if err := t.templates.copyFromS3.Execute(&sql, copyFromS3Params{
-			Target:                         m.target,
-			Columns:                        m.columns,
-			ManifestURL:                    m.stagedFile.fileURI(manifestFile),
-			Config:                         t.cfg,
-			CaseSensitiveIdentifierEnabled: caseSensitiveIdentifierEnabled,
-			TruncateColumns:                m.truncateColumns,
-		}); err != nil {
-			return err
-		}
-		*m.sql = sql.String()
+		b.copyStatements = append(b.copyStatements, copyStatement{
+			sql: m.sql,
+			params: copyFromS3Params{
+				Target:                         m.target,
+				Columns:                        m.columns,
+				ManifestURL:                    m.stagedFile.fileURI(manifestFile),
+				Config:                         t.cfg,
+				CaseSensitiveIdentifierEnabled: caseSensitiveIdentifierEnabled,
+				TruncateColumns:                m.truncateColumns,
+			},
+		})
 	}
 
 	// Render templates that rely only on the target table.
@@ -426,6 +464,13 @@ func (t *transactor) addBinding(
 		}
 	}
 
+	if target.History != nil {
+		var err error
+		if b.historyMergeSQL, err = sql.RenderTableTemplate(target, t.templates.historyMerge); err != nil {
+			return err
+		}
+	}
+
 	// The load table template is re-evaluated every transaction to account for the specific string
 	// lengths observed for string keys in the load key set.
 	b.createLoadTableTemplate = t.templates.createLoadTable
@@ -464,6 +509,32 @@ func (t *transactor) addBinding(
 	return nil
 }
 
+// renderCopyStatements renders the COPY statements of all bindings if the AWS
+// credentials have changed since they were last rendered.
+func (d *transactor) renderCopyStatements(ctx context.Context) error {
+	creds, err := d.awsCredentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving AWS credentials: %w", err)
+	} else if creds == d.renderedCredentials {
+		return nil
+	}
+
+	for _, b := range d.bindings {
+		for _, c := range b.copyStatements {
+			c.params.Credentials = creds
+
+			var sql strings.Builder
+			if err := d.templates.copyFromS3.Execute(&sql, c.params); err != nil {
+				return fmt.Errorf("rendering copy statement for %s: %w", b.target.Identifier, err)
+			}
+			*c.sql = sql.String()
+		}
+	}
+	d.renderedCredentials = creds
+
+	return nil
+}
+
 func (t *transactor) UnmarshalState(state json.RawMessage) error                  { return nil }
 func (t *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error) { return nil, nil }
 
@@ -520,6 +591,10 @@ func (d *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage)
 		return nil
 	}
 
+	if err := d.renderCopyStatements(ctx); err != nil {
+		return err
+	}
+
 	conn, err := pgx.Connect(ctx, d.cfg.toURI())
 	if err != nil {
 		return fmt.Errorf("load pgx.Connect: %w", err)
@@ -646,7 +721,32 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		// Unfortunately Redshift does not support MERGE INTO statements with multiple clauses
 		// so we can't use it to both update and delete rows. So instead we use a separate
 		// temporary table and run a `DELETE USING` query to delete rows
-		if d.cfg.HardDelete && it.Delete {
+		if b.target.History != nil && it.Delete {
+			if !it.Exists {
+				// Ignore items which do not exist and are already deleted
+				continue
+			}
+
+			// Deleted documents are staged to close out their current row in
+			// the history table, and their keys are staged so that a new row is
+			// not inserted for them.
+			b.hasStores, b.hasDeletes = true, true
+
+			keys, err := b.target.ConvertKey(it.Key)
+			if err != nil {
+				return nil, fmt.Errorf("converting delete parameters: %w", err)
+			}
+			b.deleteFile.start()
+			if err := b.deleteFile.encodeRow(ctx, keys); err != nil {
+				return nil, fmt.Errorf("encoding row for delete: %w", err)
+			}
+
+			file = b.storeFile
+			converted, err = b.target.ConvertAll(it.Key, it.Values, it.RawJSON)
+			if err != nil {
+				return nil, fmt.Errorf("converting store parameters: %w", err)
+			}
+		} else if d.cfg.HardDelete && it.Delete {
 			if it.Exists {
 				file = b.deleteFile
 				b.hasDeletes = true
@@ -748,6 +848,10 @@ func (d *transactor) commit(ctx context.Context, varcharColumnUpdates map[string
 		}
 	}()
 
+	if err := d.renderCopyStatements(ctx); err != nil {
+		return err
+	}
+
 	conn, err := pgx.Connect(ctx, d.cfg.toURI())
 	if err != nil {
 		return fmt.Errorf("store pgx.Connect: %w", err)
@@ -816,6 +920,14 @@ func (d *transactor) commit(ctx context.Context, varcharColumnUpdates map[string
 		}
 		d.be.StartedResourceCommit(b.target.Path)
 
+		if b.target.History != nil {
+			if err := commitHistory(ctx, txn, d.cfg.Bucket, b); err != nil {
+				return err
+			}
+			d.be.FinishedResourceCommit(b.target.Path)
+			continue
+		}
+
 		if b.hasDeletes {
 			// Create the temporary table for staging values to delete from the target table.
 			// Redshift actually supports transactional DDL for creating tables, so this can be
@@ -865,6 +977,32 @@ func (d *transactor) commit(ctx context.Context, varcharColumnUpdates map[string
 	return nil
 }
 
+// commitHistory applies the staged documents of a binding in history mode.
+// The staging tables for documents and deleted keys are always created, since
+// the history merge statements reference both of them.
+func commitHistory(ctx context.Context, txn pgx.Tx, bucket string, b *binding) error {
+	for _, create := range []string{b.createStoreTableSQL, b.createDeleteTableSQL} {
+		if _, err := txn.Exec(ctx, create); err != nil {
+			return fmt.Errorf("creating staging table: %w", err)
+		}
+	}
+
+	if _, err := txn.Exec(ctx, b.copyIntoMergeTableSQL); err != nil {
+		return handleCopyIntoErr(ctx, txn, bucket, b.storeFile.prefix, b.target.Identifier, err)
+	}
+	if b.hasDeletes {
+		if _, err := txn.Exec(ctx, b.copyIntoDeleteTableSQL); err != nil {
+			return handleCopyIntoErr(ctx, txn, bucket, b.deleteFile.prefix, b.target.Identifier, err)
+		}
+	}
+
+	if _, err := txn.Exec(ctx, b.historyMergeSQL); err != nil {
+		return fmt.Errorf("merging history to table '%s': %w", b.target.Identifier, err)
+	}
+
+	return nil
+}
+
 // handleCopyIntoErr queries the `sys_load_error_detail` table for relevant COPY INTO error details
 // and returns a more useful error than the opaque error returned by Redshift. This function will
 // always return an error. `sys_load_error_detail` is queried instead of `stl_load_errors` since it
@@ -8,6 +8,7 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	sql "github.com/estuary/connectors/materialize-sql"
 	"github.com/estuary/flow/go/protocols/fdb/tuple"
 )
@@ -165,6 +166,7 @@ type copyFromS3Params struct {
 	Columns                        []*sql.Column
 	ManifestURL                    string
 	Config                         *config
+	Credentials                    aws.Credentials
 	CaseSensitiveIdentifierEnabled bool
 	TruncateColumns                bool
 }
@@ -180,6 +182,7 @@ type templates struct {
 	createStoreTable  *template.Template
 	createDeleteTable *template.Template
 	mergeInto         *template.Template
+	historyMerge      *template.Template
 	deleteQuery       *template.Template
 	loadQuery         *template.Template
 	copyFromS3        *template.Template
@@ -205,12 +208,25 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
 	{{- if $ind }},{{ end }}
 	{{$col.Identifier}} {{$col.DDL}}
 {{- end }}
-);
+{{- template "historyColumnDefinitions" $ }}
+)
+{{- if and $.LayoutColumns $.LayoutColumns.DistributeBy }}
+DISTKEY({{ $.LayoutColumns.DistributeBy.Identifier }})
+{{- end }}
+{{- if and $.LayoutColumns $.LayoutColumns.ClusterBy }}
+COMPOUND SORTKEY(
+	{{- range $ind, $col := $.LayoutColumns.ClusterBy }}
+	{{- if $ind }}, {{ end -}}
+	{{$col.Identifier}}
+	{{- end -}}
+)
+{{- end }};
 
 COMMENT ON TABLE {{$.Identifier}} IS {{Literal $.Comment}};
 {{- range $col := .Columns }}
 COMMENT ON COLUMN {{$.Identifier}}.{{$col.Identifier}} IS {{Literal $col.Comment}};
 {{- end}}
+{{- template "historyColumnComments" $ }}
 {{ end }}
 
 -- Idempotent creation of the load table for staging load keys.
@@ -228,12 +244,23 @@ CREATE TEMPORARY TABLE {{ template "temp_name" $.Target }} (
 );
 {{ end }}
 
--- Idempotent creation of the store table for staging new records.
+-- Idempotent creation of the store table for staging new records. Tables in
+-- history mode have additional columns which are not staged, so only the
+-- staged columns are selected from them.
 
 {{ define "createStoreTable" }}
+{{ if $.History -}}
+CREATE TEMPORARY TABLE {{ template "temp_name" . }} AS
+SELECT {{ range $ind, $col := $.Columns }}
+	{{- if $ind }}, {{ end -}}
+	{{$col.Identifier}}
+{{- end }}
+FROM {{$.Identifier}} WHERE false;
+{{- else -}}
 CREATE TEMPORARY TABLE {{ template "temp_name" . }} (
 	LIKE {{$.Identifier}}
 );
+{{- end }}
 {{ end }}
 
 {{ define "createDeleteTable" }}
@@ -290,6 +317,34 @@ WHERE {{ range $ind, $key := $.Keys }}
 {{- end }}
 {{ end }}
 
+-- Templated query which applies staged documents to a table in history mode.
+-- The current row of each staged document is closed out, and a new current
+-- row is inserted for each staged document that isn't a deletion.
+
+{{ define "historyMerge" }}
+UPDATE {{ $.Identifier }}
+SET {{ $.History.ValidTo.Identifier }} = r.{{ $.History.PublishedAt.Identifier }},
+	{{ $.History.IsCurrent.Identifier }} = false
+FROM {{ template "temp_name" . }} AS r
+WHERE {{ range $ind, $key := $.Keys }}
+{{- if $ind }} AND {{end -}}
+	{{$.Identifier}}.{{$key.Identifier}} = r.{{$key.Identifier}}
+{{- end }}
+	AND {{$.Identifier}}.{{ $.History.IsCurrent.Identifier }}
+	AND {{$.Identifier}}.{{ $.History.ValidFrom.Identifier }} < r.{{ $.History.PublishedAt.Identifier }};
+
+INSERT INTO {{ $.Identifier }} ({{ template "historyInsertColumns" $ }})
+SELECT {{ template "historyInsertValues" $ }}
+FROM {{ template "temp_name" . }} AS r
+WHERE NOT EXISTS (
+	SELECT 1 FROM {{ template "temp_name_deleted" . }} AS d
+	WHERE {{ range $ind, $key := $.Keys }}
+	{{- if $ind }} AND {{end -}}
+		d.{{$key.Identifier}} = r.{{$key.Identifier}}
+	{{- end }}
+);
+{{ end }}
+
 -- Templated query which joins keys from the load table with the target table, and returns values. It
 -- deliberately skips the trailing semi-colon as these queries are composed with a UNION ALL.
 
@@ -302,6 +357,9 @@ SELECT {{ $.Binding }}, r.{{$.Document.Identifier}}
 		{{ if $ind }} AND {{ else }} ON  {{ end -}}
 			l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
 	{{- end }}
+	{{- if $.History }}
+		AND r.{{ $.History.IsCurrent.Identifier }}
+	{{- end }}
 {{- else -}}
 SELECT * FROM (SELECT -1, CAST(NULL AS SUPER) LIMIT 0) as nodoc
 {{- end }}
@@ -314,7 +372,7 @@ SELECT * FROM (SELECT -1, CAST(NULL AS SUPER) LIMIT 0) as nodoc
 COPY {{ $.Target }}
 FROM '{{ $.ManifestURL }}'
 MANIFEST
-CREDENTIALS 'aws_access_key_id={{ $.Config.AWSAccessKeyID }};aws_secret_access_key={{ $.Config.AWSSecretAccessKey }}'
+CREDENTIALS 'aws_access_key_id={{ $.Credentials.AccessKeyID }};aws_secret_access_key={{ $.Credentials.SecretAccessKey }}{{ if $.Credentials.SessionToken }};token={{ $.Credentials.SessionToken }}{{ end }}'
 REGION '{{ $.Config.Region }}'
 {{ if $.CaseSensitiveIdentifierEnabled -}}
 JSON 'auto'
@@ -337,6 +395,7 @@ TRUNCATECOLUMNS;
 		createStoreTable:  tplAll.Lookup("createStoreTable"),
 		createDeleteTable: tplAll.Lookup("createDeleteTable"),
 		mergeInto:         tplAll.Lookup("mergeInto"),
+		historyMerge:      tplAll.Lookup("historyMerge"),
 		deleteQuery:       tplAll.Lookup("deleteQuery"),
 		loadQuery:         tplAll.Lookup("loadQuery"),
 		copyFromS3:        tplAll.Lookup("copyFromS3"),
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"text/template"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/bradleyjkemp/cupaloy"
 	sql "github.com/estuary/connectors/materialize-sql"
 	sqlDriver "github.com/estuary/connectors/materialize-sql"
@@ -35,6 +36,16 @@ func TestSQLGeneration(t *testing.T) {
 				templates.createDeleteTable,
 				templates.deleteQuery,
 			},
+			HistoryTableTemplates: []*template.Template{
+				templates.createTargetTable,
+				templates.createStoreTable,
+				templates.loadQuery,
+				templates.historyMerge,
+			},
+			LayoutTableTemplates: []*template.Template{
+				templates.createTargetTable,
+			},
+			Layout: &sql.TableLayout{ClusterBy: []string{"flow_published_at", "key1"}, DistributeBy: "key1"},
 		},
 	)
 
@@ -77,9 +88,11 @@ func TestSQLGeneration(t *testing.T) {
 		},
 		ManifestURL: "s3://some_bucket/files.manifest",
 		Config: &config{
-			AWSAccessKeyID:     "accessKeyID",
-			AWSSecretAccessKey: "secretKey",
-			Region:             "us-somewhere-1",
+			Region: "us-somewhere-1",
+		},
+		Credentials: aws.Credentials{
+			AccessKeyID:     "accessKeyID",
+			SecretAccessKey: "secretKey",
 		},
 		CaseSensitiveIdentifierEnabled: false,
 	}
@@ -93,7 +106,13 @@ func TestSQLGeneration(t *testing.T) {
 
 	snap.WriteString("--- Begin Copy From S3 With Case Sensitive Identifiers and Truncation ---")
 	require.NoError(t, templates.copyFromS3.Execute(snap, copyParams))
-	snap.WriteString("--- End Copy From S3 With Case Sensitive Identifiers and Truncation ---")
+	snap.WriteString("--- End Copy From S3 With Case Sensitive Identifiers and Truncation ---\n\n")
+
+	copyParams.Credentials.SessionToken = "sessionToken"
+
+	snap.WriteString("--- Begin Copy From S3 With Session Token ---")
+	require.NoError(t, templates.copyFromS3.Execute(snap, copyParams))
+	snap.WriteString("--- End Copy From S3 With Session Token ---")
 
 	cupaloy.SnapshotT(t, snap.String())
 }
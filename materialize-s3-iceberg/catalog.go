@@ -3,24 +3,111 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
+var (
+	// errNotFound is returned by catalogs when a table does not exist.
+	errNotFound = errors.New("not found")
+	// errCommitConflict is returned by catalogs when a commit to a table
+	// failed because the table was concurrently modified.
+	errCommitConflict = errors.New("commit conflict")
+)
+
+// icebergCatalog is the set of catalog operations needed by the connector.
+// Namespaces are always a single level.
+type icebergCatalog interface {
+	listNamespaces(ctx context.Context) ([]string, error)
+	createNamespace(ctx context.Context, namespace string) error
+	// loadTable returns an error wrapping errNotFound if the table does not
+	// exist.
+	loadTable(ctx context.Context, namespace, table string) (*loadedTable, error)
+	createTable(ctx context.Context, namespace, table, location string, schema icebergSchema, props map[string]string) error
+	dropTable(ctx context.Context, namespace, table string) error
+	// commitTable atomically applies the updates to the table if all the
+	// requirements are met, and otherwise returns an error wrapping
+	// errCommitConflict.
+	commitTable(ctx context.Context, namespace, table string, base *loadedTable, reqs []tableRequirement, updates []tableUpdate) error
+}
+
+type loadedTable struct {
+	metadata         *tableMetadata
+	metadataLocation string
+	// version is the catalog's version of the table, if it has one.
+	version string
+}
+
 type catalog struct {
 	cfg           *config
 	resourcePaths [][]string
+	catalog       icebergCatalog
+	io            fileIO
 }
 
-func newCatalog(cfg config, resourcePaths [][]string) *catalog {
+func newCatalog(ctx context.Context, cfg config, resourcePaths [][]string) (*catalog, error) {
+	s3store, err := newS3Store(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	fio := &s3FileIO{client: s3store.Client()}
+
+	var ic icebergCatalog
+	switch cfg.Catalog.CatalogType {
+	case catalogTypeRest:
+		if ic, err = newRestCatalog(ctx, cfg.Catalog.URI, cfg.Catalog.Credential, cfg.Catalog.Token, cfg.Catalog.Warehouse); err != nil {
+			return nil, err
+		}
+	case catalogTypeGlue:
+		creds := awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey)
+		awsCfg, err := creds.AWSConfig(ctx, awsConfig.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, fmt.Errorf("creating aws config: %w", err)
+		}
+		ic = newGlueCatalog(cfg.Region, awsCfg.Credentials, fio)
+	default:
+		return nil, fmt.Errorf("unhandled catalog type: %s", cfg.Catalog.CatalogType)
+	}
+
 	return &catalog{
 		cfg:           &cfg,
 		resourcePaths: resourcePaths,
+		catalog:       ic,
+		io:            fio,
+	}, nil
+}
+
+// loadTables loads the tables for each resource path concurrently, with a nil
+// result for tables that don't exist.
+func (c *catalog) loadTables(ctx context.Context, resourcePaths [][]string) ([]*loadedTable, error) {
+	var out = make([]*loadedTable, len(resourcePaths))
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(10)
+	for idx, p := range resourcePaths {
+		group.Go(func() error {
+			t, err := c.catalog.loadTable(groupCtx, p[0], p[1])
+			if errors.Is(err, errNotFound) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			out[idx] = t
+			return nil
+		})
 	}
+
+	return out, group.Wait()
 }
 
 func (c *catalog) infoSchema(ctx context.Context) (*boilerplate.InfoSchema, error) {
@@ -29,37 +116,33 @@ func (c *catalog) infoSchema(ctx context.Context) (*boilerplate.InfoSchema, erro
 		func(f string) string { return f },
 	)
 
-	if len(c.resourcePaths) == 0 {
-		// No bindings so there are no tables that we care about; nothing to do.
-		return is, nil
-	}
-
-	pathsJson, err := json.Marshal(c.resourcePaths)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling paths: %w", err)
-	}
-
-	b, err := runIcebergctl(ctx, c.cfg, "info-schema", string(pathsJson))
+	// For efficiency, only the tables that are included in the list of
+	// resources for the materialization are loaded. For the purposes of
+	// computing apply actions and validation constraints, other tables don't
+	// matter, and there may be a lot of unrelated ones.
+	tables, err := c.loadTables(ctx, c.resourcePaths)
 	if err != nil {
 		return nil, err
 	}
 
-	var got map[string][]existingIcebergColumn
-	if err := json.Unmarshal(b, &got); err != nil {
-		return nil, err
-	}
+	for idx, t := range tables {
+		if t == nil {
+			continue
+		}
 
-	for res, fields := range got {
-		parts := strings.Split(res, ".")
-		namespace := parts[0]
-		table := parts[1]
+		namespace, table := c.resourcePaths[idx][0], c.resourcePaths[idx][1]
 		is.PushResource(namespace, table)
 
-		for _, f := range fields {
+		schema, err := t.metadata.currentSchema()
+		if err != nil {
+			return nil, fmt.Errorf("table %s.%s: %w", namespace, table, err)
+		}
+
+		for _, f := range schema.Fields {
 			is.PushField(boilerplate.EndpointField{
 				Name:     f.Name,
-				Nullable: f.Nullable,
-				Type:     string(f.Type),
+				Nullable: !f.Required,
+				Type:     f.typeString(),
 			}, namespace, table)
 		}
 	}
@@ -70,78 +153,64 @@ func (c *catalog) infoSchema(ctx context.Context) (*boilerplate.InfoSchema, erro
 // Table paths returns the registered storage path for each resource path in a
 // list having the order corresponding to the input list of resource paths.
 func (c *catalog) tablePaths(ctx context.Context, resourcePaths [][]string) ([]string, error) {
-	tableNames := make([]string, 0, len(resourcePaths))
-	for _, p := range resourcePaths {
-		tableNames = append(tableNames, pathToFQN(p))
-	}
-
-	tableNamesJson, err := json.Marshal(tableNames)
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := runIcebergctl(ctx, c.cfg, "table-paths", string(tableNamesJson))
+	tables, err := c.loadTables(ctx, resourcePaths)
 	if err != nil {
 		return nil, err
 	}
 
-	fqnToPath := make(map[string]string)
-	if err := json.Unmarshal(b, &fqnToPath); err != nil {
-		return nil, err
-	}
-
 	out := make([]string, 0, len(resourcePaths))
-	for _, p := range resourcePaths {
-		out = append(out, fqnToPath[pathToFQN(p)])
+	for idx, t := range tables {
+		if t == nil {
+			return nil, fmt.Errorf("table %q does not exist", pathToFQN(resourcePaths[idx]))
+		}
+		out = append(out, t.metadata.Location)
 	}
 
 	return out, nil
 }
 
 func (c *catalog) listNamespaces(ctx context.Context) ([]string, error) {
-	var got []string
-
-	if b, err := runIcebergctl(ctx, c.cfg, "list-namespaces"); err != nil {
-		return nil, err
-	} else if err := json.Unmarshal(b, &got); err != nil {
-		return nil, err
-	}
-
-	return got, nil
+	return c.catalog.listNamespaces(ctx)
 }
 
 func (c *catalog) createNamespace(ctx context.Context, namespace string) error {
-	_, err := runIcebergctl(ctx, c.cfg, "create-namespace", namespace)
-	return err
+	return c.catalog.createNamespace(ctx, namespace)
 }
 
 func (c *catalog) CreateResource(ctx context.Context, spec *pf.MaterializationSpec, bindingIndex int) (string, boilerplate.ActionApplyFn, error) {
 	b := spec.Bindings[bindingIndex]
 
-	tc := tableCreate{Location: tablePath(c.cfg.Bucket, c.cfg.Prefix, b.ResourcePath[0], b.ResourcePath[1])}
+	location := tablePath(c.cfg.Bucket, c.cfg.Prefix, b.ResourcePath[0], b.ResourcePath[1])
 
 	parquetSchema, err := parquetSchema(b.FieldSelection.AllFields(), b.Collection, b.FieldSelection.FieldConfigJsonMap)
 	if err != nil {
 		return "", nil, err
 	}
 
-	for _, f := range parquetSchema {
-		tc.Fields = append(tc.Fields, existingIcebergColumn{
-			Name:     f.Name,
-			Nullable: !f.Required,
-			Type:     parquetTypeToIcebergType(f.DataType),
-		})
+	schema := icebergSchema{Type: "struct", SchemaID: 0}
+	for idx, f := range parquetSchema {
+		schema.Fields = append(schema.Fields, newIcebergField(idx+1, f.Name, f.Required, parquetTypeToIcebergType(f.DataType)))
 	}
 
-	input, err := json.Marshal(tc)
-	if err != nil {
-		return "", nil, err
+	if !b.DeltaUpdates {
+		// Tables with standard updates have at most one current row per key,
+		// which is described by setting the key columns as the identifier
+		// fields. Identifier fields must be required.
+		for idx := range b.FieldSelection.Keys {
+			if !schema.Fields[idx].Required {
+				schema.IdentifierFieldIDs = nil
+				break
+			}
+			schema.IdentifierFieldIDs = append(schema.IdentifierFieldIDs, schema.Fields[idx].ID)
+		}
 	}
 
 	fqn := pathToFQN(b.ResourcePath)
 
 	return fmt.Sprintf("create table %q", fqn), func(ctx context.Context) error {
-		if _, err := runIcebergctl(ctx, c.cfg, "create-table", fqn, string(input)); err != nil {
+		if err := c.catalog.createTable(ctx, b.ResourcePath[0], b.ResourcePath[1], location, schema, map[string]string{
+			nameMappingProperty: schema.nameMapping(),
+		}); err != nil {
 			return fmt.Errorf("creating table %q: %w", fqn, err)
 		}
 
@@ -153,7 +222,7 @@ func (c *catalog) DeleteResource(ctx context.Context, path []string) (string, bo
 	fqn := pathToFQN(path)
 
 	return fmt.Sprintf("drop table %q", fqn), func(ctx context.Context) error {
-		if _, err := runIcebergctl(ctx, c.cfg, "drop-table", fqn); err != nil {
+		if err := c.catalog.dropTable(ctx, path[0], path[1]); err != nil {
 			return fmt.Errorf("dropping table %q: %w", fqn, err)
 		}
 
@@ -170,34 +239,26 @@ func (c *catalog) UpdateResource(_ context.Context, spec *pf.MaterializationSpec
 
 	b := spec.Bindings[bindingIndex]
 
-	ta := tableAlter{}
-
-	for _, f := range bindingUpdate.NewlyNullableFields {
-		ta.NewlyNullableColumns = append(ta.NewlyNullableColumns, f.Name)
-	}
-
+	var newColumns []icebergField
 	for _, p := range bindingUpdate.NewProjections {
 		s, err := projectionToParquetSchemaElement(p, b.FieldSelection.FieldConfigJsonMap[p.Field])
 		if err != nil {
 			return "", nil, err
 		}
 
-		ta.NewColumns = append(ta.NewColumns, existingIcebergColumn{
-			Name:     s.Name,
-			Nullable: true, // always true for added columns
-			Type:     parquetTypeToIcebergType(s.DataType),
-		})
+		// Field IDs are assigned when the update is applied.
+		newColumns = append(newColumns, newIcebergField(0, s.Name, false, parquetTypeToIcebergType(s.DataType)))
 	}
 
-	input, err := json.Marshal(ta)
-	if err != nil {
-		return "", nil, err
+	var newlyNullable = make(map[string]bool)
+	for _, f := range bindingUpdate.NewlyNullableFields {
+		newlyNullable[f.Name] = true
 	}
 
 	fqn := pathToFQN(b.ResourcePath)
 
 	return fmt.Sprintf("alter table %q", fqn), func(ctx context.Context) error {
-		if _, err := runIcebergctl(ctx, c.cfg, "alter-table", fqn, string(input)); err != nil {
+		if err := c.alterTable(ctx, b.ResourcePath, newColumns, newlyNullable); err != nil {
 			return fmt.Errorf("altering table %q: %w", fqn, err)
 		}
 
@@ -205,36 +266,218 @@ func (c *catalog) UpdateResource(_ context.Context, spec *pf.MaterializationSpec
 	}, nil
 }
 
+// alterTable adds a new schema to the table with the new columns added and
+// the newly nullable columns made optional, and makes it the current schema.
+func (c *catalog) alterTable(ctx context.Context, tablePath []string, newColumns []icebergField, newlyNullable map[string]bool) error {
+	t, err := c.catalog.loadTable(ctx, tablePath[0], tablePath[1])
+	if err != nil {
+		return err
+	}
+
+	current, err := t.metadata.currentSchema()
+	if err != nil {
+		return err
+	}
+
+	var next = icebergSchema{
+		Type:               "struct",
+		IdentifierFieldIDs: current.IdentifierFieldIDs,
+	}
+	for _, s := range t.metadata.Schemas {
+		next.SchemaID = max(next.SchemaID, s.SchemaID+1)
+	}
+	for _, f := range current.Fields {
+		if newlyNullable[f.Name] {
+			f.Required = false
+		}
+		next.Fields = append(next.Fields, f)
+	}
+	var lastColumnID = t.metadata.LastColumnID
+	for _, f := range newColumns {
+		lastColumnID++
+		f.ID = lastColumnID
+		next.Fields = append(next.Fields, f)
+	}
+
+	var updates = []tableUpdate{
+		addSchemaUpdate(next, lastColumnID),
+		setCurrentSchemaUpdate(-1),
+	}
+	if _, ok := t.metadata.Properties[nameMappingProperty]; ok || len(newColumns) > 0 {
+		updates = append(updates, setPropertiesUpdate(map[string]string{
+			nameMappingProperty: next.nameMapping(),
+		}))
+	}
+
+	return c.catalog.commitTable(ctx, tablePath[0], tablePath[1], t, []tableRequirement{
+		assertTableUUID(t.metadata.TableUUID),
+		assertCurrentSchemaID(current.SchemaID),
+		assertLastAssignedFieldID(t.metadata.LastColumnID),
+	}, updates)
+}
+
+// appendFiles appends data files at filePaths to the table, along with
+// equality delete files at deleteFilePaths which delete prior rows having the
+// same key columns as the rows of the data files.
+//
+// The prevCheckpoint and nextCheckpoint arguments are used to avoid
+// duplicating data from appending the same files that have previously been
+// appended. A possible scenario is this: Files are successfully appended to
+// Table1 and Table2 but not Table3 in response to the connector receiving a
+// StartCommit message, but the connector is restarted before the transaction
+// is fully completed and acknowledged. Upon restart, a re-application of the
+// persisted driver checkpoint is attempted (ref: "Recovery Log with Idempotent
+// Apply" pattern). Table1 and Table2 should not have the same files appended
+// again, but Table3 does still need to have the files appended.
+//
+// When a table is updated to append files, its checkpoint property for the
+// materialization is updated to nextCheckpoint. The previously described
+// scenario would then play out like this:
+//
+//  1. The materialization connector persists values for prevCheckpoint and
+//     nextCheckpoint of "0001" and "0002", respectively, in its driver
+//     checkpoint via StartedCommit.
+//
+//  2. During the partial completion of the transaction, Table1 and Table2 are
+//     updated to have a checkpoint property of "0002" atomically with
+//     appending files to them.
+//
+//  3. The re-application of the checkpoint on connector restart sees that
+//     Table1 and Table2 already have a checkpoint of "0002" and so the files
+//     are not appended to them again. Table3 is still at "0001" and so files
+//     are appended.
+//
+// The commit requires that the table's current snapshot is still the one that
+// the checkpoint property was read from, so a concurrent append of the same
+// files by a zombie process will cause one of the commits to fail and be
+// retried, at which point it will see the updated checkpoint.
 func (c *catalog) appendFiles(
 	ctx context.Context,
 	materialization string,
 	tablePath []string,
+	keyColumns []string,
 	filePaths []string,
+	deleteFilePaths []string,
 	prevCheckpoint string,
 	nextCheckpoint string,
 ) error {
 	fqn := pathToFQN(tablePath)
 
-	b, err := runIcebergctl(
-		ctx,
-		c.cfg,
-		"append-files",
-		materialization,
-		fqn,
-		prevCheckpoint,
-		nextCheckpoint,
-		strings.Join(filePaths, ","),
-	)
-	if err != nil {
-		return err
-	}
+	var files, deleteFiles []dataFile
+	for attempt := 1; ; attempt++ {
+		t, err := c.catalog.loadTable(ctx, tablePath[0], tablePath[1])
+		if err != nil {
+			return err
+		}
+
+		var checkpoints = make(map[string]string)
+		if cp, ok := t.metadata.Properties[checkpointsProperty]; ok {
+			if err := json.Unmarshal([]byte(cp), &checkpoints); err != nil {
+				return fmt.Errorf("parsing %s property of table %q: %w", checkpointsProperty, fqn, err)
+			}
+		}
+
+		// The table checkpoint will be unset if this is the first commit to
+		// the table.
+		var cp = checkpoints[materialization]
+		if cp == nextCheckpoint {
+			log.WithFields(log.Fields{
+				"table":      fqn,
+				"checkpoint": nextCheckpoint,
+			}).Info("table checkpoint is already current; not appending files again")
+			return nil
+		}
+		// TODO(whb): Re-enable a sanity check that cp is either unset or equal
+		// to prevCheckpoint after any tasks effected by the disabled bindings
+		// state tracking bug have moved past it. An absent checkpoint table
+		// property would still be allowed to accommodate cases where the user
+		// may have manually dropped the table and the materialization
+		// automatically re-created it, outside the normal backfill counter
+		// increment process.
+
+		checkpoints[materialization] = nextCheckpoint
+		checkpointsJSON, err := json.Marshal(checkpoints)
+		if err != nil {
+			return err
+		}
+
+		schema, err := t.metadata.currentSchema()
+		if err != nil {
+			return err
+		}
+
+		var keyFieldIDs []int
+		for _, k := range keyColumns {
+			idx := slices.IndexFunc(schema.Fields, func(f icebergField) bool { return f.Name == k })
+			if idx == -1 {
+				return fmt.Errorf("table %q has no key column %q", fqn, k)
+			}
+			keyFieldIDs = append(keyFieldIDs, schema.Fields[idx].ID)
+		}
+
+		if attempt == 1 {
+			// The bounds of the first key column of the files are included in
+			// their manifest entries, so that files which can't contain the
+			// keys being loaded are skipped without reading them.
+			var bounds *columnBounds
+			if len(keyColumns) > 0 {
+				bounds = &columnBounds{column: keyColumns[0], fieldID: keyFieldIDs[0]}
+			}
+			if files, err = statDataFiles(ctx, c.io, filePaths, bounds); err != nil {
+				return fmt.Errorf("reading data files: %w", err)
+			} else if deleteFiles, err = statDataFiles(ctx, c.io, deleteFilePaths, bounds); err != nil {
+				return fmt.Errorf("reading delete files: %w", err)
+			}
+		}
+
+		// Equality delete files have the key columns of the table.
+		for idx := range deleteFiles {
+			deleteFiles[idx].content = fileContentEqualityDelete
+			deleteFiles[idx].equalityIDs = keyFieldIDs
+		}
+
+		snap, err := newSnapshot(ctx, c.io, t.metadata, files, deleteFiles)
+		if err != nil {
+			return fmt.Errorf("creating snapshot for table %q: %w", fqn, err)
+		}
+
+		var parentID *int64
+		if parent := t.metadata.currentSnapshot(); parent != nil {
+			parentID = &parent.SnapshotID
+		}
+
+		if err := c.catalog.commitTable(ctx, tablePath[0], tablePath[1], t, []tableRequirement{
+			assertTableUUID(t.metadata.TableUUID),
+			assertRefSnapshotID(mainBranch, parentID),
+		}, []tableUpdate{
+			addSnapshotUpdate(*snap),
+			setBranchUpdate(mainBranch, snap.SnapshotID),
+			setPropertiesUpdate(map[string]string{checkpointsProperty: string(checkpointsJSON)}),
+		}); errors.Is(err, errCommitConflict) && attempt < 3 {
+			log.WithFields(log.Fields{
+				"table":   fqn,
+				"attempt": attempt,
+				"error":   err,
+			}).Warn("table was modified concurrently, retrying append")
+			time.Sleep(time.Duration(attempt*2) * time.Second)
+			continue
+		} else if err != nil {
+			return err
+		}
 
-	if len(b) > 0 {
 		log.WithFields(log.Fields{
-			"table":  fqn,
-			"output": string(b),
-		}).Info("append files")
+			"table":              fqn,
+			"previousCheckpoint": cp,
+			"checkpoint":         nextCheckpoint,
+			"files":              len(files),
+			"deleteFiles":        len(deleteFiles),
+			"attempts":           attempt,
+		}).Info("appended files to table")
+
+		return nil
 	}
+}
 
-	return nil
+func trimSlash(s string) string {
+	return strings.TrimSuffix(s, "/")
 }
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/google/uuid"
+)
+
+// glueCatalog uses AWS Glue as an Iceberg catalog. Glue only tracks the
+// location of the current metadata file for each table, so table metadata
+// files are written by the connector itself. Tables are updated using Glue's
+// optimistic locking of table versions.
+//
+// Requests are made directly to the Glue JSON API. See
+// https://docs.aws.amazon.com/glue/latest/webapi/API_Operations.html.
+type glueCatalog struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	creds    aws.CredentialsProvider
+	signer   *v4.Signer
+	io       fileIO
+}
+
+type glueError struct {
+	Type    string
+	Message string
+}
+
+func (e *glueError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+func newGlueCatalog(region string, creds aws.CredentialsProvider, io fileIO) *glueCatalog {
+	return &glueCatalog{
+		client:   http.DefaultClient,
+		endpoint: fmt.Sprintf("https://glue.%s.amazonaws.com/", region),
+		region:   region,
+		creds:    creds,
+		signer:   v4.NewSigner(),
+		io:       io,
+	}
+}
+
+func (c *glueCatalog) do(ctx context.Context, op string, input any, out any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("encoding %s request: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSGlue."+op)
+
+	creds, err := c.creds.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving AWS credentials: %w", err)
+	}
+
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "glue", c.region, time.Now()); err != nil {
+		return fmt.Errorf("signing %s request: %w", op, err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading %s response: %w", op, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var errRes struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(resBody, &errRes)
+		if errRes.Type == "" {
+			errRes.Type = res.Status
+		}
+		// The type may be qualified, like "com.amazonaws.glue#EntityNotFoundException".
+		if _, t, ok := strings.Cut(errRes.Type, "#"); ok {
+			errRes.Type = t
+		}
+		return &glueError{Type: errRes.Type, Message: errRes.Message}
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(resBody, out); err != nil {
+			return fmt.Errorf("decoding %s response: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func isGlueError(err error, typ string) bool {
+	glueErr, ok := err.(*glueError)
+	return ok && glueErr.Type == typ
+}
+
+func (c *glueCatalog) listNamespaces(ctx context.Context) ([]string, error) {
+	var out []string
+	var nextToken string
+
+	for {
+		var input = map[string]any{}
+		if nextToken != "" {
+			input["NextToken"] = nextToken
+		}
+
+		var res struct {
+			DatabaseList []struct {
+				Name string
+			}
+			NextToken string
+		}
+		if err := c.do(ctx, "GetDatabases", input, &res); err != nil {
+			return nil, fmt.Errorf("listing databases: %w", err)
+		}
+
+		for _, db := range res.DatabaseList {
+			out = append(out, db.Name)
+		}
+
+		if nextToken = res.NextToken; nextToken == "" {
+			return out, nil
+		}
+	}
+}
+
+func (c *glueCatalog) createNamespace(ctx context.Context, namespace string) error {
+	if err := c.do(ctx, "CreateDatabase", map[string]any{
+		"DatabaseInput": map[string]any{"Name": namespace},
+	}, nil); err != nil {
+		return fmt.Errorf("creating database %q: %w", namespace, err)
+	}
+	return nil
+}
+
+// glueTable is the subset of a Glue table that is retained when it is
+// updated.
+type glueTable struct {
+	Name              string
+	Description       string            `json:",omitempty"`
+	Owner             string            `json:",omitempty"`
+	TableType         string            `json:",omitempty"`
+	Parameters        map[string]string `json:",omitempty"`
+	StorageDescriptor map[string]any    `json:",omitempty"`
+	PartitionKeys     json.RawMessage   `json:",omitempty"`
+	Retention         int               `json:",omitempty"`
+}
+
+func (c *glueCatalog) getTable(ctx context.Context, namespace, table string) (*glueTable, string, error) {
+	var res struct {
+		Table struct {
+			glueTable
+			VersionId string
+		}
+	}
+	if err := c.do(ctx, "GetTable", map[string]any{
+		"DatabaseName": namespace,
+		"Name":         table,
+	}, &res); err != nil {
+		if isGlueError(err, "EntityNotFoundException") {
+			err = errNotFound
+		}
+		return nil, "", fmt.Errorf("getting table %s.%s: %w", namespace, table, err)
+	}
+
+	return &res.Table.glueTable, res.Table.VersionId, nil
+}
+
+func (c *glueCatalog) loadTable(ctx context.Context, namespace, table string) (*loadedTable, error) {
+	t, version, err := c.getTable(ctx, namespace, table)
+	if err != nil {
+		return nil, err
+	}
+
+	location, ok := t.Parameters["metadata_location"]
+	if !ok || !strings.EqualFold(t.Parameters["table_type"], "ICEBERG") {
+		return nil, fmt.Errorf("table %s.%s is not an Iceberg table", namespace, table)
+	}
+
+	b, err := c.io.get(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata for table %s.%s: %w", namespace, table, err)
+	}
+
+	var meta tableMetadata
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("decoding metadata for table %s.%s: %w", namespace, table, err)
+	}
+
+	return &loadedTable{metadata: &meta, metadataLocation: location, version: version}, nil
+}
+
+var metadataVersionRe = regexp.MustCompile(`/(\d+)-[^/]*\.metadata\.json$`)
+
+// writeMetadata writes a new metadata file for the table, following the
+// naming convention of other Iceberg clients.
+func (c *glueCatalog) writeMetadata(ctx context.Context, meta *tableMetadata, prevLocation string) (string, error) {
+	var version = 0
+	if m := metadataVersionRe.FindStringSubmatch(prevLocation); m != nil {
+		prev, _ := strconv.Atoi(m[1])
+		version = prev + 1
+	}
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("encoding table metadata: %w", err)
+	}
+
+	location := fmt.Sprintf("%s/%05d-%s.metadata.json", meta.metadataDir(), version, uuid.NewString())
+	if err := c.io.put(ctx, location, b); err != nil {
+		return "", fmt.Errorf("writing table metadata: %w", err)
+	}
+
+	return location, nil
+}
+
+// glueColumns returns the Glue columns for the schema, which are informational
+// only but allow the table to be inspected in the Glue console.
+func glueColumns(schema icebergSchema) []map[string]any {
+	var out = make([]map[string]any, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		out = append(out, map[string]any{
+			"Name": f.Name,
+			"Type": glueColumnType(f.typeString()),
+			"Parameters": map[string]string{
+				"iceberg.field.id":       strconv.Itoa(f.ID),
+				"iceberg.field.optional": strconv.FormatBool(!f.Required),
+				"iceberg.field.current":  "true",
+			},
+		})
+	}
+	return out
+}
+
+func glueColumnType(t string) string {
+	switch icebergType(t) {
+	case icebergTypeLong:
+		return "bigint"
+	case icebergTypeTimestamptz:
+		return "timestamp"
+	case icebergTypeUuid:
+		return "string"
+	default:
+		return t
+	}
+}
+
+func (c *glueCatalog) createTable(ctx context.Context, namespace, table, location string, schema icebergSchema, props map[string]string) error {
+	meta := newTableMetadata(trimSlash(location), schema, props)
+
+	metadataLocation, err := c.writeMetadata(ctx, meta, "")
+	if err != nil {
+		return fmt.Errorf("creating table %s.%s: %w", namespace, table, err)
+	}
+
+	if err := c.do(ctx, "CreateTable", map[string]any{
+		"DatabaseName": namespace,
+		"TableInput": glueTable{
+			Name:      table,
+			TableType: "EXTERNAL_TABLE",
+			Parameters: map[string]string{
+				"table_type":        "ICEBERG",
+				"metadata_location": metadataLocation,
+			},
+			StorageDescriptor: map[string]any{
+				"Location": meta.Location,
+				"Columns":  glueColumns(schema),
+			},
+		},
+	}, nil); err != nil {
+		return fmt.Errorf("creating table %s.%s: %w", namespace, table, err)
+	}
+
+	return nil
+}
+
+func (c *glueCatalog) dropTable(ctx context.Context, namespace, table string) error {
+	if err := c.do(ctx, "DeleteTable", map[string]any{
+		"DatabaseName": namespace,
+		"Name":         table,
+	}, nil); err != nil {
+		return fmt.Errorf("dropping table %s.%s: %w", namespace, table, err)
+	}
+	return nil
+}
+
+func (c *glueCatalog) commitTable(ctx context.Context, namespace, table string, base *loadedTable, reqs []tableRequirement, updates []tableUpdate) error {
+	meta, err := applyUpdates(base.metadata, reqs, updates)
+	if err != nil {
+		return fmt.Errorf("committing table %s.%s: %w", namespace, table, err)
+	}
+
+	meta.MetadataLog = append(meta.MetadataLog, metadataLogEntry{
+		MetadataFile: base.metadataLocation,
+		TimestampMs:  base.metadata.LastUpdatedMs,
+	})
+	var maxPrevious = 100
+	if v, err := strconv.Atoi(meta.Properties[metadataPreviousMaxProperty]); err == nil {
+		maxPrevious = v
+	}
+	if len(meta.MetadataLog) > maxPrevious {
+		meta.MetadataLog = meta.MetadataLog[len(meta.MetadataLog)-maxPrevious:]
+	}
+
+	metadataLocation, err := c.writeMetadata(ctx, meta, base.metadataLocation)
+	if err != nil {
+		return fmt.Errorf("committing table %s.%s: %w", namespace, table, err)
+	}
+
+	// The Glue table is re-read, since it isn't retained by loadTable. Its
+	// version must still match that of the loaded metadata.
+	t, version, err := c.getTable(ctx, namespace, table)
+	if err != nil {
+		return fmt.Errorf("committing table %s.%s: %w", namespace, table, err)
+	} else if version != base.version {
+		return fmt.Errorf("committing table %s.%s: %w: table version is %s instead of %s", namespace, table, errCommitConflict, version, base.version)
+	}
+
+	schema, err := meta.currentSchema()
+	if err != nil {
+		return err
+	}
+
+	if t.Parameters == nil {
+		t.Parameters = make(map[string]string)
+	}
+	t.Parameters["metadata_location"] = metadataLocation
+	t.Parameters["previous_metadata_location"] = base.metadataLocation
+	if t.StorageDescriptor == nil {
+		t.StorageDescriptor = make(map[string]any)
+	}
+	t.StorageDescriptor["Columns"] = glueColumns(schema)
+
+	if err := c.do(ctx, "UpdateTable", map[string]any{
+		"DatabaseName": namespace,
+		"TableInput":   t,
+		"VersionId":    base.version,
+	}, nil); err != nil {
+		if isGlueError(err, "ConcurrentModificationException") {
+			err = fmt.Errorf("%w: %w", errCommitConflict, err)
+		}
+		return fmt.Errorf("committing table %s.%s: %w", namespace, table, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// restCatalog is a client for the Iceberg REST catalog API. See
+// https://github.com/apache/iceberg/blob/main/open-api/rest-catalog-open-api.yaml.
+type restCatalog struct {
+	client  *http.Client
+	baseURL string // includes the catalog prefix, if there is one
+	token   string
+}
+
+type restError struct {
+	Code    int    `json:"code"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *restError) Error() string {
+	return fmt.Sprintf("%s (%d): %s", e.Type, e.Code, e.Message)
+}
+
+func newRestCatalog(ctx context.Context, uri, credential, token, warehouse string) (*restCatalog, error) {
+	c := &restCatalog{
+		client:  http.DefaultClient,
+		baseURL: trimSlash(uri) + "/v1",
+		token:   token,
+	}
+
+	if credential != "" {
+		// Exchange the credential for a token using the OAuth2 client
+		// credentials flow. The credential is either "client_id:secret" or
+		// just a secret.
+		form := url.Values{
+			"grant_type": {"client_credentials"},
+			"scope":      {"catalog"},
+		}
+		if id, secret, ok := strings.Cut(credential, ":"); ok {
+			form.Set("client_id", id)
+			form.Set("client_secret", secret)
+		} else {
+			form.Set("client_secret", credential)
+		}
+
+		var res struct {
+			AccessToken string `json:"access_token"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/oauth/tokens", form, &res); err != nil {
+			return nil, fmt.Errorf("fetching catalog token: %w", err)
+		}
+		c.token = res.AccessToken
+	}
+
+	var cfg struct {
+		Defaults  map[string]string `json:"defaults"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/config?warehouse="+url.QueryEscape(warehouse), nil, &cfg); err != nil {
+		return nil, fmt.Errorf("fetching catalog config: %w", err)
+	}
+
+	var props = cfg.Defaults
+	if props == nil {
+		props = make(map[string]string)
+	}
+	for k, v := range cfg.Overrides {
+		props[k] = v
+	}
+
+	if u, ok := props["uri"]; ok && u != "" {
+		c.baseURL = trimSlash(u) + "/v1"
+	}
+	if p, ok := props["prefix"]; ok && p != "" {
+		c.baseURL += "/" + strings.Trim(p, "/")
+	}
+
+	return c, nil
+}
+
+// do sends a request to the catalog, where the body is sent as a form if it
+// is url.Values and as JSON otherwise. The response is decoded into out if it
+// is not nil.
+func (c *restCatalog) do(ctx context.Context, method, path string, body any, out any) error {
+	var reqBody io.Reader
+	var contentType string
+	if form, ok := body.(url.Values); ok {
+		reqBody = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var errRes struct {
+			Error restError `json:"error"`
+		}
+		if err := json.Unmarshal(resBody, &errRes); err != nil || errRes.Error.Code == 0 {
+			errRes.Error = restError{Code: res.StatusCode, Type: res.Status, Message: string(resBody)}
+		}
+		return &errRes.Error
+	}
+
+	if out != nil && len(resBody) > 0 {
+		if err := json.Unmarshal(resBody, out); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func tableURLPath(namespace, table string) string {
+	return "/namespaces/" + url.PathEscape(namespace) + "/tables/" + url.PathEscape(table)
+}
+
+func (c *restCatalog) listNamespaces(ctx context.Context) ([]string, error) {
+	var out []string
+	var pageToken string
+
+	for {
+		var path = "/namespaces"
+		if pageToken != "" {
+			path += "?pageToken=" + url.QueryEscape(pageToken)
+		}
+
+		var res struct {
+			Namespaces    [][]string `json:"namespaces"`
+			NextPageToken string     `json:"next-page-token"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+			return nil, fmt.Errorf("listing namespaces: %w", err)
+		}
+
+		for _, ns := range res.Namespaces {
+			out = append(out, strings.Join(ns, "."))
+		}
+
+		if pageToken = res.NextPageToken; pageToken == "" {
+			return out, nil
+		}
+	}
+}
+
+func (c *restCatalog) createNamespace(ctx context.Context, namespace string) error {
+	if err := c.do(ctx, http.MethodPost, "/namespaces", map[string]any{
+		"namespace":  []string{namespace},
+		"properties": map[string]string{},
+	}, nil); err != nil {
+		return fmt.Errorf("creating namespace %q: %w", namespace, err)
+	}
+	return nil
+}
+
+type restLoadTableResult struct {
+	MetadataLocation string         `json:"metadata-location"`
+	Metadata         *tableMetadata `json:"metadata"`
+}
+
+func (c *restCatalog) loadTable(ctx context.Context, namespace, table string) (*loadedTable, error) {
+	var res restLoadTableResult
+	if err := c.do(ctx, http.MethodGet, tableURLPath(namespace, table), nil, &res); err != nil {
+		if restErr, ok := err.(*restError); ok && restErr.Code == http.StatusNotFound {
+			return nil, fmt.Errorf("loading table %s.%s: %w", namespace, table, errNotFound)
+		}
+		return nil, fmt.Errorf("loading table %s.%s: %w", namespace, table, err)
+	}
+
+	return &loadedTable{metadata: res.Metadata, metadataLocation: res.MetadataLocation}, nil
+}
+
+func (c *restCatalog) createTable(ctx context.Context, namespace, table, location string, schema icebergSchema, props map[string]string) error {
+	if err := c.do(ctx, http.MethodPost, "/namespaces/"+url.PathEscape(namespace)+"/tables", map[string]any{
+		"name":       table,
+		"location":   location,
+		"schema":     schema,
+		"properties": props,
+	}, nil); err != nil {
+		return fmt.Errorf("creating table %s.%s: %w", namespace, table, err)
+	}
+	return nil
+}
+
+func (c *restCatalog) dropTable(ctx context.Context, namespace, table string) error {
+	if err := c.do(ctx, http.MethodDelete, tableURLPath(namespace, table), nil, nil); err != nil {
+		return fmt.Errorf("dropping table %s.%s: %w", namespace, table, err)
+	}
+	return nil
+}
+
+func (c *restCatalog) commitTable(ctx context.Context, namespace, table string, _ *loadedTable, reqs []tableRequirement, updates []tableUpdate) error {
+	if err := c.do(ctx, http.MethodPost, tableURLPath(namespace, table), map[string]any{
+		"requirements": reqs,
+		"updates":      updates,
+	}, nil); err != nil {
+		if restErr, ok := err.(*restError); ok && restErr.Code == http.StatusConflict {
+			err = fmt.Errorf("%w: %w", errCommitConflict, err)
+		}
+		return fmt.Errorf("committing table %s.%s: %w", namespace, table, err)
+	}
+	return nil
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	enc "github.com/estuary/connectors/materialize-boilerplate/stream-encode"
+	"github.com/stretchr/testify/require"
+)
+
+// memFileIO is an in-memory fileIO.
+type memFileIO struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newMemFileIO() *memFileIO {
+	return &memFileIO{files: make(map[string][]byte)}
+}
+
+func (m *memFileIO) get(_ context.Context, uri string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.files[uri]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", uri, errNotFound)
+	}
+	return b, nil
+}
+
+func (m *memFileIO) put(_ context.Context, uri string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[uri] = data
+	return nil
+}
+
+func (m *memFileIO) tail(ctx context.Context, uri string, n int64) (int64, []byte, error) {
+	b, err := m.get(ctx, uri)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int64(len(b)), b[max(0, int64(len(b))-n):], nil
+}
+
+func (m *memFileIO) readRange(ctx context.Context, uri string, offset, n int64) ([]byte, error) {
+	b, err := m.get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return b[offset:min(offset+n, int64(len(b)))], nil
+}
+
+// restCatalogStandIn is a minimal in-memory implementation of the Iceberg
+// REST catalog API, which applies commits the same way as the Glue catalog
+// does.
+type restCatalogStandIn struct {
+	mu         sync.Mutex
+	namespaces []string
+	tables     map[string]*tableMetadata
+	commits    int
+}
+
+func newRestCatalogStandIn(t *testing.T) (*restCatalogStandIn, *httptest.Server) {
+	t.Helper()
+
+	s := &restCatalogStandIn{tables: make(map[string]*tableMetadata)}
+
+	writeJSON := func(w http.ResponseWriter, code int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		require.NoError(t, json.NewEncoder(w).Encode(v))
+	}
+	writeErr := func(w http.ResponseWriter, code int, msg string) {
+		writeJSON(w, code, map[string]any{"error": restError{Code: code, Type: http.StatusText(code), Message: msg}})
+	}
+	tableKey := func(r *http.Request) string {
+		return r.PathValue("namespace") + "." + r.PathValue("table")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/config", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "test_warehouse", r.URL.Query().Get("warehouse"))
+		require.Equal(t, "Bearer some_token", r.Header.Get("Authorization"))
+		writeJSON(w, http.StatusOK, map[string]any{
+			"defaults":  map[string]string{},
+			"overrides": map[string]string{"prefix": "test_prefix"},
+		})
+	})
+	mux.HandleFunc("GET /v1/test_prefix/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var out = [][]string{}
+		for _, ns := range s.namespaces {
+			out = append(out, []string{ns})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"namespaces": out})
+	})
+	mux.HandleFunc("POST /v1/test_prefix/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var req struct {
+			Namespace []string `json:"namespace"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		s.namespaces = append(s.namespaces, req.Namespace[0])
+		writeJSON(w, http.StatusOK, map[string]any{"namespace": req.Namespace})
+	})
+	mux.HandleFunc("POST /v1/test_prefix/namespaces/{namespace}/tables", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var req struct {
+			Name       string            `json:"name"`
+			Location   string            `json:"location"`
+			Schema     icebergSchema     `json:"schema"`
+			Properties map[string]string `json:"properties"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		key := r.PathValue("namespace") + "." + req.Name
+		if _, ok := s.tables[key]; ok {
+			writeErr(w, http.StatusConflict, "table already exists")
+			return
+		}
+		s.tables[key] = newTableMetadata(trimSlash(req.Location), req.Schema, req.Properties)
+		writeJSON(w, http.StatusOK, restLoadTableResult{Metadata: s.tables[key]})
+	})
+	mux.HandleFunc("GET /v1/test_prefix/namespaces/{namespace}/tables/{table}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		m, ok := s.tables[tableKey(r)]
+		if !ok {
+			writeErr(w, http.StatusNotFound, "table does not exist")
+			return
+		}
+		writeJSON(w, http.StatusOK, restLoadTableResult{
+			MetadataLocation: m.metadataDir() + "/" + strconv.Itoa(s.commits) + ".metadata.json",
+			Metadata:         m,
+		})
+	})
+	mux.HandleFunc("POST /v1/test_prefix/namespaces/{namespace}/tables/{table}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		m, ok := s.tables[tableKey(r)]
+		if !ok {
+			writeErr(w, http.StatusNotFound, "table does not exist")
+			return
+		}
+
+		var req struct {
+			Requirements []tableRequirement `json:"requirements"`
+			Updates      []tableUpdate      `json:"updates"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		next, err := applyUpdates(m, req.Requirements, req.Updates)
+		if err != nil {
+			writeErr(w, http.StatusConflict, err.Error())
+			return
+		}
+		s.tables[tableKey(r)] = next
+		s.commits++
+		writeJSON(w, http.StatusOK, restLoadTableResult{Metadata: next})
+	})
+	mux.HandleFunc("DELETE /v1/test_prefix/namespaces/{namespace}/tables/{table}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.tables[tableKey(r)]; !ok {
+			writeErr(w, http.StatusNotFound, "table does not exist")
+			return
+		}
+		delete(s.tables, tableKey(r))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return s, server
+}
+
+func testRestCatalog(t *testing.T) (*catalog, *restCatalogStandIn, *memFileIO) {
+	t.Helper()
+	ctx := context.Background()
+
+	standIn, server := newRestCatalogStandIn(t)
+
+	rc, err := newRestCatalog(ctx, server.URL, "", "some_token", "test_warehouse")
+	require.NoError(t, err)
+
+	cfg := config{Bucket: "test-bucket", Namespace: "test_namespace"}
+	fio := newMemFileIO()
+
+	return &catalog{
+		cfg:           &cfg,
+		resourcePaths: [][]string{{"test_namespace", "test_table"}},
+		catalog:       rc,
+		io:            fio,
+	}, standIn, fio
+}
+
+// putParquetFile writes a parquet file with the given number of rows in the
+// same way that the transactor does.
+func putParquetFile(t *testing.T, fio fileIO, uri string, rows int) {
+	t.Helper()
+
+	var data [][]any
+	for idx := 0; idx < rows; idx++ {
+		data = append(data, []any{int64(idx), fmt.Sprintf("value %d", idx)})
+	}
+
+	putParquetRows(t, fio, uri, enc.ParquetSchema{
+		{Name: "key", DataType: enc.PrimitiveTypeInteger, Required: true},
+		{Name: "value", DataType: enc.LogicalTypeString},
+	}, data)
+}
+
+func putParquetRows(t *testing.T, fio fileIO, uri string, schema enc.ParquetSchema, rows [][]any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	encoder := enc.NewParquetEncoder(&nopWriteCloser{&buf}, schema, enc.WithParquetCompression(enc.Snappy))
+	for _, row := range rows {
+		require.NoError(t, encoder.Encode(row))
+	}
+	require.NoError(t, encoder.Close())
+
+	require.NoError(t, fio.put(context.Background(), uri, buf.Bytes()))
+}
+
+func TestRestCatalog(t *testing.T) {
+	ctx := context.Background()
+	c, standIn, fio := testRestCatalog(t)
+
+	// Namespaces.
+	require.NoError(t, c.createNamespace(ctx, "test_namespace"))
+	namespaces, err := c.listNamespaces(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test_namespace"}, namespaces)
+
+	// Tables that don't exist are not included in the info schema.
+	is, err := c.infoSchema(ctx)
+	require.NoError(t, err)
+	require.False(t, is.HasResource([]string{"test_namespace", "test_table"}))
+	_, err = c.tablePaths(ctx, c.resourcePaths)
+	require.ErrorContains(t, err, "does not exist")
+
+	// Table creation.
+	location := tablePath(c.cfg.Bucket, c.cfg.Prefix, "test_namespace", "test_table")
+	schema := icebergSchema{Type: "struct", Fields: []icebergField{
+		newIcebergField(1, "key", true, icebergTypeLong),
+		newIcebergField(2, "value", false, icebergTypeString),
+	}}
+	require.NoError(t, c.catalog.createTable(ctx, "test_namespace", "test_table", location, schema, map[string]string{
+		nameMappingProperty: schema.nameMapping(),
+	}))
+
+	tablePaths, err := c.tablePaths(ctx, c.resourcePaths)
+	require.NoError(t, err)
+	require.Equal(t, []string{trimSlash(location)}, tablePaths)
+
+	// Schema evolution.
+	require.NoError(t, c.alterTable(ctx, []string{"test_namespace", "test_table"},
+		[]icebergField{newIcebergField(0, "added", false, icebergTypeDouble)},
+		map[string]bool{"key": true},
+	))
+
+	is, err = c.infoSchema(ctx)
+	require.NoError(t, err)
+	fields, err := is.FieldsForResource([]string{"test_namespace", "test_table"})
+	require.NoError(t, err)
+	require.Len(t, fields, 3)
+	require.Equal(t, "added", fields[2].Name)
+	require.Equal(t, "double", fields[2].Type)
+	require.True(t, fields[0].Nullable)
+
+	m := standIn.tables["test_namespace.test_table"]
+	require.Equal(t, 1, m.CurrentSchemaID)
+	require.Equal(t, 3, m.LastColumnID)
+	require.Len(t, m.Schemas, 2)
+	require.Equal(t,
+		`[{"field-id":1,"names":["key"]},{"field-id":2,"names":["value"]},{"field-id":3,"names":["added"]}]`,
+		m.Properties[nameMappingProperty],
+	)
+
+	// Appending files.
+	file1 := tablePaths[0] + "/data/1.parquet"
+	file2 := tablePaths[0] + "/data/2.parquet"
+	file3 := tablePaths[0] + "/data/3.parquet"
+	putParquetFile(t, fio, file1, 3)
+	putParquetFile(t, fio, file2, 5)
+	putParquetFile(t, fio, file3, 7)
+
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], []string{"key"}, []string{file1, file2}, nil, "", "0001"))
+
+	m = standIn.tables["test_namespace.test_table"]
+	first := m.currentSnapshot()
+	require.NotNil(t, first)
+	require.Nil(t, first.ParentSnapshotID)
+	require.Equal(t, int64(1), first.SequenceNumber)
+	require.Equal(t, "2", first.Summary["added-data-files"])
+	require.Equal(t, "8", first.Summary["added-records"])
+	require.Equal(t, "8", first.Summary["total-records"])
+	require.Equal(t, `{"test/materialization":"0001"}`, m.Properties[checkpointsProperty])
+
+	manifests, err := readManifestList(ctx, fio, first.ManifestList)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	require.Equal(t, int32(2), manifests[0].addedFilesCount)
+	require.Equal(t, int64(8), manifests[0].addedRowsCount)
+	require.Equal(t, first.SnapshotID, manifests[0].addedSnapshotID)
+
+	// Re-applying the same checkpoint does not append the files again.
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], []string{"key"}, []string{file1, file2}, nil, "", "0001"))
+	require.Equal(t, first.SnapshotID, standIn.tables["test_namespace.test_table"].currentSnapshot().SnapshotID)
+
+	// A subsequent append carries forward the prior manifests.
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], []string{"key"}, []string{file3}, nil, "0001", "0002"))
+
+	m = standIn.tables["test_namespace.test_table"]
+	second := m.currentSnapshot()
+	require.Equal(t, first.SnapshotID, *second.ParentSnapshotID)
+	require.Equal(t, int64(2), second.SequenceNumber)
+	require.Equal(t, "15", second.Summary["total-records"])
+	require.Equal(t, "3", second.Summary["total-data-files"])
+	require.Equal(t, `{"test/materialization":"0002"}`, m.Properties[checkpointsProperty])
+	require.Len(t, m.Snapshots, 2)
+
+	manifests, err = readManifestList(ctx, fio, second.ManifestList)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+	require.Equal(t, second.SnapshotID, manifests[0].addedSnapshotID)
+	require.Equal(t, first.SnapshotID, manifests[1].addedSnapshotID)
+
+	// Commits based on stale metadata are rejected.
+	stale := &loadedTable{metadata: m}
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], []string{"key"}, []string{file1}, nil, "0002", "0003"))
+	err = c.catalog.commitTable(ctx, "test_namespace", "test_table", stale, []tableRequirement{
+		assertRefSnapshotID(mainBranch, &second.SnapshotID),
+	}, []tableUpdate{setPropertiesUpdate(map[string]string{"some": "property"})})
+	require.ErrorIs(t, err, errCommitConflict)
+
+	// Dropping tables.
+	require.NoError(t, c.catalog.dropTable(ctx, "test_namespace", "test_table"))
+	_, err = c.catalog.loadTable(ctx, "test_namespace", "test_table")
+	require.ErrorIs(t, err, errNotFound)
+}
+
+func TestStandardUpdates(t *testing.T) {
+	ctx := context.Background()
+	c, standIn, fio := testRestCatalog(t)
+	require.NoError(t, c.createNamespace(ctx, "test_namespace"))
+
+	// A table keyed on a string and an integer, with the root document.
+	pqSchema := enc.ParquetSchema{
+		{Name: "key1", DataType: enc.LogicalTypeString, Required: true},
+		{Name: "key2", DataType: enc.PrimitiveTypeInteger, Required: true},
+		{Name: "value", DataType: enc.PrimitiveTypeInteger},
+		{Name: "flow_document", DataType: enc.LogicalTypeString, Required: true},
+	}
+	keySchema := pqSchema[:2]
+	keyColumns := []string{"key1", "key2"}
+
+	schema := icebergSchema{Type: "struct", IdentifierFieldIDs: []int{1, 2}}
+	for idx, f := range pqSchema {
+		schema.Fields = append(schema.Fields, newIcebergField(idx+1, f.Name, f.Required, parquetTypeToIcebergType(f.DataType)))
+	}
+	location := tablePath(c.cfg.Bucket, c.cfg.Prefix, "test_namespace", "test_table")
+	require.NoError(t, c.catalog.createTable(ctx, "test_namespace", "test_table", location, schema, nil))
+	dataDir := trimSlash(location) + "/data/"
+
+	row := func(k1 string, k2 int64, v int64) []any {
+		return []any{k1, k2, v, fmt.Sprintf(`{"key1":%q,"key2":%d,"value":%d}`, k1, k2, v)}
+	}
+	load := func(keys ...[]any) []string {
+		docs, err := c.loadDocuments(ctx, c.resourcePaths[0], keySchema, "flow_document", keys)
+		require.NoError(t, err)
+
+		var out []string
+		for _, d := range docs {
+			out = append(out, string(d))
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	// Nothing is loaded from an empty table.
+	require.Empty(t, load([]any{"a", int64(1)}))
+
+	// The first transaction only has new keys.
+	putParquetRows(t, fio, dataDir+"1.parquet", pqSchema, [][]any{
+		row("a", 1, 1), row("a", 2, 1), row("b", 1, 1),
+	})
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], keyColumns, []string{dataDir + "1.parquet"}, nil, "", "0001"))
+
+	require.Equal(t, []string{
+		`{"key1":"a","key2":1,"value":1}`,
+		`{"key1":"b","key2":1,"value":1}`,
+	}, load([]any{"a", int64(1)}, []any{"b", int64(1)}, []any{"c", int64(1)}))
+
+	// The second transaction updates existing keys and adds a new one.
+	putParquetRows(t, fio, dataDir+"2.parquet", pqSchema, [][]any{
+		row("a", 2, 2), row("b", 1, 2), row("c", 1, 2),
+	})
+	putParquetRows(t, fio, dataDir+"2-deletes.parquet", keySchema, [][]any{
+		{"a", int64(2)}, {"b", int64(1)},
+	})
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], keyColumns, []string{dataDir + "2.parquet"}, []string{dataDir + "2-deletes.parquet"}, "0001", "0002"))
+
+	m := standIn.tables["test_namespace.test_table"]
+	snap := m.currentSnapshot()
+	require.Equal(t, "overwrite", snap.Summary["operation"])
+	require.Equal(t, "1", snap.Summary["added-equality-delete-files"])
+	require.Equal(t, "2", snap.Summary["added-equality-deletes"])
+	require.Equal(t, "1", snap.Summary["total-delete-files"])
+
+	manifests, err := readManifestList(ctx, fio, snap.ManifestList)
+	require.NoError(t, err)
+	require.Len(t, manifests, 3)
+	require.Equal(t, int32(manifestContentData), manifests[0].content)
+	require.Equal(t, int32(manifestContentDeletes), manifests[1].content)
+
+	entries, err := readManifestEntries(ctx, fio, manifests[1])
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(2), entries[0].sequenceNumber)
+	require.Equal(t, int32(fileContentEqualityDelete), entries[0].file.content)
+	require.Equal(t, []int{1, 2}, entries[0].file.equalityIDs)
+	require.Equal(t, int64(2), entries[0].file.recordCount)
+
+	require.Equal(t, []string{
+		`{"key1":"a","key2":1,"value":1}`,
+		`{"key1":"a","key2":2,"value":2}`,
+		`{"key1":"b","key2":1,"value":2}`,
+		`{"key1":"c","key2":1,"value":2}`,
+	}, load([]any{"a", int64(1)}, []any{"a", int64(2)}, []any{"b", int64(1)}, []any{"c", int64(1)}, []any{"d", int64(1)}))
+
+	// Keys outside of the range of the stored keys are skipped by using the
+	// row group statistics of the data files.
+	require.Empty(t, load([]any{"z", int64(1)}))
+
+	// The bounds of the first key column are included in the manifest entries
+	// of files.
+	entries, err = readManifestEntries(ctx, fio, manifests[0])
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, map[int][]byte{1: []byte("a")}, entries[0].file.lowerBounds)
+	require.Equal(t, map[int][]byte{1: []byte("c")}, entries[0].file.upperBounds)
+
+	// Hard deletes are committed with only an equality delete file.
+	putParquetRows(t, fio, dataDir+"3-deletes.parquet", keySchema, [][]any{
+		{"c", int64(1)},
+	})
+	require.NoError(t, c.appendFiles(ctx, "test/materialization", c.resourcePaths[0], keyColumns, nil, []string{dataDir + "3-deletes.parquet"}, "0002", "0003"))
+	require.Equal(t, []string{
+		`{"key1":"a","key2":1,"value":1}`,
+	}, load([]any{"a", int64(1)}, []any{"c", int64(1)}))
+
+	// Files that can't contain any of the keys based on the bounds in their
+	// manifest entries are not read. The first data file only has keys "a"
+	// and "b".
+	delete(fio.files, dataDir+"1.parquet")
+	require.Empty(t, load([]any{"c", int64(1)}))
+}
+
+func TestStatDataFile(t *testing.T) {
+	ctx := context.Background()
+	fio := newMemFileIO()
+
+	putParquetFile(t, fio, "s3://bucket/small.parquet", 10)
+	putParquetFile(t, fio, "s3://bucket/large.parquet", 100000)
+
+	for _, tt := range []struct {
+		uri  string
+		rows int64
+	}{
+		{"s3://bucket/small.parquet", 10},
+		{"s3://bucket/large.parquet", 100000},
+	} {
+		f, err := statDataFile(ctx, fio, tt.uri, nil)
+		require.NoError(t, err)
+		require.Equal(t, tt.uri, f.path)
+		require.Equal(t, tt.rows, f.recordCount)
+		require.Equal(t, int64(len(fio.files[tt.uri])), f.sizeBytes)
+	}
+
+	require.NoError(t, fio.put(ctx, "s3://bucket/not-parquet.json", []byte(`{"hello":"world"}`)))
+	_, err := statDataFile(ctx, fio, "s3://bucket/not-parquet.json", nil)
+	require.ErrorContains(t, err, "is not a parquet file")
+}
+
+func TestTableMetadataRoundTrip(t *testing.T) {
+	// Fields of the metadata that aren't modeled are preserved.
+	in := `{"format-version":2,"table-uuid":"9c12d441-03fe-4693-9a96-a0705ddf69c1","location":"s3://bucket/table","last-sequence-number":0,"last-updated-ms":1,"last-column-id":1,"schemas":[{"type":"struct","schema-id":0,"fields":[{"id":1,"name":"key","required":true,"type":"long"}]}],"current-schema-id":0,"partition-specs":[{"spec-id":0,"fields":[]}],"default-spec-id":0,"last-partition-id":999,"sort-orders":[{"order-id":0,"fields":[]}],"default-sort-order-id":0,"statistics":[]}`
+
+	var m tableMetadata
+	require.NoError(t, json.Unmarshal([]byte(in), &m))
+	require.Equal(t, "s3://bucket/table/metadata", m.metadataDir())
+
+	out, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.JSONEq(t, in, string(out))
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	awsHttp "github.com/aws/smithy-go/transport/http"
 	"github.com/estuary/connectors/filesink"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	cerrors "github.com/estuary/connectors/go/connector-errors"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
@@ -23,8 +24,10 @@ import (
 	pf "github.com/estuary/flow/go/protocols/flow"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 	"github.com/google/uuid"
+	"github.com/invopop/jsonschema"
 	iso8601 "github.com/senseyeio/duration"
 	log "github.com/sirupsen/logrus"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
 
 type catalogType string
@@ -34,17 +37,17 @@ const (
 	catalogTypeRest catalogType = "Iceberg REST Server"
 )
 
-// There is an equivalent pydantic model in iceberg-ctl, and the config schema is generated from
-// that. The fields of this struct must be compatible with that model.
 type config struct {
-	Bucket             string        `json:"bucket"`
-	AWSAccessKeyID     string        `json:"aws_access_key_id"`
-	AWSSecretAccessKey string        `json:"aws_secret_access_key"`
-	Namespace          string        `json:"namespace"`
-	Region             string        `json:"region"`
-	UploadInterval     string        `json:"upload_interval"`
-	Prefix             string        `json:"prefix,omitempty"`
-	Catalog            catalogConfig `json:"catalog"`
+	Credentials        *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication,description=AWS credentials for accessing AWS services." jsonschema_extras:"order=0"`
+	AWSAccessKeyID     string                    `json:"aws_access_key_id,omitempty" jsonschema:"title=AWS Access Key ID,description=Deprecated: use Authentication instead. Access Key ID for accessing AWS services." jsonschema_extras:"order=1"`
+	AWSSecretAccessKey string                    `json:"aws_secret_access_key,omitempty" jsonschema:"title=AWS Secret Access Key,description=Deprecated: use Authentication instead. Secret Access Key for accessing AWS services." jsonschema_extras:"secret=true,order=2"`
+	Bucket             string                    `json:"bucket" jsonschema:"title=Bucket,description=The S3 bucket to write data files to." jsonschema_extras:"order=3"`
+	Prefix             string                    `json:"prefix,omitempty" jsonschema:"title=Prefix,description=Optional prefix that will be used to store objects." jsonschema_extras:"order=4"`
+	Region             string                    `json:"region" jsonschema:"title=Region,description=AWS region." jsonschema_extras:"order=5"`
+	Namespace          string                    `json:"namespace" jsonschema:"title=Namespace,description=Namespace for bound collection tables (unless overridden within the binding resource configuration).,pattern=^[^.]*$" jsonschema_extras:"order=6"`
+	UploadInterval     string                    `json:"upload_interval" jsonschema:"title=Upload Interval,description=Frequency at which files will be uploaded. Must be a valid ISO8601 duration string no greater than 4 hours.,default=PT300S,format=duration" jsonschema_extras:"order=7"`
+	Catalog            catalogConfig             `json:"catalog" jsonschema:"title=Catalog" jsonschema_extras:"order=8"`
+	HardDelete         bool                      `json:"hard_delete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=9"`
 }
 
 type catalogConfig struct {
@@ -57,11 +60,74 @@ type catalogConfig struct {
 	Warehouse  string `json:"warehouse,omitempty"`
 }
 
+// JSONSchema is implemented manually since the configuration for each type of
+// catalog is a different variant of the catalog config.
+func (catalogConfig) JSONSchema() *jsonschema.Schema {
+	restProps := orderedmap.New[string, *jsonschema.Schema]()
+	restProps.Set("catalog_type", &jsonschema.Schema{
+		Title:   "Catalog Type",
+		Type:    "string",
+		Default: catalogTypeRest,
+		Const:   catalogTypeRest,
+		Extras:  map[string]interface{}{"order": 0},
+	})
+	restProps.Set("uri", &jsonschema.Schema{
+		Title:       "URI",
+		Description: "URI identifying the REST catalog, in the format of 'https://yourserver.com/catalog'.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 1},
+	})
+	restProps.Set("credential", &jsonschema.Schema{
+		Title:       "Credential",
+		Description: "Credential for connecting to the catalog.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 2, "secret": true},
+	})
+	restProps.Set("token", &jsonschema.Schema{
+		Title:       "Token",
+		Description: "Token for connecting to the catalog.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 3, "secret": true},
+	})
+	restProps.Set("warehouse", &jsonschema.Schema{
+		Title:       "Warehouse",
+		Description: "Warehouse to connect to.",
+		Type:        "string",
+		Extras:      map[string]interface{}{"order": 4},
+	})
+
+	glueProps := orderedmap.New[string, *jsonschema.Schema]()
+	glueProps.Set("catalog_type", &jsonschema.Schema{
+		Title:   "Catalog Type",
+		Type:    "string",
+		Default: catalogTypeGlue,
+		Const:   catalogTypeGlue,
+	})
+
+	return &jsonschema.Schema{
+		Title: "Catalog",
+		OneOf: []*jsonschema.Schema{
+			{
+				Title:      "REST",
+				Required:   []string{"catalog_type", "uri", "warehouse"},
+				Properties: restProps,
+			},
+			{
+				Title:      "AWS Glue",
+				Required:   []string{"catalog_type"},
+				Properties: glueProps,
+			},
+		},
+		Extras: map[string]interface{}{
+			"discriminator": map[string]string{"propertyName": "catalog_type"},
+		},
+		Type: "object",
+	}
+}
+
 func (c config) Validate() error {
 	var requiredProperties = [][]string{
 		{"bucket", c.Bucket},
-		{"aws_access_key_id", c.AWSAccessKeyID},
-		{"aws_secret_access_key", c.AWSSecretAccessKey},
 		{"namespace", c.Namespace},
 		{"region", c.Region},
 		{"upload_interval", c.UploadInterval},
@@ -72,6 +138,10 @@ func (c config) Validate() error {
 		}
 	}
 
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
+	}
+
 	if c.Catalog.CatalogType == "" {
 		return fmt.Errorf("missing 'catalog_type'")
 	}
@@ -103,6 +173,23 @@ func (c config) Validate() error {
 	return nil
 }
 
+func newS3Store(ctx context.Context, cfg config) (*filesink.S3Store, error) {
+	s3store, err := filesink.NewS3Store(ctx, filesink.S3StoreConfig{
+		Bucket:      cfg.Bucket,
+		Credentials: cfg.Credentials,
+		AccessKeys: awsauth.AccessKeys{
+			AWSAccessKeyID:     cfg.AWSAccessKeyID,
+			AWSSecretAccessKey: cfg.AWSSecretAccessKey,
+		},
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating s3 store: %w", err)
+	}
+
+	return s3store, nil
+}
+
 func parse8601(in string) (time.Duration, error) {
 	parsed, err := iso8601.ParseISO8601(in)
 	if err != nil {
@@ -124,7 +211,7 @@ func parse8601(in string) (time.Duration, error) {
 type resource struct {
 	Table     string `json:"table" jsonschema:"title=Table,description=Name of the database table." jsonschema_extras:"x-collection-name=true"`
 	Namespace string `json:"namespace,omitempty" jsonschema:"title=Alternative Namespace,description=Alternative namespace for this table (optional)."`
-	Delta     *bool  `json:"delta_updates,omitempty" jsonschema:"default=true,title=Delta Update,description=Should updates to this table be done via delta updates. Currently this connector only supports delta updates."`
+	Delta     *bool  `json:"delta_updates,omitempty" jsonschema:"default=true,title=Delta Update,description=Should updates to this table be done via delta updates. Otherwise the table will reflect the current state of each document using equality deletes."`
 }
 
 func newResource(cfg config) resource {
@@ -158,8 +245,6 @@ func (r resource) Validate() error {
 		return fmt.Errorf("table %q must not contain dots", r.Table)
 	} else if strings.Contains(r.Namespace, ".") {
 		return fmt.Errorf("namespace %q must not contain dots", r.Namespace)
-	} else if r.Delta != nil && !*r.Delta {
-		return fmt.Errorf("connector only supports delta update mode: delta update must be enabled")
 	}
 
 	return nil
@@ -168,7 +253,7 @@ func (r resource) Validate() error {
 type driver struct{}
 
 func (driver) Spec(ctx context.Context, req *pm.Request_Spec) (*pm.Response_Spec, error) {
-	endpointSchema, err := runIcebergctl(ctx, nil, "print-config-schema")
+	endpointSchema, err := schemagen.GenerateSchema("EndpointConfig", &config{}).MarshalJSON()
 	if err != nil {
 		return nil, fmt.Errorf("generating endpoint schema: %w", err)
 	}
@@ -194,14 +279,9 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 	// Test creating, reading, and deleting an object from the configured bucket and prefix.
 	errs := &sql.PrereqErr{}
 
-	s3store, err := filesink.NewS3Store(ctx, filesink.S3StoreConfig{
-		Bucket:             cfg.Bucket,
-		AWSAccessKeyID:     cfg.AWSAccessKeyID,
-		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
-		Region:             cfg.Region,
-	})
+	s3store, err := newS3Store(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("creating s3 store: %w", err)
+		return nil, err
 	}
 
 	s3client := s3store.Client()
@@ -256,7 +336,10 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 		resourcePaths = append(resourcePaths, res.path())
 	}
 
-	catalog := newCatalog(cfg, resourcePaths)
+	catalog, err := newCatalog(ctx, cfg, resourcePaths)
+	if err != nil {
+		return nil, err
+	}
 
 	is, err := catalog.infoSchema(ctx)
 	if err != nil {
@@ -309,7 +392,10 @@ func (driver) Apply(ctx context.Context, req *pm.Request_Apply) (*pm.Response_Ap
 		resourcePaths = append(resourcePaths, b.ResourcePath)
 	}
 
-	catalog := newCatalog(cfg, resourcePaths)
+	catalog, err := newCatalog(ctx, cfg, resourcePaths)
+	if err != nil {
+		return nil, err
+	}
 
 	existingNamespaces, err := catalog.listNamespaces(ctx)
 	if err != nil {
@@ -359,14 +445,21 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 
 		resourcePaths = append(resourcePaths, res.path())
 		bindings = append(bindings, binding{
-			path:       b.ResourcePath,
-			pqSchema:   pqSchema,
-			includeDoc: b.FieldSelection.Document != "",
-			stateKey:   b.StateKey,
+			path:         b.ResourcePath,
+			pqSchema:     pqSchema,
+			includeDoc:   b.FieldSelection.Document != "",
+			stateKey:     b.StateKey,
+			deltaUpdates: b.DeltaUpdates,
+			keySchema:    pqSchema[:len(b.FieldSelection.Keys)],
+			docField:     b.FieldSelection.Document,
 		})
 	}
 
-	catalog := newCatalog(cfg, resourcePaths)
+	catalog, err := newCatalog(ctx, cfg, resourcePaths)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	tablePaths, err := catalog.tablePaths(ctx, resourcePaths)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("looking up table paths: %w", err)
@@ -376,14 +469,9 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 		bindings[idx].catalogTablePath = tablePaths[idx]
 	}
 
-	s3store, err := filesink.NewS3Store(ctx, filesink.S3StoreConfig{
-		Bucket:             cfg.Bucket,
-		AWSAccessKeyID:     cfg.AWSAccessKeyID,
-		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
-		Region:             cfg.Region,
-	})
+	s3store, err := newS3Store(ctx, cfg)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("creating s3 store: %w", err)
+		return nil, nil, nil, err
 	}
 
 	interval, err := parse8601(cfg.UploadInterval)
@@ -407,6 +495,7 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 		bucket:          cfg.Bucket,
 		prefix:          cfg.Prefix,
 		store:           s3store,
+		hardDelete:      cfg.HardDelete,
 	}, &pm.Response_Opened{}, opts, nil
 }
 
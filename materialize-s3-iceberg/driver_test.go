@@ -15,10 +15,6 @@ import (
 )
 
 func TestSpec(t *testing.T) {
-	if os.Getenv("TEST_DATABASE") != "yes" {
-		t.Skipf("skipping %q: ${TEST_DATABASE} != \"yes\"", t.Name())
-	}
-
 	var resp, err = driver{}.
 		Spec(context.Background(), &pm.Request_Spec{})
 	require.NoError(t, err)
@@ -63,10 +59,8 @@ func TestValidateAndApply(t *testing.T) {
 		Table:     "test_table",
 	}
 
-	catalog := catalog{
-		cfg:           &cfg,
-		resourcePaths: [][]string{{"test_namespace", "test_table"}},
-	}
+	catalog, err := newCatalog(ctx, cfg, [][]string{{"test_namespace", "test_table"}})
+	require.NoError(t, err)
 
 	boilerplate.RunValidateAndApplyTestCases(
 		t,
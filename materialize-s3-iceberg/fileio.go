@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/parquet/metadata"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/sync/errgroup"
+)
+
+// fileIO reads and writes the files of Iceberg tables, which are identified by
+// their full URI.
+type fileIO interface {
+	get(ctx context.Context, uri string) ([]byte, error)
+	put(ctx context.Context, uri string, data []byte) error
+	// tail returns the total size of a file and up to the last n bytes of
+	// it.
+	tail(ctx context.Context, uri string, n int64) (size int64, data []byte, err error)
+	// readRange returns n bytes of a file starting at offset.
+	readRange(ctx context.Context, uri string, offset, n int64) ([]byte, error)
+}
+
+// fileReaderAt adapts a file of a fileIO having a known size to an
+// io.ReaderAt, so that only the parts of a file that are needed are read.
+type fileReaderAt struct {
+	ctx  context.Context
+	fio  fileIO
+	uri  string
+	size int64
+}
+
+func (r *fileReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	n := min(int64(len(p)), r.size-off)
+	data, err := r.fio.readRange(r.ctx, r.uri, off, n)
+	if err != nil {
+		return 0, err
+	}
+	copied := copy(p, data)
+	if copied < len(p) {
+		return copied, io.EOF
+	}
+
+	return copied, nil
+}
+
+func (r *fileReaderAt) Seek(offset int64, whence int) (int64, error) {
+	// Seeking is only used by the parquet reader to determine the size of
+	// the file.
+	if offset == 0 && whence == io.SeekEnd {
+		return r.size, nil
+	}
+	return 0, fmt.Errorf("unsupported seek of %q", r.uri)
+}
+
+type s3FileIO struct {
+	client *s3.Client
+}
+
+func splitS3URI(uri string) (bucket, key string, err error) {
+	for _, scheme := range []string{"s3://", "s3a://", "s3n://"} {
+		if rest, ok := strings.CutPrefix(uri, scheme); ok {
+			if bucket, key, ok = strings.Cut(rest, "/"); ok && bucket != "" && key != "" {
+				return bucket, key, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("invalid S3 URI %q", uri)
+}
+
+func (s *s3FileIO) get(ctx context.Context, uri string) ([]byte, error) {
+	bucket, key, err := splitS3URI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting %q: %w", uri, err)
+	}
+	defer res.Body.Close()
+
+	return io.ReadAll(res.Body)
+}
+
+func (s *s3FileIO) put(ctx context.Context, uri string, data []byte) error {
+	bucket, key, err := splitS3URI(uri)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return fmt.Errorf("putting %q: %w", uri, err)
+	}
+
+	return nil
+}
+
+func (s *s3FileIO) tail(ctx context.Context, uri string, n int64) (int64, []byte, error) {
+	bucket, key, err := splitS3URI(uri)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=-%d", n)),
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("getting %q: %w", uri, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("reading %q: %w", uri, err)
+	}
+
+	// The Content-Range is of the form "bytes 100-199/200" for ranged
+	// requests.
+	var size = int64(len(data))
+	if res.ContentRange != nil {
+		if _, total, ok := strings.Cut(*res.ContentRange, "/"); ok {
+			if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+				return 0, nil, fmt.Errorf("parsing content range %q of %q: %w", *res.ContentRange, uri, err)
+			}
+		}
+	}
+
+	return size, data, nil
+}
+
+func (s *s3FileIO) readRange(ctx context.Context, uri string, offset, n int64) ([]byte, error) {
+	bucket, key, err := splitS3URI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+n-1)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting %q: %w", uri, err)
+	}
+	defer res.Body.Close()
+
+	return io.ReadAll(res.Body)
+}
+
+const (
+	parquetMagic = "PAR1"
+	// Read this much of the end of parquet files initially, which is usually
+	// enough to include their entire footer.
+	parquetFooterReadSize = 64 * 1024
+)
+
+// columnBounds identifies a column of parquet files for which the bounds of
+// its values are included in their manifest entries.
+type columnBounds struct {
+	column  string
+	fieldID int
+}
+
+// statDataFile reads the footer of a parquet file to get the information
+// needed to append it to a table. The bounds of the values of the column are
+// included if it is not nil and the file has statistics for it.
+func statDataFile(ctx context.Context, fio fileIO, uri string, bounds *columnBounds) (dataFile, error) {
+	size, tail, err := fio.tail(ctx, uri, parquetFooterReadSize)
+	if err != nil {
+		return dataFile{}, err
+	} else if len(tail) < 8 || string(tail[len(tail)-4:]) != parquetMagic {
+		return dataFile{}, fmt.Errorf("%q is not a parquet file", uri)
+	}
+
+	footerLen := int64(binary.LittleEndian.Uint32(tail[len(tail)-8 : len(tail)-4]))
+	if footerLen+8 > size {
+		return dataFile{}, fmt.Errorf("%q has invalid footer length %d", uri, footerLen)
+	} else if footerLen+8 > int64(len(tail)) {
+		if _, tail, err = fio.tail(ctx, uri, footerLen+8); err != nil {
+			return dataFile{}, err
+		}
+	}
+
+	meta, err := metadata.NewFileMetaData(tail[int64(len(tail))-8-footerLen:len(tail)-8], nil)
+	if err != nil {
+		return dataFile{}, fmt.Errorf("reading footer of %q: %w", uri, err)
+	}
+
+	var out = dataFile{
+		path:        uri,
+		content:     fileContentData,
+		recordCount: meta.NumRows,
+		sizeBytes:   size,
+	}
+
+	if bounds != nil {
+		if lower, upper, err := fileColumnBounds(meta, bounds.column); err != nil {
+			return dataFile{}, fmt.Errorf("reading statistics of %q: %w", uri, err)
+		} else if lower != nil {
+			out.lowerBounds = map[int][]byte{bounds.fieldID: lower}
+			out.upperBounds = map[int][]byte{bounds.fieldID: upper}
+		}
+	}
+
+	return out, nil
+}
+
+func statDataFiles(ctx context.Context, fio fileIO, uris []string, bounds *columnBounds) ([]dataFile, error) {
+	var out = make([]dataFile, len(uris))
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(10)
+	for idx, uri := range uris {
+		group.Go(func() error {
+			f, err := statDataFile(groupCtx, fio, uri, bounds)
+			if err != nil {
+				return err
+			}
+			out[idx] = f
+			return nil
+		})
+	}
+
+	return out, group.Wait()
+}
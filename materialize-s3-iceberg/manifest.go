@@ -0,0 +1,645 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/linkedin/goavro/v2"
+)
+
+// Avro schemas for version 2 manifest files and manifest lists. Iceberg
+// readers resolve fields by their "field-id", so these must be retained in
+// the written schemas. See https://iceberg.apache.org/spec/#manifests.
+const (
+	manifestEntrySchema = `{
+	"type": "record",
+	"name": "manifest_entry",
+	"fields": [
+		{"name": "status", "type": "int", "field-id": 0},
+		{"name": "snapshot_id", "type": ["null", "long"], "default": null, "field-id": 1},
+		{"name": "sequence_number", "type": ["null", "long"], "default": null, "field-id": 3},
+		{"name": "file_sequence_number", "type": ["null", "long"], "default": null, "field-id": 4},
+		{"name": "data_file", "field-id": 2, "type": {
+			"type": "record",
+			"name": "r2",
+			"fields": [
+				{"name": "content", "type": "int", "field-id": 134},
+				{"name": "file_path", "type": "string", "field-id": 100},
+				{"name": "file_format", "type": "string", "field-id": 101},
+				{"name": "partition", "type": {"type": "record", "name": "r102", "fields": []}, "field-id": 102},
+				{"name": "record_count", "type": "long", "field-id": 103},
+				{"name": "file_size_in_bytes", "type": "long", "field-id": 104},
+				{"name": "lower_bounds", "type": ["null", {"type": "array", "logicalType": "map", "items": {
+					"type": "record",
+					"name": "k126_v127",
+					"fields": [
+						{"name": "key", "type": "int", "field-id": 126},
+						{"name": "value", "type": "bytes", "field-id": 127}
+					]
+				}}], "default": null, "field-id": 125},
+				{"name": "upper_bounds", "type": ["null", {"type": "array", "logicalType": "map", "items": {
+					"type": "record",
+					"name": "k129_v130",
+					"fields": [
+						{"name": "key", "type": "int", "field-id": 129},
+						{"name": "value", "type": "bytes", "field-id": 130}
+					]
+				}}], "default": null, "field-id": 128},
+				{"name": "equality_ids", "type": ["null", {"type": "array", "element-id": 136, "items": "int"}], "default": null, "field-id": 135}
+			]
+		}}
+	]
+}`
+
+	manifestFileSchema = `{
+	"type": "record",
+	"name": "manifest_file",
+	"fields": [
+		{"name": "manifest_path", "type": "string", "field-id": 500},
+		{"name": "manifest_length", "type": "long", "field-id": 501},
+		{"name": "partition_spec_id", "type": "int", "field-id": 502},
+		{"name": "content", "type": "int", "field-id": 517},
+		{"name": "sequence_number", "type": "long", "field-id": 515},
+		{"name": "min_sequence_number", "type": "long", "field-id": 516},
+		{"name": "added_snapshot_id", "type": "long", "field-id": 503},
+		{"name": "added_files_count", "type": "int", "field-id": 504},
+		{"name": "existing_files_count", "type": "int", "field-id": 505},
+		{"name": "deleted_files_count", "type": "int", "field-id": 506},
+		{"name": "added_rows_count", "type": "long", "field-id": 512},
+		{"name": "existing_rows_count", "type": "long", "field-id": 513},
+		{"name": "deleted_rows_count", "type": "long", "field-id": 514},
+		{"name": "partitions", "type": ["null", {"type": "array", "element-id": 508, "items": {
+			"type": "record",
+			"name": "r508",
+			"fields": [
+				{"name": "contains_null", "type": "boolean", "field-id": 509},
+				{"name": "contains_nan", "type": ["null", "boolean"], "default": null, "field-id": 518},
+				{"name": "lower_bound", "type": ["null", "bytes"], "default": null, "field-id": 510},
+				{"name": "upper_bound", "type": ["null", "bytes"], "default": null, "field-id": 511}
+			]
+		}}], "default": null, "field-id": 507}
+	]
+}`
+)
+
+var (
+	manifestEntryCodec = mustCodec(manifestEntrySchema)
+	manifestFileCodec  = mustCodec(manifestFileSchema)
+)
+
+func mustCodec(schema string) *goavro.Codec {
+	c, err := goavro.NewCodec(schema)
+	if err != nil {
+		panic(fmt.Sprintf("invalid avro schema: %s", err))
+	}
+	return c
+}
+
+const (
+	manifestContentData    = 0
+	manifestContentDeletes = 1
+
+	fileContentData           = 0
+	fileContentEqualityDelete = 2
+
+	entryStatusDeleted = 2
+)
+
+// dataFile is a parquet file of a table, which is either a data file or an
+// equality delete file.
+type dataFile struct {
+	path        string
+	content     int32
+	recordCount int64
+	sizeBytes   int64
+	// equalityIDs are the field IDs of the columns of an equality delete
+	// file.
+	equalityIDs []int
+	// lowerBounds and upperBounds are the bounds of the values of columns in
+	// the file by their field ID, in Iceberg's single-value serialization.
+	// Files written by other engines may not have bounds, or may have bounds
+	// for strings that are truncated to a prefix.
+	lowerBounds map[int][]byte
+	upperBounds map[int][]byte
+}
+
+// manifestEntry is a file tracked by a manifest.
+type manifestEntry struct {
+	status int32
+	// sequenceNumber is the data sequence number of the file, which is
+	// inherited from the manifest if it is not set explicitly.
+	sequenceNumber int64
+	file           dataFile
+}
+
+// manifestFile is an entry of a manifest list.
+type manifestFile struct {
+	path               string
+	length             int64
+	partitionSpecID    int32
+	content            int32
+	sequenceNumber     int64
+	minSequenceNumber  int64
+	addedSnapshotID    int64
+	addedFilesCount    int32
+	existingFilesCount int32
+	deletedFilesCount  int32
+	addedRowsCount     int64
+	existingRowsCount  int64
+	deletedRowsCount   int64
+	partitions         []any
+}
+
+func (f manifestFile) toAvro() map[string]any {
+	var partitions any
+	if f.partitions != nil {
+		partitions = goavro.Union("array", f.partitions)
+	}
+
+	return map[string]any{
+		"manifest_path":        f.path,
+		"manifest_length":      f.length,
+		"partition_spec_id":    f.partitionSpecID,
+		"content":              f.content,
+		"sequence_number":      f.sequenceNumber,
+		"min_sequence_number":  f.minSequenceNumber,
+		"added_snapshot_id":    f.addedSnapshotID,
+		"added_files_count":    f.addedFilesCount,
+		"existing_files_count": f.existingFilesCount,
+		"deleted_files_count":  f.deletedFilesCount,
+		"added_rows_count":     f.addedRowsCount,
+		"existing_rows_count":  f.existingRowsCount,
+		"deleted_rows_count":   f.deletedRowsCount,
+		"partitions":           partitions,
+	}
+}
+
+// manifestFileFromAvro reads a manifest list entry, which may have been
+// written by another engine and with an older version of the spec.
+func manifestFileFromAvro(rec map[string]any) (manifestFile, error) {
+	path, ok := rec["manifest_path"].(string)
+	if !ok {
+		return manifestFile{}, fmt.Errorf("manifest list entry has no manifest_path")
+	}
+
+	// Version 1 manifest lists name the file counts differently.
+	var count = func(v2Name, v1Name string) int32 {
+		if v, ok := rec[v2Name]; ok {
+			return int32(avroLong(v))
+		}
+		return int32(avroLong(rec[v1Name]))
+	}
+
+	var partitions []any
+	if p, ok := avroUnionValue(rec["partitions"]).([]any); ok {
+		for _, summary := range p {
+			s, ok := summary.(map[string]any)
+			if !ok {
+				return manifestFile{}, fmt.Errorf("invalid partition field summary %#v", summary)
+			}
+			partitions = append(partitions, map[string]any{
+				"contains_null": s["contains_null"],
+				"contains_nan":  avroOptional("boolean", s["contains_nan"]),
+				"lower_bound":   avroOptional("bytes", s["lower_bound"]),
+				"upper_bound":   avroOptional("bytes", s["upper_bound"]),
+			})
+		}
+	}
+
+	return manifestFile{
+		path:               path,
+		length:             avroLong(rec["manifest_length"]),
+		partitionSpecID:    int32(avroLong(rec["partition_spec_id"])),
+		content:            int32(avroLong(rec["content"])),
+		sequenceNumber:     avroLong(rec["sequence_number"]),
+		minSequenceNumber:  avroLong(rec["min_sequence_number"]),
+		addedSnapshotID:    avroLong(rec["added_snapshot_id"]),
+		addedFilesCount:    count("added_files_count", "added_data_files_count"),
+		existingFilesCount: count("existing_files_count", "existing_data_files_count"),
+		deletedFilesCount:  count("deleted_files_count", "deleted_data_files_count"),
+		addedRowsCount:     avroLong(rec["added_rows_count"]),
+		existingRowsCount:  avroLong(rec["existing_rows_count"]),
+		deletedRowsCount:   avroLong(rec["deleted_rows_count"]),
+		partitions:         partitions,
+	}, nil
+}
+
+// avroUnionValue unwraps a decoded union value.
+func avroUnionValue(v any) any {
+	if m, ok := v.(map[string]any); ok && len(m) == 1 {
+		for _, inner := range m {
+			return inner
+		}
+	}
+	return v
+}
+
+func avroLong(v any) int64 {
+	switch v := avroUnionValue(v).(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+func avroOptional(typ string, v any) any {
+	if v = avroUnionValue(v); v == nil {
+		return nil
+	}
+	return goavro.Union(typ, v)
+}
+
+// boundsToAvro converts column bounds to the Avro representation of an Iceberg
+// map, which is an array of key/value records.
+func boundsToAvro(bounds map[int][]byte) any {
+	if bounds == nil {
+		return nil
+	}
+
+	var ids = make([]int, 0, len(bounds))
+	for id := range bounds {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var out = make([]any, 0, len(bounds))
+	for _, id := range ids {
+		out = append(out, map[string]any{"key": int32(id), "value": bounds[id]})
+	}
+	return goavro.Union("array", out)
+}
+
+func boundsFromAvro(v any) map[int][]byte {
+	kvs, ok := avroUnionValue(v).([]any)
+	if !ok {
+		return nil
+	}
+
+	var out = make(map[int][]byte, len(kvs))
+	for _, kv := range kvs {
+		if kv, ok := kv.(map[string]any); ok {
+			if b, ok := kv["value"].([]byte); ok {
+				out[int(avroLong(kv["key"]))] = b
+			}
+		}
+	}
+	return out
+}
+
+func writeOCF(codec *goavro.Codec, meta map[string]string, records []any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var metaData = make(map[string][]byte, len(meta))
+	for k, v := range meta {
+		metaData[k] = []byte(v)
+	}
+
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Codec:           codec,
+		CompressionName: goavro.CompressionDeflateLabel,
+		MetaData:        metaData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating avro writer: %w", err)
+	} else if err := w.Append(records); err != nil {
+		return nil, fmt.Errorf("writing avro records: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func readManifestList(ctx context.Context, io fileIO, path string) ([]manifestFile, error) {
+	b, err := io.get(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest list %q: %w", path, err)
+	}
+
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest list %q: %w", path, err)
+	}
+
+	var out []manifestFile
+	for r.Scan() {
+		v, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest list %q: %w", path, err)
+		}
+		rec, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("manifest list %q has invalid entry %#v", path, v)
+		}
+		f, err := manifestFileFromAvro(rec)
+		if err != nil {
+			return nil, fmt.Errorf("manifest list %q: %w", path, err)
+		}
+		out = append(out, f)
+	}
+
+	return out, r.Err()
+}
+
+// readManifestEntries reads the entries of a manifest. Files written by
+// other engines may include fields that aren't needed here, and these are
+// ignored.
+func readManifestEntries(ctx context.Context, io fileIO, mf manifestFile) ([]manifestEntry, error) {
+	b, err := io.get(ctx, mf.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest %q: %w", mf.path, err)
+	}
+
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest %q: %w", mf.path, err)
+	}
+
+	var out []manifestEntry
+	for r.Scan() {
+		v, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest %q: %w", mf.path, err)
+		}
+		rec, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("manifest %q has invalid entry %#v", mf.path, v)
+		}
+		df, ok := rec["data_file"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("manifest %q has entry without data_file", mf.path)
+		}
+
+		var entry = manifestEntry{
+			status:         int32(avroLong(rec["status"])),
+			sequenceNumber: mf.sequenceNumber,
+			file: dataFile{
+				content:     int32(avroLong(df["content"])),
+				recordCount: avroLong(df["record_count"]),
+				sizeBytes:   avroLong(df["file_size_in_bytes"]),
+			},
+		}
+		if entry.file.path, ok = df["file_path"].(string); !ok {
+			return nil, fmt.Errorf("manifest %q has entry without file_path", mf.path)
+		}
+		if seq := avroUnionValue(rec["sequence_number"]); seq != nil {
+			entry.sequenceNumber = avroLong(seq)
+		}
+		if ids, ok := avroUnionValue(df["equality_ids"]).([]any); ok {
+			for _, id := range ids {
+				entry.file.equalityIDs = append(entry.file.equalityIDs, int(avroLong(id)))
+			}
+		}
+		entry.file.lowerBounds = boundsFromAvro(df["lower_bounds"])
+		entry.file.upperBounds = boundsFromAvro(df["upper_bounds"])
+
+		out = append(out, entry)
+	}
+
+	return out, r.Err()
+}
+
+// writeManifest writes a manifest for files added by a snapshot, returning the
+// entry for it in the manifest list.
+func writeManifest(
+	ctx context.Context,
+	io fileIO,
+	m *tableMetadata,
+	schema icebergSchema,
+	path string,
+	content int32,
+	snapshotID int64,
+	sequenceNumber int64,
+	files []dataFile,
+) (manifestFile, error) {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return manifestFile{}, err
+	}
+
+	var entries []any
+	var addedRows int64
+	for _, f := range files {
+		var equalityIDs any
+		if f.equalityIDs != nil {
+			var ids = make([]any, 0, len(f.equalityIDs))
+			for _, id := range f.equalityIDs {
+				ids = append(ids, int32(id))
+			}
+			equalityIDs = goavro.Union("array", ids)
+		}
+
+		entries = append(entries, map[string]any{
+			"status":               int32(1), // ADDED
+			"snapshot_id":          goavro.Union("long", snapshotID),
+			"sequence_number":      nil, // Inherited from the manifest list.
+			"file_sequence_number": nil,
+			"data_file": map[string]any{
+				"content":            f.content,
+				"file_path":          f.path,
+				"file_format":        "PARQUET",
+				"partition":          map[string]any{},
+				"record_count":       f.recordCount,
+				"file_size_in_bytes": f.sizeBytes,
+				"equality_ids":       equalityIDs,
+				"lower_bounds":       boundsToAvro(f.lowerBounds),
+				"upper_bounds":       boundsToAvro(f.upperBounds),
+			},
+		})
+		addedRows += f.recordCount
+	}
+
+	var contentName = "data"
+	if content == manifestContentDeletes {
+		contentName = "deletes"
+	}
+
+	manifest, err := writeOCF(manifestEntryCodec, map[string]string{
+		"schema":            string(schemaJSON),
+		"schema-id":         strconv.Itoa(schema.SchemaID),
+		"partition-spec":    "[]",
+		"partition-spec-id": strconv.Itoa(m.DefaultSpecID),
+		"format-version":    "2",
+		"content":           contentName,
+	}, entries)
+	if err != nil {
+		return manifestFile{}, fmt.Errorf("writing manifest: %w", err)
+	}
+	if err := io.put(ctx, path, manifest); err != nil {
+		return manifestFile{}, fmt.Errorf("uploading manifest: %w", err)
+	}
+
+	return manifestFile{
+		path:              path,
+		length:            int64(len(manifest)),
+		partitionSpecID:   int32(m.DefaultSpecID),
+		content:           content,
+		sequenceNumber:    sequenceNumber,
+		minSequenceNumber: sequenceNumber,
+		addedSnapshotID:   snapshotID,
+		addedFilesCount:   int32(len(files)),
+		addedRowsCount:    addedRows,
+		partitions:        []any{},
+	}, nil
+}
+
+// newSnapshot writes manifests for the data files and equality delete files,
+// and a manifest list including them as well as the manifests of the current
+// snapshot of the table. It returns the snapshot to be added to the table.
+func newSnapshot(ctx context.Context, io fileIO, m *tableMetadata, dataFiles []dataFile, deleteFiles []dataFile) (*snapshot, error) {
+	if m.FormatVersion != 2 {
+		return nil, fmt.Errorf("appending files to tables with format version %d is not supported", m.FormatVersion)
+	} else if spec, err := m.defaultSpec(); err != nil {
+		return nil, err
+	} else if len(spec.Fields) != 0 {
+		return nil, fmt.Errorf("appending files to partitioned tables is not supported")
+	}
+
+	schema, err := m.currentSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotID = rand.Int63()
+	var sequenceNumber = m.LastSequenceNumber + 1
+	var commitUUID = uuid.NewString()
+	var dir = m.metadataDir()
+
+	var manifests []manifestFile
+	for idx, added := range []struct {
+		content int32
+		files   []dataFile
+	}{
+		{manifestContentData, dataFiles},
+		{manifestContentDeletes, deleteFiles},
+	} {
+		if len(added.files) == 0 {
+			continue
+		}
+
+		mf, err := writeManifest(
+			ctx, io, m, schema,
+			fmt.Sprintf("%s/%s-m%d.avro", dir, commitUUID, idx),
+			added.content, snapshotID, sequenceNumber, added.files,
+		)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, mf)
+	}
+
+	var listMeta = map[string]string{
+		"snapshot-id":     strconv.FormatInt(snapshotID, 10),
+		"sequence-number": strconv.FormatInt(sequenceNumber, 10),
+		"format-version":  "2",
+	}
+
+	var parent = m.currentSnapshot()
+	var parentID *int64
+	if parent != nil {
+		parentID = &parent.SnapshotID
+		listMeta["parent-snapshot-id"] = strconv.FormatInt(parent.SnapshotID, 10)
+
+		existing, err := readManifestList(ctx, io, parent.ManifestList)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, existing...)
+	} else {
+		listMeta["parent-snapshot-id"] = "null"
+	}
+
+	var records = make([]any, 0, len(manifests))
+	for _, mf := range manifests {
+		records = append(records, mf.toAvro())
+	}
+	manifestList, err := writeOCF(manifestFileCodec, listMeta, records)
+	if err != nil {
+		return nil, fmt.Errorf("writing manifest list: %w", err)
+	}
+	var manifestListPath = fmt.Sprintf("%s/snap-%d-1-%s.avro", dir, snapshotID, commitUUID)
+	if err := io.put(ctx, manifestListPath, manifestList); err != nil {
+		return nil, fmt.Errorf("uploading manifest list: %w", err)
+	}
+
+	var addedRows, addedBytes, addedDeletes, addedDeleteBytes int64
+	for _, f := range dataFiles {
+		addedRows += f.recordCount
+		addedBytes += f.sizeBytes
+	}
+	for _, f := range deleteFiles {
+		addedDeletes += f.recordCount
+		addedDeleteBytes += f.sizeBytes
+	}
+
+	// Appends that also delete rows are "overwrite" operations, in the same
+	// way as row-level changes made by other engines.
+	var operation = "append"
+	if len(deleteFiles) > 0 {
+		operation = "overwrite"
+	}
+
+	var summary = map[string]string{
+		"operation":                   operation,
+		"added-data-files":            strconv.Itoa(len(dataFiles)),
+		"added-records":               strconv.FormatInt(addedRows, 10),
+		"added-files-size":            strconv.FormatInt(addedBytes+addedDeleteBytes, 10),
+		"total-data-files":            strconv.Itoa(len(dataFiles)),
+		"total-records":               strconv.FormatInt(addedRows, 10),
+		"total-files-size":            strconv.FormatInt(addedBytes+addedDeleteBytes, 10),
+		"total-delete-files":          strconv.Itoa(len(deleteFiles)),
+		"total-equality-deletes":      strconv.FormatInt(addedDeletes, 10),
+		"total-position-deletes":      "0",
+		"added-delete-files":          strconv.Itoa(len(deleteFiles)),
+		"added-equality-delete-files": strconv.Itoa(len(deleteFiles)),
+		"added-equality-deletes":      strconv.FormatInt(addedDeletes, 10),
+	}
+	if len(deleteFiles) == 0 {
+		delete(summary, "added-delete-files")
+		delete(summary, "added-equality-delete-files")
+		delete(summary, "added-equality-deletes")
+	}
+	if parent != nil {
+		// Running totals are carried forward from the parent snapshot if
+		// it has them, and otherwise omitted since they can't be known
+		// without reading all of the manifests.
+		for _, total := range []struct {
+			key   string
+			added int64
+		}{
+			{"total-data-files", int64(len(dataFiles))},
+			{"total-records", addedRows},
+			{"total-files-size", addedBytes + addedDeleteBytes},
+			{"total-delete-files", int64(len(deleteFiles))},
+			{"total-equality-deletes", addedDeletes},
+			{"total-position-deletes", 0},
+		} {
+			if prev, err := strconv.ParseInt(parent.Summary[total.key], 10, 64); err == nil {
+				summary[total.key] = strconv.FormatInt(prev+total.added, 10)
+			} else {
+				delete(summary, total.key)
+			}
+		}
+	}
+
+	return &snapshot{
+		SnapshotID:       snapshotID,
+		ParentSnapshotID: parentID,
+		SequenceNumber:   sequenceNumber,
+		TimestampMs:      time.Now().UnixMilli(),
+		ManifestList:     manifestListPath,
+		Summary:          summary,
+		SchemaID:         &schema.SchemaID,
+	}, nil
+}
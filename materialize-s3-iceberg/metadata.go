@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file contains the subset of the Iceberg table metadata model that the
+// connector works with. See https://iceberg.apache.org/spec/#table-metadata.
+// Fields of the table metadata that aren't modeled here are retained as-is
+// when metadata is read and written, so that catalogs which require the
+// connector to write complete metadata files (AWS Glue) don't lose anything
+// written by other engines.
+
+const (
+	// checkpointsProperty is the table property holding the checkpoints of
+	// materializations which have appended files to the table, keyed by the
+	// materialization name.
+	checkpointsProperty = "flow_checkpoints_v1"
+
+	// nameMappingProperty provides the mapping of column names in data files
+	// to table field IDs. The parquet files written by the connector do not
+	// have field IDs, so readers need this mapping to be able to read them.
+	nameMappingProperty = "schema.name-mapping.default"
+
+	metadataPathProperty        = "write.metadata.path"
+	metadataPreviousMaxProperty = "write.metadata.previous-versions-max"
+
+	mainBranch = "main"
+)
+
+type icebergSchema struct {
+	Type               string         `json:"type"`
+	SchemaID           int            `json:"schema-id"`
+	IdentifierFieldIDs []int          `json:"identifier-field-ids,omitempty"`
+	Fields             []icebergField `json:"fields"`
+}
+
+type icebergField struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	// Type is the JSON representation of the field type, which is a string
+	// for primitive types and an object for nested types.
+	Type json.RawMessage `json:"type"`
+	Doc  string          `json:"doc,omitempty"`
+}
+
+func newIcebergField(id int, name string, required bool, typ icebergType) icebergField {
+	t, _ := json.Marshal(typ)
+	return icebergField{ID: id, Name: name, Required: required, Type: t}
+}
+
+// typeString returns the name of a primitive type, or the type of a nested
+// type (struct, list, or map).
+func (f icebergField) typeString() string {
+	var primitive string
+	if err := json.Unmarshal(f.Type, &primitive); err == nil {
+		return primitive
+	}
+
+	var nested struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(f.Type, &nested); err == nil {
+		return nested.Type
+	}
+
+	return string(f.Type)
+}
+
+// nameMapping returns the JSON name mapping for the top-level fields of the
+// schema.
+func (s icebergSchema) nameMapping() string {
+	type mappedField struct {
+		FieldID int      `json:"field-id"`
+		Names   []string `json:"names"`
+	}
+
+	var out = make([]mappedField, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		out = append(out, mappedField{FieldID: f.ID, Names: []string{f.Name}})
+	}
+
+	b, _ := json.Marshal(out)
+	return string(b)
+}
+
+type partitionSpec struct {
+	SpecID int               `json:"spec-id"`
+	Fields []json.RawMessage `json:"fields"`
+}
+
+type snapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         *int              `json:"schema-id,omitempty"`
+}
+
+type snapshotRef struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+type snapshotLogEntry struct {
+	SnapshotID  int64 `json:"snapshot-id"`
+	TimestampMs int64 `json:"timestamp-ms"`
+}
+
+type metadataLogEntry struct {
+	MetadataFile string `json:"metadata-file"`
+	TimestampMs  int64  `json:"timestamp-ms"`
+}
+
+type tableMetadata struct {
+	FormatVersion      int                    `json:"format-version"`
+	TableUUID          string                 `json:"table-uuid"`
+	Location           string                 `json:"location"`
+	LastSequenceNumber int64                  `json:"last-sequence-number"`
+	LastUpdatedMs      int64                  `json:"last-updated-ms"`
+	LastColumnID       int                    `json:"last-column-id"`
+	Schemas            []icebergSchema        `json:"schemas"`
+	CurrentSchemaID    int                    `json:"current-schema-id"`
+	PartitionSpecs     []partitionSpec        `json:"partition-specs"`
+	DefaultSpecID      int                    `json:"default-spec-id"`
+	LastPartitionID    int                    `json:"last-partition-id"`
+	Properties         map[string]string      `json:"properties,omitempty"`
+	CurrentSnapshotID  *int64                 `json:"current-snapshot-id,omitempty"`
+	Snapshots          []snapshot             `json:"snapshots,omitempty"`
+	SnapshotLog        []snapshotLogEntry     `json:"snapshot-log,omitempty"`
+	MetadataLog        []metadataLogEntry     `json:"metadata-log,omitempty"`
+	SortOrders         []json.RawMessage      `json:"sort-orders"`
+	DefaultSortOrderID int                    `json:"default-sort-order-id"`
+	Refs               map[string]snapshotRef `json:"refs,omitempty"`
+
+	// Fields of the metadata which are not otherwise modeled.
+	extra map[string]json.RawMessage
+}
+
+type tableMetadataFields tableMetadata
+
+// tableMetadataKeys are the JSON keys of the fields of tableMetadata.
+var tableMetadataKeys = []string{
+	"format-version", "table-uuid", "location", "last-sequence-number", "last-updated-ms",
+	"last-column-id", "schemas", "current-schema-id", "partition-specs", "default-spec-id",
+	"last-partition-id", "properties", "current-snapshot-id", "snapshots", "snapshot-log",
+	"metadata-log", "sort-orders", "default-sort-order-id", "refs",
+}
+
+func (m *tableMetadata) UnmarshalJSON(b []byte) error {
+	var fields tableMetadataFields
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal(b, &extra); err != nil {
+		return err
+	}
+	for _, k := range tableMetadataKeys {
+		delete(extra, k)
+	}
+
+	*m = tableMetadata(fields)
+	if len(extra) > 0 {
+		m.extra = extra
+	}
+
+	return nil
+}
+
+func (m tableMetadata) MarshalJSON() ([]byte, error) {
+	known, err := json.Marshal(tableMetadataFields(m))
+	if err != nil || len(m.extra) == 0 {
+		return known, err
+	}
+
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(known, &out); err != nil {
+		return nil, err
+	}
+	for k, v := range m.extra {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// newTableMetadata creates the metadata for a new unpartitioned table.
+func newTableMetadata(location string, schema icebergSchema, props map[string]string) *tableMetadata {
+	var lastColumnID int
+	for _, f := range schema.Fields {
+		lastColumnID = max(lastColumnID, f.ID)
+	}
+
+	return &tableMetadata{
+		FormatVersion:      2,
+		TableUUID:          uuid.NewString(),
+		Location:           location,
+		LastUpdatedMs:      time.Now().UnixMilli(),
+		LastColumnID:       lastColumnID,
+		Schemas:            []icebergSchema{schema},
+		CurrentSchemaID:    schema.SchemaID,
+		PartitionSpecs:     []partitionSpec{{SpecID: 0, Fields: []json.RawMessage{}}},
+		DefaultSpecID:      0,
+		LastPartitionID:    999,
+		Properties:         props,
+		SortOrders:         []json.RawMessage{json.RawMessage(`{"order-id":0,"fields":[]}`)},
+		DefaultSortOrderID: 0,
+	}
+}
+
+func (m *tableMetadata) currentSchema() (icebergSchema, error) {
+	for _, s := range m.Schemas {
+		if s.SchemaID == m.CurrentSchemaID {
+			return s, nil
+		}
+	}
+	return icebergSchema{}, fmt.Errorf("current schema %d not found in table metadata", m.CurrentSchemaID)
+}
+
+// currentSnapshot returns the current snapshot of the table, or nil if the
+// table has no snapshots.
+func (m *tableMetadata) currentSnapshot() *snapshot {
+	var id int64 = -1
+	if ref, ok := m.Refs[mainBranch]; ok {
+		id = ref.SnapshotID
+	} else if m.CurrentSnapshotID != nil {
+		id = *m.CurrentSnapshotID
+	}
+
+	for idx := range m.Snapshots {
+		if m.Snapshots[idx].SnapshotID == id {
+			return &m.Snapshots[idx]
+		}
+	}
+	return nil
+}
+
+func (m *tableMetadata) defaultSpec() (partitionSpec, error) {
+	for _, s := range m.PartitionSpecs {
+		if s.SpecID == m.DefaultSpecID {
+			return s, nil
+		}
+	}
+	return partitionSpec{}, fmt.Errorf("default partition spec %d not found in table metadata", m.DefaultSpecID)
+}
+
+// metadataDir is the location that metadata files for the table are written
+// to, without a trailing slash.
+func (m *tableMetadata) metadataDir() string {
+	if p, ok := m.Properties[metadataPathProperty]; ok {
+		return trimSlash(p)
+	}
+	return trimSlash(m.Location) + "/metadata"
+}
+
+// tableUpdate is a change to table metadata, in the form used by the Iceberg
+// REST catalog API. Only the updates used by the connector are supported.
+type tableUpdate struct {
+	Action       string            `json:"action"`
+	Schema       *icebergSchema    `json:"schema,omitempty"`
+	LastColumnID *int              `json:"last-column-id,omitempty"`
+	SchemaID     *int              `json:"schema-id,omitempty"`
+	Snapshot     *snapshot         `json:"snapshot,omitempty"`
+	RefName      string            `json:"ref-name,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	SnapshotID   *int64            `json:"snapshot-id,omitempty"`
+	Updates      map[string]string `json:"updates,omitempty"`
+}
+
+func addSchemaUpdate(schema icebergSchema, lastColumnID int) tableUpdate {
+	return tableUpdate{Action: "add-schema", Schema: &schema, LastColumnID: &lastColumnID}
+}
+
+func setCurrentSchemaUpdate(schemaID int) tableUpdate {
+	return tableUpdate{Action: "set-current-schema", SchemaID: &schemaID}
+}
+
+func addSnapshotUpdate(s snapshot) tableUpdate {
+	return tableUpdate{Action: "add-snapshot", Snapshot: &s}
+}
+
+func setBranchUpdate(branch string, snapshotID int64) tableUpdate {
+	return tableUpdate{Action: "set-snapshot-ref", RefName: branch, Type: "branch", SnapshotID: &snapshotID}
+}
+
+func setPropertiesUpdate(props map[string]string) tableUpdate {
+	return tableUpdate{Action: "set-properties", Updates: props}
+}
+
+// tableRequirement is a condition that table metadata must satisfy for a
+// commit of updates to it to succeed.
+type tableRequirement struct {
+	Type string
+	// For assert-table-uuid.
+	UUID string
+	// For assert-ref-snapshot-id, where a nil SnapshotID requires that the
+	// ref does not exist.
+	Ref        string
+	SnapshotID *int64
+	// For assert-current-schema-id.
+	CurrentSchemaID int
+	// For assert-last-assigned-field-id.
+	LastAssignedFieldID int
+}
+
+func (r tableRequirement) MarshalJSON() ([]byte, error) {
+	var out = map[string]any{"type": r.Type}
+
+	switch r.Type {
+	case "assert-table-uuid":
+		out["uuid"] = r.UUID
+	case "assert-ref-snapshot-id":
+		out["ref"] = r.Ref
+		out["snapshot-id"] = r.SnapshotID
+	case "assert-current-schema-id":
+		out["current-schema-id"] = r.CurrentSchemaID
+	case "assert-last-assigned-field-id":
+		out["last-assigned-field-id"] = r.LastAssignedFieldID
+	default:
+		return nil, fmt.Errorf("unknown table requirement type %q", r.Type)
+	}
+
+	return json.Marshal(out)
+}
+
+func (r *tableRequirement) UnmarshalJSON(b []byte) error {
+	var in struct {
+		Type                string `json:"type"`
+		UUID                string `json:"uuid"`
+		Ref                 string `json:"ref"`
+		SnapshotID          *int64 `json:"snapshot-id"`
+		CurrentSchemaID     int    `json:"current-schema-id"`
+		LastAssignedFieldID int    `json:"last-assigned-field-id"`
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*r = tableRequirement(in)
+	return nil
+}
+
+func assertTableUUID(uuid string) tableRequirement {
+	return tableRequirement{Type: "assert-table-uuid", UUID: uuid}
+}
+
+func assertRefSnapshotID(ref string, snapshotID *int64) tableRequirement {
+	return tableRequirement{Type: "assert-ref-snapshot-id", Ref: ref, SnapshotID: snapshotID}
+}
+
+func assertCurrentSchemaID(id int) tableRequirement {
+	return tableRequirement{Type: "assert-current-schema-id", CurrentSchemaID: id}
+}
+
+func assertLastAssignedFieldID(id int) tableRequirement {
+	return tableRequirement{Type: "assert-last-assigned-field-id", LastAssignedFieldID: id}
+}
+
+// check returns an error if the table metadata does not satisfy the
+// requirement.
+func (r tableRequirement) check(m *tableMetadata) error {
+	switch r.Type {
+	case "assert-table-uuid":
+		if m.TableUUID != r.UUID {
+			return fmt.Errorf("table UUID %q does not match expected %q", m.TableUUID, r.UUID)
+		}
+	case "assert-ref-snapshot-id":
+		ref, ok := m.Refs[r.Ref]
+		if r.Ref == mainBranch && !ok && m.CurrentSnapshotID != nil && *m.CurrentSnapshotID != -1 {
+			// Tables written with older versions of the spec may only have
+			// a current snapshot ID.
+			ref, ok = snapshotRef{SnapshotID: *m.CurrentSnapshotID, Type: "branch"}, true
+		}
+
+		if r.SnapshotID == nil && ok {
+			return fmt.Errorf("ref %q was created concurrently", r.Ref)
+		} else if r.SnapshotID != nil && !ok {
+			return fmt.Errorf("ref %q was removed concurrently", r.Ref)
+		} else if r.SnapshotID != nil && ref.SnapshotID != *r.SnapshotID {
+			return fmt.Errorf("ref %q has snapshot %d instead of expected %d", r.Ref, ref.SnapshotID, *r.SnapshotID)
+		}
+	case "assert-current-schema-id":
+		if m.CurrentSchemaID != r.CurrentSchemaID {
+			return fmt.Errorf("current schema %d does not match expected %d", m.CurrentSchemaID, r.CurrentSchemaID)
+		}
+	case "assert-last-assigned-field-id":
+		if m.LastColumnID != r.LastAssignedFieldID {
+			return fmt.Errorf("last assigned field ID %d does not match expected %d", m.LastColumnID, r.LastAssignedFieldID)
+		}
+	default:
+		return fmt.Errorf("unknown table requirement type %q", r.Type)
+	}
+
+	return nil
+}
+
+// applyUpdates returns a copy of the table metadata with the requirements
+// checked and the updates applied, as a catalog would when committing them.
+func applyUpdates(base *tableMetadata, reqs []tableRequirement, updates []tableUpdate) (*tableMetadata, error) {
+	for _, r := range reqs {
+		if err := r.check(base); err != nil {
+			return nil, fmt.Errorf("%w: %w", errCommitConflict, err)
+		}
+	}
+
+	var m = *base
+	m.Schemas = slices.Clone(m.Schemas)
+	m.Snapshots = slices.Clone(m.Snapshots)
+	m.SnapshotLog = slices.Clone(m.SnapshotLog)
+	m.Properties = maps.Clone(m.Properties)
+	m.Refs = maps.Clone(m.Refs)
+
+	var lastAddedSchemaID *int
+	for _, u := range updates {
+		switch u.Action {
+		case "add-schema":
+			if u.Schema == nil {
+				return nil, fmt.Errorf("add-schema update is missing schema")
+			}
+			m.Schemas = append(m.Schemas, *u.Schema)
+			lastAddedSchemaID = &u.Schema.SchemaID
+			for _, f := range u.Schema.Fields {
+				m.LastColumnID = max(m.LastColumnID, f.ID)
+			}
+			if u.LastColumnID != nil {
+				m.LastColumnID = max(m.LastColumnID, *u.LastColumnID)
+			}
+		case "set-current-schema":
+			if u.SchemaID == nil {
+				return nil, fmt.Errorf("set-current-schema update is missing schema-id")
+			} else if *u.SchemaID == -1 {
+				if lastAddedSchemaID == nil {
+					return nil, fmt.Errorf("set-current-schema of -1 without an added schema")
+				}
+				m.CurrentSchemaID = *lastAddedSchemaID
+			} else {
+				m.CurrentSchemaID = *u.SchemaID
+			}
+		case "add-snapshot":
+			if u.Snapshot == nil {
+				return nil, fmt.Errorf("add-snapshot update is missing snapshot")
+			}
+			m.Snapshots = append(m.Snapshots, *u.Snapshot)
+			m.LastSequenceNumber = max(m.LastSequenceNumber, u.Snapshot.SequenceNumber)
+		case "set-snapshot-ref":
+			if u.SnapshotID == nil {
+				return nil, fmt.Errorf("set-snapshot-ref update is missing snapshot-id")
+			}
+			if m.Refs == nil {
+				m.Refs = make(map[string]snapshotRef)
+			}
+			m.Refs[u.RefName] = snapshotRef{SnapshotID: *u.SnapshotID, Type: u.Type}
+			if u.RefName == mainBranch {
+				var id = *u.SnapshotID
+				m.CurrentSnapshotID = &id
+				m.SnapshotLog = append(m.SnapshotLog, snapshotLogEntry{SnapshotID: id, TimestampMs: time.Now().UnixMilli()})
+			}
+		case "set-properties":
+			if m.Properties == nil {
+				m.Properties = make(map[string]string)
+			}
+			maps.Copy(m.Properties, u.Updates)
+		default:
+			return nil, fmt.Errorf("unsupported table update action %q", u.Action)
+		}
+	}
+
+	m.LastUpdatedMs = time.Now().UnixMilli()
+
+	return &m, nil
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/file"
+	"github.com/apache/arrow/go/v17/parquet/metadata"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+	enc "github.com/estuary/connectors/materialize-boilerplate/stream-encode"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// loadKeys is a set of keys to load documents for. The keys are encoded to
+// parquet and read back in the same way as the key columns of data files, so
+// that they can be compared regardless of the types of the key columns.
+type loadKeys struct {
+	keys map[string]struct{}
+	// bounds are the minimum and maximum values of the first key column for
+	// each row group of the encoded keys, which are used to skip row groups
+	// of data files that can't contain any of the keys.
+	bounds []metadata.TypedStatistics
+}
+
+func newLoadKeys(ctx context.Context, keySchema enc.ParquetSchema, keys [][]any) (*loadKeys, error) {
+	var buf bytes.Buffer
+	encoder := enc.NewParquetEncoder(&nopWriteCloser{&buf}, keySchema)
+	for _, k := range keys {
+		if err := encoder.Encode(k); err != nil {
+			return nil, fmt.Errorf("encoding key: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("closing key encoder: %w", err)
+	}
+
+	var columns = make([]string, 0, len(keySchema))
+	for _, s := range keySchema {
+		columns = append(columns, s.Name)
+	}
+
+	var out = &loadKeys{keys: make(map[string]struct{}, len(keys))}
+	if err := readParquetColumns(ctx, bytes.NewReader(buf.Bytes()), columns, nil, func(cols []arrow.Array, rows int) error {
+		for row := 0; row < rows; row++ {
+			out.keys[keyString(cols, row)] = struct{}{}
+		}
+		return nil
+	}, func(rg *metadata.RowGroupMetaData, keyColumn int) error {
+		stats, err := keyStatistics(rg, keyColumn)
+		if err != nil {
+			return err
+		}
+		out.bounds = append(out.bounds, stats)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("reading encoded keys: %w", err)
+	}
+
+	return out, nil
+}
+
+// mayContain returns false if the row group definitely does not contain any of
+// the keys.
+func (k *loadKeys) mayContain(rg *metadata.RowGroupMetaData, keyColumn int) bool {
+	stats, err := keyStatistics(rg, keyColumn)
+	if err != nil || stats == nil {
+		return true
+	}
+
+	for _, b := range k.bounds {
+		if b == nil || statisticsOverlap(b, stats) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mayContainFile returns false if the bounds of the first key column of a file
+// show that it does not contain any of the keys.
+func (k *loadKeys) mayContainFile(f dataFile, keyFieldID int) bool {
+	lower, upper := f.lowerBounds[keyFieldID], f.upperBounds[keyFieldID]
+	if lower == nil || upper == nil {
+		return true
+	}
+
+	for _, b := range k.bounds {
+		if b == nil || boundsOverlap(b, lower, upper) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fileColumnBounds returns the lower and upper bounds of the values of a
+// column of a parquet file from the statistics of its row groups, in Iceberg's
+// single-value serialization. The bounds are nil if any row group does not
+// have statistics for the column.
+func fileColumnBounds(meta *metadata.FileMetaData, column string) ([]byte, []byte, error) {
+	idx := meta.Schema.ColumnIndexByName(column)
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("file has no column %q", column)
+	}
+
+	var merged metadata.TypedStatistics
+	for rgIdx := range meta.RowGroups {
+		stats, err := keyStatistics(meta.RowGroup(rgIdx), idx)
+		if err != nil {
+			return nil, nil, err
+		} else if stats == nil {
+			return nil, nil, nil
+		} else if merged == nil {
+			merged = stats
+		} else {
+			merged.Merge(stats)
+		}
+	}
+
+	switch merged.(type) {
+	case *metadata.Int32Statistics, *metadata.Int64Statistics, *metadata.Float64Statistics, *metadata.ByteArrayStatistics:
+		// The plain encoding of these types is the same as the single-value
+		// serialization of the Iceberg types they are written for.
+		return merged.EncodeMin(), merged.EncodeMax(), nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// boundsOverlap returns true if the range of values described by the
+// statistics may overlap with the range of the serialized lower and upper
+// bounds. Bounds which are not of the same type as the statistics are assumed
+// to overlap.
+func boundsOverlap(s metadata.TypedStatistics, lower, upper []byte) bool {
+	switch s := s.(type) {
+	case *metadata.Int32Statistics:
+		if len(lower) == 4 && len(upper) == 4 {
+			lo, hi := int32(binary.LittleEndian.Uint32(lower)), int32(binary.LittleEndian.Uint32(upper))
+			return s.Min() <= hi && lo <= s.Max()
+		}
+	case *metadata.Int64Statistics:
+		if len(lower) == 8 && len(upper) == 8 {
+			lo, hi := int64(binary.LittleEndian.Uint64(lower)), int64(binary.LittleEndian.Uint64(upper))
+			return s.Min() <= hi && lo <= s.Max()
+		}
+	case *metadata.Float64Statistics:
+		if len(lower) == 8 && len(upper) == 8 {
+			lo, hi := math.Float64frombits(binary.LittleEndian.Uint64(lower)), math.Float64frombits(binary.LittleEndian.Uint64(upper))
+			return s.Min() <= hi && lo <= s.Max()
+		}
+	case *metadata.ByteArrayStatistics:
+		return bytes.Compare(s.Min(), upper) <= 0 && bytes.Compare(lower, s.Max()) <= 0
+	}
+
+	return true
+}
+
+// keyStatistics returns the statistics of the first key column of a row group,
+// if it has them.
+func keyStatistics(rg *metadata.RowGroupMetaData, keyColumn int) (metadata.TypedStatistics, error) {
+	col, err := rg.ColumnChunk(keyColumn)
+	if err != nil {
+		return nil, err
+	} else if ok, err := col.StatsSet(); err != nil || !ok {
+		return nil, err
+	}
+
+	stats, err := col.Statistics()
+	if err != nil || stats == nil || !stats.HasMinMax() {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// statisticsOverlap returns true if the ranges of values described by a and b
+// may overlap. Only the physical types used for keys are compared, and
+// otherwise they are assumed to overlap.
+func statisticsOverlap(a, b metadata.TypedStatistics) bool {
+	overlap := func(cmp int, cmp2 int) bool { return cmp <= 0 && cmp2 <= 0 }
+
+	switch a := a.(type) {
+	case *metadata.Int32Statistics:
+		if b, ok := b.(*metadata.Int32Statistics); ok {
+			return overlap(compare(a.Min(), b.Max()), compare(b.Min(), a.Max()))
+		}
+	case *metadata.Int64Statistics:
+		if b, ok := b.(*metadata.Int64Statistics); ok {
+			return overlap(compare(a.Min(), b.Max()), compare(b.Min(), a.Max()))
+		}
+	case *metadata.Float64Statistics:
+		if b, ok := b.(*metadata.Float64Statistics); ok {
+			return overlap(compare(a.Min(), b.Max()), compare(b.Min(), a.Max()))
+		}
+	case *metadata.ByteArrayStatistics:
+		if b, ok := b.(*metadata.ByteArrayStatistics); ok {
+			return overlap(bytes.Compare(a.Min(), b.Max()), bytes.Compare(b.Min(), a.Max()))
+		}
+	}
+
+	return true
+}
+
+func compare[T int32 | int64 | float64](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+type nopWriteCloser struct {
+	w io.Writer
+}
+
+func (w *nopWriteCloser) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func (w *nopWriteCloser) Close() error {
+	return nil
+}
+
+// keyString is a canonical representation of a row of key column values.
+func keyString(cols []arrow.Array, row int) string {
+	var vals = make([]string, 0, len(cols))
+	for _, c := range cols {
+		vals = append(vals, c.ValueStr(row))
+	}
+
+	b, _ := json.Marshal(vals)
+	return string(b)
+}
+
+// readParquetColumns reads the named columns of a parquet file, calling fn
+// with batches of rows. Row groups are skipped if include returns false for
+// them, and onRowGroup is called with the metadata of each row group that is
+// read. Both are also given the index of the first named column, which must be
+// the first key column.
+func readParquetColumns(
+	ctx context.Context,
+	r parquet.ReaderAtSeeker,
+	columns []string,
+	include func(rg *metadata.RowGroupMetaData, keyColumn int) bool,
+	fn func(cols []arrow.Array, rows int) error,
+	onRowGroup func(rg *metadata.RowGroupMetaData, keyColumn int) error,
+) error {
+	pr, err := file.NewParquetReader(r)
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	var indices = make([]int, 0, len(columns))
+	for _, c := range columns {
+		idx := pr.MetaData().Schema.ColumnIndexByName(c)
+		if idx < 0 {
+			return fmt.Errorf("file has no column %q", c)
+		}
+		indices = append(indices, idx)
+	}
+
+	fr, err := pqarrow.NewFileReader(pr, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+
+	for rgIdx := 0; rgIdx < pr.NumRowGroups(); rgIdx++ {
+		rg := pr.MetaData().RowGroup(rgIdx)
+		if include != nil && !include(rg, indices[0]) {
+			continue
+		} else if onRowGroup != nil {
+			if err := onRowGroup(rg, indices[0]); err != nil {
+				return err
+			}
+		}
+
+		if err := func() error {
+			tbl, err := fr.ReadRowGroups(ctx, indices, []int{rgIdx})
+			if err != nil {
+				return err
+			}
+			defer tbl.Release()
+
+			// The columns of the table are re-ordered to match the
+			// requested column order, since the table has them in file
+			// order.
+			var order = make([]int, 0, len(columns))
+			for _, c := range columns {
+				order = append(order, tbl.Schema().FieldIndices(c)[0])
+			}
+
+			tr := array.NewTableReader(tbl, 0)
+			defer tr.Release()
+			for tr.Next() {
+				rec := tr.Record()
+				var cols = make([]arrow.Array, 0, len(order))
+				for _, idx := range order {
+					cols = append(cols, rec.Column(idx))
+				}
+				if err := fn(cols, int(rec.NumRows())); err != nil {
+					return err
+				}
+			}
+
+			return tr.Err()
+		}(); err != nil {
+			return fmt.Errorf("reading row group %d: %w", rgIdx, err)
+		}
+	}
+
+	return nil
+}
+
+// loadDocuments returns the current documents of the table for the keys by
+// reading the current snapshot of the table. Only rows that have not been
+// deleted by an equality delete file with a later sequence number are
+// current.
+func (c *catalog) loadDocuments(ctx context.Context, tablePath []string, keySchema enc.ParquetSchema, docField string, keys [][]any) ([]json.RawMessage, error) {
+	fqn := pathToFQN(tablePath)
+
+	t, err := c.catalog.loadTable(ctx, tablePath[0], tablePath[1])
+	if err != nil {
+		return nil, err
+	}
+
+	snap := t.metadata.currentSnapshot()
+	if snap == nil {
+		return nil, nil // the table is empty
+	}
+
+	schema, err := t.metadata.currentSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	var keyColumns []string
+	var keyFieldIDs []int
+	for _, s := range keySchema {
+		idx := slices.IndexFunc(schema.Fields, func(f icebergField) bool { return f.Name == s.Name })
+		if idx == -1 {
+			return nil, fmt.Errorf("table %q has no key column %q", fqn, s.Name)
+		}
+		keyColumns = append(keyColumns, s.Name)
+		keyFieldIDs = append(keyFieldIDs, schema.Fields[idx].ID)
+	}
+
+	lk, err := newLoadKeys(ctx, keySchema, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := readManifestList(ctx, c.io, snap.ManifestList)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var dataFiles, deleteFiles []manifestEntry
+	var pruned int
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(10)
+	for _, mf := range manifests {
+		group.Go(func() error {
+			entries, err := readManifestEntries(groupCtx, c.io, mf)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, e := range entries {
+				if e.status == entryStatusDeleted {
+					continue
+				} else if !lk.mayContainFile(e.file, keyFieldIDs[0]) {
+					pruned++
+					continue
+				}
+
+				switch e.file.content {
+				case fileContentData:
+					dataFiles = append(dataFiles, e)
+				case fileContentEqualityDelete:
+					if !slices.Equal(e.file.equalityIDs, keyFieldIDs) {
+						return fmt.Errorf("equality delete file %q has equality field IDs %v instead of %v", e.file.path, e.file.equalityIDs, keyFieldIDs)
+					}
+					deleteFiles = append(deleteFiles, e)
+				default:
+					return fmt.Errorf("file %q has unsupported content type %d", e.file.path, e.file.content)
+				}
+			}
+
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, fmt.Errorf("reading manifests of table %q: %w", fqn, err)
+	}
+
+	// The highest sequence number of an equality delete for each key.
+	var deletedAt = make(map[string]int64)
+	if err := c.scanFiles(ctx, deleteFiles, keyColumns, lk, func(e manifestEntry, cols []arrow.Array, rows int) {
+		for row := 0; row < rows; row++ {
+			key := keyString(cols, row)
+			if _, ok := lk.keys[key]; ok {
+				deletedAt[key] = max(deletedAt[key], e.sequenceNumber)
+			}
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("reading equality deletes of table %q: %w", fqn, err)
+	}
+
+	type found struct {
+		sequenceNumber int64
+		doc            json.RawMessage
+	}
+	var docs = make(map[string]found)
+	if err := c.scanFiles(ctx, dataFiles, append(keyColumns, docField), lk, func(e manifestEntry, cols []arrow.Array, rows int) {
+		for row := 0; row < rows; row++ {
+			key := keyString(cols[:len(keyColumns)], row)
+			if _, ok := lk.keys[key]; !ok {
+				continue
+			} else if deletedAt[key] > e.sequenceNumber {
+				continue
+			} else if prev, ok := docs[key]; ok && prev.sequenceNumber >= e.sequenceNumber {
+				continue
+			}
+			docs[key] = found{
+				sequenceNumber: e.sequenceNumber,
+				doc:            json.RawMessage(cols[len(keyColumns)].ValueStr(row)),
+			}
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("reading data files of table %q: %w", fqn, err)
+	}
+
+	log.WithFields(log.Fields{
+		"table":       fqn,
+		"keys":        len(lk.keys),
+		"found":       len(docs),
+		"dataFiles":   len(dataFiles),
+		"deleteFiles": len(deleteFiles),
+		"prunedFiles": pruned,
+	}).Debug("loaded documents")
+
+	var out = make([]json.RawMessage, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.doc)
+	}
+
+	return out, nil
+}
+
+// scanFiles reads the columns of files concurrently. Calls to fn are
+// serialized.
+func (c *catalog) scanFiles(
+	ctx context.Context,
+	files []manifestEntry,
+	columns []string,
+	lk *loadKeys,
+	fn func(e manifestEntry, cols []arrow.Array, rows int),
+) error {
+	var mu sync.Mutex
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(5)
+	for _, e := range files {
+		group.Go(func() error {
+			r := &fileReaderAt{ctx: groupCtx, fio: c.io, uri: e.file.path, size: e.file.sizeBytes}
+			if err := readParquetColumns(groupCtx, r, columns, lk.mayContain, func(cols []arrow.Array, rows int) error {
+				mu.Lock()
+				defer mu.Unlock()
+				fn(e, cols, rows)
+				return nil
+			}, nil); err != nil {
+				return fmt.Errorf("reading %q: %w", e.file.path, err)
+			}
+			return nil
+		})
+	}
+
+	return group.Wait()
+}
@@ -70,6 +70,21 @@ type binding struct {
 	includeDoc       bool
 	stateKey         string
 	catalogTablePath string
+	deltaUpdates     bool
+	// keySchema is the schema of the key columns, which are the first columns
+	// of pqSchema. It is also the schema of equality delete files.
+	keySchema enc.ParquetSchema
+	// docField is the column of the root document, which is required for
+	// bindings using standard updates.
+	docField string
+}
+
+func (b binding) keyColumns() []string {
+	var out = make([]string, 0, len(b.keySchema))
+	for _, s := range b.keySchema {
+		out = append(out, s.Name)
+	}
+	return out
 }
 
 type connectorState struct {
@@ -86,6 +101,10 @@ type bindingState struct {
 	PreviousCheckpoint string   `json:"previousCheckpoint,omitempty"`
 	CurrentCheckpoint  string   `json:"currentCheckpoint,omitempty"`
 	FileKeys           []string `json:"fileKeys"`
+	// DeleteFileKeys are equality delete files for the keys of stored
+	// documents that already existed, which are appended to the table along
+	// with FileKeys for bindings using standard updates.
+	DeleteFileKeys []string `json:"deleteFileKeys,omitempty"`
 }
 
 func hashCheckpoint(cp *protocol.Checkpoint) (string, error) {
@@ -105,6 +124,7 @@ type transactor struct {
 	prefix          string
 	store           *filesink.S3Store
 	state           connectorState
+	hardDelete      bool
 }
 
 func (t *transactor) UnmarshalState(state json.RawMessage) error {
@@ -127,25 +147,51 @@ func (t *transactor) UnmarshalState(state json.RawMessage) error {
 }
 
 func (t *transactor) Load(it *m.LoadIterator, loaded func(int, json.RawMessage) error) error {
+	var keys = make([][][]any, len(t.bindings))
 	for it.Next() {
-		panic("driver only supports delta updates")
+		if t.bindings[it.Binding].deltaUpdates {
+			panic("load for delta updates binding")
+		}
+		keys[it.Binding] = append(keys[it.Binding], it.Key.ToInterface())
+	}
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("load iterator error: %w", err)
+	}
+
+	// Documents are loaded from the current snapshot of each table, which must
+	// include the files appended by the prior transaction.
+	it.WaitForAcknowledged()
+
+	for idx, b := range t.bindings {
+		if len(keys[idx]) == 0 {
+			continue
+		}
+
+		docs, err := t.catalog.loadDocuments(it.Context(), b.path, b.keySchema, b.docField, keys[idx])
+		if err != nil {
+			return fmt.Errorf("loading documents for %s: %w", pathToFQN(b.path), err)
+		}
+		for _, doc := range docs {
+			if err := loaded(idx, doc); err != nil {
+				return err
+			}
+		}
 	}
+
 	return nil
 }
 
 func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 	var ctx = it.Context()
-	var encoder *enc.ParquetEncoder
+	var encoder, deleteEncoder *enc.ParquetEncoder
 	var group errgroup.Group
 	var states = t.state.BindingStates
 
-	startFile := func(b binding) {
-		// Start uploading a new file, either because the binding changed or because the prior file
-		// got sufficiently large.
+	upload := func(b binding, fileKeys *[]string, pqSchema enc.ParquetSchema) *enc.ParquetEncoder {
 		r, w := io.Pipe()
 
 		key, s3Path := filePath(b.catalogTablePath)
-		states[b.stateKey].FileKeys = append(states[b.stateKey].FileKeys, s3Path)
+		*fileKeys = append(*fileKeys, s3Path)
 
 		group.Go(func() error {
 			ll := log.WithFields(log.Fields{
@@ -163,19 +209,39 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			return nil
 		})
 
-		encoder = enc.NewParquetEncoder(w, b.pqSchema, enc.WithParquetCompression(enc.Snappy))
+		return enc.NewParquetEncoder(w, pqSchema, enc.WithParquetCompression(enc.Snappy))
+	}
+
+	startFile := func(b binding) {
+		// Start uploading a new file, either because the binding changed or because the prior file
+		// got sufficiently large.
+		encoder = upload(b, &states[b.stateKey].FileKeys, b.pqSchema)
+	}
+
+	startDeleteFile := func(b binding) {
+		// Equality delete files are started as needed, and finished along
+		// with the data file they accompany.
+		deleteEncoder = upload(b, &states[b.stateKey].DeleteFileKeys, b.keySchema)
 	}
 
 	finishFile := func() error {
-		if encoder == nil {
+		if encoder == nil && deleteEncoder == nil {
 			return nil
-		} else if err := encoder.Close(); err != nil {
-			return fmt.Errorf("closing encoder: %w", err)
-		} else if err := group.Wait(); err != nil {
+		} else if encoder != nil {
+			if err := encoder.Close(); err != nil {
+				return fmt.Errorf("closing encoder: %w", err)
+			}
+		}
+		if deleteEncoder != nil {
+			if err := deleteEncoder.Close(); err != nil {
+				return fmt.Errorf("closing delete encoder: %w", err)
+			}
+		}
+		if err := group.Wait(); err != nil {
 			return fmt.Errorf("group.Wait(): %w", err)
 		}
 
-		encoder = nil
+		encoder, deleteEncoder = nil, nil
 		return nil
 	}
 
@@ -190,6 +256,20 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		}
 		lastBinding = it.Binding
 
+		if !b.deltaUpdates && t.hardDelete && it.Delete {
+			// Deleted documents are removed from the table with an equality
+			// delete, and no new row is written for them.
+			if !it.Exists {
+				continue
+			} else if deleteEncoder == nil {
+				startDeleteFile(b)
+			}
+			if err := deleteEncoder.Encode(it.Key.ToInterface()); err != nil {
+				return nil, fmt.Errorf("encoding delete: %w", err)
+			}
+			continue
+		}
+
 		if encoder == nil {
 			startFile(b)
 		}
@@ -205,6 +285,17 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			return nil, fmt.Errorf("encoding row: %w", err)
 		}
 
+		if !b.deltaUpdates && it.Exists {
+			// Rows for keys that already exist in the table are replaced by
+			// deleting them with an equality delete in the same commit.
+			if deleteEncoder == nil {
+				startDeleteFile(b)
+			}
+			if err := deleteEncoder.Encode(it.Key.ToInterface()); err != nil {
+				return nil, fmt.Errorf("encoding delete: %w", err)
+			}
+		}
+
 		if encoder.Written() > fileSizeLimit {
 			if err := finishFile(); err != nil {
 				return nil, fmt.Errorf("finishFile on file size limit: %w", err)
@@ -232,7 +323,7 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		for _, b := range t.bindings {
 			bindingState := t.state.BindingStates[b.stateKey]
 
-			if len(bindingState.FileKeys) == 0 {
+			if len(bindingState.FileKeys) == 0 && len(bindingState.DeleteFileKeys) == 0 {
 				continue // no data for this binding
 			}
 
@@ -252,7 +343,7 @@ func (t *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 	for _, b := range t.bindings {
 		bindingState := t.state.BindingStates[b.stateKey]
 
-		if len(bindingState.FileKeys) == 0 {
+		if len(bindingState.FileKeys) == 0 && len(bindingState.DeleteFileKeys) == 0 {
 			continue // no data for this binding
 		}
 
@@ -265,12 +356,23 @@ func (t *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 		ll.Info("starting appendFiles for table")
 		appendCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 		defer cancel()
-		if err := t.catalog.appendFiles(appendCtx, t.materialization, b.path, bindingState.FileKeys, bindingState.PreviousCheckpoint, bindingState.CurrentCheckpoint); err != nil {
+		if err := t.catalog.appendFiles(
+			appendCtx,
+			t.materialization,
+			b.path,
+			b.keyColumns(),
+			bindingState.FileKeys,
+			bindingState.DeleteFileKeys,
+			bindingState.PreviousCheckpoint,
+			bindingState.CurrentCheckpoint,
+		); err != nil {
 			return nil, fmt.Errorf("appendFiles for %s: %w", b.path, err)
 		}
 		ll.Info("finished appendFiles for table")
 
-		bindingState.FileKeys = nil // reset for next txn
+		// Reset for next txn.
+		bindingState.FileKeys = nil
+		bindingState.DeleteFileKeys = nil
 	}
 
 	checkpointJSON, err := json.Marshal(t.state)
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
+	payloadtemplate "github.com/estuary/connectors/go/payload-template"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
@@ -44,15 +46,26 @@ func (c config) buildAPI() (*SlackAPI, error) {
 type resource struct {
 	Channel      string            `json:"channel" jsonschema:"title=Channel,description=The name of the channel to post messages to (or a raw channel ID like \"id:C123456\")"`
 	SenderConfig SlackSenderConfig `json:"sender_config" jsonschema:"title=Configure Appearance"`
+	Template     string            `json:"template,omitempty" jsonschema:"title=Message Template,description=Optional Go text/template which renders each message as a JSON object with 'text' and/or 'blocks' properties from the fields of the document. If set then the 'text' and 'blocks' fields are not used." jsonschema_extras:"multiline=true"`
 }
 
 func (r resource) Validate() error {
 	if r.Channel == "" {
 		return fmt.Errorf("missing required channel name/id")
+	} else if _, err := r.parseTemplate(); err != nil {
+		return fmt.Errorf("template: %w", err)
 	}
 	return nil
 }
 
+// parseTemplate returns the parsed message template, or nil if there isn't one.
+func (r resource) parseTemplate() (*payloadtemplate.Template, error) {
+	if r.Template == "" {
+		return nil, nil
+	}
+	return payloadtemplate.Parse(r.Template)
+}
+
 func (driver) Spec(ctx context.Context, req *pm.Request_Spec) (*pm.Response_Spec, error) {
 	endpointSchema, err := schemagen.GenerateSchema("Slack Connection", &config{}).MarshalJSON()
 	if err != nil {
@@ -85,6 +98,18 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 			return nil, fmt.Errorf("parsing resource config: %w", err)
 		}
 
+		tmpl, err := res.parseTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		var templateFields []string
+		if tmpl != nil {
+			if err := tmpl.Validate(binding.Collection.Projections); err != nil {
+				return nil, fmt.Errorf("validating template for %s: %w", binding.Collection.Name, err)
+			}
+			templateFields = tmpl.Fields()
+		}
+
 		var constraints = make(map[string]*pm.Response_Validated_Constraint)
 		for _, projection := range binding.Collection.Projections {
 			var constraint pm.Response_Validated_Constraint
@@ -95,6 +120,16 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 					Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
 					Reason: "The Slack materialization requires a message timestamp",
 				}
+			case slices.Contains(templateFields, projection.Field):
+				constraint = pm.Response_Validated_Constraint{
+					Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
+					Reason: "This field is referenced by the message template",
+				}
+			case tmpl != nil && !projection.IsPrimaryKey:
+				constraint = pm.Response_Validated_Constraint{
+					Type:   pm.Response_Validated_Constraint_FIELD_OPTIONAL,
+					Reason: "Fields not referenced by the message template will be ignored",
+				}
 			case projection.Field == "text":
 				constraint = pm.Response_Validated_Constraint{
 					Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
@@ -119,7 +154,7 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 			constraints[projection.Field] = &constraint
 		}
 
-		if constraints["text"] == nil {
+		if constraints["text"] == nil && tmpl == nil {
 			return nil, fmt.Errorf("'text' field required")
 		}
 		if constraints["ts"] == nil {
@@ -185,10 +220,15 @@ func (d driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boil
 		if err := json.Unmarshal(b.ResourceConfigJson, &res); err != nil {
 			return nil, nil, nil, fmt.Errorf("unable to parse resource config: %w", err)
 		}
+		tmpl, err := res.parseTemplate()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("parsing template: %w", err)
+		}
 		bindings = append(bindings, &binding{
 			resource:   res,
 			collection: string(b.Collection.Name),
 			spec:       b,
+			template:   tmpl,
 		})
 	}
 
@@ -209,6 +249,44 @@ type binding struct {
 	spec       *pf.MaterializationSpec_Binding
 	resource   resource
 	collection string
+	// template renders messages if set, instead of using the 'text' and
+	// 'blocks' fields.
+	template *payloadtemplate.Template
+}
+
+// templateMessage is the message rendered by a binding's template.
+type templateMessage struct {
+	Text   string       `json:"text"`
+	Blocks slack.Blocks `json:"blocks"`
+}
+
+func renderMessage(b *binding, keys, values tuple.Tuple) (string, []slack.Block, error) {
+	var fields = append(append([]string{}, b.spec.FieldSelection.Keys...), b.spec.FieldSelection.Values...)
+	var all = make([]any, 0, len(fields))
+	for _, v := range keys {
+		all = append(all, v)
+	}
+	for _, v := range values {
+		all = append(all, v)
+	}
+
+	data, err := payloadtemplate.Data(fields, all)
+	if err != nil {
+		return "", nil, err
+	}
+	rendered, err := b.template.Render(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var msg templateMessage
+	if err := json.Unmarshal(rendered, &msg); err != nil {
+		return "", nil, fmt.Errorf("invalid rendered message %s: %w", string(rendered), err)
+	} else if msg.Text == "" && len(msg.Blocks.BlockSet) == 0 {
+		return "", nil, fmt.Errorf("rendered message %s must have 'text' or 'blocks'", string(rendered))
+	}
+
+	return msg.Text, msg.Blocks.BlockSet, nil
 }
 
 func buildDocument(b *binding, keys, values tuple.Tuple) map[string]interface{} {
@@ -252,7 +330,7 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			return nil, fmt.Errorf("missing timestamp")
 		}
 
-		if !textOk {
+		if b.template == nil && !textOk {
 			return nil, fmt.Errorf("missing text")
 		}
 
@@ -264,7 +342,11 @@ func (t *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 
 		var blockSet []slack.Block
 
-		if parsed["blocks"] != nil {
+		if b.template != nil {
+			if text, blockSet, err = renderMessage(b, it.Key, it.Values); err != nil {
+				return nil, err
+			}
+		} else if parsed["blocks"] != nil {
 			var blocks = parsed["blocks"].(json.RawMessage)
 			var blocksParsed slack.Blocks
 
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/estuary/flow/go/protocols/fdb/tuple"
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMessage(t *testing.T) {
+	newBinding := func(text string) *binding {
+		res := resource{Channel: "general", Template: text}
+		tmpl, err := res.parseTemplate()
+		require.NoError(t, err)
+
+		return &binding{
+			resource: res,
+			spec: &pf.MaterializationSpec_Binding{
+				FieldSelection: pf.FieldSelection{
+					Keys:   []string{"id"},
+					Values: []string{"ts", "doc"},
+				},
+			},
+			template: tmpl,
+		}
+	}
+
+	keys := tuple.Tuple{"abc"}
+	values := tuple.Tuple{"2024-01-02T03:04:05Z", []byte(`{"name":"Widget","count":3}`)}
+
+	text, blocks, err := renderMessage(newBinding(`{"text": {{ printf "%s: %v" .doc.name .doc.count | json }}}`), keys, values)
+	require.NoError(t, err)
+	require.Equal(t, "Widget: 3", text)
+	require.Empty(t, blocks)
+
+	text, blocks, err = renderMessage(newBinding(`{"blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": {{ printf "*%s* (%s)" .doc.name .id | json }}}}]}`), keys, values)
+	require.NoError(t, err)
+	require.Equal(t, "", text)
+	require.Len(t, blocks, 1)
+	section, ok := blocks[0].(*slack.SectionBlock)
+	require.True(t, ok)
+	require.Equal(t, "*Widget* (abc)", section.Text.Text)
+
+	_, _, err = renderMessage(newBinding(`{"other": {{ json .id }}}`), keys, values)
+	require.ErrorContains(t, err, "must have 'text' or 'blocks'")
+
+	_, _, err = renderMessage(newBinding(`[{{ json .id }}]`), keys, values)
+	require.ErrorContains(t, err, "invalid rendered message")
+}
+
+func TestValidateTemplate(t *testing.T) {
+	validate := func(res resource) (*pm.Response_Validated, error) {
+		resJSON, err := json.Marshal(res)
+		require.NoError(t, err)
+
+		return driver{}.Validate(context.Background(), &pm.Request_Validate{
+			ConfigJson: json.RawMessage(`{"credentials":{"client_id":"id","client_secret":"secret","access_token":"token"}}`),
+			Bindings: []*pm.Request_Validate_Binding{{
+				ResourceConfigJson: resJSON,
+				Collection: pf.CollectionSpec{
+					Name: "acme/messages",
+					Projections: []pf.Projection{
+						{Field: "id", IsPrimaryKey: true},
+						{Field: "ts"},
+						{Field: "summary"},
+						{Field: "other"},
+					},
+				},
+			}},
+		})
+	}
+
+	// Without a template the collection must have a 'text' field.
+	_, err := validate(resource{Channel: "general"})
+	require.ErrorContains(t, err, "'text' field required")
+
+	got, err := validate(resource{Channel: "general", Template: `{"text": {{ json .summary }}}`})
+	require.NoError(t, err)
+	constraints := got.Bindings[0].Constraints
+	require.Equal(t, pm.Response_Validated_Constraint_FIELD_REQUIRED, constraints["ts"].Type)
+	require.Equal(t, pm.Response_Validated_Constraint_FIELD_REQUIRED, constraints["summary"].Type)
+	require.Equal(t, pm.Response_Validated_Constraint_FIELD_OPTIONAL, constraints["other"].Type)
+
+	_, err = validate(resource{Channel: "general", Template: `{"text": {{ json .missing }}}`})
+	require.ErrorContains(t, err, "validating template for acme/messages")
+
+	_, err = validate(resource{Channel: "general", Template: `{"text": {{ json .summary }`})
+	require.ErrorContains(t, err, "template")
+}
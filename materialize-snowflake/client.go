@@ -105,6 +105,18 @@ func (c *client) AlterTable(ctx context.Context, ta sql.TableAlter) (string, boi
 		}
 	}
 
+	if ta.LayoutChanged {
+		if ta.LayoutColumns == nil || len(ta.LayoutColumns.ClusterBy) == 0 {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CLUSTERING KEY;", ta.Identifier))
+		} else {
+			var cols []string
+			for _, col := range ta.LayoutColumns.ClusterBy {
+				cols = append(cols, col.Identifier)
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s CLUSTER BY (%s);", ta.Identifier, strings.Join(cols, ", ")))
+		}
+	}
+
 	return strings.Join(stmts, "\n"), func(ctx context.Context) error {
 		for _, stmt := range stmts {
 			if _, err := c.db.ExecContext(ctx, stmt); err != nil {
@@ -27,6 +27,7 @@ type config struct {
 	Role          string                     `json:"role,omitempty" jsonschema:"title=Role,description=The user role used to perform actions." jsonschema_extras:"order=6"`
 	Account       string                     `json:"account,omitempty" jsonschema:"title=Account,description=Optional Snowflake account identifier." jsonschema_extras:"order=7"`
 	HardDelete    bool                       `json:"hardDelete,omitempty" jsonschema:"title=Hard Delete,description=If this option is enabled items deleted in the source will also be deleted from the destination. By default is disabled and _meta/op in the destination will signify whether rows have been deleted (soft-delete).,default=false" jsonschema_extras:"order=8"`
+	Streaming     bool                       `json:"snowpipe_streaming,omitempty" jsonschema:"title=Snowpipe Streaming,description=Use Snowpipe Streaming to append rows of delta update bindings directly to their tables instead of loading staged files with Snowpipe. Requires Private Key (JWT) authentication.,default=false" jsonschema_extras:"order=9"`
 	Credentials   credentialConfig           `json:"credentials" jsonschema:"title=Authentication"`
 	Schedule      boilerplate.ScheduleConfig `json:"syncSchedule,omitempty" jsonschema:"title=Sync Schedule,description=Configure schedule of transactions for the materialization."`
 	DBTJobTrigger dbt.JobConfig              `json:"dbt_job_trigger,omitempty" jsonschema:"title=dbt Cloud Job Trigger,description=Trigger a dbt Job when new data is available"`
@@ -147,6 +148,10 @@ func (c *config) Validate() error {
 		return err
 	}
 
+	if c.Streaming && c.Credentials.AuthType != JWT {
+		return fmt.Errorf("snowpipe_streaming requires Private Key (JWT) authentication")
+	}
+
 	if err := c.DBTJobTrigger.Validate(); err != nil {
 		return err
 	}
@@ -43,3 +43,19 @@ func TestConfigURI(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigStreamingRequiresJWT(t *testing.T) {
+	cfg := config{
+		Host:      "orgname-accountname.snowflakecomputing.com",
+		Database:  "mydb",
+		Schema:    "myschema",
+		Streaming: true,
+		Credentials: credentialConfig{
+			AuthType: UserPass,
+			User:     "will",
+			Password: "some+complex/password",
+		},
+	}
+
+	require.ErrorContains(t, cfg.Validate(), "snowpipe_streaming requires Private Key (JWT) authentication")
+}
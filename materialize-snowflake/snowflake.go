@@ -25,9 +25,11 @@ import (
 )
 
 type tableConfig struct {
-	Table  string `json:"table" jsonschema_extras:"x-collection-name=true"`
-	Schema string `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)" jsonschema_extras:"x-schema-name=true"`
-	Delta  bool   `json:"delta_updates,omitempty" jsonschema:"title=Delta Updates,description=Use Private Key authentication to enable Snowpipe for Delta Update bindings" jsonschema_extras:"x-delta-updates=true"`
+	Table     string   `json:"table" jsonschema_extras:"x-collection-name=true"`
+	Schema    string   `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)" jsonschema_extras:"x-schema-name=true"`
+	Delta     bool     `json:"delta_updates,omitempty" jsonschema:"title=Delta Updates,description=Use Private Key authentication to enable Snowpipe for Delta Update bindings" jsonschema_extras:"x-delta-updates=true"`
+	History   bool     `json:"history_mode,omitempty" jsonschema:"title=History Mode,description=Keep a row for every version of each document instead of only the latest version. Rows have valid_from and valid_to times and an is_current flag."`
+	ClusterBy []string `json:"cluster_by,omitempty" jsonschema:"title=Clustering Key,description=Fields to use as the clustering key of the table (optional). Snowflake recommends a clustering key of no more than 3 or 4 fields. Changing the clustering key of an existing table re-clusters it in the background."`
 
 	// If the endpoint schema is the same as the resource schema, the resource path will be only the
 	// table name. This is to provide compatibility for materializations that were created prior to
@@ -77,6 +79,17 @@ func (c tableConfig) DeltaUpdates() bool {
 	return c.Delta
 }
 
+func (c tableConfig) HistoryMode() bool {
+	return c.History
+}
+
+func (c tableConfig) Layout() *sql.TableLayout {
+	if len(c.ClusterBy) == 0 {
+		return nil
+	}
+	return &sql.TableLayout{ClusterBy: c.ClusterBy}
+}
+
 // newSnowflakeDriver creates a new Driver for Snowflake.
 func newSnowflakeDriver() *sql.Driver {
 	return &sql.Driver{
@@ -169,6 +182,11 @@ type transactor struct {
 	db         *stdsql.DB
 	pipeClient *PipeClient
 
+	// Used for Snowpipe Streaming, if enabled. Channels are opened once and
+	// are keyed by the name of their pipe.
+	streamClient *StreamingClient
+	channels     map[string]*streamingChannel
+
 	// Variables exclusively used by Load.
 	load struct {
 		conn *stdsql.Conn
@@ -218,6 +236,7 @@ func newTransactor(
 	}
 
 	var pipeClient *PipeClient
+	var streamClient *StreamingClient
 	if cfg.Credentials.AuthType == JWT {
 		var accountName string
 		if err := db.QueryRowContext(ctx, "SELECT CURRENT_ACCOUNT()").Scan(&accountName); err != nil {
@@ -227,17 +246,26 @@ func newTransactor(
 		if err != nil {
 			return nil, nil, fmt.Errorf("NewPipeClient: %w", err)
 		}
+
+		if cfg.Streaming {
+			streamClient, err = NewStreamingClient(ctx, cfg, accountName, ep.Tenant)
+			if err != nil {
+				return nil, nil, fmt.Errorf("NewStreamingClient: %w", err)
+			}
+		}
 	}
 
 	var d = &transactor{
-		cfg:        cfg,
-		ep:         ep,
-		templates:  renderTemplates(ep.Dialect),
-		db:         db,
-		pipeClient: pipeClient,
-		_range:     open.Range,
-		version:    open.Version,
-		be:         be,
+		cfg:          cfg,
+		ep:           ep,
+		templates:    renderTemplates(ep.Dialect),
+		db:           db,
+		pipeClient:   pipeClient,
+		streamClient: streamClient,
+		channels:     make(map[string]*streamingChannel),
+		_range:       open.Range,
+		version:      open.Version,
+		be:           be,
 	}
 
 	d.store.fence = &fence
@@ -282,6 +310,11 @@ type binding struct {
 	target sql.Table
 
 	pipeName string
+	// Rows of delta update bindings are appended to a Snowpipe Streaming
+	// channel of the pipe when streaming is enabled.
+	streaming   bool
+	pipeCreated bool
+	channel     string
 	// Variables exclusively used by Load.
 	load struct {
 		loadQuery   string
@@ -295,6 +328,7 @@ type binding struct {
 		copyInto    string
 		mustMerge   bool
 		mergeBounds *sql.MergeBoundsBuilder
+		stream      *streamingWriter
 	}
 }
 
@@ -316,6 +350,12 @@ func (d *transactor) addBinding(target sql.Table) error {
 			pipeName = strings.ToUpper(strings.Trim(pipeName, "`"))
 			b.pipeName = fmt.Sprintf("%s.%s.%s", d.cfg.Database, d.cfg.Schema, pipeName)
 		}
+
+		if d.streamClient != nil {
+			b.streaming = true
+			b.channel = fmt.Sprintf("flow_%08x", d._range.KeyBegin)
+			b.store.stream = newStreamingWriter(streamingFields(target))
+		}
 	}
 
 	d.bindings = append(d.bindings, b)
@@ -436,6 +476,11 @@ type checkpointItem struct {
 	PipeName  string
 	PipeFiles []fileRecord
 	Version   string
+	// Channel is the Snowpipe Streaming channel of the pipe that rows are
+	// appended to, and OffsetToken is that of the last rows appended by the
+	// transaction. They are only set for streaming bindings.
+	Channel     string
+	OffsetToken string
 }
 
 type checkpoint = map[string]*checkpointItem
@@ -451,7 +496,7 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		}
 
 		var flowDocument = it.RawJSON
-		if d.cfg.HardDelete && it.Delete {
+		if (d.cfg.HardDelete || b.target.History != nil) && it.Delete {
 			if it.Exists {
 				flowDocument = json.RawMessage(`"delete"`)
 			} else {
@@ -460,13 +505,23 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			}
 		}
 
-		if err := b.store.stage.start(ctx, d.db); err != nil {
-			return nil, err
-		}
 		converted, err := b.target.ConvertAll(it.Key, it.Values, flowDocument)
 		if err != nil {
 			return nil, fmt.Errorf("converting Store: %w", err)
 		}
+
+		if b.streaming {
+			if err := d.startStreaming(ctx, b); err != nil {
+				return nil, err
+			} else if err := b.store.stream.encodeRow(ctx, converted); err != nil {
+				return nil, fmt.Errorf("appending Store to channel: %w", err)
+			}
+			continue
+		}
+
+		if err := b.store.stage.start(ctx, d.db); err != nil {
+			return nil, err
+		}
 		if err = b.store.stage.encodeRow(converted); err != nil {
 			return nil, fmt.Errorf("encoding Store to scratch file: %w", err)
 		}
@@ -481,6 +536,24 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 	// These are keyed on the binding table name so that in case of a recovery being necessary
 	// we don't run queries belonging to bindings that have been removed
 	for _, b := range d.bindings {
+		if b.streaming && b.store.stream.started {
+			// The rows have already been appended to the channel, and are
+			// committed to the table asynchronously. Acknowledge waits for them
+			// to be committed.
+			offsetToken, err := b.store.stream.finish(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("appending Store to channel: %w", err)
+			}
+			d.cp[b.target.StateKey] = &checkpointItem{
+				Table:       b.target.Identifier,
+				PipeName:    b.pipeName,
+				Version:     d.version,
+				Channel:     b.channel,
+				OffsetToken: offsetToken,
+			}
+			continue
+		}
+
 		if !b.store.stage.started {
 			continue
 		}
@@ -490,7 +563,22 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 			return nil, err
 		}
 
-		if b.store.mustMerge {
+		if b.target.History != nil {
+			// Documents are always merged into history tables, since the
+			// current rows of existing documents must be closed out and new
+			// rows must have their history columns populated.
+			historyMergeQuery, err := renderBoundedQueryTemplate(d.templates.historyMerge, b.target, dir, b.store.mergeBounds.Build())
+			if err != nil {
+				return nil, fmt.Errorf("historyMerge template: %w", err)
+			}
+			d.cp[b.target.StateKey] = &checkpointItem{
+				Table:     b.target.Identifier,
+				Query:     historyMergeQuery,
+				StagedDir: dir,
+				Version:   d.version,
+			}
+			b.store.mustMerge = false
+		} else if b.store.mustMerge {
 			mergeIntoQuery, err := renderBoundedQueryTemplate(d.templates.mergeInto, b.target, dir, b.store.mergeBounds.Build())
 			if err != nil {
 				return nil, fmt.Errorf("mergeInto template: %w", err)
@@ -669,6 +757,33 @@ func (d *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 					return fmt.Errorf("cleaning up files: %w", err)
 				}
 
+				return nil
+			})
+		} else if item.Channel != "" {
+			if d.streamClient == nil {
+				log.WithField("table", item.Table).Warn("snowpipe streaming is not enabled: not waiting for rows appended to channel to be committed")
+				continue
+			}
+
+			ch, err := d.streamingChannel(ctx, item.PipeName, item.Channel)
+			if err != nil {
+				return nil, err
+			}
+			token, ok := parseStreamingOffsetToken(item.OffsetToken)
+			if !ok {
+				return nil, fmt.Errorf("invalid offset token %q for table %q", item.OffsetToken, item.Table)
+			} else if token.appended(ch.lastCommittedOffsetToken) {
+				continue
+			}
+
+			item := item
+			group.Go(func() error {
+				d.be.StartedResourceCommit(path)
+				if err := d.waitForStreamingCommit(groupCtx, ch, token); err != nil {
+					return fmt.Errorf("snowpipe streaming to table %q: %w", item.Table, err)
+				}
+				d.be.FinishedResourceCommit(path)
+
 				return nil
 			})
 		} else if len(item.PipeFiles) > 0 {
@@ -833,6 +948,11 @@ func (d *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 	// the binding and running the queries that are pending for its last transaction.
 	var checkpointClear = make(checkpoint)
 	for _, b := range d.bindings {
+		if b.streaming {
+			// The checkpoint of a streaming binding is kept, since the offset
+			// token of its last transaction numbers its next transaction.
+			continue
+		}
 		checkpointClear[b.target.StateKey] = nil
 		delete(d.cp, b.target.StateKey)
 	}
@@ -845,6 +965,7 @@ func (d *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 				checkpointClear[stateKey] = nil
 				delete(d.cp, stateKey)
 
+				delete(d.channels, item.PipeName)
 				log.WithField("pipeName", item.PipeName).WithField("stateKey", stateKey).Info("dropping pipe and clearing checkpoint")
 				if _, err := d.store.conn.ExecContext(ctx, fmt.Sprintf("DROP PIPE IF EXISTS %s", d.ep.Dialect.Identifier(item.PipeName))); err != nil {
 					return nil, fmt.Errorf("dropping pipe %s failed: %w", item.PipeName, err)
@@ -860,6 +981,91 @@ func (d *transactor) Acknowledge(ctx context.Context) (*pf.ConnectorState, error
 	return &pf.ConnectorState{UpdatedJson: json.RawMessage(checkpointJSON), MergePatch: true}, nil
 }
 
+// startStreaming starts the rows of a transaction of a streaming binding, if
+// they have not been started yet. The pipe of the binding is created and its
+// channel is opened the first time they are used.
+func (d *transactor) startStreaming(ctx context.Context, b *binding) error {
+	if b.store.stream.started {
+		return nil
+	}
+
+	// Since the pipe name is versioned by the spec, an updated spec will use a
+	// new pipe.
+	if !b.pipeCreated {
+		if createPipe, err := renderTableShardVersionTemplate(b.target, d._range.KeyBegin, d.version, d.templates.createStreamingPipe); err != nil {
+			return fmt.Errorf("createStreamingPipe template: %w", err)
+		} else if _, err := d.db.ExecContext(ctx, createPipe); err != nil {
+			return fmt.Errorf("creating streaming pipe for table %q: %w", b.target.Path, err)
+		}
+		b.pipeCreated = true
+	}
+
+	ch, err := d.streamingChannel(ctx, b.pipeName, b.channel)
+	if err != nil {
+		return err
+	}
+
+	var checkpointed string
+	if item, ok := d.cp[b.target.StateKey]; ok && item.PipeName == b.pipeName && item.Channel == b.channel {
+		checkpointed = item.OffsetToken
+	}
+	var txn = nextStreamingTxn(checkpointed, ch.lastCommittedOffsetToken)
+
+	b.store.stream.start(txn, ch.lastCommittedOffsetToken, func(ctx context.Context, rows []byte, offsetToken string) error {
+		return d.streamClient.AppendRows(ctx, ch, rows, offsetToken)
+	})
+
+	return nil
+}
+
+// streamingChannel returns the named channel of a pipe, opening it if this is
+// the first time it has been used.
+func (d *transactor) streamingChannel(ctx context.Context, pipeName string, channel string) (*streamingChannel, error) {
+	if ch, ok := d.channels[pipeName]; ok {
+		return ch, nil
+	}
+
+	var pipeNameParts = strings.Split(pipeName, ".")
+	if len(pipeNameParts) != 3 {
+		return nil, fmt.Errorf("invalid pipe name %q", pipeName)
+	}
+
+	ch, err := d.streamClient.OpenChannel(ctx, pipeNameParts[0], pipeNameParts[1], pipeNameParts[2], channel)
+	if err != nil {
+		return nil, err
+	}
+	d.channels[pipeName] = ch
+
+	return ch, nil
+}
+
+// waitForStreamingCommit waits until the channel has committed the rows that
+// were appended with the offset token.
+func (d *transactor) waitForStreamingCommit(ctx context.Context, ch *streamingChannel, token streamingOffsetToken) error {
+	var retryDelay = 500 * time.Millisecond
+	var deadline = time.Now().Add(streamingCommitTimeout)
+
+	for {
+		status, err := d.streamClient.ChannelStatus(ctx, ch)
+		if err != nil {
+			return err
+		} else if status.RowsErrorCount > ch.rowsErrorCount {
+			return fmt.Errorf("%d rows could not be inserted into channel %q of pipe %q: %s", status.RowsErrorCount-ch.rowsErrorCount, ch.name, ch.pipe, status.LastErrorMessage)
+		} else if status.LastCommittedOffsetToken != nil && token.appended(*status.LastCommittedOffsetToken) {
+			ch.lastCommittedOffsetToken = *status.LastCommittedOffsetToken
+			return nil
+		} else if time.Now().After(deadline) {
+			return fmt.Errorf("rows appended to channel %q of pipe %q with offset token %q were not committed within %s", ch.name, ch.pipe, token, streamingCommitTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
 func (d *transactor) pathForStateKey(stateKey string) []string {
 	for _, b := range d.bindings {
 		if b.target.StateKey == stateKey {
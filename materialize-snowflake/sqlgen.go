@@ -144,14 +144,16 @@ func toJsonCast(migration sql.ColumnTypeMigration) string {
 }
 
 type templates struct {
-	createTargetTable *template.Template
-	alterTableColumns *template.Template
-	loadQuery         *template.Template
-	copyInto          *template.Template
-	mergeInto         *template.Template
-	pipeName          *template.Template
-	createPipe        *template.Template
-	copyHistory       *template.Template
+	createTargetTable   *template.Template
+	alterTableColumns   *template.Template
+	loadQuery           *template.Template
+	copyInto            *template.Template
+	mergeInto           *template.Template
+	historyMerge        *template.Template
+	pipeName            *template.Template
+	createPipe          *template.Template
+	createStreamingPipe *template.Template
+	copyHistory         *template.Template
 }
 
 func renderTemplates(dialect sql.Dialect) templates {
@@ -168,6 +170,7 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
 {{- if $ind }},{{ end }}
 	{{$col.Identifier}} {{$col.DDL}}
 {{- end }}
+{{- template "historyColumnDefinitions" $ }}
 {{- if not $.DeltaUpdates }},
 
 	PRIMARY KEY (
@@ -175,14 +178,22 @@ CREATE TABLE IF NOT EXISTS {{$.Identifier}} (
 	{{- if $ind }}, {{end -}}
 	{{$key.Identifier}}
 	{{- end -}}
+	{{- template "historyKeyColumns" $ -}}
 )
 {{- end }}
-);
+){{ if and $.LayoutColumns $.LayoutColumns.ClusterBy }}
+CLUSTER BY (
+	{{- range $ind, $col := $.LayoutColumns.ClusterBy }}
+	{{- if $ind }}, {{end -}}
+	{{$col.Identifier}}
+	{{- end -}}
+){{ end }};
 
 COMMENT ON TABLE {{$.Identifier}} IS {{Literal $.Comment}};
 {{- range $col := .Columns }}
 COMMENT ON COLUMN {{$.Identifier}}.{{$col.Identifier}} IS {{Literal $col.Comment}};
 {{- end}}
+{{- template "historyColumnComments" $ }}
 {{ end }}
 
 -- Templated query which performs table alterations by adding columns and/or
@@ -230,6 +241,9 @@ SELECT {{ $.Table.Binding }}, {{ $.Table.Identifier }}.{{ $.Table.Document.Ident
 	{{ $.Table.Identifier }}.{{ $bound.Identifier }} = r.{{ $bound.Identifier }}
 	{{- if $bound.LiteralLower }} AND {{ $.Table.Identifier }}.{{ $bound.Identifier }} >= {{ $bound.LiteralLower }} AND {{ $.Table.Identifier }}.{{ $bound.Identifier }} <= {{ $bound.LiteralUpper }}{{ end }}
 	{{- end }}
+	{{- if $.Table.History }}
+	AND {{ $.Table.Identifier }}.{{ $.Table.History.IsCurrent.Identifier }}
+	{{- end }}
 {{ else -}}
 SELECT * FROM (SELECT -1, CAST(NULL AS VARIANT) LIMIT 0) as nodoc
 {{ end -}}
@@ -256,6 +270,26 @@ CREATE PIPE {{ template "pipe_name" . }}
 );
 {{ end }}
 
+-- Pipe used with Snowpipe Streaming. Rows are appended as JSON objects with
+-- keys c0, c1, etc. for each column of the table, in order.
+
+{{ define "createStreamingPipe" }}
+CREATE PIPE IF NOT EXISTS {{ template "pipe_name" . }}
+  COMMENT = 'Snowpipe Streaming pipe for table {{ $.Table.Path }}'
+  AS COPY INTO {{ $.Table.Identifier }} (
+	{{ range $ind, $key := $.Table.Columns }}
+		{{- if $ind }}, {{ end -}}
+		{{$key.Identifier -}}
+	{{- end }}
+) FROM (
+	SELECT {{ range $ind, $key := $.Table.Columns }}
+	{{- if $ind }}, {{ end -}}
+	{{ if eq $key.DDL "VARIANT" }}NULLIF($1:c{{$ind}}, PARSE_JSON('null')){{ else }}$1:c{{$ind}}{{ end }} AS {{$key.Identifier -}}
+	{{- end }}
+	FROM TABLE(DATA_SOURCE(TYPE => 'STREAMING'))
+);
+{{ end }}
+
 {{ define "copyInto" }}
 COPY INTO {{ $.Table.Identifier }} (
 	{{ range $ind, $key := $.Table.Columns }}
@@ -313,6 +347,43 @@ WHEN NOT MATCHED and r.{{ $.Table.Document.Identifier }}!='delete' THEN
 );
 {{ end }}
 
+-- Templated query which merges staged documents into a table in history mode.
+-- Each staged document is included twice in the source of the merge: Once to
+-- close out the current row for its key, and once to insert a new current row
+-- if it isn't a deletion. The conditions for matching are such that the query
+-- has no effect if it is run again for the same staged documents.
+
+{{ define "historyMerge" }}
+MERGE INTO {{ $.Table.Identifier }} AS l
+USING (
+	WITH staged AS (
+		SELECT {{ range $ind, $key := $.Table.Columns }}
+			{{- if $ind }}, {{ end -}}
+			{{ if eq $key.DDL "VARIANT" }}NULLIF($1[{{$ind}}], PARSE_JSON('null')){{ else }}$1[{{$ind}}]{{ end }} AS {{$key.Identifier -}}
+		{{- end }}
+		FROM {{ $.File }}
+	)
+	SELECT staged.*, TRUE AS flow_history_close FROM staged
+	UNION ALL
+	SELECT staged.*, FALSE AS flow_history_close FROM staged
+	{{- if $.Table.Document }} WHERE staged.{{ $.Table.Document.Identifier }}!='delete'{{ end }}
+) AS r
+ON {{ range $ind, $bound := $.Bounds }}
+	{{ if $ind -}} AND {{ end -}}
+	l.{{ $bound.Identifier }} = r.{{ $bound.Identifier }}
+	{{- if $bound.LiteralLower }} AND l.{{ $bound.Identifier }} >= {{ $bound.LiteralLower }} AND l.{{ $bound.Identifier }} <= {{ $bound.LiteralUpper }}{{ end }}
+{{- end }}
+	AND (
+		(r.flow_history_close AND l.{{ $.Table.History.IsCurrent.Identifier }} AND l.{{ $.Table.History.ValidFrom.Identifier }} < r.{{ $.Table.History.PublishedAt.Identifier }})
+		OR (NOT r.flow_history_close AND l.{{ $.Table.History.ValidFrom.Identifier }} = r.{{ $.Table.History.PublishedAt.Identifier }})
+	)
+WHEN MATCHED AND r.flow_history_close THEN
+	UPDATE SET l.{{ $.Table.History.ValidTo.Identifier }} = r.{{ $.Table.History.PublishedAt.Identifier }}, l.{{ $.Table.History.IsCurrent.Identifier }} = FALSE
+WHEN NOT MATCHED AND NOT r.flow_history_close THEN
+	INSERT ({{ template "historyInsertColumns" $.Table }})
+	VALUES ({{ template "historyInsertValues" $.Table }});
+{{ end }}
+
 {{ define "copyHistory" }}
 SELECT FILE_NAME, STATUS, FIRST_ERROR_MESSAGE FROM TABLE(INFORMATION_SCHEMA.COPY_HISTORY(
   TABLE_NAME=>'{{ $.TableName }}',
@@ -323,14 +394,16 @@ FILE_NAME IN ('{{ Join $.Files "','" }}')
   `)
 
 	return templates{
-		createTargetTable: tplAll.Lookup("createTargetTable"),
-		alterTableColumns: tplAll.Lookup("alterTableColumns"),
-		loadQuery:         tplAll.Lookup("loadQuery"),
-		copyInto:          tplAll.Lookup("copyInto"),
-		mergeInto:         tplAll.Lookup("mergeInto"),
-		pipeName:          tplAll.Lookup("pipe_name"),
-		createPipe:        tplAll.Lookup("createPipe"),
-		copyHistory:       tplAll.Lookup("copyHistory"),
+		createTargetTable:   tplAll.Lookup("createTargetTable"),
+		alterTableColumns:   tplAll.Lookup("alterTableColumns"),
+		loadQuery:           tplAll.Lookup("loadQuery"),
+		copyInto:            tplAll.Lookup("copyInto"),
+		mergeInto:           tplAll.Lookup("mergeInto"),
+		historyMerge:        tplAll.Lookup("historyMerge"),
+		pipeName:            tplAll.Lookup("pipe_name"),
+		createPipe:          tplAll.Lookup("createPipe"),
+		createStreamingPipe: tplAll.Lookup("createStreamingPipe"),
+		copyHistory:         tplAll.Lookup("copyHistory"),
 	}
 }
 
@@ -344,6 +417,16 @@ FILE_FORMAT = (
 COMMENT = 'Internal stage used by Estuary Flow to stage loaded & stored documents'
 ;`
 
+// streamingFields are the keys of the JSON objects appended to a Snowpipe
+// Streaming channel for the columns of the table.
+func streamingFields(table sql.Table) []string {
+	var fields []string
+	for idx := range table.Columns() {
+		fields = append(fields, fmt.Sprintf("c%d", idx))
+	}
+	return fields
+}
+
 type tableShardVersion struct {
 	Table         sql.Table
 	ShardKeyBegin string
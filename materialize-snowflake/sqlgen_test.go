@@ -28,19 +28,34 @@ func TestSQLGeneration(t *testing.T) {
 			TableTemplates: []*template.Template{
 				templates.createTargetTable,
 			},
+			HistoryTableTemplates: []*template.Template{
+				templates.createTargetTable,
+			},
+			LayoutTableTemplates: []*template.Template{
+				templates.createTargetTable,
+			},
+			Layout:           &sql.TableLayout{ClusterBy: []string{"flow_published_at", "key1"}},
 			TplAddColumns:    templates.alterTableColumns,
 			TplDropNotNulls:  templates.alterTableColumns,
 			TplCombinedAlter: templates.alterTableColumns,
 		},
 	)
 
-	for _, tpl := range []*template.Template{
-		templates.loadQuery,
-		templates.mergeInto,
+	for _, tc := range []struct {
+		tbl sql.Table
+		tpl *template.Template
+	}{
+		{tables[0], templates.loadQuery},
+		{tables[0], templates.mergeInto},
+		{tables[2], templates.loadQuery},
+		{tables[2], templates.historyMerge},
 	} {
-		tbl := tables[0]
+		tbl, tpl := tc.tbl, tc.tpl
 		require.False(t, tbl.DeltaUpdates)
 		var testcase = tbl.Identifier + " " + tpl.Name()
+		if tbl.History != nil {
+			testcase = tbl.Identifier + " history " + tpl.Name()
+		}
 
 		bounds := []sql.MergeBound{
 			{
@@ -106,5 +121,23 @@ func TestSQLGeneration(t *testing.T) {
 		snap.WriteString("--- End " + testcase + " ---\n\n")
 	}
 
+	for _, tpl := range []*template.Template{
+		templates.createStreamingPipe,
+	} {
+		tbl := tables[1]
+		require.True(t, tbl.DeltaUpdates)
+		var testcase = tbl.Identifier + " " + tpl.Name()
+
+		var tf = tableShardVersion{
+			Table:         tbl,
+			ShardKeyBegin: "00000000",
+			Version:       "abcd",
+		}
+
+		snap.WriteString("--- Begin " + testcase + " ---")
+		require.NoError(t, tpl.Execute(snap, &tf))
+		snap.WriteString("--- End " + testcase + " ---\n\n")
+	}
+
 	cupaloy.SnapshotT(t, snap.String())
 }
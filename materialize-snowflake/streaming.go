@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	enc "github.com/estuary/connectors/materialize-boilerplate/stream-encode"
+	log "github.com/sirupsen/logrus"
+)
+
+// StreamingClient appends rows to Snowpipe Streaming channels using the
+// Snowpipe Streaming REST API. Rows are sent to a pipe which reads from
+// `TABLE(DATA_SOURCE(TYPE => 'STREAMING'))`, and each append may include an
+// offset token which Snowflake persists along with the rows. The latest
+// committed offset token of a channel is reported when the channel is opened,
+// which is how we determine which rows of a transaction were already appended
+// when recovering.
+//
+// See https://docs.snowflake.com/en/user-guide/snowpipe-streaming/snowpipe-streaming-high-performance-rest-api
+type StreamingClient struct {
+	cfg         *config
+	base        string
+	accountName string
+	ingestHost  string
+	httpClient  http.Client
+
+	// The key-pair JWT is used to discover the ingest host and to request a
+	// token scoped to it, which is then used for all requests to the ingest
+	// host.
+	jwt         string
+	scopedToken string
+	expiry      time.Time
+}
+
+type streamingChannel struct {
+	database string
+	schema   string
+	pipe     string
+	name     string
+
+	continuationToken        string
+	lastCommittedOffsetToken string
+	rowsErrorCount           int
+}
+
+type channelStatus struct {
+	StatusCode               string  `json:"channel_status_code"`
+	LastCommittedOffsetToken *string `json:"last_committed_offset_token"`
+	RowsInserted             int     `json:"rows_inserted"`
+	RowsErrorCount           int     `json:"rows_error_count"`
+	LastErrorMessage         string  `json:"last_error_message"`
+}
+
+type StreamingError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e StreamingError) Error() string {
+	return fmt.Sprintf("(%s) %s", e.Code, e.Message)
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+func NewStreamingClient(ctx context.Context, cfg *config, accountName string, tenant string) (*StreamingClient, error) {
+	var dsn, err = cfg.toURI(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("building snowflake dsn: %w", err)
+	}
+
+	dsnURL, err := url.Parse(fmt.Sprintf("https://%s", dsn))
+	if err != nil {
+		return nil, fmt.Errorf("parsing snowflake dsn: %w", err)
+	}
+
+	var c = &StreamingClient{
+		cfg:         cfg,
+		base:        dsnURL.Hostname(),
+		accountName: accountName,
+		httpClient:  http.Client{},
+	}
+
+	if err := c.refreshTokens(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *StreamingClient) refreshTokens(ctx context.Context) error {
+	if c.scopedToken != "" && time.Until(c.expiry).Minutes() >= 5 {
+		return nil
+	}
+
+	key, err := c.cfg.Credentials.privateKey()
+	if err != nil {
+		return err
+	}
+	jwtToken, expiry, err := generateJWTToken(key, c.cfg.Credentials.User, c.accountName)
+	if err != nil {
+		return fmt.Errorf("creating jwt token: %w", err)
+	}
+	c.jwt = jwtToken
+
+	if c.ingestHost == "" {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://%s/v2/streaming/hostname", c.base), nil)
+		if err != nil {
+			return fmt.Errorf("creating hostname request: %w", err)
+		}
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.jwt))
+		req.Header.Add("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+
+		host, err := c.do(req)
+		if err != nil {
+			return fmt.Errorf("fetching ingest hostname: %w", err)
+		}
+		c.ingestHost = strings.TrimSpace(string(host))
+	}
+
+	var form = url.Values{}
+	form.Add("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Add("scope", c.ingestHost)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://%s/oauth/token", c.base), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("creating scoped token request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.jwt))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	token, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("fetching scoped token: %w", err)
+	}
+
+	// The scoped token is valid for at least as long as the JWT used to
+	// request it, so both are refreshed together.
+	c.scopedToken = strings.TrimSpace(string(token))
+	c.expiry = expiry
+
+	return nil
+}
+
+func (c *StreamingClient) channelURL(ch *streamingChannel) string {
+	return fmt.Sprintf(
+		"https://%s/v2/streaming/databases/%s/schemas/%s/pipes/%s/channels/%s",
+		c.ingestHost,
+		url.PathEscape(ch.database),
+		url.PathEscape(ch.schema),
+		url.PathEscape(ch.pipe),
+		url.PathEscape(ch.name),
+	)
+}
+
+// OpenChannel opens the named channel of a pipe, creating it if it does not
+// exist yet. Opening a channel invalidates any previous opener of the same
+// channel.
+func (c *StreamingClient) OpenChannel(ctx context.Context, database, schema, pipe, name string) (*streamingChannel, error) {
+	var ch = &streamingChannel{
+		database: database,
+		schema:   schema,
+		pipe:     pipe,
+		name:     name,
+	}
+
+	var resp struct {
+		NextContinuationToken string        `json:"next_continuation_token"`
+		ChannelStatus         channelStatus `json:"channel_status"`
+	}
+	if err := c.request(ctx, "PUT", c.channelURL(ch), "application/json", []byte("{}"), &resp); err != nil {
+		return nil, fmt.Errorf("opening channel %q of pipe %q: %w", name, pipe, err)
+	}
+
+	ch.continuationToken = resp.NextContinuationToken
+	if resp.ChannelStatus.LastCommittedOffsetToken != nil {
+		ch.lastCommittedOffsetToken = *resp.ChannelStatus.LastCommittedOffsetToken
+	}
+	ch.rowsErrorCount = resp.ChannelStatus.RowsErrorCount
+
+	log.WithFields(log.Fields{
+		"pipe":                     pipe,
+		"channel":                  name,
+		"lastCommittedOffsetToken": ch.lastCommittedOffsetToken,
+	}).Debug("snowpipe streaming: opened channel")
+
+	return ch, nil
+}
+
+// AppendRows appends a request of newline-delimited JSON rows to the channel.
+// The offset token is committed along with the rows.
+func (c *StreamingClient) AppendRows(ctx context.Context, ch *streamingChannel, rows []byte, offsetToken string) error {
+	var query = url.Values{}
+	query.Add("continuationToken", ch.continuationToken)
+	query.Add("offsetToken", offsetToken)
+
+	var appendURL = fmt.Sprintf(
+		"https://%s/v2/streaming/data/databases/%s/schemas/%s/pipes/%s/channels/%s/rows?%s",
+		c.ingestHost,
+		url.PathEscape(ch.database),
+		url.PathEscape(ch.schema),
+		url.PathEscape(ch.pipe),
+		url.PathEscape(ch.name),
+		query.Encode(),
+	)
+
+	var resp struct {
+		NextContinuationToken string `json:"next_continuation_token"`
+	}
+	if err := c.request(ctx, "POST", appendURL, ndjsonContentType, rows, &resp); err != nil {
+		return fmt.Errorf("appending rows to channel %q of pipe %q: %w", ch.name, ch.pipe, err)
+	}
+	ch.continuationToken = resp.NextContinuationToken
+
+	return nil
+}
+
+// ChannelStatus returns the current status of the channel.
+func (c *StreamingClient) ChannelStatus(ctx context.Context, ch *streamingChannel) (*channelStatus, error) {
+	var statusURL = fmt.Sprintf(
+		"https://%s/v2/streaming/databases/%s/schemas/%s/pipes/%s:bulk-channel-status",
+		c.ingestHost,
+		url.PathEscape(ch.database),
+		url.PathEscape(ch.schema),
+		url.PathEscape(ch.pipe),
+	)
+
+	reqBody, err := json.Marshal(map[string][]string{"channel_names": {ch.name}})
+	if err != nil {
+		return nil, fmt.Errorf("marshal channel status body: %w", err)
+	}
+
+	var resp struct {
+		ChannelStatuses map[string]channelStatus `json:"channel_statuses"`
+	}
+	if err := c.request(ctx, "POST", statusURL, "application/json", reqBody, &resp); err != nil {
+		return nil, fmt.Errorf("fetching status of channel %q of pipe %q: %w", ch.name, ch.pipe, err)
+	}
+
+	status, ok := resp.ChannelStatuses[ch.name]
+	if !ok {
+		return nil, fmt.Errorf("no status for channel %q of pipe %q", ch.name, ch.pipe)
+	}
+
+	return &status, nil
+}
+
+func (c *StreamingClient) request(ctx context.Context, method string, url string, contentType string, body []byte, out any) error {
+	if err := c.refreshTokens(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.scopedToken))
+	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", userAgent)
+
+	respBody, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *StreamingClient) do(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"method": req.Method,
+		"url":    req.URL.Path,
+		"status": resp.StatusCode,
+	}).Debug("streaming client")
+
+	if resp.StatusCode >= 400 {
+		var errResponse StreamingError
+		if err := json.Unmarshal(respBody, &errResponse); err != nil || errResponse.Message == "" {
+			return nil, fmt.Errorf("response error code: %d %s", resp.StatusCode, string(respBody))
+		}
+		return nil, errResponse
+	}
+
+	return respBody, nil
+}
+
+// Appends to a channel are limited to 16MB of uncompressed rows per request.
+// Requests are kept well under that.
+const streamingMaxRequestBytes = 4 * 1024 * 1024
+
+// Rows that were appended to a channel are committed asynchronously, and are
+// expected to be committed well within this long.
+const streamingCommitTimeout = 10 * time.Minute
+
+// streamingOffsetToken identifies an append request of a transaction of a
+// binding. Transactions of a binding are numbered sequentially, and the rows
+// of a transaction are split into requests in the order they are stored, so a
+// transaction which is replayed after a failure appends the same rows with the
+// same tokens.
+type streamingOffsetToken struct {
+	txn   int
+	batch int
+}
+
+func (t streamingOffsetToken) String() string {
+	return fmt.Sprintf("%d:%d", t.txn, t.batch)
+}
+
+func parseStreamingOffsetToken(s string) (streamingOffsetToken, bool) {
+	var parts = strings.Split(s, ":")
+	if len(parts) != 2 {
+		return streamingOffsetToken{}, false
+	}
+
+	txn, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return streamingOffsetToken{}, false
+	}
+	batch, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return streamingOffsetToken{}, false
+	}
+
+	return streamingOffsetToken{txn: txn, batch: batch}, true
+}
+
+// appended returns true if the rows identified by t were already appended to a
+// channel which last committed the `committed` token.
+func (t streamingOffsetToken) appended(committed string) bool {
+	c, ok := parseStreamingOffsetToken(committed)
+	if !ok {
+		return false
+	}
+	return t.txn < c.txn || (t.txn == c.txn && t.batch <= c.batch)
+}
+
+// nextStreamingTxn returns the number of the next transaction of a binding,
+// given the offset token of its last checkpointed transaction and the last
+// offset token committed by its channel. If the channel committed rows of the
+// transaction following the checkpointed one, that transaction failed after
+// appending them and is now being replayed, so it keeps its number and the
+// rows which were already appended are skipped.
+func nextStreamingTxn(checkpointed string, committed string) int {
+	var next = 1
+	if t, ok := parseStreamingOffsetToken(checkpointed); ok {
+		next = t.txn + 1
+	}
+
+	// A channel which is further ahead than that means the checkpoint was
+	// lost, and numbering continues from the channel so that no rows are
+	// skipped.
+	if c, ok := parseStreamingOffsetToken(committed); ok && c.txn > next {
+		next = c.txn + 1
+	}
+
+	return next
+}
+
+// streamingWriter appends the rows of a binding's transaction to its channel
+// as they are stored. Rows are encoded as JSON objects and buffered into
+// requests of up to streamingMaxRequestBytes.
+type streamingWriter struct {
+	fields   []string
+	maxBytes int
+
+	started    bool
+	txn        int
+	batch      int
+	committed  string
+	appendRows func(ctx context.Context, rows []byte, offsetToken string) error
+	last       string
+
+	row     bytes.Buffer
+	encoder *enc.JsonEncoder
+	buf     []byte
+}
+
+func newStreamingWriter(fields []string) *streamingWriter {
+	return &streamingWriter{
+		fields:   fields,
+		maxBytes: streamingMaxRequestBytes,
+	}
+}
+
+// start begins the rows of transaction `txn`. Requests which the channel has
+// already committed, per its `committed` offset token, are not appended again.
+func (w *streamingWriter) start(txn int, committed string, appendRows func(ctx context.Context, rows []byte, offsetToken string) error) {
+	w.started = true
+	w.txn = txn
+	w.batch = 0
+	w.committed = committed
+	w.appendRows = appendRows
+	w.last = ""
+
+	w.row.Reset()
+	w.encoder = enc.NewJsonEncoder(nopWriteCloser{&w.row}, w.fields, enc.WithJsonDisableCompression())
+	w.buf = w.buf[:0]
+}
+
+func (w *streamingWriter) encodeRow(ctx context.Context, row []any) error {
+	w.row.Reset()
+	if err := w.encoder.Encode(row); err != nil {
+		return err
+	}
+
+	if len(w.buf) > 0 && len(w.buf)+w.row.Len() > w.maxBytes {
+		if err := w.flush(ctx); err != nil {
+			return err
+		}
+	}
+	w.buf = append(w.buf, w.row.Bytes()...)
+
+	return nil
+}
+
+func (w *streamingWriter) flush(ctx context.Context) error {
+	var token = streamingOffsetToken{txn: w.txn, batch: w.batch}
+	w.batch++
+	w.last = token.String()
+
+	if token.appended(w.committed) {
+		log.WithField("offsetToken", w.last).Debug("snowpipe streaming: skipping rows that were already appended")
+	} else if err := w.appendRows(ctx, w.buf, w.last); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+
+	return nil
+}
+
+// finish appends any remaining rows of the transaction, and returns the offset
+// token of its last request.
+func (w *streamingWriter) finish(ctx context.Context) (string, error) {
+	if len(w.buf) > 0 {
+		if err := w.flush(ctx); err != nil {
+			return "", err
+		}
+	}
+	w.started = false
+
+	return w.last, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
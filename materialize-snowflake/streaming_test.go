@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingOffsetToken(t *testing.T) {
+	var tok = streamingOffsetToken{txn: 3, batch: 2}
+	require.Equal(t, "3:2", tok.String())
+
+	parsed, ok := parseStreamingOffsetToken(tok.String())
+	require.True(t, ok)
+	require.Equal(t, tok, parsed)
+
+	for _, tc := range []struct {
+		committed string
+		want      bool
+	}{
+		{"", false},
+		{"not-a-token", false},
+		{"abc:1", false},
+		{"2:9", false},
+		{"3:1", false},
+		{"3:2", true},
+		{"3:3", true},
+		{"4:0", true},
+	} {
+		require.Equal(t, tc.want, tok.appended(tc.committed), tc.committed)
+	}
+}
+
+func TestNextStreamingTxn(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		checkpointed string
+		committed    string
+		want         int
+	}{
+		{"first transaction", "", "", 1},
+		{"first transaction replayed", "", "1:4", 1},
+		{"committed transaction", "3:2", "3:2", 4},
+		{"replayed transaction", "3:2", "4:0", 4},
+		{"channel behind checkpoint", "3:2", "2:5", 4},
+		{"lost checkpoint", "", "7:1", 8},
+		{"checkpoint behind channel", "3:2", "6:0", 7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, nextStreamingTxn(tc.checkpointed, tc.committed))
+		})
+	}
+}
+
+func TestStreamingWriter(t *testing.T) {
+	var ctx = context.Background()
+
+	type request struct {
+		rows        string
+		offsetToken string
+	}
+	var requests []request
+	var appendRows = func(ctx context.Context, rows []byte, offsetToken string) error {
+		requests = append(requests, request{string(rows), offsetToken})
+		return nil
+	}
+
+	var w = newStreamingWriter([]string{"c0", "c1"})
+	w.maxBytes = 50
+
+	w.start(2, "1:3", appendRows)
+	for idx := 0; idx < 5; idx++ {
+		require.NoError(t, w.encodeRow(ctx, []any{idx, "value"}))
+	}
+	token, err := w.finish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "2:2", token)
+	require.False(t, w.started)
+	require.Equal(t, []request{
+		{`{"c0":0,"c1":"value"}` + "\n" + `{"c0":1,"c1":"value"}` + "\n", "2:0"},
+		{`{"c0":2,"c1":"value"}` + "\n" + `{"c0":3,"c1":"value"}` + "\n", "2:1"},
+		{`{"c0":4,"c1":"value"}` + "\n", "2:2"},
+	}, requests)
+
+	// Requests of a replayed transaction which the channel already committed
+	// are skipped.
+	requests = nil
+	w.start(2, "2:0", appendRows)
+	for idx := 0; idx < 4; idx++ {
+		require.NoError(t, w.encodeRow(ctx, []any{idx, "value"}))
+	}
+	token, err = w.finish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "2:1", token)
+	require.Equal(t, []request{
+		{`{"c0":2,"c1":"value"}` + "\n" + `{"c0":3,"c1":"value"}` + "\n", "2:1"},
+	}, requests)
+}
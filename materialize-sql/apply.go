@@ -36,6 +36,21 @@ type TableAlter struct {
 	// we detect the temporary columns using their suffixes and pass these in-progress migrations
 	// to the connector client to finish
 	ColumnTypeChanges []ColumnTypeMigration
+
+	// PreviousLayout is the layout of the table per the previously applied resource configuration,
+	// which is only set if it is different from the current Layout of the Table. Either of them
+	// may be nil if the table has the default layout.
+	PreviousLayout *TableLayout
+	// LayoutChanged is true if the layout of the table has changed.
+	LayoutChanged bool
+
+	// PreviousEmbeddingColumn is the embedding column of the table per the previously applied
+	// resource configuration, which is only set if it is different from the current
+	// EmbeddingColumn of the Table. Either of them may be empty if the table has no embedding
+	// column.
+	PreviousEmbeddingColumn string
+	// EmbeddingColumnChanged is true if the embedding column of the table has changed.
+	EmbeddingColumnChanged bool
 }
 
 var _ boilerplate.Applier = (*sqlApplier)(nil)
@@ -118,6 +133,14 @@ func (a *sqlApplier) UpdateResource(ctx context.Context, spec *pf.Materializatio
 		DropNotNulls: bindingUpdate.NewlyNullableFields,
 	}
 
+	if table.History != nil {
+		// The history columns of the table are not part of the field selection,
+		// but must retain their constraints.
+		alter.DropNotNulls = slices.DeleteFunc(slices.Clone(alter.DropNotNulls), func(f boilerplate.EndpointField) bool {
+			return IsHistoryColumn(f.Name)
+		})
+	}
+
 	for _, newProjection := range bindingUpdate.NewProjections {
 		col, err := getColumn(newProjection.Field)
 		if err != nil {
@@ -180,8 +203,23 @@ func (a *sqlApplier) UpdateResource(ctx context.Context, spec *pf.Materializatio
 		}
 	}
 
+	if bindingUpdate.ExistingResourceConfigJson != nil {
+		previous, err := existingResource(a.endpoint, bindingUpdate.ExistingResourceConfigJson)
+		if err != nil {
+			return "", nil, err
+		}
+		if layout := resourceLayout(previous); !layout.Equal(table.Layout) {
+			alter.PreviousLayout = layout
+			alter.LayoutChanged = true
+		}
+		if column := resourceEmbeddingColumn(previous); column != table.EmbeddingColumn {
+			alter.PreviousEmbeddingColumn = column
+			alter.EmbeddingColumnChanged = true
+		}
+	}
+
 	// If there is nothing to do, skip
-	if len(alter.AddColumns) == 0 && len(alter.DropNotNulls) == 0 && len(alter.ColumnTypeChanges) == 0 {
+	if len(alter.AddColumns) == 0 && len(alter.DropNotNulls) == 0 && len(alter.ColumnTypeChanges) == 0 && !alter.LayoutChanged && !alter.EmbeddingColumnChanged {
 		return "", nil, nil
 	}
 
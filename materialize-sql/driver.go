@@ -144,6 +144,30 @@ func (d *Driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Re
 			return nil, err
 		}
 
+		if isHistoryMode(res) {
+			if err := validateHistoryBinding(res, bindingSpec.Collection, constraints); err != nil {
+				return nil, err
+			}
+		}
+
+		// Tables which will not be re-created by a backfill must keep the
+		// history mode they were created with.
+		if existing := findExistingBinding(res.Path(), req.LastMaterialization); existing != nil &&
+			existing.Backfill == bindingSpec.Backfill && is.HasResource(res.Path()) {
+			previous, err := existingResource(endpoint, existing.ResourceConfigJson)
+			if err != nil {
+				return nil, err
+			} else if err := validateHistoryModeChange(res, previous); err != nil {
+				return nil, err
+			}
+		}
+
+		if layout := resourceLayout(res); layout != nil {
+			if err := validateLayoutBinding(res, layout, bindingSpec.Collection, constraints); err != nil {
+				return nil, err
+			}
+		}
+
 		resp.Bindings = append(resp.Bindings,
 			&pm.Response_Validated_Binding{
 				Constraints:  constraints,
@@ -0,0 +1,22 @@
+package sql
+
+// EmbeddingResource is an optional interface that a Resource may implement if
+// the endpoint supports storing an embedding of each document in a column of
+// the table, such as a pgvector column.
+//
+// The embedding column is not a projection of the bound collection, so it is
+// not part of the columns of the Table. The endpoint's client adds it to the
+// table in CreateTable, and in AlterTable when an embedding column is
+// configured for an existing table.
+type EmbeddingResource interface {
+	// EmbeddingColumn returns the name of the embedding column of the table,
+	// or an empty string if the table doesn't have one.
+	EmbeddingColumn() string
+}
+
+func resourceEmbeddingColumn(res Resource) string {
+	if er, ok := res.(EmbeddingResource); ok {
+		return er.EmbeddingColumn()
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package sql
+
+import (
+	"testing"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	"github.com/stretchr/testify/require"
+)
+
+type testEmbeddingResource struct {
+	table     string
+	embedding string
+}
+
+func (r testEmbeddingResource) Validate() error         { return nil }
+func (r testEmbeddingResource) Path() TablePath         { return TablePath{"a", "b", r.table} }
+func (r testEmbeddingResource) DeltaUpdates() bool      { return false }
+func (r testEmbeddingResource) EmbeddingColumn() string { return r.embedding }
+
+func TestResolveEmbeddingTable(t *testing.T) {
+	specBytes, err := testFS.ReadFile("testdata/generated_specs/flow.proto")
+	require.NoError(t, err)
+	var spec pf.MaterializationSpec
+	require.NoError(t, spec.Unmarshal(specBytes))
+
+	shape := BuildTableShape(&spec, 0, testEmbeddingResource{table: "key_value", embedding: "embedding"})
+	require.Equal(t, "embedding", shape.EmbeddingColumn)
+
+	// The embedding column is not part of the columns of the table.
+	table, err := ResolveTable(shape, newTestDialect())
+	require.NoError(t, err)
+	require.NotContains(t, table.ColumnNames(), "embedding")
+
+	shape = BuildTableShape(&spec, 0, testEmbeddingResource{table: "key_value"})
+	require.Equal(t, "", shape.EmbeddingColumn)
+	shape = BuildTableShape(&spec, 0, testHistoryResource{table: "key_value"})
+	require.Equal(t, "", shape.EmbeddingColumn)
+}
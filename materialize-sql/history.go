@@ -0,0 +1,244 @@
+package sql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+)
+
+// Names of the columns which are added to tables materialized in history mode.
+const (
+	HistoryValidFromColumn = "valid_from"
+	HistoryValidToColumn   = "valid_to"
+	HistoryIsCurrentColumn = "is_current"
+)
+
+// historyPublishedAtPtr is the document location of the `flow_published_at`
+// projection, which provides the time a version of a document became valid.
+const historyPublishedAtPtr = "/_meta/uuid"
+
+// historyTemplates are the parts of the templates for tables in history mode
+// which are the same for every dialect. They are included in every template
+// parsed by MustParseTemplate, and take a Table as input.
+//
+// The `historyTrue` template is the literal that is inserted into the
+// `is_current` column of new rows, which dialects without a boolean literal of
+// TRUE may redefine.
+const historyTemplates = `
+{{ define "historyTrue" }}TRUE{{ end }}
+
+-- Definitions of the history columns of the table, which follow the
+-- definitions of its other columns.
+
+{{ define "historyColumnDefinitions" -}}
+{{- if $.History }}
+{{- range $col := $.History.Columns }},
+	{{$col.Identifier}} {{$col.DDL}}
+{{- end }}
+{{- end }}
+{{- end }}
+
+-- The history column that is part of the primary key of the table, which
+-- follows the columns of the collection key.
+
+{{ define "historyKeyColumns" -}}
+{{- if $.History }}, {{ $.History.ValidFrom.Identifier }}{{ end -}}
+{{- end }}
+
+{{ define "historyColumnComments" -}}
+{{- if $.History }}
+{{- range $col := $.History.Columns }}
+COMMENT ON COLUMN {{$.Identifier}}.{{$col.Identifier}} IS {{Literal $col.Comment}};
+{{- end }}
+{{- end }}
+{{- end }}
+
+-- The columns and values of the new current row inserted for a staged document
+-- aliased as "r".
+
+{{ define "historyInsertColumns" -}}
+{{- range $col := $.Columns }}{{ $col.Identifier }}, {{ end -}}
+{{ $.History.ValidFrom.Identifier }}, {{ $.History.ValidTo.Identifier }}, {{ $.History.IsCurrent.Identifier }}
+{{- end }}
+
+{{ define "historyInsertValues" -}}
+{{- range $col := $.Columns }}r.{{ $col.Identifier }}, {{ end -}}
+r.{{ $.History.PublishedAt.Identifier }}, NULL, {{ template "historyTrue" }}
+{{- end }}
+`
+
+// HistoryResource is an optional interface that a Resource may implement if
+// the endpoint supports materializing bindings in history mode.
+//
+// A table materialized in history mode is a type-2 slowly changing dimension
+// of the source collection, with a row for every version of each document
+// rather than only the latest one. When a document is stored, the current row
+// for its key is closed out by setting its `valid_to` to the time the new
+// version was published, and `is_current` to false. A row for the new version
+// is then inserted with a `valid_from` of its published time and `is_current`
+// of true. Deletions close out the current row without inserting a new one.
+type HistoryResource interface {
+	// HistoryMode is true if the resource should be materialized in history
+	// mode.
+	HistoryMode() bool
+}
+
+func isHistoryMode(res Resource) bool {
+	hr, ok := res.(HistoryResource)
+	return ok && hr.HistoryMode()
+}
+
+// HistoryColumns are the additional columns of a Table materialized in
+// history mode.
+type HistoryColumns struct {
+	// PublishedAt is the materialized `flow_published_at` column, which is
+	// the source of values for ValidFrom and ValidTo.
+	PublishedAt *Column
+
+	ValidFrom Column
+	ValidTo   Column
+	IsCurrent Column
+}
+
+// Columns returns the history columns which are added to the table, ordered
+// as ValidFrom, ValidTo, then IsCurrent.
+func (h *HistoryColumns) Columns() []*Column {
+	return []*Column{&h.ValidFrom, &h.ValidTo, &h.IsCurrent}
+}
+
+func resolveHistoryColumns(table *Table, dialect Dialect) (*HistoryColumns, error) {
+	var out HistoryColumns
+
+	for idx := range table.Values {
+		if table.Values[idx].Ptr == historyPublishedAtPtr {
+			out.PublishedAt = &table.Values[idx]
+			break
+		}
+	}
+	if out.PublishedAt == nil {
+		return nil, fmt.Errorf("history mode requires the field 'flow_published_at' to be materialized")
+	}
+
+	var projections = []Projection{
+		{
+			Projection: pf.Projection{
+				Field: HistoryValidFromColumn,
+				Inference: pf.Inference{
+					Types:   []string{"string"},
+					Exists:  pf.Inference_MUST,
+					String_: &pf.Inference_String{Format: "date-time"},
+				},
+			},
+			Comment: "The time at which this version of the document became valid.",
+		},
+		{
+			Projection: pf.Projection{
+				Field: HistoryValidToColumn,
+				Inference: pf.Inference{
+					Types:   []string{"string", "null"},
+					Exists:  pf.Inference_MAY,
+					String_: &pf.Inference_String{Format: "date-time"},
+				},
+			},
+			Comment: "The time at which this version of the document was superseded or deleted, or null if it is the current version.",
+		},
+		{
+			Projection: pf.Projection{
+				Field: HistoryIsCurrentColumn,
+				Inference: pf.Inference{
+					Types:  []string{"boolean"},
+					Exists: pf.Inference_MUST,
+				},
+			},
+			Comment: "Whether this is the current version of the document.",
+		},
+	}
+
+	var offset = len(table.Columns())
+	for idx, col := range out.Columns() {
+		resolved, err := ResolveColumn(offset+idx, &projections[idx], dialect)
+		if err != nil {
+			return nil, fmt.Errorf("resolving history column %s: %w", projections[idx].Field, err)
+		}
+		*col = resolved
+	}
+
+	return &out, nil
+}
+
+// IsHistoryColumn returns true if the column name is one of the columns added
+// to tables materialized in history mode.
+func IsHistoryColumn(name string) bool {
+	for _, c := range []string{HistoryValidFromColumn, HistoryValidToColumn, HistoryIsCurrentColumn} {
+		if strings.EqualFold(name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHistoryBinding checks that a binding can be materialized in history
+// mode, and requires the fields that history mode depends on.
+func validateHistoryBinding(
+	res Resource,
+	collection pf.CollectionSpec,
+	constraints map[string]*pm.Response_Validated_Constraint,
+) error {
+	if res.DeltaUpdates() {
+		return fmt.Errorf("history mode cannot be used with delta updates for table %s", res.Path())
+	}
+
+	var publishedAt string
+	for _, p := range collection.Projections {
+		if IsHistoryColumn(p.Field) {
+			return fmt.Errorf("history mode cannot be used for table %s because the collection has a field named %q", res.Path(), p.Field)
+		} else if p.Ptr == historyPublishedAtPtr && publishedAt == "" {
+			publishedAt = p.Field
+		}
+	}
+	if publishedAt == "" {
+		return fmt.Errorf("history mode cannot be used for table %s because collection %s has no 'flow_published_at' projection", res.Path(), collection.Name)
+	}
+
+	if c := constraints[publishedAt]; c != nil && (c.Type == pm.Response_Validated_Constraint_FIELD_FORBIDDEN ||
+		c.Type == pm.Response_Validated_Constraint_UNSATISFIABLE) {
+		return fmt.Errorf("history mode cannot be used for table %s: field %q cannot be materialized: %s", res.Path(), publishedAt, c.Reason)
+	}
+	constraints[publishedAt] = &pm.Response_Validated_Constraint{
+		Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
+		Reason: "This field is required to materialize the table in history mode",
+	}
+
+	return nil
+}
+
+// validateHistoryModeChange checks that an existing table is not switched into
+// or out of history mode. A table in history mode has additional columns, a
+// primary key which includes its `valid_from` column, and a row for every
+// version of each document, so it can only change modes by backfilling the
+// binding to re-create the table.
+func validateHistoryModeChange(res Resource, existing Resource) error {
+	if isHistoryMode(res) && !isHistoryMode(existing) {
+		return fmt.Errorf("history mode cannot be enabled for existing table %s: backfill the binding to re-create the table in history mode", res.Path())
+	} else if !isHistoryMode(res) && isHistoryMode(existing) {
+		return fmt.Errorf("history mode cannot be disabled for existing table %s: backfill the binding to re-create the table without history mode", res.Path())
+	}
+	return nil
+}
+
+// findExistingBinding returns the binding of the last applied materialization
+// for the resource path, or nil if there isn't one.
+func findExistingBinding(path TablePath, lastSpec *pf.MaterializationSpec) *pf.MaterializationSpec_Binding {
+	if lastSpec == nil {
+		return nil
+	}
+	for _, b := range lastSpec.Bindings {
+		if slices.Equal(path, b.ResourcePath) {
+			return b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"testing"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+	"github.com/stretchr/testify/require"
+)
+
+type testHistoryResource struct {
+	table   string
+	delta   bool
+	history bool
+}
+
+func (r testHistoryResource) Validate() error    { return nil }
+func (r testHistoryResource) Path() TablePath    { return TablePath{"a", "b", r.table} }
+func (r testHistoryResource) DeltaUpdates() bool { return r.delta }
+func (r testHistoryResource) HistoryMode() bool  { return r.history }
+
+func TestResolveHistoryTable(t *testing.T) {
+	specBytes, err := testFS.ReadFile("testdata/generated_specs/flow.proto")
+	require.NoError(t, err)
+	var spec pf.MaterializationSpec
+	require.NoError(t, spec.Unmarshal(specBytes))
+
+	shape := BuildTableShape(&spec, 0, testHistoryResource{table: "key_value", history: true})
+	require.True(t, shape.History)
+
+	table, err := ResolveTable(shape, newTestDialect())
+	require.NoError(t, err)
+	require.NotNil(t, table.History)
+
+	require.Equal(t, "flow_published_at", table.History.PublishedAt.Field)
+	require.Equal(t, "valid_from", table.History.ValidFrom.Identifier)
+	require.Equal(t, "TIMESTAMPTZ NOT NULL", table.History.ValidFrom.DDL)
+	require.Equal(t, "TIMESTAMPTZ", table.History.ValidTo.DDL)
+	require.Equal(t, "BOOLEAN NOT NULL", table.History.IsCurrent.DDL)
+	require.Equal(t, "$21", table.History.ValidFrom.Placeholder)
+
+	// The history columns are not part of the columns with stored values.
+	require.NotContains(t, table.ColumnNames(), "valid_from")
+
+	// Tables not in history mode do not have history columns.
+	shape = BuildTableShape(&spec, 0, testHistoryResource{table: "key_value"})
+	table, err = ResolveTable(shape, newTestDialect())
+	require.NoError(t, err)
+	require.Nil(t, table.History)
+
+	// The flow_published_at field must be materialized.
+	shape = BuildTableShape(&spec, 0, testHistoryResource{table: "key_value", history: true})
+	shape.Values = nil
+	_, err = ResolveTable(shape, newTestDialect())
+	require.ErrorContains(t, err, "history mode requires the field 'flow_published_at'")
+}
+
+func TestValidateHistoryBinding(t *testing.T) {
+	specBytes, err := testFS.ReadFile("testdata/generated_specs/flow.proto")
+	require.NoError(t, err)
+	var spec pf.MaterializationSpec
+	require.NoError(t, spec.Unmarshal(specBytes))
+	collection := spec.Bindings[0].Collection
+
+	constraints := map[string]*pm.Response_Validated_Constraint{
+		"flow_published_at": {Type: pm.Response_Validated_Constraint_FIELD_OPTIONAL},
+	}
+	require.NoError(t, validateHistoryBinding(testHistoryResource{table: "key_value", history: true}, collection, constraints))
+	require.Equal(t, pm.Response_Validated_Constraint_FIELD_REQUIRED, constraints["flow_published_at"].Type)
+
+	require.ErrorContains(t,
+		validateHistoryBinding(testHistoryResource{table: "key_value", delta: true, history: true}, collection, constraints),
+		"cannot be used with delta updates",
+	)
+
+	conflicting := collection
+	conflicting.Projections = append([]pf.Projection{{Field: "Is_Current", Ptr: "/Is_Current"}}, collection.Projections...)
+	require.ErrorContains(t,
+		validateHistoryBinding(testHistoryResource{table: "key_value", history: true}, conflicting, constraints),
+		`the collection has a field named "Is_Current"`,
+	)
+}
+
+func TestValidateHistoryModeChange(t *testing.T) {
+	standard := testHistoryResource{table: "key_value"}
+	history := testHistoryResource{table: "key_value", history: true}
+
+	require.NoError(t, validateHistoryModeChange(standard, standard))
+	require.NoError(t, validateHistoryModeChange(history, history))
+	require.ErrorContains(t, validateHistoryModeChange(history, standard), "history mode cannot be enabled for existing table")
+	require.ErrorContains(t, validateHistoryModeChange(standard, history), "history mode cannot be disabled for existing table")
+}
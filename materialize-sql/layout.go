@@ -0,0 +1,174 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+)
+
+// LayoutResource is an optional interface that a Resource may implement if the
+// endpoint supports configuring the physical layout of a table, such as the
+// columns it is partitioned or clustered by.
+//
+// The fields of the layout must be projections of the bound collection, and
+// are required to be selected when the binding is validated. They are
+// resolved to columns of the Table, which the endpoint's CreateTableTemplate
+// renders into the table definition.
+type LayoutResource interface {
+	// Layout returns the configured layout of the table, or nil if the table
+	// uses the default layout of the endpoint.
+	Layout() *TableLayout
+}
+
+// TableLayout is the physical layout of a table, as configured by its
+// resource. The meaning of each component is specific to the endpoint.
+type TableLayout struct {
+	// PartitionBy are the fields that the table is partitioned by.
+	PartitionBy []string
+	// ClusterBy are the fields that the table is clustered or sorted by.
+	ClusterBy []string
+	// DistributeBy is the field that rows of the table are distributed across
+	// nodes by, if any.
+	DistributeBy string
+	// Options are additional endpoint-specific layout options, such as the
+	// granularity of time partitioning. It is compared along with the other
+	// components to determine if the layout of a table has changed.
+	Options any
+}
+
+// Fields returns all of the distinct fields of the layout.
+func (l *TableLayout) Fields() []string {
+	var out []string
+	var add = func(f string) {
+		for _, o := range out {
+			if o == f {
+				return
+			}
+		}
+		out = append(out, f)
+	}
+
+	for _, f := range l.PartitionBy {
+		add(f)
+	}
+	for _, f := range l.ClusterBy {
+		add(f)
+	}
+	if l.DistributeBy != "" {
+		add(l.DistributeBy)
+	}
+
+	return out
+}
+
+// Equal returns true if the layouts are the same. A nil layout is only equal
+// to another nil layout.
+func (l *TableLayout) Equal(other *TableLayout) bool {
+	if l == nil || other == nil {
+		return l == other
+	}
+	return reflect.DeepEqual(*l, *other)
+}
+
+// LayoutColumns are the columns of a Table per its TableLayout.
+type LayoutColumns struct {
+	PartitionBy  []Column
+	ClusterBy    []Column
+	DistributeBy *Column
+}
+
+func resourceLayout(res Resource) *TableLayout {
+	if lr, ok := res.(LayoutResource); ok {
+		return lr.Layout()
+	}
+	return nil
+}
+
+func resolveLayoutColumns(table *Table) (*LayoutColumns, error) {
+	var getColumn = func(field string) (Column, error) {
+		for _, c := range table.Columns() {
+			if c.Field == field {
+				return *c, nil
+			}
+		}
+		return Column{}, fmt.Errorf("field %q of the table layout is not a selected field", field)
+	}
+
+	var out LayoutColumns
+	for _, f := range table.Layout.PartitionBy {
+		col, err := getColumn(f)
+		if err != nil {
+			return nil, err
+		}
+		out.PartitionBy = append(out.PartitionBy, col)
+	}
+	for _, f := range table.Layout.ClusterBy {
+		col, err := getColumn(f)
+		if err != nil {
+			return nil, err
+		}
+		out.ClusterBy = append(out.ClusterBy, col)
+	}
+	if f := table.Layout.DistributeBy; f != "" {
+		col, err := getColumn(f)
+		if err != nil {
+			return nil, err
+		}
+		out.DistributeBy = &col
+	}
+
+	return &out, nil
+}
+
+// validateLayoutBinding checks that the fields of a table layout can be
+// materialized, and requires them.
+func validateLayoutBinding(
+	res Resource,
+	layout *TableLayout,
+	collection pf.CollectionSpec,
+	constraints map[string]*pm.Response_Validated_Constraint,
+) error {
+	for _, fields := range [][]string{layout.PartitionBy, layout.ClusterBy} {
+		for idx, field := range fields {
+			if field == "" {
+				return fmt.Errorf("the layout of table %s includes an empty field name", res.Path())
+			} else if slices.Contains(fields[:idx], field) {
+				return fmt.Errorf("the layout of table %s includes field %q more than once", res.Path(), field)
+			}
+		}
+	}
+
+	for _, field := range layout.Fields() {
+		if collection.GetProjection(field) == nil {
+			return fmt.Errorf("the layout of table %s includes field %q, which is not a projection of collection %s", res.Path(), field, collection.Name)
+		}
+
+		if c := constraints[field]; c != nil && (c.Type == pm.Response_Validated_Constraint_FIELD_FORBIDDEN ||
+			c.Type == pm.Response_Validated_Constraint_UNSATISFIABLE) {
+			return fmt.Errorf("the layout of table %s includes field %q, which cannot be materialized: %s", res.Path(), field, c.Reason)
+		}
+		constraints[field] = &pm.Response_Validated_Constraint{
+			Type:   pm.Response_Validated_Constraint_FIELD_REQUIRED,
+			Reason: "This field is required by the partitioning or clustering configuration of the table",
+		}
+	}
+
+	return nil
+}
+
+// existingResource parses the previously applied resource configuration of a
+// binding.
+func existingResource(endpoint *Endpoint, existingResourceConfigJson json.RawMessage) (Resource, error) {
+	res := endpoint.NewResource(endpoint)
+	// The existing resource configuration is not parsed strictly, since it may
+	// have been written by an earlier version of the connector.
+	if err := json.Unmarshal(existingResourceConfigJson, res); err != nil {
+		return nil, fmt.Errorf("unmarshalling existing resource config: %w", err)
+	}
+
+	return res, nil
+}
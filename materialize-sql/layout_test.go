@@ -0,0 +1,128 @@
+package sql
+
+import (
+	"testing"
+
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+	"github.com/stretchr/testify/require"
+)
+
+type testLayoutResource struct {
+	table  string
+	layout *TableLayout
+}
+
+func (r testLayoutResource) Validate() error      { return nil }
+func (r testLayoutResource) Path() TablePath      { return TablePath{"a", "b", r.table} }
+func (r testLayoutResource) DeltaUpdates() bool   { return false }
+func (r testLayoutResource) Layout() *TableLayout { return r.layout }
+
+func TestResolveLayoutTable(t *testing.T) {
+	specBytes, err := testFS.ReadFile("testdata/generated_specs/flow.proto")
+	require.NoError(t, err)
+	var spec pf.MaterializationSpec
+	require.NoError(t, spec.Unmarshal(specBytes))
+
+	layout := &TableLayout{
+		PartitionBy:  []string{"flow_published_at"},
+		ClusterBy:    []string{"key1", "string"},
+		DistributeBy: "key2",
+	}
+
+	shape := BuildTableShape(&spec, 0, testLayoutResource{table: "key_value", layout: layout})
+	require.Equal(t, layout, shape.Layout)
+
+	table, err := ResolveTable(shape, newTestDialect())
+	require.NoError(t, err)
+	require.NotNil(t, table.LayoutColumns)
+
+	require.Len(t, table.LayoutColumns.PartitionBy, 1)
+	require.Equal(t, "flow_published_at", table.LayoutColumns.PartitionBy[0].Field)
+	require.Equal(t, "TIMESTAMPTZ NOT NULL", table.LayoutColumns.PartitionBy[0].DDL)
+	require.Equal(t, []string{"key1", "string"}, []string{
+		table.LayoutColumns.ClusterBy[0].Identifier,
+		table.LayoutColumns.ClusterBy[1].Identifier,
+	})
+	require.Equal(t, "key2", table.LayoutColumns.DistributeBy.Field)
+
+	// Tables with the default layout do not have layout columns.
+	shape = BuildTableShape(&spec, 0, testLayoutResource{table: "key_value"})
+	table, err = ResolveTable(shape, newTestDialect())
+	require.NoError(t, err)
+	require.Nil(t, table.LayoutColumns)
+
+	// Fields of the layout must be selected.
+	shape = BuildTableShape(&spec, 0, testLayoutResource{table: "key_value", layout: &TableLayout{ClusterBy: []string{"string"}}})
+	shape.Values = nil
+	_, err = ResolveTable(shape, newTestDialect())
+	require.ErrorContains(t, err, `field "string" of the table layout is not a selected field`)
+}
+
+func TestValidateLayoutBinding(t *testing.T) {
+	specBytes, err := testFS.ReadFile("testdata/generated_specs/flow.proto")
+	require.NoError(t, err)
+	var spec pf.MaterializationSpec
+	require.NoError(t, spec.Unmarshal(specBytes))
+	collection := spec.Bindings[0].Collection
+
+	res := testLayoutResource{table: "key_value", layout: &TableLayout{
+		PartitionBy: []string{"flow_published_at"},
+		ClusterBy:   []string{"string", "flow_published_at"},
+	}}
+
+	constraints := map[string]*pm.Response_Validated_Constraint{
+		"flow_published_at": {Type: pm.Response_Validated_Constraint_FIELD_OPTIONAL},
+		"string":            {Type: pm.Response_Validated_Constraint_LOCATION_RECOMMENDED},
+	}
+	require.NoError(t, validateLayoutBinding(res, res.layout, collection, constraints))
+	require.Equal(t, pm.Response_Validated_Constraint_FIELD_REQUIRED, constraints["flow_published_at"].Type)
+	require.Equal(t, pm.Response_Validated_Constraint_FIELD_REQUIRED, constraints["string"].Type)
+
+	missing := testLayoutResource{table: "key_value", layout: &TableLayout{ClusterBy: []string{"nope"}}}
+	require.ErrorContains(t,
+		validateLayoutBinding(missing, missing.layout, collection, constraints),
+		`includes field "nope", which is not a projection`,
+	)
+
+	duplicate := testLayoutResource{table: "key_value", layout: &TableLayout{ClusterBy: []string{"string", "key1", "string"}}}
+	require.ErrorContains(t,
+		validateLayoutBinding(duplicate, duplicate.layout, collection, constraints),
+		`includes field "string" more than once`,
+	)
+
+	empty := testLayoutResource{table: "key_value", layout: &TableLayout{PartitionBy: []string{""}}}
+	require.ErrorContains(t,
+		validateLayoutBinding(empty, empty.layout, collection, constraints),
+		"includes an empty field name",
+	)
+
+	constraints["string"] = &pm.Response_Validated_Constraint{
+		Type:   pm.Response_Validated_Constraint_FIELD_FORBIDDEN,
+		Reason: "forbidden",
+	}
+	require.ErrorContains(t,
+		validateLayoutBinding(res, res.layout, collection, constraints),
+		`includes field "string", which cannot be materialized: forbidden`,
+	)
+}
+
+func TestTableLayoutEqual(t *testing.T) {
+	type opts struct{ Granularity string }
+
+	var nilLayout *TableLayout
+	require.True(t, nilLayout.Equal(nil))
+	require.False(t, nilLayout.Equal(&TableLayout{}))
+	require.False(t, (&TableLayout{}).Equal(nil))
+
+	a := &TableLayout{ClusterBy: []string{"a", "b"}, Options: opts{Granularity: "DAY"}}
+	require.True(t, a.Equal(&TableLayout{ClusterBy: []string{"a", "b"}, Options: opts{Granularity: "DAY"}}))
+	require.False(t, a.Equal(&TableLayout{ClusterBy: []string{"b", "a"}, Options: opts{Granularity: "DAY"}}))
+	require.False(t, a.Equal(&TableLayout{ClusterBy: []string{"a", "b"}, Options: opts{Granularity: "HOUR"}}))
+
+	require.Equal(t, []string{"a", "b", "c"}, (&TableLayout{
+		PartitionBy:  []string{"a"},
+		ClusterBy:    []string{"b", "a"},
+		DistributeBy: "c",
+	}).Fields())
+}
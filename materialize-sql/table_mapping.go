@@ -23,6 +23,13 @@ type TableShape struct {
 	Comment string
 	// The table is operating in delta-updates mode (instead of a standard materialization).
 	DeltaUpdates bool
+	// The table is operating in history mode, and has a row for every version of each document.
+	History bool
+	// The physical layout of the table configured by its resource, or nil for the default layout.
+	Layout *TableLayout
+	// The name of the embedding column of the table configured by its resource, or empty if it
+	// doesn't have one.
+	EmbeddingColumn string
 
 	Keys, Values []Projection
 	Document     *Projection
@@ -42,6 +49,12 @@ type Table struct {
 	Keys, Values []Column
 	Document     *Column
 
+	// History columns of the table, which are only present if it is operating in history mode.
+	History *HistoryColumns
+
+	// Columns of the table's layout, which are only present if it has a configured Layout.
+	LayoutColumns *LayoutColumns
+
 	// The stateKey associated with this table's binding
 	StateKey string
 }
@@ -169,6 +182,22 @@ func ResolveTable(shape TableShape, dialect Dialect) (Table, error) {
 		*col = resolved
 	}
 
+	if shape.History {
+		history, err := resolveHistoryColumns(&table, dialect)
+		if err != nil {
+			return Table{}, fmt.Errorf("resolving %s: %w", shape.Path, err)
+		}
+		table.History = history
+	}
+
+	if shape.Layout != nil {
+		layout, err := resolveLayoutColumns(&table)
+		if err != nil {
+			return Table{}, fmt.Errorf("resolving %s: %w", shape.Path, err)
+		}
+		table.LayoutColumns = layout
+	}
+
 	return table, nil
 }
 
@@ -199,14 +228,17 @@ func BuildTableShape(spec *pf.MaterializationSpec, index int, resource Resource)
 	)
 
 	return TableShape{
-		Path:         resource.Path(),
-		Binding:      index,
-		Source:       binding.Collection.Name,
-		Comment:      comment,
-		DeltaUpdates: resource.DeltaUpdates(),
-		Keys:         keys,
-		Values:       values,
-		Document:     document,
+		Path:            resource.Path(),
+		Binding:         index,
+		Source:          binding.Collection.Name,
+		Comment:         comment,
+		DeltaUpdates:    resource.DeltaUpdates(),
+		History:         isHistoryMode(resource),
+		Layout:          resourceLayout(resource),
+		EmbeddingColumn: resourceEmbeddingColumn(resource),
+		Keys:            keys,
+		Values:          values,
+		Document:        document,
 	}
 }
 
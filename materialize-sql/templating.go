@@ -28,6 +28,7 @@ func MustParseTemplate(dialect Dialect, name, body string) *template.Template {
 		"First":      func(s []string) string { return s[0] },
 		"Backtick":   func() string { return "`" }, // Go string literals don't allow a ` character
 	})
+	template.Must(tpl.Parse(historyTemplates))
 	return template.Must(tpl.Parse(body))
 }
 
@@ -163,6 +163,15 @@ type TestTemplates struct {
 	// TableTemplates are all templates that take a Table as input for
 	// rendering.
 	TableTemplates []*template.Template
+	// HistoryTableTemplates are templates that take a Table as input and are
+	// rendered for a table in history mode. If any are provided, the history
+	// mode table is returned after the standard and delta updates tables.
+	HistoryTableTemplates []*template.Template
+	// LayoutTableTemplates are templates that take a Table as input and are
+	// rendered for a table with the configured Layout. If any are provided,
+	// the table with the layout is returned after all other tables.
+	LayoutTableTemplates []*template.Template
+	Layout               *TableLayout
 	// TplAddColumns is a template that adds one or more columns to a table.
 	TplAddColumns *template.Template
 	// TplDropNotNulls is a template that drops one or more NOT NULL
@@ -216,6 +225,38 @@ func RunSqlGenTests(
 		}
 	}
 
+	if len(templates.HistoryTableTemplates) > 0 {
+		shape := BuildTableShape(&spec, 0, newResource(spec.Bindings[0].ResourcePath[0], false))
+		shape.History = true
+
+		table, err := ResolveTable(shape, dialect)
+		require.NoError(t, err)
+		tables = append(tables, table)
+
+		for _, tpl := range templates.HistoryTableTemplates {
+			var testcase = table.Identifier + " history " + tpl.Name()
+			snap.WriteString("--- Begin " + testcase + " ---\n")
+			require.NoError(t, tpl.Execute(&snap, &table))
+			snap.WriteString("--- End " + testcase + " ---\n\n")
+		}
+	}
+
+	if len(templates.LayoutTableTemplates) > 0 {
+		shape := BuildTableShape(&spec, 0, newResource(spec.Bindings[0].ResourcePath[0], false))
+		shape.Layout = templates.Layout
+
+		table, err := ResolveTable(shape, dialect)
+		require.NoError(t, err)
+		tables = append(tables, table)
+
+		for _, tpl := range templates.LayoutTableTemplates {
+			var testcase = table.Identifier + " layout " + tpl.Name()
+			snap.WriteString("--- Begin " + testcase + " ---\n")
+			require.NoError(t, tpl.Execute(&snap, &table))
+			snap.WriteString("--- End " + testcase + " ---\n\n")
+		}
+	}
+
 	addCols := []Column{
 		{Identifier: "first_new_column", MappedType: MappedType{NullableDDL: "STRING"}},
 		{Identifier: "second_new_column", MappedType: MappedType{NullableDDL: "BOOL"}},
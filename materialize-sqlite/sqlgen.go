@@ -152,6 +152,15 @@ var (
   {{- end -}}
   ;
   {{ end }}
+
+  {{ define "updateFence" }}
+  UPDATE {{ Identifier $.TablePath }}
+    SET   checkpoint = {{ Literal (Base64Std $.Checkpoint) }}
+    WHERE materialization = {{ Literal $.Materialization.String }}
+    AND   key_begin = {{ $.KeyBegin }}
+    AND   key_end   = {{ $.KeyEnd }}
+    AND   fence     = {{ $.Fence }};
+  {{ end }}
   `)
 	tplCreateTargetTable = tplAll.Lookup("createTargetTable")
 	tplCreateLoadTable   = tplAll.Lookup("createLoadTable")
@@ -162,6 +171,8 @@ var (
 
 	tplStoreInsert = tplAll.Lookup("storeInsert")
 	tplStoreUpdate = tplAll.Lookup("storeUpdate")
+
+	tplUpdateFence = tplAll.Lookup("updateFence")
 )
 
 const attachSQL = "ATTACH DATABASE '' AS load ;"
@@ -4,10 +4,13 @@ import (
 	"context"
 	stdsql "database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
@@ -19,18 +22,76 @@ import (
 	"go.gazette.dev/core/consumer/protocol"
 )
 
-const databasePath = "/tmp/sqlite.db"
+const defaultDatabasePath = "/tmp/sqlite.db"
 
 type config struct {
-	path string
+	Path     string          `json:"path,omitempty" jsonschema:"title=Database Path,description=Absolute path of the SQLite database file. This may be on a mounted volume. Defaults to /tmp/sqlite.db which is limited to 500MB."`
+	WAL      bool            `json:"wal,omitempty" jsonschema:"title=WAL Mode,description=Use write-ahead logging for the database journal. This allows the database to be read while it is being written to.,default=false"`
+	Snapshot *snapshotConfig `json:"snapshot,omitempty" jsonschema:"title=Snapshots,description=Periodically write a consistent copy of the database to a second path."`
+}
+
+type snapshotConfig struct {
+	Path     string `json:"path,omitempty" jsonschema:"title=Snapshot Path,description=Absolute path to write snapshots of the database to with VACUUM INTO. Each snapshot replaces the previous one. Snapshots are disabled if this is not set."`
+	Interval string `json:"interval,omitempty" jsonschema:"title=Snapshot Interval,description=Minimum time between snapshots as a Go duration string. A snapshot is written after the first transaction that commits once this much time has passed since the last one. Defaults to 1h."`
 }
 
 func (c config) Validate() error {
+	if c.Path != "" && !filepath.IsAbs(c.Path) {
+		return fmt.Errorf("path %q must be absolute", c.Path)
+	}
+
+	if c.Snapshot != nil {
+		if err := c.Snapshot.validate(c.databasePath()); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c snapshotConfig) validate(databasePath string) error {
+	if c.Path != "" {
+		if !filepath.IsAbs(c.Path) {
+			return fmt.Errorf("path %q must be absolute", c.Path)
+		} else if filepath.Clean(c.Path) == filepath.Clean(databasePath) {
+			return fmt.Errorf("path must be different than the database path")
+		}
+	}
+
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil {
+			return fmt.Errorf("parsing interval %q: %w", c.Interval, err)
+		} else if d <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+	}
+
 	return nil
 }
 
+func (c config) databasePath() string {
+	if c.Path == "" {
+		return defaultDatabasePath
+	}
+	return c.Path
+}
+
+// snapshotsEnabled returns true if snapshots of the database are configured.
+func (c config) snapshotsEnabled() bool {
+	return c.Snapshot != nil && c.Snapshot.Path != ""
+}
+
+func (c config) snapshotInterval() time.Duration {
+	if c.Snapshot == nil || c.Snapshot.Interval == "" {
+		return time.Hour
+	}
+	d, _ := time.ParseDuration(c.Snapshot.Interval)
+	return d
+}
+
 type tableConfig struct {
 	Table string `json:"table" jsonschema_extras:"x-collection-name=true"`
+	Delta bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
 }
 
 func (c tableConfig) Validate() error {
@@ -45,7 +106,7 @@ func (c tableConfig) Path() sql.TablePath {
 }
 
 func (c tableConfig) DeltaUpdates() bool {
-	return false // SQLite doesn't support delta updates. We probably _could_ support it though.
+	return c.Delta
 }
 
 func newTableConfig(ep *sql.Endpoint) sql.Resource {
@@ -59,27 +120,16 @@ func NewSQLiteDriver() *sql.Driver {
 		EndpointSpecType: new(config),
 		ResourceSpecType: new(tableConfig),
 		StartTunnel:      func(ctx context.Context, conf any) error { return nil },
-		NewEndpoint: func(ctx context.Context, _ json.RawMessage, _ string) (*sql.Endpoint, error) {
-			var path = databasePath
-
-			// SQLite / go-sqlite3 is a bit fickle about raced opens of a newly created database,
-			// often returning "database is locked" errors. We can resolve by ensuring one sql.Open
-			// completes before the next starts. This is only required for SQLite, not other drivers.
-			sqliteOpenMu.Lock()
-			db, err := stdsql.Open("sqlite3", path)
-			if err == nil {
-				err = db.PingContext(ctx)
-			}
-			sqliteOpenMu.Unlock()
-
-			if err != nil {
-				return nil, fmt.Errorf("opening SQLite database %q: %w", path, err)
+		NewEndpoint: func(ctx context.Context, raw json.RawMessage, _ string) (*sql.Endpoint, error) {
+			var cfg config
+			if err := pf.UnmarshalStrict(raw, &cfg); err != nil {
+				return nil, fmt.Errorf("parsing endpoint configuration: %w", err)
 			}
 
 			return &sql.Endpoint{
-				Config:              config{path: path},
+				Config:              cfg,
 				Dialect:             sqliteDialect,
-				MetaCheckpoints:     nil,
+				MetaCheckpoints:     sql.FlowCheckpointsTable(nil),
 				NewClient:           newClient,
 				CreateTableTemplate: tplCreateTargetTable,
 				NewResource:         newTableConfig,
@@ -91,17 +141,59 @@ func NewSQLiteDriver() *sql.Driver {
 	}
 }
 
+// openDatabase creates the database file and its directory if they don't
+// exist, and sets its journal mode. The journal mode is persisted in the
+// database file, so it only needs to be set once rather than for every
+// connection.
+func openDatabase(ctx context.Context, cfg config) error {
+	var path = cfg.databasePath()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating directory for SQLite database %q: %w", path, err)
+	}
+
+	var journalMode = "DELETE"
+	if cfg.WAL {
+		journalMode = "WAL"
+	}
+
+	// SQLite / go-sqlite3 is a bit fickle about raced opens of a newly created database,
+	// often returning "database is locked" errors. We can resolve by ensuring one sql.Open
+	// completes before the next starts. This is only required for SQLite, not other drivers.
+	sqliteOpenMu.Lock()
+	db, err := stdsql.Open("sqlite3", path)
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	if err == nil {
+		_, err = db.ExecContext(ctx, fmt.Sprintf("PRAGMA journal_mode=%s;", journalMode))
+	}
+	if db != nil {
+		db.Close()
+	}
+	sqliteOpenMu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("opening SQLite database %q: %w", path, err)
+	}
+
+	return nil
+}
+
 type client struct {
-	db *stdsql.DB
+	db  *stdsql.DB
+	cfg config
 }
 
 func newClient(ctx context.Context, ep *sql.Endpoint) (sql.Client, error) {
-	db, err := stdsql.Open("sqlite3", databasePath)
+	var cfg = ep.Config.(config)
+
+	db, err := stdsql.Open("sqlite3", cfg.databasePath())
 	if err != nil {
 		return nil, err
 	}
 
-	return &client{db: db}, nil
+	return &client{db: db, cfg: cfg}, nil
 }
 
 func (c *client) InfoSchema(ctx context.Context, resourcePaths [][]string) (is *boilerplate.InfoSchema, err error) {
@@ -116,6 +208,9 @@ func (c *client) AlterTable(ctx context.Context, ta sql.TableAlter) (string, boi
 }
 
 func (c *client) CreateTable(ctx context.Context, tc sql.TableCreate) error {
+	if err := openDatabase(ctx, c.cfg); err != nil {
+		return err
+	}
 	_, err := c.db.ExecContext(ctx, tc.TableCreateSql)
 	return err
 }
@@ -128,10 +223,21 @@ func (c *client) ExecStatements(ctx context.Context, statements []string) error
 	return sql.StdSQLExecStatements(ctx, c.db, statements)
 }
 
-// We don't need a fence since there is only going to be a single sqlite
-// materialization instance writing to the file, and it's ephemeral
+// InstallFence installs the fence in the checkpoints table of the database,
+// and returns the checkpoint of the last transaction committed to it. The
+// database may be new, so the checkpoints table is created if it doesn't exist.
 func (c *client) InstallFence(ctx context.Context, checkpoints sql.Table, fence sql.Fence) (sql.Fence, error) {
-	return sql.Fence{}, nil
+	if err := openDatabase(ctx, c.cfg); err != nil {
+		return sql.Fence{}, err
+	}
+
+	if statement, err := sql.RenderTableTemplate(checkpoints, tplCreateTargetTable); err != nil {
+		return sql.Fence{}, err
+	} else if _, err := c.db.ExecContext(ctx, statement); err != nil {
+		return sql.Fence{}, fmt.Errorf("creating checkpoints table: %w", err)
+	}
+
+	return sql.StdInstallFence(ctx, c.db, checkpoints, fence)
 }
 
 func (c *client) Close() {
@@ -147,20 +253,25 @@ func newTransactor(
 	is *boilerplate.InfoSchema,
 	_ *boilerplate.BindingEvents,
 ) (_ m.Transactor, _ *boilerplate.MaterializeOptions, err error) {
+	var cfg = ep.Config.(config)
+
 	var d = &transactor{
 		dialect: &sqliteDialect,
+		cfg:     cfg,
 	}
 	d.store.fence = &fence
 
-	var cfg = ep.Config.(config)
+	if err := openDatabase(ctx, cfg); err != nil {
+		return nil, nil, err
+	}
 
 	// Establish connections.
-	if db, err := stdsql.Open("sqlite3", cfg.path); err != nil {
+	if db, err := stdsql.Open("sqlite3", cfg.databasePath()); err != nil {
 		return nil, nil, fmt.Errorf("load DB.Open: %w", err)
 	} else if d.load.conn, err = db.Conn(ctx); err != nil {
 		return nil, nil, fmt.Errorf("load DB.Conn: %w", err)
 	}
-	if db, err := stdsql.Open("sqlite3", cfg.path); err != nil {
+	if db, err := stdsql.Open("sqlite3", cfg.databasePath()); err != nil {
 		return nil, nil, fmt.Errorf("store DB.Open: %w", err)
 	} else if d.store.conn, err = db.Conn(ctx); err != nil {
 		return nil, nil, fmt.Errorf("store DB.Conn: %w", err)
@@ -177,7 +288,8 @@ func newTransactor(
 		}
 	}
 
-	// Since sqlite is ephemeral, we have to re-create tables before transactions
+	// The database may be ephemeral, so tables are created before transactions
+	// if they don't already exist.
 	for _, table := range bindings {
 		if statement, err := sql.RenderTableTemplate(table, ep.CreateTableTemplate); err != nil {
 			return nil, nil, err
@@ -200,6 +312,10 @@ func newTransactor(
 
 type transactor struct {
 	dialect *sql.Dialect
+	cfg     config
+
+	// lastSnapshot is when the database was last written to the snapshot path.
+	lastSnapshot time.Time
 
 	unionSQL string
 
@@ -323,8 +439,12 @@ func (d *transactor) Load(
 }
 
 func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
-	if err := checkDatabaseSize(); err != nil {
-		return nil, err
+	if d.cfg.Path == "" {
+		// The default database path is a temporary file, which is limited
+		// in size.
+		if err := checkDatabaseSize(d.cfg.databasePath()); err != nil {
+			return nil, err
+		}
 	}
 
 	var txn, err = d.store.conn.BeginTx(it.Context(), nil)
@@ -353,23 +473,56 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 
 	return func(ctx context.Context, runtimeCheckpoint *protocol.Checkpoint) (*pf.ConnectorState, m.OpFuture) {
 		return nil, m.RunAsyncOperation(func() error {
+			defer txn.Rollback()
+
+			var err error
+			if d.store.fence.Checkpoint, err = runtimeCheckpoint.Marshal(); err != nil {
+				return fmt.Errorf("marshalling checkpoint: %w", err)
+			} else if err := updateFence(ctx, txn, *d.store.fence); err != nil {
+				return err
+			}
+
 			if err = txn.Commit(); err != nil {
 				return fmt.Errorf("commit transaction: %w", err)
 			}
 
+			if d.cfg.snapshotsEnabled() && time.Since(d.lastSnapshot) >= d.cfg.snapshotInterval() {
+				if err := d.snapshot(ctx); err != nil {
+					return err
+				}
+			}
+
 			return nil
 		})
 	}, nil
 }
 
+// updateFence writes the checkpoint of the fence to the checkpoints table
+// within the transaction of the stored documents, failing if the fence has
+// been installed by another instance of the materialization.
+func updateFence(ctx context.Context, txn *stdsql.Tx, fence sql.Fence) error {
+	var fenceUpdate strings.Builder
+	if err := tplUpdateFence.Execute(&fenceUpdate, fence); err != nil {
+		return fmt.Errorf("evaluating fence template: %w", err)
+	}
+
+	if results, err := txn.ExecContext(ctx, fenceUpdate.String()); err != nil {
+		return fmt.Errorf("updating flow checkpoint: %w", err)
+	} else if rowsAffected, err := results.RowsAffected(); err != nil {
+		return fmt.Errorf("updating flow checkpoint (rows affected): %w", err)
+	} else if rowsAffected < 1 {
+		return fmt.Errorf("this instance was fenced off by another")
+	}
+
+	return nil
+}
+
 const maximumDatabaseSize = 500 * 1024 * 1024 // 500 megabytes
 const maximumDatabaseSizeText = "500mb"
 
-func checkDatabaseSize() error {
-	if file, err := os.Open(databasePath); err != nil {
-		return fmt.Errorf("cannot open database file to check for size: %w", err)
-	} else if stat, err := file.Stat(); err != nil {
-		return fmt.Errorf("cannot stat file to check for size: %w", err)
+func checkDatabaseSize(path string) error {
+	if stat, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot stat database file to check for size: %w", err)
 	} else if stat.Size() > maximumDatabaseSize {
 		return fmt.Errorf("sqlite database has exceeded maximum size %s", maximumDatabaseSizeText)
 	}
@@ -377,7 +530,40 @@ func checkDatabaseSize() error {
 	return nil
 }
 
-// RuntimeCommitted is a no-op since the SQLite database is authoritative.
+// snapshot writes a copy of the database to the snapshot path. The copy is
+// written to a temporary file first and then renamed, so that a complete
+// snapshot is always present at the snapshot path.
+func (d *transactor) snapshot(ctx context.Context) error {
+	var path = d.cfg.Snapshot.Path
+	var tmpPath = path + ".tmp"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	// VACUUM INTO fails if its target already exists, which it might if a
+	// previous snapshot was interrupted.
+	if err := os.Remove(tmpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing prior temporary snapshot: %w", err)
+	}
+
+	var start = time.Now()
+	if _, err := d.store.conn.ExecContext(ctx, "VACUUM INTO ?;", tmpPath); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	} else if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("renaming snapshot: %w", err)
+	}
+	d.lastSnapshot = start
+
+	log.WithFields(log.Fields{
+		"path":     path,
+		"duration": time.Since(start).String(),
+	}).Info("wrote database snapshot")
+
+	return nil
+}
+
+// RuntimeCommitted is a no-op since the runtime checkpoint is committed to the
+// SQLite database along with the stored documents.
 func (d *transactor) RuntimeCommitted(context.Context) error { return nil }
 
 func (d *transactor) Destroy() {
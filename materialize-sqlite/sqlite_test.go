@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+
+	sql "github.com/estuary/connectors/materialize-sql"
+	"github.com/estuary/flow/go/protocols/fdb/tuple"
+	pf "github.com/estuary/flow/go/protocols/flow"
+	pm "github.com/estuary/flow/go/protocols/materialize"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  config
+		want string
+	}{
+		{"defaults", config{}, ""},
+		{"custom path", config{Path: "/data/db.sqlite", WAL: true}, ""},
+		{"relative path", config{Path: "db.sqlite"}, `path "db.sqlite" must be absolute`},
+		{"snapshot", config{Snapshot: &snapshotConfig{Path: "/data/snapshot.sqlite", Interval: "15m"}}, ""},
+		{"snapshot without path", config{Snapshot: &snapshotConfig{}}, ""},
+		{"relative snapshot path", config{Snapshot: &snapshotConfig{Path: "snapshot.sqlite"}}, `snapshot: path "snapshot.sqlite" must be absolute`},
+		{"snapshot of database path", config{Snapshot: &snapshotConfig{Path: defaultDatabasePath}}, "snapshot: path must be different than the database path"},
+		{"invalid snapshot interval", config{Snapshot: &snapshotConfig{Path: "/data/snapshot.sqlite", Interval: "soon"}}, `snapshot: parsing interval "soon"`},
+		{"negative snapshot interval", config{Snapshot: &snapshotConfig{Path: "/data/snapshot.sqlite", Interval: "-1m"}}, "snapshot: interval must be positive"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.want == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func testTable(t *testing.T, name string, binding int, delta bool) sql.Table {
+	shape := sql.TableShape{
+		Path:         sql.TablePath{name},
+		Binding:      binding,
+		DeltaUpdates: delta,
+		Keys: []sql.Projection{{Projection: pf.Projection{
+			Field:     "id",
+			Inference: pf.Inference{Types: []string{"string"}, String_: &pf.Inference_String{}, Exists: pf.Inference_MUST},
+		}}},
+		Values: []sql.Projection{{Projection: pf.Projection{
+			Field:     "value",
+			Inference: pf.Inference{Types: []string{"integer"}, Exists: pf.Inference_MUST},
+		}}},
+	}
+	if !delta {
+		shape.Document = &sql.Projection{Projection: pf.Projection{
+			Field:     "flow_document",
+			Inference: pf.Inference{Types: []string{"object"}, Exists: pf.Inference_MUST},
+		}}
+	}
+
+	table, err := sql.ResolveTable(shape, sqliteDialect)
+	require.NoError(t, err)
+	return table
+}
+
+func newTestTransactor(t *testing.T, cfg config, tables ...sql.Table) *transactor {
+	ctx := context.Background()
+
+	raw, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	ep, err := NewSQLiteDriver().NewEndpoint(ctx, raw, "")
+	require.NoError(t, err)
+
+	tr, _, err := newTransactor(ctx, ep, sql.Fence{}, tables, pm.Request_Open{}, nil, nil)
+	require.NoError(t, err)
+
+	return tr.(*transactor)
+}
+
+func storeRow(t *testing.T, tr *transactor, binding int, stmt string, key string, value int64) error {
+	b := tr.bindings[binding]
+
+	var doc json.RawMessage
+	if b.target.Document != nil {
+		doc = json.RawMessage(`{"id":"` + key + `"}`)
+	}
+	converted, err := b.target.ConvertAll(tuple.Tuple{key}, tuple.Tuple{value}, doc)
+	require.NoError(t, err)
+
+	_, err = tr.store.conn.ExecContext(context.Background(), stmt, converted...)
+	return err
+}
+
+func countRows(t *testing.T, path string, table string) int {
+	db, err := stdsql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table+";").Scan(&count))
+	return count
+}
+
+func TestTransactorPath(t *testing.T) {
+	// The directory of the database is created if it doesn't exist.
+	path := filepath.Join(t.TempDir(), "nested", "dir", "db.sqlite")
+	tr := newTestTransactor(t, config{Path: path}, testTable(t, "standard", 0, false))
+	defer tr.Destroy()
+
+	require.NoError(t, storeRow(t, tr, 0, tr.bindings[0].store.insertSQL, "a", 1))
+	require.Equal(t, 1, countRows(t, path, "standard"))
+	require.NoError(t, checkDatabaseSize(path))
+}
+
+func TestTransactorDeltaUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	tr := newTestTransactor(t, config{Path: path}, testTable(t, "standard", 0, false), testTable(t, "delta", 1, true))
+	defer tr.Destroy()
+
+	// Standard tables have a primary key, so a key can only be inserted once.
+	require.NoError(t, storeRow(t, tr, 0, tr.bindings[0].store.insertSQL, "a", 1))
+	require.ErrorContains(t, storeRow(t, tr, 0, tr.bindings[0].store.insertSQL, "a", 2), "UNIQUE constraint failed")
+	require.NoError(t, storeRow(t, tr, 0, tr.bindings[0].store.updateSQL, "a", 2))
+	require.Equal(t, 1, countRows(t, path, "standard"))
+
+	// Delta updates tables have no primary key, and every store is appended.
+	require.NoError(t, storeRow(t, tr, 1, tr.bindings[1].store.insertSQL, "a", 1))
+	require.NoError(t, storeRow(t, tr, 1, tr.bindings[1].store.insertSQL, "a", 2))
+	require.Equal(t, 2, countRows(t, path, "delta"))
+}
+
+func TestTransactorWAL(t *testing.T) {
+	journalMode := func(path string) string {
+		db, err := stdsql.Open("sqlite3", path)
+		require.NoError(t, err)
+		defer db.Close()
+
+		var mode string
+		require.NoError(t, db.QueryRow("PRAGMA journal_mode;").Scan(&mode))
+		return mode
+	}
+
+	walPath := filepath.Join(t.TempDir(), "wal.sqlite")
+	newTestTransactor(t, config{Path: walPath, WAL: true}, testTable(t, "standard", 0, false)).Destroy()
+	require.Equal(t, "wal", journalMode(walPath))
+
+	defaultPath := filepath.Join(t.TempDir(), "default.sqlite")
+	newTestTransactor(t, config{Path: defaultPath}, testTable(t, "standard", 0, false)).Destroy()
+	require.Equal(t, "delete", journalMode(defaultPath))
+}
+
+func TestTransactorSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db.sqlite")
+	snapshotPath := filepath.Join(dir, "snapshots", "snapshot.sqlite")
+
+	tr := newTestTransactor(t, config{
+		Path:     path,
+		WAL:      true,
+		Snapshot: &snapshotConfig{Path: snapshotPath},
+	}, testTable(t, "standard", 0, false))
+	defer tr.Destroy()
+	require.True(t, tr.cfg.snapshotsEnabled())
+
+	require.NoError(t, storeRow(t, tr, 0, tr.bindings[0].store.insertSQL, "a", 1))
+	require.NoError(t, tr.snapshot(context.Background()))
+	require.Equal(t, 1, countRows(t, snapshotPath, "standard"))
+	require.False(t, tr.lastSnapshot.IsZero())
+
+	// Later snapshots replace earlier ones.
+	require.NoError(t, storeRow(t, tr, 0, tr.bindings[0].store.insertSQL, "b", 2))
+	require.NoError(t, tr.snapshot(context.Background()))
+	require.Equal(t, 2, countRows(t, snapshotPath, "standard"))
+	require.NoFileExists(t, snapshotPath+".tmp")
+}
+
+func TestFence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "db.sqlite")
+
+	raw, err := json.Marshal(config{Path: path})
+	require.NoError(t, err)
+	ep, err := NewSQLiteDriver().NewEndpoint(ctx, raw, "")
+	require.NoError(t, err)
+	checkpoints, err := sql.ResolveTable(*ep.MetaCheckpoints, ep.Dialect)
+	require.NoError(t, err)
+
+	installFence := func() sql.Fence {
+		c, err := newClient(ctx, ep)
+		require.NoError(t, err)
+		defer c.Close()
+
+		fence, err := c.InstallFence(ctx, checkpoints, sql.Fence{
+			TablePath:       ep.MetaCheckpoints.Path,
+			Materialization: "acmeCo/sqlite",
+			KeyBegin:        0,
+			KeyEnd:          math.MaxUint32,
+			Checkpoint:      pm.ExplicitZeroCheckpoint,
+		})
+		require.NoError(t, err)
+		return fence
+	}
+	updateFence := func(fence sql.Fence, checkpoint string) error {
+		db, err := stdsql.Open("sqlite3", path)
+		require.NoError(t, err)
+		defer db.Close()
+
+		txn, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txn.Rollback()
+
+		fence.Checkpoint = []byte(checkpoint)
+		if err := updateFence(ctx, txn, fence); err != nil {
+			return err
+		}
+		return txn.Commit()
+	}
+
+	// The checkpoints table is created in a new database.
+	first := installFence()
+	require.Equal(t, pm.ExplicitZeroCheckpoint, first.Checkpoint)
+	require.NoError(t, updateFence(first, "committed"))
+
+	// The checkpoint committed to the database is recovered by the next
+	// instance, which fences off the prior one.
+	second := installFence()
+	require.Equal(t, first.Fence+1, second.Fence)
+	require.Equal(t, []byte("committed"), second.Checkpoint)
+	require.ErrorContains(t, updateFence(first, "zombie"), "fenced off")
+	require.NoError(t, updateFence(second, "next"))
+}
@@ -100,9 +100,10 @@ func (c *config) ToURI() string {
 }
 
 type tableConfig struct {
-	Table  string `json:"table" jsonschema:"title=Table,description=Name of the database table" jsonschema_extras:"x-collection-name=true"`
-	Schema string `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)" jsonschema_extras:"x-schema-name=true"`
-	Delta  bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	Table   string `json:"table" jsonschema:"title=Table,description=Name of the database table" jsonschema_extras:"x-collection-name=true"`
+	Schema  string `json:"schema,omitempty" jsonschema:"title=Alternative Schema,description=Alternative schema for this table (optional)" jsonschema_extras:"x-schema-name=true"`
+	Delta   bool   `json:"delta_updates,omitempty" jsonschema:"default=false,title=Delta Update,description=Should updates to this table be done via delta updates. Default is false." jsonschema_extras:"x-delta-updates=true"`
+	History bool   `json:"history_mode,omitempty" jsonschema:"default=false,title=History Mode,description=Keep a row for every version of each document instead of only the latest version. Rows have valid_from and valid_to times and an is_current flag. Default is false."`
 }
 
 func newTableConfig(ep *sql.Endpoint) sql.Resource {
@@ -132,6 +133,10 @@ func (c tableConfig) DeltaUpdates() bool {
 	return c.Delta
 }
 
+func (c tableConfig) HistoryMode() bool {
+	return c.History
+}
+
 func newSqlServerDriver() *sql.Driver {
 	return &sql.Driver{
 		DocumentationURL: "https://go.estuary.dev/materialize-sqlserver",
@@ -312,6 +317,13 @@ type binding struct {
 func (t *transactor) addBinding(ctx context.Context, target sql.Table) error {
 	var b = &binding{target: target}
 
+	var mergeTpl, directCopyTpl = t.templates.mergeInto, t.templates.directCopy
+	if target.History != nil {
+		// Documents are always applied to history tables by closing out their
+		// current rows and inserting new ones.
+		mergeTpl, directCopyTpl = t.templates.historyMerge, t.templates.historyMerge
+	}
+
 	for _, m := range []struct {
 		sql *string
 		tpl *template.Template
@@ -324,8 +336,8 @@ func (t *transactor) addBinding(ctx context.Context, target sql.Table) error {
 		{&b.tempStoreTruncate, t.templates.tempStoreTruncate},
 		{&b.tempStoreTableName, t.templates.tempStoreTableName},
 		{&b.tempLoadTableName, t.templates.tempLoadTableName},
-		{&b.mergeInto, t.templates.mergeInto},
-		{&b.directCopy, t.templates.directCopy},
+		{&b.mergeInto, mergeTpl},
+		{&b.directCopy, directCopyTpl},
 	} {
 		var err error
 		if *m.sql, err = sql.RenderTableTemplate(target, m.tpl); err != nil {
@@ -495,7 +507,7 @@ func (d *transactor) Store(it *m.StoreIterator) (_ m.StartCommitFunc, err error)
 		var b = d.bindings[it.Binding]
 
 		var flowDocument = it.RawJSON
-		if d.cfg.HardDelete && it.Delete {
+		if (d.cfg.HardDelete || b.target.History != nil) && it.Delete {
 			if it.Exists {
 				flowDocument = json.RawMessage(`"delete"`)
 			} else {
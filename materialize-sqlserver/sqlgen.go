@@ -163,6 +163,7 @@ type templates struct {
 	createTargetTable  *template.Template
 	directCopy         *template.Template
 	mergeInto          *template.Template
+	historyMerge       *template.Template
 	loadInsert         *template.Template
 	loadQuery          *template.Template
 	updateFence        *template.Template
@@ -188,6 +189,7 @@ CREATE TABLE {{$.Identifier}} (
 		{{- if $ind }},{{ end }}
 		{{$col.Identifier}} {{$col.DDL}}
 	{{- end }}
+	{{- template "historyColumnDefinitions" $ }}
 	{{- if not $.DeltaUpdates }},
 
 		PRIMARY KEY (
@@ -195,6 +197,7 @@ CREATE TABLE {{$.Identifier}} (
 		{{- if $ind }}, {{end -}}
 		{{$key.Identifier}}
 	{{- end -}}
+	{{- template "historyKeyColumns" $ -}}
 	)
 	{{- end }}
 );
@@ -292,6 +295,9 @@ SELECT {{ $.Binding }}, r.{{$.Document.Identifier}}
 		{{ if $ind }} AND {{ else }} ON  {{ end -}}
 		l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
 	{{- end }}
+	{{- if $.History }}
+		AND r.{{ $.History.IsCurrent.Identifier }} = 1
+	{{- end }}
 {{ else -}}
 SELECT TOP 0 -1, NULL
 {{ end }}
@@ -355,6 +361,36 @@ SELECT TOP 0 -1, NULL
 	);
 {{ end }}
 
+-- SQL Server has no boolean literals, so the is_current column of new rows in
+-- history mode is set to 1.
+
+{{ define "historyTrue" }}1{{ end }}
+
+-- Templated query which applies staged documents to a table in history mode.
+-- The current row of each staged document is closed out, and a new current
+-- row is inserted for each staged document that isn't a deletion.
+
+{{ define "historyMerge" }}
+	UPDATE l
+	SET l.{{ $.History.ValidTo.Identifier }} = r.{{ $.History.PublishedAt.Identifier }},
+		l.{{ $.History.IsCurrent.Identifier }} = 0
+	FROM {{ $.Identifier }} AS l
+	JOIN {{ template "temp_store_name" . }} AS r
+	ON {{ range $ind, $key := $.Keys }}
+		{{- if $ind }} AND {{ end -}}
+		l.{{ $key.Identifier }} = r.{{ $key.Identifier }}
+	{{- end }}
+	WHERE l.{{ $.History.IsCurrent.Identifier }} = 1
+	AND l.{{ $.History.ValidFrom.Identifier }} < r.{{ $.History.PublishedAt.Identifier }};
+
+	INSERT INTO {{ $.Identifier }} ({{ template "historyInsertColumns" $ }})
+	SELECT {{ template "historyInsertValues" $ }}
+	FROM {{ template "temp_store_name" . }} AS r
+	{{- if $.Document }}
+	WHERE r.{{ $.Document.Identifier }} != '"delete"'
+	{{- end }};
+{{ end }}
+
 {{ define "updateFence" }}
 UPDATE {{ Identifier $.TablePath }}
 	SET   "checkpoint" = {{ Literal (Base64Std $.Checkpoint) }}
@@ -376,6 +412,7 @@ UPDATE {{ Identifier $.TablePath }}
 		createTargetTable:  tplAll.Lookup("createTargetTable"),
 		directCopy:         tplAll.Lookup("directCopy"),
 		mergeInto:          tplAll.Lookup("mergeInto"),
+		historyMerge:       tplAll.Lookup("historyMerge"),
 		loadInsert:         tplAll.Lookup("loadInsert"),
 		loadQuery:          tplAll.Lookup("loadQuery"),
 		updateFence:        tplAll.Lookup("updateFence"),
@@ -38,6 +38,11 @@ func TestSQLGeneration(t *testing.T) {
 				templates.loadInsert,
 				templates.loadQuery,
 			},
+			HistoryTableTemplates: []*template.Template{
+				templates.createTargetTable,
+				templates.loadQuery,
+				templates.historyMerge,
+			},
 			TplAddColumns:  templates.alterTableColumns,
 			TplUpdateFence: templates.updateFence,
 		},
@@ -1,20 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	"os"
 )
 
 type s3config struct {
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=Access Key ID" jsonschema_extras:"order=0"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=Secret Access Key" jsonschema_extras:"secret=true,order=1"`
-	Region             string `json:"region" jsonschema:"title=Region" jsonschema_extras:"order=2"`
-	Bucket             string `json:"bucket" jsonschema:"title=Bucket" jsonschema_extras:"order=3"`
+	Credentials awsauth.CredentialConfig `json:"credentials" jsonschema:"title=Authentication" jsonschema_extras:"order=0"`
+	Region      string                   `json:"region" jsonschema:"title=Region" jsonschema_extras:"order=1"`
+	Bucket      string                   `json:"bucket" jsonschema:"title=Bucket" jsonschema_extras:"order=2"`
 }
 
 // CloudOperator is the interface of the object responsible for uploading local files to the cloud.
@@ -73,18 +74,17 @@ func (u *S3Operator) Delete(prefix string) error {
 }
 
 // NewS3Operator creates an uploader for s3 given input configs.
-func NewS3Operator(cfg s3config) (*S3Operator, error) {
+func NewS3Operator(ctx context.Context, cfg s3config) (*S3Operator, error) {
 	var c = aws.NewConfig()
 	// We use localstack on a docker network for testing this connector, and so we
 	// need to use path style addressing of S3
 	c.S3ForcePathStyle = aws.Bool(true)
 
-	if cfg.AWSSecretAccessKey != "" {
-		var creds = credentials.NewStaticCredentials(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, "")
-		c = c.WithCredentials(creds)
-	} else {
-		c = c.WithCredentials(credentials.AnonymousCredentials)
+	creds, err := cfg.Credentials.V1Credentials(ctx, awsConfig.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("creating aws credentials: %w", err)
 	}
+	c = c.WithCredentials(creds)
 	c = c.WithCredentialsChainVerboseErrors(true)
 
 	if cfg.Region != "" {
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/url"
 
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	sql "github.com/estuary/connectors/materialize-sql"
@@ -20,16 +21,16 @@ import (
 // config represents the endpoint configuration for starburst.
 // It must match the one defined for the source specs (flow.yaml) in Rust.
 type config struct {
-	Host               string `json:"host" jsonschema:"title=Host and optional port" jsonschema_extras:"order=0"`
-	Catalog            string `json:"catalog" jsonschema:"title=Catalog" jsonschema_extras:"order=1"`
-	Schema             string `json:"schema" jsonschema:"title=Schema" jsonschema_extras:"order=2"`
-	Account            string `json:"account" jsonschema:"title=Account" jsonschema_extras:"order=3"`
-	Password           string `json:"password" jsonschema:"title=Password" jsonschema_extras:"secret=true,order=4"`
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=Access Key ID" jsonschema_extras:"order=5"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=Secret Access Key" jsonschema_extras:"secret=true,order=6"`
-	Region             string `json:"region" jsonschema:"title=Region" jsonschema_extras:"order=7"`
-	Bucket             string `json:"bucket" jsonschema:"title=Bucket" jsonschema_extras:"order=8"`
-	BucketPath         string `json:"bucketPath" jsonschema:"title=Bucket Path,description=A prefix that will be used to store objects in S3." jsonschema_extras:"order=9"`
+	Host        string                    `json:"host" jsonschema:"title=Host and optional port" jsonschema_extras:"order=0"`
+	Catalog     string                    `json:"catalog" jsonschema:"title=Catalog" jsonschema_extras:"order=1"`
+	Schema      string                    `json:"schema" jsonschema:"title=Schema" jsonschema_extras:"order=2"`
+	Account     string                    `json:"account" jsonschema:"title=Account" jsonschema_extras:"order=3"`
+	Password    string                    `json:"password" jsonschema:"title=Password" jsonschema_extras:"secret=true,order=4"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication,description=AWS credentials for the S3 bucket." jsonschema_extras:"order=5"`
+	awsauth.AccessKeys
+	Region     string `json:"region" jsonschema:"title=Region" jsonschema_extras:"order=8"`
+	Bucket     string `json:"bucket" jsonschema:"title=Bucket" jsonschema_extras:"order=9"`
+	BucketPath string `json:"bucketPath" jsonschema:"title=Bucket Path,description=A prefix that will be used to store objects in S3." jsonschema_extras:"order=10"`
 
 	Schedule boilerplate.ScheduleConfig `json:"syncSchedule,omitempty" jsonschema:"title=Sync Schedule,description=Configure schedule of transactions for the materialization."`
 }
@@ -58,8 +59,6 @@ func (c *config) Validate() error {
 		{"catalog", c.Catalog},
 		{"schema", c.Schema},
 		{"password", c.Password},
-		{"awsAccessKeyId", c.AWSAccessKeyID},
-		{"awsSecretAccessKey", c.AWSSecretAccessKey},
 		{"region", c.Region},
 		{"bucket", c.Bucket},
 		{"bucketPath", c.BucketPath},
@@ -70,6 +69,10 @@ func (c *config) Validate() error {
 		}
 	}
 
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -179,8 +182,8 @@ func newTransactor(
 		}
 	}
 
-	s3Config := s3config{AWSAccessKeyID: cfg.AWSAccessKeyID, AWSSecretAccessKey: cfg.AWSSecretAccessKey, Region: cfg.Region, Bucket: cfg.Bucket}
-	transactor.s3Operator, err = NewS3Operator(s3Config)
+	s3Config := s3config{Credentials: awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey), Region: cfg.Region, Bucket: cfg.Bucket}
+	transactor.s3Operator, err = NewS3Operator(ctx, s3Config)
 	if err != nil {
 		return nil, nil, fmt.Errorf("creating s3 operator: %w", err)
 	}
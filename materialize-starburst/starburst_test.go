@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
 	pm "github.com/estuary/flow/go/protocols/materialize"
 
@@ -142,16 +143,18 @@ func getSchema(ctx context.Context, db *stdsql.DB, catalog string, schema string
 
 func TestStarburstConfig(t *testing.T) {
 	var validConfig = config{
-		Host:               "test.com:400",
-		Catalog:            "mycatalog",
-		Schema:             "test_schema",
-		Account:            "test@acme.com/public",
-		Password:           "pass",
-		AWSAccessKeyID:     "AWSAccessKeyID",
-		AWSSecretAccessKey: "AWSSecretAccessKey",
-		Region:             "ue-east-1",
-		Bucket:             "test-bucket",
-		BucketPath:         "warehouse/test_dir",
+		Host:     "test.com:400",
+		Catalog:  "mycatalog",
+		Schema:   "test_schema",
+		Account:  "test@acme.com/public",
+		Password: "pass",
+		AccessKeys: awsauth.AccessKeys{
+			AWSAccessKeyID:     "AWSAccessKeyID",
+			AWSSecretAccessKey: "AWSSecretAccessKey",
+		},
+		Region:     "ue-east-1",
+		Bucket:     "test-bucket",
+		BucketPath: "warehouse/test_dir",
 	}
 	require.NoError(t, validConfig.Validate())
 	var uri = validConfig.ToURI()
@@ -185,6 +188,16 @@ func TestStarburstConfig(t *testing.T) {
 	noAwsSecretKey.AWSSecretAccessKey = ""
 	require.Error(t, noAwsSecretKey.Validate(), "expected validation error")
 
+	var assumeRole = validConfig
+	assumeRole.AWSAccessKeyID = ""
+	assumeRole.AWSSecretAccessKey = ""
+	assumeRole.Credentials = &awsauth.CredentialConfig{AuthType: awsauth.ASSUME_ROLE_AUTH_TYPE, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	require.NoError(t, assumeRole.Validate())
+
+	var noRoleArn = assumeRole
+	noRoleArn.Credentials = &awsauth.CredentialConfig{AuthType: awsauth.ASSUME_ROLE_AUTH_TYPE}
+	require.Error(t, noRoleArn.Validate(), "expected validation error")
+
 	var noRegion = validConfig
 	noRegion.Region = ""
 	require.Error(t, noRegion.Validate(), "expected validation error")
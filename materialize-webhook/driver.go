@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/estuary/connectors/filesink"
+	payloadtemplate "github.com/estuary/connectors/go/payload-template"
 	m "github.com/estuary/connectors/go/protocols/materialize"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
 	boilerplate "github.com/estuary/connectors/materialize-boilerplate"
@@ -38,9 +42,80 @@ type headers struct {
 	CustomHeaders []customHeader `json:"customHeaders,omitempty" jsonschema:"title=Custom Headers"`
 }
 
+type payloadFormat string
+
+const (
+	// A JSON array of documents per request.
+	payloadFormatArray payloadFormat = "array"
+	// Newline-delimited documents per request.
+	payloadFormatNDJSON payloadFormat = "ndjson"
+	// A single document per request.
+	payloadFormatDocument payloadFormat = "document"
+
+	contentTypeJSON   = "application/json"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+type compression string
+
+const (
+	compressionNone compression = "none"
+	compressionGzip compression = "gzip"
+)
+
 type config struct {
-	Address pf.Endpoint `json:"address" jsonschema:"title=Address,description=Base address URL. Must end in a trailing '/'."`
-	Headers headers     `json:"headers,omitempty"`
+	Address       pf.Endpoint       `json:"address" jsonschema:"title=Address,description=Base address URL. Must end in a trailing '/'."`
+	Headers       headers           `json:"headers,omitempty"`
+	PayloadFormat payloadFormat     `json:"payloadFormat,omitempty" jsonschema:"title=Payload Format,description=How documents are sent. Use array for a JSON array of documents per request; ndjson for newline-delimited documents per request; or document for one request per document.,enum=array,enum=ndjson,enum=document,default=array"`
+	Compression   compression       `json:"compression,omitempty" jsonschema:"title=Compression,description=Compression to apply to request bodies.,enum=none,enum=gzip,default=none"`
+	SigningSecret string            `json:"signingSecret,omitempty" jsonschema:"title=Signing Secret,description=If set then requests include an X-Flow-Timestamp header and an X-Flow-Signature header with the HMAC-SHA256 of the timestamp and the request body using this secret." jsonschema_extras:"secret=true"`
+	DeadLetter    *deadLetterConfig `json:"deadLetter,omitempty" jsonschema:"title=Dead Letter Store,description=If configured then requests that permanently fail are written to this store instead of failing the materialization."`
+	Advanced      advancedConfig    `json:"advanced,omitempty" jsonschema:"title=Advanced Options,description=Options for advanced users. You should not typically need to modify these." jsonschema_extras:"advanced=true"`
+}
+
+// deadLetterConfig configures the store that permanently failed requests are
+// written to. Exactly one of the stores must be configured.
+type deadLetterConfig struct {
+	S3        *filesink.S3StoreConfig        `json:"s3,omitempty" jsonschema:"title=S3,description=Write failed requests to an S3 bucket." jsonschema_extras:"order=0"`
+	GCS       *filesink.GCSStoreConfig       `json:"gcs,omitempty" jsonschema:"title=Google Cloud Storage,description=Write failed requests to a GCS bucket." jsonschema_extras:"order=1"`
+	AzureBlob *filesink.AzureBlobStoreConfig `json:"azureBlob,omitempty" jsonschema:"title=Azure Blob Storage,description=Write failed requests to an Azure Blob Storage container." jsonschema_extras:"order=2"`
+}
+
+func (c deadLetterConfig) Validate() error {
+	var configured []string
+	if c.S3 != nil {
+		if err := c.S3.Validate(); err != nil {
+			return fmt.Errorf("s3: %w", err)
+		}
+		configured = append(configured, "s3")
+	}
+	if c.GCS != nil {
+		if err := c.GCS.Validate(); err != nil {
+			return fmt.Errorf("gcs: %w", err)
+		}
+		configured = append(configured, "gcs")
+	}
+	if c.AzureBlob != nil {
+		if err := c.AzureBlob.Validate(); err != nil {
+			return fmt.Errorf("azureBlob: %w", err)
+		}
+		configured = append(configured, "azureBlob")
+	}
+
+	if len(configured) == 0 {
+		return fmt.Errorf("one of 's3', 'gcs', or 'azureBlob' must be configured")
+	} else if len(configured) > 1 {
+		return fmt.Errorf("only one store can be configured but got %s", strings.Join(configured, ", "))
+	}
+
+	return nil
+}
+
+type advancedConfig struct {
+	RequestTimeout string `json:"requestTimeout,omitempty" jsonschema:"title=Request Timeout,description=Timeout for each request as a Go duration string. Defaults to 30s."`
+	MaxAttempts    int    `json:"maxAttempts,omitempty" jsonschema:"title=Maximum Attempts,description=Maximum number of attempts for requests that fail with a network error or a 408 / 429 / 5xx response. Defaults to 5."`
+	InitialBackoff string `json:"initialBackoff,omitempty" jsonschema:"title=Initial Backoff,description=Time to wait before the first retry of a failed request as a Go duration string. This is doubled for each subsequent retry. A Retry-After header in the response takes precedence unless it exceeds the maximum backoff. Defaults to 1s."`
+	MaxBackoff     string `json:"maxBackoff,omitempty" jsonschema:"title=Maximum Backoff,description=Maximum time to wait between retries as a Go duration string. Requests with a longer Retry-After are treated as permanently failed. Defaults to 1m."`
 }
 
 // Validate returns an error if the config is not well-formed.
@@ -57,20 +132,105 @@ func (c config) Validate() error {
 			return fmt.Errorf("header: %w", err)
 		}
 	}
+
+	switch c.PayloadFormat {
+	case "", payloadFormatArray, payloadFormatNDJSON, payloadFormatDocument:
+	default:
+		return fmt.Errorf("invalid payloadFormat %q", c.PayloadFormat)
+	}
+
+	switch c.Compression {
+	case "", compressionNone, compressionGzip:
+	default:
+		return fmt.Errorf("invalid compression %q", c.Compression)
+	}
+
+	if c.DeadLetter != nil {
+		if err := c.DeadLetter.Validate(); err != nil {
+			return fmt.Errorf("deadLetter: %w", err)
+		}
+	}
+
+	for _, d := range []struct {
+		name string
+		val  string
+	}{
+		{"requestTimeout", c.Advanced.RequestTimeout},
+		{"initialBackoff", c.Advanced.InitialBackoff},
+		{"maxBackoff", c.Advanced.MaxBackoff},
+	} {
+		if d.val == "" {
+			continue
+		} else if parsed, err := time.ParseDuration(d.val); err != nil {
+			return fmt.Errorf("parsing %s %q: %w", d.name, d.val, err)
+		} else if parsed <= 0 {
+			return fmt.Errorf("%s must be positive", d.name)
+		}
+	}
+
+	if c.Advanced.MaxAttempts < 0 {
+		return fmt.Errorf("maxAttempts cannot be negative")
+	}
+
 	return nil
 }
 
+// newSender builds a sender for the configuration, which must be valid.
+func (c config) newSender(ctx context.Context) (*sender, error) {
+	duration := func(v string, def time.Duration) time.Duration {
+		if v == "" {
+			return def
+		}
+		d, _ := time.ParseDuration(v)
+		return d
+	}
+
+	var s = &sender{
+		client:         &http.Client{Timeout: duration(c.Advanced.RequestTimeout, 30*time.Second)},
+		customHeaders:  c.Headers.CustomHeaders,
+		gzip:           c.Compression == compressionGzip,
+		maxAttempts:    c.Advanced.MaxAttempts,
+		initialBackoff: duration(c.Advanced.InitialBackoff, time.Second),
+		maxBackoff:     duration(c.Advanced.MaxBackoff, time.Minute),
+	}
+	if s.maxAttempts == 0 {
+		s.maxAttempts = 5
+	}
+	if c.SigningSecret != "" {
+		s.signingSecret = []byte(c.SigningSecret)
+	}
+	if c.DeadLetter != nil {
+		var err error
+		if s.deadLetter, err = newDeadLetterStore(ctx, *c.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
 type resource struct {
 	RelativePath string `json:"relativePath,omitempty" jsonschema:"title=Relative Path,description=Path which is joined with the base Address to build a complete URL" jsonschema_extras:"x-collection-name=true"`
+	Template     string `json:"template,omitempty" jsonschema:"title=Body Template,description=Optional Go text/template which renders the JSON sent for each document from its fields instead of sending the full document. For example: {\"summary\": {{ json .summary }}}." jsonschema_extras:"multiline=true"`
 }
 
 func (r resource) Validate() error {
 	if _, err := url.Parse(r.RelativePath); err != nil {
 		return fmt.Errorf("relativePath: %w", err)
+	} else if _, err := r.parseTemplate(); err != nil {
+		return fmt.Errorf("template: %w", err)
 	}
 	return nil
 }
 
+// parseTemplate returns the parsed body template, or nil if there isn't one.
+func (r resource) parseTemplate() (*payloadtemplate.Template, error) {
+	if r.Template == "" {
+		return nil, nil
+	}
+	return payloadtemplate.Parse(r.Template)
+}
+
 func (r resource) URL() *url.URL {
 	var u, err = url.Parse(r.RelativePath)
 	if err != nil {
@@ -98,7 +258,8 @@ func (driver) Spec(ctx context.Context, req *pm.Request_Spec) (*pm.Response_Spec
 }
 
 // Validate validates the Webhook configuration and constrains projections
-// to the document root (only).
+// to the document root (only), as well as any fields referenced by the body
+// template.
 func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Response_Validated, error) {
 	var cfg config
 	if err := pf.UnmarshalStrict(req.ConfigJson, &cfg); err != nil {
@@ -118,6 +279,18 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 			return nil, fmt.Errorf("resolved webhook address %s is not absolute", resolved)
 		}
 
+		tmpl, err := res.parseTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		var templateFields []string
+		if tmpl != nil {
+			if err := tmpl.Validate(binding.Collection.Projections); err != nil {
+				return nil, fmt.Errorf("validating template for %s: %w", binding.Collection.Name, err)
+			}
+			templateFields = tmpl.Fields()
+		}
+
 		var constraints = make(map[string]*pm.Response_Validated_Constraint)
 		for _, projection := range binding.Collection.Projections {
 			var constraint = new(pm.Response_Validated_Constraint)
@@ -128,6 +301,9 @@ func (driver) Validate(ctx context.Context, req *pm.Request_Validate) (*pm.Respo
 			case projection.IsPrimaryKey:
 				constraint.Type = pm.Response_Validated_Constraint_LOCATION_REQUIRED
 				constraint.Reason = "Document keys must be included"
+			case slices.Contains(templateFields, projection.Field):
+				constraint.Type = pm.Response_Validated_Constraint_FIELD_REQUIRED
+				constraint.Reason = "This field is referenced by the body template"
 			default:
 				constraint.Type = pm.Response_Validated_Constraint_FIELD_FORBIDDEN
 				constraint.Reason = "Webhooks only materialize the full document"
@@ -157,27 +333,57 @@ func (driver) NewTransactor(ctx context.Context, open pm.Request_Open, _ *boiler
 		return nil, nil, nil, fmt.Errorf("parsing endpoint config: %w", err)
 	}
 
-	var addresses []*url.URL
+	var bindings []binding
 
-	for _, binding := range open.Materialization.Bindings {
+	for _, b := range open.Materialization.Bindings {
 		// Join paths of each binding with the base URL.
 		var res resource
-		if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
+		if err := pf.UnmarshalStrict(b.ResourceConfigJson, &res); err != nil {
 			return nil, nil, nil, fmt.Errorf("parsing resource config: %w", err)
 		}
-		addresses = append(addresses, cfg.Address.URL().ResolveReference(res.URL()))
+		tmpl, err := res.parseTemplate()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("parsing template: %w", err)
+		}
+		bindings = append(bindings, binding{
+			address:  cfg.Address.URL().ResolveReference(res.URL()),
+			template: tmpl,
+			fields:   b.FieldSelection.AllFields(),
+		})
+	}
+
+	sender, err := cfg.newSender(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var format = cfg.PayloadFormat
+	if format == "" {
+		format = payloadFormatArray
 	}
 
 	var transactor = &transactor{
-		addresses:     addresses,
-		customHeaders: cfg.Headers.CustomHeaders,
+		bindings: bindings,
+		format:   format,
+		sender:   sender,
 	}
 	return transactor, &pm.Response_Opened{}, nil, nil
 }
 
+type binding struct {
+	address *url.URL
+	// template renders the body of each document if set. Otherwise the full
+	// document is sent.
+	template *payloadtemplate.Template
+	// fields are the names of the selected keys, values, and document, in
+	// the order of the template data.
+	fields []string
+}
+
 type transactor struct {
-	addresses     []*url.URL
-	customHeaders []customHeader
+	bindings []binding
+	format   payloadFormat
+	sender   *sender
 }
 
 func (t *transactor) UnmarshalState(state json.RawMessage) error                  { return nil }
@@ -191,109 +397,101 @@ func (d *transactor) Load(it *m.LoadIterator, _ func(int, json.RawMessage) error
 	return nil
 }
 
-// Store streams StoreIterator documents directly into the webhook body.
+// Store sends StoreIterator documents to the webhook in requests of up to
+// about 1 MiB. A request is sent while the next one is being built, and
+// requests are sent in order.
 func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
-	var pipeWriter *io.PipeWriter
-	group := errgroup.Group{}
-
-	startWebhook := func(address string) {
-		var pipeReader *io.PipeReader
-		pipeReader, pipeWriter = io.Pipe()
-
-		group.Go(func() error {
-			request, err := http.NewRequest("POST", address, pipeReader)
-
-			if err != nil {
-				pipeReader.CloseWithError(err)
-				return fmt.Errorf("http.NewRequest(%s): %w", address, err)
-			}
-
-			request.Header.Add("Content-Type", "application/json")
-
-			for i := range d.customHeaders {
-				header := d.customHeaders[i]
-				request.Header.Add(header.Name, header.Value)
-			}
-
-			response, err := http.DefaultClient.Do(request)
+	group, groupCtx := errgroup.WithContext(it.Context())
+	// Only one request is in flight at a time, so Go blocks until the prior
+	// request has completed.
+	group.SetLimit(1)
 
-			if err != nil {
-				pipeReader.CloseWithError(err)
-				return fmt.Errorf("sending webhook: %w", err)
-			}
-
-			if err := response.Body.Close(); err != nil {
-				pipeReader.CloseWithError(err)
-				return fmt.Errorf("response.Body.Close(): %w", err)
-			}
-
-			if response.StatusCode < 200 || response.StatusCode >= 300 {
-				err := fmt.Errorf("unexpected webhook response code %d from %s", response.StatusCode, address)
-				pipeReader.CloseWithError(err)
-				return err
-			}
-
-			return nil
-		})
-	}
+	const requestSizeCutoff = 1024 * 1024 // 1 MiB
+	var body bytes.Buffer
+	var docs int
+	var lastBinding = -1
 
-	finishWebhook := func() error {
-		if pipeWriter == nil {
+	finishRequest := func() error {
+		if docs == 0 {
 			return nil
 		}
 
-		if _, err := pipeWriter.Write([]byte("]")); err != nil {
-			return fmt.Errorf("pipeWriter.Write(): %w", err)
+		var req = webhookRequest{
+			address:     d.bindings[lastBinding].address.String(),
+			contentType: contentTypeJSON,
+			docs:        docs,
 		}
-
-		if err := pipeWriter.Close(); err != nil {
-			return fmt.Errorf("pipeWriter.Close(): %w", err)
+		switch d.format {
+		case payloadFormatArray:
+			body.WriteByte(']')
+		case payloadFormatNDJSON:
+			req.contentType = contentTypeNDJSON
 		}
+		req.body = bytes.Clone(body.Bytes())
+		body.Reset()
+		docs = 0
 
-		if err := group.Wait(); err != nil {
-			return fmt.Errorf("group.Wait(): %w", err)
+		if groupCtx.Err() != nil {
+			return group.Wait()
 		}
+		group.Go(func() error {
+			if err := d.sender.send(groupCtx, req); err != nil {
+				return fmt.Errorf("sending %d documents to %s: %w", req.docs, req.address, err)
+			}
+			return nil
+		})
 
-		pipeWriter = nil
 		return nil
 	}
 
-	const requestSizeCutoff = 1024 * 1024 // 1 MiB
-	byteCount := 0
-	var previousAddress string
-
 	for it.Next() {
-		address := d.addresses[it.Binding].String()
-
-		// The webhook for the previous binding must finish before the next binding's webhook starts.
-		if previousAddress != "" && address != previousAddress {
-			if err := finishWebhook(); err != nil {
-				return nil, fmt.Errorf("finishWebhooks: %w", err)
+		// The request for the previous binding must finish before the next binding's request starts.
+		if lastBinding != -1 && lastBinding != it.Binding {
+			if err := finishRequest(); err != nil {
+				return nil, err
 			}
 		}
+		lastBinding = it.Binding
 
-		previousAddress = address
+		var doc = it.RawJSON
+		if b := d.bindings[it.Binding]; b.template != nil {
+			var values = make([]any, 0, len(b.fields))
+			for _, v := range it.Key {
+				values = append(values, v)
+			}
+			for _, v := range it.Values {
+				values = append(values, v)
+			}
+			values = append(values, it.RawJSON)
 
-		if pipeWriter == nil {
-			startWebhook(address)
-			if _, err := pipeWriter.Write([]byte("[")); err != nil {
-				return nil, fmt.Errorf("pipeWriter.Write: %w", err)
+			data, err := payloadtemplate.Data(b.fields, values)
+			if err != nil {
+				return nil, err
+			} else if doc, err = b.template.Render(data); err != nil {
+				return nil, err
 			}
-		} else if _, err := pipeWriter.Write([]byte(",")); err != nil {
-			return nil, fmt.Errorf("pipeWriter.Write: %w", err)
 		}
 
-		if _, err := pipeWriter.Write(it.RawJSON); err != nil {
-			return nil, fmt.Errorf("pipeWriter.Write: %w", err)
+		switch d.format {
+		case payloadFormatArray:
+			if docs == 0 {
+				body.WriteByte('[')
+			} else {
+				body.WriteByte(',')
+			}
+			body.Write(doc)
+		case payloadFormatNDJSON:
+			body.Write(doc)
+			body.WriteByte('\n')
+		case payloadFormatDocument:
+			body.Write(doc)
 		}
-		byteCount += len(it.RawJSON)
+		docs++
 
-		if byteCount >= requestSizeCutoff {
-			if err := finishWebhook(); err != nil {
-				return nil, fmt.Errorf("finishWebhook: %w", err)
+		if d.format == payloadFormatDocument || body.Len() >= requestSizeCutoff {
+			if err := finishRequest(); err != nil {
+				return nil, err
 			}
-
-			byteCount = 0
 		}
 	}
 
@@ -301,8 +499,10 @@ func (d *transactor) Store(it *m.StoreIterator) (m.StartCommitFunc, error) {
 		return nil, fmt.Errorf("store iterator error: %w", err)
 	}
 
-	if err := finishWebhook(); err != nil {
-		return nil, fmt.Errorf("finishWebhook: %w", err)
+	if err := finishRequest(); err != nil {
+		return nil, err
+	} else if err := group.Wait(); err != nil {
+		return nil, err
 	}
 
 	return nil, nil
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/estuary/connectors/filesink"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Headers set on signed requests. The signature is the hex-encoded
+	// HMAC-SHA256 of the timestamp, a '.', and the request body as sent.
+	timestampHeader = "X-Flow-Timestamp"
+	signatureHeader = "X-Flow-Signature"
+)
+
+// webhookRequest is a request body for a single webhook call.
+type webhookRequest struct {
+	address     string
+	contentType string
+	body        []byte
+	docs        int
+}
+
+// sender sends webhook requests, retrying requests that fail with
+// transient errors.
+type sender struct {
+	client         *http.Client
+	customHeaders  []customHeader
+	signingSecret  []byte
+	gzip           bool
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	// deadLetter receives requests that have permanently failed, if it is
+	// configured. Otherwise failed requests are an error.
+	deadLetter *deadLetterStore
+}
+
+// permanentError is a failed request that will not succeed if retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryableError is a failed request that may succeed if retried, optionally
+// after a delay requested by the server.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func (s *sender) send(ctx context.Context, req webhookRequest) error {
+	var body = req.body
+	if s.gzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return fmt.Errorf("compressing request body: %w", err)
+		} else if err := gz.Close(); err != nil {
+			return fmt.Errorf("compressing request body: %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	var backoff = s.initialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.do(ctx, req, body)
+
+		var retryable *retryableError
+		if err == nil {
+			return nil
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		} else if !errors.As(err, &retryable) || attempt >= s.maxAttempts {
+			break
+		}
+
+		var wait = backoff
+		if retryable.retryAfter > s.maxBackoff {
+			// Waiting for longer than the maximum backoff would stall the
+			// transaction, so the request is instead treated as permanently
+			// failed.
+			err = &permanentError{fmt.Errorf("%w: requested Retry-After of %s exceeds the maximum backoff of %s", err, retryable.retryAfter, s.maxBackoff)}
+			break
+		} else if retryable.retryAfter > 0 {
+			wait = retryable.retryAfter
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+
+		log.WithFields(log.Fields{
+			"address": req.address,
+			"attempt": attempt,
+			"wait":    wait.String(),
+			"error":   err.Error(),
+		}).Warn("webhook request failed, will retry")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	if s.deadLetter == nil {
+		return err
+	}
+
+	key, dlErr := s.deadLetter.put(ctx, req)
+	if dlErr != nil {
+		return fmt.Errorf("writing failed request to dead letter store: %w (request failed with: %w)", dlErr, err)
+	}
+
+	log.WithFields(log.Fields{
+		"address": req.address,
+		"docs":    req.docs,
+		"key":     key,
+		"error":   err.Error(),
+	}).Warn("webhook request permanently failed and was written to the dead letter store")
+
+	return nil
+}
+
+// do makes a single attempt at a request.
+func (s *sender) do(ctx context.Context, req webhookRequest, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, "POST", req.address, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("http.NewRequest(%s): %w", req.address, err)}
+	}
+
+	request.Header.Set("Content-Type", req.contentType)
+	if s.gzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	for _, header := range s.customHeaders {
+		request.Header.Add(header.Name, header.Value)
+	}
+	if s.signingSecret != nil {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set(timestampHeader, timestamp)
+		request.Header.Set(signatureHeader, "sha256="+sign(s.signingSecret, timestamp, body))
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		// Network errors and timeouts are assumed to be transient.
+		return &retryableError{err: fmt.Errorf("sending webhook to %s: %w", req.address, err)}
+	}
+	defer response.Body.Close()
+
+	// Include the start of the response in errors, which may describe why the
+	// request failed.
+	snippet, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("unexpected webhook response code %d from %s: %s", response.StatusCode, req.address, strings.TrimSpace(string(snippet)))
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests,
+		response.StatusCode == http.StatusRequestTimeout,
+		response.StatusCode >= 500:
+		return &retryableError{err: err, retryAfter: parseRetryAfter(response.Header.Get("Retry-After"))}
+	default:
+		return &permanentError{err}
+	}
+}
+
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date. Zero is returned if it is absent or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	} else if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t))
+	}
+	return 0
+}
+
+// deadLetterStore writes the bodies of permanently failed requests to an
+// object store.
+type deadLetterStore struct {
+	store  filesink.Store
+	prefix string
+}
+
+func newDeadLetterStore(ctx context.Context, cfg deadLetterConfig) (*deadLetterStore, error) {
+	var store filesink.Store
+	var prefix string
+	var err error
+
+	switch {
+	case cfg.S3 != nil:
+		store, err = filesink.NewS3Store(ctx, *cfg.S3)
+		prefix = cfg.S3.Prefix
+	case cfg.GCS != nil:
+		store, err = filesink.NewGCSStore(ctx, *cfg.GCS)
+		prefix = cfg.GCS.Prefix
+	case cfg.AzureBlob != nil:
+		store, err = filesink.NewAzureBlobStore(ctx, *cfg.AzureBlob)
+		prefix = cfg.AzureBlob.Prefix
+	default:
+		return nil, fmt.Errorf("no dead letter store configured")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating dead letter store: %w", err)
+	}
+
+	return &deadLetterStore{store: store, prefix: prefix}, nil
+}
+
+// put writes the uncompressed body of the request to an object named for the
+// host and path of its address, returning the key of the object.
+func (d *deadLetterStore) put(ctx context.Context, req webhookRequest) (string, error) {
+	var dir = "webhook"
+	if u, err := url.Parse(req.address); err == nil {
+		dir = strings.Trim(path.Join(u.Host, u.Path), "/")
+	}
+
+	var ext = ".json"
+	if req.contentType == contentTypeNDJSON {
+		ext = ".ndjson"
+	}
+
+	key := path.Join(d.prefix, dir, time.Now().UTC().Format("20060102T150405Z")+"-"+uuid.NewString()+ext)
+	if err := d.store.PutStream(ctx, bytes.NewReader(req.body), key); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
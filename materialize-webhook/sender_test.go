@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/estuary/connectors/filesink"
+	"github.com/stretchr/testify/require"
+)
+
+func testSender() *sender {
+	return &sender{
+		client:         &http.Client{Timeout: 5 * time.Second},
+		maxAttempts:    3,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     10 * time.Millisecond,
+	}
+}
+
+func TestSenderRetries(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch attempts.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	s := testSender()
+	req := webhookRequest{address: srv.URL, contentType: contentTypeJSON, body: []byte(`[{"a":1}]`), docs: 1}
+	require.NoError(t, s.send(ctx, req))
+	require.Equal(t, int32(3), attempts.Load())
+
+	// Attempts are exhausted.
+	attempts.Store(0)
+	s.maxAttempts = 2
+	require.ErrorContains(t, s.send(ctx, req), "unexpected webhook response code 429")
+	require.Equal(t, int32(2), attempts.Load())
+}
+
+func TestSenderRetryAfterExceedsMaxBackoff(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	req := webhookRequest{address: srv.URL, contentType: contentTypeJSON, body: []byte(`[{"a":1}]`), docs: 1}
+	err := testSender().send(context.Background(), req)
+	require.ErrorContains(t, err, "requested Retry-After of 24h0m0s exceeds the maximum backoff of 10ms")
+	require.Equal(t, int32(1), attempts.Load())
+
+	// The request is written to the dead letter store instead, if it is configured.
+	dir := t.TempDir()
+	s := testSender()
+	s.deadLetter = &deadLetterStore{store: filesink.NewLocalStore(dir), prefix: "failed"}
+	require.NoError(t, s.send(context.Background(), req))
+	require.Equal(t, int32(2), attempts.Load())
+}
+
+func TestSenderPermanentError(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("bad document"))
+	}))
+	defer srv.Close()
+
+	err := testSender().send(context.Background(), webhookRequest{address: srv.URL, contentType: contentTypeJSON, body: []byte(`[]`)})
+	require.ErrorContains(t, err, "unexpected webhook response code 400")
+	require.ErrorContains(t, err, "bad document")
+	require.Equal(t, int32(1), attempts.Load())
+}
+
+func TestSenderDeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	s := testSender()
+	s.deadLetter = &deadLetterStore{store: filesink.NewLocalStore(dir), prefix: "failed"}
+
+	var body = []byte("{\"a\":1}\n")
+	require.NoError(t, s.send(context.Background(), webhookRequest{address: srv.URL + "/some/path", contentType: contentTypeNDJSON, body: body, docs: 1}))
+
+	matches, err := filepath.Glob(filepath.Join(dir, "failed", strings.TrimPrefix(srv.URL, "http://"), "some", "path", "*.ndjson"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	written, err := os.ReadFile(matches[0])
+	require.NoError(t, err)
+	require.Equal(t, body, written)
+}
+
+func TestDeadLetterConfig(t *testing.T) {
+	gcs := &filesink.GCSStoreConfig{Bucket: "bucket", CredentialsJSON: "{}", UploadInterval: "5m"}
+	azure := &filesink.AzureBlobStoreConfig{
+		StorageAccountName: "account",
+		ContainerName:      "container",
+		Credentials:        filesink.AzureBlobCredentials{AuthType: filesink.AZURE_SAS_AUTH_TYPE, SASToken: "token"},
+		UploadInterval:     "5m",
+	}
+
+	require.NoError(t, deadLetterConfig{GCS: gcs}.Validate())
+	require.NoError(t, deadLetterConfig{AzureBlob: azure}.Validate())
+	require.ErrorContains(t, deadLetterConfig{}.Validate(), "must be configured")
+	require.ErrorContains(t, deadLetterConfig{GCS: gcs, AzureBlob: azure}.Validate(), "only one store")
+	require.ErrorContains(t, deadLetterConfig{GCS: &filesink.GCSStoreConfig{Bucket: "bucket"}}.Validate(), "gcs: missing 'credentialsJson'")
+}
+
+func TestSenderSignedGzip(t *testing.T) {
+	var secret = []byte("shh")
+	var body = []byte("{\"a\":1}\n{\"a\":2}\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		ts := r.Header.Get(timestampHeader)
+		require.NotEmpty(t, ts)
+		require.Equal(t, "sha256="+sign(secret, ts, raw), r.Header.Get(signatureHeader))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		require.Equal(t, contentTypeNDJSON, r.Header.Get("Content-Type"))
+		require.Equal(t, "bar", r.Header.Get("X-Foo"))
+
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, body, decoded)
+	}))
+	defer srv.Close()
+
+	s := testSender()
+	s.signingSecret = secret
+	s.gzip = true
+	s.customHeaders = []customHeader{{Name: "X-Foo", Value: "bar"}}
+
+	require.NoError(t, s.send(context.Background(), webhookRequest{address: srv.URL, contentType: contentTypeNDJSON, body: body, docs: 2}))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	require.Equal(t, time.Duration(0), parseRetryAfter(""))
+	require.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+	require.Equal(t, 30*time.Second, parseRetryAfter("30"))
+
+	d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.Greater(t, d, 59*time.Minute)
+	require.Equal(t, time.Duration(0), parseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+}
@@ -77,6 +77,11 @@ func (c *capture) backfill(ctx context.Context, t *table, dur time.Duration) err
 		return nil
 	}
 
+	return c.finishBackfill(t)
+}
+
+// finishBackfill checkpoints that the backfill of the table is complete.
+func (c *capture) finishBackfill(t *table) error {
 	// Approximate completion time of the table backfill.
 	finished := time.Now()
 
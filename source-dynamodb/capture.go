@@ -28,6 +28,11 @@ type tableState struct {
 	BackfillSegmentProgress map[int]segmentState   `json:"backfillSegmentProgress,omitempty"`
 	Shards                  map[string]*shardState `json:"shards,omitempty"`
 	StreamArn               string                 `json:"streamArn,omitempty"`
+	Export                  *exportState           `json:"export,omitempty"`
+	// Dedupe holds the change records seen by the table's changeDeduplicator.
+	// It is checkpointed incrementally, with pruned entries removed by null
+	// values of the merge patch.
+	Dedupe map[string]*seenChange `json:"dedupe,omitempty"`
 }
 
 type segmentState struct {
@@ -35,6 +40,18 @@ type segmentState struct {
 	FinishedAt        time.Time `json:"finishedAt,omitempty"`
 }
 
+// exportState is the progress of reading a table export for a backfill using
+// backfillModeExport.
+type exportState struct {
+	ExportArn  string    `json:"exportArn"`
+	ExportTime time.Time `json:"exportTime"`
+	// DataFile is the index in the export manifest of the data file being read,
+	// and DataFileItems is the number of items from that file that have already
+	// been emitted.
+	DataFile      int `json:"dataFile"`
+	DataFileItems int `json:"dataFileItems"`
+}
+
 type shardState struct {
 	LastReadSequence string `json:"lastReadSequence,omitempty"`
 	FinishedReading  bool   `json:"finishedReading,omitempty"`
@@ -80,7 +97,7 @@ func (c *capture) initializeTable(ctx context.Context, binding int, tableName st
 		bindingIdx: binding,
 	}
 
-	d, hasStream, err := discoverTable(ctx, c.client, tableName)
+	d, hasStream, err := discoverTable(ctx, c.client, tableName, c.config.Advanced.ChangeSource)
 	if err != nil {
 		return nil, fmt.Errorf("discover table %s for capture: %w", tableName, err)
 	} else if !hasStream {
@@ -105,6 +122,13 @@ func (c *capture) initializeTable(ctx context.Context, binding int, tableName st
 
 	state := c.state.Tables[sk]
 
+	if c.config.Advanced.ChangeSource == changeSourceKinesis {
+		// Change records may be delivered out of order or more than once by Kinesis.
+		t.dedupe = newChangeDeduplicator(tableName, d.keyFields, state.Dedupe)
+	}
+	// The restored records are only held by the deduplicator.
+	state.Dedupe = nil
+
 	if state.Shards == nil {
 		// Never emitted a document from reading a shard.
 		state.Shards = make(map[string]*shardState)
@@ -130,6 +154,16 @@ func (c *capture) initializeTable(ctx context.Context, binding int, tableName st
 		}).Info("setting backfill segments for table")
 		state.TotalBackfillSegments = segments
 
+		// Start the table export now if the table will be backfilled from one, since change
+		// events from prior to the export time will not be captured from the stream.
+		if c.config.Advanced.BackfillMode == backfillModeExport {
+			export, err := c.startExport(ctx, t.tableName, d.arn)
+			if err != nil {
+				return nil, fmt.Errorf("starting export: %w", err)
+			}
+			state.Export = export
+		}
+
 		// Get a persistent reference to the stream ARN. It is an error if this ever changes.
 		log.WithFields(log.Fields{
 			"table":     t.tableName,
@@ -182,6 +216,10 @@ func (c *capture) initializeTable(ctx context.Context, binding int, tableName st
 	t.backfillComplete = state.BackfillFinishedAt != nil && !state.BackfillFinishedAt.IsZero()
 	t.totalBackfillSegments = state.TotalBackfillSegments
 	t.streamArn = state.StreamArn
+	if state.Export != nil {
+		t.exportArn = state.Export.ExportArn
+		t.exportTime = state.Export.ExportTime
+	}
 
 	t.shardMonitorDelay = defaultShardMonitorDelay
 	t.scanLimitFromConfig = 0 // The actual scan limit used may be lower if the table has low provisioned RCUs. 0 is unlimited.
@@ -266,6 +304,18 @@ func (c *capture) captureTable(ctx context.Context, t *table) error {
 		// duration provided by backfillDuration. Alternating between backfilling a streaming is a
 		// little simpler than backfilling continuously concurrently with streaming because of the
 		// need to manage the horizon time for streaming while the backfill is in progress.
+		if t.exportArn != "" {
+			log.WithFields(log.Fields{
+				"table":            t.tableName,
+				"exportArn":        t.exportArn,
+				"backfillDuration": backfillDuration.String(),
+			}).Info("backfilling table from export")
+			if err := c.backfillExport(ctx, t, backfillDuration); err != nil {
+				return fmt.Errorf("backfilling table '%s' from export: %w", t.tableName, err)
+			}
+			continue
+		}
+
 		log.WithFields(log.Fields{
 			"table":            t.tableName,
 			"segments":         t.totalBackfillSegments,
@@ -317,25 +367,9 @@ func (c *capture) emitBackfill(
 	records []map[string]types.AttributeValue,
 	keyFields []string,
 ) error {
-	docs := make([]json.RawMessage, 0, len(records))
-
-	for _, r := range records {
-		doc, err := decodeAttributes(r, keyFields)
-		if err != nil {
-			return err
-		}
-
-		doc["_meta"] = backfillItemMeta{
-			Snapshot: true,
-			Op:       dynamoOpsToChangeOps[streamTypes.OperationTypeInsert],
-		}
-
-		raw, err := json.Marshal(doc)
-		if err != nil {
-			return fmt.Errorf("serializing document: %w", err)
-		}
-
-		docs = append(docs, raw)
+	docs, err := makeBackfillDocs(records, keyFields)
+	if err != nil {
+		return err
 	}
 
 	c.mu.Lock()
@@ -362,12 +396,38 @@ func (c *capture) emitBackfill(
 	return nil
 }
 
+func makeBackfillDocs(records []map[string]types.AttributeValue, keyFields []string) ([]json.RawMessage, error) {
+	docs := make([]json.RawMessage, 0, len(records))
+
+	for _, r := range records {
+		doc, err := decodeAttributes(r, keyFields)
+		if err != nil {
+			return nil, err
+		}
+
+		doc["_meta"] = backfillItemMeta{
+			Snapshot: true,
+			Op:       dynamoOpsToChangeOps[streamTypes.OperationTypeInsert],
+		}
+
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("serializing document: %w", err)
+		}
+
+		docs = append(docs, raw)
+	}
+
+	return docs, nil
+}
+
 func (c *capture) emitStream(
 	binding int,
 	sk boilerplate.StateKey,
 	shardId string,
 	state shardState,
 	records []streamTypes.Record,
+	dedupe map[string]*seenChange,
 	keyFields []string,
 ) error {
 	docs := make([]json.RawMessage, 0, len(records))
@@ -452,6 +512,7 @@ func (c *capture) emitStream(
 				Shards: map[string]*shardState{
 					shardId: &state,
 				},
+				Dedupe: dedupe,
 			},
 		},
 	}
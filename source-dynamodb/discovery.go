@@ -19,6 +19,7 @@ import (
 
 type discoveredTable struct {
 	name      string
+	arn       string
 	streamArn string
 	// keyFields and keyTypes are ordered as partition key followed by sort key, if there is a sort
 	// key.
@@ -50,6 +51,7 @@ func (s columnSchema) toType() *jsonschema.Schema {
 
 const noDiscoveredTablesMsg = `Could not discover any tables to capture; no data will be captured. Possible causes:
   - No tables with DynamoDB streams enabled. DynamoDB streams must be enabled with "View type" set to "New and old images" for each table to capture.
+  - No tables with an active Kinesis streaming destination, if capturing change events from Kinesis.
   - Insufficient access to list tables. The IAM user configured for the capture must have ListTables permissions.
   - There are no tables to capture in the AWS region configured for the capture.`
 
@@ -73,7 +75,7 @@ func (driver) Discover(ctx context.Context, req *pc.Request_Discover) (*pc.Respo
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
 
-	tables, err := discoverTables(ctx, client)
+	tables, err := discoverTables(ctx, client, cfg.Advanced.ChangeSource)
 	if err != nil {
 		return nil, fmt.Errorf("discovering tables: %w", err)
 	}
@@ -236,7 +238,7 @@ func (driver) Discover(ctx context.Context, req *pc.Request_Discover) (*pc.Respo
 	return &pc.Response_Discovered{Bindings: bindings}, nil
 }
 
-func discoverTables(ctx context.Context, c *client) ([]discoveredTable, error) {
+func discoverTables(ctx context.Context, c *client, source changeSource) ([]discoveredTable, error) {
 	allTableNames := []string{}
 
 	var exclusiveStartTableName *string
@@ -269,7 +271,7 @@ func discoverTables(ctx context.Context, c *client) ([]discoveredTable, error) {
 	for _, t := range allTableNames {
 		t := t
 		group.Go(func() error {
-			discovered, include, err := discoverTable(groupCtx, c, t)
+			discovered, include, err := discoverTable(groupCtx, c, t, source)
 			if err != nil {
 				return fmt.Errorf("discovering table: %w", err)
 			}
@@ -291,7 +293,7 @@ func discoverTables(ctx context.Context, c *client) ([]discoveredTable, error) {
 	return out, nil
 }
 
-func discoverTable(ctx context.Context, c *client, table string) (discoveredTable, bool, error) {
+func discoverTable(ctx context.Context, c *client, table string, source changeSource) (discoveredTable, bool, error) {
 	tableDescribe, err := c.db.DescribeTable(ctx, &dynamodb.DescribeTableInput{
 		TableName: aws.String(table),
 	})
@@ -299,27 +301,39 @@ func discoverTable(ctx context.Context, c *client, table string) (discoveredTabl
 		return discoveredTable{}, false, fmt.Errorf("describe table: %w", err)
 	}
 
-	if tableDescribe.Table.StreamSpecification == nil ||
-		tableDescribe.Table.StreamSpecification.StreamEnabled == nil ||
-		!*tableDescribe.Table.StreamSpecification.StreamEnabled {
-		log.WithField("table", tableDescribe.Table.TableName).Info("table will not be captured since it does not have streaming enabled")
-		return discoveredTable{}, false, nil
-	}
+	var streamArn string
+	if source == changeSourceKinesis {
+		if streamArn, err = kinesisDestination(ctx, c, table); err != nil {
+			return discoveredTable{}, false, err
+		} else if streamArn == "" {
+			log.WithField("table", tableDescribe.Table.TableName).Info("table will not be captured since it does not have an active Kinesis streaming destination")
+			return discoveredTable{}, false, nil
+		}
+	} else {
+		if tableDescribe.Table.StreamSpecification == nil ||
+			tableDescribe.Table.StreamSpecification.StreamEnabled == nil ||
+			!*tableDescribe.Table.StreamSpecification.StreamEnabled {
+			log.WithField("table", tableDescribe.Table.TableName).Info("table will not be captured since it does not have streaming enabled")
+			return discoveredTable{}, false, nil
+		}
 
-	if tableDescribe.Table.LatestStreamArn == nil {
-		// This condition may not actually be possible, but with everything in the AWS SDK being a
-		// pointer it's hard to tell.
-		log.WithField("table", tableDescribe.Table.TableName).Warn("streaming enabled for table but no stream ARN found; table will not be captured")
-		return discoveredTable{}, false, nil
-	}
+		if tableDescribe.Table.LatestStreamArn == nil {
+			// This condition may not actually be possible, but with everything in the AWS SDK being a
+			// pointer it's hard to tell.
+			log.WithField("table", tableDescribe.Table.TableName).Warn("streaming enabled for table but no stream ARN found; table will not be captured")
+			return discoveredTable{}, false, nil
+		}
 
-	if tableDescribe.Table.StreamSpecification.StreamViewType != types.StreamViewTypeNewAndOldImages {
-		log.WithFields(log.Fields{
-			"table":          tableDescribe.Table.TableName,
-			"streamArn":      tableDescribe.Table.LatestStreamArn,
-			"streamViewType": tableDescribe.Table.StreamSpecification.StreamViewType,
-		}).Warn("streamViewType must be NEW_AND_OLD_IMAGES; table will not be captured")
-		return discoveredTable{}, false, nil
+		if tableDescribe.Table.StreamSpecification.StreamViewType != types.StreamViewTypeNewAndOldImages {
+			log.WithFields(log.Fields{
+				"table":          tableDescribe.Table.TableName,
+				"streamArn":      tableDescribe.Table.LatestStreamArn,
+				"streamViewType": tableDescribe.Table.StreamSpecification.StreamViewType,
+			}).Warn("streamViewType must be NEW_AND_OLD_IMAGES; table will not be captured")
+			return discoveredTable{}, false, nil
+		}
+
+		streamArn = *tableDescribe.Table.LatestStreamArn
 	}
 
 	var rcus int
@@ -331,7 +345,8 @@ func discoverTable(ctx context.Context, c *client, table string) (discoveredTabl
 
 	out := discoveredTable{
 		name:      table,
-		streamArn: *tableDescribe.Table.LatestStreamArn,
+		arn:       aws.ToString(tableDescribe.Table.TableArn),
+		streamArn: streamArn,
 		rcus:      rcus,
 	}
 
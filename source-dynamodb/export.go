@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	boilerplate "github.com/estuary/connectors/source-boilerplate"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Delay between checks of the status of an in-progress table export.
+	exportPollInterval = 30 * time.Second
+
+	// Number of exported items to emit per checkpoint.
+	exportItemsPerCheckpoint = 1000
+
+	// Change events with an ApproximateCreationDateTime this much older than the export time are
+	// known to be included in the export, and are not emitted.
+	exportTimeTolerance = 1 * time.Minute
+
+	// Name of the file listing the data files of an export, which is written alongside the
+	// manifest summary.
+	exportManifestFiles = "manifest-files.json"
+)
+
+// dynamoFormat returns the DynamoDB export format of the configured export format.
+func (f exportFormat) dynamoFormat() types.ExportFormat {
+	if f == exportFormatIon {
+		return types.ExportFormatIon
+	}
+	return types.ExportFormatDynamodbJson
+}
+
+// startExport starts a point-in-time export of the table to S3, in the configured export format.
+func (c *capture) startExport(ctx context.Context, tableName, tableArn string) (*exportState, error) {
+	requested := time.Now()
+
+	res, err := c.client.db.ExportTableToPointInTime(ctx, &dynamodb.ExportTableToPointInTimeInput{
+		TableArn:     aws.String(tableArn),
+		S3Bucket:     aws.String(c.config.Advanced.ExportBucket),
+		S3Prefix:     nilIfEmpty(c.config.Advanced.ExportPrefix),
+		ExportFormat: c.config.Advanced.ExportFormat.dynamoFormat(),
+	})
+	if err != nil {
+		var pitrErr *types.PointInTimeRecoveryUnavailableException
+		if errors.As(err, &pitrErr) {
+			return nil, fmt.Errorf("point-in-time recovery must be enabled for table %s to backfill it from an export: %w", tableName, err)
+		}
+		return nil, fmt.Errorf("exporting table %s: %w", tableName, err)
+	}
+
+	export := &exportState{
+		ExportArn:  *res.ExportDescription.ExportArn,
+		ExportTime: requested,
+	}
+	if res.ExportDescription.ExportTime != nil {
+		export.ExportTime = *res.ExportDescription.ExportTime
+	}
+
+	log.WithFields(log.Fields{
+		"table":      tableName,
+		"exportArn":  export.ExportArn,
+		"exportTime": export.ExportTime.String(),
+	}).Info("started table export")
+
+	return export, nil
+}
+
+// backfillExport backfills a table from its export, waiting for the export to complete if it is
+// still in progress. Like backfill, it returns after the provided duration even if the backfill is
+// not yet complete so that the table streams can be caught up.
+func (c *capture) backfillExport(ctx context.Context, t *table, dur time.Duration) error {
+	deadline := time.Now().Add(dur)
+
+	var desc *types.ExportDescription
+	for {
+		res, err := c.client.db.DescribeExport(ctx, &dynamodb.DescribeExportInput{
+			ExportArn: aws.String(t.exportArn),
+		})
+		if err != nil {
+			return fmt.Errorf("describing export %s: %w", t.exportArn, err)
+		}
+		desc = res.ExportDescription
+
+		switch desc.ExportStatus {
+		case types.ExportStatusCompleted:
+		case types.ExportStatusFailed:
+			// The binding must be re-backfilled to start a new export, since change events from
+			// prior to the failed export's time will not have been captured.
+			return fmt.Errorf(
+				"export %s of table %s failed with code %s: %s: this binding must be re-backfilled for the capture to continue",
+				t.exportArn, t.tableName, aws.ToString(desc.FailureCode), aws.ToString(desc.FailureMessage),
+			)
+		default:
+			log.WithFields(log.Fields{
+				"table":     t.tableName,
+				"exportArn": t.exportArn,
+				"status":    desc.ExportStatus,
+			}).Info("waiting for table export to complete")
+
+			if time.Now().Add(exportPollInterval).After(deadline) {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(exportPollInterval):
+				continue
+			}
+		}
+		break
+	}
+
+	dataFiles, err := c.exportDataFiles(ctx, desc)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	state := *c.state.Tables[t.stateKey].Export
+	c.mu.Unlock()
+
+	for idx := state.DataFile; idx < len(dataFiles); idx++ {
+		if time.Now().After(deadline) {
+			return nil
+		}
+
+		skip := 0
+		if idx == state.DataFile {
+			skip = state.DataFileItems
+		}
+
+		if err := c.readExportDataFile(ctx, t, *desc.S3Bucket, dataFiles[idx], desc.ExportFormat, idx, skip); err != nil {
+			return err
+		}
+	}
+
+	return c.finishBackfill(t)
+}
+
+type exportManifestFile struct {
+	ItemCount     int64  `json:"itemCount"`
+	DataFileS3Key string `json:"dataFileS3Key"`
+}
+
+// exportDataFiles lists the S3 keys of the data files of a completed export, in the order they
+// appear in the manifest.
+func (c *capture) exportDataFiles(ctx context.Context, desc *types.ExportDescription) ([]string, error) {
+	manifestKey := path.Join(path.Dir(aws.ToString(desc.ExportManifest)), exportManifestFiles)
+
+	obj, err := c.client.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: desc.S3Bucket,
+		Key:    aws.String(manifestKey),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting export manifest %s: %w", manifestKey, err)
+	}
+	defer obj.Body.Close()
+
+	var out []string
+	dec := json.NewDecoder(obj.Body)
+	for {
+		var file exportManifestFile
+		if err := dec.Decode(&file); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding export manifest %s: %w", manifestKey, err)
+		}
+
+		if file.ItemCount == 0 {
+			continue
+		}
+		out = append(out, file.DataFileS3Key)
+	}
+
+	return out, nil
+}
+
+// readExportDataFile emits the items of a gzipped DynamoDB JSON or ION export data file, skipping
+// the first items that were emitted previously. The format of the file is that of the export,
+// which may differ from the current configuration if it was changed after the export started.
+func (c *capture) readExportDataFile(ctx context.Context, t *table, bucket, key string, format types.ExportFormat, dataFile, skip int) error {
+	log.WithFields(log.Fields{
+		"table":    t.tableName,
+		"dataFile": key,
+		"skip":     skip,
+	}).Debug("reading export data file")
+
+	obj, err := c.client.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("getting export data file %s: %w", key, err)
+	}
+	defer obj.Body.Close()
+
+	gz, err := gzip.NewReader(obj.Body)
+	if err != nil {
+		return fmt.Errorf("reading export data file %s: %w", key, err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	// DynamoDB items are at most 400 KB, but their JSON or Ion representation may be somewhat larger.
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	state := exportState{
+		ExportArn:  t.exportArn,
+		ExportTime: t.exportTime,
+		DataFile:   dataFile,
+	}
+
+	var items []map[string]types.AttributeValue
+	for scanner.Scan() {
+		if state.DataFileItems++; state.DataFileItems <= skip {
+			continue
+		}
+
+		var item map[string]types.AttributeValue
+		if format == types.ExportFormatIon {
+			item, err = parseIonExportItem(scanner.Bytes())
+		} else {
+			item, err = parseExportItem(scanner.Bytes())
+		}
+		if err != nil {
+			return fmt.Errorf("parsing item %d of export data file %s: %w", state.DataFileItems, key, err)
+		} else if item == nil {
+			continue // Ion version markers are not items.
+		}
+		items = append(items, item)
+
+		if len(items) == exportItemsPerCheckpoint {
+			if err := c.emitExport(t.bindingIdx, t.stateKey, state, items, t.keyFields); err != nil {
+				return fmt.Errorf("emitting export documents for table %s: %w", t.tableName, err)
+			}
+			items = items[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading export data file %s: %w", key, err)
+	}
+
+	// Emit the remaining items of the file, and checkpoint that it has been completely read.
+	state.DataFile, state.DataFileItems = dataFile+1, 0
+	if err := c.emitExport(t.bindingIdx, t.stateKey, state, items, t.keyFields); err != nil {
+		return fmt.Errorf("emitting export documents for table %s: %w", t.tableName, err)
+	}
+
+	return nil
+}
+
+func (c *capture) emitExport(
+	binding int,
+	sk boilerplate.StateKey,
+	state exportState,
+	records []map[string]types.AttributeValue,
+	keyFields []string,
+) error {
+	docs, err := makeBackfillDocs(records, keyFields)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ts := c.state.Tables[sk]
+	ts.Export = &state
+	c.state.Tables[sk] = ts
+
+	stateUpdate := captureState{
+		Tables: map[boilerplate.StateKey]tableState{
+			sk: {Export: &state},
+		},
+	}
+
+	if err := c.stream.Documents(binding, docs...); err != nil {
+		return fmt.Errorf("outputting export documents: %w", err)
+	} else if err := c.checkpoint(stateUpdate); err != nil {
+		return fmt.Errorf("outputting export checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// parseExportItem parses a line of a DynamoDB JSON export data file, which is an object with the
+// item as its "Item" property.
+func parseExportItem(line []byte) (map[string]types.AttributeValue, error) {
+	var wrapper struct {
+		Item map[string]json.RawMessage `json:"Item"`
+	}
+	if err := json.Unmarshal(line, &wrapper); err != nil {
+		return nil, err
+	} else if wrapper.Item == nil {
+		return nil, fmt.Errorf("line has no item")
+	}
+
+	return parseDynamoJSONMap(wrapper.Item)
+}
+
+func parseDynamoJSONMap(m map[string]json.RawMessage) (map[string]types.AttributeValue, error) {
+	out := make(map[string]types.AttributeValue, len(m))
+	for k, v := range m {
+		av, err := parseDynamoJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", k, err)
+		}
+		out[k] = av
+	}
+
+	return out, nil
+}
+
+// parseDynamoJSON parses an attribute value in the DynamoDB JSON format, which is an object with
+// a single property naming the attribute type, such as {"S": "hello"} or {"N": "1.5"}.
+func parseDynamoJSON(raw json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	} else if len(typed) != 1 {
+		return nil, fmt.Errorf("attribute value must have exactly one type, got %d", len(typed))
+	}
+
+	var typ string
+	var val json.RawMessage
+	for typ, val = range typed {
+	}
+
+	switch typ {
+	case "S":
+		var v types.AttributeValueMemberS
+		return &v, json.Unmarshal(val, &v.Value)
+	case "N":
+		var v types.AttributeValueMemberN
+		return &v, json.Unmarshal(val, &v.Value)
+	case "B":
+		var v types.AttributeValueMemberB
+		return &v, json.Unmarshal(val, &v.Value)
+	case "BOOL":
+		var v types.AttributeValueMemberBOOL
+		return &v, json.Unmarshal(val, &v.Value)
+	case "NULL":
+		var v types.AttributeValueMemberNULL
+		return &v, json.Unmarshal(val, &v.Value)
+	case "SS":
+		var v types.AttributeValueMemberSS
+		return &v, json.Unmarshal(val, &v.Value)
+	case "NS":
+		var v types.AttributeValueMemberNS
+		return &v, json.Unmarshal(val, &v.Value)
+	case "BS":
+		var v types.AttributeValueMemberBS
+		return &v, json.Unmarshal(val, &v.Value)
+	case "L":
+		var elems []json.RawMessage
+		if err := json.Unmarshal(val, &elems); err != nil {
+			return nil, err
+		}
+		v := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, 0, len(elems))}
+		for idx, e := range elems {
+			av, err := parseDynamoJSON(e)
+			if err != nil {
+				return nil, fmt.Errorf("list element %d: %w", idx, err)
+			}
+			v.Value = append(v.Value, av)
+		}
+		return v, nil
+	case "M":
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(val, &m); err != nil {
+			return nil, err
+		}
+		av, err := parseDynamoJSONMap(m)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: av}, nil
+	default:
+		return nil, fmt.Errorf("unknown attribute value type %q", typ)
+	}
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	streamTypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExportItem(t *testing.T) {
+	line := `{"Item":{"pk":{"S":"key"},"sk":{"N":"1.5"},"bin":{"B":"AQID"},"bool":{"BOOL":true},"null":{"NULL":true},"ss":{"SS":["a","b"]},"ns":{"NS":["1","2"]},"bs":{"BS":["AQ=="]},"list":{"L":[{"S":"x"},{"N":"2"}]},"map":{"M":{"nested":{"M":{"n":{"N":"3"}}}}}}}`
+
+	got, err := parseExportItem([]byte(line))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]types.AttributeValue{
+		"pk":   &types.AttributeValueMemberS{Value: "key"},
+		"sk":   &types.AttributeValueMemberN{Value: "1.5"},
+		"bin":  &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+		"bool": &types.AttributeValueMemberBOOL{Value: true},
+		"null": &types.AttributeValueMemberNULL{Value: true},
+		"ss":   &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"ns":   &types.AttributeValueMemberNS{Value: []string{"1", "2"}},
+		"bs":   &types.AttributeValueMemberBS{Value: [][]byte{{1}}},
+		"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "x"},
+			&types.AttributeValueMemberN{Value: "2"},
+		}},
+		"map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"nested": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"n": &types.AttributeValueMemberN{Value: "3"},
+			}},
+		}},
+	}, got)
+
+	// Exported items are translated the same as scanned items.
+	doc, err := decodeAttributes(got, []string{"pk", "sk"})
+	require.NoError(t, err)
+	require.Equal(t, "1.5", doc["sk"])
+
+	_, err = parseExportItem([]byte(`{"Item":{"pk":{"S":"a","N":"1"}}}`))
+	require.Error(t, err)
+	_, err = parseExportItem([]byte(`{"Item":{"pk":{"X":"a"}}}`))
+	require.Error(t, err)
+}
+
+func TestParseIonExportItem(t *testing.T) {
+	line := `$ion_1_0 {Item:{pk:"key",sk:1.5,int:1_000,dec:2000.,exp:1.5d2,bin:{{AQID}},bool:true,null:null,typedNull:null.string,` +
+		`ss:$dynamodb_SS::["a","b"],ns:$dynamodb_NS::[1,2.5],bs:$dynamodb_BS::[{{AQ==}}],list:["x",2,false],` +
+		`map:{nested:{n:3}},'quoted field':"tab\there é",sym:'a symbol',long:'''one ''' '''two'''}}`
+
+	got, err := parseIonExportItem([]byte(line))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]types.AttributeValue{
+		"pk":        &types.AttributeValueMemberS{Value: "key"},
+		"sk":        &types.AttributeValueMemberN{Value: "1.5"},
+		"int":       &types.AttributeValueMemberN{Value: "1000"},
+		"dec":       &types.AttributeValueMemberN{Value: "2000"},
+		"exp":       &types.AttributeValueMemberN{Value: "1.5E2"},
+		"bin":       &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+		"bool":      &types.AttributeValueMemberBOOL{Value: true},
+		"null":      &types.AttributeValueMemberNULL{Value: true},
+		"typedNull": &types.AttributeValueMemberNULL{Value: true},
+		"ss":        &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"ns":        &types.AttributeValueMemberNS{Value: []string{"1", "2.5"}},
+		"bs":        &types.AttributeValueMemberBS{Value: [][]byte{{1}}},
+		"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "x"},
+			&types.AttributeValueMemberN{Value: "2"},
+			&types.AttributeValueMemberBOOL{Value: false},
+		}},
+		"map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"nested": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"n": &types.AttributeValueMemberN{Value: "3"},
+			}},
+		}},
+		"quoted field": &types.AttributeValueMemberS{Value: "tab\there é"},
+		"sym":          &types.AttributeValueMemberS{Value: "a symbol"},
+		"long":         &types.AttributeValueMemberS{Value: "one two"},
+	}, got)
+
+	// Items without a version marker are parsed the same, and lines with only a
+	// version marker have no item.
+	got, err = parseIonExportItem([]byte(`{Item:{pk:"key"}}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "key"}}, got)
+	got, err = parseIonExportItem([]byte(`$ion_1_0`))
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	// Exported items are translated the same as scanned items.
+	doc, err := decodeAttributes(map[string]types.AttributeValue{"pk": &types.AttributeValueMemberN{Value: "1.5E2"}}, []string{"pk"})
+	require.NoError(t, err)
+	require.Equal(t, "150", doc["pk"])
+
+	for _, bad := range []string{
+		`{Item:{pk:"key"}`,
+		`{Item:"key"}`,
+		`{Other:{pk:"key"}}`,
+		`{Item:{pk:-inf}}`,
+		`{Item:{pk:2007-01-01T}}`,
+		`{Item:{pk:$dynamodb_SS::"a"}}`,
+		`{Item:{pk:$dynamodb_SS::[1]}}`,
+		`{Item:{pk:"a" sk:"b"}}`,
+		`{Item:{pk:(a b)}}`,
+		`{Item:{pk:"key"}} {Item:{pk:"key"}}`,
+	} {
+		_, err := parseIonExportItem([]byte(bad))
+		require.Error(t, err, bad)
+	}
+}
+
+func TestRecordsAfterExport(t *testing.T) {
+	exportTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tbl := &table{exportTime: exportTime}
+
+	before := streamTypes.Record{EventID: aws.String("before"), Dynamodb: &streamTypes.StreamRecord{
+		ApproximateCreationDateTime: aws.Time(exportTime.Add(-time.Hour)),
+	}}
+	after := streamTypes.Record{EventID: aws.String("after"), Dynamodb: &streamTypes.StreamRecord{
+		ApproximateCreationDateTime: aws.Time(exportTime),
+	}}
+	unknown := streamTypes.Record{EventID: aws.String("unknown"), Dynamodb: &streamTypes.StreamRecord{}}
+
+	require.Equal(t,
+		[]streamTypes.Record{after, unknown},
+		tbl.recordsAfterExport([]streamTypes.Record{before, after, unknown}),
+	)
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Annotations of Ion lists which hold DynamoDB sets in ION exports.
+const (
+	ionStringSetAnnotation = "$dynamodb_SS"
+	ionNumberSetAnnotation = "$dynamodb_NS"
+	ionBinarySetAnnotation = "$dynamodb_BS"
+
+	ionVersionMarker = "$ion_1_0"
+)
+
+// parseIonExportItem parses a line of an ION export data file, which is an Ion text struct with the
+// item as its "Item" field, optionally preceded by an Ion version marker. It returns a nil item for
+// lines which only contain version markers.
+//
+// Only the subset of Ion text used by DynamoDB exports is supported: structs, lists, strings,
+// symbols, numbers, booleans, nulls and blobs. Attribute values are translated the same as
+// DynamoDB JSON export items, with lists annotated as sets translated to DynamoDB sets.
+func parseIonExportItem(line []byte) (map[string]types.AttributeValue, error) {
+	p := &ionParser{data: line}
+
+	var item map[string]types.AttributeValue
+	for {
+		p.skipWhitespace()
+		if p.pos == len(p.data) {
+			return item, nil
+		} else if p.consumeKeyword(ionVersionMarker) {
+			continue
+		} else if item != nil {
+			return nil, fmt.Errorf("line has more than one item")
+		}
+
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		wrapper, ok := val.(*types.AttributeValueMemberM)
+		if !ok {
+			return nil, fmt.Errorf("line is not a struct")
+		} else if m, ok := wrapper.Value["Item"].(*types.AttributeValueMemberM); !ok {
+			return nil, fmt.Errorf("line has no item")
+		} else {
+			item = m.Value
+		}
+	}
+}
+
+type ionParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *ionParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *ionParser) skipWhitespace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *ionParser) peek(s string) bool {
+	return strings.HasPrefix(string(p.data[p.pos:]), s)
+}
+
+func (p *ionParser) consume(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// consumeKeyword consumes the identifier `s` if it is the next identifier.
+func (p *ionParser) consumeKeyword(s string) bool {
+	if !p.peek(s) {
+		return false
+	} else if end := p.pos + len(s); end < len(p.data) && isIdentifierPart(p.data[end]) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+func (p *ionParser) parseValue() (types.AttributeValue, error) {
+	var annotations []string
+	for {
+		p.skipWhitespace()
+		if p.pos == len(p.data) {
+			return nil, p.errorf("unexpected end of value")
+		}
+
+		// Symbols are either annotations of the following value, or are themselves the value.
+		var sym string
+		var quoted bool
+		if c := p.data[p.pos]; isIdentifierStart(c) {
+			start := p.pos
+			for p.pos < len(p.data) && isIdentifierPart(p.data[p.pos]) {
+				p.pos++
+			}
+			sym = string(p.data[start:p.pos])
+		} else if c == '\'' && !p.peek("'''") {
+			p.pos++
+			s, err := p.parseQuoted('\'')
+			if err != nil {
+				return nil, err
+			}
+			sym, quoted = s, true
+		} else {
+			break
+		}
+
+		p.skipWhitespace()
+		if p.consume("::") {
+			annotations = append(annotations, sym)
+			continue
+		}
+
+		return p.symbolValue(sym, quoted, annotations)
+	}
+
+	var val types.AttributeValue
+	var err error
+
+	switch c := p.data[p.pos]; {
+	case c == '"' || p.peek("'''"):
+		var s string
+		if s, err = p.parseString(); err == nil {
+			val = &types.AttributeValueMemberS{Value: s}
+		}
+	case c == '-' || (c >= '0' && c <= '9'):
+		var n string
+		if n, err = p.parseNumber(); err == nil {
+			val = &types.AttributeValueMemberN{Value: n}
+		}
+	case p.peek("{{"):
+		var b []byte
+		if b, err = p.parseBlob(); err == nil {
+			val = &types.AttributeValueMemberB{Value: b}
+		}
+	case c == '{':
+		var m map[string]types.AttributeValue
+		if m, err = p.parseStruct(); err == nil {
+			val = &types.AttributeValueMemberM{Value: m}
+		}
+	case c == '[':
+		var l []types.AttributeValue
+		if l, err = p.parseList(); err == nil {
+			val = &types.AttributeValueMemberL{Value: l}
+		}
+	default:
+		return nil, p.errorf("unsupported Ion value starting with %q", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return annotate(val, annotations)
+}
+
+// symbolValue returns the value of a symbol which is not an annotation. Unquoted symbols may be
+// the keywords of Ion nulls and booleans, and other symbols are translated as strings.
+func (p *ionParser) symbolValue(sym string, quoted bool, annotations []string) (types.AttributeValue, error) {
+	var val types.AttributeValue
+
+	switch {
+	case quoted:
+		val = &types.AttributeValueMemberS{Value: sym}
+	case sym == "null":
+		// Typed nulls such as null.string are all translated as DynamoDB nulls.
+		if p.consume(".") {
+			for p.pos < len(p.data) && isIdentifierPart(p.data[p.pos]) {
+				p.pos++
+			}
+		}
+		val = &types.AttributeValueMemberNULL{Value: true}
+	case sym == "true" || sym == "false":
+		val = &types.AttributeValueMemberBOOL{Value: sym == "true"}
+	case sym == "nan":
+		return nil, p.errorf("unsupported Ion value nan")
+	default:
+		val = &types.AttributeValueMemberS{Value: sym}
+	}
+
+	return annotate(val, annotations)
+}
+
+// annotate translates lists annotated as DynamoDB sets into their set types.
+func annotate(val types.AttributeValue, annotations []string) (types.AttributeValue, error) {
+	if len(annotations) == 0 {
+		return val, nil
+	} else if len(annotations) != 1 {
+		return nil, fmt.Errorf("unsupported Ion annotations %v", annotations)
+	}
+
+	list, ok := val.(*types.AttributeValueMemberL)
+	if !ok {
+		return nil, fmt.Errorf("Ion annotation %q of a value which is not a list", annotations[0])
+	}
+
+	switch annotations[0] {
+	case ionStringSetAnnotation:
+		out := &types.AttributeValueMemberSS{Value: make([]string, 0, len(list.Value))}
+		for _, e := range list.Value {
+			s, ok := e.(*types.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("string set element of type %T", e)
+			}
+			out.Value = append(out.Value, s.Value)
+		}
+		return out, nil
+	case ionNumberSetAnnotation:
+		out := &types.AttributeValueMemberNS{Value: make([]string, 0, len(list.Value))}
+		for _, e := range list.Value {
+			n, ok := e.(*types.AttributeValueMemberN)
+			if !ok {
+				return nil, fmt.Errorf("number set element of type %T", e)
+			}
+			out.Value = append(out.Value, n.Value)
+		}
+		return out, nil
+	case ionBinarySetAnnotation:
+		out := &types.AttributeValueMemberBS{Value: make([][]byte, 0, len(list.Value))}
+		for _, e := range list.Value {
+			b, ok := e.(*types.AttributeValueMemberB)
+			if !ok {
+				return nil, fmt.Errorf("binary set element of type %T", e)
+			}
+			out.Value = append(out.Value, b.Value)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported Ion annotation %q", annotations[0])
+	}
+}
+
+func (p *ionParser) parseStruct() (map[string]types.AttributeValue, error) {
+	p.pos++ // Opening brace.
+	out := make(map[string]types.AttributeValue)
+
+	for {
+		p.skipWhitespace()
+		if p.consume("}") {
+			return out, nil
+		} else if p.pos == len(p.data) {
+			return nil, p.errorf("unterminated struct")
+		}
+
+		var name string
+		var err error
+		switch c := p.data[p.pos]; {
+		case c == '"' || p.peek("'''"):
+			name, err = p.parseString()
+		case c == '\'':
+			p.pos++
+			name, err = p.parseQuoted('\'')
+		case isIdentifierStart(c):
+			start := p.pos
+			for p.pos < len(p.data) && isIdentifierPart(p.data[p.pos]) {
+				p.pos++
+			}
+			name = string(p.data[start:p.pos])
+		default:
+			err = p.errorf("invalid struct field name starting with %q", c)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipWhitespace()
+		if !p.consume(":") {
+			return nil, p.errorf("expected ':' after struct field name %q", name)
+		}
+
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		out[name] = val
+
+		p.skipWhitespace()
+		if !p.consume(",") && !p.peek("}") {
+			return nil, p.errorf("expected ',' or '}' after struct field %q", name)
+		}
+	}
+}
+
+func (p *ionParser) parseList() ([]types.AttributeValue, error) {
+	p.pos++ // Opening bracket.
+	out := []types.AttributeValue{}
+
+	for {
+		p.skipWhitespace()
+		if p.consume("]") {
+			return out, nil
+		} else if p.pos == len(p.data) {
+			return nil, p.errorf("unterminated list")
+		}
+
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, fmt.Errorf("list element %d: %w", len(out), err)
+		}
+		out = append(out, val)
+
+		p.skipWhitespace()
+		if !p.consume(",") && !p.peek("]") {
+			return nil, p.errorf("expected ',' or ']' after list element %d", len(out)-1)
+		}
+	}
+}
+
+// parseString parses a short "quoted" string, or a sequence of '''long''' strings which are
+// concatenated.
+func (p *ionParser) parseString() (string, error) {
+	if p.consume(`"`) {
+		return p.parseQuoted('"')
+	}
+
+	var sb strings.Builder
+	for {
+		p.skipWhitespace()
+		if !p.consume("'''") {
+			return sb.String(), nil
+		}
+
+		end := -1
+		for i := p.pos; i < len(p.data); i++ {
+			if p.data[i] == '\\' {
+				i++ // Skip the escaped character.
+			} else if strings.HasPrefix(string(p.data[i:]), "'''") {
+				end = i - p.pos
+				break
+			}
+		}
+		if end == -1 {
+			return "", p.errorf("unterminated long string")
+		}
+
+		s, err := unescapeIon(p.data[p.pos : p.pos+end])
+		if err != nil {
+			return "", p.errorf("%s", err)
+		}
+		sb.WriteString(s)
+		p.pos += end + 3
+	}
+}
+
+// parseQuoted parses the remainder of a string or symbol after its opening quote.
+func (p *ionParser) parseQuoted(quote byte) (string, error) {
+	start := p.pos
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos += 2
+		case quote:
+			s, err := unescapeIon(p.data[start:p.pos])
+			if err != nil {
+				return "", p.errorf("%s", err)
+			}
+			p.pos++
+			return s, nil
+		default:
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated quoted text")
+}
+
+func unescapeIon(b []byte) (string, error) {
+	if !strings.ContainsRune(string(b), '\\') {
+		return string(b), nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' {
+			sb.WriteByte(b[i])
+			continue
+		} else if i++; i == len(b) {
+			return "", fmt.Errorf("invalid trailing escape")
+		}
+
+		switch c := b[i]; c {
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'v':
+			sb.WriteByte('\v')
+		case '0':
+			sb.WriteByte(0)
+		case '?', '\'', '"', '/', '\\':
+			sb.WriteByte(c)
+		case '\n':
+			// Escaped newlines are line continuations.
+		case 'x', 'u', 'U':
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			if i+digits >= len(b) {
+				return "", fmt.Errorf("invalid escape \\%c", c)
+			}
+			r, err := strconv.ParseUint(string(b[i+1:i+1+digits]), 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", fmt.Errorf("invalid escape \\%c%s", c, b[i+1:i+1+digits])
+			}
+			sb.WriteRune(rune(r))
+			i += digits
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// parseNumber parses an Ion int, decimal or float into the string representation of a DynamoDB
+// number.
+func (p *ionParser) parseNumber() (string, error) {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '.' || c == '_' || c == '+' || c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			p.pos++
+		} else {
+			break
+		}
+	}
+	text := strings.ReplaceAll(string(p.data[start:p.pos]), "_", "")
+
+	lower := strings.ToLower(strings.TrimPrefix(text, "-"))
+	if strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "0b") {
+		n, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			return "", p.errorf("invalid Ion int %q", text)
+		}
+		return strconv.FormatInt(n, 10), nil
+	}
+
+	// Decimals use 'd' for their exponent, and may have a trailing decimal point.
+	n := strings.NewReplacer("d", "E", "D", "E", "e", "E").Replace(text)
+	if mantissa, exp, ok := strings.Cut(n, "E"); ok {
+		n = strings.TrimSuffix(mantissa, ".") + "E" + exp
+	} else {
+		n = strings.TrimSuffix(n, ".")
+	}
+
+	if f, err := strconv.ParseFloat(n, 64); err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", p.errorf("invalid Ion number %q", text)
+	}
+	return n, nil
+}
+
+// parseBlob parses a {{ base64 }} blob.
+func (p *ionParser) parseBlob() ([]byte, error) {
+	p.pos += 2 // Opening braces.
+
+	end := strings.Index(string(p.data[p.pos:]), "}}")
+	if end == -1 {
+		return nil, p.errorf("unterminated blob")
+	}
+	content := strings.Join(strings.Fields(string(p.data[p.pos:p.pos+end])), "")
+	if strings.HasPrefix(content, `"`) || strings.HasPrefix(content, "'") {
+		return nil, p.errorf("unsupported Ion clob")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, p.errorf("invalid blob: %s", err)
+	}
+	p.pos += end + 2
+
+	return b, nil
+}
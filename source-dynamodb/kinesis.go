@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	streamTypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesisTypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Records for an item are only deduplicated against other records for the same item that are
+	// within this long of the most recent record seen for the table. This bounds the memory and
+	// checkpoint size used for deduplication.
+	dedupeWindow = 15 * time.Minute
+
+	// ApproximateCreationDateTime values larger than this are in microseconds rather than
+	// milliseconds, which is the case for tables with a Kinesis streaming destination configured
+	// for microsecond precision.
+	maxMillisecondTimestamp = 1e14
+)
+
+// kinesisDestination returns the ARN of the active Kinesis data stream that the table is streaming
+// to, or an empty string if there isn't one.
+func kinesisDestination(ctx context.Context, c *client, table string) (string, error) {
+	res, err := c.db.DescribeKinesisStreamingDestination(ctx, &dynamodb.DescribeKinesisStreamingDestinationInput{
+		TableName: aws.String(table),
+	})
+	if err != nil {
+		return "", fmt.Errorf("describe kinesis streaming destination: %w", err)
+	}
+
+	for _, d := range res.KinesisDataStreamDestinations {
+		if d.DestinationStatus == types.DestinationStatusActive {
+			return aws.ToString(d.StreamArn), nil
+		}
+	}
+
+	return "", nil
+}
+
+// listKinesisShards lists the shards of a Kinesis data stream. They are returned as DynamoDB stream
+// shards so that the same logic can be used for reading shards from either kind of stream. Kinesis
+// shards that are the result of merging two shards have an additional "adjacent" parent shard which
+// is not tracked, so records for an item may be read out of order after a merge. These are dropped
+// by the table's changeDeduplicator.
+func (c *capture) listKinesisShards(ctx context.Context, streamArn string) (map[string]streamTypes.Shard, error) {
+	shards := make(map[string]streamTypes.Shard)
+
+	input := &kinesis.ListShardsInput{StreamARN: aws.String(streamArn)}
+	for {
+		if err := c.listShardsLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("list shards limiter wait: %w", err)
+		}
+
+		res, err := c.client.kinesis.ListShards(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listing kinesis shards: %w", err)
+		}
+
+		for _, s := range res.Shards {
+			shards[*s.ShardId] = streamTypes.Shard{
+				ShardId:       s.ShardId,
+				ParentShardId: s.ParentShardId,
+				SequenceNumberRange: &streamTypes.SequenceNumberRange{
+					StartingSequenceNumber: s.SequenceNumberRange.StartingSequenceNumber,
+					EndingSequenceNumber:   s.SequenceNumberRange.EndingSequenceNumber,
+				},
+			}
+		}
+
+		if res.NextToken == nil { // Pagination
+			break
+		}
+		// The stream must not be specified when paginating with a token.
+		input = &kinesis.ListShardsInput{NextToken: res.NextToken}
+	}
+
+	return shards, nil
+}
+
+func (c *capture) getKinesisShardIterator(ctx context.Context, t *table, shard streamTypes.Shard, lastReadSeq string) (*string, error) {
+	input := &kinesis.GetShardIteratorInput{
+		ShardId:   shard.ShardId,
+		StreamARN: aws.String(t.streamArn),
+	}
+
+	if lastReadSeq == "" {
+		input.ShardIteratorType = kinesisTypes.ShardIteratorTypeTrimHorizon
+	} else {
+		input.ShardIteratorType = kinesisTypes.ShardIteratorTypeAfterSequenceNumber
+		input.StartingSequenceNumber = aws.String(lastReadSeq)
+	}
+
+	res, err := c.client.kinesis.GetShardIterator(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.ShardIterator, nil
+}
+
+// getKinesisRecords reads records from a Kinesis shard and converts them into DynamoDB stream
+// records. A Kinesis data stream may be the destination for more than one table, and records for
+// other tables are skipped.
+func (c *capture) getKinesisRecords(ctx context.Context, t *table, iter *string) ([]streamTypes.Record, *string, error) {
+	res, err := c.client.kinesis.GetRecords(ctx, &kinesis.GetRecordsInput{
+		ShardIterator: iter,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]streamTypes.Record, 0, len(res.Records))
+	for _, r := range res.Records {
+		rec, tableName, err := parseKinesisChangeRecord(r.Data, *r.SequenceNumber)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing kinesis record %s: %w", *r.SequenceNumber, err)
+		} else if tableName != t.tableName {
+			continue
+		}
+		out = append(out, rec)
+	}
+
+	return out, res.NextShardIterator, nil
+}
+
+// kinesisChangeRecord is the JSON representation of a change record written to a Kinesis data
+// stream by Kinesis Data Streams for DynamoDB.
+type kinesisChangeRecord struct {
+	EventID      string `json:"eventID"`
+	EventName    string `json:"eventName"`
+	TableName    string `json:"tableName"`
+	UserIdentity *struct {
+		PrincipalId string `json:"principalId"`
+		Type        string `json:"type"`
+	} `json:"userIdentity"`
+	Dynamodb struct {
+		ApproximateCreationDateTime int64                      `json:"ApproximateCreationDateTime"`
+		Keys                        map[string]json.RawMessage `json:"Keys"`
+		NewImage                    map[string]json.RawMessage `json:"NewImage"`
+		OldImage                    map[string]json.RawMessage `json:"OldImage"`
+		SizeBytes                   int64                      `json:"SizeBytes"`
+	} `json:"dynamodb"`
+}
+
+// parseKinesisChangeRecord converts the data of a Kinesis record into a DynamoDB stream record, so
+// that it is captured exactly like a record read from a DynamoDB stream. The Kinesis sequence
+// number is used as the sequence number of the record. The name of the table the record is for is
+// also returned.
+func parseKinesisChangeRecord(data []byte, sequenceNumber string) (streamTypes.Record, string, error) {
+	var rec kinesisChangeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return streamTypes.Record{}, "", err
+	}
+
+	created := time.UnixMilli(rec.Dynamodb.ApproximateCreationDateTime)
+	if rec.Dynamodb.ApproximateCreationDateTime > maxMillisecondTimestamp {
+		created = time.UnixMicro(rec.Dynamodb.ApproximateCreationDateTime)
+	}
+
+	out := streamTypes.Record{
+		EventID:   aws.String(rec.EventID),
+		EventName: streamTypes.OperationType(rec.EventName),
+		Dynamodb: &streamTypes.StreamRecord{
+			ApproximateCreationDateTime: aws.Time(created.UTC()),
+			SequenceNumber:              aws.String(sequenceNumber),
+			SizeBytes:                   aws.Int64(rec.Dynamodb.SizeBytes),
+			StreamViewType:              streamTypes.StreamViewTypeNewAndOldImages,
+		},
+	}
+
+	if rec.UserIdentity != nil {
+		out.UserIdentity = &streamTypes.Identity{
+			PrincipalId: aws.String(rec.UserIdentity.PrincipalId),
+			Type:        aws.String(rec.UserIdentity.Type),
+		}
+	}
+
+	for _, img := range []struct {
+		raw map[string]json.RawMessage
+		out *map[string]streamTypes.AttributeValue
+	}{
+		{rec.Dynamodb.Keys, &out.Dynamodb.Keys},
+		{rec.Dynamodb.NewImage, &out.Dynamodb.NewImage},
+		{rec.Dynamodb.OldImage, &out.Dynamodb.OldImage},
+	} {
+		if img.raw == nil {
+			continue
+		}
+
+		attrs, err := parseDynamoJSONMap(img.raw)
+		if err != nil {
+			return streamTypes.Record{}, "", err
+		}
+		if *img.out, err = toStreamsAttributeValueMap(attrs); err != nil {
+			return streamTypes.Record{}, "", err
+		}
+	}
+
+	return out, rec.TableName, nil
+}
+
+func toStreamsAttributeValueMap(attrs map[string]types.AttributeValue) (map[string]streamTypes.AttributeValue, error) {
+	out := make(map[string]streamTypes.AttributeValue, len(attrs))
+	for k, v := range attrs {
+		av, err := toStreamsAttributeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", k, err)
+		}
+		out[k] = av
+	}
+	return out, nil
+}
+
+// toStreamsAttributeValue converts a DynamoDB attribute value into the equivalent DynamoDB streams
+// attribute value. It is the inverse of attributevalue.FromDynamoDBStreams.
+func toStreamsAttributeValue(av types.AttributeValue) (streamTypes.AttributeValue, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &streamTypes.AttributeValueMemberS{Value: v.Value}, nil
+	case *types.AttributeValueMemberN:
+		return &streamTypes.AttributeValueMemberN{Value: v.Value}, nil
+	case *types.AttributeValueMemberB:
+		return &streamTypes.AttributeValueMemberB{Value: v.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return &streamTypes.AttributeValueMemberBOOL{Value: v.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return &streamTypes.AttributeValueMemberNULL{Value: v.Value}, nil
+	case *types.AttributeValueMemberSS:
+		return &streamTypes.AttributeValueMemberSS{Value: v.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return &streamTypes.AttributeValueMemberNS{Value: v.Value}, nil
+	case *types.AttributeValueMemberBS:
+		return &streamTypes.AttributeValueMemberBS{Value: v.Value}, nil
+	case *types.AttributeValueMemberL:
+		out := &streamTypes.AttributeValueMemberL{Value: make([]streamTypes.AttributeValue, 0, len(v.Value))}
+		for idx, e := range v.Value {
+			elem, err := toStreamsAttributeValue(e)
+			if err != nil {
+				return nil, fmt.Errorf("list element %d: %w", idx, err)
+			}
+			out.Value = append(out.Value, elem)
+		}
+		return out, nil
+	case *types.AttributeValueMemberM:
+		m, err := toStreamsAttributeValueMap(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &streamTypes.AttributeValueMemberM{Value: m}, nil
+	default:
+		return nil, fmt.Errorf("unhandled attribute value type %T", av)
+	}
+}
+
+// changeDeduplicator drops change records for an item that are duplicates of, or older than, the
+// most recent record emitted for the item. Unlike DynamoDB streams, Kinesis may deliver change
+// records more than once and out of order. The ApproximateCreationDateTime of records is used to
+// determine their order.
+//
+// The records seen within the dedupe window are persisted in the table's checkpointed state, so
+// that records re-delivered after a restart are also dropped.
+type changeDeduplicator struct {
+	tableName string
+	keyFields []string
+
+	mu        sync.Mutex
+	seen      map[string]*seenChange
+	newest    time.Time
+	lastPrune time.Time
+}
+
+// seenChange is the most recent change record emitted for an item. It is persisted in the table
+// state keyed by the base64 encoding of the item's packed key.
+type seenChange struct {
+	At      time.Time `json:"t"`
+	EventID string    `json:"e"`
+}
+
+// newChangeDeduplicator creates a changeDeduplicator for a table, which is initialized with the
+// records seen by the deduplicator prior to the capture restarting.
+func newChangeDeduplicator(tableName string, keyFields []string, restored map[string]*seenChange) *changeDeduplicator {
+	d := &changeDeduplicator{
+		tableName: tableName,
+		keyFields: keyFields,
+		seen:      make(map[string]*seenChange, len(restored)),
+	}
+
+	for k, v := range restored {
+		if v == nil {
+			continue
+		}
+		d.seen[k] = v
+		if v.At.After(d.newest) {
+			d.newest = v.At
+		}
+	}
+
+	return d
+}
+
+// filter returns the records that should be emitted, and the changes to the persisted seen
+// records that must be checkpointed along with them. Entries of the returned changes that are nil
+// have been pruned from the dedupe window. A nil changeDeduplicator does not filter any records.
+func (d *changeDeduplicator) filter(records []streamTypes.Record) ([]streamTypes.Record, map[string]*seenChange, error) {
+	if d == nil {
+		return records, nil, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]streamTypes.Record, 0, len(records))
+	changes := make(map[string]*seenChange)
+	for _, r := range records {
+		keys, err := attributevalue.FromDynamoDBStreamsMap(r.Dynamodb.Keys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("converting from streams attribute value: %w", err)
+		}
+		encoded, err := encodeKey(d.keyFields, keys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encoding record key: %w", err)
+		}
+		key := base64.StdEncoding.EncodeToString(encoded)
+
+		at := *r.Dynamodb.ApproximateCreationDateTime
+		eventID := aws.ToString(r.EventID)
+
+		if prev, ok := d.seen[key]; ok && (at.Before(prev.At) || (at.Equal(prev.At) && eventID == prev.EventID)) {
+			log.WithFields(log.Fields{
+				"table":                       d.tableName,
+				"eventId":                     eventID,
+				"approximateCreationDateTime": at.String(),
+				"previousEventId":             prev.EventID,
+			}).Debug("dropping duplicate or out of order change record")
+			continue
+		}
+
+		seen := &seenChange{At: at, EventID: eventID}
+		d.seen[key] = seen
+		changes[key] = seen
+		if at.After(d.newest) {
+			d.newest = at
+		}
+		out = append(out, r)
+	}
+
+	if d.newest.Sub(d.lastPrune) > dedupeWindow {
+		horizon := d.newest.Add(-dedupeWindow)
+		for k, v := range d.seen {
+			if v.At.Before(horizon) {
+				delete(d.seen, k)
+				changes[k] = nil
+			}
+		}
+		d.lastPrune = d.newest
+	}
+
+	return out, changes, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	streamTypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKinesisChangeRecord(t *testing.T) {
+	data := `{
+		"awsRegion": "us-east-1",
+		"eventID": "b2d5a9a2-e1d6-4a8f-8d62-6b2f3c3e8a11",
+		"eventName": "MODIFY",
+		"userIdentity": null,
+		"recordFormat": "application/json",
+		"tableName": "someTable",
+		"dynamodb": {
+			"ApproximateCreationDateTime": 1696334400123,
+			"Keys": {"pk": {"N": "1"}},
+			"NewImage": {"pk": {"N": "1"}, "val": {"M": {"nested": {"L": [{"S": "a"}, {"BOOL": true}]}}}},
+			"OldImage": {"pk": {"N": "1"}, "val": {"NULL": true}, "removed": {"SS": ["x"]}},
+			"SizeBytes": 42
+		},
+		"eventSource": "aws:dynamodb"
+	}`
+
+	rec, tableName, err := parseKinesisChangeRecord([]byte(data), "4960")
+	require.NoError(t, err)
+	require.Equal(t, "someTable", tableName)
+	require.Equal(t, "b2d5a9a2-e1d6-4a8f-8d62-6b2f3c3e8a11", *rec.EventID)
+	require.Equal(t, streamTypes.OperationTypeModify, rec.EventName)
+	require.Nil(t, rec.UserIdentity)
+	require.Equal(t, "4960", *rec.Dynamodb.SequenceNumber)
+	require.Equal(t, time.UnixMilli(1696334400123).UTC(), *rec.Dynamodb.ApproximateCreationDateTime)
+
+	// Images are decoded the same as those of DynamoDB stream records.
+	keyFields := []string{"pk"}
+	newImage, err := decodeStreamRecordAttributes(rec.Dynamodb.NewImage, keyFields)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"pk":  "1",
+		"val": map[string]any{"nested": []any{"a", true}},
+	}, newImage)
+
+	oldImage, err := decodeStreamRecordAttributes(rec.Dynamodb.OldImage, keyFields)
+	require.NoError(t, err)
+	require.Equal(t, "1", oldImage["pk"])
+	require.Nil(t, oldImage["val"])
+
+	// Timestamps with microsecond precision.
+	rec, _, err = parseKinesisChangeRecord([]byte(`{"tableName":"someTable","dynamodb":{"ApproximateCreationDateTime":1696334400123456,"Keys":{"pk":{"N":"1"}}}}`), "4961")
+	require.NoError(t, err)
+	require.Equal(t, time.UnixMicro(1696334400123456).UTC(), *rec.Dynamodb.ApproximateCreationDateTime)
+}
+
+func TestChangeDeduplicator(t *testing.T) {
+	base := time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC)
+
+	record := func(eventID, pk string, offset time.Duration) streamTypes.Record {
+		return streamTypes.Record{
+			EventID: aws.String(eventID),
+			Dynamodb: &streamTypes.StreamRecord{
+				ApproximateCreationDateTime: aws.Time(base.Add(offset)),
+				Keys: map[string]streamTypes.AttributeValue{
+					"pk": &streamTypes.AttributeValueMemberS{Value: pk},
+				},
+			},
+		}
+	}
+
+	eventIDs := func(records []streamTypes.Record) []string {
+		var out []string
+		for _, r := range records {
+			out = append(out, *r.EventID)
+		}
+		return out
+	}
+
+	d := newChangeDeduplicator("someTable", []string{"pk"}, nil)
+
+	got, _, err := d.filter([]streamTypes.Record{
+		record("1", "a", 0),
+		record("2", "b", 0),
+		record("1", "a", 0),           // Duplicate.
+		record("3", "a", time.Second), // Newer.
+		record("4", "a", 0),           // Older than the last emitted for this key.
+		record("5", "a", time.Second), // Same time, but a different event.
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2", "3", "5"}, eventIDs(got))
+
+	// Keys are tracked across calls.
+	got, _, err = d.filter([]streamTypes.Record{
+		record("2", "b", 0),
+		record("6", "b", time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"6"}, eventIDs(got))
+
+	// Keys which have not been seen within the dedupe window are forgotten.
+	_, _, err = d.filter([]streamTypes.Record{record("7", "c", 2*dedupeWindow)})
+	require.NoError(t, err)
+	got, _, err = d.filter([]streamTypes.Record{record("1", "a", 0)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, eventIDs(got))
+
+	// Records seen prior to a restart are restored from their checkpointed changes.
+	var checkpointed = make(map[string]*seenChange)
+	d = newChangeDeduplicator("someTable", []string{"pk"}, nil)
+	for _, batch := range [][]streamTypes.Record{
+		{record("1", "a", 0), record("2", "b", 0)},
+		{record("3", "a", time.Second)},
+		{record("4", "c", 2*dedupeWindow)}, // Prunes "a" and "b".
+		{record("5", "d", 2*dedupeWindow)},
+	} {
+		_, changes, err := d.filter(batch)
+		require.NoError(t, err)
+		for k, v := range changes {
+			if v == nil {
+				delete(checkpointed, k)
+			} else {
+				checkpointed[k] = v
+			}
+		}
+	}
+	require.Len(t, checkpointed, 2)
+
+	restored := newChangeDeduplicator("someTable", []string{"pk"}, checkpointed)
+	got, _, err = restored.filter([]streamTypes.Record{
+		record("4", "c", 2*dedupeWindow), // Re-delivered after the restart.
+		record("5", "d", 2*dedupeWindow), // Re-delivered after the restart.
+		record("6", "d", 2*dedupeWindow+time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"6"}, eventIDs(got))
+
+	// A nil changeDeduplicator does not filter records.
+	var nilDedupe *changeDeduplicator
+	got, _, err = nilDedupe.filter([]streamTypes.Record{record("1", "a", 0), record("1", "a", 0)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "1"}, eventIDs(got))
+}
@@ -7,30 +7,56 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 )
 
 type config struct {
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=Access Key ID,description=AWS Access Key ID for capturing from the DynamoDB table." jsonschema_extras:"order=1"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=Secret Access Key,description=AWS Secret Access Key for capturing from the DynamoDB table." jsonschema_extras:"secret=true,order=2"`
-	Region             string `json:"region" jsonschema:"title=Region,description=Region of the DynamoDB table." jsonschema_extras:"order=3"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication" jsonschema_extras:"order=0"`
+	awsauth.AccessKeys
+	Region string `json:"region" jsonschema:"title=Region,description=Region of the DynamoDB table." jsonschema_extras:"order=3"`
 
 	Advanced advancedConfig `json:"advanced,omitempty" jsonschema:"title=Advanced Options,description=Options for advanced users. You should not typically need to modify these." jsonschema_extra:"advanced=true"`
 }
 
 type advancedConfig struct {
-	BackfillSegments int    `json:"backfillSegments,omitempty" jsonschema:"title=Backfill Table Segments,description=Number of segments to use for backfill table scans. Has no effect if changed after the backfill has started."`
-	ScanLimit        int    `json:"scanLimit,omitempty" jsonschema:"title=Scan Limit,description=Limit the number of items to evaluate for each table backfill scan request."`
-	Endpoint         string `json:"endpoint,omitempty" jsonschema:"title=AWS Endpoint,description=The AWS endpoint URI to connect to. Use if you're capturing from a compatible API that isn't provided by AWS."`
+	ChangeSource     changeSource `json:"changeSource,omitempty" jsonschema:"title=Change Source,description=Where change events of tables are read from. 'dynamodbStreams' reads from the DynamoDB stream of each table. 'kinesis' reads from the Kinesis data stream that each table is streaming to with Kinesis Data Streams for DynamoDB. Defaults to 'dynamodbStreams'. Changing this requires the bindings to be re-backfilled.,enum=dynamodbStreams,enum=kinesis"`
+	BackfillMode     backfillMode `json:"backfillMode,omitempty" jsonschema:"title=Backfill Mode,description=How tables are backfilled. 'scan' reads the table with parallel scans which consume read capacity. 'export' uses a point-in-time export of the table to S3 which does not consume read capacity but requires point-in-time recovery to be enabled for the table. Defaults to 'scan'. Has no effect if changed after the backfill has started.,enum=scan,enum=export"`
+	ExportBucket     string       `json:"exportBucket,omitempty" jsonschema:"title=Export S3 Bucket,description=Name of the S3 bucket to export tables to when using the 'export' backfill mode."`
+	ExportPrefix     string       `json:"exportPrefix,omitempty" jsonschema:"title=Export S3 Prefix,description=Optional prefix for the S3 objects of table exports when using the 'export' backfill mode."`
+	ExportFormat     exportFormat `json:"exportFormat,omitempty" jsonschema:"title=Export Format,description=Format of table exports when using the 'export' backfill mode. Defaults to 'dynamodbJson'.,enum=dynamodbJson,enum=ion"`
+	BackfillSegments int          `json:"backfillSegments,omitempty" jsonschema:"title=Backfill Table Segments,description=Number of segments to use for backfill table scans. Has no effect if changed after the backfill has started."`
+	ScanLimit        int          `json:"scanLimit,omitempty" jsonschema:"title=Scan Limit,description=Limit the number of items to evaluate for each table backfill scan request."`
+	Endpoint         string       `json:"endpoint,omitempty" jsonschema:"title=AWS Endpoint,description=The AWS endpoint URI to connect to. Use if you're capturing from a compatible API that isn't provided by AWS."`
 }
 
+type changeSource string
+
+const (
+	changeSourceDynamoDBStreams changeSource = "dynamodbStreams"
+	changeSourceKinesis         changeSource = "kinesis"
+)
+
+type backfillMode string
+
+const (
+	backfillModeScan   backfillMode = "scan"
+	backfillModeExport backfillMode = "export"
+)
+
+type exportFormat string
+
+const (
+	exportFormatDynamoDBJSON exportFormat = "dynamodbJson"
+	exportFormatIon          exportFormat = "ion"
+)
+
 func (c *config) Validate() error {
 	var requiredProperties = [][]string{
-		{"awsAccessKeyId", c.AWSAccessKeyID},
-		{"awsSecretAccessKey", c.AWSSecretAccessKey},
 		{"region", c.Region},
 	}
 	for _, req := range requiredProperties {
@@ -39,6 +65,10 @@ func (c *config) Validate() error {
 		}
 	}
 
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
+	}
+
 	if c.Advanced.BackfillSegments < 0 {
 		return fmt.Errorf("backfillSegments cannot be negative")
 	}
@@ -46,6 +76,28 @@ func (c *config) Validate() error {
 		return fmt.Errorf("scanLimit cannot be negative")
 	}
 
+	switch c.Advanced.ChangeSource {
+	case "", changeSourceDynamoDBStreams, changeSourceKinesis:
+	default:
+		return fmt.Errorf("invalid changeSource %q", c.Advanced.ChangeSource)
+	}
+
+	switch c.Advanced.BackfillMode {
+	case "", backfillModeScan:
+	case backfillModeExport:
+		if c.Advanced.ExportBucket == "" {
+			return fmt.Errorf("missing 'exportBucket' for export backfill mode")
+		}
+	default:
+		return fmt.Errorf("invalid backfillMode %q", c.Advanced.BackfillMode)
+	}
+
+	switch c.Advanced.ExportFormat {
+	case "", exportFormatDynamoDBJSON, exportFormatIon:
+	default:
+		return fmt.Errorf("invalid exportFormat %q", c.Advanced.ExportFormat)
+	}
+
 	return nil
 }
 
@@ -73,9 +125,6 @@ func (r *resource) Validate() error {
 
 func (c *config) toClient(ctx context.Context) (*client, error) {
 	opts := []func(*awsConfig.LoadOptions) error{
-		awsConfig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(c.AWSAccessKeyID, c.AWSSecretAccessKey, ""),
-		),
 		awsConfig.WithRegion(c.Region),
 		awsConfig.WithRetryer(func() aws.Retryer {
 			// Bump up the number of retry maximum attempts from the default of 3. The maximum retry
@@ -95,20 +144,25 @@ func (c *config) toClient(ctx context.Context) (*client, error) {
 		opts = append(opts, awsConfig.WithEndpointResolverWithOptions(customResolver))
 	}
 
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, opts...)
+	creds := awsauth.ResolveCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey)
+	awsCfg, err := creds.AWSConfig(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating aws config: %w", err)
 	}
 
 	return &client{
-		db:     dynamodb.NewFromConfig(awsCfg),
-		stream: dynamodbstreams.NewFromConfig(awsCfg),
+		db:      dynamodb.NewFromConfig(awsCfg),
+		stream:  dynamodbstreams.NewFromConfig(awsCfg),
+		s3:      s3.NewFromConfig(awsCfg),
+		kinesis: kinesis.NewFromConfig(awsCfg),
 	}, nil
 }
 
 type client struct {
-	db     *dynamodb.Client
-	stream *dynamodbstreams.Client
+	db      *dynamodb.Client
+	stream  *dynamodbstreams.Client
+	s3      *s3.Client
+	kinesis *kinesis.Client
 }
 
 func main() {
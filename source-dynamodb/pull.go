@@ -57,6 +57,13 @@ type table struct {
 	totalBackfillSegments int
 	streamArn             string
 	backfillComplete      bool
+
+	// Set if change events are read from a Kinesis data stream rather than a DynamoDB stream.
+	dedupe *changeDeduplicator
+
+	// Set if the table is backfilled from an export rather than by scanning it.
+	exportArn  string
+	exportTime time.Time
 }
 
 func (driver) Pull(open *pc.Request_Open, stream *boilerplate.PullOutput) error {
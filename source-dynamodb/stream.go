@@ -229,11 +229,10 @@ func (c *capture) readShard(
 
 	lastReadSeq := state.LastReadSequence
 
-	iterOutput, err := c.client.stream.GetShardIterator(ctx, t.getShardIteratorInput(shard, lastReadSeq))
+	iter, err := c.getShardIterator(ctx, t, shard, lastReadSeq)
 	if err != nil {
 		return fmt.Errorf("getting shard iterator: %w", err)
 	}
-	iter := iterOutput.ShardIterator
 
 	log.WithFields(log.Fields{
 		"table":     t.tableName,
@@ -272,16 +271,14 @@ func (c *capture) readShard(
 			return err
 		}
 
-		recs, err := c.client.stream.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
-			ShardIterator: iter,
-		})
+		records, nextIter, err := c.getRecords(ctx, t, iter)
 		if err != nil {
 			return fmt.Errorf("getting stream records: %w", err)
 		}
 
 		reachedHorizon := false
 		lastItemIdx := 0
-		for _, r := range recs.Records {
+		for _, r := range records {
 			if horizon != 0 && time.Since(*r.Dynamodb.ApproximateCreationDateTime) <= horizon {
 				// If this record is more recent than the desired time horizon, we will checkpoint
 				// everything prior to this record and then return.
@@ -301,13 +298,18 @@ func (c *capture) readShard(
 		}
 
 		// Checkpoint new records or the fact that this shard is closed.
-		if lastItemIdx > 0 || recs.NextShardIterator == nil {
+		if lastItemIdx > 0 || nextIter == nil {
 			newState := shardState{
 				LastReadSequence: lastReadSeq,
-				FinishedReading:  recs.NextShardIterator == nil,
+				FinishedReading:  nextIter == nil,
+			}
+
+			emit, dedupe, err := t.dedupe.filter(t.recordsAfterExport(records[:lastItemIdx]))
+			if err != nil {
+				return fmt.Errorf("deduplicating stream records for table '%s': %w", t.tableName, err)
 			}
 
-			if err := c.emitStream(t.bindingIdx, t.stateKey, *shard.ShardId, newState, recs.Records[:lastItemIdx], t.keyFields); err != nil {
+			if err := c.emitStream(t.bindingIdx, t.stateKey, *shard.ShardId, newState, emit, dedupe, t.keyFields); err != nil {
 				return fmt.Errorf("emitting stream documents for table '%s': %w", t.tableName, err)
 			}
 		}
@@ -320,7 +322,7 @@ func (c *capture) readShard(
 
 		// Advance for the next round. A nil NextShardIterator from the GetRecords response
 		// indicates that the shard is closed and will yield no additional records.
-		iter = recs.NextShardIterator
+		iter = nextIter
 	}
 
 	log.WithFields(log.Fields{
@@ -332,6 +334,57 @@ func (c *capture) readShard(
 	return nil
 }
 
+// recordsAfterExport filters out change events that are already reflected in the
+// export the table was backfilled from. Stream record creation times are only
+// approximate, so events from shortly before the export time are retained, as
+// are events without a creation time.
+func (t *table) recordsAfterExport(records []streamTypes.Record) []streamTypes.Record {
+	if t.exportTime.IsZero() {
+		return records
+	}
+
+	cutoff := t.exportTime.Add(-exportTimeTolerance)
+	out := make([]streamTypes.Record, 0, len(records))
+	for _, r := range records {
+		if r.Dynamodb != nil && r.Dynamodb.ApproximateCreationDateTime != nil && r.Dynamodb.ApproximateCreationDateTime.Before(cutoff) {
+			continue
+		}
+		out = append(out, r)
+	}
+
+	return out
+}
+
+func (c *capture) getShardIterator(ctx context.Context, t *table, shard streamTypes.Shard, lastReadSeq string) (*string, error) {
+	if c.config.Advanced.ChangeSource == changeSourceKinesis {
+		return c.getKinesisShardIterator(ctx, t, shard, lastReadSeq)
+	}
+
+	iterOutput, err := c.client.stream.GetShardIterator(ctx, t.getShardIteratorInput(shard, lastReadSeq))
+	if err != nil {
+		return nil, err
+	}
+
+	return iterOutput.ShardIterator, nil
+}
+
+// getRecords returns the records for a shard iterator, and the iterator for the next records of the
+// shard. A nil next iterator means the shard is closed and all of its records have been read.
+func (c *capture) getRecords(ctx context.Context, t *table, iter *string) ([]streamTypes.Record, *string, error) {
+	if c.config.Advanced.ChangeSource == changeSourceKinesis {
+		return c.getKinesisRecords(ctx, t, iter)
+	}
+
+	recs, err := c.client.stream.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+		ShardIterator: iter,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return recs.Records, recs.NextShardIterator, nil
+}
+
 func (t *table) getShardIteratorInput(shard streamTypes.Shard, lastReadSeq string) *dynamodbstreams.GetShardIteratorInput {
 	input := &dynamodbstreams.GetShardIteratorInput{
 		ShardId:   shard.ShardId,
@@ -351,6 +404,10 @@ func (t *table) getShardIteratorInput(shard streamTypes.Shard, lastReadSeq strin
 }
 
 func (c *capture) listShards(ctx context.Context, streamArn string) (map[string]streamTypes.Shard, error) {
+	if c.config.Advanced.ChangeSource == changeSourceKinesis {
+		return c.listKinesisShards(ctx, streamArn)
+	}
+
 	shards := make(map[string]streamTypes.Shard)
 
 	var exclusiveStartShardId *string
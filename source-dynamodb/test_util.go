@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,9 +33,11 @@ func testClient(t *testing.T) (*client, config) {
 	ctx := context.Background()
 
 	config := config{
-		AWSAccessKeyID:     *accessKeyId,
-		AWSSecretAccessKey: *secretAccessKey,
-		Region:             *region,
+		AccessKeys: awsauth.AccessKeys{
+			AWSAccessKeyID:     *accessKeyId,
+			AWSSecretAccessKey: *secretAccessKey,
+		},
+		Region: *region,
 		Advanced: advancedConfig{
 			Endpoint:         *endpoint,
 			BackfillSegments: 2, // Keep snapshotted checkpoints readable
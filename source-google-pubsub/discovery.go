@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/estuary/connectors/go/decoder"
 	pc "github.com/estuary/flow/go/protocols/capture"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	"github.com/invopop/jsonschema"
@@ -40,6 +41,16 @@ func (driver) Discover(ctx context.Context, req *pc.Request_Discover) (*pc.Respo
 	bindings := make([]*pc.Response_Discovered_Binding, 0, len(discoveredTopics))
 
 	for _, topic := range discoveredTopics {
+		dec, err := cfg.decoder(nil, topic)
+		if err != nil {
+			return nil, err
+		}
+
+		schema, err := documentSchema(ctx, dec)
+		if err != nil {
+			return nil, fmt.Errorf("generating schema for topic %q: %w", topic, err)
+		}
+
 		resourceJson, err := json.Marshal(resource{Topic: topic})
 		if err != nil {
 			return nil, fmt.Errorf("marshalling resource: %w", err)
@@ -48,7 +59,7 @@ func (driver) Discover(ctx context.Context, req *pc.Request_Discover) (*pc.Respo
 		bindings = append(bindings, &pc.Response_Discovered_Binding{
 			RecommendedName:    topic,
 			ResourceConfigJson: resourceJson,
-			DocumentSchemaJson: minimalSchema,
+			DocumentSchemaJson: schema,
 			Key:                []string{"/_meta/id"},
 		})
 	}
@@ -65,45 +76,47 @@ type documentMetadata struct {
 	OrderingKey string            `json:"orderingKey,omitempty"`
 }
 
+var metaSchema = &jsonschema.Schema{
+	Type:     "object",
+	Required: []string{"id", "subscription"},
+	Extras: map[string]interface{}{
+		"properties": map[string]*jsonschema.Schema{
+			"id": {
+				Type:  "string",
+				Title: "ID of the message",
+			},
+			"topic": {
+				Type:  "string",
+				Title: "Topic the message was published to",
+			},
+			"subscription": {
+				Type:  "string",
+				Title: "Subscription the message was read from",
+			},
+			"publishTime": {
+				Type:   "string",
+				Format: "date-time",
+				Title:  "Time the message was published",
+			},
+			"attributes": {
+				Type:  "object",
+				Title: "The key-value pairs this message is labelled with",
+			},
+			"orderingKey": {
+				Type:  "string",
+				Title: "The ordering key used for the message",
+			},
+		},
+	},
+}
+
 func generateMinimalSchema() json.RawMessage {
 	var schema = &jsonschema.Schema{
 		Type:     "object",
 		Required: []string{"_meta"},
 		Extras: map[string]interface{}{
 			"properties": map[string]*jsonschema.Schema{
-				"_meta": {
-					Type:     "object",
-					Required: []string{"id", "subscription"},
-					Extras: map[string]interface{}{
-						"properties": map[string]*jsonschema.Schema{
-							"id": {
-								Type:  "string",
-								Title: "ID of the message",
-							},
-							"topic": {
-								Type:  "string",
-								Title: "Topic the message was published to",
-							},
-							"subscription": {
-								Type:  "string",
-								Title: "Subscription the message was read from",
-							},
-							"publishTime": {
-								Type:   "string",
-								Format: "date-time",
-								Title:  "Time the message was published",
-							},
-							"attributes": {
-								Type:  "object",
-								Title: "The key-value pairs this message is labelled with",
-							},
-							"orderingKey": {
-								Type:  "string",
-								Title: "The ordering key used for the message",
-							},
-						},
-					},
-				},
+				"_meta": metaSchema,
 			},
 			"x-infer-schema": true,
 		},
@@ -115,3 +128,22 @@ func generateMinimalSchema() json.RawMessage {
 	}
 	return json.RawMessage(bs)
 }
+
+// documentSchema returns the schema for documents of a topic. Topics with a
+// message format that carries its own schema use a schema derived from it, and
+// all others use the minimal schema with inference.
+func documentSchema(ctx context.Context, dec decoder.Decoder) (json.RawMessage, error) {
+	derived, err := dec.Schema(ctx)
+	if err != nil {
+		return nil, err
+	} else if derived == nil {
+		return minimalSchema, nil
+	}
+
+	properties, _ := derived["properties"].(map[string]any)
+	properties["_meta"] = metaSchema
+	required, _ := derived["required"].([]string)
+	derived["required"] = append(required, "_meta")
+
+	return json.Marshal(derived)
+}
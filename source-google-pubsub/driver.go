@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/estuary/connectors/go/decoder"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 	pc "github.com/estuary/flow/go/protocols/capture"
@@ -48,6 +49,10 @@ func (driver) Validate(ctx context.Context, req *pc.Request_Validate) (*pc.Respo
 			return nil, fmt.Errorf("parsing resource config: %w", err)
 		}
 
+		if _, err := cfg.decoder(&res, res.Topic); err != nil {
+			return nil, err
+		}
+
 		out = append(out, &pc.Response_Validated_Binding{
 			ResourcePath: []string{res.Topic},
 		})
@@ -165,9 +170,14 @@ func (driver) Pull(open *pc.Request_Open, stream *boilerplate.PullOutput) error
 			return fmt.Errorf("parsing resource config: %w", err)
 		}
 
+		dec, err := cfg.decoder(&res, res.Topic)
+		if err != nil {
+			return err
+		}
+
 		sub := subscriptionName(cfg.SubscriptionPrefix, res.Topic, open.Capture.Name.String())
 		group.Go(func() error {
-			return captureResource(groupCtx, e, client, res.Topic, sub, idx)
+			return captureResource(groupCtx, e, client, dec, res.Topic, sub, idx)
 		})
 	}
 
@@ -178,6 +188,7 @@ func captureResource(
 	ctx context.Context,
 	emitter *emitter,
 	client *pubsub.Client,
+	dec decoder.Decoder,
 	topic string,
 	subscription string,
 	binding int,
@@ -196,6 +207,7 @@ func captureResource(
 	return sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
 		rtAck, err := emitter.emit(ctx, emitMessage{
 			m:           m,
+			dec:         dec,
 			binding:     binding,
 			subcription: subscription,
 			topic:       topic,
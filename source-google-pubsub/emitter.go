@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/estuary/connectors/go/decoder"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 	"github.com/segmentio/encoding/json"
 )
@@ -20,6 +21,7 @@ type runtimeAck chan struct{}
 
 type emitMessage struct {
 	m           *pubsub.Message
+	dec         decoder.Decoder
 	binding     int
 	subcription string
 	topic       string
@@ -75,7 +77,7 @@ func (e *emitter) emit(ctx context.Context, m emitMessage) (runtimeAck, error) {
 	}
 
 	// Now output the message.
-	if doc, err := makeDoc(m); err != nil {
+	if doc, err := makeDoc(ctx, m); err != nil {
 		return nil, fmt.Errorf("making document: %w", err)
 	} else if err := e.stream.DocumentsAndCheckpoint(emptyCheckpoint, true, m.binding, doc); err != nil {
 		return nil, fmt.Errorf("emitting document: %w", err)
@@ -115,7 +117,7 @@ func (e *emitter) runtimeAckWorker(ctx context.Context) error {
 	}
 }
 
-func makeDoc(m emitMessage) (json.RawMessage, error) {
+func makeDoc(ctx context.Context, m emitMessage) (json.RawMessage, error) {
 	msg := m.m
 
 	meta := documentMetadata{
@@ -134,11 +136,9 @@ func makeDoc(m emitMessage) (json.RawMessage, error) {
 		meta.OrderingKey = msg.OrderingKey
 	}
 
-	var doc map[string]any
-	// TODO(whb): For now we only support messages encoded as JSON. Future
-	// support for other formats could be considered.
-	if err := json.Unmarshal(msg.Data, &doc); err != nil {
-		return nil, fmt.Errorf("could not unmarshal message data as a JSON object: %w", err)
+	doc, err := m.dec.Decode(ctx, msg.Data)
+	if err != nil {
+		return nil, err
 	}
 	doc["_meta"] = meta
 
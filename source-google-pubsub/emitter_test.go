@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/estuary/connectors/go/decoder"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 	pc "github.com/estuary/flow/go/protocols/capture"
 	"github.com/stretchr/testify/require"
@@ -33,6 +34,9 @@ func TestEmitter(t *testing.T) {
 		return emitter.runtimeAckWorker(groupCtx)
 	})
 
+	dec, err := decoder.New(decoder.FormatConfig{}, nil, "")
+	require.NoError(t, err)
+
 	// Emit some messages concurrently with many goroutines.
 	numMessages := 50_000
 	half := numMessages / 2
@@ -49,6 +53,7 @@ func TestEmitter(t *testing.T) {
 					ID:   strconv.Itoa(idx),
 					Data: []byte("{}"),
 				},
+				dec:         dec,
 				subcription: "sub",
 				topic:       "topic",
 			}
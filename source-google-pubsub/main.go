@@ -6,15 +6,18 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/estuary/connectors/go/decoder"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 )
 
 type config struct {
-	ProjectID          string `json:"projectId" jsonschema:"title=Project ID,description=Google Cloud Project ID that contains the PubSub topics." jsonschema_extras:"order=0"`
-	CredentialsJSON    string `json:"credentialsJson" jsonschema:"title=Service Account JSON,description=Google Cloud Service Account JSON credentials to use for authentication." jsonschema_extras:"secret=true,multiline=true,order=1"`
-	SubscriptionPrefix string `json:"subscriptionPrefix,omitempty" jsonschema:"title=Subscription Prefix,description=Prefix to prepend to the PubSub topics subscription names. Subscription names will be in the form of <prefix>_EstuaryFlow_<random string> if a prefix is provided vs. EstuaryFlow_<random string> if no prefix is provided." jsonschema_extras:"order=2"`
+	ProjectID          string                        `json:"projectId" jsonschema:"title=Project ID,description=Google Cloud Project ID that contains the PubSub topics." jsonschema_extras:"order=0"`
+	CredentialsJSON    string                        `json:"credentialsJson" jsonschema:"title=Service Account JSON,description=Google Cloud Service Account JSON credentials to use for authentication." jsonschema_extras:"secret=true,multiline=true,order=1"`
+	SubscriptionPrefix string                        `json:"subscriptionPrefix,omitempty" jsonschema:"title=Subscription Prefix,description=Prefix to prepend to the PubSub topics subscription names. Subscription names will be in the form of <prefix>_EstuaryFlow_<random string> if a prefix is provided vs. EstuaryFlow_<random string> if no prefix is provided." jsonschema_extras:"order=2"`
+	MessageFormat      *decoder.FormatConfig         `json:"messageFormat,omitempty" jsonschema:"title=Message Format,description=Default format of message data for all topics. Can be overridden for individual topics in their binding configuration." jsonschema_extras:"order=3"`
+	SchemaRegistry     *decoder.SchemaRegistryConfig `json:"schemaRegistry,omitempty" jsonschema:"title=Schema Registry,description=Connection details for a Confluent-compatible schema registry used to decode Avro messages." jsonschema_extras:"order=4"`
 }
 
 func (c *config) Validate() error {
@@ -24,11 +27,34 @@ func (c *config) Validate() error {
 		return fmt.Errorf("missing service account credentials JSON")
 	} else if !json.Valid([]byte(c.CredentialsJSON)) {
 		return fmt.Errorf("service account credentials must be valid JSON, and the provided credentials were not")
+	} else if c.MessageFormat != nil {
+		if err := c.MessageFormat.Validate(); err != nil {
+			return fmt.Errorf("invalid messageFormat: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// decoder creates the decoder for messages of a topic, using the message
+// format of the resource if it has one and the default for the endpoint
+// otherwise. A nil resource selects the endpoint default.
+func (c *config) decoder(res *resource, topic string) (decoder.Decoder, error) {
+	var format decoder.FormatConfig
+	if res != nil && res.MessageFormat != nil {
+		format = *res.MessageFormat
+	} else if c.MessageFormat != nil {
+		format = *c.MessageFormat
+	}
+
+	dec, err := decoder.New(format, c.SchemaRegistry, topic+"-value")
+	if err != nil {
+		return nil, fmt.Errorf("creating decoder for topic %q: %w", topic, err)
+	}
+
+	return dec, nil
+}
+
 func (c *config) client(ctx context.Context) (*pubsub.Client, error) {
 	creds, err := google.CredentialsFromJSON(ctx, []byte(c.CredentialsJSON), pubsub.ScopePubSub)
 	if err != nil {
@@ -48,12 +74,17 @@ func (c *config) client(ctx context.Context) (*pubsub.Client, error) {
 }
 
 type resource struct {
-	Topic string `json:"topic" jsonschema:"title=Topic,description=Name of the PubSub topic to subscribe to."`
+	Topic         string                `json:"topic" jsonschema:"title=Topic,description=Name of the PubSub topic to subscribe to."`
+	MessageFormat *decoder.FormatConfig `json:"messageFormat,omitempty" jsonschema:"title=Message Format,description=Format of message data for this topic. Uses the endpoint message format if not set."`
 }
 
 func (r *resource) Validate() error {
 	if r.Topic == "" {
 		return fmt.Errorf("missing topic")
+	} else if r.MessageFormat != nil {
+		if err := r.MessageFormat.Validate(); err != nil {
+			return fmt.Errorf("invalid messageFormat: %w", err)
+		}
 	}
 
 	return nil
@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/estuary/connectors/go/decoder"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 	"github.com/segmentio/encoding/json"
 	log "github.com/sirupsen/logrus"
@@ -21,6 +22,12 @@ type capture struct {
 	client *kinesis.Client
 	stream *boilerplate.PullOutput
 
+	// decoders are indexed by binding.
+	decoders []decoder.Decoder
+	// consumers are the ARNs of enhanced fan-out consumers keyed by the ARN of
+	// their stream, and are only set when reading with enhanced fan-out.
+	consumers map[string]string
+
 	updateState map[boilerplate.StateKey]map[string]*string
 }
 
@@ -244,36 +251,113 @@ func (c *capture) readShard(
 		}
 	}
 
-	readLog := ll
-	iteratorInput := &kinesis.GetShardIteratorInput{
-		StreamARN:         &stream.arn,
-		ShardId:           &shard.shardId,
-		ShardIteratorType: types.ShardIteratorTypeTrimHorizon,
+	pos, err := startingPosition(state[shard.shardId])
+	if err != nil {
+		return err
 	}
-	if seq := state[shard.shardId]; seq != nil {
-		iteratorInput.ShardIteratorType = types.ShardIteratorTypeAfterSequenceNumber
-		iteratorInput.StartingSequenceNumber = seq
-		readLog = readLog.WithField("startingSequenceNumber", *seq)
+
+	readLog := ll
+	if pos.sequence != nil {
+		readLog = readLog.WithField("startingCheckpoint", *state[shard.shardId])
 	}
 	readLog.Info("started reading kinesis shard")
 
+	var children []types.ChildShard
+	if consumerARN, ok := c.consumers[stream.arn]; ok {
+		children, err = c.subscribeShard(ctx, consumerARN, stream, stateKey, bindingIndex, shard, pos)
+	} else {
+		children, err = c.pollShard(ctx, stream, stateKey, bindingIndex, shard, pos)
+	}
+	if err != nil {
+		return err
+	}
+
+	ll.WithField("childShards", len(children)).Info("finished reading shard")
+	tracker.setFinished(shard.shardId)
+	for _, s := range children {
+		output <- childShardCompletionEvent{
+			child: s,
+		}
+	}
+
+	return nil
+}
+
+// shardPosition is the position in a shard to start reading from.
+type shardPosition struct {
+	iteratorType types.ShardIteratorType
+	sequence     *string
+
+	// resume is set if the capture last stopped partway through the user
+	// records of an aggregated record, which must then be read again to emit
+	// its remaining user records.
+	resume *shardCheckpoint
+}
+
+func startingPosition(cp *string) (shardPosition, error) {
+	if cp == nil {
+		return shardPosition{iteratorType: types.ShardIteratorTypeTrimHorizon}, nil
+	}
+
+	parsed, err := parseShardCheckpoint(*cp)
+	if err != nil {
+		return shardPosition{}, err
+	}
+
+	pos := shardPosition{
+		iteratorType: types.ShardIteratorTypeAfterSequenceNumber,
+		sequence:     &parsed.sequence,
+	}
+	if parsed.subSequence != nil {
+		pos.iteratorType = types.ShardIteratorTypeAtSequenceNumber
+		pos.resume = &parsed
+	}
+
+	return pos, nil
+}
+
+// invalidStartingSequence checks if reading from a position failed because its
+// sequence number is invalid, and resets the position to read from the
+// beginning of the shard if so.
+func (p *shardPosition) invalidStartingSequence(err error) bool {
+	var invalidArugmentErr *types.InvalidArgumentException
+	if !errors.As(err, &invalidArugmentErr) || p.sequence == nil {
+		return false
+	}
+
+	// This error occurs if a stream is deleted and re-created, or if the
+	// retention limit of a sequence number is exceeded. In either case the
+	// only thing to do is start reading the shard from the beginning, which is
+	// actually the "future" relative to an expired sequence.
+	log.WithError(invalidArugmentErr).Warn("starting sequence was invalid; will attempt to read shard from TRIM_HORIZON")
+	*p = shardPosition{iteratorType: types.ShardIteratorTypeTrimHorizon}
+
+	return true
+}
+
+// pollShard reads a shard with GetRecords until it is closed, and returns its
+// child shards.
+func (c *capture) pollShard(
+	ctx context.Context,
+	stream kinesisStream,
+	stateKey boilerplate.StateKey,
+	bindingIndex int,
+	shard shardToRead,
+	pos shardPosition,
+) ([]types.ChildShard, error) {
 	var iterator *string
 	for {
-		iterInit, err := c.client.GetShardIterator(ctx, iteratorInput)
+		iterInit, err := c.client.GetShardIterator(ctx, &kinesis.GetShardIteratorInput{
+			StreamARN:              &stream.arn,
+			ShardId:                &shard.shardId,
+			ShardIteratorType:      pos.iteratorType,
+			StartingSequenceNumber: pos.sequence,
+		})
 		if err != nil {
-			var invalidArugmentErr *types.InvalidArgumentException
-			if errors.As(err, &invalidArugmentErr) && iteratorInput.ShardIteratorType == types.ShardIteratorTypeAfterSequenceNumber {
-				// This error occurs if a stream is deleted and re-created, or
-				// if the retention limit of a sequence number is exceeded. In
-				// either case the only thing to do is start reading the shard
-				// from the beginning, which is actually the "future" relative
-				// to an expired sequence.
-				iteratorInput.ShardIteratorType = types.ShardIteratorTypeTrimHorizon
-				iteratorInput.StartingSequenceNumber = nil
-				log.WithError(invalidArugmentErr).Warn("starting sequence was invalid; will attempt to read shard from TRIM_HORIZON")
+			if pos.invalidStartingSequence(err) {
 				continue
 			}
-			return fmt.Errorf("getting shard iterator: %w", err)
+			return nil, fmt.Errorf("getting shard iterator: %w", err)
 		}
 
 		iterator = iterInit.ShardIterator
@@ -286,29 +370,25 @@ func (c *capture) readShard(
 			StreamARN:     &stream.arn,
 		})
 		if err != nil {
-			return fmt.Errorf("get records: %w", err)
+			return nil, fmt.Errorf("get records: %w", err)
 		}
 
-		if err := c.processRecords(res.Records, stream, stateKey, bindingIndex, shard); err != nil {
-			return err
+		if err := c.processRecords(ctx, res.Records, stream, stateKey, bindingIndex, shard, pos.resume); err != nil {
+			return nil, err
+		}
+		if len(res.Records) > 0 {
+			pos.resume = nil
 		}
 
 		if res.NextShardIterator == nil {
-			ll.WithField("childShards", len(res.ChildShards)).Info("finished reading shard")
-			tracker.setFinished(shard.shardId)
-			for _, s := range res.ChildShards {
-				output <- childShardCompletionEvent{
-					child: s,
-				}
-			}
-			return nil
+			return res.ChildShards, nil
 		}
 		iterator = res.NextShardIterator
 
 		if *res.MillisBehindLatest == 0 && len(res.Records) == 0 {
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return nil, ctx.Err()
 			case <-time.After(1 * time.Second):
 				// Small delay to avoid hot-looping on a shard with no new data.
 			}
@@ -316,38 +396,78 @@ func (c *capture) readShard(
 	}
 }
 
+// maxDocsPerCheckpoint bounds the number of documents emitted between
+// checkpoints. This matches the maximum number of records returned by a single
+// GetRecords call, but aggregated records may contain many more user records
+// than that.
+const maxDocsPerCheckpoint = 10_000
+
 func (c *capture) processRecords(
+	ctx context.Context,
 	records []types.Record,
 	stream kinesisStream,
 	stateKey boilerplate.StateKey,
 	bindingIndex int,
 	shard shardToRead,
+	resume *shardCheckpoint,
 ) error {
 	if len(records) == 0 {
 		return nil
 	}
 
-	var lastSequence string
-	for _, r := range records {
-		lastSequence = *r.SequenceNumber
+	var sinceCheckpoint int
+	for idx, r := range records {
+		userRecords, err := deaggregate(r.Data, *r.PartitionKey)
+		if err != nil {
+			return fmt.Errorf("de-aggregating record %s for stream %s: %w", *r.SequenceNumber, stream.name, err)
+		}
+
+		start := 0
+		if idx == 0 && resume != nil && resume.sequence == *r.SequenceNumber {
+			start = *resume.subSequence + 1
+		}
+
+		for subSequence := start; subSequence < len(userRecords); subSequence++ {
+			userRecord := userRecords[subSequence]
+
+			doc, err := c.decoders[bindingIndex].Decode(ctx, userRecord.data)
+			if err != nil {
+				return fmt.Errorf("decoding record for stream %s: %w", stream.name, err)
+			}
 
-		doc := map[string]any{
-			metaProperty: map[string]any{
-				sequenceNumber: *r.SequenceNumber,
-				partitionKey:   *r.PartitionKey,
+			meta := map[string]any{
+				sequenceNumber:    *r.SequenceNumber,
+				subSequenceNumber: subSequence,
+				partitionKey:      userRecord.partitionKey,
 				sourceProperty: map[string]any{
 					streamSource: stream.name,
 					shardSource:  shard.shardId,
 				},
-			},
-		}
+			}
+			if userRecord.explicitHashKey != nil {
+				meta[explicitHashKey] = *userRecord.explicitHashKey
+			}
+			doc[metaProperty] = meta
 
-		if err := json.Unmarshal(r.Data, &doc); err != nil {
-			return fmt.Errorf("unmarshalling record for stream %s: %w", stream.name, err)
-		} else if docBytes, err := json.Marshal(doc); err != nil {
-			return err
-		} else if err := c.emitDoc(docBytes, stateKey, bindingIndex, shard.shardId, lastSequence); err != nil {
-			return err
+			// The checkpoint for the last user record of an aggregated record
+			// is the record itself, so that reading resumes after it.
+			cp := shardCheckpoint{sequence: *r.SequenceNumber}
+			if subSequence < len(userRecords)-1 {
+				cp.subSequence = &subSequence
+			}
+
+			if docBytes, err := json.Marshal(doc); err != nil {
+				return err
+			} else if err := c.emitDoc(docBytes, stateKey, bindingIndex, shard.shardId, cp.String()); err != nil {
+				return err
+			}
+
+			if sinceCheckpoint++; sinceCheckpoint == maxDocsPerCheckpoint {
+				if err := c.emitState(); err != nil {
+					return err
+				}
+				sinceCheckpoint = 0
+			}
 		}
 	}
 
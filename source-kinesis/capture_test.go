@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/bradleyjkemp/cupaloy"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	st "github.com/estuary/connectors/source-boilerplate/testing"
 	pf "github.com/estuary/flow/go/protocols/flow"
 	log "github.com/sirupsen/logrus"
@@ -25,9 +26,9 @@ func testConfig(t *testing.T) Config {
 	}
 
 	return Config{
-		Region:             "local",
-		AWSAccessKeyID:     "x",
-		AWSSecretAccessKey: "x",
+		Region:         "local",
+		AccessKeys:     awsauth.AccessKeys{AWSAccessKeyID: "x", AWSSecretAccessKey: "x"},
+		SubSequenceKey: true,
 		Advanced: advancedConfig{
 			Endpoint: "http://localhost:4566",
 		},
@@ -70,9 +71,24 @@ func TestDiscover(t *testing.T) {
 }
 
 func TestCapture(t *testing.T) {
-	ctx := context.Background()
+	cupaloy.SnapshotT(t, runCapture(t, testConfig(t)))
+}
 
+func TestCaptureEnhancedFanOut(t *testing.T) {
 	conf := testConfig(t)
+	conf.Advanced.EnhancedFanOut = true
+
+	// Reading with enhanced fan-out must produce exactly the same results as
+	// reading by polling, so the snapshot of TestCapture is used.
+	want, err := os.ReadFile(".snapshots/TestCapture")
+	require.NoError(t, err)
+	require.Equal(t, string(want), runCapture(t, conf)+"\n")
+}
+
+func runCapture(t *testing.T, conf Config) string {
+	t.Helper()
+	ctx := context.Background()
+
 	client, err := connect(ctx, &conf)
 	require.NoError(t, err)
 
@@ -160,7 +176,7 @@ func TestCapture(t *testing.T) {
 	addData(t, 10, 20)
 	advanceCapture(t, &capture)
 
-	cupaloy.SnapshotT(t, capture.Summary())
+	return capture.Summary()
 }
 
 func advanceCapture(t testing.TB, cs *st.CaptureSpec) {
@@ -9,39 +9,66 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
+	"github.com/estuary/connectors/go/decoder"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 )
 
 // Config represents the fully merged endpoint configuration for Kinesis.
 type Config struct {
-	Region             string `json:"region" jsonschema:"title=AWS Region,description=The name of the AWS region where the Kinesis stream is located"`
-	AWSAccessKeyID     string `json:"awsAccessKeyId" jsonschema:"title=AWS Access Key ID,description=Part of the AWS credentials that will be used to connect to Kinesis"`
-	AWSSecretAccessKey string `json:"awsSecretAccessKey" jsonschema:"title=AWS Secret Access Key,description=Part of the AWS credentials that will be used to connect to Kinesis" jsonschema_extras:"secret=true"`
+	Region      string                    `json:"region" jsonschema:"title=AWS Region,description=The name of the AWS region where the Kinesis stream is located"`
+	Credentials *awsauth.CredentialConfig `json:"credentials,omitempty" jsonschema:"title=Authentication"`
+	awsauth.AccessKeys
+
+	MessageFormat  *decoder.FormatConfig         `json:"messageFormat,omitempty" jsonschema:"title=Message Format,description=Default format of records in all streams. Can be overridden for individual streams in their binding configuration."`
+	SchemaRegistry *decoder.SchemaRegistryConfig `json:"schemaRegistry,omitempty" jsonschema:"title=Schema Registry,description=Connection details for a Confluent-compatible schema registry used to decode Avro records."`
+	SubSequenceKey bool                          `json:"subSequenceKey,omitempty" jsonschema:"title=Key Aggregated Records by Sub-Sequence Number,description=Include the sub-sequence number of records in the keys of discovered collections so that each user record of a record aggregated by the Kinesis Producer Library has a distinct key. Changing this for an existing capture changes the keys of its collections when they are rediscovered.,default=true"`
 
 	Advanced advancedConfig `json:"advanced,omitempty"`
 }
 
 type advancedConfig struct {
-	Endpoint string `json:"endpoint,omitempty" jsonschema:"title=AWS Endpoint,description=The AWS endpoint URI to connect to (useful if you're capturing from a kinesis-compatible API that isn't provided by AWS)"`
+	Endpoint       string `json:"endpoint,omitempty" jsonschema:"title=AWS Endpoint,description=The AWS endpoint URI to connect to (useful if you're capturing from a kinesis-compatible API that isn't provided by AWS)"`
+	EnhancedFanOut bool   `json:"enhancedFanOut,omitempty" jsonschema:"title=Enhanced Fan-Out,description=Read streams using enhanced fan-out with a dedicated stream consumer registered for this capture. Enhanced fan-out consumers have their own read throughput for each shard instead of sharing it with other consumers of the stream. Additional AWS charges apply."`
 }
 
 func (c *Config) Validate() error {
 	if c.Region == "" {
 		return fmt.Errorf("missing region")
 	}
-	if c.AWSAccessKeyID == "" {
-		return fmt.Errorf("missing awsAccessKeyId")
+	if err := awsauth.ValidateCredentials(c.Credentials, c.AWSAccessKeyID, c.AWSSecretAccessKey); err != nil {
+		return err
 	}
-	if c.AWSSecretAccessKey == "" {
-		return fmt.Errorf("missing awsSecretAccessKey")
+	if c.MessageFormat != nil {
+		if err := c.MessageFormat.Validate(); err != nil {
+			return fmt.Errorf("invalid messageFormat: %w", err)
+		}
 	}
 	return nil
 }
 
+// newDecoder creates the decoder for records of a stream, using the message
+// format of the resource if it has one and the default for the endpoint
+// otherwise. A nil resource selects the endpoint default.
+func (c *Config) newDecoder(res *resource, stream string) (decoder.Decoder, error) {
+	var format decoder.FormatConfig
+	if res != nil && res.MessageFormat != nil {
+		format = *res.MessageFormat
+	} else if c.MessageFormat != nil {
+		format = *c.MessageFormat
+	}
+
+	dec, err := decoder.New(format, c.SchemaRegistry, stream+"-value")
+	if err != nil {
+		return nil, fmt.Errorf("creating decoder for stream %s: %w", stream, err)
+	}
+
+	return dec, nil
+}
+
 func connect(ctx context.Context, cfg *Config) (*kinesis.Client, error) {
 	var err = cfg.Validate()
 	if err != nil {
@@ -49,9 +76,6 @@ func connect(ctx context.Context, cfg *Config) (*kinesis.Client, error) {
 	}
 
 	opts := []func(*awsConfig.LoadOptions) error{
-		awsConfig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, ""),
-		),
 		awsConfig.WithRegion(cfg.Region),
 		awsConfig.WithRetryer(func() aws.Retryer {
 			// Bump up the number of retry maximum attempts from the default of 3. The maximum retry
@@ -71,7 +95,8 @@ func connect(ctx context.Context, cfg *Config) (*kinesis.Client, error) {
 		opts = append(opts, awsConfig.WithEndpointResolverWithOptions(customResolver))
 	}
 
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, opts...)
+	creds := awsauth.ResolveCredentials(cfg.Credentials, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey)
+	awsCfg, err := creds.AWSConfig(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating aws config: %w", err)
 	}
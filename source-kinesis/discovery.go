@@ -1,64 +1,111 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
+	"github.com/estuary/connectors/go/decoder"
+
 	pc "github.com/estuary/flow/go/protocols/capture"
 	"github.com/invopop/jsonschema"
 )
 
 const (
-	metaProperty   = "_meta"
-	sourceProperty = "source"
-	sequenceNumber = "sequence_number"
-	partitionKey   = "partition_key"
-	streamSource   = "stream"
-	shardSource    = "shard"
+	metaProperty      = "_meta"
+	sourceProperty    = "source"
+	sequenceNumber    = "sequence_number"
+	subSequenceNumber = "sub_sequence_number"
+	partitionKey      = "partition_key"
+	explicitHashKey   = "explicit_hash_key"
+	streamSource      = "stream"
+	shardSource       = "shard"
 )
 
-// Provides a default schema to use for collections.
-var minimalSchema = &jsonschema.Schema{
-	Type:                 "object",
-	Required:             []string{metaProperty},
-	AdditionalProperties: nil,
-	Extras: map[string]any{
-		"x-infer-schema": true,
-
-		"properties": map[string]*jsonschema.Schema{
-			metaProperty: {
-				Type:     "object",
-				Required: []string{sequenceNumber, partitionKey},
-				Extras: map[string]any{
-					"properties": map[string]*jsonschema.Schema{
-						sequenceNumber: {Type: "string"},
-						partitionKey:   {Type: "string"},
-						sourceProperty: {
-							Type:     "object",
-							Required: []string{shardSource, streamSource},
-							Extras: map[string]any{
-								"properties": map[string]*jsonschema.Schema{
-									streamSource: {Type: "string"},
-									shardSource:  {Type: "string"},
-								},
-							},
+// metaSchema returns the schema of the `_meta` property of documents. The
+// sub-sequence number is only required if it is part of the collection key.
+func metaSchema(subSequenceKey bool) *jsonschema.Schema {
+	var required = []string{sequenceNumber, partitionKey}
+	if subSequenceKey {
+		required = []string{sequenceNumber, subSequenceNumber, partitionKey}
+	}
+
+	return &jsonschema.Schema{
+		Type:     "object",
+		Required: required,
+		Extras: map[string]any{
+			"properties": map[string]*jsonschema.Schema{
+				sequenceNumber: {Type: "string"},
+				subSequenceNumber: {
+					Type:        "integer",
+					Description: "Index of the user record within a record aggregated by the Kinesis Producer Library, or 0 for records that are not aggregated.",
+				},
+				partitionKey:    {Type: "string"},
+				explicitHashKey: {Type: "string"},
+				sourceProperty: {
+					Type:     "object",
+					Required: []string{shardSource, streamSource},
+					Extras: map[string]any{
+						"properties": map[string]*jsonschema.Schema{
+							streamSource: {Type: "string"},
+							shardSource:  {Type: "string"},
 						},
 					},
 				},
 			},
 		},
-	},
+	}
 }
 
-func discoverStreams(streams []kinesisStream) ([]*pc.Response_Discovered_Binding, error) {
-	var out = make([]*pc.Response_Discovered_Binding, 0, len(streams))
+// minimalSchema provides a default schema to use for collections.
+func minimalSchema(subSequenceKey bool) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:                 "object",
+		Required:             []string{metaProperty},
+		AdditionalProperties: nil,
+		Extras: map[string]any{
+			"x-infer-schema": true,
+
+			"properties": map[string]*jsonschema.Schema{
+				metaProperty: metaSchema(subSequenceKey),
+			},
+		},
+	}
+}
 
-	bs, err := json.Marshal(minimalSchema)
+// documentSchema returns the schema for documents of a stream. Streams with a
+// message format that carries its own schema use a schema derived from it,
+// and all others use the minimal schema with inference.
+func documentSchema(ctx context.Context, dec decoder.Decoder, subSequenceKey bool) (json.RawMessage, error) {
+	derived, err := dec.Schema(ctx)
 	if err != nil {
-		panic(fmt.Errorf("error generating schema: %v", err))
+		return nil, err
+	} else if derived == nil {
+		return json.Marshal(minimalSchema(subSequenceKey))
 	}
 
+	properties, _ := derived["properties"].(map[string]any)
+	properties[metaProperty] = metaSchema(subSequenceKey)
+	required, _ := derived["required"].([]string)
+	derived["required"] = append(required, metaProperty)
+
+	return json.Marshal(derived)
+}
+
+func discoverStreams(ctx context.Context, cfg *Config, streams []kinesisStream) ([]*pc.Response_Discovered_Binding, error) {
+	var out = make([]*pc.Response_Discovered_Binding, 0, len(streams))
+
 	for _, s := range streams {
+		dec, err := cfg.newDecoder(nil, s.name)
+		if err != nil {
+			return nil, err
+		}
+
+		bs, err := documentSchema(ctx, dec, cfg.SubSequenceKey)
+		if err != nil {
+			return nil, fmt.Errorf("generating schema for stream %s: %w", s.name, err)
+		}
+
 		resourceJSON, err := json.Marshal(resource{Stream: s.name})
 		if err != nil {
 			return nil, fmt.Errorf("serializing resource json: %w", err)
@@ -67,9 +114,28 @@ func discoverStreams(streams []kinesisStream) ([]*pc.Response_Discovered_Binding
 			RecommendedName:    s.name,
 			DocumentSchemaJson: bs,
 			ResourceConfigJson: resourceJSON,
-			Key:                []string{fmt.Sprintf("/%s/%s", metaProperty, sequenceNumber), fmt.Sprintf("/%s/%s", metaProperty, partitionKey)},
+			Key:                discoveredKey(cfg.SubSequenceKey),
 		})
 	}
 
 	return out, nil
 }
+
+// discoveredKey returns the collection key of discovered streams. The
+// sub-sequence number distinguishes the user records of aggregated records,
+// but is only part of the key if enabled so that rediscovering the streams of
+// existing captures doesn't change the keys of their collections.
+func discoveredKey(subSequenceKey bool) []string {
+	if subSequenceKey {
+		return []string{
+			fmt.Sprintf("/%s/%s", metaProperty, sequenceNumber),
+			fmt.Sprintf("/%s/%s", metaProperty, subSequenceNumber),
+			fmt.Sprintf("/%s/%s", metaProperty, partitionKey),
+		}
+	}
+
+	return []string{
+		fmt.Sprintf("/%s/%s", metaProperty, sequenceNumber),
+		fmt.Sprintf("/%s/%s", metaProperty, partitionKey),
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	boilerplate "github.com/estuary/connectors/source-boilerplate"
+	log "github.com/sirupsen/logrus"
+)
+
+// subscriptionDuration is how long a SubscribeToShard subscription lasts
+// before Kinesis closes it, after which the shard must be subscribed to again.
+// Subscriptions are also ended by the connector after this long in case they
+// aren't closed by the server.
+const subscriptionDuration = 5 * time.Minute
+
+// consumerName creates a deterministic name for the enhanced fan-out stream
+// consumer of a capture. Consumers are registered per-stream, so the name only
+// needs to be unique across captures of the same stream.
+func consumerName(captureName string) string {
+	// Use a digest of the capture name, since there are restrictions on the
+	// length of consumer names and the characters they may contain.
+	hsh := md5.New()
+	hsh.Write([]byte(captureName))
+
+	return fmt.Sprintf("EstuaryFlow_%s", hex.EncodeToString(hsh.Sum(nil)))
+}
+
+// ensureConsumer registers the named enhanced fan-out consumer for a stream if
+// it doesn't already exist. It returns the ARN of the consumer, and whether it
+// was newly registered.
+func ensureConsumer(ctx context.Context, client *kinesis.Client, streamARN string, name string) (string, bool, error) {
+	desc, err := client.DescribeStreamConsumer(ctx, &kinesis.DescribeStreamConsumerInput{
+		StreamARN:    &streamARN,
+		ConsumerName: &name,
+	})
+	if err == nil {
+		return *desc.ConsumerDescription.ConsumerARN, false, nil
+	}
+
+	var notFoundErr *types.ResourceNotFoundException
+	if !errors.As(err, &notFoundErr) {
+		return "", false, fmt.Errorf("describing stream consumer %q: %w", name, err)
+	}
+
+	res, err := client.RegisterStreamConsumer(ctx, &kinesis.RegisterStreamConsumerInput{
+		StreamARN:    &streamARN,
+		ConsumerName: &name,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("registering stream consumer %q: %w", name, err)
+	}
+
+	return *res.Consumer.ConsumerARN, true, nil
+}
+
+// deregisterConsumer deregisters the named enhanced fan-out consumer of a
+// stream. It returns whether the consumer existed.
+func deregisterConsumer(ctx context.Context, client *kinesis.Client, streamARN string, name string) (bool, error) {
+	_, err := client.DeregisterStreamConsumer(ctx, &kinesis.DeregisterStreamConsumerInput{
+		StreamARN:    &streamARN,
+		ConsumerName: &name,
+	})
+
+	var notFoundErr *types.ResourceNotFoundException
+	if errors.As(err, &notFoundErr) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("deregistering stream consumer %q: %w", name, err)
+	}
+
+	return true, nil
+}
+
+// awaitConsumerActive waits for a newly registered consumer to be ready to
+// subscribe to shards.
+func awaitConsumerActive(ctx context.Context, client *kinesis.Client, consumerARN string) error {
+	for {
+		desc, err := client.DescribeStreamConsumer(ctx, &kinesis.DescribeStreamConsumerInput{
+			ConsumerARN: &consumerARN,
+		})
+		if err != nil {
+			return fmt.Errorf("describing stream consumer %q: %w", consumerARN, err)
+		}
+
+		switch status := desc.ConsumerDescription.ConsumerStatus; status {
+		case types.ConsumerStatusActive:
+			return nil
+		case types.ConsumerStatusCreating:
+			log.WithField("consumer", consumerARN).Info("waiting for stream consumer to become active")
+		default:
+			return fmt.Errorf("stream consumer %q has status %s", consumerARN, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// subscribeShard reads a shard with enhanced fan-out until it is closed, and
+// returns its child shards. Subscriptions only last for 5 minutes, so the shard
+// is re-subscribed to from the last position read whenever one ends.
+func (c *capture) subscribeShard(
+	ctx context.Context,
+	consumerARN string,
+	stream kinesisStream,
+	stateKey boilerplate.StateKey,
+	bindingIndex int,
+	shard shardToRead,
+	pos shardPosition,
+) ([]types.ChildShard, error) {
+	for {
+		children, finished, err := c.readSubscription(ctx, consumerARN, stream, stateKey, bindingIndex, shard, &pos)
+		if err != nil {
+			var inUseErr *types.ResourceInUseException
+			if pos.invalidStartingSequence(err) {
+				continue
+			} else if errors.As(err, &inUseErr) {
+				// Only one subscription to a shard may be active for a consumer
+				// at a time, and a prior one may not have been cleaned up yet
+				// if the connector recently restarted.
+				log.WithError(err).WithField("kinesisShard", shard.shardId).Info("shard subscription is in use; will retry")
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(5 * time.Second):
+					continue
+				}
+			}
+			return nil, err
+		} else if finished {
+			return children, nil
+		}
+	}
+}
+
+// readSubscription reads events from a single subscription to a shard, and
+// advances the position as they are processed. It returns when the
+// subscription ends, and reports if that was because the shard is closed.
+func (c *capture) readSubscription(
+	ctx context.Context,
+	consumerARN string,
+	stream kinesisStream,
+	stateKey boilerplate.StateKey,
+	bindingIndex int,
+	shard shardToRead,
+	pos *shardPosition,
+) ([]types.ChildShard, bool, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res, err := c.client.SubscribeToShard(subCtx, &kinesis.SubscribeToShardInput{
+		ConsumerARN: &consumerARN,
+		ShardId:     &shard.shardId,
+		StartingPosition: &types.StartingPosition{
+			Type:           pos.iteratorType,
+			SequenceNumber: pos.sequence,
+		},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("subscribing to shard: %w", err)
+	}
+
+	events := res.GetStream()
+	defer events.Close()
+
+	timer := time.NewTimer(subscriptionDuration)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-timer.C:
+			return nil, false, nil
+		case ev, ok := <-events.Events():
+			if !ok {
+				if err := events.Err(); err != nil {
+					return nil, false, fmt.Errorf("reading shard subscription: %w", err)
+				}
+				return nil, false, nil
+			}
+
+			event, ok := ev.(*types.SubscribeToShardEventStreamMemberSubscribeToShardEvent)
+			if !ok {
+				return nil, false, fmt.Errorf("unexpected shard subscription event type %T", ev)
+			}
+
+			if err := c.processRecords(ctx, event.Value.Records, stream, stateKey, bindingIndex, shard, pos.resume); err != nil {
+				return nil, false, err
+			}
+			if len(event.Value.Records) > 0 {
+				pos.resume = nil
+			}
+
+			if event.Value.ContinuationSequenceNumber == nil {
+				// The shard is closed, and all of its records have been read.
+				return event.Value.ChildShards, true, nil
+			}
+			pos.iteratorType = types.ShardIteratorTypeAfterSequenceNumber
+			pos.sequence = event.Value.ContinuationSequenceNumber
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// kplMagic is the prefix of Kinesis records that were aggregated by the Kinesis
+// Producer Library (KPL). It is followed by a protobuf encoded AggregatedRecord
+// message, and the record ends with the MD5 digest of that message.
+//
+// Ref: https://github.com/awslabs/amazon-kinesis-producer/blob/master/aggregation-format.md
+var kplMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+// kplUserRecord is a single user record within an aggregated Kinesis record.
+type kplUserRecord struct {
+	partitionKey    string
+	explicitHashKey *string
+	data            []byte
+}
+
+// deaggregate extracts the user records from a KPL aggregated record. Records
+// which are not aggregated are returned as a single user record with the
+// partition key of the Kinesis record. Like the Kinesis Client Library, a
+// record that has the KPL magic prefix but a mismatched digest is treated as
+// not aggregated.
+func deaggregate(data []byte, partitionKey string) ([]kplUserRecord, error) {
+	notAggregated := []kplUserRecord{{partitionKey: partitionKey, data: data}}
+
+	if len(data) < len(kplMagic)+md5.Size || !bytes.Equal(data[:len(kplMagic)], kplMagic) {
+		return notAggregated, nil
+	}
+
+	message := data[len(kplMagic) : len(data)-md5.Size]
+	digest := md5.Sum(message)
+	if !bytes.Equal(digest[:], data[len(data)-md5.Size:]) {
+		return notAggregated, nil
+	}
+
+	return parseAggregatedRecord(message)
+}
+
+// parseAggregatedRecord parses an AggregatedRecord message:
+//
+//	message AggregatedRecord {
+//	  repeated string partition_key_table     = 1;
+//	  repeated string explicit_hash_key_table = 2;
+//	  repeated Record records                 = 3;
+//	}
+func parseAggregatedRecord(b []byte) ([]kplUserRecord, error) {
+	var partitionKeys, explicitHashKeys []string
+	var records []kplRecord
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("reading aggregated record field tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if typ != protowire.BytesType || num < 1 || num > 3 {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return nil, fmt.Errorf("skipping aggregated record field %d: %w", num, protowire.ParseError(n))
+			}
+			b = b[n:]
+			continue
+		}
+
+		val, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, fmt.Errorf("reading aggregated record field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		switch num {
+		case 1:
+			partitionKeys = append(partitionKeys, string(val))
+		case 2:
+			explicitHashKeys = append(explicitHashKeys, string(val))
+		case 3:
+			r, err := parseRecord(val)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+	}
+
+	out := make([]kplUserRecord, 0, len(records))
+	for idx, r := range records {
+		if r.partitionKeyIndex >= uint64(len(partitionKeys)) {
+			return nil, fmt.Errorf("user record %d has invalid partition key index %d", idx, r.partitionKeyIndex)
+		}
+
+		userRecord := kplUserRecord{
+			partitionKey: partitionKeys[r.partitionKeyIndex],
+			data:         r.data,
+		}
+
+		if r.explicitHashKeyIndex != nil {
+			if *r.explicitHashKeyIndex >= uint64(len(explicitHashKeys)) {
+				return nil, fmt.Errorf("user record %d has invalid explicit hash key index %d", idx, *r.explicitHashKeyIndex)
+			}
+			userRecord.explicitHashKey = &explicitHashKeys[*r.explicitHashKeyIndex]
+		}
+
+		out = append(out, userRecord)
+	}
+
+	return out, nil
+}
+
+type kplRecord struct {
+	partitionKeyIndex    uint64
+	explicitHashKeyIndex *uint64
+	data                 []byte
+}
+
+// parseRecord parses a Record message. Tags are not used and are skipped.
+//
+//	message Record {
+//	  required uint64 partition_key_index     = 1;
+//	  optional uint64 explicit_hash_key_index = 2;
+//	  required bytes  data                    = 3;
+//	  repeated Tag    tags                    = 4;
+//	}
+func parseRecord(b []byte) (kplRecord, error) {
+	var out kplRecord
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return out, fmt.Errorf("reading user record field tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		switch {
+		case (num == 1 || num == 2) && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return out, fmt.Errorf("reading user record field %d: %w", num, protowire.ParseError(n))
+			}
+			b = b[n:]
+
+			if num == 1 {
+				out.partitionKeyIndex = v
+			} else {
+				out.explicitHashKeyIndex = &v
+			}
+		case num == 3 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return out, fmt.Errorf("reading user record data: %w", protowire.ParseError(n))
+			}
+			b = b[n:]
+			out.data = v
+		default:
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return out, fmt.Errorf("skipping user record field %d: %w", num, protowire.ParseError(n))
+			}
+			b = b[n:]
+		}
+	}
+
+	return out, nil
+}
+
+// shardCheckpoint is the position of the last emitted record of a shard. If
+// the last emitted record was an aggregated record and not all of its user
+// records have been emitted, subSequence is the index of the last user record
+// that was.
+type shardCheckpoint struct {
+	sequence    string
+	subSequence *int
+}
+
+// parseShardCheckpoint parses a checkpoint serialized by String. Checkpoints of
+// complete records are only a sequence number, which is compatible with
+// checkpoints from before aggregated records were supported.
+func parseShardCheckpoint(s string) (shardCheckpoint, error) {
+	seq, sub, found := strings.Cut(s, ":")
+	if !found {
+		return shardCheckpoint{sequence: seq}, nil
+	}
+
+	subSequence, err := strconv.Atoi(sub)
+	if err != nil {
+		return shardCheckpoint{}, fmt.Errorf("invalid sub-sequence number in shard checkpoint %q: %w", s, err)
+	}
+
+	return shardCheckpoint{sequence: seq, subSequence: &subSequence}, nil
+}
+
+func (c shardCheckpoint) String() string {
+	if c.subSequence == nil {
+		return c.sequence
+	}
+	return fmt.Sprintf("%s:%d", c.sequence, *c.subSequence)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/md5"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// makeAggregatedRecord encodes user records in the KPL aggregated record
+// format. Explicit hash keys are only included for user records that have one.
+func makeAggregatedRecord(t *testing.T, records []kplUserRecord) []byte {
+	t.Helper()
+
+	var msg []byte
+	keyIndex := make(map[string]uint64)
+	var hashKeys []string
+
+	for _, r := range records {
+		if _, ok := keyIndex[r.partitionKey]; !ok {
+			keyIndex[r.partitionKey] = uint64(len(keyIndex))
+			msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+			msg = protowire.AppendString(msg, r.partitionKey)
+		}
+		if r.explicitHashKey != nil {
+			hashKeys = append(hashKeys, *r.explicitHashKey)
+			msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+			msg = protowire.AppendString(msg, *r.explicitHashKey)
+		}
+	}
+
+	var hashKeyIdx uint64
+	for _, r := range records {
+		var rec []byte
+		rec = protowire.AppendTag(rec, 1, protowire.VarintType)
+		rec = protowire.AppendVarint(rec, keyIndex[r.partitionKey])
+		if r.explicitHashKey != nil {
+			rec = protowire.AppendTag(rec, 2, protowire.VarintType)
+			rec = protowire.AppendVarint(rec, hashKeyIdx)
+			hashKeyIdx++
+		}
+		rec = protowire.AppendTag(rec, 3, protowire.BytesType)
+		rec = protowire.AppendBytes(rec, r.data)
+		// A tag, which should be ignored.
+		rec = protowire.AppendTag(rec, 4, protowire.BytesType)
+		rec = protowire.AppendBytes(rec, []byte{0x0a, 0x01, 'k'})
+
+		msg = protowire.AppendTag(msg, 3, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, rec)
+	}
+
+	digest := md5.Sum(msg)
+	out := append([]byte{}, kplMagic...)
+	out = append(out, msg...)
+	return append(out, digest[:]...)
+}
+
+func TestDeaggregate(t *testing.T) {
+	hashKey := "12345"
+	want := []kplUserRecord{
+		{partitionKey: "a", data: []byte(`{"i":1}`)},
+		{partitionKey: "b", data: []byte(`{"i":2}`), explicitHashKey: &hashKey},
+		{partitionKey: "a", data: []byte(`{"i":3}`)},
+	}
+
+	aggregated := makeAggregatedRecord(t, want)
+	got, err := deaggregate(aggregated, "outer")
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// Records that are not aggregated are returned as-is.
+	got, err = deaggregate([]byte(`{"i":1}`), "outer")
+	require.NoError(t, err)
+	require.Equal(t, []kplUserRecord{{partitionKey: "outer", data: []byte(`{"i":1}`)}}, got)
+
+	// As are records with the magic prefix but a mismatched digest.
+	corrupted := append([]byte{}, aggregated...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	got, err = deaggregate(corrupted, "outer")
+	require.NoError(t, err)
+	require.Equal(t, []kplUserRecord{{partitionKey: "outer", data: corrupted}}, got)
+}
+
+func TestShardCheckpoint(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want shardCheckpoint
+	}{
+		{in: "49590338271490256608559692538361571095921575989136588898", want: shardCheckpoint{sequence: "49590338271490256608559692538361571095921575989136588898"}},
+		{in: "49590338271490256608559692538361571095921575989136588898:4", want: shardCheckpoint{sequence: "49590338271490256608559692538361571095921575989136588898", subSequence: ptr(4)}},
+	} {
+		got, err := parseShardCheckpoint(tt.in)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, got)
+		require.Equal(t, tt.in, got.String())
+	}
+
+	_, err := parseShardCheckpoint("123:x")
+	require.Error(t, err)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -7,6 +7,7 @@ import (
 	"maps"
 	"slices"
 
+	"github.com/estuary/connectors/go/decoder"
 	schemagen "github.com/estuary/connectors/go/schema-gen"
 	boilerplate "github.com/estuary/connectors/source-boilerplate"
 	pc "github.com/estuary/flow/go/protocols/capture"
@@ -17,13 +18,19 @@ import (
 type driver struct{}
 
 type resource struct {
-	Stream string `json:"stream" jsonschema:"title=Stream Name"`
+	Stream        string                `json:"stream" jsonschema:"title=Stream Name"`
+	MessageFormat *decoder.FormatConfig `json:"messageFormat,omitempty" jsonschema:"title=Message Format,description=Format of records in this stream. Uses the endpoint message format if not set."`
 }
 
 func (r resource) Validate() error {
 	if r.Stream == "" {
 		return fmt.Errorf("stream is required")
 	}
+	if r.MessageFormat != nil {
+		if err := r.MessageFormat.Validate(); err != nil {
+			return fmt.Errorf("invalid messageFormat: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -73,6 +80,10 @@ func (d *driver) Validate(ctx context.Context, req *pc.Request_Validate) (*pc.Re
 			return nil, fmt.Errorf("stream %s does not exist", res.Stream)
 		}
 
+		if _, err := config.newDecoder(&res, res.Stream); err != nil {
+			return nil, err
+		}
+
 		bindings = append(bindings, &pc.Response_Validated_Binding{
 			ResourcePath: []string{res.Stream},
 		})
@@ -97,7 +108,7 @@ func (d *driver) Discover(ctx context.Context, req *pc.Request_Discover) (*pc.Re
 		return nil, fmt.Errorf("listing streams: %w", err)
 	}
 
-	bindings, err := discoverStreams(streams)
+	bindings, err := discoverStreams(ctx, &config, streams)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +146,8 @@ func (d *driver) Pull(open *pc.Request_Open, stream *boilerplate.PullOutput) err
 	var c = &capture{
 		client:      client,
 		stream:      stream,
+		decoders:    make([]decoder.Decoder, len(open.Capture.Bindings)),
+		consumers:   make(map[string]string),
 		updateState: make(map[boilerplate.StateKey]map[string]*string),
 	}
 
@@ -150,6 +163,10 @@ func (d *driver) Pull(open *pc.Request_Open, stream *boilerplate.PullOutput) err
 			return fmt.Errorf("error parsing resource config: %w", err)
 		}
 
+		if c.decoders[i], err = config.newDecoder(&res, res.Stream); err != nil {
+			return err
+		}
+
 		sk := boilerplate.StateKey(binding.StateKey)
 		if _, ok := state.Streams[sk]; !ok {
 			state.Streams[sk] = make(map[string]*string)
@@ -162,6 +179,19 @@ func (d *driver) Pull(open *pc.Request_Open, stream *boilerplate.PullOutput) err
 			return fmt.Errorf("stream %s does not exist", res.Stream)
 		}
 
+		if config.Advanced.EnhancedFanOut {
+			// Consumers are registered by Apply, but that may not have
+			// happened yet if enhanced fan-out was enabled for an existing
+			// capture without re-applying it.
+			consumerARN, _, err := ensureConsumer(ctx, client, streams[streamIdx].arn, consumerName(open.Capture.Name.String()))
+			if err != nil {
+				return err
+			} else if err := awaitConsumerActive(ctx, client, consumerARN); err != nil {
+				return err
+			}
+			c.consumers[streams[streamIdx].arn] = consumerARN
+		}
+
 		group.Go(func() error {
 			return c.readStream(groupCtx, streams[streamIdx], sk, i, maps.Clone(state.Streams[sk]))
 		})
@@ -171,7 +201,116 @@ func (d *driver) Pull(open *pc.Request_Open, stream *boilerplate.PullOutput) err
 }
 
 func (d *driver) Apply(ctx context.Context, req *pc.Request_Apply) (*pc.Response_Applied, error) {
-	return &pc.Response_Applied{ActionDescription: ""}, nil
+	var config Config
+	if err := pf.UnmarshalStrict(req.Capture.ConfigJson, &config); err != nil {
+		return nil, fmt.Errorf("parsing config json: %w", err)
+	}
+
+	wanted, err := fanOutStreams(req.Capture)
+	if err != nil {
+		return nil, err
+	}
+	var registered = make(map[string]bool)
+	if req.LastCapture != nil {
+		if registered, err = fanOutStreams(req.LastCapture); err != nil {
+			return nil, err
+		}
+	}
+
+	// Consumers of the last applied spec that are no longer needed, either
+	// because enhanced fan-out was disabled or their bindings were removed, are
+	// deregistered so that they don't continue to incur charges.
+	var unneeded []string
+	for stream := range registered {
+		if !wanted[stream] {
+			unneeded = append(unneeded, stream)
+		}
+	}
+	slices.Sort(unneeded)
+
+	if len(wanted) == 0 && len(unneeded) == 0 {
+		return &pc.Response_Applied{ActionDescription: ""}, nil
+	}
+
+	client, err := connect(ctx, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	streams, err := listStreams(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("listing streams: %w", err)
+	}
+	var streamARN = func(name string) (string, bool) {
+		streamIdx := slices.IndexFunc(streams, func(s kinesisStream) bool {
+			return s.name == name
+		})
+		if streamIdx == -1 {
+			return "", false
+		}
+		return streams[streamIdx].arn, true
+	}
+
+	var desc string
+	name := consumerName(req.Capture.Name.String())
+	for _, binding := range req.Capture.Bindings {
+		var res resource
+		if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
+			return nil, fmt.Errorf("error parsing resource config: %w", err)
+		} else if !wanted[res.Stream] {
+			continue
+		}
+
+		arn, ok := streamARN(res.Stream)
+		if !ok {
+			return nil, fmt.Errorf("stream %s does not exist", res.Stream)
+		}
+
+		if _, created, err := ensureConsumer(ctx, client, arn, name); err != nil {
+			return nil, err
+		} else if created {
+			desc += fmt.Sprintf("Registered stream consumer %q for stream %q\n", name, res.Stream)
+		}
+	}
+
+	for _, stream := range unneeded {
+		arn, ok := streamARN(stream)
+		if !ok {
+			continue // The stream and its consumers no longer exist.
+		}
+
+		if deleted, err := deregisterConsumer(ctx, client, arn, name); err != nil {
+			return nil, err
+		} else if deleted {
+			desc += fmt.Sprintf("Deregistered stream consumer %q for stream %q\n", name, stream)
+		}
+	}
+
+	return &pc.Response_Applied{ActionDescription: desc}, nil
+}
+
+// fanOutStreams returns the names of the streams which have an enhanced
+// fan-out consumer registered for the capture spec.
+func fanOutStreams(spec *pf.CaptureSpec) (map[string]bool, error) {
+	var config Config
+	if err := pf.UnmarshalStrict(spec.ConfigJson, &config); err != nil {
+		return nil, fmt.Errorf("parsing config json: %w", err)
+	}
+
+	var out = make(map[string]bool)
+	if !config.Advanced.EnhancedFanOut {
+		return out, nil
+	}
+
+	for _, binding := range spec.Bindings {
+		var res resource
+		if err := pf.UnmarshalStrict(binding.ResourceConfigJson, &res); err != nil {
+			return nil, fmt.Errorf("error parsing resource config: %w", err)
+		}
+		out[res.Stream] = true
+	}
+
+	return out, nil
 }
 
 func main() {
@@ -3,10 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
 	pc "github.com/estuary/flow/go/protocols/capture"
+	pf "github.com/estuary/flow/go/protocols/flow"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,3 +22,46 @@ func TestSpec(t *testing.T) {
 	require.NoError(t, err)
 	cupaloy.SnapshotT(t, string(formatted))
 }
+
+func TestDiscoverSubSequenceKey(t *testing.T) {
+	ctx := context.Background()
+	streams := []kinesisStream{{name: "stream"}}
+
+	for _, subSequenceKey := range []bool{false, true} {
+		bindings, err := discoverStreams(ctx, &Config{SubSequenceKey: subSequenceKey}, streams)
+		require.NoError(t, err)
+		require.Equal(t, discoveredKey(subSequenceKey), bindings[0].Key)
+
+		var schema struct {
+			Properties map[string]struct {
+				Required []string `json:"required"`
+			} `json:"properties"`
+		}
+		require.NoError(t, json.Unmarshal(bindings[0].DocumentSchemaJson, &schema))
+		require.Equal(t, subSequenceKey, slices.Contains(schema.Properties[metaProperty].Required, subSequenceNumber))
+	}
+
+	require.Equal(t, []string{"/_meta/sequence_number", "/_meta/partition_key"}, discoveredKey(false))
+}
+
+func TestFanOutStreams(t *testing.T) {
+	spec := func(enhancedFanOut bool, streams ...string) *pf.CaptureSpec {
+		out := &pf.CaptureSpec{
+			ConfigJson: json.RawMessage(fmt.Sprintf(`{"region":"us-east-1","awsAccessKeyId":"x","awsSecretAccessKey":"x","advanced":{"enhancedFanOut":%t}}`, enhancedFanOut)),
+		}
+		for _, s := range streams {
+			out.Bindings = append(out.Bindings, &pf.CaptureSpec_Binding{
+				ResourceConfigJson: json.RawMessage(fmt.Sprintf(`{"stream":%q}`, s)),
+			})
+		}
+		return out
+	}
+
+	got, err := fanOutStreams(spec(true, "a", "b"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"a": true, "b": true}, got)
+
+	got, err = fanOutStreams(spec(false, "a", "b"))
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
@@ -10,12 +10,14 @@ import (
 	"strings"
 	"time"
 
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/estuary/connectors/filesource"
+	awsauth "github.com/estuary/connectors/go/auth/aws"
 	cerrors "github.com/estuary/connectors/go/connector-errors"
 	"github.com/estuary/flow/go/parser"
 	pf "github.com/estuary/flow/go/protocols/flow"
@@ -23,14 +25,15 @@ import (
 )
 
 type config struct {
-	AWSAccessKeyID     string         `json:"awsAccessKeyId"`
-	AWSSecretAccessKey string         `json:"awsSecretAccessKey"`
-	Bucket             string         `json:"bucket"`
-	MatchKeys          string         `json:"matchKeys"`
-	Parser             *parser.Config `json:"parser"`
-	Prefix             string         `json:"prefix"`
-	Region             string         `json:"region"`
-	Advanced           advancedConfig `json:"advanced"`
+	Credentials        *awsauth.CredentialConfig `json:"credentials"`
+	AWSAccessKeyID     string                    `json:"awsAccessKeyId"`
+	AWSSecretAccessKey string                    `json:"awsSecretAccessKey"`
+	Bucket             string                    `json:"bucket"`
+	MatchKeys          string                    `json:"matchKeys"`
+	Parser             *parser.Config            `json:"parser"`
+	Prefix             string                    `json:"prefix"`
+	Region             string                    `json:"region"`
+	Advanced           advancedConfig            `json:"advanced"`
 }
 
 type advancedConfig struct {
@@ -48,6 +51,11 @@ func (c config) Validate() error {
 	if c.AWSAccessKeyID != "" && c.AWSSecretAccessKey == "" {
 		return fmt.Errorf("missing awsSecretAccessKey")
 	}
+	if c.Credentials != nil {
+		if err := c.Credentials.Validate(); err != nil {
+			return fmt.Errorf("invalid credentials: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -78,7 +86,13 @@ type s3Store struct {
 func newS3Store(ctx context.Context, cfg config) (*s3Store, error) {
 	var c = aws.NewConfig()
 
-	if cfg.AWSSecretAccessKey != "" {
+	if cfg.Credentials != nil {
+		creds, err := cfg.Credentials.V1Credentials(ctx, awsConfig.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, fmt.Errorf("creating aws credentials: %w", err)
+		}
+		c = c.WithCredentials(creds)
+	} else if cfg.AWSSecretAccessKey != "" {
 		var creds = credentials.NewStaticCredentials(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, "")
 		c = c.WithCredentials(creds)
 	} else {
@@ -277,6 +291,13 @@ func main() {
 			return newS3Store(ctx, cfg.(config))
 		},
 		ConfigSchema: func(parserSchema json.RawMessage) json.RawMessage {
+			credentialsSchema := awsauth.CredentialConfig{}.JSONSchema()
+			credentialsSchema.Extras["order"] = 0
+			credentialsJSON, err := json.Marshal(credentialsSchema)
+			if err != nil {
+				panic(fmt.Errorf("generating credentials schema: %w", err))
+			}
+
 			return json.RawMessage(`{
 		"$schema": "http://json-schema.org/draft-07/schema#",
 		"title":   "S3 Source",
@@ -286,43 +307,44 @@ func main() {
 			"region"
 		],
 		"properties": {
+			"credentials": ` + string(credentialsJSON) + `,
 			"awsAccessKeyId": {
 				"type":        "string",
 				"title":       "AWS Access Key ID",
-				"description": "Part of the AWS credentials that will be used to connect to S3. Required unless the bucket is public and allows anonymous listings and reads.",
-				"order": 0
+				"description": "Deprecated: use Authentication instead. Part of the AWS credentials that will be used to connect to S3. Not required if Authentication is configured, or if the bucket is public and allows anonymous listings and reads.",
+				"order": 1
 			},
 			"awsSecretAccessKey": {
 				"type":        "string",
 				"title":       "AWS Secret Access Key",
-				"description": "Part of the AWS credentials that will be used to connect to S3. Required unless the bucket is public and allows anonymous listings and reads.",
+				"description": "Deprecated: use Authentication instead. Part of the AWS credentials that will be used to connect to S3. Not required if Authentication is configured, or if the bucket is public and allows anonymous listings and reads.",
 				"secret":      true,
-				"order":       1
+				"order":       2
 			},
 			"region": {
 				"type":        "string",
 				"title":       "AWS Region",
 				"description": "The name of the AWS region where the S3 bucket is located.",
-				"order":       2
+				"order":       3
 			},
 			"bucket": {
 				"type":        "string",
 				"title":       "Bucket",
 				"description": "Name of the S3 bucket",
-				"order":       3
+				"order":       4
 			},
 			"prefix": {
 				"type":        "string",
 				"title":       "Prefix",
 				"description": "Prefix within the bucket to capture from.",
-				"order":       4
+				"order":       5
 			},
 			"matchKeys": {
 				"type":        "string",
 				"title":       "Match Keys",
 				"format":      "regex",
 				"description": "Filter applied to all object keys under the prefix. If provided, only objects whose absolute path matches this regex will be read. For example, you can use \".*\\.json\" to only capture json files.",
-				"order":       5
+				"order":       6
 			},
 			"advanced": {
 				"properties": {
@@ -5,6 +5,9 @@ export AWS_ACCESS_KEY_ID="${AWS_ACCESS_KEY_ID:=test}"
 export AWS_SECRET_ACCESS_KEY="${AWS_SECRET_ACCESS_KEY:=test}"
 export AWS_DEFAULT_REGION="${AWS_DEFAULT_REGION:=test}"
 export KINESIS_ENDPOINT="${KINESIS_ENDPOINT:=http://source-kinesis-db-1.flow-test:4566}"
+# Read the stream using enhanced fan-out unless otherwise specified, since
+# polling is covered by the connector's own tests.
+export KINESIS_ENHANCED_FAN_OUT="${KINESIS_ENHANCED_FAN_OUT:=true}"
 
 export TEST_STREAM="estuary-test-$(shuf -zer -n6 {a..z} | tr -d '\0')"
 export RESOURCE="{ \"stream\": \"${TEST_STREAM}\" }"
@@ -16,7 +19,8 @@ config_json_template='{
     "awsSecretAccessKey": "$AWS_SECRET_ACCESS_KEY",
     "region": "${AWS_DEFAULT_REGION}",
     "advanced": {
-        "endpoint": "${KINESIS_ENDPOINT}"
+        "endpoint": "${KINESIS_ENDPOINT}",
+        "enhancedFanOut": ${KINESIS_ENHANCED_FAN_OUT}
     }
 }'
 